@@ -0,0 +1,156 @@
+package Behavior
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/jermon/GoEV3/Control"
+	"github.com/jermon/GoEV3/Drive"
+)
+
+// Drives a drive base towards an object, slowing down as it gets closer and
+// stopping at a set gap, for docking against walls or picking up objects
+// gently. It backs off if the object is closer than the gap.
+//
+//	a := Behavior.NewApproach(base, func() float64 { return us.Distance().Centimeters() }, 5)
+//	a.SetSpeeds(10, 60)
+//	a.Approach(stop)
+type Approach struct {
+	lock sync.Mutex
+
+	base     *Drive.DriveBase
+	distance func() float64
+	pid      *Control.PID
+
+	gap       float64
+	tolerance float64
+	minSpeed  int16
+	interval  time.Duration
+	reading   float64
+}
+
+// Creates a controller approaching to `gap` centimeters, as measured by
+// `distance` in centimeters, e.g. from an ultrasonic or infrared sensor facing
+// forward.
+func NewApproach(base *Drive.DriveBase, distance func() float64, gap float64) *Approach {
+	a := new(Approach)
+	a.base = base
+	a.distance = distance
+	a.gap = gap
+	a.tolerance = 1
+	a.minSpeed = 8
+	a.interval = 20 * time.Millisecond
+
+	a.pid = Control.NewPID(3, 0, 0)
+	a.pid.SetSetpoint(gap)
+	a.pid.SetOutputLimits(-50, 50)
+
+	return a
+}
+
+// Sets the gap in centimeters to stop at.
+func (self *Approach) SetGap(gap float64) {
+	self.lock.Lock()
+	self.gap = gap
+	self.lock.Unlock()
+
+	self.pid.SetSetpoint(gap)
+}
+
+// Sets how far from the gap, in centimeters, counts as arrived.
+func (self *Approach) SetTolerance(tolerance float64) {
+	self.lock.Lock()
+	self.tolerance = tolerance
+	self.lock.Unlock()
+}
+
+// Sets the speed per centimeter of distance left to cover, as passed to Motor.Run.
+func (self *Approach) SetGain(gain float64) {
+	self.pid.SetGains(gain, 0, 0)
+}
+
+// Sets the lowest speed, enough to overcome friction until arrived, and the
+// highest one, used while far away.
+func (self *Approach) SetSpeeds(min int16, max int16) {
+	self.lock.Lock()
+	self.minSpeed = min
+	self.lock.Unlock()
+
+	self.pid.SetOutputLimits(-float64(max), float64(max))
+}
+
+// Returns the last distance measured, in centimeters.
+func (self *Approach) Distance() float64 {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	return self.reading
+}
+
+// Reports whether the last distance measured is within the tolerance of the gap.
+func (self *Approach) Arrived() bool {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	return math.Abs(self.reading-self.gap) <= self.tolerance
+}
+
+// Measures the distance once and returns the forward speed for the next step,
+// 0 once arrived.
+func (self *Approach) Step() int16 {
+	d := self.distance()
+
+	self.lock.Lock()
+	self.reading = d
+	minSpeed := self.minSpeed
+	self.lock.Unlock()
+
+	if self.Arrived() {
+		return 0
+	}
+
+	// Too far gives a negative output, which must drive forward.
+	speed := -self.pid.Update(d)
+	if math.Abs(speed) < float64(minSpeed) {
+		speed = math.Copysign(float64(minSpeed), speed)
+	}
+
+	return int16(math.Round(speed))
+}
+
+// Proposes the next approach step to an Arbiter, wanting control until arrived.
+func (self *Approach) Propose() (Command, bool) {
+	speed := self.Step()
+	return Command{self.base.Left(): speed, self.base.Right(): speed}, speed != 0
+}
+
+// Drives until arrived, returning true, or until a value is sent to `stop`.
+// The motors are stopped before returning.
+func (self *Approach) Approach(stop <-chan bool) bool {
+	self.pid.Reset()
+
+	self.lock.Lock()
+	interval := self.interval
+	self.lock.Unlock()
+
+	defer self.base.Stop()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		speed := self.Step()
+		if speed == 0 {
+			return true
+		}
+
+		self.base.Tank(speed, speed)
+
+		select {
+		case <-stop:
+			return false
+		case <-ticker.C:
+		}
+	}
+}
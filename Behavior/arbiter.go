@@ -0,0 +1,172 @@
+// Provides a subsumption-style behavior arbitration layer.
+//
+// Every tick each behavior looks at the sensors and may propose motor commands.
+// The arbiter picks the proposal of the highest-priority behavior that wants
+// control and applies it, so e.g. "avoid obstacle" overrides "follow line",
+// which overrides "wander":
+//
+//	a := Behavior.NewArbiter()
+//	a.Add("wander", 1, func() (Behavior.Command, bool) {
+//		return Behavior.Command{left: 40, right: 40}, true
+//	})
+//	a.Add("avoid", 10, func() (Behavior.Command, bool) {
+//		return Behavior.Command{left: -30, right: 30}, ir.ReadProximity() < 30
+//	})
+//	a.Run(stop, 20*time.Millisecond)
+package Behavior
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/jermon/GoEV3/Motor"
+)
+
+// Motor speeds proposed by a behavior, as passed to Motor.Run. A speed of zero stops the motor.
+type Command map[*Motor.Motor]int16
+
+type entry struct {
+	name     string
+	priority int
+	propose  func() (Command, bool)
+}
+
+// Picks the winning behavior every tick and drives the motors accordingly.
+type Arbiter struct {
+	lock      sync.Mutex
+	behaviors []*entry
+	active    string
+	applied   Command
+	onSwitch  func(from string, to string)
+}
+
+// Creates an arbiter without behaviors.
+func NewArbiter() *Arbiter {
+	return new(Arbiter)
+}
+
+// Adds a behavior. `propose` is called every tick and returns the motor command
+// the behavior would like to execute and whether it wants control at all.
+// Behaviors with higher priority take precedence.
+func (self *Arbiter) Add(name string, priority int, propose func() (Command, bool)) {
+	self.lock.Lock()
+	self.behaviors = append(self.behaviors, &entry{name, priority, propose})
+	sort.SliceStable(self.behaviors, func(i, j int) bool {
+		return self.behaviors[i].priority > self.behaviors[j].priority
+	})
+	self.lock.Unlock()
+}
+
+// Removes the behavior with the given name.
+func (self *Arbiter) Remove(name string) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	for i, b := range self.behaviors {
+		if b.name == name {
+			self.behaviors = append(self.behaviors[:i], self.behaviors[i+1:]...)
+			return
+		}
+	}
+}
+
+// Registers a callback invoked whenever a different behavior takes control.
+func (self *Arbiter) OnSwitch(fn func(from string, to string)) {
+	self.lock.Lock()
+	self.onSwitch = fn
+	self.lock.Unlock()
+}
+
+// Returns the name of the behavior in control, or an empty string.
+func (self *Arbiter) Active() string {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	return self.active
+}
+
+// Polls the behaviors once and applies the winning command. Returns the name of
+// the winning behavior, or an empty string if none wants control, in which case
+// all motors are stopped.
+func (self *Arbiter) Tick() string {
+	self.lock.Lock()
+	behaviors := append([]*entry(nil), self.behaviors...)
+	self.lock.Unlock()
+
+	var winner string
+	var command Command
+
+	for _, b := range behaviors {
+		if c, ok := b.propose(); ok {
+			winner, command = b.name, c
+			break
+		}
+	}
+
+	self.lock.Lock()
+	previous := self.active
+	applied := self.applied
+	notify := self.onSwitch
+	self.active = winner
+	self.applied = command
+	self.lock.Unlock()
+
+	apply(applied, command)
+
+	if winner != previous && notify != nil {
+		notify(previous, winner)
+	}
+
+	return winner
+}
+
+// Writes only the speeds that changed since the previous command, stopping
+// motors that the new command doesn't mention.
+func apply(previous Command, next Command) {
+	for m, speed := range next {
+		if old, ok := previous[m]; ok && old == speed {
+			continue
+		}
+
+		if speed == 0 {
+			m.Stop()
+		} else {
+			m.Run(speed)
+		}
+	}
+
+	for m, speed := range previous {
+		if _, ok := next[m]; !ok && speed != 0 {
+			m.Stop()
+		}
+	}
+}
+
+// Stops every motor commanded by the active behavior.
+func (self *Arbiter) StopAll() {
+	self.lock.Lock()
+	applied := self.applied
+	self.applied = nil
+	self.active = ""
+	self.lock.Unlock()
+
+	apply(applied, nil)
+}
+
+// Ticks the arbiter at the given interval until a value is sent to `stop`, then stops the motors.
+func (self *Arbiter) Run(stop <-chan bool, tick time.Duration) {
+	ticker := time.NewTicker(tick)
+	defer ticker.Stop()
+	defer self.StopAll()
+
+	for {
+		self.Tick()
+
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
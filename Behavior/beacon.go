@@ -0,0 +1,179 @@
+package Behavior
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/jermon/GoEV3/Drive"
+	"github.com/jermon/GoEV3/Sensors"
+)
+
+// Steers a drive base towards the IR beacon on one channel, as read by an
+// infrared sensor in seek mode facing forward, slowing down as it gets closer
+// and stopping at a set distance. While the beacon isn't seen, the robot
+// spins towards where it was last seen to find it again.
+//
+//	f := Behavior.NewBeaconFollower(base, ir, Sensors.Channel1)
+//	f.SetStopDistance(10)
+//	f.Follow(stop)
+type BeaconFollower struct {
+	lock sync.Mutex
+
+	base    *Drive.DriveBase
+	sensor  *Sensors.InfraredSensor
+	channel Sensors.Channel
+
+	steeringGain float64
+	speedGain    float64
+	stopDistance int16
+	minSpeed     int16
+	maxSpeed     int16
+	searchSpeed  int16
+	interval     time.Duration
+
+	reading  Sensors.BeaconReading
+	lastSide float64
+}
+
+// Creates a follower of the beacon on `channel`, seen by `sensor`.
+func NewBeaconFollower(base *Drive.DriveBase, sensor *Sensors.InfraredSensor, channel Sensors.Channel) *BeaconFollower {
+	f := new(BeaconFollower)
+	f.base = base
+	f.sensor = sensor
+	f.channel = channel
+	f.steeringGain = 4
+	f.speedGain = 2
+	f.stopDistance = 5
+	f.minSpeed = 10
+	f.maxSpeed = 60
+	f.searchSpeed = 20
+	f.interval = 20 * time.Millisecond
+	f.lastSide = 1
+
+	return f
+}
+
+// Sets the steering, in range [-100, 100] as for Drive.SteeringSpeeds, per step
+// of the beacon's heading, and the speed per step of its distance beyond the
+// stop distance. The defaults are 4 and 2.
+func (self *BeaconFollower) SetGains(steering float64, speed float64) {
+	self.lock.Lock()
+	self.steeringGain = steering
+	self.speedGain = speed
+	self.lock.Unlock()
+}
+
+// Sets the distance to the beacon, in the sensor's steps in range [0, 100],
+// to stop at.
+func (self *BeaconFollower) SetStopDistance(distance int16) {
+	self.lock.Lock()
+	self.stopDistance = distance
+	self.lock.Unlock()
+}
+
+// Sets the lowest speed, enough to overcome friction until arrived, and the
+// highest one, used while far away.
+func (self *BeaconFollower) SetSpeeds(min int16, max int16) {
+	self.lock.Lock()
+	self.minSpeed = min
+	self.maxSpeed = max
+	self.lock.Unlock()
+}
+
+// Sets the speed to spin at while the beacon isn't seen; 0 stops instead.
+func (self *BeaconFollower) SetSearchSpeed(speed int16) {
+	self.lock.Lock()
+	self.searchSpeed = speed
+	self.lock.Unlock()
+}
+
+// Returns the last beacon reading.
+func (self *BeaconFollower) Reading() Sensors.BeaconReading {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	return self.reading
+}
+
+// Reports whether the beacon was within the stop distance at the last reading.
+func (self *BeaconFollower) Arrived() bool {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	return self.reading.Detected && self.reading.Distance <= self.stopDistance
+}
+
+// Reads the beacon once and returns the wheel speeds for the next step, both
+// 0 once arrived.
+func (self *BeaconFollower) Step() (int16, int16) {
+	r, _ := self.sensor.ReadBeacon(self.channel)
+
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	self.reading = r
+
+	if !r.Detected {
+		if self.lastSide > 0 {
+			return self.searchSpeed, -self.searchSpeed
+		}
+		return -self.searchSpeed, self.searchSpeed
+	}
+
+	if r.Heading != 0 {
+		self.lastSide = float64(r.Heading)
+	}
+	if r.Distance <= self.stopDistance {
+		return 0, 0
+	}
+
+	speed := math.Min(float64(self.maxSpeed), float64(r.Distance-self.stopDistance)*self.speedGain)
+	speed = math.Max(float64(self.minSpeed), speed)
+
+	// The heading grows to the right, as does the steering.
+	return Drive.SteeringSpeeds(float64(r.Heading)*self.steeringGain, int16(math.Round(speed)))
+}
+
+// Proposes the next step to an Arbiter, wanting control while the beacon is
+// seen and not yet reached.
+func (self *BeaconFollower) Propose() (Command, bool) {
+	left, right := self.Step()
+	return Command{self.base.Left(): left, self.base.Right(): right}, self.Reading().Detected && !self.Arrived()
+}
+
+// Follows the beacon until arrived, returning true, or until a value is sent
+// to `stop`. The motors are stopped before returning.
+func (self *BeaconFollower) Follow(stop <-chan bool) bool {
+	self.lock.Lock()
+	interval := self.interval
+	self.lock.Unlock()
+
+	defer self.base.Stop()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		left, right := self.Step()
+		if self.Arrived() {
+			return true
+		}
+
+		self.base.Tank(left, right)
+
+		select {
+		case <-stop:
+			return false
+		case <-ticker.C:
+		}
+	}
+}
+
+// Drives towards the beacon on `channel` with the default gains until it is
+// reached, returning true, or until a value is sent to `stop`:
+//
+//	Behavior.FollowBeacon(base, ir, Sensors.Channel1, stop)
+func FollowBeacon(base *Drive.DriveBase, sensor *Sensors.InfraredSensor, channel Sensors.Channel, stop <-chan bool) bool {
+	return NewBeaconFollower(base, sensor, channel).Follow(stop)
+}
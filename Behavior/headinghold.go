@@ -0,0 +1,153 @@
+package Behavior
+
+import (
+	"sync"
+	"time"
+
+	"github.com/jermon/GoEV3/Control"
+	"github.com/jermon/GoEV3/Drive"
+	"github.com/jermon/GoEV3/Sensors"
+	"github.com/jermon/GoEV3/Units"
+)
+
+// Anything that reports the robot's heading, counter-clockwise being positive:
+// a gyro, a compass, or an estimate fusing several of them.
+type HeadingSource interface {
+	Heading() Units.Angle
+}
+
+// Adapts a function to a HeadingSource.
+type HeadingFunc func() Units.Angle
+
+func (self HeadingFunc) Heading() Units.Angle {
+	return self()
+}
+
+// Returns the heading measured by a gyro sensor in angle mode. The gyro
+// counts clockwise, so its angle is negated.
+func GyroHeading(gyro *Sensors.GyroSensor) HeadingSource {
+	return HeadingFunc(func() Units.Angle { return -gyro.Angle() })
+}
+
+// Returns the heading measured by the wheel encoders of a drive base, which
+// drifts as the wheels slip.
+func OdometryHeading(base *Drive.DriveBase) HeadingSource {
+	return HeadingFunc(base.Rotation)
+}
+
+// Keeps a drive base on a commanded heading while it drives, steering with a
+// PID controller on the heading error:
+//
+//	hold := Behavior.NewHeadingHold(base, Behavior.GyroHeading(gyro))
+//	hold.SetSpeed(40)
+//	go hold.Hold(stop)
+//	...
+//	hold.SetTarget(hold.Target() + 90*Units.Degree)
+type HeadingHold struct {
+	lock sync.Mutex
+
+	base   *Drive.DriveBase
+	source HeadingSource
+	pid    *Control.PID
+
+	target   Units.Angle
+	speed    int16
+	interval time.Duration
+}
+
+// Creates a controller holding the current heading of `source`.
+func NewHeadingHold(base *Drive.DriveBase, source HeadingSource) *HeadingHold {
+	h := new(HeadingHold)
+	h.base = base
+	h.source = source
+	h.target = source.Heading()
+	h.speed = 30
+	h.interval = 20 * time.Millisecond
+
+	// The error is measured, so the setpoint is 0.
+	h.pid = Control.NewPID(2, 0, 0.1)
+	h.pid.SetOutputLimits(-100, 100)
+	h.pid.SetDerivativeFilter(60 * time.Millisecond)
+
+	return h
+}
+
+// Sets the heading to hold, counter-clockwise being positive.
+func (self *HeadingHold) SetTarget(heading Units.Angle) {
+	self.lock.Lock()
+	self.target = heading
+	self.lock.Unlock()
+}
+
+// Returns the heading held.
+func (self *HeadingHold) Target() Units.Angle {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	return self.target
+}
+
+// Sets the forward speed, as passed to Motor.Run. Negative speeds drive backwards.
+func (self *HeadingHold) SetSpeed(speed int16) {
+	self.lock.Lock()
+	self.speed = speed
+	self.lock.Unlock()
+}
+
+// Sets the controller gains, in steering units per degree of error.
+func (self *HeadingHold) SetGains(kp float64, ki float64, kd float64) {
+	self.pid.SetGains(kp, ki, kd)
+}
+
+// Returns how far the heading is from the target, the shorter way round,
+// positive when the robot must turn counter-clockwise.
+func (self *HeadingHold) Error() Units.Angle {
+	return (self.Target() - self.source.Heading()).Normalized()
+}
+
+// Reads the heading once and returns the wheel speeds for the next step.
+func (self *HeadingHold) Step() (int16, int16) {
+	self.lock.Lock()
+	speed := self.speed
+	self.lock.Unlock()
+
+	// A positive error needs a left turn, which is negative steering. Steering
+	// is mirrored when driving backwards.
+	steering := self.pid.Update(-self.Error().Degrees())
+	if speed >= 0 {
+		steering = -steering
+	}
+
+	return Drive.SteeringSpeeds(steering, speed)
+}
+
+// Proposes the next heading hold step to an Arbiter.
+func (self *HeadingHold) Propose() (Command, bool) {
+	l, r := self.Step()
+	return Command{self.base.Left(): l, self.base.Right(): r}, true
+}
+
+// Drives on the target heading until a value is sent to `stop`. The motors are
+// stopped before returning.
+func (self *HeadingHold) Hold(stop <-chan bool) {
+	self.pid.Reset()
+
+	self.lock.Lock()
+	interval := self.interval
+	self.lock.Unlock()
+
+	defer self.base.Stop()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		self.base.Tank(self.Step())
+
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
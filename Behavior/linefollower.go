@@ -0,0 +1,495 @@
+package Behavior
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/jermon/GoEV3/Control"
+	"github.com/jermon/GoEV3/Drive"
+	"github.com/jermon/GoEV3/Sensors"
+)
+
+// Edge of the line a follower tracks.
+type Edge int
+
+const (
+	// The line is kept on the right side of the sensor.
+	LeftEdge Edge = -1
+	// The line is kept on the left side of the sensor.
+	RightEdge = 1
+)
+
+// Reflected light intensities measured over the line and over the background.
+type LightCalibration struct {
+	Black uint8
+	White uint8
+}
+
+// Default calibration, suitable for black tape on a white mat.
+var DefaultLightCalibration = LightCalibration{Black: 5, White: 80}
+
+// Maps a raw reflected light intensity to the range [0, 100], 0 being the line
+// and 100 the background.
+func (self LightCalibration) Normalize(raw uint8) float64 {
+	span := float64(self.White) - float64(self.Black)
+	if span <= 0 {
+		return float64(raw)
+	}
+
+	return math.Max(0, math.Min(100, (float64(raw)-float64(self.Black))/span*100))
+}
+
+// Normalized reading below which the sensor is considered to be fully over the line.
+const darkThreshold = 10
+
+// Number of consecutive dark readings that are counted as an intersection.
+const intersectionSamples = 3
+
+// Kinds of markers detected while following a line.
+type Marker int
+
+const (
+	// A junction or crossing line, seen as wide dark surface under the sensor.
+	Junction Marker = iota
+	// A patch of one of the colors set with SetMarkerColors.
+	ColorPatch
+)
+
+func (self Marker) String() string {
+	if self == Junction {
+		return "junction"
+	}
+
+	return "color patch"
+}
+
+// Reports a marker passed while following a line.
+type MarkerEvent struct {
+	Kind Marker
+	// Color of color patches.
+	Color Sensors.Color
+	// Number of markers of this kind passed since Follow was called, this one included.
+	Count int
+	// Centimeters traveled since Follow was called.
+	Distance float64
+}
+
+// Follows the edge of a line with a color sensor, steering a drive base with a PID controller.
+type LineFollower struct {
+	lock sync.Mutex
+
+	base   *Drive.DriveBase
+	sensor *Sensors.ColorSensor
+	pid    *Control.PID
+
+	speed          int16
+	aggressiveness float64
+	edge           Edge
+	calibration    LightCalibration
+
+	reading   float64
+	darkCount int
+	start     float64
+	interval  time.Duration
+	baseGains [3]float64
+
+	junctions    int
+	markerColors []Sensors.Color
+	// Last color read and the color patch being passed, for debouncing.
+	candidate Sensors.Color
+	patch     Sensors.Color
+	patches   int
+	onMarker  []func(MarkerEvent)
+}
+
+// Creates a line follower driving `base` and reading `sensor`.
+func NewLineFollower(base *Drive.DriveBase, sensor *Sensors.ColorSensor) *LineFollower {
+	f := new(LineFollower)
+	f.base = base
+	f.sensor = sensor
+	f.speed = 30
+	f.aggressiveness = 1
+	f.edge = RightEdge
+	f.calibration = DefaultLightCalibration
+	f.interval = 10 * time.Millisecond
+	f.baseGains = [3]float64{1, 0, 0.02}
+
+	f.pid = Control.NewPID(f.baseGains[0], f.baseGains[1], f.baseGains[2])
+	f.pid.SetSetpoint(50)
+	f.pid.SetOutputLimits(-100, 100)
+	f.pid.SetDerivativeFilter(30 * time.Millisecond)
+
+	return f
+}
+
+// Sets the forward speed, as passed to Motor.Run.
+func (self *LineFollower) SetSpeed(speed int16) {
+	self.lock.Lock()
+	self.speed = speed
+	self.lock.Unlock()
+}
+
+// Scales the PID gains. Values above 1 correct harder, values below 1 drive smoother.
+func (self *LineFollower) SetAggressiveness(aggressiveness float64) {
+	self.lock.Lock()
+	self.aggressiveness = aggressiveness
+	g := self.baseGains
+	self.lock.Unlock()
+
+	self.pid.SetGains(g[0]*aggressiveness, g[1]*aggressiveness, g[2]*aggressiveness)
+}
+
+// Sets the PID gains used at an aggressiveness of 1.
+func (self *LineFollower) SetGains(kp float64, ki float64, kd float64) {
+	self.lock.Lock()
+	self.baseGains = [3]float64{kp, ki, kd}
+	a := self.aggressiveness
+	self.lock.Unlock()
+
+	self.pid.SetGains(kp*a, ki*a, kd*a)
+}
+
+// Sets the normalized reading, in range [0, 100], kept while following: 50,
+// the default, is halfway between the line and the background.
+func (self *LineFollower) SetTarget(target float64) {
+	self.pid.SetSetpoint(target)
+}
+
+// Selects which edge of the line to follow.
+func (self *LineFollower) SetEdge(edge Edge) {
+	self.lock.Lock()
+	self.edge = edge
+	self.lock.Unlock()
+}
+
+// Sets the reflected light calibration.
+func (self *LineFollower) SetCalibration(calibration LightCalibration) {
+	self.lock.Lock()
+	self.calibration = calibration
+	self.lock.Unlock()
+}
+
+// Returns the reflected light calibration.
+func (self *LineFollower) Calibration() LightCalibration {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	return self.calibration
+}
+
+// Sets how often the sensor is sampled while following.
+func (self *LineFollower) SetInterval(interval time.Duration) {
+	self.lock.Lock()
+	self.interval = interval
+	self.lock.Unlock()
+}
+
+// Calibrates by slowly turning in place over the line for the given duration,
+// half of it in each direction, recording the darkest and brightest readings.
+func (self *LineFollower) Calibrate(duration time.Duration) LightCalibration {
+	black, white := uint8(100), uint8(0)
+	deadline := time.Now().Add(duration)
+	turnBackAt := time.Now().Add(duration / 2)
+
+	self.base.Tank(15, -15)
+
+	for time.Now().Before(deadline) {
+		if time.Now().After(turnBackAt) {
+			self.base.Tank(-15, 15)
+			turnBackAt = deadline
+		}
+
+		value := self.sensor.ReadReflectedLightIntensity()
+		if value < black {
+			black = value
+		}
+		if value > white {
+			white = value
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	self.base.Stop()
+
+	c := LightCalibration{Black: black, White: white}
+	self.SetCalibration(c)
+
+	return c
+}
+
+// Sets colors that mark points along the line, such as patches where a
+// mission changes course. Each step then also reads the color, which requires
+// switching sensor modes and makes it noticeably slower.
+func (self *LineFollower) SetMarkerColors(colors ...Sensors.Color) {
+	self.lock.Lock()
+	self.markerColors = append([]Sensors.Color(nil), colors...)
+	self.lock.Unlock()
+}
+
+// Registers a callback invoked, from the following loop, whenever a junction
+// or color patch is passed.
+func (self *LineFollower) OnMarker(fn func(MarkerEvent)) {
+	self.lock.Lock()
+	self.onMarker = append(self.onMarker, fn)
+	self.lock.Unlock()
+}
+
+// Returns the number of junctions passed since Follow was called.
+func (self *LineFollower) Junctions() int {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	return self.junctions
+}
+
+// Returns the number of color patches passed since Follow was called.
+func (self *LineFollower) ColorPatches() int {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	return self.patches
+}
+
+// Returns the drive base being steered.
+func (self *LineFollower) Base() *Drive.DriveBase {
+	return self.base
+}
+
+// Returns the color sensor being read.
+func (self *LineFollower) Sensor() *Sensors.ColorSensor {
+	return self.sensor
+}
+
+// Returns the last normalized reading, 0 being the line and 100 the background.
+func (self *LineFollower) Reading() float64 {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	return self.reading
+}
+
+// Returns the distance, in centimeters, traveled since Follow was called.
+func (self *LineFollower) DistanceTraveled() float64 {
+	self.lock.Lock()
+	start := self.start
+	self.lock.Unlock()
+
+	return self.base.Distance() - start
+}
+
+// Reads the sensor once and returns the wheel speeds that keep the sensor on the edge.
+func (self *LineFollower) Step() (int16, int16) {
+	raw := self.sensor.ReadReflectedLightIntensity()
+
+	self.lock.Lock()
+	reading := self.calibration.Normalize(raw)
+	self.reading = reading
+	if reading < darkThreshold {
+		self.darkCount++
+	} else {
+		self.darkCount = 0
+	}
+
+	var events []MarkerEvent
+	if self.darkCount == intersectionSamples {
+		self.junctions++
+		events = append(events, MarkerEvent{Kind: Junction, Count: self.junctions})
+	}
+
+	markerColors := self.markerColors
+	edge := self.edge
+	speed := self.speed
+	self.lock.Unlock()
+
+	if len(markerColors) > 0 {
+		if e, ok := self.checkColor(markerColors); ok {
+			events = append(events, e)
+		}
+	}
+
+	self.emit(events)
+
+	// Seeing more of the line than the setpoint means drifting onto it.
+	steering := self.pid.Update(reading) * float64(edge)
+
+	return Drive.SteeringSpeeds(steering, speed)
+}
+
+// Reads the color and reports a patch of a marker color once it was seen on
+// two consecutive readings, which filters out the colors seen briefly when
+// crossing from one to another.
+func (self *LineFollower) checkColor(markerColors []Sensors.Color) (MarkerEvent, bool) {
+	color := self.sensor.ReadColor()
+
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	if color != self.candidate {
+		self.candidate = color
+		return MarkerEvent{}, false
+	}
+
+	if color == self.patch {
+		return MarkerEvent{}, false
+	}
+
+	self.patch = Sensors.None
+	for _, c := range markerColors {
+		if c == color {
+			self.patch = color
+			self.patches++
+			return MarkerEvent{Kind: ColorPatch, Color: color, Count: self.patches}, true
+		}
+	}
+
+	return MarkerEvent{}, false
+}
+
+func (self *LineFollower) emit(events []MarkerEvent) {
+	if len(events) == 0 {
+		return
+	}
+
+	self.lock.Lock()
+	callbacks := append(([]func(MarkerEvent))(nil), self.onMarker...)
+	self.lock.Unlock()
+
+	distance := self.DistanceTraveled()
+	for _, e := range events {
+		e.Distance = distance
+		for _, fn := range callbacks {
+			fn(e)
+		}
+	}
+}
+
+// Proposes the next line following step to an Arbiter.
+func (self *LineFollower) Propose() (Command, bool) {
+	l, r := self.Step()
+	return Command{self.base.Left(): l, self.base.Right(): r}, true
+}
+
+// Condition checked on every step that ends line following when it returns true.
+type StopCondition func(f *LineFollower) bool
+
+// Stops when the sensor crosses the given number of intersections, i.e. stays
+// fully over dark surface for several consecutive readings.
+func AtIntersection(count int) StopCondition {
+	return func(f *LineFollower) bool {
+		return f.Junctions() >= count
+	}
+}
+
+// Stops when the given number of color patches have been passed. See SetMarkerColors.
+func AtColorPatch(count int) StopCondition {
+	return func(f *LineFollower) bool {
+		return f.ColorPatches() >= count
+	}
+}
+
+// Stops after driving the given distance in centimeters.
+func AfterDistance(distance float64) StopCondition {
+	return func(f *LineFollower) bool {
+		return math.Abs(f.DistanceTraveled()) >= distance
+	}
+}
+
+// Stops when the sensor detects one of the given colors. Reading colors requires
+// switching sensor modes, which makes each step noticeably slower.
+func AtColor(colors ...Sensors.Color) StopCondition {
+	return func(f *LineFollower) bool {
+		color := f.sensor.ReadColor()
+
+		for _, c := range colors {
+			if c == color {
+				return true
+			}
+		}
+
+		return false
+	}
+}
+
+// Follows the line until one of the conditions is met or a value is sent to
+// `stop`. Returns true if a condition ended the run. The motors are stopped
+// before returning.
+func (self *LineFollower) Follow(stop <-chan bool, conditions ...StopCondition) bool {
+	self.pid.Reset()
+
+	self.lock.Lock()
+	self.darkCount = 0
+	self.junctions = 0
+	self.patches = 0
+	self.candidate, self.patch = Sensors.None, Sensors.None
+	interval := self.interval
+	self.lock.Unlock()
+
+	start := self.base.Distance()
+	self.lock.Lock()
+	self.start = start
+	self.lock.Unlock()
+
+	defer self.base.Stop()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		l, r := self.Step()
+
+		for _, condition := range conditions {
+			if condition(self) {
+				return true
+			}
+		}
+
+		self.base.Tank(l, r)
+
+		select {
+		case <-stop:
+			return false
+		case <-ticker.C:
+		}
+	}
+}
+
+// Settings of FollowLine. Zero values stand for the defaults of LineFollower.
+type LineFollowOptions struct {
+	// Normalized reading kept, see SetTarget.
+	Target float64
+	// Forward speed, as passed to Motor.Run.
+	Speed int16
+	// Edge of the line followed.
+	Edge Edge
+	// Reflected light calibration.
+	Calibration LightCalibration
+}
+
+// Follows the line with `sensor`, driving `base`, until one of the conditions
+// is met or a value is sent to `stop`, and returns true if a condition ended
+// the run:
+//
+//	Behavior.FollowLine(base, color, Behavior.LineFollowOptions{Speed: 40, Edge: Behavior.LeftEdge},
+//		stop, Behavior.AtIntersection(2))
+//
+// Use a LineFollower for finer control, e.g. of the gains or of markers.
+func FollowLine(base *Drive.DriveBase, sensor *Sensors.ColorSensor, options LineFollowOptions, stop <-chan bool, conditions ...StopCondition) bool {
+	f := NewLineFollower(base, sensor)
+
+	if options.Target != 0 {
+		f.SetTarget(options.Target)
+	}
+	if options.Speed != 0 {
+		f.SetSpeed(options.Speed)
+	}
+	if options.Edge != 0 {
+		f.SetEdge(options.Edge)
+	}
+	if options.Calibration != (LightCalibration{}) {
+		f.SetCalibration(options.Calibration)
+	}
+
+	return f.Follow(stop, conditions...)
+}
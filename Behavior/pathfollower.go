@@ -0,0 +1,164 @@
+package Behavior
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/jermon/GoEV3/Drive"
+	"github.com/jermon/GoEV3/Geometry"
+)
+
+// Anything that reports the robot pose, such as Drive.Odometry.
+type PoseSource = Geometry.PoseSource
+
+// A point to drive through, in centimeters.
+type Waypoint struct {
+	X, Y float64
+}
+
+// Heading error in degrees beyond which the robot turns in place before driving on.
+const turnInPlaceError = 60
+
+// Drives a drive base through a list of waypoints, steering towards the next
+// one with the pose reported by odometry. Speed ramps up from the start and
+// down towards the last waypoint:
+//
+//	odometry := base.NewOdometry()
+//	go odometry.Run(stop, 10*time.Millisecond)
+//
+//	p := Behavior.NewPathFollower(base, odometry)
+//	p.Follow(stop, Behavior.Waypoint{50, 0}, Behavior.Waypoint{50, 50}, Behavior.Waypoint{0, 50})
+type PathFollower struct {
+	lock sync.Mutex
+
+	base *Drive.DriveBase
+	pose PoseSource
+
+	tolerance    float64
+	minSpeed     float64
+	maxSpeed     float64
+	acceleration float64
+	turnGain     float64
+	interval     time.Duration
+
+	index int
+}
+
+// Creates a path follower driving `base` and locating it with `pose`.
+func NewPathFollower(base *Drive.DriveBase, pose PoseSource) *PathFollower {
+	p := new(PathFollower)
+	p.base = base
+	p.pose = pose
+	p.tolerance = 2
+	p.minSpeed = 5
+	p.maxSpeed = 25
+	p.acceleration = 30
+	p.turnGain = 3
+	p.interval = 20 * time.Millisecond
+
+	return p
+}
+
+// Sets the distance in centimeters within which a waypoint counts as reached.
+func (self *PathFollower) SetTolerance(tolerance float64) {
+	self.lock.Lock()
+	self.tolerance = tolerance
+	self.lock.Unlock()
+}
+
+// Sets the speed profile in centimeters per second: the speed starts at
+// `min`, grows by `acceleration` every second up to `max`, and decreases by
+// as much towards the last waypoint.
+func (self *PathFollower) SetSpeeds(min float64, max float64, acceleration float64) {
+	self.lock.Lock()
+	self.minSpeed, self.maxSpeed, self.acceleration = min, max, acceleration
+	self.lock.Unlock()
+}
+
+// Sets the turning rate, in degrees per second per degree of heading error.
+func (self *PathFollower) SetTurnGain(gain float64) {
+	self.lock.Lock()
+	self.turnGain = gain
+	self.lock.Unlock()
+}
+
+// Returns the index of the waypoint being driven to.
+func (self *PathFollower) Index() int {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	return self.index
+}
+
+// Returns the distance along the path from `from` through the remaining waypoints.
+func remaining(from Geometry.Point, path []Waypoint) float64 {
+	total := 0.0
+	for _, w := range path {
+		p := Geometry.Point(w)
+		total += from.Distance(p)
+		from = p
+	}
+
+	return total
+}
+
+// Drives through the waypoints in order. Returns true once the last one is
+// reached, or false if a value is sent to `stop` first. The motors are
+// stopped before returning.
+func (self *PathFollower) Follow(stop <-chan bool, waypoints ...Waypoint) bool {
+	self.lock.Lock()
+	tolerance, interval := self.tolerance, self.interval
+	minSpeed, maxSpeed, acceleration, gain := self.minSpeed, self.maxSpeed, self.acceleration, self.turnGain
+	self.index = 0
+	self.lock.Unlock()
+
+	defer self.base.Stop()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	speed := minSpeed
+	last := time.Now()
+
+	for i := 0; i < len(waypoints); {
+		pose := Geometry.Of(self.pose)
+		target := Geometry.Point(waypoints[i])
+
+		if pose.DistanceTo(target) <= tolerance {
+			i++
+			self.lock.Lock()
+			self.index = i
+			self.lock.Unlock()
+			continue
+		}
+
+		now := time.Now()
+		dt := now.Sub(last).Seconds()
+		last = now
+
+		heading := pose.BearingTo(target)
+
+		// Accelerate up to the top speed, and brake so as to reach the last
+		// waypoint at the lowest one.
+		braking := math.Sqrt(minSpeed*minSpeed + 2*acceleration*remaining(pose.Point(), waypoints[i:]))
+		speed = math.Min(speed+acceleration*dt, math.Min(maxSpeed, braking))
+		speed = math.Max(speed, minSpeed)
+
+		linear := speed
+		if math.Abs(heading) > turnInPlaceError {
+			linear = 0
+			speed = minSpeed
+		}
+
+		self.base.SetVelocity(linear, gain*heading)
+
+		select {
+		case <-stop:
+			return false
+		case <-ticker.C:
+		}
+	}
+
+	return true
+}
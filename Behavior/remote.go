@@ -0,0 +1,29 @@
+package Behavior
+
+import (
+	"time"
+
+	"github.com/jermon/GoEV3/Drive"
+	"github.com/jermon/GoEV3/Sensors"
+	"github.com/jermon/GoEV3/Teleop"
+)
+
+// Drives a drive base with the IR remote on `channel`, read by `sensor`, with
+// the buttons of Teleop.DefaultRemoteLayout: the red ones run the left wheel
+// forwards (up) or backwards (down), the blue ones the right wheel, and the
+// robot stops while the beacon is on. Returns once a value is sent to `stop`,
+// with the motors stopped:
+//
+//	Behavior.DriveWithRemote(base, ir, Sensors.Channel1, stop)
+//
+// Use Teleop.NewRemoteMapping for another layout or speeds.
+func DriveWithRemote(base *Drive.DriveBase, sensor *Sensors.InfraredSensor, channel Sensors.Channel, stop <-chan bool) {
+	// Stops reading the remote once the mapping returns, `stop` being taken by it.
+	done := make(chan bool)
+	defer close(done)
+
+	layout := Teleop.DefaultRemoteLayout
+	layout.Channel = channel
+
+	Teleop.NewRemoteMapping(base, Teleop.NewRemote(sensor, done), layout).Run(stop, 20*time.Millisecond)
+}
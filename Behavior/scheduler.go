@@ -0,0 +1,213 @@
+package Behavior
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/jermon/GoEV3/Motor"
+)
+
+// Runs behaviors whose actions take their time, e.g. backing off and turning
+// away from an obstacle, rather than proposing speeds every tick as with the
+// Arbiter. Every tick the behavior of highest priority that wants control is
+// found. If none is running, its action starts; if one of lower priority is,
+// that one is suppressed first: its stop channel is closed, the scheduler
+// waits for its action to return, and the motors are stopped. An action that
+// returns on its own leaves the choice to the next tick:
+//
+//	s := Behavior.NewScheduler(left, right)
+//	s.Add("wander", 1, func() bool { return true }, func(stop <-chan bool) {
+//		left.Run(40)
+//		right.Run(40)
+//		<-stop
+//	})
+//	s.Add("avoid", 10, func() bool { return ir.ReadProximity() < 30 }, func(stop <-chan bool) {
+//		left.Run(-30)
+//		right.Run(30)
+//		select {
+//		case <-stop:
+//		case <-time.After(time.Second):
+//		}
+//	})
+//	s.Run(stop, 20*time.Millisecond)
+type Scheduler struct {
+	lock      sync.Mutex
+	behaviors []*task
+	motors    []*Motor.Motor
+	running   *task
+	stop      chan bool
+	done      chan bool
+	onSwitch  func(from string, to string)
+}
+
+type task struct {
+	name     string
+	priority int
+	wants    func() bool
+	action   func(stop <-chan bool)
+}
+
+// Creates a scheduler without behaviors, stopping `motors` whenever a
+// behavior is suppressed and when it stops running.
+func NewScheduler(motors ...*Motor.Motor) *Scheduler {
+	s := new(Scheduler)
+	s.motors = motors
+
+	return s
+}
+
+// Adds a behavior. `wants` is called every tick and reports whether the
+// behavior wants control, e.g. from the sensors; it must return promptly.
+// `action` runs in a goroutine of its own once the behavior takes control,
+// and must return promptly once `stop` is closed. Behaviors with higher
+// priority take precedence.
+func (self *Scheduler) Add(name string, priority int, wants func() bool, action func(stop <-chan bool)) {
+	self.lock.Lock()
+	self.behaviors = append(self.behaviors, &task{name, priority, wants, action})
+	sort.SliceStable(self.behaviors, func(i, j int) bool {
+		return self.behaviors[i].priority > self.behaviors[j].priority
+	})
+	self.lock.Unlock()
+}
+
+// Registers a callback invoked whenever a different behavior takes control,
+// or none when `to` is empty.
+func (self *Scheduler) OnSwitch(fn func(from string, to string)) {
+	self.lock.Lock()
+	self.onSwitch = fn
+	self.lock.Unlock()
+}
+
+// Returns the name of the behavior whose action is running, or an empty string.
+func (self *Scheduler) Active() string {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	self.reap()
+	if self.running == nil {
+		return ""
+	}
+
+	return self.running.name
+}
+
+// Forgets the running behavior if its action returned. Must be called with
+// the lock held.
+func (self *Scheduler) reap() {
+	if self.running == nil {
+		return
+	}
+
+	select {
+	case <-self.done:
+		self.running = nil
+	default:
+	}
+}
+
+// Picks the behavior to run once, suppressing the running one for it if
+// needed. Returns the name of the behavior whose action is running, or an
+// empty string.
+func (self *Scheduler) Tick() string {
+	self.lock.Lock()
+	behaviors := append([]*task(nil), self.behaviors...)
+	self.lock.Unlock()
+
+	var winner *task
+	for _, b := range behaviors {
+		if b.wants() {
+			winner = b
+			break
+		}
+	}
+
+	self.lock.Lock()
+	self.reap()
+	previous := self.running
+	if winner == nil || (previous != nil && winner.priority <= previous.priority) {
+		self.lock.Unlock()
+		return self.Active()
+	}
+	self.lock.Unlock()
+
+	from := ""
+	if previous != nil {
+		from = previous.name
+		self.suppress()
+	}
+	self.start(from, winner)
+
+	return winner.name
+}
+
+// Starts the action of `b`, taking over from the behavior named `from`.
+func (self *Scheduler) start(from string, b *task) {
+	stop := make(chan bool)
+	done := make(chan bool)
+
+	self.lock.Lock()
+	self.running, self.stop, self.done = b, stop, done
+	notify := self.onSwitch
+	self.lock.Unlock()
+
+	go func() {
+		defer close(done)
+		b.action(stop)
+	}()
+
+	if notify != nil {
+		notify(from, b.name)
+	}
+}
+
+// Stops the running action, waits for it to return and stops the motors.
+func (self *Scheduler) suppress() {
+	self.lock.Lock()
+	running, stop, done := self.running, self.stop, self.done
+	self.running = nil
+	self.lock.Unlock()
+
+	if running == nil {
+		return
+	}
+
+	close(stop)
+	<-done
+
+	for _, m := range self.motors {
+		m.Stop()
+	}
+}
+
+// Ticks the scheduler at the given interval until a value is sent to `stop`,
+// then suppresses the running behavior and stops the motors.
+func (self *Scheduler) Run(stop <-chan bool, tick time.Duration) {
+	ticker := time.NewTicker(tick)
+	defer ticker.Stop()
+
+	for {
+		self.Tick()
+
+		select {
+		case <-stop:
+			self.lock.Lock()
+			from := ""
+			if self.running != nil {
+				from = self.running.name
+			}
+			notify := self.onSwitch
+			self.lock.Unlock()
+
+			self.suppress()
+			for _, m := range self.motors {
+				m.Stop()
+			}
+			if from != "" && notify != nil {
+				notify(from, "")
+			}
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,218 @@
+package Behavior
+
+import (
+	"sync"
+	"time"
+
+	"github.com/jermon/GoEV3/Drive"
+	"github.com/jermon/GoEV3/Sensors"
+)
+
+// Tunable parameters of a SumoBot.
+type SumoParams struct {
+	// Reflected light intensity above which a sensor is over the white border.
+	EdgeThreshold uint8
+	// Centimeters within which the opponent is charged, and pushed at full power.
+	DetectRange float64
+	PushRange   float64
+	// Speeds, as passed to Motor.Run.
+	SearchSpeed int16
+	ChargeSpeed int16
+	PushSpeed   int16
+	EscapeSpeed int16
+	// Time spent backing away from the border, then turning away from it.
+	BackupTime time.Duration
+	TurnTime   time.Duration
+	// Time the searching spin lasts before changing direction, so that a
+	// narrow sensor beam sweeps back over where the opponent was.
+	SweepTime time.Duration
+	// Wait before moving, as sumo rules require after the start signal.
+	StartDelay time.Duration
+}
+
+// Parameters suiting a mini-sumo robot on a 77 cm ring.
+var DefaultSumoParams = SumoParams{
+	EdgeThreshold: 50,
+	DetectRange:   60,
+	PushRange:     10,
+	SearchSpeed:   35,
+	ChargeSpeed:   80,
+	PushSpeed:     100,
+	EscapeSpeed:   60,
+	BackupTime:    400 * time.Millisecond,
+	TurnTime:      350 * time.Millisecond,
+	SweepTime:     1500 * time.Millisecond,
+	StartDelay:    5 * time.Second,
+}
+
+// A ready-made sumo robot, built from behaviors on an Arbiter: escaping the
+// white border takes precedence over pushing the opponent, which takes
+// precedence over charging it, which takes precedence over searching for it.
+//
+//	sumo := Behavior.NewSumoBot(base, func() float64 { return us.Distance().Centimeters() })
+//	sumo.SetEdgeSensors(leftColor, rightColor)
+//	sumo.Run(stop)
+type SumoBot struct {
+	lock sync.Mutex
+
+	base     *Drive.DriveBase
+	opponent func() float64
+	left     *Sensors.ColorSensor
+	right    *Sensors.ColorSensor
+	params   SumoParams
+
+	// Escape maneuver in progress, started when the border was seen.
+	escapeStart time.Time
+	escapeTurn  int16
+	sweepStart  time.Time
+	sweepDir    int16
+	distance    float64
+
+	arbiter *Arbiter
+}
+
+// Creates a sumo robot driving `base` and measuring the distance to the
+// opponent in centimeters with `opponent`, e.g. from an ultrasonic or
+// infrared sensor facing forward.
+func NewSumoBot(base *Drive.DriveBase, opponent func() float64) *SumoBot {
+	s := new(SumoBot)
+	s.base = base
+	s.opponent = opponent
+	s.params = DefaultSumoParams
+	s.sweepDir = 1
+
+	s.arbiter = NewArbiter()
+	s.arbiter.Add("escape", 100, s.escape)
+	s.arbiter.Add("push", 30, s.push)
+	s.arbiter.Add("charge", 20, s.charge)
+	s.arbiter.Add("search", 1, s.search)
+
+	return s
+}
+
+// Sets the color sensors facing down at the front left and front right that
+// detect the border. The right one may be nil for robots with a single one.
+func (self *SumoBot) SetEdgeSensors(left *Sensors.ColorSensor, right *Sensors.ColorSensor) {
+	self.lock.Lock()
+	self.left, self.right = left, right
+	self.lock.Unlock()
+}
+
+// Sets the tunable parameters.
+func (self *SumoBot) SetParams(params SumoParams) {
+	self.lock.Lock()
+	self.params = params
+	self.lock.Unlock()
+}
+
+// Returns the tunable parameters.
+func (self *SumoBot) Params() SumoParams {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	return self.params
+}
+
+// Returns the arbiter running the behaviors, to add behaviors of one's own or
+// watch which one is in control.
+func (self *SumoBot) Arbiter() *Arbiter {
+	return self.arbiter
+}
+
+func (self *SumoBot) tank(left int16, right int16) Command {
+	return Command{self.base.Left(): left, self.base.Right(): right}
+}
+
+func (self *SumoBot) overEdge(sensor *Sensors.ColorSensor, threshold uint8) bool {
+	return sensor != nil && sensor.ReadReflectedLightIntensity() > threshold
+}
+
+// Backs away from the border, then turns away from the side it was seen on.
+func (self *SumoBot) escape() (Command, bool) {
+	self.lock.Lock()
+	left, right, p := self.left, self.right, self.params
+	self.lock.Unlock()
+
+	onLeft := self.overEdge(left, p.EdgeThreshold)
+	onRight := self.overEdge(right, p.EdgeThreshold)
+
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	now := time.Now()
+	if onLeft || onRight {
+		self.escapeStart = now
+		// Turning clockwise, away from a border on the left.
+		self.escapeTurn = 1
+		if onRight && !onLeft {
+			self.escapeTurn = -1
+		}
+	}
+
+	elapsed := now.Sub(self.escapeStart)
+	switch {
+	case self.escapeStart.IsZero() || elapsed >= p.BackupTime+p.TurnTime:
+		return nil, false
+	case elapsed < p.BackupTime:
+		return self.tank(-p.EscapeSpeed, -p.EscapeSpeed), true
+	default:
+		s := p.EscapeSpeed * self.escapeTurn
+		return self.tank(s, -s), true
+	}
+}
+
+func (self *SumoBot) measure() float64 {
+	d := self.opponent()
+
+	self.lock.Lock()
+	self.distance = d
+	self.lock.Unlock()
+
+	return d
+}
+
+// Pushes at full power once in contact.
+func (self *SumoBot) push() (Command, bool) {
+	p := self.Params()
+	d := self.measure()
+
+	return self.tank(p.PushSpeed, p.PushSpeed), d < p.PushRange
+}
+
+// Charges the opponent in sight.
+func (self *SumoBot) charge() (Command, bool) {
+	self.lock.Lock()
+	p, d := self.params, self.distance
+	self.lock.Unlock()
+
+	return self.tank(p.ChargeSpeed, p.ChargeSpeed), d < p.DetectRange
+}
+
+// Spins in place, changing direction every sweep.
+func (self *SumoBot) search() (Command, bool) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	now := time.Now()
+	if self.sweepStart.IsZero() || now.Sub(self.sweepStart) >= self.params.SweepTime {
+		if !self.sweepStart.IsZero() {
+			self.sweepDir = -self.sweepDir
+		}
+		self.sweepStart = now
+	}
+
+	s := self.params.SearchSpeed * self.sweepDir
+	return self.tank(s, -s), true
+}
+
+// Waits the start delay, then fights until a value is sent to `stop`. The
+// motors are stopped before returning.
+func (self *SumoBot) Run(stop <-chan bool) {
+	select {
+	case <-stop:
+		return
+	case <-time.After(self.Params().StartDelay):
+	}
+
+	self.arbiter.Run(stop, 10*time.Millisecond)
+}
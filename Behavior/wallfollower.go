@@ -0,0 +1,265 @@
+package Behavior
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/jermon/GoEV3/Control"
+	"github.com/jermon/GoEV3/Drive"
+	"github.com/jermon/GoEV3/Sensors"
+)
+
+// Side of the robot the wall is kept on.
+type Side int
+
+const (
+	WallOnLeft  Side = -1
+	WallOnRight      = 1
+)
+
+// Kinds of corners detected while following a wall.
+type Corner int
+
+const (
+	// The wall turns towards the robot, detected by the optional front sensor.
+	InsideCorner Corner = iota
+	// The wall ends or turns away from the robot.
+	OutsideCorner
+)
+
+func (self Corner) String() string {
+	if self == InsideCorner {
+		return "inside"
+	}
+
+	return "outside"
+}
+
+type wallState int
+
+const (
+	followingWall wallState = iota
+	turningInside
+	turningOutside
+)
+
+// Keeps a drive base at a set distance from a wall using an ultrasonic sensor
+// facing sideways and a PD controller.
+type WallFollower struct {
+	lock sync.Mutex
+
+	base   *Drive.DriveBase
+	sensor *Sensors.UltrasonicSensor
+	front  *Sensors.UltrasonicSensor
+	pid    *Control.PID
+
+	side           Side
+	distance       float64
+	speed          int16
+	lostDistance   float64
+	frontThreshold float64
+	interval       time.Duration
+
+	state    wallState
+	corners  int
+	onCorner func(Corner)
+
+	// Wheel distances at the start of an inside corner turn.
+	turnStartLeft  float64
+	turnStartRight float64
+}
+
+// Creates a wall follower keeping `distance` centimeters between `sensor` and
+// a wall on the given side.
+func NewWallFollower(base *Drive.DriveBase, sensor *Sensors.UltrasonicSensor, side Side, distance float64) *WallFollower {
+	f := new(WallFollower)
+	f.base = base
+	f.sensor = sensor
+	f.side = side
+	f.distance = distance
+	f.speed = 30
+	f.lostDistance = distance*2 + 20
+	f.frontThreshold = distance + 5
+	f.interval = 20 * time.Millisecond
+
+	f.pid = Control.NewPID(3, 0, 0.2)
+	f.pid.SetSetpoint(distance)
+	f.pid.SetOutputLimits(-60, 60)
+	f.pid.SetDerivativeFilter(60 * time.Millisecond)
+
+	return f
+}
+
+// Adds a forward-facing ultrasonic sensor used to detect inside corners.
+func (self *WallFollower) SetFrontSensor(sensor *Sensors.UltrasonicSensor) {
+	self.lock.Lock()
+	self.front = sensor
+	self.lock.Unlock()
+}
+
+// Sets the forward speed, as passed to Motor.Run.
+func (self *WallFollower) SetSpeed(speed int16) {
+	self.lock.Lock()
+	self.speed = speed
+	self.lock.Unlock()
+}
+
+// Sets the proportional and derivative gains, in steering units per centimeter of error.
+func (self *WallFollower) SetGains(kp float64, kd float64) {
+	self.pid.SetGains(kp, 0, kd)
+}
+
+// Sets the distance beyond which the wall is considered lost (an outside corner)
+// and the front distance below which an inside corner is detected, both in centimeters.
+func (self *WallFollower) SetCornerThresholds(lostDistance float64, frontThreshold float64) {
+	self.lock.Lock()
+	self.lostDistance = lostDistance
+	self.frontThreshold = frontThreshold
+	self.lock.Unlock()
+}
+
+// Registers a callback invoked whenever a corner is detected.
+func (self *WallFollower) OnCorner(fn func(Corner)) {
+	self.lock.Lock()
+	self.onCorner = fn
+	self.lock.Unlock()
+}
+
+// Returns the number of corners detected since Follow was called.
+func (self *WallFollower) Corners() int {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	return self.corners
+}
+
+func (self *WallFollower) corner(kind Corner) {
+	self.lock.Lock()
+	self.corners++
+	notify := self.onCorner
+	self.lock.Unlock()
+
+	if notify != nil {
+		notify(kind)
+	}
+}
+
+// Reads the sensors once and returns the wheel speeds for the next step.
+func (self *WallFollower) Step() (int16, int16) {
+	self.lock.Lock()
+	front := self.front
+	side := float64(self.side)
+	speed := self.speed
+	lost := self.lostDistance
+	threshold := self.frontThreshold
+	state := self.state
+	self.lock.Unlock()
+
+	distance := float64(self.sensor.ReadDistance())
+
+	frontDistance := lost
+	if front != nil {
+		frontDistance = float64(front.ReadDistance())
+	}
+
+	next := state
+
+	switch state {
+	case followingWall:
+		if front != nil && frontDistance < threshold {
+			next = turningInside
+			l, r := self.base.WheelDistances()
+
+			self.lock.Lock()
+			self.turnStartLeft, self.turnStartRight = l, r
+			self.lock.Unlock()
+
+			self.corner(InsideCorner)
+		} else if distance > lost {
+			next = turningOutside
+			self.corner(OutsideCorner)
+		}
+	case turningInside:
+		// Turn by a right angle, measured with the wheel encoders.
+		if math.Abs(self.turnedAngle()) >= 90 {
+			next = followingWall
+		}
+	case turningOutside:
+		if distance <= lost {
+			next = followingWall
+		}
+	}
+
+	if next != state {
+		self.pid.Reset()
+
+		self.lock.Lock()
+		self.state = next
+		self.lock.Unlock()
+	}
+
+	switch next {
+	case turningInside:
+		// Spin away from the wall.
+		return Drive.SteeringSpeeds(-100*side, speed)
+	case turningOutside:
+		// Arc around the end of the wall.
+		return Drive.SteeringSpeeds(35*side, speed)
+	}
+
+	// Too far from the wall gives a negative output, which must steer towards it.
+	steering := -self.pid.Update(distance) * side
+
+	return Drive.SteeringSpeeds(steering, speed)
+}
+
+// Returns the rotation in degrees since the start of the inside corner turn.
+func (self *WallFollower) turnedAngle() float64 {
+	l, r := self.base.WheelDistances()
+
+	self.lock.Lock()
+	dl, dr := l-self.turnStartLeft, r-self.turnStartRight
+	self.lock.Unlock()
+
+	return (dr - dl) / self.base.TrackWidth() * 180 / math.Pi
+}
+
+// Proposes the next wall following step to an Arbiter.
+func (self *WallFollower) Propose() (Command, bool) {
+	l, r := self.Step()
+	return Command{self.base.Left(): l, self.base.Right(): r}, true
+}
+
+// Follows the wall until `corners` corners have been passed (0 meaning forever)
+// or a value is sent to `stop`. The motors are stopped before returning.
+func (self *WallFollower) Follow(stop <-chan bool, corners int) {
+	self.pid.Reset()
+
+	self.lock.Lock()
+	self.corners = 0
+	self.state = followingWall
+	interval := self.interval
+	self.lock.Unlock()
+
+	defer self.base.Stop()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		l, r := self.Step()
+
+		if corners > 0 && self.Corners() >= corners {
+			return
+		}
+
+		self.base.Tank(l, r)
+
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,68 @@
+// Provides Bluetooth RFCOMM (serial port profile) connections through the Linux BlueZ socket API,
+// and line and JSON message framing for talking to phone serial controller apps,
+// as well as a minimal GATT client for Bluetooth Low Energy devices.
+package Bluetooth
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+var ErrUnsupported = errors.New("bluetooth: Bluetooth sockets are not supported on this platform")
+
+// A Bluetooth device address such as "00:16:53:4F:2B:1A".
+type Address [6]byte
+
+// Parses an address in the usual colon-separated hexadecimal notation.
+func ParseAddress(s string) (Address, error) {
+	var a Address
+
+	parts := strings.Split(s, ":")
+	if len(parts) != 6 {
+		return a, fmt.Errorf("bluetooth: invalid address %q", s)
+	}
+
+	for i, part := range parts {
+		b, err := strconv.ParseUint(part, 16, 8)
+		if err != nil {
+			return a, fmt.Errorf("bluetooth: invalid address %q", s)
+		}
+		a[i] = byte(b)
+	}
+
+	return a, nil
+}
+
+func (self Address) String() string {
+	return fmt.Sprintf("%02X:%02X:%02X:%02X:%02X:%02X", self[0], self[1], self[2], self[3], self[4], self[5])
+}
+
+// An RFCOMM connection.
+type Conn struct {
+	*os.File
+	remote Address
+}
+
+// Returns the address of the connected peer.
+func (self *Conn) RemoteAddress() Address {
+	return self.remote
+}
+
+// Accepts incoming RFCOMM connections on a channel.
+type Listener struct {
+	fd      int
+	channel uint8
+}
+
+// Returns the RFCOMM channel the listener is bound to.
+func (self *Listener) Channel() uint8 {
+	return self.channel
+}
+
+// Accepts incoming Bluetooth Low Energy connections to the attribute protocol.
+type LEListener struct {
+	fd int
+}
@@ -0,0 +1,322 @@
+package Bluetooth
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Attribute protocol opcodes.
+const (
+	attErrorResponse      = 0x01
+	attExchangeMTURequest = 0x02
+	attExchangeMTUReply   = 0x03
+	attFindInfoRequest    = 0x04
+	attFindInfoReply      = 0x05
+	attReadByTypeRequest  = 0x08
+	attReadByTypeReply    = 0x09
+	attReadRequest        = 0x0A
+	attReadReply          = 0x0B
+	attReadBlobRequest    = 0x0C
+	attReadBlobReply      = 0x0D
+	attReadByGroupRequest = 0x10
+	attReadByGroupReply   = 0x11
+	attWriteRequest       = 0x12
+	attWriteReply         = 0x13
+	attNotification       = 0x1B
+	attIndication         = 0x1D
+	attConfirmation       = 0x1E
+	attWriteCommand       = 0x52
+)
+
+// Attribute protocol error codes.
+const (
+	attErrInvalidHandle     = 0x01
+	attErrReadNotPermitted  = 0x02
+	attErrWriteNotPermitted = 0x03
+	attErrRequestNotSupp    = 0x06
+	attErrAttributeNotFound = 0x0A
+)
+
+// Attribute types of GATT declarations.
+const (
+	gattPrimaryService = 0x2800
+	gattCharacteristic = 0x2803
+	gattClientConfig   = 0x2902
+)
+
+// How long a GATT client waits for the response to a request.
+const gattTimeout = 5 * time.Second
+
+// A 128-bit Bluetooth UUID.
+type UUID [16]byte
+
+// Parses a UUID such as "00001623-1212-efde-1623-785feabcd123", or a 16-bit
+// one such as "2902" which is expanded with the Bluetooth base UUID.
+func ParseUUID(s string) (UUID, error) {
+	var u UUID
+
+	if len(s) == 4 {
+		s = "0000" + s + "-0000-1000-8000-00805f9b34fb"
+	}
+
+	b, err := hex.DecodeString(strings.ReplaceAll(s, "-", ""))
+	if err != nil || len(b) != 16 {
+		return u, fmt.Errorf("bluetooth: invalid UUID %q", s)
+	}
+	copy(u[:], b)
+
+	return u, nil
+}
+
+// Like ParseUUID, but panics on invalid UUIDs. For package-level constants.
+func MustParseUUID(s string) UUID {
+	u, err := ParseUUID(s)
+	if err != nil {
+		panic(err)
+	}
+
+	return u
+}
+
+// Returns the UUID of a 16-bit assigned number.
+func UUID16(n uint16) UUID {
+	u := MustParseUUID("00000000-0000-1000-8000-00805f9b34fb")
+	binary.BigEndian.PutUint16(u[2:4], n)
+
+	return u
+}
+
+func (self UUID) String() string {
+	h := hex.EncodeToString(self[:])
+	return h[:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:]
+}
+
+// Returns the UUID in the little-endian byte order used on the wire.
+func (self UUID) wire() []byte {
+	b := make([]byte, 16)
+	for i := range self {
+		b[i] = self[15-i]
+	}
+
+	return b
+}
+
+func uuidFromWire(b []byte) UUID {
+	if len(b) == 2 {
+		return UUID16(binary.LittleEndian.Uint16(b))
+	}
+
+	var u UUID
+	for i := range u {
+		u[i] = b[15-i]
+	}
+
+	return u
+}
+
+// Error response of a GATT peer.
+type ATTError struct {
+	Opcode byte
+	Handle uint16
+	Code   byte
+}
+
+func (self *ATTError) Error() string {
+	return fmt.Sprintf("bluetooth: ATT error 0x%02x for request 0x%02x on handle 0x%04x", self.Code, self.Opcode, self.Handle)
+}
+
+var errGATTTimeout = errors.New("bluetooth: GATT request timed out")
+
+// A GATT client talking to a Bluetooth Low Energy device over a connection
+// opened with DialLE.
+type GATTClient struct {
+	conn io.ReadWriteCloser
+
+	// Serializes requests; ATT allows a single outstanding request.
+	requestLock sync.Mutex
+	responses   chan []byte
+
+	lock     sync.Mutex
+	handlers map[uint16]func([]byte)
+	err      error
+	done     chan bool
+}
+
+// Starts a client on an established connection.
+func NewGATTClient(conn io.ReadWriteCloser) *GATTClient {
+	c := new(GATTClient)
+	c.conn = conn
+	c.responses = make(chan []byte, 1)
+	c.handlers = make(map[uint16]func([]byte))
+	c.done = make(chan bool)
+
+	go c.receive()
+	return c
+}
+
+// Connects to the device with the given address and starts a client.
+func DialGATT(address string, random bool) (*GATTClient, error) {
+	conn, err := DialLE(address, random)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewGATTClient(conn), nil
+}
+
+func (self *GATTClient) receive() {
+	defer close(self.done)
+
+	buf := make([]byte, 512)
+	for {
+		n, err := self.conn.Read(buf)
+		if err != nil {
+			self.lock.Lock()
+			self.err = err
+			self.lock.Unlock()
+			return
+		}
+		if n == 0 {
+			continue
+		}
+
+		pdu := append([]byte(nil), buf[:n]...)
+
+		switch pdu[0] {
+		case attNotification, attIndication:
+			if pdu[0] == attIndication {
+				self.conn.Write([]byte{attConfirmation})
+			}
+			if len(pdu) < 3 {
+				continue
+			}
+
+			self.lock.Lock()
+			fn := self.handlers[binary.LittleEndian.Uint16(pdu[1:3])]
+			self.lock.Unlock()
+
+			if fn != nil {
+				fn(pdu[3:])
+			}
+		default:
+			select {
+			case self.responses <- pdu:
+			default:
+			}
+		}
+	}
+}
+
+// Sends a request and waits for its response, which must have the opcode `reply`.
+func (self *GATTClient) request(pdu []byte, reply byte) ([]byte, error) {
+	self.requestLock.Lock()
+	defer self.requestLock.Unlock()
+
+	if _, err := self.conn.Write(pdu); err != nil {
+		return nil, err
+	}
+
+	select {
+	case response := <-self.responses:
+		if response[0] == attErrorResponse && len(response) >= 5 {
+			return nil, &ATTError{response[1], binary.LittleEndian.Uint16(response[2:4]), response[4]}
+		}
+		if response[0] != reply {
+			return nil, fmt.Errorf("bluetooth: unexpected ATT response 0x%02x", response[0])
+		}
+		return response[1:], nil
+	case <-self.done:
+		self.lock.Lock()
+		defer self.lock.Unlock()
+		return nil, self.err
+	case <-time.After(gattTimeout):
+		return nil, errGATTTimeout
+	}
+}
+
+// Returns the handle of the value of the characteristic with the given UUID.
+func (self *GATTClient) FindCharacteristic(uuid UUID) (uint16, error) {
+	start := uint16(0x0001)
+
+	for {
+		pdu := []byte{attReadByTypeRequest, 0, 0, 0xFF, 0xFF, 0, 0}
+		binary.LittleEndian.PutUint16(pdu[1:3], start)
+		binary.LittleEndian.PutUint16(pdu[5:7], gattCharacteristic)
+
+		data, err := self.request(pdu, attReadByTypeReply)
+		var attErr *ATTError
+		if errors.As(err, &attErr) && attErr.Code == attErrAttributeNotFound {
+			return 0, fmt.Errorf("bluetooth: characteristic %v not found", uuid)
+		}
+		if err != nil {
+			return 0, err
+		}
+
+		// Each entry holds the declaration handle, properties, value handle and UUID.
+		length := int(data[0])
+		if length < 7 {
+			return 0, errors.New("bluetooth: malformed characteristic declaration")
+		}
+
+		last := start
+		for entry := data[1:]; len(entry) >= length; entry = entry[length:] {
+			last = binary.LittleEndian.Uint16(entry[0:2])
+			if uuidFromWire(entry[5:length]) == uuid {
+				return binary.LittleEndian.Uint16(entry[3:5]), nil
+			}
+		}
+
+		if last == 0xFFFF {
+			return 0, fmt.Errorf("bluetooth: characteristic %v not found", uuid)
+		}
+		start = last + 1
+	}
+}
+
+// Reads the value of the attribute with the given handle.
+func (self *GATTClient) Read(handle uint16) ([]byte, error) {
+	pdu := []byte{attReadRequest, 0, 0}
+	binary.LittleEndian.PutUint16(pdu[1:3], handle)
+
+	return self.request(pdu, attReadReply)
+}
+
+// Writes the value of the attribute with the given handle and waits for the acknowledgement.
+func (self *GATTClient) Write(handle uint16, value []byte) error {
+	pdu := append([]byte{attWriteRequest, 0, 0}, value...)
+	binary.LittleEndian.PutUint16(pdu[1:3], handle)
+
+	_, err := self.request(pdu, attWriteReply)
+	return err
+}
+
+// Writes the value of the attribute with the given handle without waiting for an acknowledgement.
+func (self *GATTClient) WriteCommand(handle uint16, value []byte) error {
+	pdu := append([]byte{attWriteCommand, 0, 0}, value...)
+	binary.LittleEndian.PutUint16(pdu[1:3], handle)
+
+	_, err := self.conn.Write(pdu)
+	return err
+}
+
+// Enables notifications of the characteristic whose value has the given
+// handle, and calls `fn` with every notified value. The client configuration
+// descriptor is expected to directly follow the value, as is usual.
+func (self *GATTClient) Subscribe(handle uint16, fn func(value []byte)) error {
+	self.lock.Lock()
+	self.handlers[handle] = fn
+	self.lock.Unlock()
+
+	return self.Write(handle+1, []byte{0x01, 0x00})
+}
+
+// Closes the connection.
+func (self *GATTClient) Close() error {
+	return self.conn.Close()
+}
@@ -0,0 +1,473 @@
+package Bluetooth
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"sync"
+)
+
+// Characteristic properties.
+const (
+	propRead            = 0x02
+	propWriteNoResponse = 0x04
+	propWrite           = 0x08
+	propNotify          = 0x10
+)
+
+// Largest ATT MTU the server agrees to.
+const serverMTU = 247
+
+// Default ATT MTU of LE links.
+const defaultMTU = 23
+
+// A characteristic served by a GATTServer.
+type Characteristic struct {
+	UUID UUID
+	// Returns the current value. The characteristic isn't readable if nil.
+	Read func() []byte
+	// Receives written values. The characteristic isn't writable if nil.
+	Write func(value []byte)
+	// Whether clients can subscribe to notifications sent with GATTServer.Notify.
+	Notify bool
+
+	valueHandle uint16
+}
+
+func (self *Characteristic) properties() byte {
+	var p byte
+	if self.Read != nil {
+		p |= propRead
+	}
+	if self.Write != nil {
+		p |= propWrite | propWriteNoResponse
+	}
+	if self.Notify {
+		p |= propNotify
+	}
+
+	return p
+}
+
+// A primary service served by a GATTServer.
+type Service struct {
+	UUID            UUID
+	Characteristics []*Characteristic
+}
+
+type attribute struct {
+	handle uint16
+	typ    UUID
+	// Static value of declarations.
+	value []byte
+	// Set on characteristic values and client configuration descriptors.
+	char *Characteristic
+	cccd bool
+	// Last handle of the group, on service declarations.
+	groupEnd uint16
+}
+
+// Serves GATT services to Bluetooth Low Energy clients such as phone apps.
+type GATTServer struct {
+	attrs []*attribute
+
+	lock  sync.Mutex
+	conns map[*gattConn]bool
+}
+
+type gattConn struct {
+	conn      io.ReadWriteCloser
+	writeLock sync.Mutex
+	mtu       int
+	// Characteristics the client subscribed to, by value handle.
+	subscribed map[uint16]bool
+}
+
+// Creates a server for the given services, preceded by the generic access
+// service holding the device name.
+func NewGATTServer(name string, services ...*Service) *GATTServer {
+	s := new(GATTServer)
+	s.conns = make(map[*gattConn]bool)
+
+	nameValue := []byte(name)
+	gap := &Service{UUID: UUID16(0x1800), Characteristics: []*Characteristic{
+		{UUID: UUID16(0x2A00), Read: func() []byte { return nameValue }},
+	}}
+
+	for _, service := range append([]*Service{gap}, services...) {
+		s.add(service)
+	}
+
+	return s
+}
+
+func (self *GATTServer) add(service *Service) {
+	next := func() uint16 {
+		return uint16(len(self.attrs) + 1)
+	}
+
+	decl := &attribute{handle: next(), typ: UUID16(gattPrimaryService), value: shortest(service.UUID)}
+	self.attrs = append(self.attrs, decl)
+
+	for _, c := range service.Characteristics {
+		declHandle := next()
+		c.valueHandle = declHandle + 1
+
+		value := []byte{c.properties(), 0, 0}
+		binary.LittleEndian.PutUint16(value[1:3], c.valueHandle)
+		value = append(value, shortest(c.UUID)...)
+
+		self.attrs = append(self.attrs,
+			&attribute{handle: declHandle, typ: UUID16(gattCharacteristic), value: value},
+			&attribute{handle: c.valueHandle, typ: c.UUID, char: c})
+
+		if c.Notify {
+			self.attrs = append(self.attrs, &attribute{handle: next(), typ: UUID16(gattClientConfig), char: c, cccd: true})
+		}
+	}
+
+	decl.groupEnd = uint16(len(self.attrs))
+}
+
+// Returns the 16-bit wire form of UUIDs derived from the Bluetooth base UUID,
+// and the 128-bit form of others.
+func shortest(u UUID) []byte {
+	base := UUID16(0)
+	if bytes.Equal(u[:2], base[:2]) && bytes.Equal(u[4:], base[4:]) {
+		return []byte{u[3], u[2]}
+	}
+
+	return u.wire()
+}
+
+// Accepts connections from `listener` and serves them until it fails.
+func (self *GATTServer) Serve(listener *LEListener) error {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return err
+		}
+
+		go self.ServeConn(conn)
+	}
+}
+
+// Listens on the local adapter and serves all connections. See ListenLE.
+func (self *GATTServer) ListenAndServe() error {
+	listener, err := ListenLE()
+	if err != nil {
+		return err
+	}
+	defer listener.Close()
+
+	return self.Serve(listener)
+}
+
+// Serves a single connection until it is closed.
+func (self *GATTServer) ServeConn(conn io.ReadWriteCloser) error {
+	c := &gattConn{conn: conn, mtu: defaultMTU, subscribed: make(map[uint16]bool)}
+
+	self.lock.Lock()
+	self.conns[c] = true
+	self.lock.Unlock()
+
+	defer func() {
+		self.lock.Lock()
+		delete(self.conns, c)
+		self.lock.Unlock()
+		conn.Close()
+	}()
+
+	buf := make([]byte, 512)
+	for {
+		n, err := conn.Read(buf)
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			continue
+		}
+
+		if response := self.handle(c, buf[:n]); response != nil {
+			c.writeLock.Lock()
+			_, err = conn.Write(response)
+			c.writeLock.Unlock()
+
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// Sends a notification with the characteristic's new value to every client
+// that subscribed to it. Values longer than a client's MTU allows are truncated.
+func (self *GATTServer) Notify(c *Characteristic, value []byte) {
+	self.lock.Lock()
+	var conns []*gattConn
+	for conn := range self.conns {
+		if conn.subscribed[c.valueHandle] {
+			conns = append(conns, conn)
+		}
+	}
+	self.lock.Unlock()
+
+	for _, conn := range conns {
+		pdu := []byte{attNotification, 0, 0}
+		binary.LittleEndian.PutUint16(pdu[1:3], c.valueHandle)
+		pdu = append(pdu, truncate(value, conn.mtu-3)...)
+
+		conn.writeLock.Lock()
+		conn.conn.Write(pdu)
+		conn.writeLock.Unlock()
+	}
+}
+
+func truncate(value []byte, n int) []byte {
+	if len(value) > n {
+		return value[:n]
+	}
+
+	return value
+}
+
+func attError(opcode byte, handle uint16, code byte) []byte {
+	pdu := []byte{attErrorResponse, opcode, 0, 0, code}
+	binary.LittleEndian.PutUint16(pdu[2:4], handle)
+
+	return pdu
+}
+
+// Returns the attributes with handles in [start, end].
+func (self *GATTServer) inRange(start uint16, end uint16) []*attribute {
+	var result []*attribute
+	for _, a := range self.attrs {
+		if a.handle >= start && a.handle <= end {
+			result = append(result, a)
+		}
+	}
+
+	return result
+}
+
+func (self *GATTServer) attribute(handle uint16) *attribute {
+	if handle == 0 || int(handle) > len(self.attrs) {
+		return nil
+	}
+
+	return self.attrs[handle-1]
+}
+
+func (self *GATTServer) read(c *gattConn, a *attribute) ([]byte, byte) {
+	switch {
+	case a.cccd:
+		self.lock.Lock()
+		defer self.lock.Unlock()
+		if c.subscribed[a.char.valueHandle] {
+			return []byte{0x01, 0x00}, 0
+		}
+		return []byte{0x00, 0x00}, 0
+	case a.char != nil:
+		if a.char.Read == nil {
+			return nil, attErrReadNotPermitted
+		}
+		return a.char.Read(), 0
+	}
+
+	return a.value, 0
+}
+
+// Handles a PDU and returns the response, or nil if none is due.
+func (self *GATTServer) handle(c *gattConn, pdu []byte) []byte {
+	opcode := pdu[0]
+	params := pdu[1:]
+
+	handleAt := func(i int) uint16 {
+		return binary.LittleEndian.Uint16(params[i : i+2])
+	}
+
+	switch {
+	case opcode == attExchangeMTURequest && len(params) >= 2:
+		mtu := int(handleAt(0))
+		if mtu > serverMTU {
+			mtu = serverMTU
+		}
+		if mtu > defaultMTU {
+			c.mtu = mtu
+		}
+		response := []byte{attExchangeMTUReply, 0, 0}
+		binary.LittleEndian.PutUint16(response[1:3], serverMTU)
+		return response
+
+	case opcode == attFindInfoRequest && len(params) >= 4:
+		start := handleAt(0)
+		attrs := self.inRange(start, handleAt(2))
+		if len(attrs) == 0 {
+			return attError(opcode, start, attErrAttributeNotFound)
+		}
+
+		format := byte(2)
+		if len(shortest(attrs[0].typ)) == 2 {
+			format = 1
+		}
+
+		response := []byte{attFindInfoReply, format}
+		for _, a := range attrs {
+			typ := shortest(a.typ)
+			if (len(typ) == 2) != (format == 1) || len(response)+2+len(typ) > c.mtu {
+				break
+			}
+			response = binary.LittleEndian.AppendUint16(response, a.handle)
+			response = append(response, typ...)
+		}
+		return response
+
+	case (opcode == attReadByTypeRequest || opcode == attReadByGroupRequest) && (len(params) == 6 || len(params) == 20):
+		start := handleAt(0)
+		typ := uuidFromWire(params[4:])
+		group := opcode == attReadByGroupRequest
+
+		if group && typ != UUID16(gattPrimaryService) {
+			return attError(opcode, start, 0x10) // Unsupported group type.
+		}
+
+		response := []byte{opcode + 1, 0}
+		length := 0
+		for _, a := range self.inRange(start, handleAt(2)) {
+			if a.typ != typ {
+				continue
+			}
+
+			value, code := self.read(c, a)
+			if code != 0 {
+				if length == 0 {
+					return attError(opcode, a.handle, code)
+				}
+				break
+			}
+
+			entry := binary.LittleEndian.AppendUint16(nil, a.handle)
+			if group {
+				entry = binary.LittleEndian.AppendUint16(entry, a.groupEnd)
+			}
+			entry = append(entry, truncate(value, c.mtu-2-len(entry))...)
+
+			// All entries of a response have the same length.
+			if length != 0 && len(entry) != length || len(response)+len(entry) > c.mtu {
+				break
+			}
+			length = len(entry)
+			response = append(response, entry...)
+		}
+
+		if length == 0 {
+			return attError(opcode, start, attErrAttributeNotFound)
+		}
+		response[1] = byte(length)
+		return response
+
+	case opcode == attReadRequest && len(params) >= 2, opcode == attReadBlobRequest && len(params) >= 4:
+		h := handleAt(0)
+		a := self.attribute(h)
+		if a == nil {
+			return attError(opcode, h, attErrInvalidHandle)
+		}
+
+		value, code := self.read(c, a)
+		if code != 0 {
+			return attError(opcode, h, code)
+		}
+
+		if opcode == attReadBlobRequest {
+			offset := int(handleAt(2))
+			if offset > len(value) {
+				return attError(opcode, h, 0x07) // Invalid offset.
+			}
+			value = value[offset:]
+		}
+
+		return append([]byte{opcode + 1}, truncate(value, c.mtu-1)...)
+
+	case (opcode == attWriteRequest || opcode == attWriteCommand) && len(params) >= 2:
+		h := handleAt(0)
+		value := params[2:]
+		a := self.attribute(h)
+
+		var code byte
+		switch {
+		case a == nil:
+			code = attErrInvalidHandle
+		case a.cccd:
+			self.lock.Lock()
+			c.subscribed[a.char.valueHandle] = len(value) > 0 && value[0]&0x01 != 0
+			self.lock.Unlock()
+		case a.char != nil && a.char.Write != nil:
+			a.char.Write(append([]byte(nil), value...))
+		default:
+			code = attErrWriteNotPermitted
+		}
+
+		if opcode == attWriteCommand {
+			return nil
+		}
+		if code != 0 {
+			return attError(opcode, h, code)
+		}
+		return []byte{attWriteReply}
+	}
+
+	// Commands never get a response, not even an error.
+	if opcode&0x40 != 0 || opcode == attConfirmation {
+		return nil
+	}
+
+	return attError(opcode, 0, attErrRequestNotSupp)
+}
+
+// Makes the local adapter advertise itself under `name` as connectable,
+// listing the given service, so phone apps can find and connect to a
+// GATTServer. Requires the hciconfig and hcitool utilities.
+func Advertise(name string, service UUID) error {
+	// Flags: LE general discoverable, BR/EDR not supported.
+	data := []byte{0x02, 0x01, 0x06}
+
+	uuid := shortest(service)
+	if len(uuid) == 2 {
+		data = append(data, 3, 0x03)
+	} else {
+		data = append(data, 17, 0x07)
+	}
+	data = append(data, uuid...)
+
+	if room := 31 - len(data) - 2; room > 0 {
+		data = append(data, byte(len(truncate([]byte(name), room))+1), 0x09)
+		data = append(data, truncate([]byte(name), room)...)
+	}
+
+	args := []string{"-i", "hci0", "cmd", "0x08", "0x0008", fmt.Sprintf("%02x", len(data))}
+	for i := 0; i < 31; i++ {
+		b := byte(0)
+		if i < len(data) {
+			b = data[i]
+		}
+		args = append(args, fmt.Sprintf("%02x", b))
+	}
+
+	commands := [][]string{
+		{"hciconfig", "hci0", "up"},
+		append([]string{"hcitool"}, args...),
+		{"hciconfig", "hci0", "leadv", "0"},
+	}
+
+	for _, command := range commands {
+		out, err := exec.Command(command[0], command[1:]...).CombinedOutput()
+		if err != nil {
+			return fmt.Errorf("bluetooth: %s failed: %v: %s", command[0], err, strings.TrimSpace(string(out)))
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,119 @@
+//go:build linux && (arm || arm64 || amd64)
+
+package Bluetooth
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	btprotoL2CAP = 0
+
+	// Fixed channel of the attribute protocol on LE links.
+	attCID = 4
+
+	bdaddrLEPublic = 1
+	bdaddrLERandom = 2
+)
+
+// struct sockaddr_l2 from <bluetooth/l2cap.h>.
+type sockaddrL2 struct {
+	family     uint16
+	psm        uint16
+	bdaddr     [6]byte
+	cid        uint16
+	bdaddrType uint8
+	_          uint8
+}
+
+func leSocket() (int, error) {
+	return syscall.Socket(afBluetooth, syscall.SOCK_SEQPACKET|syscall.SOCK_CLOEXEC, btprotoL2CAP)
+}
+
+func bindLE(fd int) error {
+	sa := sockaddrL2{family: afBluetooth, cid: attCID, bdaddrType: bdaddrLEPublic}
+	_, _, errno := syscall.Syscall(syscall.SYS_BIND, uintptr(fd), uintptr(unsafe.Pointer(&sa)), unsafe.Sizeof(sa))
+	if errno != 0 {
+		return os.NewSyscallError("bind", errno)
+	}
+
+	return nil
+}
+
+// Opens a Bluetooth Low Energy connection to the attribute protocol of the
+// device with the given address. `random` selects a random rather than a
+// public device address. Each read and write carries a single ATT PDU.
+func DialLE(address string, random bool) (*Conn, error) {
+	remote, err := ParseAddress(address)
+	if err != nil {
+		return nil, err
+	}
+
+	fd, err := leSocket()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := bindLE(fd); err != nil {
+		syscall.Close(fd)
+		return nil, err
+	}
+
+	sa := sockaddrL2{family: afBluetooth, bdaddr: remote.bdaddr(), cid: attCID, bdaddrType: bdaddrLEPublic}
+	if random {
+		sa.bdaddrType = bdaddrLERandom
+	}
+
+	_, _, errno := syscall.Syscall(syscall.SYS_CONNECT, uintptr(fd), uintptr(unsafe.Pointer(&sa)), unsafe.Sizeof(sa))
+	if errno != 0 {
+		syscall.Close(fd)
+		return nil, os.NewSyscallError("connect", errno)
+	}
+
+	return &Conn{os.NewFile(uintptr(fd), "le:"+address), remote}, nil
+}
+
+// Listens for Bluetooth Low Energy connections to the attribute protocol of
+// the local adapter, for serving GATT services. The adapter must be advertising
+// for peers to find it; see Advertise.
+func ListenLE() (*LEListener, error) {
+	fd, err := leSocket()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := bindLE(fd); err != nil {
+		syscall.Close(fd)
+		return nil, err
+	}
+
+	if err := syscall.Listen(fd, 1); err != nil {
+		syscall.Close(fd)
+		return nil, os.NewSyscallError("listen", err)
+	}
+
+	return &LEListener{fd}, nil
+}
+
+// Waits for and returns the next connection.
+func (self *LEListener) Accept() (*Conn, error) {
+	var sa sockaddrL2
+	length := uint32(unsafe.Sizeof(sa))
+
+	nfd, _, errno := syscall.Syscall(syscall.SYS_ACCEPT, uintptr(self.fd), uintptr(unsafe.Pointer(&sa)), uintptr(unsafe.Pointer(&length)))
+	if errno != 0 {
+		return nil, os.NewSyscallError("accept", errno)
+	}
+
+	syscall.CloseOnExec(int(nfd))
+	remote := fromBdaddr(sa.bdaddr)
+
+	return &Conn{os.NewFile(nfd, "le:"+remote.String()), remote}, nil
+}
+
+// Stops listening.
+func (self *LEListener) Close() error {
+	return syscall.Close(self.fd)
+}
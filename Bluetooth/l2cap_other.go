@@ -0,0 +1,31 @@
+//go:build !linux || !(arm || arm64 || amd64)
+
+package Bluetooth
+
+// Opens a Bluetooth Low Energy connection to the attribute protocol of the
+// device with the given address. `random` selects a random rather than a
+// public device address. Each read and write carries a single ATT PDU.
+func DialLE(address string, random bool) (*Conn, error) {
+	if _, err := ParseAddress(address); err != nil {
+		return nil, err
+	}
+
+	return nil, ErrUnsupported
+}
+
+// Listens for Bluetooth Low Energy connections to the attribute protocol of
+// the local adapter, for serving GATT services. The adapter must be advertising
+// for peers to find it; see Advertise.
+func ListenLE() (*LEListener, error) {
+	return nil, ErrUnsupported
+}
+
+// Waits for and returns the next connection.
+func (self *LEListener) Accept() (*Conn, error) {
+	return nil, ErrUnsupported
+}
+
+// Stops listening.
+func (self *LEListener) Close() error {
+	return ErrUnsupported
+}
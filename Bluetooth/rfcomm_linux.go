@@ -0,0 +1,111 @@
+//go:build linux && (arm || arm64 || amd64)
+
+package Bluetooth
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	afBluetooth   = 31
+	btprotoRFCOMM = 3
+)
+
+// struct sockaddr_rc from <bluetooth/rfcomm.h>.
+type sockaddrRC struct {
+	family  uint16
+	bdaddr  [6]byte
+	channel uint8
+	_       uint8
+}
+
+// BlueZ stores addresses in little-endian byte order.
+func (self Address) bdaddr() [6]byte {
+	var b [6]byte
+	for i := range self {
+		b[i] = self[5-i]
+	}
+
+	return b
+}
+
+func fromBdaddr(b [6]byte) Address {
+	var a Address
+	for i := range b {
+		a[i] = b[5-i]
+	}
+
+	return a
+}
+
+func socket() (int, error) {
+	return syscall.Socket(afBluetooth, syscall.SOCK_STREAM|syscall.SOCK_CLOEXEC, btprotoRFCOMM)
+}
+
+// Connects to the RFCOMM channel of the device with the given address.
+// The LEGO EV3 brick serves its serial port on channel 1.
+func Dial(address string, channel uint8) (*Conn, error) {
+	remote, err := ParseAddress(address)
+	if err != nil {
+		return nil, err
+	}
+
+	fd, err := socket()
+	if err != nil {
+		return nil, err
+	}
+
+	sa := sockaddrRC{family: afBluetooth, bdaddr: remote.bdaddr(), channel: channel}
+	_, _, errno := syscall.Syscall(syscall.SYS_CONNECT, uintptr(fd), uintptr(unsafe.Pointer(&sa)), unsafe.Sizeof(sa))
+	if errno != 0 {
+		syscall.Close(fd)
+		return nil, os.NewSyscallError("connect", errno)
+	}
+
+	return &Conn{os.NewFile(uintptr(fd), "rfcomm:"+address), remote}, nil
+}
+
+// Listens for RFCOMM connections on the given channel of the local adapter.
+func Listen(channel uint8) (*Listener, error) {
+	fd, err := socket()
+	if err != nil {
+		return nil, err
+	}
+
+	sa := sockaddrRC{family: afBluetooth, channel: channel}
+	_, _, errno := syscall.Syscall(syscall.SYS_BIND, uintptr(fd), uintptr(unsafe.Pointer(&sa)), unsafe.Sizeof(sa))
+	if errno != 0 {
+		syscall.Close(fd)
+		return nil, os.NewSyscallError("bind", errno)
+	}
+
+	if err := syscall.Listen(fd, 1); err != nil {
+		syscall.Close(fd)
+		return nil, os.NewSyscallError("listen", err)
+	}
+
+	return &Listener{fd, channel}, nil
+}
+
+// Waits for and returns the next connection.
+func (self *Listener) Accept() (*Conn, error) {
+	var sa sockaddrRC
+	length := uint32(unsafe.Sizeof(sa))
+
+	nfd, _, errno := syscall.Syscall(syscall.SYS_ACCEPT, uintptr(self.fd), uintptr(unsafe.Pointer(&sa)), uintptr(unsafe.Pointer(&length)))
+	if errno != 0 {
+		return nil, os.NewSyscallError("accept", errno)
+	}
+
+	syscall.CloseOnExec(int(nfd))
+	remote := fromBdaddr(sa.bdaddr)
+
+	return &Conn{os.NewFile(nfd, "rfcomm:"+remote.String()), remote}, nil
+}
+
+// Stops listening.
+func (self *Listener) Close() error {
+	return syscall.Close(self.fd)
+}
@@ -0,0 +1,28 @@
+//go:build !linux || !(arm || arm64 || amd64)
+
+package Bluetooth
+
+// Connects to the RFCOMM channel of the device with the given address.
+// The LEGO EV3 brick serves its serial port on channel 1.
+func Dial(address string, channel uint8) (*Conn, error) {
+	if _, err := ParseAddress(address); err != nil {
+		return nil, err
+	}
+
+	return nil, ErrUnsupported
+}
+
+// Listens for RFCOMM connections on the given channel of the local adapter.
+func Listen(channel uint8) (*Listener, error) {
+	return nil, ErrUnsupported
+}
+
+// Waits for and returns the next connection.
+func (self *Listener) Accept() (*Conn, error) {
+	return nil, ErrUnsupported
+}
+
+// Stops listening.
+func (self *Listener) Close() error {
+	return ErrUnsupported
+}
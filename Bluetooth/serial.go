@@ -0,0 +1,252 @@
+package Bluetooth
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"sync"
+)
+
+// The RFCOMM channel serial port apps usually connect to.
+const SerialChannel = 1
+
+// A connection exchanging newline-terminated text lines, as sent by generic
+// Bluetooth serial controller apps, or JSON messages, one per line.
+type Serial struct {
+	conn   io.ReadWriteCloser
+	reader *bufio.Reader
+
+	writeLock sync.Mutex
+}
+
+// Wraps an established byte stream, usually a *Conn.
+func NewSerial(conn io.ReadWriteCloser) *Serial {
+	s := new(Serial)
+	s.conn = conn
+	s.reader = bufio.NewReader(conn)
+
+	return s
+}
+
+// Connects to the serial port profile of the device with the given address.
+func DialSerial(address string, channel uint8) (*Serial, error) {
+	conn, err := Dial(address, channel)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewSerial(conn), nil
+}
+
+// Waits for the next line and returns it without its "\n" or "\r\n" terminator.
+// Apps that terminate lines with a lone "\r" are supported too.
+func (self *Serial) ReadLine() (string, error) {
+	var b strings.Builder
+
+	for {
+		c, err := self.reader.ReadByte()
+		if err != nil {
+			if err == io.EOF && b.Len() > 0 {
+				return b.String(), nil
+			}
+			return "", err
+		}
+
+		switch c {
+		case '\n':
+			return b.String(), nil
+		case '\r':
+			if next, err := self.reader.Peek(1); err == nil && next[0] == '\n' {
+				self.reader.ReadByte()
+			}
+			return b.String(), nil
+		}
+
+		b.WriteByte(c)
+	}
+}
+
+// Sends a line, appending "\n".
+func (self *Serial) WriteLine(line string) error {
+	self.writeLock.Lock()
+	defer self.writeLock.Unlock()
+
+	_, err := io.WriteString(self.conn, line+"\n")
+	return err
+}
+
+// Waits for the next line and decodes it as JSON into `v`.
+func (self *Serial) ReadJSON(v interface{}) error {
+	line, err := self.ReadLine()
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal([]byte(line), v)
+}
+
+// Sends `v` encoded as JSON on a single line.
+func (self *Serial) WriteJSON(v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	return self.WriteLine(string(data))
+}
+
+// Closes the connection.
+func (self *Serial) Close() error {
+	return self.conn.Close()
+}
+
+// A command received over a serial connection.
+type Request struct {
+	Conn *Serial
+	// The line as received.
+	Line string
+	// Lowercased first word of a text line, or the "command" field of a JSON message.
+	Name string
+	// Remaining words of a text line.
+	Args []string
+	// The whole JSON message, or nil for text lines.
+	Data json.RawMessage
+}
+
+// Decodes the JSON message into `v`.
+func (self Request) Decode(v interface{}) error {
+	if self.Data == nil {
+		return errors.New("bluetooth: request is not a JSON message")
+	}
+
+	return json.Unmarshal(self.Data, v)
+}
+
+// Sends a line back to the sender.
+func (self Request) Reply(line string) error {
+	return self.Conn.WriteLine(line)
+}
+
+// Dispatches the commands received over serial connections to handlers.
+//
+// A text line such as "forward 50" invokes the handler registered for
+// "forward" with the arguments ["50"]; a JSON line such as
+// {"command": "forward", "speed": 50} the same handler with the message in Data.
+type Commands struct {
+	lock     sync.Mutex
+	handlers map[string]func(Request)
+	fallback func(Request)
+}
+
+// Creates an empty set of commands.
+func NewCommands() *Commands {
+	c := new(Commands)
+	c.handlers = make(map[string]func(Request))
+
+	return c
+}
+
+// Registers the handler of the named command. Names are case insensitive.
+func (self *Commands) Handle(name string, fn func(Request)) {
+	self.lock.Lock()
+	self.handlers[strings.ToLower(name)] = fn
+	self.lock.Unlock()
+}
+
+// Registers the handler of the lines no other handler is registered for,
+// e.g. the single characters many controller apps send for their buttons.
+func (self *Commands) HandleDefault(fn func(Request)) {
+	self.lock.Lock()
+	self.fallback = fn
+	self.lock.Unlock()
+}
+
+// Reads lines from `conn` and dispatches them until the connection fails or is closed.
+func (self *Commands) Serve(conn *Serial) error {
+	for {
+		line, err := conn.ReadLine()
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+
+		self.dispatch(parseRequest(conn, line))
+	}
+}
+
+func parseRequest(conn *Serial, line string) Request {
+	r := Request{Conn: conn, Line: line}
+
+	trimmed := strings.TrimSpace(line)
+	if strings.HasPrefix(trimmed, "{") {
+		var message struct {
+			Command string `json:"command"`
+		}
+		if json.Unmarshal([]byte(trimmed), &message) == nil {
+			r.Name = strings.ToLower(message.Command)
+			r.Data = json.RawMessage(trimmed)
+			return r
+		}
+	}
+
+	if fields := strings.Fields(trimmed); len(fields) > 0 {
+		r.Name = strings.ToLower(fields[0])
+		r.Args = fields[1:]
+	}
+
+	return r
+}
+
+func (self *Commands) dispatch(r Request) {
+	self.lock.Lock()
+	fn, ok := self.handlers[r.Name]
+	if !ok {
+		fn = self.fallback
+	}
+	self.lock.Unlock()
+
+	if fn != nil {
+		fn(r)
+	}
+}
+
+// Accepts serial connections on the given channel and serves the commands
+// they send, each connection in its own goroutine. Only returns if listening fails.
+func ListenAndServeSerial(channel uint8, commands *Commands) error {
+	l, err := Listen(channel)
+	if err != nil {
+		return err
+	}
+	defer l.Close()
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return err
+		}
+
+		go func() {
+			s := NewSerial(conn)
+			defer s.Close()
+			commands.Serve(s)
+		}()
+	}
+}
+
+// Advertises the serial port profile on the given channel through the SDP
+// server, which phone apps need in order to find it. Requires the sdptool
+// utility and bluetoothd running in compatibility mode (--compat).
+func RegisterSerialPort(channel uint8) error {
+	out, err := exec.Command("sdptool", "add", fmt.Sprintf("--channel=%d", channel), "SP").CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("bluetooth: sdptool failed: %v: %s", err, strings.TrimSpace(string(out)))
+	}
+
+	return nil
+}
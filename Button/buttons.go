@@ -4,8 +4,12 @@ package Button
 import (
 	"bytes"
 	"encoding/binary"
-	"log"
+	"github.com/jermon/GoEV3/Platform"
+	"github.com/jermon/GoEV3/utilities"
+	"io"
 	"os"
+	"path/filepath"
+	"strconv"
 	"sync"
 )
 
@@ -24,13 +28,26 @@ const (
 	Right       = 106
 	Enter       = 28
 	Escape      = 14
+	// The back button, as ev3dev names Escape.
+	Back = Escape
 )
 
+// Event devices of the brick buttons on kernels naming them after their driver.
+const keysPattern = "/dev/input/by-path/*ev3-keys*"
+
 func findFilename() string {
-	filename := "/dev/input/by-path/platform-gpio-keys.0-event"
+	filename := Platform.Current().ButtonDevice
 
+	if filename == "" {
+		utilities.Fatalf("The platform has no buttons")
+	}
+	filename = utilities.HostPath(filename)
 	if _, err := os.Stat(filename); os.IsNotExist(err) {
-		log.Fatal("Cannot find keys file")
+		matches, _ := filepath.Glob(utilities.HostPath(keysPattern))
+		if len(matches) == 0 {
+			utilities.Fatalf("Cannot find keys file")
+		}
+		filename = matches[0]
 	}
 
 	return filename
@@ -104,15 +121,26 @@ func Watch() {
 	}()
 }
 
-// Checks if the given button is currently pressed. This call must be preceded with a call to `Watch`.
+// Checks if the given button is currently pressed. After a call to `Watch` it
+// answers from the watched events, otherwise it asks the device.
 func IsPressed(kind Kind) bool {
-	var result bool
-
 	bMapLock.Lock()
-	result = bPressedMap[kind]
+	watched := bPressedMap != nil
+	result := bPressedMap[kind]
 	bMapLock.Unlock()
 
-	return result
+	if watched {
+		return result
+	}
+
+	f, err := os.Open(findFilename())
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	state, ok := readKeyState(f)
+	return ok && state[kind/8]&(1<<(kind%8)) != 0
 }
 
 // Waits for any button to be pressed.
@@ -143,3 +171,98 @@ func WaitAny() Kind {
 		}
 	}
 }
+
+// Size of a struct input_event: a timeval of two longs, then the type and
+// code as 16-bit and the value as 32-bit integers.
+const eventSize = 2*strconv.IntSize/8 + 8
+
+// Key events in struct input_event.
+const evKey = 1
+
+// Reads key events from `f`, calling `fn` for each press and release, until
+// the read fails, e.g. once `f` is closed, or `fn` returns false.
+func readEvents(f *os.File, fn func(kind Kind, pressed bool) bool) {
+	b := make([]byte, eventSize)
+	for {
+		if _, err := io.ReadFull(f, b); err != nil {
+			return
+		}
+
+		event := b[eventSize-8:]
+		if binary.LittleEndian.Uint16(event[0:2]) != evKey {
+			continue
+		}
+
+		code := binary.LittleEndian.Uint16(event[2:4])
+		value := int32(binary.LittleEndian.Uint32(event[4:8]))
+		// 2 is sent while a key is held down, auto-repeating.
+		if value == 2 {
+			continue
+		}
+
+		if !fn(Kind(code), value == 1) {
+			return
+		}
+	}
+}
+
+// Calls `fn` whenever a button is pressed or released, until a value is sent
+// to `stop`. Blocks until then.
+func Listen(stop <-chan bool, fn func(kind Kind, pressed bool)) {
+	f, err := os.Open(findFilename())
+	if err != nil {
+		utilities.Fatal(err)
+	}
+
+	go readEvents(f, func(kind Kind, pressed bool) bool {
+		fn(kind, pressed)
+		return true
+	})
+
+	<-stop
+	// Unblocks the pending read.
+	f.Close()
+}
+
+// Blocks until any button is pressed, and returns it.
+func WaitForAnyPress() Kind {
+	f, err := os.Open(findFilename())
+	if err != nil {
+		utilities.Fatal(err)
+	}
+	defer f.Close()
+
+	var kind Kind
+	readEvents(f, func(k Kind, pressed bool) bool {
+		kind = k
+		return !pressed
+	})
+
+	return kind
+}
+
+// Registers a callback to be triggered when a button is pressed. The listening
+// can be stopped by sending any boolean value to a `stop` channel:
+//
+//	Button.OnPress(stop, func(kind Button.Kind) {
+//		if kind == Button.Back {
+//			...
+//		}
+//	})
+func OnPress(stop <-chan bool, fn func(kind Kind)) {
+	go Listen(stop, func(kind Kind, pressed bool) {
+		if pressed {
+			fn(kind)
+		}
+	})
+}
+
+// Registers a callback to be triggered when a button is released. The
+// listening can be stopped by sending any boolean value to a `stop` channel.
+func OnRelease(stop <-chan bool, fn func(kind Kind)) {
+	go Listen(stop, func(kind Kind, pressed bool) {
+		if !pressed {
+			fn(kind)
+		}
+	})
+}
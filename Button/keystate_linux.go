@@ -0,0 +1,22 @@
+//go:build linux
+
+package Button
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// Bytes of the key bitmap, one bit per key code up to KEY_MAX.
+const keyBitmapSize = 0x300 / 8
+
+// Reads which keys of an event device are down with EVIOCGKEY.
+func readKeyState(f *os.File) ([keyBitmapSize]byte, bool) {
+	var state [keyBitmapSize]byte
+
+	request := uintptr(uint32(2)<<30 | uint32(keyBitmapSize)<<16 | 'E'<<8 | 0x18)
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), request, uintptr(unsafe.Pointer(&state[0])))
+
+	return state, errno == 0
+}
@@ -0,0 +1,14 @@
+//go:build !linux
+
+package Button
+
+import (
+	"os"
+)
+
+const keyBitmapSize = 0x300 / 8
+
+// Event devices only exist on Linux.
+func readKeyState(f *os.File) ([keyBitmapSize]byte, bool) {
+	return [keyBitmapSize]byte{}, false
+}
@@ -0,0 +1,127 @@
+package Control
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// Estimates a tilt angle by fusing a gyro's rotational rate with the tilt
+// measured from gravity by an accelerometer, such as the HiTechnic
+// accelerometer or the Mindsensors AbsoluteIMU.
+//
+// Integrating the gyro rate is smooth but drifts; the accelerometer tilt
+// doesn't drift but is disturbed by every bump and acceleration. The filter
+// trusts the gyro over short time spans and the accelerometer over long ones,
+// the crossover being its time constant. Angles are in degrees and rates in
+// degrees per second, as reported by the EV3 gyro sensor:
+//
+//	filter := Control.NewComplementaryFilter(time.Second)
+//	for {
+//		pitch, _ := Control.TiltFromAcceleration(ax, ay, az)
+//		tilt := filter.Update(pitch, float64(gyro.ReadRotationalSpeed()))
+//		...
+//	}
+type ComplementaryFilter struct {
+	lock sync.Mutex
+
+	timeConstant time.Duration
+
+	angle       float64
+	lastUpdate  time.Time
+	initialized bool
+}
+
+// Creates a filter with the given time constant. Half a second to a couple of
+// seconds suits balancing robots; longer constants reject more vibration but
+// correct gyro drift more slowly.
+func NewComplementaryFilter(timeConstant time.Duration) *ComplementaryFilter {
+	f := new(ComplementaryFilter)
+	f.timeConstant = timeConstant
+
+	return f
+}
+
+// Changes the time constant.
+func (self *ComplementaryFilter) SetTimeConstant(timeConstant time.Duration) {
+	self.lock.Lock()
+	self.timeConstant = timeConstant
+	self.lock.Unlock()
+}
+
+// Sets the estimated angle, e.g. to a known starting position. The next update
+// starts from it instead of from the accelerometer tilt.
+func (self *ComplementaryFilter) Reset(angle float64) {
+	self.lock.Lock()
+	self.angle = angle
+	self.initialized = true
+	self.lastUpdate = time.Time{}
+	self.lock.Unlock()
+}
+
+// Returns the estimated angle.
+func (self *ComplementaryFilter) Angle() float64 {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	return self.angle
+}
+
+// Updates the estimate with the accelerometer tilt and gyro rate measured now,
+// using the wall-clock time elapsed since the previous call.
+func (self *ComplementaryFilter) Update(accelAngle float64, rate float64) float64 {
+	now := time.Now()
+
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	var dt time.Duration
+	if !self.lastUpdate.IsZero() {
+		dt = now.Sub(self.lastUpdate)
+	}
+	self.lastUpdate = now
+
+	return self.update(accelAngle, rate, dt)
+}
+
+// Updates the estimate with measurements taken `dt` after the previous ones.
+// Useful for simulations and loops with their own timing.
+func (self *ComplementaryFilter) UpdateWithDt(accelAngle float64, rate float64, dt time.Duration) float64 {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	return self.update(accelAngle, rate, dt)
+}
+
+// The lock must be held.
+func (self *ComplementaryFilter) update(accelAngle float64, rate float64, dt time.Duration) float64 {
+	if !self.initialized {
+		self.initialized = true
+		self.angle = accelAngle
+		return self.angle
+	}
+
+	seconds := dt.Seconds()
+	if seconds <= 0 {
+		return self.angle
+	}
+
+	alpha := 1.0
+	if tau := self.timeConstant.Seconds(); tau > 0 {
+		alpha = tau / (tau + seconds)
+	}
+
+	self.angle = alpha*(self.angle+rate*seconds) + (1-alpha)*accelAngle
+
+	return self.angle
+}
+
+// Returns the pitch and roll, in degrees, of an accelerometer at rest from the
+// gravity it measures along its axes, in any unit. Pitch is the rotation about
+// the Y axis and roll about the X axis, Z pointing up when level.
+func TiltFromAcceleration(x float64, y float64, z float64) (pitch float64, roll float64) {
+	pitch = math.Atan2(-x, math.Hypot(y, z)) * 180 / math.Pi
+	roll = math.Atan2(y, z) * 180 / math.Pi
+
+	return pitch, roll
+}
@@ -0,0 +1,208 @@
+// Provides reusable feedback controllers for user control loops and the library's own behaviors.
+package Control
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/jermon/GoEV3/utilities"
+)
+
+// Proportional-integral-derivative controller.
+//
+// The derivative term acts on the measurement rather than on the error, so
+// setpoint changes don't cause output spikes, and it can be low-pass filtered to
+// tame noisy sensors. Integration is suspended while the output is saturated,
+// which prevents integral windup.
+type PID struct {
+	lock sync.Mutex
+
+	kp, ki, kd float64
+	setpoint   float64
+
+	minOutput, maxOutput float64
+	filter               time.Duration
+	sampleTime           time.Duration
+
+	integral        float64
+	derivative      float64
+	lastMeasurement float64
+	lastOutput      float64
+	lastUpdate      time.Time
+	initialized     bool
+}
+
+// Creates a controller with the given gains and unlimited output.
+func NewPID(kp float64, ki float64, kd float64) *PID {
+	c := new(PID)
+	c.kp, c.ki, c.kd = kp, ki, kd
+	c.minOutput = math.Inf(-1)
+	c.maxOutput = math.Inf(1)
+
+	return c
+}
+
+// Changes the controller gains.
+func (self *PID) SetGains(kp float64, ki float64, kd float64) {
+	self.lock.Lock()
+	self.kp, self.ki, self.kd = kp, ki, kd
+	self.lock.Unlock()
+}
+
+// Returns the controller gains.
+func (self *PID) Gains() (kp float64, ki float64, kd float64) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	return self.kp, self.ki, self.kd
+}
+
+// Sets the value the controller drives the measurement towards.
+func (self *PID) SetSetpoint(setpoint float64) {
+	self.lock.Lock()
+	self.setpoint = setpoint
+	self.lock.Unlock()
+}
+
+// Returns the current setpoint.
+func (self *PID) Setpoint() float64 {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	return self.setpoint
+}
+
+// Clamps the controller output to [min, max]. The integral term is clamped as well.
+func (self *PID) SetOutputLimits(min float64, max float64) {
+	if min > max {
+		min, max = max, min
+	}
+
+	self.lock.Lock()
+	self.minOutput, self.maxOutput = min, max
+	self.integral = clamp(self.integral, min, max)
+	self.lock.Unlock()
+}
+
+// Low-pass filters the derivative term with the given time constant. Zero disables filtering.
+func (self *PID) SetDerivativeFilter(timeConstant time.Duration) {
+	self.lock.Lock()
+	self.filter = timeConstant
+	self.lock.Unlock()
+}
+
+// Sets the minimal interval between two output computations. Calls to Update
+// arriving earlier return the previous output. Zero computes on every call.
+func (self *PID) SetSampleTime(sampleTime time.Duration) {
+	self.lock.Lock()
+	self.sampleTime = sampleTime
+	self.lock.Unlock()
+}
+
+// Clears the accumulated integral and derivative state.
+func (self *PID) Reset() {
+	self.lock.Lock()
+	self.integral = 0
+	self.derivative = 0
+	self.lastOutput = 0
+	self.initialized = false
+	self.lock.Unlock()
+}
+
+// Computes the controller output for the given measurement, using the wall-clock
+// time elapsed since the previous call.
+func (self *PID) Update(measurement float64) float64 {
+	now := time.Now()
+
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	if !self.initialized {
+		self.lastUpdate = now
+		return self.update(measurement, 0)
+	}
+
+	dt := now.Sub(self.lastUpdate)
+	if dt < self.sampleTime {
+		return self.lastOutput
+	}
+
+	self.lastUpdate = now
+
+	return self.update(measurement, dt)
+}
+
+// Computes the controller output for the given measurement taken `dt` after the
+// previous one. Useful for simulations and loops with their own timing.
+func (self *PID) UpdateWithDt(measurement float64, dt time.Duration) float64 {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	return self.update(measurement, dt)
+}
+
+// The lock must be held.
+func (self *PID) update(measurement float64, dt time.Duration) float64 {
+	err := self.setpoint - measurement
+	seconds := dt.Seconds()
+
+	if !self.initialized || seconds <= 0 {
+		self.initialized = true
+		self.lastMeasurement = measurement
+		self.lastOutput = clamp(self.kp*err+self.integral, self.minOutput, self.maxOutput)
+
+		return self.lastOutput
+	}
+
+	rawDerivative := -(measurement - self.lastMeasurement) / seconds
+	if self.filter > 0 {
+		alpha := seconds / (self.filter.Seconds() + seconds)
+		self.derivative += alpha * (rawDerivative - self.derivative)
+	} else {
+		self.derivative = rawDerivative
+	}
+	self.lastMeasurement = measurement
+
+	proportional := self.kp * err
+	derivative := self.kd * self.derivative
+	integral := self.integral + self.ki*err*seconds
+
+	unclamped := proportional + integral + derivative
+	output := clamp(unclamped, self.minOutput, self.maxOutput)
+
+	// Only integrate while the output isn't saturated, or when the error drives it back.
+	if output == unclamped || (unclamped > output) != (err > 0) {
+		self.integral = clamp(integral, self.minOutput, self.maxOutput)
+	}
+
+	self.lastOutput = output
+
+	return output
+}
+
+// Runs the control loop every `interval` until a value is sent to `stop`:
+// reads the measurement with `read` and passes the output to `write`, e.g.
+// to keep a robot on a heading:
+//
+//	pid := Control.NewPID(2, 0.1, 0.5)
+//	pid.SetOutputLimits(-50, 50)
+//	pid.Run(stop, 20*time.Millisecond, func() float64 {
+//		return float64(gyro.ReadAngle())
+//	}, func(output float64) {
+//		base.Steer(output, 40)
+//	})
+//
+// The controller is reset first. Runs on the shared polling scheduler, using
+// the time elapsed between reads.
+func (self *PID) Run(stop <-chan bool, interval time.Duration, read func() float64, write func(output float64)) {
+	self.Reset()
+
+	utilities.PollUntil(stop, interval, func() {
+		write(self.Update(read()))
+	})
+}
+
+func clamp(value float64, min float64, max float64) float64 {
+	return math.Max(min, math.Min(max, value))
+}
@@ -0,0 +1,189 @@
+package Control
+
+import (
+	"math"
+	"sync"
+	"testing"
+	"time"
+)
+
+// A measurement and the time since the previous one, fed to UpdateWithDt.
+type sample struct {
+	measurement float64
+	dt          time.Duration
+}
+
+func TestPIDUpdate(t *testing.T) {
+	tests := []struct {
+		name       string
+		kp, ki, kd float64
+		min, max   float64
+		setpoint   float64
+		samples    []sample
+		want       []float64
+	}{
+		{
+			name:     "proportional",
+			kp:       2,
+			min:      math.Inf(-1),
+			max:      math.Inf(1),
+			setpoint: 10,
+			samples:  []sample{{4, 0}, {6, time.Second}, {10, time.Second}, {13, time.Second}},
+			want:     []float64{12, 8, 0, -6},
+		},
+		{
+			name:     "integral",
+			ki:       1,
+			min:      math.Inf(-1),
+			max:      math.Inf(1),
+			setpoint: 1,
+			samples:  []sample{{0, 0}, {0, time.Second}, {0, time.Second}, {0, 500 * time.Millisecond}},
+			want:     []float64{0, 1, 2, 2.5},
+		},
+		{
+			name:     "output limits",
+			kp:       100,
+			min:      -10,
+			max:      10,
+			setpoint: 0,
+			samples:  []sample{{-1, 0}, {1, time.Second}, {0.05, time.Second}},
+			want:     []float64{10, -10, -5},
+		},
+		{
+			// Saturated for a long time, then overshooting: without the windup
+			// clamp the integral would keep the output at the limit.
+			name:     "integral windup",
+			ki:       1,
+			min:      -5,
+			max:      5,
+			setpoint: 10,
+			samples: []sample{
+				{0, 0}, {0, time.Second}, {0, time.Second}, {0, time.Second},
+				{0, time.Second}, {0, time.Second}, {0, time.Second}, {0, time.Second},
+				{0, time.Second}, {0, time.Second}, {0, time.Second}, {0, time.Second},
+				{11, time.Second},
+			},
+			want: []float64{0, 5, 5, 5, 5, 5, 5, 5, 5, 5, 5, 5, -1},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			pid := NewPID(test.kp, test.ki, test.kd)
+			pid.SetOutputLimits(test.min, test.max)
+			pid.SetSetpoint(test.setpoint)
+
+			for i, s := range test.samples {
+				got := pid.UpdateWithDt(s.measurement, s.dt)
+				if math.Abs(got-test.want[i]) > 1e-9 {
+					t.Fatalf("sample %d: output %g, want %g", i, got, test.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestPIDSetpointChange(t *testing.T) {
+	tests := []struct {
+		name     string
+		setpoint float64
+		want     float64
+	}{
+		{"up", 5, 5},
+		{"down", -3, -3},
+		{"unchanged", 0, 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// A large derivative gain would kick the output if the derivative
+			// acted on the error.
+			pid := NewPID(1, 0, 10)
+			pid.UpdateWithDt(0, 0)
+			pid.UpdateWithDt(0, time.Second)
+
+			pid.SetSetpoint(test.setpoint)
+			if got := pid.UpdateWithDt(0, time.Second); math.Abs(got-test.want) > 1e-9 {
+				t.Fatalf("output %g after the setpoint changed to %g, want %g", got, test.setpoint, test.want)
+			}
+			if got := pid.Setpoint(); got != test.setpoint {
+				t.Fatalf("setpoint %g, want %g", got, test.setpoint)
+			}
+		})
+	}
+}
+
+// A first-order plant: the output moves the measurement at a rate
+// proportional to it.
+type plant struct {
+	lock   sync.Mutex
+	value  float64
+	reads  int
+	writes int
+}
+
+func (self *plant) read() float64 {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	self.reads++
+	return self.value
+}
+
+func (self *plant) write(output float64) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	self.writes++
+	self.value += output * 0.1
+}
+
+func (self *plant) counts() (int, int) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	return self.reads, self.writes
+}
+
+func TestPIDRun(t *testing.T) {
+	p := new(plant)
+	pid := NewPID(2, 0, 0)
+	pid.SetSetpoint(10)
+
+	const interval = 5 * time.Millisecond
+	const duration = 200 * time.Millisecond
+
+	stop := make(chan bool)
+	done := make(chan bool)
+	go func() {
+		pid.Run(stop, interval, p.read, p.write)
+		close(done)
+	}()
+
+	time.Sleep(duration)
+	stop <- true
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Run didn't return after stop")
+	}
+
+	reads, writes := p.counts()
+	if reads != writes {
+		t.Fatalf("%d reads for %d writes", reads, writes)
+	}
+	// Lenient bounds: the scheduler may fall behind on a loaded machine.
+	if max := int(duration/interval) + 2; reads < 5 || reads > max {
+		t.Fatalf("%d iterations in %v, expected at most %d at %v", reads, duration, max, interval)
+	}
+	if value := p.read(); math.Abs(value-10) > 0.5 {
+		t.Fatalf("plant at %g, expected it to settle near the setpoint 10", value)
+	}
+
+	// No more calls once Run returned, but for one that was in progress.
+	time.Sleep(10 * interval)
+	if _, after := p.counts(); after > writes+1 {
+		t.Fatalf("%d writes after Run returned", after-writes)
+	}
+}
@@ -0,0 +1,260 @@
+// Serves a built-in web dashboard showing live sensor charts and motor state,
+// with a virtual joystick driving the robot from a phone or laptop browser:
+//
+//	d := Dashboard.New()
+//	d.AddSensor("distance", func() float64 { return float64(sonar.ReadDistance()) })
+//	d.AddMotor("arm", arm)
+//	d.SetDrive(base)
+//	go d.Run(stop, 50*time.Millisecond)
+//	d.ListenAndServe(":8080")
+//
+// The page is backed by a small JSON API, usable by other clients too:
+//
+//	GET  /api/telemetry   current sensor values and motor state
+//	POST /api/drive       {"forward": 0.5, "turn": -0.2}, both in range [-1, 1]
+//	POST /api/stop        stops the drive and all motors
+package Dashboard
+
+import (
+	"embed"
+	"encoding/json"
+	"io/fs"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/jermon/GoEV3/Drive"
+	"github.com/jermon/GoEV3/Motor"
+	"github.com/jermon/GoEV3/Teleop"
+)
+
+//go:embed assets
+var assets embed.FS
+
+// How long a joystick command stays in effect without a new one, so the robot
+// stops when the browser goes away.
+const driveTimeout = 500 * time.Millisecond
+
+type sensor struct {
+	name string
+	read func() float64
+}
+
+type motor struct {
+	name  string
+	motor *Motor.Motor
+}
+
+// A dashboard of sensors and motors, with optional drive controls.
+type Dashboard struct {
+	lock    sync.Mutex
+	sensors []sensor
+	motors  []motor
+	mapping *Teleop.Mapping
+
+	forward  float64
+	turn     float64
+	received time.Time
+}
+
+// Creates an empty dashboard.
+func New() *Dashboard {
+	return new(Dashboard)
+}
+
+// Charts the values returned by `read` under `name`.
+func (self *Dashboard) AddSensor(name string, read func() float64) {
+	self.lock.Lock()
+	self.sensors = append(self.sensors, sensor{name, read})
+	self.lock.Unlock()
+}
+
+// Shows the speed, position, duty cycle and state of a motor under `name`.
+func (self *Dashboard) AddMotor(name string, m *Motor.Motor) {
+	self.lock.Lock()
+	self.motors = append(self.motors, motor{name, m})
+	self.lock.Unlock()
+}
+
+// Enables the joystick, which drives `base` arcade style while Run runs.
+func (self *Dashboard) SetDrive(base *Drive.DriveBase) {
+	m := Teleop.NewMapping(base)
+	m.SetDrive(self.Forward(), self.Turn())
+	m.SetCurves(Teleop.Linear, Teleop.Linear)
+
+	self.lock.Lock()
+	self.mapping = m
+	self.lock.Unlock()
+}
+
+// Returns the last joystick command, or zeros once it expired.
+func (self *Dashboard) command() (float64, float64) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	if time.Since(self.received) > driveTimeout {
+		return 0, 0
+	}
+
+	return self.forward, self.turn
+}
+
+// Reads the joystick's forward command, positive being forward. For use with a
+// custom Teleop.Mapping instead of SetDrive.
+func (self *Dashboard) Forward() Teleop.Source {
+	return func() float64 {
+		f, _ := self.command()
+		return f
+	}
+}
+
+// Reads the joystick's turn command, positive being left.
+func (self *Dashboard) Turn() Teleop.Source {
+	return func() float64 {
+		_, t := self.command()
+		return t
+	}
+}
+
+// Applies joystick commands every `interval` until a value is sent to `stop`,
+// then stops the drive. Does nothing without SetDrive.
+func (self *Dashboard) Run(stop <-chan bool, interval time.Duration) {
+	self.lock.Lock()
+	mapping := self.mapping
+	self.lock.Unlock()
+
+	if mapping == nil {
+		<-stop
+		return
+	}
+
+	mapping.Run(stop, interval)
+}
+
+// Telemetry returned by /api/telemetry.
+type Telemetry struct {
+	// Milliseconds since the Unix epoch.
+	Time    int64                 `json:"time"`
+	Sensors map[string]float64    `json:"sensors"`
+	Motors  map[string]MotorState `json:"motors"`
+	Drive   bool                  `json:"drive"`
+}
+
+// State of a motor as returned by /api/telemetry.
+type MotorState struct {
+	Speed     int16  `json:"speed"`
+	Position  int32  `json:"position"`
+	DutyCycle int16  `json:"duty_cycle"`
+	State     string `json:"state"`
+}
+
+// Reads all sensors and motors.
+func (self *Dashboard) Telemetry() *Telemetry {
+	self.lock.Lock()
+	sensors := append([]sensor(nil), self.sensors...)
+	motors := append([]motor(nil), self.motors...)
+	drive := self.mapping != nil
+	self.lock.Unlock()
+
+	t := &Telemetry{
+		Time:    time.Now().UnixNano() / int64(time.Millisecond),
+		Sensors: make(map[string]float64),
+		Motors:  make(map[string]MotorState),
+		Drive:   drive,
+	}
+
+	for _, s := range sensors {
+		value := s.read()
+		// NaN and infinities can't be encoded as JSON.
+		if math.IsNaN(value) || math.IsInf(value, 0) {
+			continue
+		}
+		t.Sensors[s.name] = value
+	}
+
+	for _, m := range motors {
+		t.Motors[m.name] = MotorState{
+			Speed:     m.motor.CurrentSpeed(),
+			Position:  m.motor.CurrentPosition(),
+			DutyCycle: m.motor.CurrentPower(),
+			State:     m.motor.GetState(),
+		}
+	}
+
+	return t
+}
+
+// Stops the drive and all motors, and drops the joystick command.
+func (self *Dashboard) Stop() {
+	self.lock.Lock()
+	self.forward, self.turn = 0, 0
+	motors := append([]motor(nil), self.motors...)
+	self.lock.Unlock()
+
+	for _, m := range motors {
+		m.motor.Stop()
+	}
+}
+
+func clamp(value float64) float64 {
+	if math.IsNaN(value) {
+		return 0
+	}
+
+	return math.Max(-1, math.Min(1, value))
+}
+
+// Serves the dashboard page and its API.
+func (self *Dashboard) Handler() http.Handler {
+	static, _ := fs.Sub(assets, "assets")
+
+	mux := http.NewServeMux()
+	mux.Handle("/", http.FileServer(http.FS(static)))
+
+	mux.HandleFunc("/api/telemetry", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		json.NewEncoder(w).Encode(self.Telemetry())
+	})
+
+	mux.HandleFunc("/api/drive", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var command struct {
+			Forward float64 `json:"forward"`
+			Turn    float64 `json:"turn"`
+		}
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1024)).Decode(&command); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		self.lock.Lock()
+		self.forward, self.turn = clamp(command.Forward), clamp(command.Turn)
+		self.received = time.Now()
+		self.lock.Unlock()
+
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	mux.HandleFunc("/api/stop", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		self.Stop()
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	return mux
+}
+
+// Serves the dashboard on `addr`. This call blocks.
+func (self *Dashboard) ListenAndServe(addr string) error {
+	return http.ListenAndServe(addr, self.Handler())
+}
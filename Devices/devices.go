@@ -0,0 +1,186 @@
+// Provides the list of the motors and sensors connected, and events as they
+// are plugged in and out.
+//
+// Programs can wait for a device rather than fail when it isn't there yet:
+//
+//	go Devices.Watch(stop, time.Second, func(e Devices.Event) {
+//		if e.Attached && e.Driver == "lego-ev3-color" {
+//			color = Sensors.FindColorSensor(Sensors.InPort(e.Port))
+//		}
+//	})
+package Devices
+
+import (
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jermon/GoEV3/Motor"
+	"github.com/jermon/GoEV3/Platform"
+	"github.com/jermon/GoEV3/Sensors"
+	"github.com/jermon/GoEV3/utilities"
+)
+
+// Classes of the devices listed.
+type Class string
+
+const (
+	ClassTachoMotor Class = "tacho-motor"
+	ClassDCMotor          = "dc-motor"
+	ClassServoMotor       = "servo-motor"
+	ClassSensor           = "lego-sensor"
+)
+
+const classPath = "/sys/class"
+
+// Classes of motors, in the order they are listed.
+var motorClasses = []Class{ClassTachoMotor, ClassDCMotor, ClassServoMotor}
+
+// A motor connected.
+type MotorInfo struct {
+	Port   Motor.OutPort
+	Class  Class
+	Driver string
+	// Folder of the motor in sysfs.
+	Path     string
+	Commands []string
+}
+
+// A sensor connected.
+type SensorInfo struct {
+	Port   Sensors.InPort
+	Driver string
+	// Folder of the sensor in sysfs.
+	Path  string
+	Modes []string
+	Mode  string
+}
+
+// Returns the folders of the devices of a class.
+func folders(class Class) []string {
+	var result []string
+
+	for _, name := range utilities.ListDir(path.Join(classPath, string(class))) {
+		result = append(result, path.Join(classPath, string(class), name))
+	}
+	sort.Strings(result)
+
+	return result
+}
+
+// Returns the motors connected, of all classes.
+func ListMotors() []MotorInfo {
+	var motors []MotorInfo
+
+	for _, class := range motorClasses {
+		for _, folder := range folders(class) {
+			motors = append(motors, MotorInfo{
+				Port:     Motor.CanonicalOutPort(utilities.ReadStringValue(folder, "address")),
+				Class:    class,
+				Driver:   utilities.ReadStringValue(folder, "driver_name"),
+				Path:     folder,
+				Commands: strings.Fields(utilities.ReadStringValue(folder, "commands")),
+			})
+		}
+	}
+
+	return motors
+}
+
+// Returns the sensors connected.
+func ListSensors() []SensorInfo {
+	var sensors []SensorInfo
+
+	for _, folder := range folders(ClassSensor) {
+		sensors = append(sensors, SensorInfo{
+			Port:   Sensors.CanonicalInPort(utilities.ReadStringValue(folder, "address")),
+			Driver: utilities.ReadStringValue(folder, "driver_name"),
+			Path:   folder,
+			Modes:  strings.Fields(utilities.ReadStringValue(folder, "modes")),
+			Mode:   utilities.ReadStringValue(folder, "mode"),
+		})
+	}
+
+	return sensors
+}
+
+// A device plugged in or out.
+type Event struct {
+	// True when the device appeared, false when it disappeared.
+	Attached bool
+	Class    Class
+	// Name of the port, e.g. "in1" or "outA".
+	Port   string
+	Driver string
+	// Folder of the device in sysfs, gone once it is detached.
+	Path string
+}
+
+// Identifies a device across scans. A device swapped for another between
+// scans may get the same folder, so the driver is part of it.
+type deviceKey struct {
+	path   string
+	driver string
+}
+
+// Returns the devices connected, of all classes.
+func scan() map[deviceKey]Event {
+	devices := make(map[deviceKey]Event)
+
+	for _, class := range []Class{ClassTachoMotor, ClassDCMotor, ClassServoMotor, ClassSensor} {
+		for _, folder := range folders(class) {
+			address := utilities.ReadStringValue(folder, "address")
+			driver := utilities.ReadStringValue(folder, "driver_name")
+
+			devices[deviceKey{folder, driver}] = Event{
+				Attached: true,
+				Class:    class,
+				Port:     Platform.Current().PortName(address),
+				Driver:   driver,
+				Path:     folder,
+			}
+		}
+	}
+
+	return devices
+}
+
+// Calls `fn` whenever a motor or sensor is plugged in or out, scanning the
+// devices every `interval`, until a value is sent to `stop`. The devices
+// already connected are reported as attached first. Runs on the shared polling
+// scheduler, so `fn` must return promptly.
+func Watch(stop <-chan bool, interval time.Duration, fn func(e Event)) {
+	known := make(map[deviceKey]Event)
+
+	utilities.PollUntil(stop, interval, func() {
+		current := scan()
+
+		var events []Event
+		for key, e := range known {
+			if _, ok := current[key]; !ok {
+				e.Attached = false
+				events = append(events, e)
+			}
+		}
+		for key, e := range current {
+			if _, ok := known[key]; !ok {
+				events = append(events, e)
+			}
+		}
+		known = current
+
+		// Detached first, so that a device replaced on a port is seen leaving
+		// before its successor arrives.
+		sort.SliceStable(events, func(i int, j int) bool {
+			if events[i].Attached != events[j].Attached {
+				return !events[i].Attached
+			}
+			return events[i].Path < events[j].Path
+		})
+
+		for _, e := range events {
+			fn(e)
+		}
+	})
+}
@@ -0,0 +1,255 @@
+// Provides a server for the LEGO EV3 communication protocol, so the official
+// EV3 apps and third-party remote control apps can connect to a GoEV3 brick
+// over Bluetooth or Wi-Fi.
+//
+// The server understands the direct commands those apps use to run motors,
+// read sensors, play tones and set the status light, and the WRITEMAILBOX
+// system command, whose messages trigger the actions the program registers:
+//
+//	server := DirectCommand.NewServer()
+//	server.OnMailbox("grab", func(m Mailbox.Message) { gripper.Close() })
+//	go DirectCommand.Advertise("GoEV3", "0016535F2B1A", stop)
+//	go server.ListenAndServeTCP("")
+//	server.ListenAndServeBluetooth()
+//
+// Only a subset of the EV3 virtual machine's byte codes is supported; a
+// command containing any other byte code is answered with an error reply.
+package DirectCommand
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// Command and reply types.
+const (
+	directCommandReply   = 0x00
+	directCommandNoReply = 0x80
+	systemCommandReply   = 0x01
+	systemCommandNoReply = 0x81
+
+	directReply      = 0x02
+	directReplyError = 0x04
+	systemReply      = 0x03
+	systemReplyError = 0x05
+)
+
+// System command statuses.
+const (
+	statusSuccess      = 0x00
+	statusUnknownError = 0x0A
+)
+
+var errTruncated = errors.New("directcommand: truncated command")
+
+// An error caused by a byte code the server doesn't implement.
+type UnsupportedError struct {
+	Opcode byte
+}
+
+func (self *UnsupportedError) Error() string {
+	return fmt.Sprintf("directcommand: unsupported byte code 0x%02X", self.Opcode)
+}
+
+// A parameter of a byte code: a constant, a string or a reference to a
+// global or local variable.
+type param struct {
+	value    int32
+	text     string
+	isText   bool
+	variable bool
+	global   bool
+	index    int
+}
+
+// Reads byte codes and their parameters and holds the variables of one command.
+type program struct {
+	code    []byte
+	pc      int
+	globals []byte
+	locals  []byte
+}
+
+func (self *program) done() bool {
+	return self.pc >= len(self.code)
+}
+
+func (self *program) byte() (byte, error) {
+	if self.pc >= len(self.code) {
+		return 0, errTruncated
+	}
+
+	b := self.code[self.pc]
+	self.pc++
+
+	return b, nil
+}
+
+// Decodes a parameter in the EV3 "LC/LV/GV" encoding.
+func (self *program) param() (param, error) {
+	b, err := self.byte()
+	if err != nil {
+		return param{}, err
+	}
+
+	// Short format: a 6-bit constant or a variable index below 32.
+	if b&0x80 == 0 {
+		if b&0x40 == 0 {
+			v := int32(b & 0x3F)
+			if b&0x20 != 0 {
+				v -= 0x40
+			}
+			return param{value: v}, nil
+		}
+
+		return param{variable: true, global: b&0x20 != 0, index: int(b & 0x1F)}, nil
+	}
+
+	// Long format: the low bits give the number of bytes that follow.
+	if b&0x40 == 0 && b&0x07 == 0x04 {
+		start := self.pc
+		for self.pc < len(self.code) && self.code[self.pc] != 0 {
+			self.pc++
+		}
+		if self.pc >= len(self.code) {
+			return param{}, errTruncated
+		}
+		text := string(self.code[start:self.pc])
+		self.pc++
+
+		return param{text: text, isText: true}, nil
+	}
+
+	var size int
+	switch b & 0x07 {
+	case 1:
+		size = 1
+	case 2:
+		size = 2
+	case 3:
+		size = 4
+	default:
+		return param{}, fmt.Errorf("directcommand: invalid parameter encoding 0x%02X", b)
+	}
+
+	if self.pc+size > len(self.code) {
+		return param{}, errTruncated
+	}
+	data := self.code[self.pc : self.pc+size]
+	self.pc += size
+
+	var v int32
+	switch size {
+	case 1:
+		v = int32(int8(data[0]))
+	case 2:
+		v = int32(int16(binary.LittleEndian.Uint16(data)))
+	case 4:
+		v = int32(binary.LittleEndian.Uint32(data))
+	}
+
+	if b&0x40 != 0 {
+		return param{variable: true, global: b&0x20 != 0, index: int(v)}, nil
+	}
+
+	return param{value: v}, nil
+}
+
+// Reads an input parameter as an integer. Variables are read as 8-bit values.
+func (self *program) int() (int32, error) {
+	p, err := self.param()
+	if err != nil {
+		return 0, err
+	}
+
+	if p.variable {
+		memory := self.memory(p)
+		if p.index >= len(memory) {
+			return 0, fmt.Errorf("directcommand: variable %d out of range", p.index)
+		}
+		return int32(int8(memory[p.index])), nil
+	}
+
+	return p.value, nil
+}
+
+// Reads an input parameter as a string.
+func (self *program) text() (string, error) {
+	p, err := self.param()
+	if err != nil {
+		return "", err
+	}
+
+	if !p.isText {
+		return "", errors.New("directcommand: expected a string parameter")
+	}
+
+	return p.text, nil
+}
+
+// Reads an output parameter, which must refer to a variable.
+func (self *program) output() (param, error) {
+	p, err := self.param()
+	if err != nil {
+		return p, err
+	}
+
+	if !p.variable {
+		return p, errors.New("directcommand: expected a variable parameter")
+	}
+
+	return p, nil
+}
+
+func (self *program) memory(p param) []byte {
+	if p.global {
+		return self.globals
+	}
+
+	return self.locals
+}
+
+func (self *program) store(p param, data []byte) error {
+	memory := self.memory(p)
+	if p.index < 0 || p.index+len(data) > len(memory) {
+		return fmt.Errorf("directcommand: variable %d out of range", p.index)
+	}
+
+	copy(memory[p.index:], data)
+	return nil
+}
+
+func (self *program) storeInt8(p param, v int8) error {
+	return self.store(p, []byte{byte(v)})
+}
+
+func (self *program) storeInt32(p param, v int32) error {
+	return self.store(p, binary.LittleEndian.AppendUint32(nil, uint32(v)))
+}
+
+func (self *program) storeFloat(p param, v float32) error {
+	return self.store(p, binary.LittleEndian.AppendUint32(nil, math.Float32bits(v)))
+}
+
+// Stores a zero-terminated string of at most `length` bytes including the terminator.
+func (self *program) storeText(p param, text string, length int) error {
+	if length <= 0 {
+		return nil
+	}
+	if len(text) > length-1 {
+		text = text[:length-1]
+	}
+
+	return self.store(p, append([]byte(text), 0))
+}
+
+// Builds a length-prefixed reply.
+func reply(counter uint16, kind byte, body []byte) []byte {
+	frame := binary.LittleEndian.AppendUint16(nil, uint16(3+len(body)))
+	frame = binary.LittleEndian.AppendUint16(frame, counter)
+	frame = append(frame, kind)
+
+	return append(frame, body...)
+}
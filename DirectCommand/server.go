@@ -0,0 +1,1058 @@
+package DirectCommand
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jermon/GoEV3/Bluetooth"
+	"github.com/jermon/GoEV3/LED"
+	"github.com/jermon/GoEV3/Mailbox"
+	"github.com/jermon/GoEV3/Motor"
+	"github.com/jermon/GoEV3/Platform"
+	"github.com/jermon/GoEV3/Sound"
+	"github.com/jermon/GoEV3/utilities"
+)
+
+// Ports EV3 bricks use on Wi-Fi: TCP for commands, UDP for discovery beacons.
+const (
+	DefaultTCPPort = 5555
+	BeaconPort     = 3015
+)
+
+// Supported byte codes.
+const (
+	opNop           = 0x01
+	opUIWrite       = 0x82
+	opSound         = 0x94
+	opSoundReady    = 0x96
+	opInputDevice   = 0x99
+	opInputRead     = 0x9A
+	opInputReadSI   = 0x9D
+	opOutputReset   = 0xA2
+	opOutputStop    = 0xA3
+	opOutputPower   = 0xA4
+	opOutputSpeed   = 0xA5
+	opOutputStart   = 0xA6
+	opOutputPolar   = 0xA7
+	opOutputTest    = 0xA9
+	opOutputReady   = 0xAA
+	opOutputStepPow = 0xAC
+	opOutputTimePow = 0xAD
+	opOutputStepSpd = 0xAE
+	opOutputTimeSpd = 0xAF
+	opOutputClear   = 0xB2
+	opOutputCount   = 0xB3
+
+	writeMailbox = 0x9E
+)
+
+// Sub-commands.
+const (
+	uiLED = 0x1B
+
+	soundBreak = 0x00
+	soundTone  = 0x01
+
+	inputGetTypeMode = 0x05
+	inputClearAll    = 0x0A
+	inputGetName     = 0x15
+	inputReadySI     = 0x1D
+)
+
+// EV3 device type codes reported to apps.
+var typeCodes = map[string]int8{
+	"lego-ev3-l-motor": 7,
+	"lego-ev3-m-motor": 8,
+	"lego-ev3-touch":   16,
+	"lego-ev3-color":   29,
+	"lego-ev3-us":      30,
+	"lego-ev3-gyro":    32,
+	"lego-ev3-ir":      33,
+	"lego-nxt-light":   2,
+	"lego-nxt-sound":   3,
+	"lego-nxt-temp":    6,
+}
+
+// Type code of an empty port.
+const typeNone = 126
+
+const (
+	motorClassPath  = "/sys/class/tacho-motor"
+	sensorClassPath = "/sys/class/lego-sensor"
+)
+
+var outPorts = []Motor.OutPort{Motor.OutPortA, Motor.OutPortB, Motor.OutPortC, Motor.OutPortD}
+
+// Executes EV3 direct and system commands received from remote apps.
+type Server struct {
+	lock sync.Mutex
+
+	power    map[Motor.OutPort]int16
+	inverted map[Motor.OutPort]bool
+	running  map[Motor.OutPort]bool
+	moves    map[Motor.OutPort]*move
+	tone     chan bool
+
+	mailboxes map[string][]func(Mailbox.Message)
+}
+
+type move struct {
+	cancel chan bool
+	done   chan bool
+}
+
+// Creates a server. Motors and sensors are looked up when a command uses them.
+func NewServer() *Server {
+	s := new(Server)
+	s.power = make(map[Motor.OutPort]int16)
+	s.inverted = make(map[Motor.OutPort]bool)
+	s.running = make(map[Motor.OutPort]bool)
+	s.moves = make(map[Motor.OutPort]*move)
+	s.mailboxes = make(map[string][]func(Mailbox.Message))
+
+	return s
+}
+
+// Registers a callback invoked for every message an app writes to the given mailbox.
+func (self *Server) OnMailbox(mailbox string, fn func(Mailbox.Message)) {
+	self.lock.Lock()
+	self.mailboxes[mailbox] = append(self.mailboxes[mailbox], fn)
+	self.lock.Unlock()
+}
+
+// Serves commands read from `rw` until it fails or is closed.
+func (self *Server) ServeConn(rw io.ReadWriter) error {
+	return self.serve(rw, rw)
+}
+
+func (self *Server) serve(r io.Reader, w io.Writer) error {
+	header := make([]byte, 2)
+
+	for {
+		if _, err := io.ReadFull(r, header); err != nil {
+			return err
+		}
+
+		body := make([]byte, binary.LittleEndian.Uint16(header))
+		if _, err := io.ReadFull(r, body); err != nil {
+			return err
+		}
+
+		if response := self.Handle(body); response != nil {
+			if _, err := w.Write(response); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// Executes one command, given without its length prefix, and returns the
+// length-prefixed reply, or nil if the command doesn't ask for one.
+func (self *Server) Handle(body []byte) []byte {
+	if len(body) < 3 {
+		return nil
+	}
+
+	counter := binary.LittleEndian.Uint16(body)
+	kind := body[2]
+
+	switch kind {
+	case directCommandReply, directCommandNoReply:
+		if len(body) < 5 {
+			return nil
+		}
+
+		allocation := binary.LittleEndian.Uint16(body[3:])
+		prog := &program{
+			code:    body[5:],
+			globals: make([]byte, allocation&0x3FF),
+			locals:  make([]byte, allocation>>10),
+		}
+
+		err := self.execute(prog)
+		if kind == directCommandNoReply {
+			return nil
+		}
+		if err != nil {
+			return reply(counter, directReplyError, prog.globals)
+		}
+
+		return reply(counter, directReply, prog.globals)
+
+	case systemCommandReply, systemCommandNoReply:
+		if len(body) < 4 {
+			return nil
+		}
+
+		command := body[3]
+		status := byte(statusUnknownError)
+		if command == writeMailbox {
+			if m, err := Mailbox.Decode(append([]byte{body[0], body[1], systemCommandNoReply}, body[3:]...)); err == nil {
+				self.deliver(m)
+				status = statusSuccess
+			}
+		}
+
+		if kind == systemCommandNoReply {
+			return nil
+		}
+		if status != statusSuccess {
+			return reply(counter, systemReplyError, []byte{command, status})
+		}
+
+		return reply(counter, systemReply, []byte{command, status})
+	}
+
+	return nil
+}
+
+func (self *Server) deliver(m Mailbox.Message) {
+	self.lock.Lock()
+	handlers := append(([]func(Mailbox.Message))(nil), self.mailboxes[m.Mailbox]...)
+	self.lock.Unlock()
+
+	for _, fn := range handlers {
+		fn(m)
+	}
+}
+
+func (self *Server) execute(prog *program) error {
+	for !prog.done() {
+		op, err := prog.byte()
+		if err != nil {
+			return err
+		}
+
+		switch op {
+		case opNop:
+		case opUIWrite:
+			err = self.uiWrite(prog)
+		case opSound:
+			err = self.sound(prog)
+		case opSoundReady:
+			self.lock.Lock()
+			tone := self.tone
+			self.lock.Unlock()
+			if tone != nil {
+				<-tone
+			}
+		case opInputDevice:
+			err = self.inputDevice(prog)
+		case opInputRead, opInputReadSI:
+			err = self.inputRead(prog, op == opInputReadSI)
+		case opOutputReset, opOutputClear:
+			err = self.forPorts(prog, func(port Motor.OutPort, m *Motor.Motor) {
+				m.InitializePosition(0)
+			})
+		case opOutputStop:
+			err = self.outputStop(prog)
+		case opOutputPower, opOutputSpeed:
+			err = self.outputPower(prog)
+		case opOutputStart:
+			err = self.forPorts(prog, self.start)
+		case opOutputPolar:
+			err = self.outputPolarity(prog)
+		case opOutputTest:
+			err = self.outputTest(prog)
+		case opOutputReady:
+			err = self.outputReady(prog)
+		case opOutputStepPow, opOutputTimePow, opOutputStepSpd, opOutputTimeSpd:
+			err = self.outputProfile(prog, op == opOutputTimePow || op == opOutputTimeSpd)
+		case opOutputCount:
+			err = self.outputCount(prog)
+		default:
+			return &UnsupportedError{op}
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Reads the layer (brick in a daisy chain) parameter. Only layer 0, this brick, is served.
+func layer(prog *program) (bool, error) {
+	l, err := prog.int()
+	return l == 0, err
+}
+
+// Reads the layer and a bit field of output ports, and calls `fn` for every
+// connected motor among them.
+func (self *Server) forPorts(prog *program, fn func(port Motor.OutPort, m *Motor.Motor)) error {
+	ours, err := layer(prog)
+	if err != nil {
+		return err
+	}
+	nos, err := prog.int()
+	if err != nil {
+		return err
+	}
+
+	if ours {
+		for _, port := range ports(nos) {
+			if m := findMotor(port); m != nil {
+				fn(port, m)
+			}
+		}
+	}
+
+	return nil
+}
+
+func ports(nos int32) []Motor.OutPort {
+	var result []Motor.OutPort
+
+	for i, port := range outPorts {
+		if nos&(1<<uint(i)) != 0 {
+			result = append(result, port)
+		}
+	}
+
+	return result
+}
+
+func findMotor(port Motor.OutPort) *Motor.Motor {
+	if findDevice(motorClassPath, "out"+string(port)) == "" {
+		return nil
+	}
+
+	return Motor.FindMotor(port)
+}
+
+func (self *Server) signed(port Motor.OutPort, power int16) int16 {
+	if self.inverted[port] {
+		return -power
+	}
+
+	return power
+}
+
+func (self *Server) start(port Motor.OutPort, m *Motor.Motor) {
+	self.cancelMove(port)
+
+	self.lock.Lock()
+	power := self.signed(port, self.power[port])
+	self.running[port] = true
+	self.lock.Unlock()
+
+	m.Run(power)
+}
+
+func (self *Server) outputPower(prog *program) error {
+	ours, err := layer(prog)
+	if err != nil {
+		return err
+	}
+	nos, err := prog.int()
+	if err != nil {
+		return err
+	}
+	power, err := prog.int()
+	if err != nil {
+		return err
+	}
+
+	if !ours {
+		return nil
+	}
+
+	power = clamp(power, -100, 100)
+
+	for _, port := range ports(nos) {
+		self.lock.Lock()
+		self.power[port] = int16(power)
+		running := self.running[port]
+		signed := self.signed(port, int16(power))
+		self.lock.Unlock()
+
+		// The EV3 applies new power to running motors immediately.
+		if running {
+			if m := findMotor(port); m != nil {
+				m.Run(signed)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (self *Server) outputStop(prog *program) error {
+	ours, err := layer(prog)
+	if err != nil {
+		return err
+	}
+	nos, err := prog.int()
+	if err != nil {
+		return err
+	}
+	brake, err := prog.int()
+	if err != nil {
+		return err
+	}
+
+	if ours {
+		for _, port := range ports(nos) {
+			self.cancelMove(port)
+			if m := findMotor(port); m != nil {
+				halt(m, brake != 0)
+			}
+
+			self.lock.Lock()
+			self.running[port] = false
+			self.lock.Unlock()
+		}
+	}
+
+	return nil
+}
+
+func halt(m *Motor.Motor, brake bool) {
+	if brake {
+		m.EnableBrakeMode()
+	} else {
+		m.DisableBrakeMode()
+	}
+
+	m.Stop()
+}
+
+func (self *Server) outputPolarity(prog *program) error {
+	ours, err := layer(prog)
+	if err != nil {
+		return err
+	}
+	nos, err := prog.int()
+	if err != nil {
+		return err
+	}
+	polarity, err := prog.int()
+	if err != nil {
+		return err
+	}
+
+	if ours {
+		self.lock.Lock()
+		for _, port := range ports(nos) {
+			switch {
+			case polarity < 0:
+				self.inverted[port] = true
+			case polarity > 0:
+				self.inverted[port] = false
+			default:
+				self.inverted[port] = !self.inverted[port]
+			}
+		}
+		self.lock.Unlock()
+	}
+
+	return nil
+}
+
+func (self *Server) outputTest(prog *program) error {
+	ours, err := layer(prog)
+	if err != nil {
+		return err
+	}
+	nos, err := prog.int()
+	if err != nil {
+		return err
+	}
+	busy, err := prog.output()
+	if err != nil {
+		return err
+	}
+
+	result := int8(0)
+	if ours {
+		self.lock.Lock()
+		for _, port := range ports(nos) {
+			if self.running[port] || self.moves[port] != nil {
+				result = 1
+			}
+		}
+		self.lock.Unlock()
+	}
+
+	return prog.storeInt8(busy, result)
+}
+
+func (self *Server) outputReady(prog *program) error {
+	ours, err := layer(prog)
+	if err != nil {
+		return err
+	}
+	nos, err := prog.int()
+	if err != nil {
+		return err
+	}
+
+	if ours {
+		for _, port := range ports(nos) {
+			self.lock.Lock()
+			mv := self.moves[port]
+			self.lock.Unlock()
+
+			if mv != nil {
+				<-mv.done
+			}
+		}
+	}
+
+	return nil
+}
+
+// Handles the stepped and timed output commands: accelerate, run and
+// decelerate by the three given steps (degrees or milliseconds), then stop.
+// The steps are run at constant power.
+func (self *Server) outputProfile(prog *program, timed bool) error {
+	ours, err := layer(prog)
+	if err != nil {
+		return err
+	}
+
+	values := make([]int32, 6)
+	for i := range values {
+		if values[i], err = prog.int(); err != nil {
+			return err
+		}
+	}
+
+	nos, power := values[0], int16(clamp(values[1], -100, 100))
+	total := values[2] + values[3] + values[4]
+	brake := values[5] != 0
+
+	if !ours {
+		return nil
+	}
+
+	for _, port := range ports(nos) {
+		m := findMotor(port)
+		if m == nil {
+			continue
+		}
+
+		self.cancelMove(port)
+
+		mv := &move{make(chan bool), make(chan bool)}
+
+		self.lock.Lock()
+		self.moves[port] = mv
+		self.running[port] = false
+		signed := self.signed(port, power)
+		self.lock.Unlock()
+
+		go self.runMove(port, m, mv, signed, total, timed, brake)
+	}
+
+	return nil
+}
+
+func (self *Server) runMove(port Motor.OutPort, m *Motor.Motor, mv *move, power int16, total int32, timed bool, brake bool) {
+	defer close(mv.done)
+
+	start := m.CurrentPosition()
+	deadline := time.Now().Add(time.Duration(total) * time.Millisecond)
+
+	finished := func() bool {
+		if timed {
+			return !time.Now().Before(deadline)
+		}
+
+		moved := m.CurrentPosition() - start
+		if moved < 0 {
+			moved = -moved
+		}
+
+		return moved >= total
+	}
+
+	m.Run(power)
+
+	for !finished() {
+		select {
+		case <-mv.cancel:
+			return
+		case <-time.After(5 * time.Millisecond):
+		}
+	}
+
+	halt(m, brake)
+
+	self.lock.Lock()
+	if self.moves[port] == mv {
+		delete(self.moves, port)
+	}
+	self.lock.Unlock()
+}
+
+func (self *Server) cancelMove(port Motor.OutPort) {
+	self.lock.Lock()
+	mv := self.moves[port]
+	delete(self.moves, port)
+	self.lock.Unlock()
+
+	if mv != nil {
+		close(mv.cancel)
+		<-mv.done
+	}
+}
+
+func (self *Server) outputCount(prog *program) error {
+	ours, err := layer(prog)
+	if err != nil {
+		return err
+	}
+	no, err := prog.int()
+	if err != nil {
+		return err
+	}
+	tacho, err := prog.output()
+	if err != nil {
+		return err
+	}
+
+	count := int32(0)
+	if ours && no >= 0 && int(no) < len(outPorts) {
+		if m := findMotor(outPorts[no]); m != nil {
+			count = m.CurrentPosition()
+		}
+	}
+
+	return prog.storeInt32(tacho, count)
+}
+
+func (self *Server) sound(prog *program) error {
+	command, err := prog.int()
+	if err != nil {
+		return err
+	}
+
+	switch command {
+	case soundBreak:
+		Sound.PlayTone(0, 0)
+		return nil
+	case soundTone:
+		volume, err := prog.int()
+		if err != nil {
+			return err
+		}
+		frequency, err := prog.int()
+		if err != nil {
+			return err
+		}
+		duration, err := prog.int()
+		if err != nil {
+			return err
+		}
+
+		done := make(chan bool)
+		self.lock.Lock()
+		self.tone = done
+		self.lock.Unlock()
+
+		go func() {
+			defer close(done)
+			Sound.SetVolume(uint8(clamp(volume, 0, 100)))
+			Sound.PlayTone(uint32(clamp(frequency, 0, 20000)), uint64(clamp(duration, 0, 60000)))
+		}()
+
+		return nil
+	}
+
+	return &UnsupportedError{opSound}
+}
+
+func (self *Server) uiWrite(prog *program) error {
+	command, err := prog.int()
+	if err != nil {
+		return err
+	}
+	if command != uiLED {
+		return &UnsupportedError{opUIWrite}
+	}
+
+	pattern, err := prog.int()
+	if err != nil {
+		return err
+	}
+
+	// Patterns 1 to 9 are green, red and orange; steady, flashing and pulsing.
+	// Flashing and pulsing are shown steady.
+	for _, position := range []LED.Position{LED.Left, LED.Right} {
+		LED.TurnOff(LED.Amber, position)
+
+		if pattern > 0 {
+			switch (pattern - 1) % 3 {
+			case 0:
+				LED.TurnOn(LED.Green, position)
+			case 1:
+				LED.TurnOn(LED.Red, position)
+			case 2:
+				LED.TurnOn(LED.Amber, position)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (self *Server) inputDevice(prog *program) error {
+	command, err := prog.int()
+	if err != nil {
+		return err
+	}
+
+	switch command {
+	case inputClearAll:
+		_, err := layer(prog)
+		return err
+
+	case inputGetTypeMode:
+		ours, err := layer(prog)
+		if err != nil {
+			return err
+		}
+		no, err := prog.int()
+		if err != nil {
+			return err
+		}
+		typeVar, err := prog.output()
+		if err != nil {
+			return err
+		}
+		modeVar, err := prog.output()
+		if err != nil {
+			return err
+		}
+
+		t, mode := int8(typeNone), int8(0)
+		if ours {
+			t, mode = typeMode(no)
+		}
+		if err := prog.storeInt8(typeVar, t); err != nil {
+			return err
+		}
+		return prog.storeInt8(modeVar, mode)
+
+	case inputGetName:
+		ours, err := layer(prog)
+		if err != nil {
+			return err
+		}
+		no, err := prog.int()
+		if err != nil {
+			return err
+		}
+		length, err := prog.int()
+		if err != nil {
+			return err
+		}
+		name, err := prog.output()
+		if err != nil {
+			return err
+		}
+
+		text := ""
+		if folder := deviceFolder(no); ours && folder != "" {
+			text = utilities.ReadStringValue(folder, "driver_name")
+		}
+		return prog.storeText(name, text, int(length))
+
+	case inputReadySI:
+		ours, err := layer(prog)
+		if err != nil {
+			return err
+		}
+		no, err := prog.int()
+		if err != nil {
+			return err
+		}
+		if _, err := prog.int(); err != nil {
+			return err
+		}
+		mode, err := prog.int()
+		if err != nil {
+			return err
+		}
+		count, err := prog.int()
+		if err != nil {
+			return err
+		}
+
+		var values []float64
+		if ours {
+			values = readValues(no, mode)
+		}
+
+		for i := 0; i < int(count); i++ {
+			dest, err := prog.output()
+			if err != nil {
+				return err
+			}
+
+			v := 0.0
+			if i < len(values) {
+				v = values[i]
+			}
+			if err := prog.storeFloat(dest, float32(v)); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	return &UnsupportedError{opInputDevice}
+}
+
+// Handles opINPUT_READ, which yields a percentage, and opINPUT_READSI, which
+// yields a value in SI units.
+func (self *Server) inputRead(prog *program, si bool) error {
+	ours, err := layer(prog)
+	if err != nil {
+		return err
+	}
+	no, err := prog.int()
+	if err != nil {
+		return err
+	}
+	if _, err := prog.int(); err != nil {
+		return err
+	}
+	mode, err := prog.int()
+	if err != nil {
+		return err
+	}
+	dest, err := prog.output()
+	if err != nil {
+		return err
+	}
+
+	v := 0.0
+	if ours {
+		if values := readValues(no, mode); len(values) > 0 {
+			v = values[0]
+		}
+	}
+
+	if si {
+		return prog.storeFloat(dest, float32(v))
+	}
+
+	return prog.storeInt8(dest, int8(clamp(int32(v), -128, 127)))
+}
+
+// Returns the sysfs folder of the device numbered the EV3 way: 0 to 3 for the
+// input ports, 16 to 19 for the output ports.
+func deviceFolder(no int32) string {
+	switch {
+	case no >= 0 && no < 4:
+		return findDevice(sensorClassPath, fmt.Sprintf("in%d", no+1))
+	case no >= 16 && no < 20:
+		return findDevice(motorClassPath, "out"+string(outPorts[no-16]))
+	}
+
+	return ""
+}
+
+func typeMode(no int32) (int8, int8) {
+	folder := deviceFolder(no)
+	if folder == "" {
+		return typeNone, 0
+	}
+
+	t, ok := typeCodes[utilities.ReadStringValue(folder, "driver_name")]
+	if !ok {
+		t = typeNone
+	}
+
+	if no >= 16 {
+		return t, 0
+	}
+
+	current := utilities.ReadStringValue(folder, "mode")
+	for i, mode := range strings.Fields(utilities.ReadStringValue(folder, "modes")) {
+		if mode == current {
+			return t, int8(i)
+		}
+	}
+
+	return t, 0
+}
+
+// Reads the values of a device, switching a sensor to the mode with the given
+// index first unless it is -1. Motors report their position in degrees.
+func readValues(no int32, mode int32) []float64 {
+	folder := deviceFolder(no)
+	if folder == "" {
+		return nil
+	}
+
+	if no >= 16 {
+		position, err := utilities.ReadValue[float64](folder, "position")
+		if err != nil {
+			return nil
+		}
+		return []float64{position}
+	}
+
+	if mode >= 0 {
+		modes := strings.Fields(utilities.ReadStringValue(folder, "modes"))
+		if int(mode) < len(modes) && utilities.ReadStringValue(folder, "mode") != modes[mode] {
+			utilities.WriteStringValue(folder, "mode", modes[mode])
+		}
+	}
+
+	count, _ := utilities.ReadValue[int](folder, "num_values")
+	values := make([]float64, 0, count)
+
+	for i := 0; i < count; i++ {
+		v, err := utilities.ReadDecimalValue(folder, fmt.Sprintf("value%d", i))
+		if err != nil {
+			break
+		}
+		values = append(values, v)
+	}
+
+	return values
+}
+
+// Returns the folder of the device of the given class connected to `port`, or
+// an empty string if there is none.
+func findDevice(class string, port string) string {
+	for _, name := range utilities.ListDir(class) {
+		folder := path.Join(class, name)
+		if Platform.Current().Matches(port, utilities.ReadStringValue(folder, "address")) {
+			return folder
+		}
+	}
+
+	return ""
+}
+
+func clamp(v int32, min int32, max int32) int32 {
+	if v < min {
+		return min
+	}
+	if v > max {
+		return max
+	}
+
+	return v
+}
+
+// Accepts connections from paired devices on the EV3 serial port channel and
+// serves each of them until it is closed. Only returns if listening fails.
+func (self *Server) ListenAndServeBluetooth() error {
+	l, err := Bluetooth.Listen(Mailbox.BluetoothChannel)
+	if err != nil {
+		return err
+	}
+	defer l.Close()
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return err
+		}
+
+		go func() {
+			defer conn.Close()
+			self.ServeConn(conn)
+		}()
+	}
+}
+
+// Accepts Wi-Fi connections on `addr`, ":5555" if empty, and serves each of
+// them until it is closed. Clients first send an HTTP-like "GET /target"
+// request, which is accepted regardless of the serial number it names.
+func (self *Server) ListenAndServeTCP(addr string) error {
+	if addr == "" {
+		addr = fmt.Sprintf(":%d", DefaultTCPPort)
+	}
+
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	defer l.Close()
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return err
+		}
+
+		go func() {
+			defer conn.Close()
+
+			r := bufio.NewReader(conn)
+			if err := handshake(r, conn); err != nil {
+				return
+			}
+			self.serve(r, conn)
+		}()
+	}
+}
+
+func handshake(r *bufio.Reader, w io.Writer) error {
+	first := true
+
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return err
+		}
+
+		line = strings.TrimRight(line, "\r\n")
+		if first && !strings.HasPrefix(line, "GET /target") {
+			return fmt.Errorf("directcommand: unexpected handshake %q", line)
+		}
+		first = false
+
+		if line == "" {
+			break
+		}
+	}
+
+	_, err := io.WriteString(w, "Accept:EV340\r\n\r\n")
+	return err
+}
+
+// Broadcasts the UDP beacons by which apps discover bricks on the local
+// network, every second until a value is sent to `stop`. `serial` is the
+// 12-digit serial number apps display, typically the Bluetooth address
+// without colons.
+func Advertise(name string, serial string, stop <-chan bool) error {
+	conn, err := net.DialUDP("udp4", nil, &net.UDPAddr{IP: net.IPv4bcast, Port: BeaconPort})
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	beacon := fmt.Sprintf("Serial-Number: %s\r\nPort: %d\r\nName: %s\r\nProtocol: EV3\r\n", serial, DefaultTCPPort, name)
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		if _, err := conn.Write([]byte(beacon)); err != nil {
+			return err
+		}
+
+		select {
+		case <-stop:
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package Display
+
+import (
+	"image"
+	"image/draw"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/jermon/GoEV3/utilities"
+)
+
+// A scrolling strip chart of a value over time, one pixel column per value,
+// the latest on the right, for watching a sensor or a controller live:
+//
+//	chart := screen.NewChart(image.Rect(0, 64, 178, 128))
+//	chart.SetRange(0, 100)
+//	go chart.Watch(stop, 20*time.Millisecond, func() float64 { return follower.Reading() })
+//
+// Values can also be pushed, e.g. from an event handler. The range is fitted
+// to the values shown unless set with SetRange.
+type Chart struct {
+	lock sync.Mutex
+
+	screen *Screen
+	bounds image.Rectangle
+
+	values []float64
+	fixed  bool
+	min    float64
+	max    float64
+}
+
+// Creates a chart drawn within `bounds` of the screen.
+func (self *Screen) NewChart(bounds image.Rectangle) *Chart {
+	c := new(Chart)
+	c.screen = self
+	c.bounds = bounds.Intersect(self.Bounds())
+
+	return c
+}
+
+// Fixes the range of values shown, from `min` at the bottom to `max` at the
+// top. Values out of range are drawn at the edge.
+func (self *Chart) SetRange(min float64, max float64) {
+	self.lock.Lock()
+	self.fixed, self.min, self.max = true, min, max
+	self.lock.Unlock()
+}
+
+// Fits the range to the values shown again.
+func (self *Chart) AutoRange() {
+	self.lock.Lock()
+	self.fixed = false
+	self.lock.Unlock()
+}
+
+// Adds a value, scrolling the chart by one pixel.
+func (self *Chart) Push(value float64) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	// Inside the frame, one column per value.
+	columns := self.bounds.Dx() - 2
+	self.values = append(self.values, value)
+	if len(self.values) > columns {
+		self.values = self.values[len(self.values)-columns:]
+	}
+}
+
+// Returns the range of values shown.
+func (self *Chart) valueRange() (float64, float64) {
+	if self.fixed {
+		return self.min, self.max
+	}
+
+	min, max := math.Inf(1), math.Inf(-1)
+	for _, v := range self.values {
+		min, max = math.Min(min, v), math.Max(max, v)
+	}
+	if min > max {
+		return 0, 1
+	}
+	if min == max {
+		return min - 1, max + 1
+	}
+
+	return min, max
+}
+
+// Draws the chart on the screen's image, without flushing it.
+func (self *Chart) Draw() {
+	self.lock.Lock()
+	values := append([]float64(nil), self.values...)
+	min, max := self.valueRange()
+	self.lock.Unlock()
+
+	b := self.bounds
+	if b.Dx() < 3 || b.Dy() < 3 {
+		return
+	}
+
+	// Maps a value to a row inside the frame.
+	row := func(v float64) int {
+		f := (v - min) / (max - min)
+		f = math.Max(0, math.Min(1, f))
+		return b.Max.Y - 2 - int(math.Round(f*float64(b.Dy()-3)))
+	}
+
+	self.screen.Draw(func(img *image.Gray) {
+		draw.Draw(img, b, image.NewUniform(White), image.Point{}, draw.Src)
+
+		for x := b.Min.X; x < b.Max.X; x++ {
+			img.SetGray(x, b.Min.Y, Black)
+			img.SetGray(x, b.Max.Y-1, Black)
+		}
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			img.SetGray(b.Min.X, y, Black)
+			img.SetGray(b.Max.X-1, y, Black)
+		}
+
+		// Dotted zero line.
+		if min < 0 && max > 0 {
+			y := row(0)
+			for x := b.Min.X + 1; x < b.Max.X-1; x += 3 {
+				img.SetGray(x, y, Black)
+			}
+		}
+
+		// Each column joins its value to the previous one.
+		x := b.Max.X - 1 - len(values)
+		previous := -1
+		for _, v := range values {
+			y := row(v)
+			from, to := y, y
+			if previous >= 0 {
+				from, to = minInt(y, previous), maxInt(y, previous)
+			}
+			for yy := from; yy <= to; yy++ {
+				img.SetGray(x, yy, Black)
+			}
+			previous = y
+			x++
+		}
+
+		if len(values) > 0 {
+			drawText(img, b.Min.X+2, b.Min.Y+2, strconv.FormatFloat(values[len(values)-1], 'g', 4, 64))
+		}
+	})
+}
+
+// Calls `source` every `interval`, pushing its value and showing the chart,
+// until a value is sent to `stop`. Runs on the shared polling scheduler.
+func (self *Chart) Watch(stop <-chan bool, interval time.Duration, source func() float64) {
+	utilities.PollUntil(stop, interval, func() {
+		self.Push(source())
+		self.Draw()
+		self.screen.Flush()
+	})
+}
+
+func minInt(a int, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a int, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,48 @@
+package Display
+
+import (
+	"image"
+)
+
+// Glyphs of a 3 by 5 pixel font for numbers, each row's pixels in the low
+// three bits, the leftmost in the highest.
+var glyphs = map[rune][5]uint8{
+	'0': {7, 5, 5, 5, 7},
+	'1': {2, 6, 2, 2, 7},
+	'2': {7, 1, 7, 4, 7},
+	'3': {7, 1, 3, 1, 7},
+	'4': {5, 5, 7, 1, 1},
+	'5': {7, 4, 7, 1, 7},
+	'6': {7, 4, 7, 5, 7},
+	'7': {7, 1, 1, 2, 2},
+	'8': {7, 5, 7, 5, 7},
+	'9': {7, 5, 7, 1, 7},
+	'-': {0, 0, 7, 0, 0},
+	'.': {0, 0, 0, 0, 2},
+	'e': {0, 7, 7, 4, 7},
+	'+': {0, 2, 7, 2, 0},
+}
+
+// Draws `text` in black with the top left corner at (x, y), on a white
+// background that keeps it readable over a chart. Characters without a glyph
+// are left blank.
+func drawText(img *image.Gray, x int, y int, text string) {
+	for _, r := range text {
+		for dy := -1; dy <= 5; dy++ {
+			for dx := -1; dx <= 3; dx++ {
+				img.SetGray(x+dx, y+dy, White)
+			}
+		}
+
+		glyph := glyphs[r]
+		for dy, bits := range glyph {
+			for dx := 0; dx < 3; dx++ {
+				if bits&(4>>uint(dx)) != 0 {
+					img.SetGray(x+dx, y+dy, Black)
+				}
+			}
+		}
+
+		x += 4
+	}
+}
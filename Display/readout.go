@@ -0,0 +1,84 @@
+package Display
+
+import (
+	"image"
+	"image/draw"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/jermon/GoEV3/utilities"
+)
+
+// A list of labeled values, one per line, for a simple dashboard of sensor
+// readings:
+//
+//	readout := screen.NewReadout(screen.Bounds())
+//	readout.Add("light", func() float64 { return float64(color.ReadReflectedLightIntensity()) })
+//	readout.Add("angle", func() float64 { return float64(gyro.ReadAngle()) })
+//	go readout.Watch(stop, 100*time.Millisecond)
+type Readout struct {
+	lock sync.Mutex
+
+	screen *Screen
+	bounds image.Rectangle
+
+	labels  []string
+	sources []func() float64
+}
+
+// Creates a readout drawn within `bounds` of the screen.
+func (self *Screen) NewReadout(bounds image.Rectangle) *Readout {
+	r := new(Readout)
+	r.screen = self
+	r.bounds = bounds.Intersect(self.Bounds())
+
+	return r
+}
+
+// Adds a line showing `label` and the value `source` returns. Lines beyond
+// the bounds aren't shown.
+func (self *Readout) Add(label string, source func() float64) {
+	self.lock.Lock()
+	self.labels = append(self.labels, label)
+	self.sources = append(self.sources, source)
+	self.lock.Unlock()
+}
+
+// Reads the values and draws the readout on the screen's image, without
+// flushing it. Labels are left-aligned and values right-aligned.
+func (self *Readout) Draw() {
+	self.lock.Lock()
+	labels := append([]string(nil), self.labels...)
+	sources := append([]func() float64(nil), self.sources...)
+	self.lock.Unlock()
+
+	values := make([]string, len(sources))
+	for i, source := range sources {
+		values[i] = strconv.FormatFloat(source(), 'g', 4, 64)
+	}
+
+	b := self.bounds
+	self.screen.Draw(func(img *image.Gray) {
+		draw.Draw(img, b, image.NewUniform(White), image.Point{}, draw.Src)
+
+		for i := range labels {
+			y := b.Min.Y + i*CharHeight
+			if y+CharHeight > b.Max.Y {
+				break
+			}
+
+			DrawText(img, b.Min.X, y, labels[i], Black)
+			DrawText(img, b.Max.X-TextSize(values[i]).X, y, values[i], Black)
+		}
+	})
+}
+
+// Draws the readout and shows it every `interval`, until a value is sent to
+// `stop`. Runs on the shared polling scheduler.
+func (self *Readout) Watch(stop <-chan bool, interval time.Duration) {
+	utilities.PollUntil(stop, interval, func() {
+		self.Draw()
+		self.screen.Flush()
+	})
+}
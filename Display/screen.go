@@ -0,0 +1,151 @@
+// Provides drawing on the brick's LCD through the Linux framebuffer.
+//
+// Drawing goes to an in-memory grayscale image, which Flush copies to the
+// screen in the framebuffer's pixel format:
+//
+//	screen := Display.FindScreen()
+//	chart := screen.NewChart(screen.Bounds())
+//	go chart.Watch(stop, 50*time.Millisecond, func() float64 {
+//		return float64(color.ReadReflectedLightIntensity())
+//	})
+package Display
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/jermon/GoEV3/utilities"
+)
+
+const (
+	framebufferDevice = "/dev/fb0"
+	framebufferClass  = "/sys/class/graphics/fb0"
+)
+
+// Colors of the monochrome LCD.
+var (
+	Black = color.Gray{Y: 0}
+	White = color.Gray{Y: 255}
+)
+
+// The brick's LCD.
+type Screen struct {
+	lock sync.Mutex
+
+	bitsPerPixel int
+	stride       int
+	image        *image.Gray
+}
+
+// Provides access to the LCD. A missing framebuffer is a fatal error; use
+// OpenScreen to handle it.
+func FindScreen() *Screen {
+	s, err := OpenScreen()
+	if err != nil {
+		utilities.Fatal(err)
+	}
+
+	return s
+}
+
+// Provides access to the LCD, reading the framebuffer's size and format.
+func OpenScreen() (*Screen, error) {
+	if !utilities.Exists(framebufferClass) {
+		return nil, fmt.Errorf("display: no framebuffer at %s", framebufferClass)
+	}
+
+	var width, height int
+	size := strings.Split(utilities.ReadStringValue(framebufferClass, "virtual_size"), ",")
+	if len(size) == 2 {
+		width, _ = strconv.Atoi(size[0])
+		height, _ = strconv.Atoi(size[1])
+	}
+
+	s := new(Screen)
+	s.bitsPerPixel, _ = strconv.Atoi(utilities.ReadStringValue(framebufferClass, "bits_per_pixel"))
+	s.stride, _ = strconv.Atoi(utilities.ReadStringValue(framebufferClass, "stride"))
+
+	switch s.bitsPerPixel {
+	case 1, 8, 16, 32:
+	default:
+		return nil, fmt.Errorf("display: unsupported framebuffer depth %d", s.bitsPerPixel)
+	}
+	if width <= 0 || height <= 0 {
+		return nil, fmt.Errorf("display: invalid framebuffer size %v", size)
+	}
+	if s.stride <= 0 {
+		s.stride = (width*s.bitsPerPixel + 7) / 8
+	}
+
+	s.image = image.NewGray(image.Rect(0, 0, width, height))
+	s.Clear()
+
+	return s, nil
+}
+
+// Returns the screen's bounds, 178 by 128 pixels on the EV3.
+func (self *Screen) Bounds() image.Rectangle {
+	return self.image.Bounds()
+}
+
+// Calls `fn` to draw on the image shown by Flush. Drawing from several
+// goroutines, e.g. several widgets, is serialized.
+func (self *Screen) Draw(fn func(img *image.Gray)) {
+	self.lock.Lock()
+	fn(self.image)
+	self.lock.Unlock()
+}
+
+// Fills the image with white.
+func (self *Screen) Clear() {
+	self.lock.Lock()
+	draw.Draw(self.image, self.image.Bounds(), image.NewUniform(White), image.Point{}, draw.Src)
+	self.lock.Unlock()
+}
+
+// Shows the image on the screen.
+func (self *Screen) Flush() error {
+	self.lock.Lock()
+	data := self.encode()
+	self.lock.Unlock()
+
+	return utilities.CurrentBackend().WriteFile(framebufferDevice, data)
+}
+
+// Converts the image to the framebuffer's pixel format.
+func (self *Screen) encode() []byte {
+	b := self.image.Bounds()
+	data := make([]byte, self.stride*b.Dy())
+
+	for y := 0; y < b.Dy(); y++ {
+		row := data[y*self.stride:]
+
+		for x := 0; x < b.Dx(); x++ {
+			gray := self.image.Pix[y*self.image.Stride+x]
+
+			switch self.bitsPerPixel {
+			case 1:
+				// Set bits are black, the leftmost pixel in the lowest bit.
+				if gray < 128 {
+					row[x/8] |= 1 << uint(x%8)
+				}
+			case 8:
+				row[x] = gray
+			case 16:
+				// RGB565, little endian.
+				v := uint16(gray>>3)<<11 | uint16(gray>>2)<<5 | uint16(gray>>3)
+				row[2*x], row[2*x+1] = byte(v), byte(v>>8)
+			case 32:
+				// XRGB, little endian.
+				row[4*x], row[4*x+1], row[4*x+2] = gray, gray, gray
+			}
+		}
+	}
+
+	return data
+}
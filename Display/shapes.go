@@ -0,0 +1,94 @@
+package Display
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+)
+
+// Draws a line from `p0` to `p1`, both ends included, in color `c`.
+func DrawLine(img *image.Gray, p0 image.Point, p1 image.Point, c color.Gray) {
+	// Bresenham's algorithm, for all octants.
+	dx, dy := abs(p1.X-p0.X), -abs(p1.Y-p0.Y)
+	sx, sy := 1, 1
+	if p0.X > p1.X {
+		sx = -1
+	}
+	if p0.Y > p1.Y {
+		sy = -1
+	}
+
+	x, y := p0.X, p0.Y
+	err := dx + dy
+	for {
+		img.SetGray(x, y, c)
+		if x == p1.X && y == p1.Y {
+			return
+		}
+
+		e2 := 2 * err
+		if e2 >= dy {
+			err += dy
+			x += sx
+		}
+		if e2 <= dx {
+			err += dx
+			y += sy
+		}
+	}
+}
+
+// Draws the outline of `r` in color `c`, or fills it if `fill` is true.
+func DrawRectangle(img *image.Gray, r image.Rectangle, c color.Gray, fill bool) {
+	r = r.Canon()
+	if r.Empty() {
+		return
+	}
+
+	if fill {
+		draw.Draw(img, r, image.NewUniform(c), image.Point{}, draw.Src)
+		return
+	}
+
+	right, bottom := r.Max.X-1, r.Max.Y-1
+	DrawLine(img, r.Min, image.Pt(right, r.Min.Y), c)
+	DrawLine(img, image.Pt(r.Min.X, bottom), image.Pt(right, bottom), c)
+	DrawLine(img, r.Min, image.Pt(r.Min.X, bottom), c)
+	DrawLine(img, image.Pt(right, r.Min.Y), image.Pt(right, bottom), c)
+}
+
+// Draws the outline of the circle around `center` of `radius` pixels in color
+// `c`, or fills it if `fill` is true.
+func DrawCircle(img *image.Gray, center image.Point, radius int, c color.Gray, fill bool) {
+	// The midpoint algorithm, drawing the eight symmetric octants at once.
+	x, y := radius, 0
+	err := 1 - radius
+
+	for x >= y {
+		if fill {
+			DrawLine(img, image.Pt(center.X-x, center.Y+y), image.Pt(center.X+x, center.Y+y), c)
+			DrawLine(img, image.Pt(center.X-x, center.Y-y), image.Pt(center.X+x, center.Y-y), c)
+			DrawLine(img, image.Pt(center.X-y, center.Y+x), image.Pt(center.X+y, center.Y+x), c)
+			DrawLine(img, image.Pt(center.X-y, center.Y-x), image.Pt(center.X+y, center.Y-x), c)
+		} else {
+			for _, p := range [8]image.Point{{x, y}, {y, x}, {-y, x}, {-x, y}, {-x, -y}, {-y, -x}, {y, -x}, {x, -y}} {
+				img.SetGray(center.X+p.X, center.Y+p.Y, c)
+			}
+		}
+
+		y++
+		if err < 0 {
+			err += 2*y + 1
+		} else {
+			x--
+			err += 2*(y-x) + 1
+		}
+	}
+}
+
+func abs(a int) int {
+	if a < 0 {
+		return -a
+	}
+	return a
+}
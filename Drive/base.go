@@ -0,0 +1,212 @@
+// Provides APIs for driving differential-drive (two-wheeled) robots.
+package Drive
+
+import (
+	"errors"
+	"math"
+	"sync"
+
+	"github.com/jermon/GoEV3/Errors"
+	"github.com/jermon/GoEV3/Motor"
+	"github.com/jermon/GoEV3/Sensors"
+	"github.com/jermon/GoEV3/Units"
+	"github.com/jermon/GoEV3/utilities"
+)
+
+// Speed of an EV3 large motor at 100% duty cycle, in degrees per second.
+const largeMotorMaxSpeed = 1050
+
+// Two motors driving the left and right wheels of a robot.
+type DriveBase struct {
+	lock sync.Mutex
+
+	left  *Motor.Motor
+	right *Motor.Motor
+
+	wheelDiameter float64
+	trackWidth    float64
+
+	// Acceleration limit and wheel speeds being ramped between; see slew.go.
+	acceleration float64
+	target       [2]float64
+	commanded    [2]float64
+	slew         *utilities.PollTask
+
+	// Closed to cancel the timed or positioned move in progress; see moves.go.
+	motionLock   sync.Mutex
+	motionCancel chan bool
+	motionDone   chan bool
+
+	// Gyro closing the loop of DriveStraight and TurnDegrees; see gyro.go.
+	gyro *Sensors.GyroSensor
+}
+
+// Provides access to a drive base with motors at the given ports.
+// `wheelDiameter` and `trackWidth` (the distance between the wheels' contact
+// points) are measured in centimeters.
+func NewDriveBase(left Motor.OutPort, right Motor.OutPort, wheelDiameter float64, trackWidth float64) *DriveBase {
+	d := new(DriveBase)
+	d.left = Motor.FindMotor(left)
+	d.right = Motor.FindMotor(right)
+	d.wheelDiameter = wheelDiameter
+	d.trackWidth = trackWidth
+
+	return d
+}
+
+// Returns the left wheel motor.
+func (self *DriveBase) Left() *Motor.Motor {
+	return self.left
+}
+
+// Returns the right wheel motor.
+func (self *DriveBase) Right() *Motor.Motor {
+	return self.right
+}
+
+// Returns the wheel diameter in centimeters.
+func (self *DriveBase) WheelDiameter() float64 {
+	return self.wheelDiameter
+}
+
+// Returns the distance between the wheels in centimeters.
+func (self *DriveBase) TrackWidth() float64 {
+	return self.trackWidth
+}
+
+// Runs the left and right motors at the given speeds, as passed to Motor.Run.
+// See SetAccelerationLimit for ramping between speeds.
+func (self *DriveBase) Tank(leftSpeed int16, rightSpeed int16) {
+	self.cancelMotion()
+
+	self.lock.Lock()
+	self.setTarget(leftSpeed, rightSpeed)
+	self.lock.Unlock()
+}
+
+func (self *DriveBase) run(leftSpeed int16, rightSpeed int16) {
+	self.lock.Lock()
+	self.cancelSlew()
+	self.target = [2]float64{float64(leftSpeed), float64(rightSpeed)}
+	self.runLocked(leftSpeed, rightSpeed)
+	self.lock.Unlock()
+}
+
+func (self *DriveBase) runLocked(leftSpeed int16, rightSpeed int16) {
+	// Started together, so that the robot doesn't swerve as it sets off.
+	err := Motor.RunTogether([]*Motor.Motor{self.left, self.right}, []int16{leftSpeed, rightSpeed})
+	if errors.Is(err, Errors.ErrOutOfRange) {
+		utilities.Fatal(err)
+	}
+	self.commanded = [2]float64{float64(leftSpeed), float64(rightSpeed)}
+}
+
+// Returns the wheel speeds for driving at `speed` with the given steering in
+// range [-100, 100]. Negative values turn left, 0 drives straight, 50 stops
+// the right wheel and 100 spins in place.
+func SteeringSpeeds(steering float64, speed int16) (int16, int16) {
+	steering = math.Max(-100, math.Min(100, steering))
+	inner := float64(speed) * (50 - math.Abs(steering)) / 50
+
+	if steering > 0 {
+		return speed, int16(math.Round(inner))
+	}
+
+	return int16(math.Round(inner)), speed
+}
+
+// Drives at `speed` while turning according to `steering`, see SteeringSpeeds.
+func (self *DriveBase) Steer(steering float64, speed int16) {
+	leftSpeed, rightSpeed := SteeringSpeeds(steering, speed)
+	self.Tank(leftSpeed, rightSpeed)
+}
+
+// Runs the left and right motors at the given speeds, whatever their regulation mode.
+func (self *DriveBase) TankAt(leftSpeed Motor.Speed, rightSpeed Motor.Speed) {
+	self.Tank(self.left.RunValue(leftSpeed), self.right.RunValue(rightSpeed))
+}
+
+// Drives at `speed` while turning according to `steering`, see SteeringSpeeds.
+func (self *DriveBase) SteerAt(steering float64, speed Motor.Speed) {
+	steering = math.Max(-100, math.Min(100, steering))
+	inner := speed.Scaled((50 - math.Abs(steering)) / 50)
+
+	if steering > 0 {
+		self.TankAt(speed, inner)
+	} else {
+		self.TankAt(inner, speed)
+	}
+}
+
+// Stops both motors, at once whatever the acceleration limit.
+func (self *DriveBase) Stop() {
+	self.cancelMotion()
+
+	self.lock.Lock()
+	self.cancelSlew()
+	self.target, self.commanded = [2]float64{}, [2]float64{}
+	Motor.StopTogether(self.left, self.right)
+	self.lock.Unlock()
+}
+
+// Converts a distance traveled by a wheel, in centimeters, to motor degrees.
+func (self *DriveBase) DistanceToDegrees(distance float64) float64 {
+	return distance / (math.Pi * self.wheelDiameter) * 360
+}
+
+// Converts motor degrees to the distance traveled by a wheel, in centimeters.
+func (self *DriveBase) DegreesToDistance(degrees float64) float64 {
+	return degrees / 360 * math.Pi * self.wheelDiameter
+}
+
+// Returns the distance (in centimeters) traveled by the left and right wheels
+// since their positions were last reset.
+func (self *DriveBase) WheelDistances() (float64, float64) {
+	return self.DegreesToDistance(self.left.CurrentDegrees()),
+		self.DegreesToDistance(self.right.CurrentDegrees())
+}
+
+// Returns the distance (in centimeters) traveled by the left and right wheels
+// as counted by Motor.Travel, which setting the motor positions doesn't
+// change and which doesn't wrap around.
+func (self *DriveBase) WheelTravel() (float64, float64) {
+	return self.DegreesToDistance(self.left.TravelDegrees()),
+		self.DegreesToDistance(self.right.TravelDegrees())
+}
+
+// Returns the average distance traveled by both wheels, in centimeters.
+func (self *DriveBase) Distance() float64 {
+	l, r := self.WheelDistances()
+	return (l + r) / 2
+}
+
+// Returns the average distance traveled by both wheels.
+func (self *DriveBase) Traveled() Units.Distance {
+	return Units.Distance(self.Distance()) * Units.Centimeter
+}
+
+// Returns the rotation of the robot since the wheel positions were last reset,
+// measured with the wheel encoders, counter-clockwise being positive.
+func (self *DriveBase) Rotation() Units.Angle {
+	l, r := self.WheelDistances()
+	return Units.Angle((r-l)/self.trackWidth) * Units.Radian
+}
+
+// Drives `linear` forward and turns by `angular` every second. See SetVelocity.
+func (self *DriveBase) Move(linear Units.Distance, angular Units.AngularSpeed) {
+	self.SetVelocity(linear.Centimeters(), angular.DegreesPerSecond())
+}
+
+// Drives with the given linear (centimeters per second) and angular (degrees per
+// second, counter-clockwise being positive) velocity. Speeds are converted to duty
+// cycles assuming EV3 large motors with regulation mode off.
+func (self *DriveBase) SetVelocity(linear float64, angular float64) {
+	delta := angular * math.Pi / 180 * self.trackWidth / 2
+
+	toDuty := func(v float64) int16 {
+		duty := self.DistanceToDegrees(v) / largeMotorMaxSpeed * 100
+		return int16(math.Round(math.Max(-100, math.Min(100, duty))))
+	}
+
+	self.Tank(toDuty(linear-delta), toDuty(linear+delta))
+}
@@ -0,0 +1,121 @@
+package Drive
+
+import (
+	"math"
+	"time"
+
+	"github.com/jermon/GoEV3/Control"
+	"github.com/jermon/GoEV3/Sensors"
+	"github.com/jermon/GoEV3/Units"
+)
+
+// Interval between the heading reads of DriveStraight and TurnDegrees.
+const gyroInterval = 10 * time.Millisecond
+
+// Heading error, in degrees, within which TurnDegrees is done.
+const turnTolerance = 1
+
+// Lowest wheel speed of TurnDegrees, enough to overcome friction near the end.
+const minTurnSpeed = 8
+
+// Makes DriveStraight and TurnDegrees steer by the heading `gyro` measures,
+// which unlike the wheel encoders doesn't drift as the wheels slip. Without
+// a gyro, or after passing nil, they steer by the encoders.
+func (self *DriveBase) UseGyro(gyro *Sensors.GyroSensor) {
+	self.lock.Lock()
+	self.gyro = gyro
+	self.lock.Unlock()
+}
+
+// Returns the robot's heading, counter-clockwise being positive, from the gyro
+// if one is used, or from the wheel encoders.
+func (self *DriveBase) heading() Units.Angle {
+	self.lock.Lock()
+	gyro := self.gyro
+	self.lock.Unlock()
+
+	if gyro == nil {
+		return self.Rotation()
+	}
+
+	// The gyro counts clockwise.
+	return -gyro.Angle()
+}
+
+// Drives straight by `distance` centimeters at `speed`, backwards if the
+// distance is negative, steering back onto the heading it started on
+// whenever the robot veers. Returns once done, braking at the end.
+func (self *DriveBase) DriveStraight(distance float64, speed int16) {
+	self.cancelMotion()
+
+	speed = int16(math.Abs(float64(speed)))
+	if distance < 0 {
+		speed = -speed
+	}
+
+	target := self.heading()
+	start := self.Distance()
+
+	pid := Control.NewPID(2, 0, 0.1)
+	pid.SetOutputLimits(-100, 100)
+
+	ticker := time.NewTicker(gyroInterval)
+	defer ticker.Stop()
+
+	for math.Abs(self.Distance()-start) < math.Abs(distance) {
+		// A positive error needs a left turn, which is negative steering,
+		// mirrored when driving backwards.
+		steering := pid.Update(-(target - self.heading()).Normalized().Degrees())
+		if speed >= 0 {
+			steering = -steering
+		}
+
+		self.Tank(SteeringSpeeds(steering, speed))
+		<-ticker.C
+	}
+
+	self.brake()
+}
+
+// Turns in place by `angle` degrees, counter-clockwise if positive, with the
+// wheels at up to `speed`, slowing down as the heading nears the target.
+// Returns once within a degree of it, braking at the end.
+func (self *DriveBase) TurnDegrees(angle float64, speed int16) {
+	self.cancelMotion()
+
+	target := self.heading() + Units.Angle(angle)*Units.Degree
+	max := math.Abs(float64(speed))
+
+	// Turning speed per degree left to turn.
+	pid := Control.NewPID(1.5, 0, 0)
+	pid.SetOutputLimits(-max, max)
+
+	ticker := time.NewTicker(gyroInterval)
+	defer ticker.Stop()
+
+	for {
+		// Not normalized, so that turns of more than half a revolution go all the way.
+		remaining := (target - self.heading()).Degrees()
+		if math.Abs(remaining) <= turnTolerance {
+			break
+		}
+
+		s := pid.Update(-remaining)
+		if math.Abs(s) < minTurnSpeed {
+			s = math.Copysign(math.Min(minTurnSpeed, max), s)
+		}
+
+		// Counter-clockwise runs the right wheel forward.
+		wheel := int16(math.Round(s))
+		self.Tank(-wheel, wheel)
+		<-ticker.C
+	}
+
+	self.brake()
+}
+
+// Stops both wheels, braking.
+func (self *DriveBase) brake() {
+	self.halt(self.left, true)
+	self.halt(self.right, true)
+}
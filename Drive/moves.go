@@ -0,0 +1,302 @@
+package Drive
+
+import (
+	"math"
+	"time"
+
+	"github.com/jermon/GoEV3/Motor"
+)
+
+// How often running moves check the motor positions and stop conditions.
+const motionInterval = 5 * time.Millisecond
+
+// The methods below mirror the MoveTank and MoveSteering classes of ev3dev2
+// (python-ev3dev2 and EV3 MicroPython): On/Off, OnForDegrees, OnForRotations,
+// OnForSeconds and their steering counterparts. Speeds are passed as to
+// Motor.Run. `brake` selects braking instead of coasting at the end of the
+// move, and moves started with `block` set to false run in the background
+// until they complete, WaitUntilNotMoving returns, or another command is given.
+
+// Runs the motors at the given speeds until another command is given. Same as Tank.
+func (self *DriveBase) On(leftSpeed int16, rightSpeed int16) {
+	self.Tank(leftSpeed, rightSpeed)
+}
+
+// Stops both motors, braking or coasting.
+func (self *DriveBase) Off(brake bool) {
+	self.cancelMotion()
+	self.halt(self.left, brake)
+	self.halt(self.right, brake)
+}
+
+// Runs the motors until the faster one has turned by `degrees`; the slower one
+// turns proportionally less.
+func (self *DriveBase) OnForDegrees(leftSpeed int16, rightSpeed int16, degrees float64, brake bool, block bool) {
+	self.begin(block, func(cancel <-chan bool) {
+		self.runForDegrees(leftSpeed, rightSpeed, degrees, brake, cancel)
+	})
+}
+
+// Runs the motors until the faster one has turned by `rotations` full turns.
+func (self *DriveBase) OnForRotations(leftSpeed int16, rightSpeed int16, rotations float64, brake bool, block bool) {
+	self.OnForDegrees(leftSpeed, rightSpeed, rotations*360, brake, block)
+}
+
+// Runs the motors for the given duration.
+func (self *DriveBase) OnForSeconds(leftSpeed int16, rightSpeed int16, seconds float64, brake bool, block bool) {
+	self.begin(block, func(cancel <-chan bool) {
+		self.run(leftSpeed, rightSpeed)
+
+		select {
+		case <-cancel:
+			return
+		case <-time.After(time.Duration(seconds * float64(time.Second))):
+		}
+
+		self.halt(self.left, brake)
+		self.halt(self.right, brake)
+	})
+}
+
+// Runs the motors until `until` returns true, checking it every few milliseconds.
+func (self *DriveBase) OnUntil(leftSpeed int16, rightSpeed int16, until func() bool, brake bool, block bool) {
+	self.begin(block, func(cancel <-chan bool) {
+		self.run(leftSpeed, rightSpeed)
+
+		ticker := time.NewTicker(motionInterval)
+		defer ticker.Stop()
+
+		for !until() {
+			select {
+			case <-cancel:
+				return
+			case <-ticker.C:
+			}
+		}
+
+		self.halt(self.left, brake)
+		self.halt(self.right, brake)
+	})
+}
+
+// Drives straight until the wheels have traveled `distance` centimeters.
+func (self *DriveBase) OnForDistance(speed int16, distance float64, brake bool, block bool) {
+	self.OnForDegrees(speed, speed, self.DistanceToDegrees(distance), brake, block)
+}
+
+// Drives along a circular arc of `radius` centimeters, measured at the middle
+// of the robot, until its heading has turned by `angle` degrees,
+// counter-clockwise (to the left) if positive. The faster, outer wheel runs at
+// `speed`; a negative speed drives the arc backwards. A radius of 0 turns in
+// place, and a radius of half the track width pivots on the inner wheel.
+// Returns once the arc is complete, braking at its end.
+func (self *DriveBase) DriveArc(radius float64, angle float64, speed int16) {
+	left, right := self.ArcDistances(radius, angle)
+
+	fastest := math.Max(math.Abs(left), math.Abs(right))
+	if fastest == 0 {
+		return
+	}
+
+	l := int16(math.Round(float64(speed) * left / fastest))
+	r := int16(math.Round(float64(speed) * right / fastest))
+	self.OnForDegrees(l, r, self.DistanceToDegrees(fastest), true, true)
+}
+
+// Returns the distances in centimeters the left and right wheels travel along
+// an arc of `radius` centimeters turning the heading by `angle` degrees, as
+// DriveArc drives it.
+func (self *DriveBase) ArcDistances(radius float64, angle float64) (float64, float64) {
+	theta := angle * math.Pi / 180
+	center := math.Abs(radius * theta)
+	half := self.trackWidth / 2
+
+	return center - half*theta, center + half*theta
+}
+
+// Drives straight ahead at `speed` until another command is given.
+func (self *DriveBase) DriveForward(speed int16) {
+	self.Tank(speed, speed)
+}
+
+// Drives straight backwards at `speed` until another command is given.
+func (self *DriveBase) DriveBackward(speed int16) {
+	self.Tank(-speed, -speed)
+}
+
+// Turns in place to the left, counter-clockwise, by `degrees` of heading,
+// with the wheels at `speed`. Returns once done, braking at the end.
+func (self *DriveBase) RotateLeft(degrees float64, speed int16) {
+	self.DriveArc(0, degrees, speed)
+}
+
+// Turns in place to the right, clockwise, by `degrees` of heading, like RotateLeft.
+func (self *DriveBase) RotateRight(degrees float64, speed int16) {
+	self.DriveArc(0, -degrees, speed)
+}
+
+// Drives straight by `distance` centimeters at `speed`, backwards if the
+// distance is negative. Returns once done, braking at the end.
+func (self *DriveBase) TravelDistance(distance float64, speed int16) {
+	self.OnForDistance(speed, distance, true, true)
+}
+
+// Steering counterpart of On; see SteeringSpeeds.
+func (self *DriveBase) OnSteering(steering float64, speed int16) {
+	self.Steer(steering, speed)
+}
+
+// Steering counterpart of OnForDegrees.
+func (self *DriveBase) SteerForDegrees(steering float64, speed int16, degrees float64, brake bool, block bool) {
+	l, r := SteeringSpeeds(steering, speed)
+	self.OnForDegrees(l, r, degrees, brake, block)
+}
+
+// Steering counterpart of OnForRotations.
+func (self *DriveBase) SteerForRotations(steering float64, speed int16, rotations float64, brake bool, block bool) {
+	l, r := SteeringSpeeds(steering, speed)
+	self.OnForRotations(l, r, rotations, brake, block)
+}
+
+// Steering counterpart of OnForSeconds.
+func (self *DriveBase) SteerForSeconds(steering float64, speed int16, seconds float64, brake bool, block bool) {
+	l, r := SteeringSpeeds(steering, speed)
+	self.OnForSeconds(l, r, seconds, brake, block)
+}
+
+// Steering counterpart of OnUntil.
+func (self *DriveBase) SteerUntil(steering float64, speed int16, until func() bool, brake bool, block bool) {
+	l, r := SteeringSpeeds(steering, speed)
+	self.OnUntil(l, r, until, brake, block)
+}
+
+// Reports whether a move started without blocking is still running.
+func (self *DriveBase) IsMoving() bool {
+	self.motionLock.Lock()
+	done := self.motionDone
+	self.motionLock.Unlock()
+
+	if done == nil {
+		return false
+	}
+
+	select {
+	case <-done:
+		return false
+	default:
+		return true
+	}
+}
+
+// Waits for the move in progress, if any, to complete.
+func (self *DriveBase) WaitUntilNotMoving() {
+	self.motionLock.Lock()
+	done := self.motionDone
+	self.motionLock.Unlock()
+
+	if done != nil {
+		<-done
+	}
+}
+
+// Cancels the move in progress and runs `move` in its place.
+func (self *DriveBase) begin(block bool, move func(cancel <-chan bool)) {
+	self.cancelMotion()
+
+	cancel, done := make(chan bool), make(chan bool)
+
+	self.motionLock.Lock()
+	self.motionCancel, self.motionDone = cancel, done
+	self.motionLock.Unlock()
+
+	run := func() {
+		defer close(done)
+		move(cancel)
+	}
+
+	if block {
+		run()
+	} else {
+		go run()
+	}
+}
+
+// Cancels the move in progress, if any, and waits for it to give up the motors.
+func (self *DriveBase) cancelMotion() {
+	self.motionLock.Lock()
+	cancel, done := self.motionCancel, self.motionDone
+	self.motionCancel, self.motionDone = nil, nil
+	self.motionLock.Unlock()
+
+	if cancel != nil {
+		close(cancel)
+		<-done
+	}
+}
+
+func (self *DriveBase) halt(m *Motor.Motor, brake bool) {
+	if brake {
+		m.EnableBrakeMode()
+	} else {
+		m.DisableBrakeMode()
+	}
+
+	m.Stop()
+	self.stopped(m)
+}
+
+func (self *DriveBase) runForDegrees(leftSpeed int16, rightSpeed int16, degrees float64, brake bool, cancel <-chan bool) {
+	fastest := math.Max(math.Abs(float64(leftSpeed)), math.Abs(float64(rightSpeed)))
+	if fastest == 0 || degrees == 0 {
+		return
+	}
+
+	// A negative angle reverses the direction, as in ev3dev2.
+	if degrees < 0 {
+		leftSpeed, rightSpeed, degrees = -leftSpeed, -rightSpeed, -degrees
+	}
+
+	type wheel struct {
+		motor  *Motor.Motor
+		start  float64
+		target float64
+		done   bool
+	}
+
+	wheels := []*wheel{
+		{self.left, self.left.CurrentDegrees(), degrees * math.Abs(float64(leftSpeed)) / fastest, leftSpeed == 0},
+		{self.right, self.right.CurrentDegrees(), degrees * math.Abs(float64(rightSpeed)) / fastest, rightSpeed == 0},
+	}
+
+	self.run(leftSpeed, rightSpeed)
+
+	ticker := time.NewTicker(motionInterval)
+	defer ticker.Stop()
+
+	for {
+		running := false
+
+		for _, w := range wheels {
+			if w.done {
+				continue
+			}
+
+			if math.Abs(w.motor.CurrentDegrees()-w.start) >= w.target {
+				self.halt(w.motor, brake)
+				w.done = true
+			} else {
+				running = true
+			}
+		}
+
+		if !running {
+			return
+		}
+
+		select {
+		case <-cancel:
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package Drive
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/jermon/GoEV3/Sensors"
+	"github.com/jermon/GoEV3/utilities"
+)
+
+// Tracks the pose of a drive base, its position and heading on the floor, by
+// integrating the wheel travel. Wheel slip makes the heading drift; a gyro or
+// compass set with SetHeadingSource keeps it accurate.
+//
+//	odometry := base.NewOdometry()
+//	go odometry.Run(stop, 10*time.Millisecond)
+//	x, y, theta := odometry.Pose()
+type Odometry struct {
+	lock sync.Mutex
+
+	base    *DriveBase
+	heading func() float64
+
+	x, y, theta  float64
+	left, right  float64
+	headingStart float64
+	headingBase  float64
+}
+
+// Creates an odometry starting at the origin, facing along the X axis. It
+// takes its heading from the gyro the base uses, if any; see UseGyro.
+func (self *DriveBase) NewOdometry() *Odometry {
+	o := new(Odometry)
+	o.base = self
+	o.left, o.right = self.WheelTravel()
+
+	self.lock.Lock()
+	gyro := self.gyro
+	self.lock.Unlock()
+
+	if gyro != nil {
+		o.UseGyro(gyro)
+	}
+
+	return o
+}
+
+// Takes the heading from `gyro` instead of the wheel travel, or from the
+// wheel travel again after passing nil.
+func (self *Odometry) UseGyro(gyro *Sensors.GyroSensor) {
+	if gyro == nil {
+		self.SetHeadingSource(nil)
+		return
+	}
+
+	// The gyro counts clockwise.
+	self.SetHeadingSource(func() float64 { return -gyro.Angle().Degrees() })
+}
+
+// Takes the heading from `heading`, in degrees, counter-clockwise being
+// positive, instead of the wheel travel. Only its changes are used.
+func (self *Odometry) SetHeadingSource(heading func() float64) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	self.heading = heading
+	if heading != nil {
+		self.headingStart, self.headingBase = heading(), self.theta
+	}
+}
+
+// Sets the pose: the position in centimeters and the heading in degrees.
+func (self *Odometry) Reset(x float64, y float64, theta float64) {
+	l, r := self.base.WheelTravel()
+
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	self.x, self.y, self.theta = x, y, theta
+	self.left, self.right = l, r
+	if self.heading != nil {
+		self.headingStart, self.headingBase = self.heading(), theta
+	}
+}
+
+// Moves the pose back to the origin, facing along the X axis.
+func (self *Odometry) ResetPose() {
+	self.Reset(0, 0, 0)
+}
+
+// Reads the wheel positions once and updates the pose.
+func (self *Odometry) Update() {
+	l, r := self.base.WheelTravel()
+
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	dl, dr := l-self.left, r-self.right
+	self.left, self.right = l, r
+
+	distance := (dl + dr) / 2
+	theta := self.theta + (dr-dl)/self.base.trackWidth*180/math.Pi
+	if self.heading != nil {
+		theta = self.headingBase + self.heading() - self.headingStart
+	}
+
+	// Moving along the mean heading over the step approximates the arc driven.
+	mean := (self.theta + theta) / 2 * math.Pi / 180
+	self.x += distance * math.Cos(mean)
+	self.y += distance * math.Sin(mean)
+	self.theta = theta
+}
+
+// Returns the position in centimeters and the heading in degrees,
+// counter-clockwise being positive.
+func (self *Odometry) Pose() (x float64, y float64, theta float64) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	return self.x, self.y, self.theta
+}
+
+// Updates the pose every `interval`, from the shared polling scheduler, until
+// a value is sent to `stop`.
+func (self *Odometry) Run(stop <-chan bool, interval time.Duration) {
+	utilities.PollUntil(stop, interval, self.Update)
+}
@@ -0,0 +1,134 @@
+package Drive
+
+import (
+	"math"
+	"time"
+
+	"github.com/jermon/GoEV3/Motor"
+	"github.com/jermon/GoEV3/utilities"
+)
+
+// How often wheel speeds are stepped towards their target while limiting acceleration.
+const slewInterval = 20 * time.Millisecond
+
+// Limits how fast the wheel speeds given to Tank, Steer, SetVelocity and the
+// like may change, in speed units (as passed to Motor.Run) per second; 0, the
+// default, applies commands at once. Abrupt changes, such as a joystick
+// slammed forward or a behavior taking over from another, make the wheels
+// slip, which ruins odometry:
+//
+//	base.SetAccelerationLimit(200)
+//
+// brings the wheels from standstill to full speed in half a second. Both
+// wheels reach their new speeds together, so that the robot keeps to the arc
+// it was commanded. Stop and the timed and positioned moves are not limited.
+func (self *DriveBase) SetAccelerationLimit(rate float64) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	self.acceleration = math.Max(0, rate)
+	if self.acceleration == 0 && self.slew != nil {
+		self.slew.Cancel()
+		self.slew = nil
+		self.left.Run(int16(math.Round(self.target[0])))
+		self.right.Run(int16(math.Round(self.target[1])))
+		self.commanded = self.target
+	}
+}
+
+// Returns the acceleration limit set with SetAccelerationLimit.
+func (self *DriveBase) AccelerationLimit() float64 {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	return self.acceleration
+}
+
+// Returns the wheel speeds last given to the motors, which lag behind the
+// commanded ones while the acceleration is limited.
+func (self *DriveBase) WheelSpeeds() (int16, int16) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	return int16(math.Round(self.commanded[0])), int16(math.Round(self.commanded[1]))
+}
+
+// Sets the wheel speeds to reach, ramping towards them if the acceleration is
+// limited. Must be called with the lock held.
+func (self *DriveBase) setTarget(leftSpeed int16, rightSpeed int16) {
+	self.target = [2]float64{float64(leftSpeed), float64(rightSpeed)}
+
+	if self.acceleration == 0 {
+		self.runLocked(leftSpeed, rightSpeed)
+		return
+	}
+
+	if self.slew == nil {
+		// The first call may come before Poll returns; the lock held makes it
+		// wait for `task` to be set.
+		last := time.Now()
+		var task *utilities.PollTask
+		task = utilities.Poll(slewInterval, func() {
+			self.slewStep(&task, &last)
+		})
+		self.slew = task
+	}
+}
+
+// Moves the wheel speeds towards the target by the acceleration allowed since
+// `last`, scaling both steps so that they arrive together.
+func (self *DriveBase) slewStep(taskRef **utilities.PollTask, last *time.Time) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	// Cancelled or replaced in the meantime.
+	task := *taskRef
+	if self.slew != task {
+		return
+	}
+
+	now := time.Now()
+	seconds := now.Sub(*last).Seconds()
+	*last = now
+
+	dl := self.target[0] - self.commanded[0]
+	dr := self.target[1] - self.commanded[1]
+	largest := math.Max(math.Abs(dl), math.Abs(dr))
+	step := self.acceleration * seconds
+
+	next := self.target
+	if largest > step {
+		f := step / largest
+		next = [2]float64{self.commanded[0] + dl*f, self.commanded[1] + dr*f}
+	} else {
+		task.Cancel()
+		self.slew = nil
+	}
+
+	self.left.Run(int16(math.Round(next[0])))
+	self.right.Run(int16(math.Round(next[1])))
+	self.commanded = next
+}
+
+// Stops ramping towards the target, leaving the motors as they are. Must be
+// called with the lock held.
+func (self *DriveBase) cancelSlew() {
+	if self.slew != nil {
+		self.slew.Cancel()
+		self.slew = nil
+	}
+}
+
+// Records that a wheel motor was stopped outside of Tank, so that the next
+// ramp starts from standstill.
+func (self *DriveBase) stopped(m *Motor.Motor) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	self.cancelSlew()
+	for i, w := range []*Motor.Motor{self.left, self.right} {
+		if w == m {
+			self.target[i], self.commanded[i] = 0, 0
+		}
+	}
+}
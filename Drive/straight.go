@@ -0,0 +1,154 @@
+package Drive
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/jermon/GoEV3/Control"
+)
+
+// Drives straight without a gyro by keeping the wheel encoders in step: the
+// left motor, the master, runs at the set speed while the right one, the
+// slave, is trimmed by a PID controller so that it turns as far as the master.
+// Motors of the same model differ enough to make a robot veer by several
+// centimeters per meter when both get the same power; balancing the encoders
+// also corrects for a wheel dragging on the floor.
+//
+//	straight := base.NewStraightDrive()
+//	straight.SetSpeed(50)
+//	straight.DriveDistance(100, stop)
+type StraightDrive struct {
+	lock sync.Mutex
+
+	base *DriveBase
+	pid  *Control.PID
+
+	speed    int16
+	interval time.Duration
+
+	masterStart float64
+	slaveStart  float64
+}
+
+// Creates a controller for the drive base, counting encoder travel from now.
+func (self *DriveBase) NewStraightDrive() *StraightDrive {
+	s := new(StraightDrive)
+	s.base = self
+	s.speed = 30
+	s.interval = 10 * time.Millisecond
+
+	// The measurement is how far the slave leads, so the setpoint is 0.
+	s.pid = Control.NewPID(1, 0.5, 0.02)
+	s.pid.SetOutputLimits(-30, 30)
+	s.Reset()
+
+	return s
+}
+
+// Sets the master's speed, as passed to Motor.Run. Negative speeds drive backwards.
+func (self *StraightDrive) SetSpeed(speed int16) {
+	self.lock.Lock()
+	self.speed = speed
+	self.lock.Unlock()
+}
+
+// Sets the controller gains, in speed units per degree the slave lags behind.
+func (self *StraightDrive) SetGains(kp float64, ki float64, kd float64) {
+	self.pid.SetGains(kp, ki, kd)
+}
+
+// Sets how often the encoders are compared while driving.
+func (self *StraightDrive) SetInterval(interval time.Duration) {
+	self.lock.Lock()
+	self.interval = interval
+	self.lock.Unlock()
+}
+
+// Starts counting encoder travel from the current positions.
+func (self *StraightDrive) Reset() {
+	master := self.base.left.CurrentDegrees()
+	slave := self.base.right.CurrentDegrees()
+
+	self.lock.Lock()
+	self.masterStart, self.slaveStart = master, slave
+	self.lock.Unlock()
+
+	self.pid.Reset()
+}
+
+// Returns how many degrees the slave has turned beyond the master since Reset.
+func (self *StraightDrive) Error() float64 {
+	master := self.base.left.CurrentDegrees()
+	slave := self.base.right.CurrentDegrees()
+
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	return (slave - self.slaveStart) - (master - self.masterStart)
+}
+
+// Returns the distance in centimeters the master has traveled since Reset.
+func (self *StraightDrive) Distance() float64 {
+	master := self.base.left.CurrentDegrees()
+
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	return self.base.DegreesToDistance(master - self.masterStart)
+}
+
+// Reads the encoders once and returns the wheel speeds for the next step.
+func (self *StraightDrive) Step() (int16, int16) {
+	self.lock.Lock()
+	speed := self.speed
+	self.lock.Unlock()
+
+	// The trim has the sign of the master's lead, so it speeds up a lagging
+	// slave whichever way the robot drives.
+	trim := self.pid.Update(self.Error())
+	slave := math.Max(-100, math.Min(100, float64(speed)+trim))
+
+	return speed, int16(math.Round(slave))
+}
+
+// Drives straight until a value is sent to `stop`. The motors are stopped
+// before returning.
+func (self *StraightDrive) Drive(stop <-chan bool) {
+	self.DriveUntil(stop, func() bool { return false })
+}
+
+// Drives straight until the master has traveled `distance` centimeters since
+// Reset. Returns true once it has, or false if a value is sent to `stop`
+// first. The motors are stopped before returning.
+func (self *StraightDrive) DriveDistance(distance float64, stop <-chan bool) bool {
+	return self.DriveUntil(stop, func() bool {
+		return math.Abs(self.Distance()) >= math.Abs(distance)
+	})
+}
+
+// Drives straight until `until` returns true, which it returns, or until a
+// value is sent to `stop`, in which case it returns false. The motors are
+// stopped before returning.
+func (self *StraightDrive) DriveUntil(stop <-chan bool, until func() bool) bool {
+	self.lock.Lock()
+	interval := self.interval
+	self.lock.Unlock()
+
+	defer self.base.Stop()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for !until() {
+		self.base.Tank(self.Step())
+
+		select {
+		case <-stop:
+			return false
+		case <-ticker.C:
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,117 @@
+package Drive
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/jermon/GoEV3/Units"
+	"github.com/jermon/GoEV3/utilities"
+)
+
+type milestone struct {
+	distance Units.Distance
+	fn       func()
+	fired    bool
+}
+
+// Measures the distance traveled and the rotation of a drive base since it was
+// last reset, without resetting the motors' positions, so several trip meters
+// can share a drive base:
+//
+//	trip := base.NewTripMeter()
+//	trip.At(50*Units.Centimeter, scanner.Start)
+//	go trip.Run(stop, 20*time.Millisecond)
+type TripMeter struct {
+	lock sync.Mutex
+
+	base       *DriveBase
+	startLeft  float64
+	startRight float64
+	milestones []*milestone
+}
+
+// Creates a trip meter starting at the current wheel positions.
+func (self *DriveBase) NewTripMeter() *TripMeter {
+	t := new(TripMeter)
+	t.base = self
+	t.Reset()
+
+	return t
+}
+
+// Starts measuring from the current wheel positions again and re-arms all milestones.
+func (self *TripMeter) Reset() {
+	left, right := self.base.left.TravelDegrees(), self.base.right.TravelDegrees()
+
+	self.lock.Lock()
+	self.startLeft, self.startRight = left, right
+	for _, m := range self.milestones {
+		m.fired = false
+	}
+	self.lock.Unlock()
+}
+
+// Returns the distance traveled by the left and right wheels since the last
+// reset, negative when driving backwards.
+func (self *TripMeter) WheelDistances() (Units.Distance, Units.Distance) {
+	left, right := self.base.left.TravelDegrees(), self.base.right.TravelDegrees()
+
+	self.lock.Lock()
+	left, right = left-self.startLeft, right-self.startRight
+	self.lock.Unlock()
+
+	return Units.Distance(self.base.DegreesToDistance(left)) * Units.Centimeter,
+		Units.Distance(self.base.DegreesToDistance(right)) * Units.Centimeter
+}
+
+// Returns the average distance traveled by both wheels since the last reset.
+func (self *TripMeter) Distance() Units.Distance {
+	l, r := self.WheelDistances()
+	return (l + r) / 2
+}
+
+// Returns the rotation of the robot since the last reset, counter-clockwise being positive.
+func (self *TripMeter) Rotation() Units.Angle {
+	l, r := self.WheelDistances()
+	return Units.Angle((r-l).Centimeters()/self.base.trackWidth) * Units.Radian
+}
+
+// Registers `fn` to be called once the robot has traveled `distance`, forwards
+// or backwards, since the last reset. Milestones are checked by Check and Run,
+// and fire again after a reset.
+func (self *TripMeter) At(distance Units.Distance, fn func()) {
+	self.lock.Lock()
+	self.milestones = append(self.milestones, &milestone{distance: distance, fn: fn})
+	sort.SliceStable(self.milestones, func(i, j int) bool {
+		return self.milestones[i].distance < self.milestones[j].distance
+	})
+	self.lock.Unlock()
+}
+
+// Calls the callbacks of the milestones reached since the last check, nearest first.
+func (self *TripMeter) Check() {
+	traveled := Units.Distance(math.Abs(float64(self.Distance())))
+
+	var reached []func()
+
+	self.lock.Lock()
+	for _, m := range self.milestones {
+		if !m.fired && traveled >= m.distance {
+			m.fired = true
+			reached = append(reached, m.fn)
+		}
+	}
+	self.lock.Unlock()
+
+	for _, fn := range reached {
+		fn()
+	}
+}
+
+// Checks the milestones every `interval`, from the shared polling scheduler,
+// until a value is sent to `stop`. Milestone functions must then return promptly.
+func (self *TripMeter) Run(stop <-chan bool, interval time.Duration) {
+	utilities.PollUntil(stop, interval, self.Check)
+}
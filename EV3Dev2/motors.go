@@ -0,0 +1,336 @@
+// Provides types and methods named after the python-ev3dev2 library, to ease
+// porting existing Python programs to Go.
+//
+// Python properties become methods and snake_case names become CamelCase:
+//
+//	# Python
+//	m = LargeMotor(OUTPUT_A)
+//	m.on_for_rotations(SpeedPercent(75), 5)
+//	print(ColorSensor(INPUT_1).reflected_light_intensity)
+//
+//	// Go
+//	m := EV3Dev2.NewLargeMotor(EV3Dev2.OUTPUT_A)
+//	m.OnForRotations(EV3Dev2.SpeedPercent(75), 5, true, true)
+//	fmt.Println(EV3Dev2.NewColorSensor(EV3Dev2.INPUT_1).ReflectedLightIntensity())
+//
+// Python's keyword arguments `brake` and `block` are always passed explicitly.
+// Speeds are percentages of the motor's full power.
+package EV3Dev2
+
+import (
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jermon/GoEV3/Drive"
+	"github.com/jermon/GoEV3/Motor"
+)
+
+// Output port names.
+const (
+	OUTPUT_A = Motor.OutPortA
+	OUTPUT_B = Motor.OutPortB
+	OUTPUT_C = Motor.OutPortC
+	OUTPUT_D = Motor.OutPortD
+)
+
+// Returns a speed given as a percentage of full power, as in ev3dev2.
+func SpeedPercent(percent float64) float64 {
+	return math.Max(-100, math.Min(100, percent))
+}
+
+func duty(speed float64) int16 {
+	return int16(math.Round(SpeedPercent(speed)))
+}
+
+// Counterpart of ev3dev2.motor.Motor.
+type TachoMotor struct {
+	motor *Motor.Motor
+
+	lock   sync.Mutex
+	cancel chan bool
+	done   chan bool
+}
+
+// Counterpart of ev3dev2.motor.LargeMotor.
+type LargeMotor struct {
+	TachoMotor
+}
+
+// Counterpart of ev3dev2.motor.MediumMotor.
+type MediumMotor struct {
+	TachoMotor
+}
+
+// Provides access to the large motor at the given port.
+func NewLargeMotor(port Motor.OutPort) *LargeMotor {
+	m := new(LargeMotor)
+	m.motor = Motor.FindMotor(port)
+
+	return m
+}
+
+// Provides access to the medium motor at the given port.
+func NewMediumMotor(port Motor.OutPort) *MediumMotor {
+	m := new(MediumMotor)
+	m.motor = Motor.FindMotor(port)
+
+	return m
+}
+
+// Returns the underlying GoEV3 motor.
+func (self *TachoMotor) Motor() *Motor.Motor {
+	return self.motor
+}
+
+// Returns the position in tacho counts (degrees).
+func (self *TachoMotor) Position() int32 {
+	return self.motor.CurrentPosition()
+}
+
+// Sets the current position.
+func (self *TachoMotor) SetPosition(position int32) {
+	self.motor.InitializePosition(position)
+}
+
+// Returns the current speed.
+func (self *TachoMotor) Speed() int16 {
+	return self.motor.CurrentSpeed()
+}
+
+// Returns the state flags, e.g. "running" or "stalled".
+func (self *TachoMotor) State() []string {
+	return strings.Fields(self.motor.GetState())
+}
+
+// Reports whether the motor is running.
+func (self *TachoMotor) IsRunning() bool {
+	return strings.Contains(self.motor.GetState(), "running")
+}
+
+// Reports whether the motor is stalled.
+func (self *TachoMotor) IsStalled() bool {
+	return strings.Contains(self.motor.GetState(), "stalled")
+}
+
+// Stops the motor and sets its position to 0.
+func (self *TachoMotor) Reset() {
+	self.Off(false)
+	self.motor.InitializePosition(0)
+}
+
+// Runs the motor until another command is given.
+func (self *TachoMotor) On(speed float64) {
+	self.stopMove()
+	self.motor.Run(duty(speed))
+}
+
+// Stops the motor, braking or coasting.
+func (self *TachoMotor) Off(brake bool) {
+	self.stopMove()
+	halt(self.motor, brake)
+}
+
+// Runs the motor until it has turned by `degrees`. A negative angle or speed reverses the direction.
+func (self *TachoMotor) OnForDegrees(speed float64, degrees float64, brake bool, block bool) {
+	if degrees < 0 {
+		speed, degrees = -speed, -degrees
+	}
+
+	start := self.motor.CurrentDegrees()
+
+	self.move(block, brake, func() bool {
+		return math.Abs(self.motor.CurrentDegrees()-start) >= degrees
+	}, speed)
+}
+
+// Runs the motor until it has turned by `rotations` full turns.
+func (self *TachoMotor) OnForRotations(speed float64, rotations float64, brake bool, block bool) {
+	self.OnForDegrees(speed, rotations*360, brake, block)
+}
+
+// Runs the motor for the given duration.
+func (self *TachoMotor) OnForSeconds(speed float64, seconds float64, brake bool, block bool) {
+	deadline := time.Now().Add(time.Duration(seconds * float64(time.Second)))
+
+	self.move(block, brake, func() bool {
+		return !time.Now().Before(deadline)
+	}, speed)
+}
+
+// Runs the motor to the given absolute position.
+func (self *TachoMotor) OnToPosition(speed float64, position int32, brake bool, block bool) {
+	delta := self.motor.CountsToDegrees(float64(position - self.motor.CurrentPosition()))
+	self.OnForDegrees(math.Abs(speed)*sign(delta), math.Abs(delta), brake, block)
+}
+
+// Waits until a move started without blocking completes.
+func (self *TachoMotor) WaitUntilNotMoving() {
+	self.lock.Lock()
+	done := self.done
+	self.lock.Unlock()
+
+	if done != nil {
+		<-done
+	}
+}
+
+func (self *TachoMotor) move(block bool, brake bool, finished func() bool, speed float64) {
+	self.stopMove()
+
+	cancel, done := make(chan bool), make(chan bool)
+
+	self.lock.Lock()
+	self.cancel, self.done = cancel, done
+	self.lock.Unlock()
+
+	run := func() {
+		defer close(done)
+
+		self.motor.Run(duty(speed))
+
+		for !finished() {
+			select {
+			case <-cancel:
+				return
+			case <-time.After(5 * time.Millisecond):
+			}
+		}
+
+		halt(self.motor, brake)
+	}
+
+	if block {
+		run()
+	} else {
+		go run()
+	}
+}
+
+func (self *TachoMotor) stopMove() {
+	self.lock.Lock()
+	cancel, done := self.cancel, self.done
+	self.cancel, self.done = nil, nil
+	self.lock.Unlock()
+
+	if cancel != nil {
+		close(cancel)
+		<-done
+	}
+}
+
+func halt(m *Motor.Motor, brake bool) {
+	if brake {
+		m.EnableBrakeMode()
+	} else {
+		m.DisableBrakeMode()
+	}
+
+	m.Stop()
+}
+
+func sign(v float64) float64 {
+	if v < 0 {
+		return -1
+	}
+
+	return 1
+}
+
+// Wheel dimensions assumed by MoveTank and MoveSteering, those of the EV3
+// education set. They only matter for distance based moves.
+const (
+	defaultWheelDiameter = 5.6
+	defaultTrackWidth    = 12
+)
+
+// Counterpart of ev3dev2.motor.MoveTank.
+type MoveTank struct {
+	base *Drive.DriveBase
+}
+
+// Provides access to a pair of motors driven tank style.
+func NewMoveTank(left Motor.OutPort, right Motor.OutPort) *MoveTank {
+	return &MoveTank{Drive.NewDriveBase(left, right, defaultWheelDiameter, defaultTrackWidth)}
+}
+
+// Returns the underlying GoEV3 drive base.
+func (self *MoveTank) DriveBase() *Drive.DriveBase {
+	return self.base
+}
+
+// Runs the motors until another command is given.
+func (self *MoveTank) On(leftSpeed float64, rightSpeed float64) {
+	self.base.On(duty(leftSpeed), duty(rightSpeed))
+}
+
+// Stops both motors.
+func (self *MoveTank) Off(brake bool) {
+	self.base.Off(brake)
+}
+
+// Runs the motors until the faster one has turned by `degrees`.
+func (self *MoveTank) OnForDegrees(leftSpeed float64, rightSpeed float64, degrees float64, brake bool, block bool) {
+	self.base.OnForDegrees(duty(leftSpeed), duty(rightSpeed), degrees, brake, block)
+}
+
+// Runs the motors until the faster one has turned by `rotations` full turns.
+func (self *MoveTank) OnForRotations(leftSpeed float64, rightSpeed float64, rotations float64, brake bool, block bool) {
+	self.base.OnForRotations(duty(leftSpeed), duty(rightSpeed), rotations, brake, block)
+}
+
+// Runs the motors for the given duration.
+func (self *MoveTank) OnForSeconds(leftSpeed float64, rightSpeed float64, seconds float64, brake bool, block bool) {
+	self.base.OnForSeconds(duty(leftSpeed), duty(rightSpeed), seconds, brake, block)
+}
+
+// Waits until a move started without blocking completes.
+func (self *MoveTank) WaitUntilNotMoving() {
+	self.base.WaitUntilNotMoving()
+}
+
+// Counterpart of ev3dev2.motor.MoveSteering.
+type MoveSteering struct {
+	base *Drive.DriveBase
+}
+
+// Provides access to a pair of motors driven with a steering value.
+func NewMoveSteering(left Motor.OutPort, right Motor.OutPort) *MoveSteering {
+	return &MoveSteering{Drive.NewDriveBase(left, right, defaultWheelDiameter, defaultTrackWidth)}
+}
+
+// Returns the underlying GoEV3 drive base.
+func (self *MoveSteering) DriveBase() *Drive.DriveBase {
+	return self.base
+}
+
+// Drives with the given steering in range [-100, 100] until another command is given.
+func (self *MoveSteering) On(steering float64, speed float64) {
+	self.base.OnSteering(steering, duty(speed))
+}
+
+// Stops both motors.
+func (self *MoveSteering) Off(brake bool) {
+	self.base.Off(brake)
+}
+
+// Drives until the faster motor has turned by `degrees`.
+func (self *MoveSteering) OnForDegrees(steering float64, speed float64, degrees float64, brake bool, block bool) {
+	self.base.SteerForDegrees(steering, duty(speed), degrees, brake, block)
+}
+
+// Drives until the faster motor has turned by `rotations` full turns.
+func (self *MoveSteering) OnForRotations(steering float64, speed float64, rotations float64, brake bool, block bool) {
+	self.base.SteerForRotations(steering, duty(speed), rotations, brake, block)
+}
+
+// Drives for the given duration.
+func (self *MoveSteering) OnForSeconds(steering float64, speed float64, seconds float64, brake bool, block bool) {
+	self.base.SteerForSeconds(steering, duty(speed), seconds, brake, block)
+}
+
+// Waits until a move started without blocking completes.
+func (self *MoveSteering) WaitUntilNotMoving() {
+	self.base.WaitUntilNotMoving()
+}
@@ -0,0 +1,244 @@
+package EV3Dev2
+
+import (
+	"time"
+
+	"github.com/jermon/GoEV3/LED"
+	"github.com/jermon/GoEV3/Sensors"
+	"github.com/jermon/GoEV3/Sound"
+)
+
+// Input port names.
+const (
+	INPUT_1 = Sensors.InPort1
+	INPUT_2 = Sensors.InPort2
+	INPUT_3 = Sensors.InPort3
+	INPUT_4 = Sensors.InPort4
+)
+
+// Color values reported by ColorSensor.Color.
+const (
+	COLOR_NOCOLOR = int(Sensors.None)
+	COLOR_BLACK   = int(Sensors.Black)
+	COLOR_BLUE    = int(Sensors.Blue)
+	COLOR_GREEN   = int(Sensors.Green)
+	COLOR_YELLOW  = int(Sensors.Yellow)
+	COLOR_RED     = int(Sensors.Red)
+	COLOR_WHITE   = int(Sensors.White)
+	COLOR_BROWN   = int(Sensors.Brown)
+)
+
+// Counterpart of ev3dev2.sensor.lego.ColorSensor.
+type ColorSensor struct {
+	sensor *Sensors.ColorSensor
+}
+
+// Provides access to the color sensor at the given port.
+func NewColorSensor(port Sensors.InPort) *ColorSensor {
+	return &ColorSensor{Sensors.FindColorSensor(port)}
+}
+
+// Returns the reflected light intensity in range [0, 100].
+func (self *ColorSensor) ReflectedLightIntensity() int {
+	return int(self.sensor.ReadReflectedLightIntensity())
+}
+
+// Returns the ambient light intensity in range [0, 100].
+func (self *ColorSensor) AmbientLightIntensity() int {
+	return int(self.sensor.ReadAmbientLightIntensity())
+}
+
+// Returns the detected color, one of the COLOR_ constants.
+func (self *ColorSensor) Color() int {
+	return int(self.sensor.ReadColor())
+}
+
+// Returns the name of the detected color, e.g. "Black" or "NoColor".
+func (self *ColorSensor) ColorName() string {
+	color := self.sensor.ReadColor()
+	if color == Sensors.None {
+		return "NoColor"
+	}
+
+	return color.String()
+}
+
+// Counterpart of ev3dev2.sensor.lego.UltrasonicSensor.
+type UltrasonicSensor struct {
+	sensor *Sensors.UltrasonicSensor
+}
+
+// Provides access to the ultrasonic sensor at the given port.
+func NewUltrasonicSensor(port Sensors.InPort) *UltrasonicSensor {
+	return &UltrasonicSensor{Sensors.FindUltrasonicSensor(port)}
+}
+
+// Returns the measured distance in centimeters.
+func (self *UltrasonicSensor) DistanceCentimeters() float64 {
+	return self.sensor.Distance().Centimeters()
+}
+
+// Returns the measured distance in inches.
+func (self *UltrasonicSensor) DistanceInches() float64 {
+	return self.sensor.Distance().Inches()
+}
+
+// Reports whether another ultrasonic sensor is transmitting nearby.
+func (self *UltrasonicSensor) OtherSensorPresent() bool {
+	return self.sensor.Listen()
+}
+
+// Counterpart of ev3dev2.sensor.lego.GyroSensor.
+type GyroSensor struct {
+	sensor *Sensors.GyroSensor
+}
+
+// Provides access to the gyro sensor at the given port.
+func NewGyroSensor(port Sensors.InPort) *GyroSensor {
+	return &GyroSensor{Sensors.FindGyroSensor(port)}
+}
+
+// Returns the angle in degrees, clockwise being positive.
+func (self *GyroSensor) Angle() int {
+	return int(self.sensor.ReadAngle())
+}
+
+// Returns the rotational speed in degrees per second.
+func (self *GyroSensor) Rate() int {
+	return int(self.sensor.ReadRotationalSpeed())
+}
+
+// Returns the angle and the rotational speed.
+func (self *GyroSensor) AngleAndRate() (int, int) {
+	return self.Angle(), self.Rate()
+}
+
+// Counterpart of ev3dev2.sensor.lego.TouchSensor.
+type TouchSensor struct {
+	sensor *Sensors.TouchSensor
+}
+
+// Provides access to the touch sensor at the given port.
+func NewTouchSensor(port Sensors.InPort) *TouchSensor {
+	return &TouchSensor{Sensors.FindTouchSensor(port)}
+}
+
+// Reports whether the sensor is pressed.
+func (self *TouchSensor) IsPressed() bool {
+	return self.sensor.IsPressed()
+}
+
+// Reports whether the sensor is released.
+func (self *TouchSensor) IsReleased() bool {
+	return !self.sensor.IsPressed()
+}
+
+// Waits until the sensor is pressed.
+func (self *TouchSensor) WaitForPressed() {
+	self.sensor.Wait()
+}
+
+// Waits until the sensor is released.
+func (self *TouchSensor) WaitForReleased() {
+	for self.sensor.IsPressed() {
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// Counterpart of ev3dev2.sensor.lego.InfraredSensor.
+type InfraredSensor struct {
+	sensor *Sensors.InfraredSensor
+}
+
+// Provides access to the infrared sensor at the given port.
+func NewInfraredSensor(port Sensors.InPort) *InfraredSensor {
+	return &InfraredSensor{Sensors.FindInfraredSensor(port)}
+}
+
+// Returns the proximity in range [0, 100].
+func (self *InfraredSensor) Proximity() int {
+	return int(self.sensor.ReadProximity())
+}
+
+// Returns the heading in range [-25, 25] of the beacon on the given channel (1 to 4).
+func (self *InfraredSensor) Heading(channel int) int {
+	return int(self.sensor.ReadIRSEEK(Sensors.Channel(channel - 1)).Heading)
+}
+
+// Returns the distance in range [0, 100] to the beacon on the given channel,
+// or -1 if no beacon is detected.
+func (self *InfraredSensor) Distance(channel int) int {
+	reading := self.sensor.ReadIRSEEK(Sensors.Channel(channel - 1))
+	if !reading.Detected {
+		return -1
+	}
+
+	return int(reading.Distance)
+}
+
+// Returns the heading and distance of the beacon on the given channel.
+func (self *InfraredSensor) HeadingAndDistance(channel int) (int, int) {
+	return self.Heading(channel), self.Distance(channel)
+}
+
+// Counterpart of ev3dev2.sound.Sound.
+type SoundPlayer struct{}
+
+// Provides access to the brick's speaker.
+func NewSound() *SoundPlayer {
+	return &SoundPlayer{}
+}
+
+// Plays a short beep.
+func (SoundPlayer) Beep() {
+	Sound.PlayTone(1000, 100)
+}
+
+// Plays a tone at the given frequency for the given duration.
+func (SoundPlayer) PlayTone(frequency float64, seconds float64) {
+	Sound.PlayTone(uint32(frequency), uint64(seconds*1000))
+}
+
+// Plays a WAV file.
+func (SoundPlayer) PlayFile(path string) {
+	Sound.Play(path)
+}
+
+// Sets the volume in range [0, 100].
+func (SoundPlayer) SetVolume(volume int) {
+	Sound.SetVolume(uint8(volume))
+}
+
+// Counterpart of ev3dev2.led.Leds.
+type Leds struct{}
+
+// Provides access to the brick's status LEDs.
+func NewLeds() *Leds {
+	return &Leds{}
+}
+
+// Sets the color of a LED group: "LEFT" or "RIGHT", and "GREEN", "RED",
+// "AMBER" or "BLACK" (off).
+func (Leds) SetColor(group string, color string) {
+	position := LED.Left
+	if group == "RIGHT" {
+		position = LED.Right
+	}
+
+	LED.TurnOff(LED.Amber, position)
+
+	switch color {
+	case "GREEN":
+		LED.TurnOn(LED.Green, position)
+	case "RED":
+		LED.TurnOn(LED.Red, position)
+	case "AMBER":
+		LED.TurnOn(LED.Amber, position)
+	}
+}
+
+// Turns all LEDs off.
+func (self Leds) AllOff() {
+	self.SetColor("LEFT", "BLACK")
+	self.SetColor("RIGHT", "BLACK")
+}
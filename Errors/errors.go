@@ -0,0 +1,73 @@
+// Provides the errors reported by GoEV3, so that programs can handle them with
+// errors.Is and errors.As:
+//
+//	m, err := Motor.OpenMotor(Motor.OutPortA)
+//	if errors.Is(err, Errors.ErrDeviceNotFound) {
+//		// Run without the arm.
+//	}
+//
+//	var deviceErr *Errors.DeviceError
+//	if errors.As(err, &deviceErr) {
+//		fmt.Println("check port", deviceErr.Port)
+//	}
+package Errors
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// No device is connected to the port, or the ev3dev drivers aren't loaded.
+	ErrDeviceNotFound = errors.New("device not found")
+	// A device is connected to the port, but not of the expected kind.
+	ErrPortMismatch = errors.New("unexpected device on port")
+	// The device went away while being used, e.g. because its cable came loose.
+	ErrDisconnected = errors.New("device disconnected")
+	// The device doesn't support the requested mode.
+	ErrInvalidMode = errors.New("invalid mode")
+	// A value is outside the range the device accepts.
+	ErrOutOfRange = errors.New("value out of range")
+	// An I/O on the device took longer than the timeout set, e.g. because its driver is wedged.
+	ErrTimeout = errors.New("I/O timed out")
+	// A wait for a device to reach a state gave up after its timeout.
+	ErrWaitTimeout = errors.New("wait timed out")
+)
+
+// Describes an error concerning a particular device.
+type DeviceError struct {
+	// One of the Err* values.
+	Kind error
+	// Kind of device, e.g. "motor" or "color sensor".
+	Device string
+	// Port the device is expected on.
+	Port string
+	// Details such as the expected and actual driver.
+	Detail string
+	// The error that caused this one, e.g. an I/O error or an environment diagnostic. May be nil.
+	Cause error
+}
+
+func (self *DeviceError) Error() string {
+	s := self.Kind.Error()
+	if self.Device != "" || self.Port != "" {
+		s = fmt.Sprintf("%s on port %s: %s", self.Device, self.Port, s)
+	}
+	if self.Detail != "" {
+		s += " (" + self.Detail + ")"
+	}
+	if self.Cause != nil {
+		s += ": " + self.Cause.Error()
+	}
+
+	return s
+}
+
+// Makes errors.Is and errors.As see both the kind and the cause.
+func (self *DeviceError) Unwrap() []error {
+	if self.Cause == nil {
+		return []error{self.Kind}
+	}
+
+	return []error{self.Kind, self.Cause}
+}
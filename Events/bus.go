@@ -0,0 +1,147 @@
+// Provides an event bus linking sensors to actions, for declarative
+// "when X do Y" robot programs.
+//
+// Watchers poll sensors and publish typed events; handlers subscribe to the
+// event types they care about, optionally filtered, for every event or only
+// the first one:
+//
+//	bus := Events.NewBus()
+//	go Events.WatchTouch(bus, touch, stop, 20*time.Millisecond)
+//	go Events.WatchColor(bus, color, stop, 20*time.Millisecond)
+//
+//	Events.On(bus, Events.Do[Events.Bumped](func() { base.Stop() }))
+//	Events.On(bus, func(e Events.ColorChanged) { fmt.Println("now on", e.To) },
+//		func(e Events.ColorChanged) bool { return e.To == Sensors.Black })
+//	Events.Once(bus, func(e Events.Bumped) { Sound.PlayTone(440, 200) })
+//
+// Buttons of the brick, gamepads and the IR remote are published alike, so
+// that any of them can start a program:
+//
+//	go Events.WatchBrickButtons(bus, stop)
+//	go Events.WatchRemote(bus, ir, stop)
+//	Events.Wait[Events.ButtonPressed](bus)
+//
+// Any type can be published as an event. Handlers run synchronously in the
+// publishing goroutine, in the order they subscribed.
+package Events
+
+import (
+	"sync"
+)
+
+// Delivers published events to the handlers subscribed to their type.
+type Bus struct {
+	lock sync.Mutex
+	subs []*Subscription
+}
+
+// Creates a bus without subscribers.
+func NewBus() *Bus {
+	return new(Bus)
+}
+
+// A handler's registration, which can be cancelled.
+type Subscription struct {
+	bus    *Bus
+	once   bool
+	accept func(event interface{}) bool
+	handle func(event interface{})
+}
+
+// Stops delivering events to the handler. Safe to call more than once.
+func (self *Subscription) Cancel() {
+	self.bus.remove(self)
+}
+
+func (self *Bus) add(s *Subscription) *Subscription {
+	self.lock.Lock()
+	self.subs = append(self.subs, s)
+	self.lock.Unlock()
+
+	return s
+}
+
+func (self *Bus) remove(s *Subscription) bool {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	for i, sub := range self.subs {
+		if sub == s {
+			self.subs = append(self.subs[:i:i], self.subs[i+1:]...)
+			return true
+		}
+	}
+
+	return false
+}
+
+// Delivers `event` to every handler subscribed to its type whose filters all accept it.
+func (self *Bus) Publish(event interface{}) {
+	self.lock.Lock()
+	subs := append(([]*Subscription)(nil), self.subs...)
+	self.lock.Unlock()
+
+	for _, s := range subs {
+		if !s.accept(event) {
+			continue
+		}
+
+		// Claim a once-only subscription first so that concurrent publishers
+		// can't both deliver to it.
+		if s.once && !self.remove(s) {
+			continue
+		}
+
+		s.handle(event)
+	}
+}
+
+func subscribe[T any](bus *Bus, once bool, fn func(T), filters []func(T) bool) *Subscription {
+	accept := func(event interface{}) bool {
+		e, ok := event.(T)
+		if !ok {
+			return false
+		}
+
+		for _, filter := range filters {
+			if !filter(e) {
+				return false
+			}
+		}
+
+		return true
+	}
+
+	handle := func(event interface{}) {
+		fn(event.(T))
+	}
+
+	return bus.add(&Subscription{bus: bus, once: once, accept: accept, handle: handle})
+}
+
+// Calls `fn` for every published event of type T accepted by all `filters`.
+func On[T any](bus *Bus, fn func(T), filters ...func(T) bool) *Subscription {
+	return subscribe(bus, false, fn, filters)
+}
+
+// Calls `fn` for the first published event of type T accepted by all
+// `filters`, then cancels the subscription.
+func Once[T any](bus *Bus, fn func(T), filters ...func(T) bool) *Subscription {
+	return subscribe(bus, true, fn, filters)
+}
+
+// Adapts an action that doesn't need the event, such as a motor's Stop method,
+// into a handler of events of type T.
+func Do[T any](action func()) func(T) {
+	return func(T) {
+		action()
+	}
+}
+
+// Blocks until an event of type T accepted by all `filters` is published, and returns it.
+func Wait[T any](bus *Bus, filters ...func(T) bool) T {
+	events := make(chan T, 1)
+	Once(bus, func(e T) { events <- e }, filters...)
+
+	return <-events
+}
@@ -0,0 +1,150 @@
+package Events
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/jermon/GoEV3/Button"
+	"github.com/jermon/GoEV3/Input"
+	"github.com/jermon/GoEV3/Sensors"
+)
+
+// Devices buttons are pressed on.
+type InputDevice int
+
+const (
+	BrickDevice InputDevice = iota
+	GamepadDevice
+	RemoteDevice
+)
+
+// A button of any input device, so that programs can start, stop or select
+// modes with whichever the robot has at hand.
+type InputButton struct {
+	Device InputDevice
+	// Button.Kind of brick buttons, number of gamepad buttons and
+	// Sensors.Button of IR remote buttons.
+	Code int
+	// Channel of IR remote buttons.
+	Channel Sensors.Channel
+}
+
+// Returns the brick button of the given kind.
+func BrickButton(kind Button.Kind) InputButton {
+	return InputButton{Device: BrickDevice, Code: int(kind)}
+}
+
+// Returns the gamepad button with the given number, as Input.Gamepad numbers them.
+func GamepadButton(number int) InputButton {
+	return InputButton{Device: GamepadDevice, Code: number}
+}
+
+// Returns the IR remote button on the given channel.
+func RemoteButton(c Sensors.Channel, b Sensors.Button) InputButton {
+	return InputButton{Device: RemoteDevice, Code: int(b), Channel: c}
+}
+
+var brickButtonNames = map[Button.Kind]string{
+	Button.Up:     "up",
+	Button.Down:   "down",
+	Button.Left:   "left",
+	Button.Right:  "right",
+	Button.Enter:  "enter",
+	Button.Escape: "escape",
+}
+
+var remoteButtonNames = map[Sensors.Button]string{
+	Sensors.RedUp:    "red up",
+	Sensors.RedDown:  "red down",
+	Sensors.BlueUp:   "blue up",
+	Sensors.BlueDown: "blue down",
+	Sensors.Beacon:   "beacon",
+}
+
+func (self InputButton) String() string {
+	switch self.Device {
+	case BrickDevice:
+		if name, ok := brickButtonNames[Button.Kind(self.Code)]; ok {
+			return "brick " + name
+		}
+		return fmt.Sprintf("brick %d", self.Code)
+	case GamepadDevice:
+		return fmt.Sprintf("gamepad %d", self.Code)
+	}
+
+	if name, ok := remoteButtonNames[Sensors.Button(self.Code)]; ok {
+		return fmt.Sprintf("remote %d %s", self.Channel+1, name)
+	}
+	return fmt.Sprintf("remote %d %d", self.Channel+1, self.Code)
+}
+
+// Published when a button of any input device is pressed.
+type ButtonPressed struct {
+	Button InputButton
+}
+
+// Published when a button of any input device is released.
+type ButtonReleased struct {
+	Button InputButton
+}
+
+// Returns a filter matching presses of any of the given buttons:
+//
+//	Events.Wait(bus, Events.AnyOf(Events.BrickButton(Button.Enter), Events.RemoteButton(Sensors.Channel1, Sensors.Beacon)))
+func AnyOf(buttons ...InputButton) func(ButtonPressed) bool {
+	return func(e ButtonPressed) bool {
+		for _, b := range buttons {
+			if b == e.Button {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+func publishButton(bus *Bus, b InputButton, pressed bool) {
+	if pressed {
+		bus.Publish(ButtonPressed{b})
+	} else {
+		bus.Publish(ButtonReleased{b})
+	}
+}
+
+// Publishes ButtonPressed and ButtonReleased events for the brick buttons on
+// `bus` until a value is sent to `stop`.
+func WatchBrickButtons(bus *Bus, stop <-chan bool) {
+	Button.Listen(stop, func(kind Button.Kind, pressed bool) {
+		publishButton(bus, BrickButton(kind), pressed)
+	})
+}
+
+// Publishes ButtonPressed and ButtonReleased events for the gamepad's buttons
+// on `bus` until a value is sent to `stop`.
+func WatchGamepad(bus *Bus, pad *Input.Gamepad, stop <-chan bool) {
+	var lock sync.Mutex
+	stopped := false
+
+	pad.OnButton(func(number int, pressed bool) {
+		lock.Lock()
+		defer lock.Unlock()
+
+		if !stopped {
+			publishButton(bus, GamepadButton(number), pressed)
+		}
+	})
+
+	<-stop
+
+	lock.Lock()
+	stopped = true
+	lock.Unlock()
+}
+
+// Publishes ButtonPressed and ButtonReleased events for the IR remote buttons
+// of all channels on `bus` until a value is sent to `stop`. Each button of a
+// combination held together is reported on its own.
+func WatchRemote(bus *Bus, sensor *Sensors.InfraredSensor, stop <-chan bool) {
+	for e := range sensor.RemoteEvents(stop) {
+		publishButton(bus, RemoteButton(e.Channel, e.Button), e.Pressed)
+	}
+}
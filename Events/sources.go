@@ -0,0 +1,108 @@
+package Events
+
+import (
+	"time"
+
+	"github.com/jermon/GoEV3/Sensors"
+	"github.com/jermon/GoEV3/Units"
+	"github.com/jermon/GoEV3/utilities"
+)
+
+// Published when the color seen by a color sensor changes.
+type ColorChanged struct {
+	Port Sensors.InPort
+	From Sensors.Color
+	To   Sensors.Color
+}
+
+// Published when a touch sensor is pressed.
+type Bumped struct {
+	Port Sensors.InPort
+}
+
+// Published when a touch sensor is released.
+type Released struct {
+	Port Sensors.InPort
+}
+
+// Published when the distance measured by an ultrasonic sensor drops below a threshold.
+type DistanceBelow struct {
+	Port      Sensors.InPort
+	Threshold Units.Distance
+	Distance  Units.Distance
+}
+
+// Published when the distance measured by an ultrasonic sensor rises back
+// above a threshold it was below.
+type DistanceAbove struct {
+	Port      Sensors.InPort
+	Threshold Units.Distance
+	Distance  Units.Distance
+}
+
+// Calls `sample` every `interval` until a value is sent to `stop`, from the
+// shared polling scheduler, so that handlers run there and must return promptly.
+func poll(stop <-chan bool, interval time.Duration, sample func()) {
+	utilities.PollUntil(stop, interval, sample)
+}
+
+// Publishes ColorChanged events on `bus` until a value is sent to `stop`. The
+// color must be seen on two consecutive readings to count, which filters out
+// the colors seen briefly when crossing from one to another.
+func WatchColor(bus *Bus, sensor *Sensors.ColorSensor, stop <-chan bool, interval time.Duration) {
+	current := sensor.ReadColor()
+	candidate := current
+
+	poll(stop, interval, func() {
+		color := sensor.ReadColor()
+
+		if color != candidate {
+			candidate = color
+			return
+		}
+
+		if color != current {
+			bus.Publish(ColorChanged{sensor.Port(), current, color})
+			current = color
+		}
+	})
+}
+
+// Publishes Bumped and Released events on `bus` until a value is sent to `stop`.
+func WatchTouch(bus *Bus, sensor *Sensors.TouchSensor, stop <-chan bool, interval time.Duration) {
+	pressed := sensor.IsPressed()
+
+	poll(stop, interval, func() {
+		now := sensor.IsPressed()
+		if now == pressed {
+			return
+		}
+		pressed = now
+
+		if pressed {
+			bus.Publish(Bumped{sensor.Port()})
+		} else {
+			bus.Publish(Released{sensor.Port()})
+		}
+	})
+}
+
+// Publishes DistanceBelow events on `bus` when the measured distance drops
+// below `threshold`, and DistanceAbove events when it rises back above it,
+// until a value is sent to `stop`. Watch the same sensor again to use several thresholds.
+func WatchDistance(bus *Bus, sensor *Sensors.UltrasonicSensor, threshold Units.Distance, stop <-chan bool, interval time.Duration) {
+	below := false
+
+	poll(stop, interval, func() {
+		distance := sensor.Distance()
+
+		switch {
+		case !below && distance < threshold:
+			below = true
+			bus.Publish(DistanceBelow{sensor.Port(), threshold, distance})
+		case below && distance >= threshold:
+			below = false
+			bus.Publish(DistanceAbove{sensor.Port(), threshold, distance})
+		}
+	})
+}
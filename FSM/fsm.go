@@ -0,0 +1,305 @@
+// Provides a small finite state machine framework for robot behaviors.
+//
+// States have optional entry, exit and per-tick actions. Transitions are taken
+// when an event is fired (optionally guarded by a condition), when a condition
+// alone becomes true, or when a state times out. Sensor readings are turned into
+// events with FireWhen:
+//
+//	m := FSM.New("drive")
+//	m.OnEnter("drive", func() { left.Run(50); right.Run(50) })
+//	m.OnEnter("back off", func() { left.Run(-30); right.Run(-30) })
+//	m.FireWhen("bumped", func() bool { return touch.IsPressed() })
+//	m.AddTransition("drive", "back off", "bumped", nil)
+//	m.AddTimeout("back off", "drive", time.Second)
+//	m.Run(stop, 20*time.Millisecond)
+package FSM
+
+import (
+	"sync"
+	"time"
+)
+
+// Name of a state.
+type State string
+
+// Name of an event triggering transitions.
+type Event string
+
+type transition struct {
+	to    State
+	event Event
+	guard func() bool
+}
+
+type stateInfo struct {
+	onEnter     func()
+	onExit      func()
+	while       func()
+	timeout     time.Duration
+	timeoutTo   State
+	transitions []transition
+}
+
+type watcher struct {
+	event     Event
+	condition func() bool
+	last      bool
+}
+
+// A finite state machine. All actions run on the goroutine calling Step or Run.
+type Machine struct {
+	lock sync.Mutex
+
+	states    map[State]*stateInfo
+	watchers  []*watcher
+	current   State
+	enteredAt time.Time
+	entered   bool
+	pending   []Event
+
+	onTransition func(from State, to State, event Event)
+}
+
+// Creates a state machine starting in the given state.
+func New(initial State) *Machine {
+	m := new(Machine)
+	m.states = make(map[State]*stateInfo)
+	m.current = initial
+
+	return m
+}
+
+// The lock must be held.
+func (self *Machine) state(s State) *stateInfo {
+	info, ok := self.states[s]
+	if !ok {
+		info = new(stateInfo)
+		self.states[s] = info
+	}
+
+	return info
+}
+
+// Sets the action run when entering the given state.
+func (self *Machine) OnEnter(s State, fn func()) {
+	self.lock.Lock()
+	self.state(s).onEnter = fn
+	self.lock.Unlock()
+}
+
+// Sets the action run when leaving the given state.
+func (self *Machine) OnExit(s State, fn func()) {
+	self.lock.Lock()
+	self.state(s).onExit = fn
+	self.lock.Unlock()
+}
+
+// Sets the action run on every tick spent in the given state.
+func (self *Machine) While(s State, fn func()) {
+	self.lock.Lock()
+	self.state(s).while = fn
+	self.lock.Unlock()
+}
+
+// Adds a transition from one state to another. If `event` is empty the transition
+// is taken as soon as `guard` returns true; otherwise it's taken when the event
+// is fired and `guard` is nil or returns true.
+func (self *Machine) AddTransition(from State, to State, event Event, guard func() bool) {
+	self.lock.Lock()
+	info := self.state(from)
+	info.transitions = append(info.transitions, transition{to, event, guard})
+	self.state(to)
+	self.lock.Unlock()
+}
+
+// Leaves the `from` state for the `to` state after spending `d` in it.
+func (self *Machine) AddTimeout(from State, to State, d time.Duration) {
+	self.lock.Lock()
+	info := self.state(from)
+	info.timeout = d
+	info.timeoutTo = to
+	self.state(to)
+	self.lock.Unlock()
+}
+
+// Fires an event. It's processed on the next tick.
+func (self *Machine) Fire(event Event) {
+	self.lock.Lock()
+	self.pending = append(self.pending, event)
+	self.lock.Unlock()
+}
+
+// Fires `event` every time `condition` changes from false to true, e.g. when a
+// touch sensor gets pressed. Conditions are evaluated on every tick.
+func (self *Machine) FireWhen(event Event, condition func() bool) {
+	self.lock.Lock()
+	self.watchers = append(self.watchers, &watcher{event: event, condition: condition})
+	self.lock.Unlock()
+}
+
+// Registers a callback invoked after every transition, e.g. for logging.
+func (self *Machine) OnTransition(fn func(from State, to State, event Event)) {
+	self.lock.Lock()
+	self.onTransition = fn
+	self.lock.Unlock()
+}
+
+// Returns the current state.
+func (self *Machine) Current() State {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	return self.current
+}
+
+// Returns how long the machine has been in the current state.
+func (self *Machine) TimeInState() time.Duration {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	if !self.entered {
+		return 0
+	}
+
+	return time.Since(self.enteredAt)
+}
+
+// Forces a transition to the given state, running exit and entry actions.
+func (self *Machine) Goto(s State) {
+	self.transition(s, "")
+}
+
+func (self *Machine) transition(to State, event Event) {
+	self.lock.Lock()
+	from := self.current
+	exit := self.state(from).onExit
+	enter := self.state(to).onEnter
+	notify := self.onTransition
+	self.current = to
+	self.enteredAt = time.Now()
+	self.entered = true
+	self.lock.Unlock()
+
+	if exit != nil {
+		exit()
+	}
+
+	if enter != nil {
+		enter()
+	}
+
+	if notify != nil {
+		notify(from, to, event)
+	}
+}
+
+// Processes pending events, conditions and timeouts once, then runs the current
+// state's per-tick action. Returns the state the machine ends up in.
+func (self *Machine) Step() State {
+	self.lock.Lock()
+	if !self.entered {
+		self.entered = true
+		self.enteredAt = time.Now()
+		enter := self.state(self.current).onEnter
+		self.lock.Unlock()
+
+		if enter != nil {
+			enter()
+		}
+
+		self.lock.Lock()
+	}
+
+	watchers := append([]*watcher(nil), self.watchers...)
+	self.lock.Unlock()
+
+	for _, w := range watchers {
+		value := w.condition()
+		if value && !w.last {
+			self.Fire(w.event)
+		}
+		w.last = value
+	}
+
+	self.lock.Lock()
+	events := self.pending
+	self.pending = nil
+	self.lock.Unlock()
+
+	for _, event := range events {
+		if to, ok := self.match(event); ok {
+			self.transition(to, event)
+		}
+	}
+
+	if to, ok := self.match(""); ok {
+		self.transition(to, "")
+	}
+
+	self.lock.Lock()
+	info := self.state(self.current)
+	expired := info.timeout > 0 && time.Since(self.enteredAt) >= info.timeout
+	timeoutTo := info.timeoutTo
+	self.lock.Unlock()
+
+	if expired {
+		self.transition(timeoutTo, "")
+	}
+
+	self.lock.Lock()
+	while := self.state(self.current).while
+	current := self.current
+	self.lock.Unlock()
+
+	if while != nil {
+		while()
+	}
+
+	return current
+}
+
+// Finds the first transition from the current state matching the event.
+func (self *Machine) match(event Event) (State, bool) {
+	self.lock.Lock()
+	transitions := append([]transition(nil), self.state(self.current).transitions...)
+	self.lock.Unlock()
+
+	for _, t := range transitions {
+		if t.event != event {
+			continue
+		}
+
+		if event == "" && t.guard == nil {
+			continue
+		}
+
+		if t.guard == nil || t.guard() {
+			return t.to, true
+		}
+	}
+
+	return "", false
+}
+
+// Steps the machine every `tick` until a value is sent to `stop` or one of the
+// given final states is reached.
+func (self *Machine) Run(stop <-chan bool, tick time.Duration, final ...State) State {
+	ticker := time.NewTicker(tick)
+	defer ticker.Stop()
+
+	for {
+		current := self.Step()
+
+		for _, s := range final {
+			if current == s {
+				return current
+			}
+		}
+
+		select {
+		case <-stop:
+			return self.Current()
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,121 @@
+// Provides points and poses on the field, and transforms between the field
+// frame and the frame of the robot.
+//
+// The field frame is fixed to the floor; the robot frame moves with the
+// robot, its X axis pointing forward and its Y axis to the left. Distances are
+// measured in centimeters and angles in degrees, counter-clockwise being
+// positive, as throughout GoEV3:
+//
+//	pose := Geometry.Of(odometry)
+//	obstacle := pose.ToField(Geometry.Point{X: distance})
+//	bearing := pose.BearingTo(Geometry.Point{X: 100, Y: 50})
+package Geometry
+
+import (
+	"math"
+)
+
+// Anything that reports the robot pose, such as Drive.Odometry. `x` and `y`
+// are measured in centimeters and `theta` in degrees, counter-clockwise being
+// positive.
+type PoseSource interface {
+	Pose() (x float64, y float64, theta float64)
+}
+
+// A point, or a vector, in centimeters.
+type Point struct {
+	X, Y float64
+}
+
+// Returns the sum of two vectors.
+func (self Point) Add(other Point) Point {
+	return Point{self.X + other.X, self.Y + other.Y}
+}
+
+// Returns the vector from `other` to the point.
+func (self Point) Sub(other Point) Point {
+	return Point{self.X - other.X, self.Y - other.Y}
+}
+
+// Returns the length of the vector.
+func (self Point) Length() float64 {
+	return math.Hypot(self.X, self.Y)
+}
+
+// Returns the distance between two points.
+func (self Point) Distance(other Point) float64 {
+	return self.Sub(other).Length()
+}
+
+// Returns the direction of the vector in degrees, 0 along the X axis.
+func (self Point) Angle() float64 {
+	return math.Atan2(self.Y, self.X) * 180 / math.Pi
+}
+
+// Returns the vector rotated by `angle` degrees around the origin.
+func (self Point) Rotate(angle float64) Point {
+	sin, cos := math.Sincos(angle * math.Pi / 180)
+
+	return Point{self.X*cos - self.Y*sin, self.X*sin + self.Y*cos}
+}
+
+// A position on the field and the heading of the robot, in degrees.
+type Pose struct {
+	X, Y  float64
+	Theta float64
+}
+
+// Returns the pose reported by `source`.
+func Of(source PoseSource) Pose {
+	x, y, theta := source.Pose()
+	return Pose{x, y, theta}
+}
+
+// Returns the position.
+func (self Pose) Point() Point {
+	return Point{self.X, self.Y}
+}
+
+// Converts a point given in the field frame to the robot frame.
+func (self Pose) ToRobot(p Point) Point {
+	return p.Sub(self.Point()).Rotate(-self.Theta)
+}
+
+// Converts a point given in the robot frame, such as an obstacle seen by a
+// sensor, to the field frame.
+func (self Pose) ToField(p Point) Point {
+	return p.Rotate(self.Theta).Add(self.Point())
+}
+
+// Returns the pose reached by moving by `delta`, given in the robot frame.
+func (self Pose) Compose(delta Pose) Pose {
+	p := self.ToField(delta.Point())
+	return Pose{p.X, p.Y, NormalizeDegrees(self.Theta + delta.Theta)}
+}
+
+// Returns `other` expressed in the frame of the pose, so that
+// self.Compose(self.Relative(other)) is `other`.
+func (self Pose) Relative(other Pose) Pose {
+	p := self.ToRobot(other.Point())
+	return Pose{p.X, p.Y, NormalizeDegrees(other.Theta - self.Theta)}
+}
+
+// Returns the distance to a point.
+func (self Pose) DistanceTo(p Point) float64 {
+	return self.Point().Distance(p)
+}
+
+// Returns how far the robot must turn to face a point, in range [-180, 180).
+func (self Pose) BearingTo(p Point) float64 {
+	return NormalizeDegrees(p.Sub(self.Point()).Angle() - self.Theta)
+}
+
+// Normalizes an angle in degrees to the range [-180, 180).
+func NormalizeDegrees(angle float64) float64 {
+	angle = math.Mod(angle+180, 360)
+	if angle < 0 {
+		angle += 360
+	}
+
+	return angle - 180
+}
@@ -0,0 +1,75 @@
+package HiTechnic
+
+import (
+	"github.com/jermon/GoEV3/Sensors"
+	"github.com/jermon/GoEV3/Units"
+	"github.com/jermon/GoEV3/utilities"
+)
+
+// HiTechnic NXT angle sensor, which measures the rotation of an axle passed
+// through it.
+type AngleSensor struct {
+	sensor *Sensors.GenericSensor
+}
+
+// Provides access to an angle sensor at the given port. A missing sensor is
+// a fatal error; use OpenAngleSensor to handle it.
+func FindAngleSensor(port Sensors.InPort) *AngleSensor {
+	a, err := OpenAngleSensor(port)
+	if err != nil {
+		utilities.Fatal(err)
+	}
+
+	return a
+}
+
+// Provides access to an angle sensor at the given port, setting up the port
+// for it if needed. Returns an error matching Errors.ErrDeviceNotFound or
+// Errors.ErrPortMismatch instead of exiting if it can't be found.
+func OpenAngleSensor(port Sensors.InPort) (*AngleSensor, error) {
+	s, err := openSensor(port, DriverAngle)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AngleSensor{s}, nil
+}
+
+// Returns the underlying sensor, for attributes without a method.
+func (self *AngleSensor) Sensor() *Sensors.GenericSensor {
+	return self.sensor
+}
+
+// Returns the input port the sensor is connected to.
+func (self *AngleSensor) Port() Sensors.InPort {
+	return self.sensor.Port()
+}
+
+// Reads the angle of the axle within a turn, in [0, 360) degrees.
+func (self *AngleSensor) Angle() (Units.Angle, error) {
+	value, err := readValue(self.sensor, "ANGLE")
+	return Units.Angle(value) * Units.Degree, err
+}
+
+// Reads the angle the axle turned through since the last Reset, over any
+// number of turns.
+func (self *AngleSensor) AccumulatedAngle() (Units.Angle, error) {
+	value, err := readValue(self.sensor, "ANGLE-ACC")
+	return Units.Angle(value) * Units.Degree, err
+}
+
+// Reads the rotational speed of the axle.
+func (self *AngleSensor) Speed() (Units.AngularSpeed, error) {
+	value, err := readValue(self.sensor, "SPEED")
+	return Units.AngularSpeed(value) * Units.RevolutionPerMinute, err
+}
+
+// Makes the current angle 0, and restarts the accumulated angle from it.
+func (self *AngleSensor) Reset() error {
+	return self.sensor.SendCommand("RESET")
+}
+
+// Closes the sensor's attribute files kept open; see Sensors.GenericSensor.Close.
+func (self *AngleSensor) Close() {
+	self.sensor.Close()
+}
@@ -0,0 +1,88 @@
+package HiTechnic
+
+import (
+	"github.com/jermon/GoEV3/Sensors"
+	"github.com/jermon/GoEV3/utilities"
+)
+
+// HiTechnic NXT color sensor V2.
+type ColorSensor struct {
+	sensor *Sensors.GenericSensor
+}
+
+// Provides access to a color sensor V2 at the given port. A missing sensor is
+// a fatal error; use OpenColorSensor to handle it.
+func FindColorSensor(port Sensors.InPort) *ColorSensor {
+	c, err := OpenColorSensor(port)
+	if err != nil {
+		utilities.Fatal(err)
+	}
+
+	return c
+}
+
+// Provides access to a color sensor V2 at the given port, setting up the port
+// for it if needed. Returns an error matching Errors.ErrDeviceNotFound or
+// Errors.ErrPortMismatch instead of exiting if it can't be found.
+func OpenColorSensor(port Sensors.InPort) (*ColorSensor, error) {
+	s, err := openSensor(port, DriverColorV2)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ColorSensor{s}, nil
+}
+
+// Returns the underlying sensor, for attributes without a method.
+func (self *ColorSensor) Sensor() *Sensors.GenericSensor {
+	return self.sensor
+}
+
+// Returns the input port the sensor is connected to.
+func (self *ColorSensor) Port() Sensors.InPort {
+	return self.sensor.Port()
+}
+
+// Reads the color number, in range [0, 17]: 0 for black, then through the
+// colors of the spectrum, to 17 for white, as printed on the sensor.
+func (self *ColorSensor) ColorNumber() (int, error) {
+	value, err := readValue(self.sensor, "COLOR")
+	return int(value), err
+}
+
+// Reads the red, green and blue components of the reflected light, and its
+// white level, each in range [0, 255].
+func (self *ColorSensor) RGB() (r, g, b, white int, err error) {
+	values, err := readValues(self.sensor, "RGB", 4)
+	if err != nil {
+		return 0, 0, 0, 0, err
+	}
+
+	return int(values[0]), int(values[1]), int(values[2]), int(values[3]), nil
+}
+
+// Reads the red, green and blue components of the ambient light, with the
+// sensor's LED off, and its white level.
+func (self *ColorSensor) PassiveRGB() (r, g, b, white int, err error) {
+	values, err := readValues(self.sensor, "PASSIVE", 4)
+	if err != nil {
+		return 0, 0, 0, 0, err
+	}
+
+	return int(values[0]), int(values[1]), int(values[2]), int(values[3]), nil
+}
+
+// Sets the frequency of the mains, 50 or 60 Hz, whose flicker of artificial
+// light the sensor filters out.
+func (self *ColorSensor) SetMainsFrequency(hertz int) error {
+	if hertz == 50 {
+		return self.sensor.SendCommand("50HZ")
+	}
+
+	return self.sensor.SendCommand("60HZ")
+}
+
+// Closes the sensor's attribute files kept open; see Sensors.GenericSensor.Close.
+func (self *ColorSensor) Close() {
+	self.sensor.Close()
+}
@@ -0,0 +1,68 @@
+package HiTechnic
+
+import (
+	"github.com/jermon/GoEV3/Sensors"
+	"github.com/jermon/GoEV3/Units"
+	"github.com/jermon/GoEV3/utilities"
+)
+
+// HiTechnic NXT compass sensor.
+type Compass struct {
+	sensor *Sensors.GenericSensor
+}
+
+// Provides access to a compass at the given port. A missing sensor is a
+// fatal error; use OpenCompass to handle it.
+func FindCompass(port Sensors.InPort) *Compass {
+	c, err := OpenCompass(port)
+	if err != nil {
+		utilities.Fatal(err)
+	}
+
+	return c
+}
+
+// Provides access to a compass at the given port, setting up the port for it
+// if needed. Returns an error matching Errors.ErrDeviceNotFound or
+// Errors.ErrPortMismatch instead of exiting if it can't be found.
+func OpenCompass(port Sensors.InPort) (*Compass, error) {
+	s, err := openSensor(port, DriverCompass)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Compass{s}, nil
+}
+
+// Returns the underlying sensor, for attributes without a method.
+func (self *Compass) Sensor() *Sensors.GenericSensor {
+	return self.sensor
+}
+
+// Returns the input port the sensor is connected to.
+func (self *Compass) Port() Sensors.InPort {
+	return self.sensor.Port()
+}
+
+// Reads the heading, clockwise from magnetic north, in [0, 360) degrees.
+func (self *Compass) Heading() (Units.Angle, error) {
+	value, err := readValue(self.sensor, "COMPASS")
+	return Units.Angle(value) * Units.Degree, err
+}
+
+// Starts calibrating the compass for the magnetic fields of the robot. Turn
+// the robot slowly through at least one and a half full turns, taking about
+// 20 seconds, and then call EndCalibration.
+func (self *Compass) BeginCalibration() error {
+	return self.sensor.SendCommand("BEGIN-CAL")
+}
+
+// Ends the calibration started with BeginCalibration, storing it in the sensor.
+func (self *Compass) EndCalibration() error {
+	return self.sensor.SendCommand("END-CAL")
+}
+
+// Closes the sensor's attribute files kept open; see Sensors.GenericSensor.Close.
+func (self *Compass) Close() {
+	self.sensor.Close()
+}
@@ -0,0 +1,90 @@
+// Provides the common HiTechnic NXT sensors: the compass, the color sensor V2
+// and the angle sensor.
+//
+// These are I2C sensors, which the ports don't always detect on their own.
+// Their constructors look for the sensor first and, if it isn't there, put
+// the port into I2C mode and load the driver before waiting for it:
+//
+//	compass := HiTechnic.FindCompass(Sensors.InPort2)
+//	heading, err := compass.Heading()
+package HiTechnic
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jermon/GoEV3/Errors"
+	"github.com/jermon/GoEV3/Ports"
+	"github.com/jermon/GoEV3/Sensors"
+)
+
+// Drivers of the sensors.
+const (
+	DriverCompass = "ht-nxt-compass"
+	DriverColorV2 = "ht-nxt-color-v2"
+	DriverAngle   = "ht-nxt-angle"
+)
+
+// I2C address of the HiTechnic sensors, as ev3dev writes it.
+const i2cAddress = "0x01"
+
+// How long to wait for a sensor to appear once its driver is loaded.
+const setupTimeout = 5 * time.Second
+
+// Provides access to the sensor with `driver` at `port`, setting up the port
+// for it if it isn't found. Returns an error matching
+// Errors.ErrDeviceNotFound or Errors.ErrPortMismatch if it still isn't.
+func openSensor(port Sensors.InPort, driver string) (*Sensors.GenericSensor, error) {
+	s, err := Sensors.OpenSensor(port, Sensors.WithRequiredDriver(driver))
+	if err == nil {
+		return s, nil
+	}
+	if !errors.Is(err, Errors.ErrDeviceNotFound) && !errors.Is(err, Errors.ErrPortMismatch) {
+		return nil, err
+	}
+
+	p, perr := Ports.OpenPort(string(Sensors.CanonicalInPort(string(port))))
+	if perr != nil {
+		return nil, err
+	}
+
+	if p.Mode() != Ports.ModeNXTI2C {
+		if err := p.SetMode(Ports.ModeNXTI2C); err != nil {
+			return nil, err
+		}
+	}
+	// The port may detect the sensor once in I2C mode, without being told.
+	p.SetDevice(driver + " " + i2cAddress)
+
+	return p.WaitForSensor(driver, setupTimeout)
+}
+
+// Reads the values of `mode` of the sensor, returning an error matching
+// Errors.ErrOutOfRange if it reports fewer than `n`.
+func readValues(s *Sensors.GenericSensor, mode string, n int) ([]float64, error) {
+	values, err := s.ValuesInMode(mode)
+	if err != nil {
+		return nil, err
+	}
+	if len(values) < n {
+		return nil, &Errors.DeviceError{
+			Kind:   Errors.ErrOutOfRange,
+			Device: "sensor",
+			Port:   string(s.Port()),
+			Detail: fmt.Sprintf("mode %s has %d values, expected %d", mode, len(values), n),
+		}
+	}
+
+	return values, nil
+}
+
+// Reads the first value of `mode` of the sensor.
+func readValue(s *Sensors.GenericSensor, mode string) (float64, error) {
+	values, err := readValues(s, mode, 1)
+	if err != nil {
+		return 0, err
+	}
+
+	return values[0], nil
+}
@@ -0,0 +1,29 @@
+//go:build linux
+
+package Input
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// Size of struct timeval at the start of every input_event.
+const timevalSize = int(unsafe.Sizeof(syscall.Timeval{}))
+
+// Number of absolute axis codes (ABS_CNT).
+const absCount = 0x40
+
+// Reads the range and dead zone of an absolute axis of an event device with EVIOCGABS.
+func readAbsInfo(f *os.File, code int) (int32, int32, int32, bool) {
+	// struct input_absinfo: value, minimum, maximum, fuzz, flat, resolution.
+	var info [6]int32
+
+	request := uintptr(uint32(2)<<30 | uint32(unsafe.Sizeof(info))<<16 | 'E'<<8 | uint32(0x40+code))
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), request, uintptr(unsafe.Pointer(&info[0])))
+	if errno != 0 {
+		return 0, 0, 0, false
+	}
+
+	return info[1], info[2], info[4], true
+}
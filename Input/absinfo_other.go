@@ -0,0 +1,16 @@
+//go:build !linux
+
+package Input
+
+import (
+	"os"
+)
+
+const timevalSize = 16
+
+const absCount = 0
+
+// Event devices only exist on Linux.
+func readAbsInfo(f *os.File, code int) (int32, int32, int32, bool) {
+	return 0, 0, 0, false
+}
@@ -0,0 +1,448 @@
+// Provides APIs for reading gamepads and joysticks plugged into the EV3's USB port.
+//
+// Both the Linux joystick interface (/dev/input/js*) and generic event devices
+// (/dev/input/event*) are supported. Axes are normalized to the range [-1, 1].
+package Input
+
+import (
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Glob pattern matching joystick devices.
+const JoystickPattern = "/dev/input/js*"
+
+var ErrNoGamepad = errors.New("input: no gamepad found")
+
+// Kinds of gamepad events.
+type EventKind int
+
+const (
+	ButtonEvent EventKind = iota
+	AxisEvent
+)
+
+// A change of a button or an axis.
+type Event struct {
+	Time time.Time
+	Kind EventKind
+	// Button or axis number. Joystick devices number them from 0; event devices
+	// use the kernel key and axis codes, e.g. 0x130 for BTN_SOUTH.
+	Number int
+	// 1 for a pressed button, 0 for a released one; [-1, 1] for axes.
+	Value float64
+}
+
+// A gamepad or joystick.
+type Gamepad struct {
+	lock sync.Mutex
+
+	path    string
+	pattern string
+	file    *os.File
+	closed  bool
+
+	axes    map[int]float64
+	buttons map[int]bool
+	ranges  map[int]axisRange
+
+	onButton     []func(number int, pressed bool)
+	onAxis       []func(number int, value float64)
+	onConnection func(connected bool)
+	events       chan Event
+}
+
+type axisRange struct {
+	min, max, flat int32
+}
+
+// Opens the joystick or event device at the given path.
+func OpenGamepad(path string) (*Gamepad, error) {
+	g := newGamepad()
+
+	if err := g.open(path); err != nil {
+		return nil, err
+	}
+
+	go g.run()
+
+	return g, nil
+}
+
+// Opens the first joystick device found.
+func FindGamepad() (*Gamepad, error) {
+	paths, _ := filepath.Glob(JoystickPattern)
+	if len(paths) == 0 {
+		return nil, ErrNoGamepad
+	}
+	sort.Strings(paths)
+
+	return OpenGamepad(paths[0])
+}
+
+// Returns a gamepad that opens the first device matching `pattern` (e.g.
+// JoystickPattern) whenever one is plugged in, and keeps watching for a new one
+// after it is unplugged. Its axes and buttons read as released while disconnected.
+func WatchGamepad(pattern string) *Gamepad {
+	g := newGamepad()
+	g.pattern = pattern
+
+	go g.run()
+
+	return g
+}
+
+func newGamepad() *Gamepad {
+	g := new(Gamepad)
+	g.axes = make(map[int]float64)
+	g.buttons = make(map[int]bool)
+	g.ranges = make(map[int]axisRange)
+
+	return g
+}
+
+func (self *Gamepad) open(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+
+	self.lock.Lock()
+	self.path = path
+	self.file = f
+	self.ranges = make(map[int]axisRange)
+	if isEventDevice(path) {
+		for code := 0; code < absCount; code++ {
+			if min, max, flat, ok := readAbsInfo(f, code); ok && max > min {
+				self.ranges[code] = axisRange{min, max, flat}
+			}
+		}
+	}
+	notify := self.onConnection
+	self.lock.Unlock()
+
+	if notify != nil {
+		notify(true)
+	}
+
+	return nil
+}
+
+func isEventDevice(path string) bool {
+	return strings.HasPrefix(filepath.Base(path), "event")
+}
+
+func (self *Gamepad) run() {
+	for {
+		self.lock.Lock()
+		f, path, closed := self.file, self.path, self.closed
+		self.lock.Unlock()
+
+		if closed {
+			return
+		}
+
+		if f == nil {
+			if !self.reconnect() {
+				return
+			}
+			continue
+		}
+
+		var err error
+		if isEventDevice(path) {
+			err = self.readEvents(f)
+		} else {
+			err = self.readJoystick(f)
+		}
+
+		f.Close()
+		self.disconnected()
+
+		if err != nil && self.pattern == "" {
+			self.lock.Lock()
+			self.closed = true
+			if self.events != nil {
+				close(self.events)
+				self.events = nil
+			}
+			self.lock.Unlock()
+			return
+		}
+	}
+}
+
+func (self *Gamepad) reconnect() bool {
+	for {
+		self.lock.Lock()
+		closed := self.closed
+		self.lock.Unlock()
+
+		if closed || self.pattern == "" {
+			return false
+		}
+
+		paths, _ := filepath.Glob(self.pattern)
+		sort.Strings(paths)
+
+		for _, path := range paths {
+			if self.open(path) == nil {
+				return true
+			}
+		}
+
+		time.Sleep(500 * time.Millisecond)
+	}
+}
+
+func (self *Gamepad) disconnected() {
+	self.lock.Lock()
+	self.file = nil
+	self.axes = make(map[int]float64)
+	self.buttons = make(map[int]bool)
+	notify := self.onConnection
+	self.lock.Unlock()
+
+	if notify != nil {
+		notify(false)
+	}
+}
+
+// Joystick API event flags.
+const (
+	jsButton = 0x01
+	jsAxis   = 0x02
+	jsInit   = 0x80
+)
+
+func (self *Gamepad) readJoystick(f *os.File) error {
+	// struct js_event: time (ms), value, type, number.
+	b := make([]byte, 8)
+
+	for {
+		if _, err := readFull(f, b); err != nil {
+			return err
+		}
+
+		value := int16(binary.LittleEndian.Uint16(b[4:6]))
+		kind := b[6] &^ jsInit
+		number := int(b[7])
+
+		switch kind {
+		case jsButton:
+			self.dispatch(Event{time.Now(), ButtonEvent, number, float64(value)})
+		case jsAxis:
+			self.dispatch(Event{time.Now(), AxisEvent, number, float64(value) / 32767})
+		}
+	}
+}
+
+// Event device types.
+const (
+	evKey = 0x01
+	evAbs = 0x03
+)
+
+func (self *Gamepad) readEvents(f *os.File) error {
+	// struct input_event: timeval, type, code, value.
+	offset := timevalSize
+	b := make([]byte, offset+8)
+
+	for {
+		if _, err := readFull(f, b); err != nil {
+			return err
+		}
+
+		kind := binary.LittleEndian.Uint16(b[offset:])
+		code := int(binary.LittleEndian.Uint16(b[offset+2:]))
+		value := int32(binary.LittleEndian.Uint32(b[offset+4:]))
+
+		switch kind {
+		case evKey:
+			// Ignore autorepeat (value 2).
+			if value <= 1 {
+				self.dispatch(Event{time.Now(), ButtonEvent, code, float64(value)})
+			}
+		case evAbs:
+			self.dispatch(Event{time.Now(), AxisEvent, code, self.normalize(code, value)})
+		}
+	}
+}
+
+func readFull(f *os.File, b []byte) (int, error) {
+	n := 0
+	for n < len(b) {
+		m, err := f.Read(b[n:])
+		n += m
+		if err != nil {
+			return n, err
+		}
+	}
+
+	return n, nil
+}
+
+func (self *Gamepad) normalize(code int, value int32) float64 {
+	self.lock.Lock()
+	r, ok := self.ranges[code]
+	self.lock.Unlock()
+
+	if !ok {
+		return float64(value) / 32767
+	}
+
+	center := float64(r.min+r.max) / 2
+	half := float64(r.max-r.min) / 2
+	offset := float64(value) - center
+
+	if offset > -float64(r.flat) && offset < float64(r.flat) {
+		return 0
+	}
+
+	return clamp(offset / half)
+}
+
+func clamp(v float64) float64 {
+	if v > 1 {
+		return 1
+	}
+	if v < -1 {
+		return -1
+	}
+
+	return v
+}
+
+func (self *Gamepad) dispatch(e Event) {
+	self.lock.Lock()
+	if e.Kind == ButtonEvent {
+		self.buttons[e.Number] = e.Value != 0
+	} else {
+		self.axes[e.Number] = e.Value
+	}
+	onButton := append(([]func(int, bool))(nil), self.onButton...)
+	onAxis := append(([]func(int, float64))(nil), self.onAxis...)
+	if self.events != nil {
+		select {
+		case self.events <- e:
+		default:
+			// Drop events nobody is reading rather than stall the device.
+		}
+	}
+	self.lock.Unlock()
+
+	if e.Kind == ButtonEvent {
+		for _, fn := range onButton {
+			fn(e.Number, e.Value != 0)
+		}
+	} else {
+		for _, fn := range onAxis {
+			fn(e.Number, e.Value)
+		}
+	}
+}
+
+// Returns the current position of the given axis in range [-1, 1].
+func (self *Gamepad) Axis(number int) float64 {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	return self.axes[number]
+}
+
+// Reports whether the given button is currently pressed.
+func (self *Gamepad) Button(number int) bool {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	return self.buttons[number]
+}
+
+// Reports whether a device is currently open.
+func (self *Gamepad) Connected() bool {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	return self.file != nil
+}
+
+// Returns the path of the open device, or an empty string if disconnected.
+func (self *Gamepad) Path() string {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	if self.file == nil {
+		return ""
+	}
+
+	return self.path
+}
+
+// Registers a callback invoked whenever a button is pressed or released.
+func (self *Gamepad) OnButton(fn func(number int, pressed bool)) {
+	self.lock.Lock()
+	self.onButton = append(self.onButton, fn)
+	self.lock.Unlock()
+}
+
+// Registers a callback invoked whenever an axis moves.
+func (self *Gamepad) OnAxis(fn func(number int, value float64)) {
+	self.lock.Lock()
+	self.onAxis = append(self.onAxis, fn)
+	self.lock.Unlock()
+}
+
+// Registers a callback invoked when a watched gamepad is plugged in or unplugged.
+func (self *Gamepad) OnConnection(fn func(connected bool)) {
+	self.lock.Lock()
+	self.onConnection = fn
+	self.lock.Unlock()
+}
+
+// Returns a channel delivering all events. Events are dropped while the
+// channel's buffer is full. The channel is closed when the gamepad is closed or
+// an unwatched device is unplugged.
+func (self *Gamepad) Events() <-chan Event {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	if self.closed {
+		events := make(chan Event)
+		close(events)
+		return events
+	}
+
+	if self.events == nil {
+		self.events = make(chan Event, 64)
+	}
+
+	return self.events
+}
+
+// Closes the device and stops watching for new ones.
+func (self *Gamepad) Close() error {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	if self.closed {
+		return nil
+	}
+	self.closed = true
+
+	if self.events != nil {
+		close(self.events)
+		self.events = nil
+	}
+
+	if self.file != nil {
+		return self.file.Close()
+	}
+
+	return nil
+}
@@ -0,0 +1,249 @@
+package Input
+
+import (
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// A key read from a terminal. Printable keys are represented by their
+// lowercase character; special keys by the constants below.
+type Key rune
+
+const (
+	KeyUp Key = -1 - iota
+	KeyDown
+	KeyLeft
+	KeyRight
+	KeyEscape
+)
+
+const (
+	KeySpace Key = ' '
+	KeyEnter Key = '\r'
+)
+
+// Reads keys from a terminal, e.g. an SSH session, and tracks which ones are held.
+//
+// Terminals only report key presses and their autorepeat, never releases, so a
+// key counts as held until no repeat has arrived for the release timeout.
+// Autorepeat only repeats the most recently pressed key, so holding two keys
+// at once reads as holding the last one.
+type Keyboard struct {
+	lock sync.Mutex
+
+	reader  io.Reader
+	restore func()
+	closed  bool
+	done    chan bool
+
+	held        map[Key]time.Time
+	initialHold time.Duration
+	repeatHold  time.Duration
+	onKey       []func(key Key, pressed bool)
+}
+
+// Puts the terminal attached to standard input into unbuffered mode without
+// echo and reads keys from it. Ctrl-C still raises SIGINT. Close restores the
+// terminal settings.
+func OpenKeyboard() (*Keyboard, error) {
+	restore, err := makeRaw(os.Stdin)
+	if err != nil {
+		return nil, err
+	}
+
+	k := NewKeyboard(os.Stdin)
+	k.restore = restore
+
+	return k, nil
+}
+
+// Reads keys from `r`, which is used as is.
+func NewKeyboard(r io.Reader) *Keyboard {
+	k := new(Keyboard)
+	k.reader = r
+	k.done = make(chan bool)
+	k.held = make(map[Key]time.Time)
+	// Terminals typically start repeating after 500ms, then every 30ms.
+	k.initialHold = 600 * time.Millisecond
+	k.repeatHold = 150 * time.Millisecond
+
+	go k.read()
+	go k.expire()
+
+	return k
+}
+
+// Sets how long a key stays held after it is first pressed and after each
+// repeat. The first must exceed the terminal's autorepeat delay.
+func (self *Keyboard) SetReleaseTimeouts(initial time.Duration, repeat time.Duration) {
+	self.lock.Lock()
+	self.initialHold = initial
+	self.repeatHold = repeat
+	self.lock.Unlock()
+}
+
+// Registers a callback invoked when a key is pressed and when it is considered released.
+func (self *Keyboard) OnKey(fn func(key Key, pressed bool)) {
+	self.lock.Lock()
+	self.onKey = append(self.onKey, fn)
+	self.lock.Unlock()
+}
+
+// Reports whether the given key is currently held.
+func (self *Keyboard) Pressed(key Key) bool {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	_, ok := self.held[key]
+	return ok
+}
+
+// Returns the forward (-1, 0 or 1) and turn (-1, 0 or 1, left being positive)
+// commands of the held WASD or arrow keys.
+func (self *Keyboard) Drive() (float64, float64) {
+	forward, turn := 0.0, 0.0
+
+	if self.Pressed('w') || self.Pressed(KeyUp) {
+		forward++
+	}
+	if self.Pressed('s') || self.Pressed(KeyDown) {
+		forward--
+	}
+	if self.Pressed('a') || self.Pressed(KeyLeft) {
+		turn++
+	}
+	if self.Pressed('d') || self.Pressed(KeyRight) {
+		turn--
+	}
+
+	return forward, turn
+}
+
+// Stops reading and restores the terminal.
+func (self *Keyboard) Close() {
+	self.lock.Lock()
+	if self.closed {
+		self.lock.Unlock()
+		return
+	}
+	self.closed = true
+	restore := self.restore
+	self.lock.Unlock()
+
+	close(self.done)
+
+	if restore != nil {
+		restore()
+	}
+}
+
+func (self *Keyboard) read() {
+	b := make([]byte, 16)
+
+	for {
+		n, err := self.reader.Read(b)
+
+		for _, key := range parseKeys(b[:n]) {
+			self.press(key)
+		}
+
+		if err != nil {
+			return
+		}
+	}
+}
+
+// Splits terminal input into keys, decoding the escape sequences of the arrow keys.
+func parseKeys(b []byte) []Key {
+	var keys []Key
+
+	for i := 0; i < len(b); i++ {
+		c := b[i]
+
+		if c == 0x1b {
+			if i+2 < len(b) && (b[i+1] == '[' || b[i+1] == 'O') {
+				switch b[i+2] {
+				case 'A':
+					keys = append(keys, KeyUp)
+				case 'B':
+					keys = append(keys, KeyDown)
+				case 'C':
+					keys = append(keys, KeyRight)
+				case 'D':
+					keys = append(keys, KeyLeft)
+				}
+				i += 2
+				continue
+			}
+
+			keys = append(keys, KeyEscape)
+			continue
+		}
+
+		if c == '\n' {
+			c = '\r'
+		}
+		if c >= 'A' && c <= 'Z' {
+			c += 'a' - 'A'
+		}
+
+		keys = append(keys, Key(c))
+	}
+
+	return keys
+}
+
+func (self *Keyboard) press(key Key) {
+	self.lock.Lock()
+	if self.closed {
+		self.lock.Unlock()
+		return
+	}
+
+	_, wasHeld := self.held[key]
+	if wasHeld {
+		self.held[key] = time.Now().Add(self.repeatHold)
+	} else {
+		self.held[key] = time.Now().Add(self.initialHold)
+	}
+	callbacks := append(([]func(Key, bool))(nil), self.onKey...)
+	self.lock.Unlock()
+
+	if !wasHeld {
+		for _, fn := range callbacks {
+			fn(key, true)
+		}
+	}
+}
+
+func (self *Keyboard) expire() {
+	ticker := time.NewTicker(20 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-self.done:
+			return
+		case now := <-ticker.C:
+			var released []Key
+
+			self.lock.Lock()
+			for key, deadline := range self.held {
+				if now.After(deadline) {
+					delete(self.held, key)
+					released = append(released, key)
+				}
+			}
+			callbacks := append(([]func(Key, bool))(nil), self.onKey...)
+			self.lock.Unlock()
+
+			for _, key := range released {
+				for _, fn := range callbacks {
+					fn(key, false)
+				}
+			}
+		}
+	}
+}
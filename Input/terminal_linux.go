@@ -0,0 +1,38 @@
+//go:build linux
+
+package Input
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// Disables line buffering and echo on the terminal, returning a function that
+// restores the previous settings.
+func makeRaw(f *os.File) (func(), error) {
+	var saved syscall.Termios
+	if err := termios(f, syscall.TCGETS, &saved); err != nil {
+		return nil, err
+	}
+
+	raw := saved
+	raw.Lflag &^= syscall.ICANON | syscall.ECHO
+	raw.Cc[syscall.VMIN] = 1
+	raw.Cc[syscall.VTIME] = 0
+
+	if err := termios(f, syscall.TCSETS, &raw); err != nil {
+		return nil, err
+	}
+
+	return func() { termios(f, syscall.TCSETS, &saved) }, nil
+}
+
+func termios(f *os.File, request uintptr, t *syscall.Termios) error {
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), request, uintptr(unsafe.Pointer(t)))
+	if errno != 0 {
+		return errno
+	}
+
+	return nil
+}
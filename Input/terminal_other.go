@@ -0,0 +1,13 @@
+//go:build !linux
+
+package Input
+
+import (
+	"errors"
+	"os"
+)
+
+// Terminal control is only implemented for Linux.
+func makeRaw(f *os.File) (func(), error) {
+	return nil, errors.New("input: raw terminal mode is not supported on this platform")
+}
@@ -0,0 +1,112 @@
+package LED
+
+import (
+	"time"
+
+	"github.com/jermon/GoEV3/Platform"
+	"github.com/jermon/GoEV3/utilities"
+)
+
+// Kernel triggers driving an LED on its own.
+type Trigger string
+
+const (
+	// No trigger: the LED keeps the brightness set.
+	TriggerNone Trigger = "none"
+	// Blinks with the on and off times set with Flash.
+	TriggerTimer = "timer"
+	// Double-blinks like a heartbeat, faster with the system load.
+	TriggerHeartbeat = "heartbeat"
+	// Stays fully on.
+	TriggerDefaultOn = "default-on"
+)
+
+// Highest brightness of the EV3 LEDs, used if theirs can't be read.
+const defaultMaxBrightness = 255
+
+// Returns the single-color LEDs making up `color`.
+func components(color Color) []Color {
+	if color == Amber {
+		return []Color{Green, Red}
+	}
+
+	return []Color{color}
+}
+
+// Writes `value` to the attribute of each LED making up `color` at `position`.
+// Does nothing on platforms without status LEDs.
+func write[T utilities.Value](color Color, position Position, attribute string, value T) {
+	if !Platform.Current().LEDs {
+		return
+	}
+
+	for _, c := range components(color) {
+		utilities.WriteValue(findFilename(c, position), attribute, value)
+	}
+}
+
+// Returns the highest brightness of the LEDs.
+func MaxBrightness() int {
+	if !Platform.Current().LEDs {
+		return 0
+	}
+
+	max, err := utilities.ReadValue[int](findFilename(Green, Left), "max_brightness")
+	if err != nil {
+		return defaultMaxBrightness
+	}
+
+	return max
+}
+
+// Sets the brightness of the given LED, from 0 for off to MaxBrightness.
+func SetBrightness(color Color, position Position, brightness int) {
+	write(color, position, "brightness", brightness)
+}
+
+// Lights the LED at `position` in `color` only, turning its other colors off
+// and stopping any trigger.
+func SetColor(position Position, color Color) {
+	Off(position)
+	SetBrightness(color, position, MaxBrightness())
+}
+
+// Lights both LEDs in `color` only:
+//
+//	LED.SetBoth(LED.Amber)
+func SetBoth(color Color) {
+	SetColor(Left, color)
+	SetColor(Right, color)
+}
+
+// Turns off the LED at `position`, stopping any trigger.
+func Off(position Position) {
+	SetTrigger(Amber, position, TriggerNone)
+	SetBrightness(Amber, position, 0)
+}
+
+// Turns off both LEDs.
+func AllOff() {
+	Off(Left)
+	Off(Right)
+}
+
+// Sets the trigger driving the given LED.
+func SetTrigger(color Color, position Position, trigger Trigger) {
+	write(color, position, "trigger", string(trigger))
+}
+
+// Blinks the given LED, `on` then `off`, until its color or trigger is set again:
+//
+//	LED.Flash(LED.Red, LED.Left, 200*time.Millisecond, 800*time.Millisecond)
+func Flash(color Color, position Position, on time.Duration, off time.Duration) {
+	SetTrigger(color, position, TriggerTimer)
+	// The timer trigger creates the delay attributes once set.
+	write(color, position, "delay_on", on.Milliseconds())
+	write(color, position, "delay_off", off.Milliseconds())
+}
+
+// Beats the given LED like a heartbeat, until its color or trigger is set again.
+func Heartbeat(color Color, position Position) {
+	SetTrigger(color, position, TriggerHeartbeat)
+}
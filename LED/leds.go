@@ -3,9 +3,8 @@ package LED
 
 import (
 	"fmt"
+	"github.com/jermon/GoEV3/Platform"
 	"github.com/jermon/GoEV3/utilities"
-	"log"
-	"os"
 )
 
 // Constants for the LED positions (left and right).
@@ -27,35 +26,44 @@ const (
 
 func findFilename(color Color, position Position) string {
 	if color == Amber {
-		log.Fatal("Amber colors must be decomposed into green and red")
+		utilities.Fatalf("Amber colors must be decomposed into green and red")
 	}
 
 	filename := fmt.Sprintf("/sys/class/leds/ev3:%s:%s:ev3dev", string(position), string(color))
 
-	if _, err := os.Stat(filename); os.IsNotExist(err) {
-		log.Fatal("Cannot find the LED interface\n", filename)
+	if !utilities.Exists(filename) {
+		utilities.Fatalf("Cannot find the LED interface %s", filename)
 	}
 
 	return filename
 }
 
 // Turns on the given LED with the specified color.
+// Does nothing on platforms without status LEDs.
 func TurnOn(color Color, position Position) {
+	if !Platform.Current().LEDs {
+		return
+	}
+
 	if color == Amber {
-		utilities.WriteIntValue(findFilename(Green, position), "brightness", 1)
-		utilities.WriteIntValue(findFilename(Red, position), "brightness", 1)
+		utilities.WriteValue(findFilename(Green, position), "brightness", 1)
+		utilities.WriteValue(findFilename(Red, position), "brightness", 1)
 	} else {
-		utilities.WriteIntValue(findFilename(color, position), "brightness", 1)
+		utilities.WriteValue(findFilename(color, position), "brightness", 1)
 	}
 }
 
 // Turns off the given LED with the specified color.
+// Does nothing on platforms without status LEDs.
 func TurnOff(color Color, position Position) {
+	if !Platform.Current().LEDs {
+		return
+	}
+
 	if color == Amber {
-		utilities.WriteIntValue(findFilename(Green, position), "brightness", 0)
-		utilities.WriteIntValue(findFilename(Red, position), "brightness", 0)
+		utilities.WriteValue(findFilename(Green, position), "brightness", 0)
+		utilities.WriteValue(findFilename(Red, position), "brightness", 0)
 	} else {
-		utilities.WriteIntValue(findFilename(color, position), "brightness", 0)
+		utilities.WriteValue(findFilename(color, position), "brightness", 0)
 	}
 }
-
@@ -0,0 +1,361 @@
+// Provides the LEGO EV3 mailbox messaging protocol.
+//
+// Mailbox messages let a GoEV3 program exchange named text, number and logic
+// values with bricks running the stock firmware and with the official LEGO
+// programming apps, which use the same "WRITEMAILBOX" system command.
+package Mailbox
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"sync"
+
+	"github.com/jermon/GoEV3/Bluetooth"
+)
+
+const (
+	systemCommandNoReply = 0x81
+	writeMailbox         = 0x9E
+)
+
+// The RFCOMM channel EV3 bricks use for their serial port profile.
+const BluetoothChannel = 1
+
+// The TCP port mailbox connections use by default, as EV3 bricks do over Wi-Fi.
+const TCPPort = 5555
+
+// Maximum mailbox name length, excluding the terminating zero.
+const MaxNameLength = 254
+
+var ErrNotMailboxMessage = errors.New("mailbox: received a command that is not a mailbox write")
+
+// A message delivered to a named mailbox.
+type Message struct {
+	Mailbox string
+	Payload []byte
+}
+
+// Creates a text message. EV3 text mailboxes carry zero-terminated strings.
+func Text(mailbox string, text string) Message {
+	return Message{mailbox, append([]byte(text), 0)}
+}
+
+// Creates a numeric message. EV3 numbers are 32-bit floats.
+func Number(mailbox string, value float32) Message {
+	payload := make([]byte, 4)
+	binary.LittleEndian.PutUint32(payload, math.Float32bits(value))
+
+	return Message{mailbox, payload}
+}
+
+// Creates a logic (boolean) message.
+func Logic(mailbox string, value bool) Message {
+	if value {
+		return Message{mailbox, []byte{1}}
+	}
+
+	return Message{mailbox, []byte{0}}
+}
+
+// Interprets the payload as text.
+func (self Message) Text() string {
+	payload := self.Payload
+	for i, b := range payload {
+		if b == 0 {
+			payload = payload[:i]
+			break
+		}
+	}
+
+	return string(payload)
+}
+
+// Interprets the payload as a number. Returns 0 if the payload isn't 4 bytes long.
+func (self Message) Number() float32 {
+	if len(self.Payload) != 4 {
+		return 0
+	}
+
+	return math.Float32frombits(binary.LittleEndian.Uint32(self.Payload))
+}
+
+// Interprets the payload as a logic value.
+func (self Message) Logic() bool {
+	return len(self.Payload) > 0 && self.Payload[0] != 0
+}
+
+// Serializes the message into a WRITEMAILBOX system command with the given message counter.
+func (self Message) Encode(counter uint16) ([]byte, error) {
+	if len(self.Mailbox) > MaxNameLength {
+		return nil, fmt.Errorf("mailbox: name %q is too long", self.Mailbox)
+	}
+	if len(self.Payload) > math.MaxUint16 {
+		return nil, errors.New("mailbox: payload is too long")
+	}
+
+	body := make([]byte, 0, 8+len(self.Mailbox)+len(self.Payload))
+	body = binary.LittleEndian.AppendUint16(body, counter)
+	body = append(body, systemCommandNoReply, writeMailbox, byte(len(self.Mailbox)+1))
+	body = append(body, self.Mailbox...)
+	body = append(body, 0)
+	body = binary.LittleEndian.AppendUint16(body, uint16(len(self.Payload)))
+	body = append(body, self.Payload...)
+
+	if len(body) > math.MaxUint16 {
+		return nil, errors.New("mailbox: message is too long")
+	}
+
+	return append(binary.LittleEndian.AppendUint16(nil, uint16(len(body))), body...), nil
+}
+
+// Reads one length-prefixed command and decodes it as a mailbox message.
+func ReadMessage(r io.Reader) (Message, error) {
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return Message{}, err
+	}
+
+	body := make([]byte, binary.LittleEndian.Uint16(header))
+	if _, err := io.ReadFull(r, body); err != nil {
+		return Message{}, err
+	}
+
+	return Decode(body)
+}
+
+// Decodes the body of a WRITEMAILBOX system command, i.e. a command without
+// its length prefix.
+func Decode(body []byte) (Message, error) {
+	// Counter, command type, system command and name length.
+	if len(body) < 5 {
+		return Message{}, errors.New("mailbox: message is too short")
+	}
+
+	if body[2] != systemCommandNoReply || body[3] != writeMailbox {
+		return Message{}, ErrNotMailboxMessage
+	}
+
+	nameLength := int(body[4])
+	rest := body[5:]
+	if nameLength == 0 || len(rest) < nameLength+2 {
+		return Message{}, errors.New("mailbox: malformed mailbox name")
+	}
+
+	name := string(rest[:nameLength-1])
+	rest = rest[nameLength:]
+
+	payloadLength := int(binary.LittleEndian.Uint16(rest))
+	rest = rest[2:]
+	if len(rest) < payloadLength {
+		return Message{}, errors.New("mailbox: truncated payload")
+	}
+
+	return Message{name, append([]byte(nil), rest[:payloadLength]...)}, nil
+}
+
+// A mailbox connection over any byte stream, e.g. Bluetooth RFCOMM.
+type Conn struct {
+	rw io.ReadWriteCloser
+
+	writeLock sync.Mutex
+	counter   uint16
+
+	lock     sync.Mutex
+	handlers map[string][]func(Message)
+}
+
+// Wraps an established byte stream.
+func NewConn(rw io.ReadWriteCloser) *Conn {
+	c := new(Conn)
+	c.rw = rw
+	c.handlers = make(map[string][]func(Message))
+
+	return c
+}
+
+// Connects to the brick or computer with the given Bluetooth address. The devices must already be paired.
+func DialBluetooth(address string) (*Conn, error) {
+	conn, err := Bluetooth.Dial(address, BluetoothChannel)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewConn(conn), nil
+}
+
+// Connects to a GoEV3 program or computer listening with ListenTCP at `address`,
+// a host with an optional port, TCPPort by default.
+func DialTCP(address string) (*Conn, error) {
+	conn, err := net.Dial("tcp", withDefaultPort(address))
+	if err != nil {
+		return nil, err
+	}
+
+	return NewConn(conn), nil
+}
+
+func withDefaultPort(address string) string {
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		return net.JoinHostPort(address, fmt.Sprint(TCPPort))
+	}
+
+	return address
+}
+
+// Closes the connection.
+func (self *Conn) Close() error {
+	return self.rw.Close()
+}
+
+// Sends a message.
+func (self *Conn) Send(m Message) error {
+	self.writeLock.Lock()
+	defer self.writeLock.Unlock()
+
+	data, err := m.Encode(self.counter)
+	if err != nil {
+		return err
+	}
+	self.counter++
+
+	_, err = self.rw.Write(data)
+
+	return err
+}
+
+// Sends text to the given mailbox.
+func (self *Conn) SendText(mailbox string, text string) error {
+	return self.Send(Text(mailbox, text))
+}
+
+// Sends a number to the given mailbox.
+func (self *Conn) SendNumber(mailbox string, value float32) error {
+	return self.Send(Number(mailbox, value))
+}
+
+// Sends a logic value to the given mailbox.
+func (self *Conn) SendLogic(mailbox string, value bool) error {
+	return self.Send(Logic(mailbox, value))
+}
+
+// Sends a value to the given mailbox as the message type matching it: text
+// for a string, logic for a bool, and a number for any integer or float.
+func (self *Conn) SendValue(mailbox string, value interface{}) error {
+	switch v := value.(type) {
+	case string:
+		return self.SendText(mailbox, v)
+	case bool:
+		return self.SendLogic(mailbox, v)
+	case float32:
+		return self.SendNumber(mailbox, v)
+	case float64:
+		return self.SendNumber(mailbox, float32(v))
+	case int:
+		return self.SendNumber(mailbox, float32(v))
+	case int8:
+		return self.SendNumber(mailbox, float32(v))
+	case int16:
+		return self.SendNumber(mailbox, float32(v))
+	case int32:
+		return self.SendNumber(mailbox, float32(v))
+	case int64:
+		return self.SendNumber(mailbox, float32(v))
+	case uint:
+		return self.SendNumber(mailbox, float32(v))
+	case uint8:
+		return self.SendNumber(mailbox, float32(v))
+	case uint16:
+		return self.SendNumber(mailbox, float32(v))
+	case uint32:
+		return self.SendNumber(mailbox, float32(v))
+	case uint64:
+		return self.SendNumber(mailbox, float32(v))
+	default:
+		return fmt.Errorf("mailbox: can't send a value of type %T", value)
+	}
+}
+
+// Waits for the next mailbox message, skipping other commands.
+func (self *Conn) Receive() (Message, error) {
+	for {
+		m, err := ReadMessage(self.rw)
+		if err == ErrNotMailboxMessage {
+			continue
+		}
+
+		return m, err
+	}
+}
+
+// Registers a callback invoked by Serve for every message sent to the given mailbox.
+func (self *Conn) OnMessage(mailbox string, fn func(Message)) {
+	self.lock.Lock()
+	self.handlers[mailbox] = append(self.handlers[mailbox], fn)
+	self.lock.Unlock()
+}
+
+// Receives messages and dispatches them to the registered callbacks until the
+// connection fails or is closed.
+func (self *Conn) Serve() error {
+	for {
+		m, err := self.Receive()
+		if err != nil {
+			return err
+		}
+
+		self.lock.Lock()
+		handlers := append(([]func(Message))(nil), self.handlers[m.Mailbox]...)
+		self.lock.Unlock()
+
+		for _, fn := range handlers {
+			fn(m)
+		}
+	}
+}
+
+// Accepts mailbox connections over Bluetooth or TCP.
+type Listener struct {
+	accept func() (io.ReadWriteCloser, error)
+	close  func() error
+}
+
+// Listens for incoming Bluetooth mailbox connections from paired devices.
+func ListenBluetooth() (*Listener, error) {
+	l, err := Bluetooth.Listen(BluetoothChannel)
+	if err != nil {
+		return nil, err
+	}
+
+	accept := func() (io.ReadWriteCloser, error) { return l.Accept() }
+	return &Listener{accept, l.Close}, nil
+}
+
+// Listens for incoming TCP mailbox connections on `address`, e.g. ":5555".
+func ListenTCP(address string) (*Listener, error) {
+	l, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, err
+	}
+
+	accept := func() (io.ReadWriteCloser, error) { return l.Accept() }
+	return &Listener{accept, l.Close}, nil
+}
+
+// Waits for and returns the next connection.
+func (self *Listener) Accept() (*Conn, error) {
+	conn, err := self.accept()
+	if err != nil {
+		return nil, err
+	}
+
+	return NewConn(conn), nil
+}
+
+// Stops listening.
+func (self *Listener) Close() error {
+	return self.close()
+}
@@ -0,0 +1,233 @@
+// Provides occupancy-grid mapping from distance sensor sweeps.
+//
+// The field is divided into square cells that accumulate evidence of being free
+// or occupied, in log-odds form. Every distance reading marks the cells along the
+// sensor beam as free and the cell where the beam ended as occupied. Readings
+// are anchored to the robot pose reported by an odometry source.
+package Mapping
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jermon/GoEV3/Geometry"
+	"github.com/jermon/GoEV3/Motor"
+	"github.com/jermon/GoEV3/Sensors"
+)
+
+// Anything that reports the robot pose. `x` and `y` are measured in centimeters
+// and `theta` in degrees, counter-clockwise being positive.
+type PoseSource = Geometry.PoseSource
+
+// Log-odds increments and limits.
+const (
+	occupiedEvidence = 0.85
+	freeEvidence     = -0.4
+	maxEvidence      = 6.0
+)
+
+// Maximum range of the EV3 ultrasonic sensor in centimeters.
+const UltrasonicMaxRange = 255
+
+// Occupancy grid covering a rectangular area of the field.
+type Grid struct {
+	lock sync.Mutex
+
+	resolution float64
+	originX    float64
+	originY    float64
+	width      int
+	height     int
+	cells      []float64
+}
+
+// Creates a grid covering `width` x `height` centimeters with its lower left
+// corner at (originX, originY). Each cell is `resolution` centimeters wide.
+func NewGrid(originX float64, originY float64, width float64, height float64, resolution float64) *Grid {
+	g := new(Grid)
+	g.resolution = resolution
+	g.originX = originX
+	g.originY = originY
+	g.width = int(math.Ceil(width / resolution))
+	g.height = int(math.Ceil(height / resolution))
+	g.cells = make([]float64, g.width*g.height)
+
+	return g
+}
+
+// Returns the grid size in cells.
+func (self *Grid) Size() (int, int) {
+	return self.width, self.height
+}
+
+// Returns the cell containing the given field coordinates and whether it lies on the grid.
+func (self *Grid) Cell(x float64, y float64) (int, int, bool) {
+	cx := int(math.Floor((x - self.originX) / self.resolution))
+	cy := int(math.Floor((y - self.originY) / self.resolution))
+
+	return cx, cy, cx >= 0 && cy >= 0 && cx < self.width && cy < self.height
+}
+
+// Returns the probability in range [0, 1] that the cell at the given field
+// coordinates is occupied. Unobserved cells report 0.5.
+func (self *Grid) Probability(x float64, y float64) float64 {
+	cx, cy, ok := self.Cell(x, y)
+	if !ok {
+		return 0.5
+	}
+
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	return 1 - 1/(1+math.Exp(self.cells[cy*self.width+cx]))
+}
+
+// Forgets all accumulated evidence.
+func (self *Grid) Clear() {
+	self.lock.Lock()
+	for i := range self.cells {
+		self.cells[i] = 0
+	}
+	self.lock.Unlock()
+}
+
+// The lock must be held.
+func (self *Grid) update(cx int, cy int, evidence float64) {
+	if cx < 0 || cy < 0 || cx >= self.width || cy >= self.height {
+		return
+	}
+
+	i := cy*self.width + cx
+	self.cells[i] = math.Max(-maxEvidence, math.Min(maxEvidence, self.cells[i]+evidence))
+}
+
+// Integrates a reading of `distance` centimeters taken at (x, y) by a sensor
+// pointing in the direction `theta` (degrees). Readings at or beyond `maxRange`
+// only clear the cells along the beam.
+func (self *Grid) AddReading(x float64, y float64, theta float64, distance float64, maxRange float64) {
+	hit := distance < maxRange
+	distance = math.Min(distance, maxRange)
+
+	rad := theta * math.Pi / 180
+	endX := x + distance*math.Cos(rad)
+	endY := y + distance*math.Sin(rad)
+
+	x0, y0, _ := self.Cell(x, y)
+	x1, y1, _ := self.Cell(endX, endY)
+
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	// Bresenham's line algorithm.
+	dx, dy := abs(x1-x0), -abs(y1-y0)
+	sx, sy := sign(x1-x0), sign(y1-y0)
+	e := dx + dy
+
+	for x0 != x1 || y0 != y1 {
+		self.update(x0, y0, freeEvidence)
+
+		e2 := 2 * e
+		if e2 >= dy {
+			e += dy
+			x0 += sx
+		}
+		if e2 <= dx {
+			e += dx
+			y0 += sy
+		}
+	}
+
+	if hit {
+		self.update(x1, y1, occupiedEvidence)
+	} else {
+		self.update(x1, y1, freeEvidence)
+	}
+}
+
+// Integrates a reading from a sensor pointing `angle` degrees relative to the
+// robot heading, at the current pose reported by `pose`.
+func (self *Grid) AddPoseReading(pose PoseSource, angle float64, distance float64, maxRange float64) {
+	x, y, theta := pose.Pose()
+	self.AddReading(x, y, theta+angle, distance, maxRange)
+}
+
+// Sweeps an ultrasonic sensor mounted on a motor from `from` to `to` motor
+// degrees in increments of `step`, integrating a reading at every position.
+// `ratio` is the number of sensor degrees per motor degree, and the motor
+// position 0 must correspond to the sensor facing forward.
+func (self *Grid) Sweep(pose PoseSource, m *Motor.Motor, sensor *Sensors.UltrasonicSensor, from int32, to int32, step int32, ratio float64) {
+	if step == 0 {
+		return
+	}
+	if (to-from)*step < 0 {
+		step = -step
+	}
+
+	for position := from; (step > 0 && position <= to) || (step < 0 && position >= to); position += step {
+		m.Turn(Motor.CommandRunToAbsPos, int64(position))
+		waitForStop(m, 2*time.Second)
+
+		angle := float64(m.CurrentPosition()) * ratio
+		self.AddPoseReading(pose, angle, float64(sensor.ReadDistance()), UltrasonicMaxRange)
+	}
+}
+
+func waitForStop(m *Motor.Motor, timeout time.Duration) {
+	deadline := time.Now().Add(timeout)
+
+	for time.Now().Before(deadline) {
+		if !strings.Contains(m.GetState(), "running") {
+			return
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// Renders the grid as a grayscale image, one pixel per cell: occupied cells are
+// black, free cells white and unobserved cells gray. North is up.
+func (self *Grid) Image() *image.Gray {
+	img := image.NewGray(image.Rect(0, 0, self.width, self.height))
+
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	for cy := 0; cy < self.height; cy++ {
+		for cx := 0; cx < self.width; cx++ {
+			p := 1 - 1/(1+math.Exp(self.cells[cy*self.width+cx]))
+			img.SetGray(cx, self.height-1-cy, color.Gray{Y: uint8(math.Round((1 - p) * 255))})
+		}
+	}
+
+	return img
+}
+
+// Writes the grid image in PNG format.
+func (self *Grid) WritePNG(w io.Writer) error {
+	return png.Encode(w, self.Image())
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+
+	return v
+}
+
+func sign(v int) int {
+	switch {
+	case v > 0:
+		return 1
+	case v < 0:
+		return -1
+	}
+
+	return 0
+}
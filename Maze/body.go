@@ -0,0 +1,117 @@
+package Maze
+
+import (
+	"math"
+	"sync"
+
+	"github.com/jermon/GoEV3/Drive"
+	"github.com/jermon/GoEV3/Sensors"
+)
+
+// Returns the distance in centimeters measured by an ultrasonic sensor.
+func UltrasonicDistance(sensor *Sensors.UltrasonicSensor) func() float64 {
+	return func() float64 { return sensor.Distance().Centimeters() }
+}
+
+// Returns the distance in centimeters estimated from the proximity measured by
+// an infrared sensor, roughly 70 cm at 100%.
+func InfraredDistance(sensor *Sensors.InfraredSensor) func() float64 {
+	return func() float64 { return float64(sensor.ReadProximity()) * 0.7 }
+}
+
+// A Body driving a drive base from cell to cell with its wheel encoders and
+// sensing walls with distance sensors, in a maze of square cells of a given
+// size.
+type DriveBody struct {
+	lock sync.Mutex
+
+	base     *Drive.DriveBase
+	cellSize float64
+	speed    int16
+	sensors  map[Side]func() float64
+	// Distance in centimeters below which a sensor sees a wall of the current cell.
+	threshold float64
+}
+
+// Creates a body driving `base` in a maze of cells `cellSize` centimeters wide.
+func NewDriveBody(base *Drive.DriveBase, cellSize float64) *DriveBody {
+	b := new(DriveBody)
+	b.base = base
+	b.cellSize = cellSize
+	b.speed = 30
+	b.sensors = make(map[Side]func() float64)
+	// Halfway between the walls of this cell and those of the next one, for
+	// sensors near the middle of the robot.
+	b.threshold = cellSize
+
+	return b
+}
+
+// Sets the functions measuring the distance in centimeters to the walls in
+// front, on the left and on the right, e.g. UltrasonicDistance. Sides without
+// a sensor are nil; at least the front needs one.
+func (self *DriveBody) SetSensors(front func() float64, left func() float64, right func() float64) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	self.sensors = make(map[Side]func() float64)
+	for side, fn := range map[Side]func() float64{Front: front, Left: left, Right: right} {
+		if fn != nil {
+			self.sensors[side] = fn
+		}
+	}
+}
+
+// Sets the distance in centimeters below which a sensor sees a wall of the
+// current cell. It defaults to the cell size.
+func (self *DriveBody) SetWallThreshold(threshold float64) {
+	self.lock.Lock()
+	self.threshold = threshold
+	self.lock.Unlock()
+}
+
+// Sets the speed, as passed to Motor.Run.
+func (self *DriveBody) SetSpeed(speed int16) {
+	self.lock.Lock()
+	self.speed = speed
+	self.lock.Unlock()
+}
+
+func (self *DriveBody) Walls() map[Side]bool {
+	self.lock.Lock()
+	sensors := self.sensors
+	threshold := self.threshold
+	self.lock.Unlock()
+
+	walls := make(map[Side]bool)
+	for side, read := range sensors {
+		walls[side] = read() < threshold
+	}
+
+	return walls
+}
+
+func (self *DriveBody) Turn(quarters int) error {
+	self.lock.Lock()
+	speed := self.speed
+	self.lock.Unlock()
+
+	if quarters < 0 {
+		speed = -speed
+	}
+
+	arc := math.Abs(float64(quarters)) * math.Pi / 2 * self.base.TrackWidth() / 2
+	self.base.OnForDegrees(-speed, speed, self.base.DistanceToDegrees(arc), true, true)
+
+	return nil
+}
+
+func (self *DriveBody) Forward(cells int) error {
+	self.lock.Lock()
+	speed := self.speed
+	self.lock.Unlock()
+
+	self.base.OnForDistance(speed, float64(cells)*self.cellSize, true, true)
+
+	return nil
+}
@@ -0,0 +1,289 @@
+// Solves mazes made of square cells, as in micromouse-style challenges: a
+// Solver explores the maze cell by cell, mapping the walls it senses, until it
+// reaches the goal, and can then drive the shortest path it found.
+//
+//	body := Maze.NewDriveBody(base, 25)
+//	body.SetSensors(Maze.UltrasonicDistance(front), Maze.InfraredDistance(left), nil)
+//
+//	m := Maze.NewMap(8, 8)
+//	solver := Maze.NewSolver(body, m, Maze.Cell{0, 0}, Maze.North, Maze.NewFloodFill())
+//	solver.SetGoals(Maze.Cell{3, 3}, Maze.Cell{3, 4}, Maze.Cell{4, 3}, Maze.Cell{4, 4})
+//	solver.Explore(stop)
+//	solver.MoveTo(Maze.Cell{0, 0}, stop)
+//	solver.RunPath(stop)
+//
+// Cells are numbered from the bottom left corner, X growing east and Y north.
+package Maze
+
+import (
+	"strings"
+)
+
+// An absolute direction in the maze.
+type Direction int
+
+const (
+	North Direction = iota
+	East
+	South
+	West
+)
+
+// Returns the direction a quarter turn counter-clockwise.
+func (self Direction) Left() Direction {
+	return (self + 3) % 4
+}
+
+// Returns the direction a quarter turn clockwise.
+func (self Direction) Right() Direction {
+	return (self + 1) % 4
+}
+
+// Returns the opposite direction.
+func (self Direction) Opposite() Direction {
+	return (self + 2) % 4
+}
+
+func (self Direction) String() string {
+	switch self {
+	case North:
+		return "north"
+	case East:
+		return "east"
+	case South:
+		return "south"
+	case West:
+		return "west"
+	default:
+		return "unknown"
+	}
+}
+
+// A cell of the maze.
+type Cell struct {
+	X, Y int
+}
+
+// Returns the neighboring cell in direction `d`.
+func (self Cell) Step(d Direction) Cell {
+	switch d {
+	case North:
+		return Cell{self.X, self.Y + 1}
+	case East:
+		return Cell{self.X + 1, self.Y}
+	case South:
+		return Cell{self.X, self.Y - 1}
+	default:
+		return Cell{self.X - 1, self.Y}
+	}
+}
+
+// The walls of a maze, as far as they are known. The outer walls are known
+// from the start.
+type Map struct {
+	width, height int
+	// Bit d of a cell is set if there is a wall in direction d.
+	walls []uint8
+	// Bit d of a cell is set if the wall in direction d has been sensed.
+	known   []uint8
+	visited []bool
+}
+
+// Creates the map of a maze of `width` by `height` cells.
+func NewMap(width int, height int) *Map {
+	m := new(Map)
+	m.width, m.height = width, height
+	m.walls = make([]uint8, width*height)
+	m.known = make([]uint8, width*height)
+	m.visited = make([]bool, width*height)
+
+	for x := 0; x < width; x++ {
+		m.SetWall(Cell{x, 0}, South, true)
+		m.SetWall(Cell{x, height - 1}, North, true)
+	}
+	for y := 0; y < height; y++ {
+		m.SetWall(Cell{0, y}, West, true)
+		m.SetWall(Cell{width - 1, y}, East, true)
+	}
+
+	return m
+}
+
+// Returns the size of the maze in cells.
+func (self *Map) Size() (int, int) {
+	return self.width, self.height
+}
+
+// Reports whether `c` is inside the maze.
+func (self *Map) Contains(c Cell) bool {
+	return c.X >= 0 && c.X < self.width && c.Y >= 0 && c.Y < self.height
+}
+
+func (self *Map) index(c Cell) int {
+	return c.Y*self.width + c.X
+}
+
+// Records whether there is a wall on side `d` of `c`, which is also the
+// opposite side of the neighboring cell.
+func (self *Map) SetWall(c Cell, d Direction, wall bool) {
+	set := func(c Cell, d Direction) {
+		if !self.Contains(c) {
+			return
+		}
+
+		i := self.index(c)
+		self.known[i] |= 1 << d
+		if wall {
+			self.walls[i] |= 1 << d
+		} else {
+			self.walls[i] &^= 1 << d
+		}
+	}
+
+	set(c, d)
+	set(c.Step(d), d.Opposite())
+}
+
+// Returns whether there is a wall on side `d` of `c`, and whether that is known.
+// Sides leading out of the maze are walls.
+func (self *Map) Wall(c Cell, d Direction) (wall bool, known bool) {
+	if !self.Contains(c) || !self.Contains(c.Step(d)) {
+		return true, true
+	}
+
+	i := self.index(c)
+	return self.walls[i]&(1<<d) != 0, self.known[i]&(1<<d) != 0
+}
+
+// Reports whether side `d` of `c` is known to be open.
+func (self *Map) Open(c Cell, d Direction) bool {
+	wall, known := self.Wall(c, d)
+	return known && !wall
+}
+
+// Marks a cell as visited.
+func (self *Map) Visit(c Cell) {
+	if self.Contains(c) {
+		self.visited[self.index(c)] = true
+	}
+}
+
+// Reports whether a cell has been visited.
+func (self *Map) Visited(c Cell) bool {
+	return self.Contains(c) && self.visited[self.index(c)]
+}
+
+// Returns the number of moves from every cell to the nearest of `goals`,
+// indexed by Y*width+X, or -1 where they can't be reached. Unknown walls are
+// assumed open if `optimistic`, as flood-fill exploration does, and closed
+// otherwise.
+func (self *Map) Distances(goals []Cell, optimistic bool) []int {
+	dist := make([]int, self.width*self.height)
+	for i := range dist {
+		dist[i] = -1
+	}
+
+	var queue []Cell
+	for _, g := range goals {
+		if self.Contains(g) && dist[self.index(g)] < 0 {
+			dist[self.index(g)] = 0
+			queue = append(queue, g)
+		}
+	}
+
+	for len(queue) > 0 {
+		c := queue[0]
+		queue = queue[1:]
+
+		for d := North; d <= West; d++ {
+			wall, known := self.Wall(c, d)
+			if wall || (!known && !optimistic) {
+				continue
+			}
+
+			n := c.Step(d)
+			if dist[self.index(n)] < 0 {
+				dist[self.index(n)] = dist[self.index(c)] + 1
+				queue = append(queue, n)
+			}
+		}
+	}
+
+	return dist
+}
+
+// Returns the distance from `c` in a result of Distances.
+func (self *Map) DistanceAt(dist []int, c Cell) int {
+	if !self.Contains(c) {
+		return -1
+	}
+
+	return dist[self.index(c)]
+}
+
+// Returns the directions of the shortest path from `from` to the nearest of
+// `goals` through walls known to be open, or false if there is none yet.
+func (self *Map) Path(from Cell, goals ...Cell) ([]Direction, bool) {
+	dist := self.Distances(goals, false)
+	if self.DistanceAt(dist, from) < 0 {
+		return nil, false
+	}
+
+	var path []Direction
+	for c := from; self.DistanceAt(dist, c) > 0; {
+		for d := North; d <= West; d++ {
+			if n := c.Step(d); self.Open(c, d) && self.DistanceAt(dist, n) == self.DistanceAt(dist, c)-1 {
+				path = append(path, d)
+				c = n
+				break
+			}
+		}
+	}
+
+	return path, true
+}
+
+// Draws the map in ASCII, north up: known walls are lines, unknown ones dots.
+func (self *Map) String() string {
+	var b strings.Builder
+
+	horizontal := func(y int, d Direction) {
+		for x := 0; x < self.width; x++ {
+			b.WriteString("+")
+			switch wall, known := self.Wall(Cell{x, y}, d); {
+			case !known:
+				b.WriteString(" . ")
+			case wall:
+				b.WriteString("---")
+			default:
+				b.WriteString("   ")
+			}
+		}
+		b.WriteString("+\n")
+	}
+
+	for y := self.height - 1; y >= 0; y-- {
+		horizontal(y, North)
+
+		for x := 0; x < self.width; x++ {
+			switch wall, known := self.Wall(Cell{x, y}, West); {
+			case !known:
+				b.WriteString(".")
+			case wall:
+				b.WriteString("|")
+			default:
+				b.WriteString(" ")
+			}
+
+			if self.Visited(Cell{x, y}) {
+				b.WriteString(" * ")
+			} else {
+				b.WriteString("   ")
+			}
+		}
+		b.WriteString("|\n")
+	}
+	horizontal(0, South)
+
+	return b.String()
+}
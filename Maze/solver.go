@@ -0,0 +1,269 @@
+package Maze
+
+import (
+	"errors"
+	"sync"
+)
+
+// Sides of the robot walls are sensed on.
+type Side int
+
+const (
+	Front Side = iota
+	Left
+	Right
+)
+
+// The robot moving through the maze.
+type Body interface {
+	// Senses the walls around the cell the robot is in, returning only the
+	// sides it has a sensor on.
+	Walls() map[Side]bool
+	// Turns in place by `quarters` quarter turns, counter-clockwise if positive.
+	Turn(quarters int) error
+	// Drives straight ahead by `cells` cells.
+	Forward(cells int) error
+}
+
+var ErrStopped = errors.New("maze: stopped")
+var ErrNoPath = errors.New("maze: no path to the goal")
+
+// Explores and solves a maze with a Body, mapping it on the way.
+type Solver struct {
+	lock sync.Mutex
+
+	body     Body
+	m        *Map
+	strategy Strategy
+	goals    []Cell
+
+	at      Cell
+	heading Direction
+	onMove  func(at Cell, heading Direction)
+}
+
+// Creates a solver for a robot standing in cell `start` facing `heading`,
+// choosing its way with `strategy`.
+func NewSolver(body Body, m *Map, start Cell, heading Direction, strategy Strategy) *Solver {
+	s := new(Solver)
+	s.body = body
+	s.m = m
+	s.strategy = strategy
+	s.at = start
+	s.heading = heading
+	m.Visit(start)
+
+	return s
+}
+
+// Sets the cells any of which ends exploration.
+func (self *Solver) SetGoals(goals ...Cell) {
+	self.lock.Lock()
+	self.goals = append([]Cell(nil), goals...)
+	self.lock.Unlock()
+}
+
+// Returns the map built so far.
+func (self *Solver) Map() *Map {
+	return self.m
+}
+
+// Returns the cell the robot is in and the direction it faces.
+func (self *Solver) Position() (Cell, Direction) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	return self.at, self.heading
+}
+
+// Sets a function called whenever the robot reaches a cell.
+func (self *Solver) OnMove(fn func(at Cell, heading Direction)) {
+	self.lock.Lock()
+	self.onMove = fn
+	self.lock.Unlock()
+}
+
+func (self *Solver) atGoal() bool {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	for _, g := range self.goals {
+		if g == self.at {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Records the walls sensed around the current cell.
+func (self *Solver) sense() {
+	at, heading := self.Position()
+
+	for side, wall := range self.body.Walls() {
+		d := heading
+		switch side {
+		case Left:
+			d = heading.Left()
+		case Right:
+			d = heading.Right()
+		}
+		self.m.SetWall(at, d, wall)
+	}
+}
+
+// Turns the robot to face `d`.
+func (self *Solver) face(d Direction) error {
+	_, heading := self.Position()
+
+	quarters := 0
+	switch d {
+	case heading.Left():
+		quarters = 1
+	case heading.Right():
+		quarters = -1
+	case heading.Opposite():
+		quarters = 2
+	}
+
+	if quarters != 0 {
+		if err := self.body.Turn(quarters); err != nil {
+			return err
+		}
+	}
+
+	self.lock.Lock()
+	self.heading = d
+	self.lock.Unlock()
+
+	return nil
+}
+
+// Drives `cells` cells ahead.
+func (self *Solver) forward(cells int) error {
+	if err := self.body.Forward(cells); err != nil {
+		return err
+	}
+
+	self.lock.Lock()
+	for i := 0; i < cells; i++ {
+		self.m.SetWall(self.at, self.heading, false)
+		self.at = self.at.Step(self.heading)
+		self.m.Visit(self.at)
+	}
+	at, heading, onMove := self.at, self.heading, self.onMove
+	self.lock.Unlock()
+
+	if onMove != nil {
+		onMove(at, heading)
+	}
+
+	return nil
+}
+
+func stopped(stop <-chan bool) bool {
+	select {
+	case <-stop:
+		return true
+	default:
+		return false
+	}
+}
+
+// Explores the maze cell by cell with the strategy until reaching a goal.
+// Returns ErrStopped if a value is sent to `stop` first, and ErrNoPath if
+// the strategy finds no way on.
+func (self *Solver) Explore(stop <-chan bool) error {
+	// Direction the robot entered the current cell in, which the strategy
+	// plans from even after turning to look around.
+	_, entered := self.Position()
+
+	for !self.atGoal() {
+		if stopped(stop) {
+			return ErrStopped
+		}
+
+		self.sense()
+
+		at, _ := self.Position()
+		self.lock.Lock()
+		goals := self.goals
+		self.lock.Unlock()
+
+		d, ok := self.strategy.Next(self.m, at, entered, goals)
+		if !ok {
+			return ErrNoPath
+		}
+
+		if err := self.face(d); err != nil {
+			return err
+		}
+
+		// Robots without side sensors only find out about a side once facing it.
+		self.sense()
+		if wall, _ := self.m.Wall(at, d); wall {
+			continue
+		}
+
+		if err := self.forward(1); err != nil {
+			return err
+		}
+		entered = d
+	}
+
+	return nil
+}
+
+// Drives along the shortest path through the known maze to `to`, running
+// straight stretches in one go.
+func (self *Solver) MoveTo(to Cell, stop <-chan bool) error {
+	at, _ := self.Position()
+
+	path, ok := self.m.Path(at, to)
+	if !ok {
+		return ErrNoPath
+	}
+
+	return self.follow(path, stop)
+}
+
+// Drives along the shortest path through the known maze to the nearest goal,
+// typically after exploring it, as a micromouse speed run does.
+func (self *Solver) RunPath(stop <-chan bool) error {
+	at, _ := self.Position()
+
+	self.lock.Lock()
+	goals := self.goals
+	self.lock.Unlock()
+
+	path, ok := self.m.Path(at, goals...)
+	if !ok {
+		return ErrNoPath
+	}
+
+	return self.follow(path, stop)
+}
+
+func (self *Solver) follow(path []Direction, stop <-chan bool) error {
+	for i := 0; i < len(path); {
+		if stopped(stop) {
+			return ErrStopped
+		}
+
+		n := 1
+		for i+n < len(path) && path[i+n] == path[i] {
+			n++
+		}
+
+		if err := self.face(path[i]); err != nil {
+			return err
+		}
+		if err := self.forward(n); err != nil {
+			return err
+		}
+
+		i += n
+	}
+
+	return nil
+}
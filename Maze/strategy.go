@@ -0,0 +1,77 @@
+package Maze
+
+// Decides where to go next while exploring.
+type Strategy interface {
+	// Returns the direction to leave cell `at` in, facing `heading`, or false
+	// if there is nowhere to go.
+	Next(m *Map, at Cell, heading Direction, goals []Cell) (Direction, bool)
+}
+
+// Side of the robot kept along a wall.
+type Hand int
+
+const (
+	LeftHand Hand = iota
+	RightHand
+)
+
+// Follows the walls on one side, which gets through any maze whose goal is
+// on its outer wall, without needing to know where the goal is. Mazes whose
+// goal is in the middle, as in micromouse, need FloodFill.
+type WallFollower struct {
+	Hand Hand
+}
+
+// Creates a wall follower keeping `hand` along the walls.
+func NewWallFollower(hand Hand) *WallFollower {
+	return &WallFollower{hand}
+}
+
+func (self *WallFollower) Next(m *Map, at Cell, heading Direction, goals []Cell) (Direction, bool) {
+	order := []Direction{heading.Left(), heading, heading.Right(), heading.Opposite()}
+	if self.Hand == RightHand {
+		order[0], order[2] = order[2], order[0]
+	}
+
+	for _, d := range order {
+		if wall, _ := m.Wall(at, d); !wall {
+			return d, true
+		}
+	}
+
+	return heading, false
+}
+
+// Heads for the goal along the shortest path through the maze as known so
+// far, assuming unknown walls are open, and plans again as walls are found.
+// It always finds the goal if it can be reached.
+type FloodFill struct{}
+
+// Creates a flood-fill strategy.
+func NewFloodFill() *FloodFill {
+	return new(FloodFill)
+}
+
+func (self *FloodFill) Next(m *Map, at Cell, heading Direction, goals []Cell) (Direction, bool) {
+	dist := m.Distances(goals, true)
+	if m.DistanceAt(dist, at) < 0 {
+		return heading, false
+	}
+
+	best, found := heading, false
+	bestDist := -1
+
+	// Going straight first, so that ties favor fewer turns.
+	for _, d := range []Direction{heading, heading.Left(), heading.Right(), heading.Opposite()} {
+		if wall, _ := m.Wall(at, d); wall {
+			continue
+		}
+
+		n := m.DistanceAt(dist, at.Step(d))
+		if n >= 0 && (!found || n < bestDist) {
+			best, bestDist, found = d, n, true
+		}
+	}
+
+	return best, found
+}
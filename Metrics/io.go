@@ -0,0 +1,54 @@
+package Metrics
+
+import (
+	"sync"
+
+	"github.com/jermon/GoEV3/utilities"
+)
+
+// Counters of the attribute I/O of one device.
+type deviceIO struct {
+	reads       *Counter
+	readSeconds *Counter
+	errors      map[string]*Counter
+}
+
+var gDeviceIO = make(map[string]*deviceIO)
+var gDeviceIOLock = &sync.Mutex{}
+
+// Records every device attribute access into the default registry.
+func init() {
+	utilities.OnAccess(recordAccess)
+}
+
+func recordAccess(a utilities.Access) {
+	gDeviceIOLock.Lock()
+	d, ok := gDeviceIO[a.Path]
+	if !ok {
+		labels := map[string]string{"device": a.Path}
+		d = &deviceIO{
+			reads:       defaultRegistry.Counter("ev3_sysfs_reads_total", "Device attribute reads.", labels),
+			readSeconds: defaultRegistry.Counter("ev3_sysfs_read_seconds_total", "Time spent reading device attributes.", labels),
+			errors:      make(map[string]*Counter),
+		}
+		gDeviceIO[a.Path] = d
+	}
+
+	var failed *Counter
+	if a.Err != nil {
+		if failed, ok = d.errors[a.Op]; !ok {
+			labels := map[string]string{"device": a.Path, "op": a.Op}
+			failed = defaultRegistry.Counter("ev3_sysfs_errors_total", "Failed device attribute reads and writes.", labels)
+			d.errors[a.Op] = failed
+		}
+	}
+	gDeviceIOLock.Unlock()
+
+	if a.Op == "read" {
+		d.reads.Inc()
+		d.readSeconds.Add(a.Duration.Seconds())
+	}
+	if failed != nil {
+		failed.Inc()
+	}
+}
@@ -0,0 +1,41 @@
+package Metrics
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jermon/GoEV3/utilities"
+)
+
+func TestAccessMetrics(t *testing.T) {
+	const device = "/sys/class/lego-sensor/sensor7"
+
+	backend := utilities.NewMemoryBackend()
+	backend.SetFile(device+"/value0", "42")
+	utilities.SetBackend(backend)
+	t.Cleanup(func() { utilities.SetBackend(nil) })
+
+	for i := 0; i < 3; i++ {
+		if _, err := utilities.ReadValue[int](device, "value0"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	utilities.ReadValue[int](device, "value1")
+	utilities.WriteValue(device, "mode", "COL-REFLECT")
+
+	w := httptest.NewRecorder()
+	Default().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	body := w.Body.String()
+
+	for _, want := range []string{
+		`ev3_sysfs_reads_total{device="` + device + `"} 4`,
+		`ev3_sysfs_errors_total{device="` + device + `",op="read"} 1`,
+		`ev3_sysfs_errors_total{device="` + device + `",op="write"} 1`,
+		`ev3_sysfs_read_seconds_total{device="` + device + `"} `,
+	} {
+		if !strings.Contains(body, want) {
+			t.Fatalf("missing %q in\n%s", want, body)
+		}
+	}
+}
@@ -0,0 +1,261 @@
+// Provides an optional Prometheus metrics exporter for long-running robot programs.
+//
+// Metrics are exposed in the Prometheus text exposition format. Gauges are sampled
+// lazily each time the endpoint is scraped, so registering a metric costs
+// nothing until somebody asks for it.
+//
+// Importing the package also records the latency of every device attribute
+// read and the errors of all reads and writes into the default registry,
+// labelled with the device's folder, e.g.
+// device="/sys/class/lego-sensor/sensor0":
+//
+//	ev3_sysfs_reads_total                attribute reads
+//	ev3_sysfs_read_seconds_total         time spent on them; divided by the
+//	                                     reads, the mean latency
+//	ev3_sysfs_errors_total{op="read"}    failed reads, or writes for op="write"
+package Metrics
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jermon/GoEV3/Motor"
+	"github.com/jermon/GoEV3/utilities"
+)
+
+const batteryPath = "/sys/class/power_supply/legoev3-battery"
+
+// Metric families.
+const (
+	gaugeType   = "gauge"
+	counterType = "counter"
+)
+
+type sample struct {
+	labels string
+	read   func() float64
+}
+
+type family struct {
+	name    string
+	help    string
+	kind    string
+	samples []sample
+}
+
+// A set of metrics served from a single endpoint.
+type Registry struct {
+	lock     sync.Mutex
+	families map[string]*family
+	order    []string
+}
+
+// Creates an empty registry.
+func NewRegistry() *Registry {
+	r := new(Registry)
+	r.families = make(map[string]*family)
+
+	return r
+}
+
+var defaultRegistry = NewRegistry()
+
+// Returns the package-level registry used by `ListenAndServe`.
+func Default() *Registry {
+	return defaultRegistry
+}
+
+func (self *Registry) add(name string, help string, kind string, labels map[string]string, read func() float64) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	f, ok := self.families[name]
+	if !ok {
+		f = &family{name: name, help: help, kind: kind}
+		self.families[name] = f
+		self.order = append(self.order, name)
+	}
+
+	f.samples = append(f.samples, sample{formatLabels(labels), read})
+}
+
+// Registers a gauge whose value is obtained by calling `read` on every scrape.
+// Gauges sharing a name must use distinct labels.
+func (self *Registry) Gauge(name string, help string, labels map[string]string, read func() float64) {
+	self.add(name, help, gaugeType, labels, read)
+}
+
+// Monotonically increasing counter, e.g. for errors or retries.
+type Counter struct {
+	lock  sync.Mutex
+	value float64
+}
+
+// Increments the counter by one.
+func (self *Counter) Inc() {
+	self.Add(1)
+}
+
+// Adds the given non-negative amount to the counter.
+func (self *Counter) Add(delta float64) {
+	if delta < 0 {
+		return
+	}
+
+	self.lock.Lock()
+	self.value += delta
+	self.lock.Unlock()
+}
+
+// Returns the current counter value.
+func (self *Counter) Value() float64 {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	return self.value
+}
+
+// Registers and returns a new counter.
+func (self *Registry) Counter(name string, help string, labels map[string]string) *Counter {
+	c := new(Counter)
+	self.add(name, help, counterType, labels, c.Value)
+
+	return c
+}
+
+// Measures how often a control loop runs. Call `Tick` once per iteration.
+type LoopRate struct {
+	lock   sync.Mutex
+	window time.Duration
+	ticks  []time.Time
+}
+
+// Records one loop iteration.
+func (self *LoopRate) Tick() {
+	now := time.Now()
+
+	self.lock.Lock()
+	self.ticks = append(self.ticks, now)
+	self.trim(now)
+	self.lock.Unlock()
+}
+
+func (self *LoopRate) trim(now time.Time) {
+	n := 0
+	for n < len(self.ticks) && now.Sub(self.ticks[n]) > self.window {
+		n++
+	}
+
+	self.ticks = self.ticks[n:]
+}
+
+// Returns the number of iterations per second over the measurement window.
+func (self *LoopRate) Rate() float64 {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	self.trim(time.Now())
+
+	return float64(len(self.ticks)) / self.window.Seconds()
+}
+
+// Registers and returns a loop rate gauge averaged over the given window.
+func (self *Registry) LoopRate(name string, help string, labels map[string]string, window time.Duration) *LoopRate {
+	l := &LoopRate{window: window}
+	self.add(name, help, gaugeType, labels, l.Rate)
+
+	return l
+}
+
+// Registers duty cycle, speed and position gauges for a motor, labelled with `name`.
+func (self *Registry) RegisterMotor(name string, m *Motor.Motor) {
+	labels := map[string]string{"motor": name}
+
+	self.Gauge("ev3_motor_duty_cycle_percent", "Current motor duty cycle.", labels, func() float64 {
+		return float64(m.CurrentPower())
+	})
+	self.Gauge("ev3_motor_speed", "Current motor speed in tacho counts per second.", labels, func() float64 {
+		return float64(m.CurrentSpeed())
+	})
+	self.Gauge("ev3_motor_position", "Current motor position in tacho counts.", labels, func() float64 {
+		return float64(m.CurrentPosition())
+	})
+}
+
+// Registers battery voltage and current gauges.
+func (self *Registry) RegisterBattery() {
+	self.Gauge("ev3_battery_voltage_volts", "Battery voltage.", nil, func() float64 {
+		return readMicroUnits(batteryPath, "voltage_now")
+	})
+	self.Gauge("ev3_battery_current_amperes", "Battery current.", nil, func() float64 {
+		return readMicroUnits(batteryPath, "current_now")
+	})
+}
+
+func readMicroUnits(folder string, attribute string) float64 {
+	value, _ := utilities.ReadFloatValue(folder, attribute, 1e-6)
+	return value
+}
+
+// Writes all metrics in the Prometheus text exposition format.
+func (self *Registry) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	self.lock.Lock()
+	families := make([]family, 0, len(self.order))
+	for _, name := range self.order {
+		f := self.families[name]
+		families = append(families, family{f.name, f.help, f.kind, append([]sample(nil), f.samples...)})
+	}
+	self.lock.Unlock()
+
+	var buf bytes.Buffer
+
+	for _, f := range families {
+		fmt.Fprintf(&buf, "# HELP %s %s\n", f.name, escapeHelp(f.help))
+		fmt.Fprintf(&buf, "# TYPE %s %s\n", f.name, f.kind)
+
+		for _, s := range f.samples {
+			fmt.Fprintf(&buf, "%s%s %s\n", f.name, s.labels, strconv.FormatFloat(s.read(), 'g', -1, 64))
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	w.Write(buf.Bytes())
+}
+
+// Serves the default registry on `addr` under /metrics. This call blocks.
+func ListenAndServe(addr string) error {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", defaultRegistry)
+
+	return http.ListenAndServe(addr, mux)
+}
+
+func formatLabels(labels map[string]string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, len(keys))
+	for i, k := range keys {
+		pairs[i] = fmt.Sprintf("%s=%q", k, labels[k])
+	}
+
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func escapeHelp(help string) string {
+	help = strings.Replace(help, "\\", "\\\\", -1)
+	return strings.Replace(help, "\n", "\\n", -1)
+}
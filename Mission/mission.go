@@ -0,0 +1,488 @@
+// Runs missions: sequences of declarative steps such as "drive 40 cm", "turn
+// 90°" or "wait for red", kept in JSON files so they can be changed without
+// recompiling, as FLL teams do between runs:
+//
+//	{
+//		"name": "crane",
+//		"steps": [
+//			{"action": "drive", "distance": 40, "speed": 50},
+//			{"action": "turn", "angle": 90, "speed": 30},
+//			{"action": "motor", "motor": "D", "speed": 60, "seconds": 2},
+//			{"action": "wait_color", "sensor": "line", "color": "red", "timeout": 5},
+//			{"action": "follow_line", "sensor": "line", "speed": 30, "junctions": 2},
+//			{"action": "turn", "angle": 90, "speed": 30}
+//		]
+//	}
+//
+// Steps can have a timeout in seconds, after which the mission is aborted, or
+// the next step started if `continue` is set:
+//
+//	mission, _ := Mission.Load("/home/robot/crane.json")
+//	runner := Mission.NewRunner(base)
+//	go func() { Button.Wait(Button.Escape); runner.Abort() }()
+//	err := runner.Run(mission)
+//
+// Motors and sensors are named by their role (see Robot.Register) or their port.
+package Mission
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jermon/GoEV3/Behavior"
+	"github.com/jermon/GoEV3/Drive"
+	"github.com/jermon/GoEV3/Motor"
+	"github.com/jermon/GoEV3/Robot"
+	"github.com/jermon/GoEV3/Sensors"
+)
+
+// How often steps waiting for a condition check it.
+const pollInterval = 10 * time.Millisecond
+
+// A step of a mission. Which fields are used depends on the action.
+type Step struct {
+	// One of the actions listed in Runner.Define, e.g. "drive".
+	Action string `json:"action"`
+	// Describes the step in progress reports. The action is used if empty.
+	Name string `json:"name,omitempty"`
+
+	// Centimeters to drive, backwards if negative.
+	Distance float64 `json:"distance,omitempty"`
+	// Degrees to turn in place, counter-clockwise if positive.
+	Angle float64 `json:"angle,omitempty"`
+	// Speed as passed to Motor.Run.
+	Speed int16 `json:"speed,omitempty"`
+	// Role or port of the motor to run.
+	Motor string `json:"motor,omitempty"`
+	// Degrees to turn the motor, backwards if negative.
+	Degrees float64 `json:"degrees,omitempty"`
+	// Duration of "wait" steps and of motor runs.
+	Seconds float64 `json:"seconds,omitempty"`
+	// Role or port of the sensor to wait on.
+	Sensor string `json:"sensor,omitempty"`
+	// Color to wait for, e.g. "red".
+	Color string `json:"color,omitempty"`
+	// Distance in centimeters below which "wait_distance" ends.
+	Below float64 `json:"below,omitempty"`
+	// Junctions after which "follow_line" ends.
+	Junctions int `json:"junctions,omitempty"`
+	// Edge of the line "follow_line" tracks, "left" or "right".
+	Edge string `json:"edge,omitempty"`
+
+	// Seconds after which the step is cancelled; no limit if 0.
+	Timeout float64 `json:"timeout,omitempty"`
+	// Goes on with the next step when the timeout expires, instead of aborting.
+	Continue bool `json:"continue,omitempty"`
+}
+
+func (self Step) String() string {
+	if self.Name != "" {
+		return self.Name
+	}
+
+	return self.Action
+}
+
+// A named sequence of steps.
+type Mission struct {
+	Name  string `json:"name"`
+	Steps []Step `json:"steps"`
+}
+
+// Reads a mission from a JSON file.
+func Load(filename string) (Mission, error) {
+	var mission Mission
+
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return mission, err
+	}
+
+	err = json.Unmarshal(data, &mission)
+	return mission, err
+}
+
+// Performs a step. It must return promptly, stopping whatever it started,
+// once `cancel` is closed.
+type Action func(step Step, cancel <-chan bool) error
+
+// Returned by Run when the mission was aborted with Abort.
+var ErrAborted = errors.New("mission: aborted")
+
+// Reported when a step exceeds its timeout.
+type TimeoutError struct {
+	Index int
+	Step  Step
+}
+
+func (self *TimeoutError) Error() string {
+	return fmt.Sprintf("mission: step %d (%v) timed out", self.Index+1, self.Step)
+}
+
+// Executes missions.
+type Runner struct {
+	lock    sync.Mutex
+	base    *Drive.DriveBase
+	actions map[string]Action
+	abort   chan bool
+	onStep  func(index int, step Step)
+}
+
+// Creates a runner driving `base`, which may be nil for missions without
+// drive or turn steps.
+func NewRunner(base *Drive.DriveBase) *Runner {
+	r := new(Runner)
+	r.base = base
+	r.abort = make(chan bool)
+	r.actions = map[string]Action{
+		"drive":         r.drive,
+		"turn":          r.turn,
+		"motor":         r.motor,
+		"wait":          r.wait,
+		"wait_color":    r.waitColor,
+		"wait_touch":    r.waitTouch,
+		"wait_distance": r.waitDistance,
+		"follow_line":   r.followLine,
+	}
+
+	return r
+}
+
+// Defines an action, or replaces a built-in one. The built-in actions are:
+//
+//	drive          drives `distance` at `speed`
+//	turn           turns in place by `angle` at `speed`
+//	motor          runs `motor` at `speed` for `seconds` or `degrees`, or leaves it running
+//	wait           waits for `seconds`
+//	wait_color     waits until color sensor `sensor` sees `color`
+//	wait_touch     waits until touch sensor `sensor` is pressed
+//	wait_distance  waits until ultrasonic or infrared sensor `sensor` reads less than `below`
+//	follow_line    follows the `edge` of a line with color sensor `sensor` at `speed`, until
+//	               passing `junctions` junctions, a patch of `color` or `distance`, whichever first
+func (self *Runner) Define(action string, fn Action) {
+	self.lock.Lock()
+	self.actions[action] = fn
+	self.lock.Unlock()
+}
+
+// Sets a function called as each step starts, e.g. to show progress on the screen.
+func (self *Runner) OnStep(fn func(index int, step Step)) {
+	self.lock.Lock()
+	self.onStep = fn
+	self.lock.Unlock()
+}
+
+// Aborts the mission running, which stops its current step and makes Run
+// return ErrAborted.
+func (self *Runner) Abort() {
+	self.lock.Lock()
+	close(self.abort)
+	self.abort = make(chan bool)
+	self.lock.Unlock()
+}
+
+// Checks that every step has a known action, so that typos are caught before
+// the robot starts moving.
+func (self *Runner) Validate(mission Mission) error {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	for i, step := range mission.Steps {
+		if _, ok := self.actions[step.Action]; !ok {
+			return fmt.Errorf("mission: step %d has unknown action %q", i+1, step.Action)
+		}
+	}
+
+	return nil
+}
+
+// Runs the steps of a mission in order. It stops at the first failing step,
+// when a step times out unless it is marked `continue`, and when aborted.
+func (self *Runner) Run(mission Mission) error {
+	if err := self.Validate(mission); err != nil {
+		return err
+	}
+
+	self.lock.Lock()
+	abort := self.abort
+	self.lock.Unlock()
+
+	for i, step := range mission.Steps {
+		self.lock.Lock()
+		action, onStep := self.actions[step.Action], self.onStep
+		self.lock.Unlock()
+
+		if onStep != nil {
+			onStep(i, step)
+		}
+
+		cancel := make(chan bool)
+		result := make(chan error, 1)
+		go func() {
+			result <- action(step, cancel)
+		}()
+
+		var timeout <-chan time.Time
+		if step.Timeout > 0 {
+			timeout = time.After(time.Duration(step.Timeout * float64(time.Second)))
+		}
+
+		select {
+		case err := <-result:
+			if err != nil {
+				return fmt.Errorf("mission: step %d (%v): %w", i+1, step, err)
+			}
+		case <-timeout:
+			close(cancel)
+			<-result
+			if !step.Continue {
+				return &TimeoutError{i, step}
+			}
+		case <-abort:
+			close(cancel)
+			<-result
+			return ErrAborted
+		}
+	}
+
+	return nil
+}
+
+// Waits until `done` returns true or `cancel` is closed.
+func poll(cancel <-chan bool, done func() bool) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for !done() {
+		select {
+		case <-cancel:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (self *Runner) driveBase() (*Drive.DriveBase, error) {
+	if self.base == nil {
+		return nil, errors.New("no drive base")
+	}
+
+	return self.base, nil
+}
+
+// Runs a non-blocking drive base move to completion, or stops it when cancelled.
+func (self *Runner) move(cancel <-chan bool, start func(base *Drive.DriveBase)) error {
+	base, err := self.driveBase()
+	if err != nil {
+		return err
+	}
+
+	start(base)
+	poll(cancel, func() bool { return !base.IsMoving() })
+
+	select {
+	case <-cancel:
+		base.Stop()
+	default:
+	}
+
+	return nil
+}
+
+func speed(step Step, sign float64) int16 {
+	s := int16(math.Abs(float64(step.Speed)))
+	if sign < 0 {
+		return -s
+	}
+
+	return s
+}
+
+func (self *Runner) drive(step Step, cancel <-chan bool) error {
+	return self.move(cancel, func(base *Drive.DriveBase) {
+		base.OnForDistance(speed(step, step.Distance), math.Abs(step.Distance), true, false)
+	})
+}
+
+func (self *Runner) turn(step Step, cancel <-chan bool) error {
+	return self.move(cancel, func(base *Drive.DriveBase) {
+		arc := math.Abs(step.Angle) * math.Pi / 180 * base.TrackWidth() / 2
+		s := speed(step, step.Angle)
+		base.OnForDegrees(-s, s, base.DistanceToDegrees(arc), true, false)
+	})
+}
+
+// Returns the motor registered under the role `name`, or the one at port `name`.
+func findMotor(name string) (*Motor.Motor, error) {
+	if m, ok := Robot.Lookup[*Motor.Motor](name); ok {
+		return m, nil
+	}
+
+	port, err := Motor.ParseOutPort(name)
+	if err != nil {
+		return nil, fmt.Errorf("no motor %q", name)
+	}
+
+	return Motor.OpenMotor(port)
+}
+
+func (self *Runner) motor(step Step, cancel <-chan bool) error {
+	m, err := findMotor(step.Motor)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case step.Degrees != 0:
+		start := m.CurrentDegrees()
+		if err := m.TryRun(speed(step, step.Degrees)); err != nil {
+			return err
+		}
+		poll(cancel, func() bool {
+			return math.Abs(m.CurrentDegrees()-start) >= math.Abs(step.Degrees)
+		})
+		m.Stop()
+
+	case step.Seconds > 0:
+		if err := m.TryRun(step.Speed); err != nil {
+			return err
+		}
+		select {
+		case <-time.After(time.Duration(step.Seconds * float64(time.Second))):
+		case <-cancel:
+		}
+		m.Stop()
+
+	default:
+		// Keeps running while the next steps go on.
+		return m.TryRun(step.Speed)
+	}
+
+	return nil
+}
+
+func (self *Runner) wait(step Step, cancel <-chan bool) error {
+	select {
+	case <-time.After(time.Duration(step.Seconds * float64(time.Second))):
+	case <-cancel:
+	}
+
+	return nil
+}
+
+// Returns the sensor registered under the role `name` if it is a T, or opens
+// the one at port `name` with `open`.
+func findSensor[T any](name string, open func(port Sensors.InPort, opts ...Sensors.Option) (T, error)) (T, error) {
+	if s, ok := Robot.Lookup[T](name); ok {
+		return s, nil
+	}
+
+	port, err := Sensors.ParseInPort(name)
+	if err != nil {
+		var zero T
+		return zero, fmt.Errorf("no sensor %q", name)
+	}
+
+	return open(port)
+}
+
+func parseColor(name string) (Sensors.Color, error) {
+	for c := Sensors.None; c <= Sensors.Brown; c++ {
+		if strings.EqualFold(c.String(), name) {
+			return c, nil
+		}
+	}
+
+	return Sensors.None, fmt.Errorf("unknown color %q", name)
+}
+
+func (self *Runner) waitColor(step Step, cancel <-chan bool) error {
+	color, err := parseColor(step.Color)
+	if err != nil {
+		return err
+	}
+
+	sensor, err := findSensor(step.Sensor, Sensors.OpenColorSensor)
+	if err != nil {
+		return err
+	}
+
+	poll(cancel, func() bool { return sensor.ReadColor() == color })
+	return nil
+}
+
+func (self *Runner) waitTouch(step Step, cancel <-chan bool) error {
+	sensor, err := findSensor(step.Sensor, Sensors.OpenTouchSensor)
+	if err != nil {
+		return err
+	}
+
+	poll(cancel, sensor.IsPressed)
+	return nil
+}
+
+func (self *Runner) waitDistance(step Step, cancel <-chan bool) error {
+	var read func() float64
+
+	if sensor, err := findSensor(step.Sensor, Sensors.OpenUltrasonicSensor); err == nil {
+		read = func() float64 { return sensor.Distance().Centimeters() }
+	} else if sensor, err := findSensor(step.Sensor, Sensors.OpenInfraredSensor); err == nil {
+		// Proximity is roughly 70 cm at 100%.
+		read = func() float64 { return float64(sensor.ReadProximity()) * 0.7 }
+	} else {
+		return err
+	}
+
+	poll(cancel, func() bool { return read() < step.Below })
+	return nil
+}
+
+func (self *Runner) followLine(step Step, cancel <-chan bool) error {
+	base, err := self.driveBase()
+	if err != nil {
+		return err
+	}
+
+	sensor, err := findSensor(step.Sensor, Sensors.OpenColorSensor)
+	if err != nil {
+		return err
+	}
+
+	f := Behavior.NewLineFollower(base, sensor)
+	if step.Speed != 0 {
+		f.SetSpeed(step.Speed)
+	}
+
+	switch step.Edge {
+	case "", "right":
+	case "left":
+		f.SetEdge(Behavior.LeftEdge)
+	default:
+		return fmt.Errorf("unknown edge %q", step.Edge)
+	}
+
+	var conditions []Behavior.StopCondition
+	if step.Junctions > 0 {
+		conditions = append(conditions, Behavior.AtIntersection(step.Junctions))
+	}
+	if step.Color != "" {
+		color, err := parseColor(step.Color)
+		if err != nil {
+			return err
+		}
+		f.SetMarkerColors(color)
+		conditions = append(conditions, Behavior.AtColorPatch(1))
+	}
+	if step.Distance > 0 {
+		conditions = append(conditions, Behavior.AfterDistance(step.Distance))
+	}
+
+	f.Follow(cancel, conditions...)
+	return nil
+}
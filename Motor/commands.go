@@ -0,0 +1,123 @@
+package Motor
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jermon/GoEV3/Errors"
+	"github.com/jermon/GoEV3/utilities"
+)
+
+// Commands a motor runs, as listed in its commands attribute.
+type Command string
+
+const (
+	// Runs until stopped, at the speed or power set.
+	CommandRunForever Command = "run-forever"
+	// Runs to the absolute position set.
+	CommandRunToAbsPos Command = "run-to-abs-pos"
+	// Runs by the position set, from the current one.
+	CommandRunToRelPos Command = "run-to-rel-pos"
+	// Runs for the time set.
+	CommandRunTimed Command = "run-timed"
+	// Stops, as the stop action says.
+	CommandStop Command = "stop"
+	// Resets all attributes to their defaults.
+	CommandReset Command = "reset"
+)
+
+// How a motor stops, as listed in its stop_commands attribute.
+type StopAction string
+
+const (
+	// Removes power, letting the motor spin to a stop.
+	Coast StopAction = "coast"
+	// Shorts the motor's windings, stopping it faster.
+	Brake StopAction = "brake"
+	// Actively holds the motor at the position it stopped at.
+	Hold StopAction = "hold"
+)
+
+const (
+	commandsFD     = "commands"
+	stopCommandsFD = "stop_commands"
+)
+
+// Returns the commands the motor's driver supports.
+func (self Motor) Commands() []Command {
+	return listCommands(self.folder)
+}
+
+func listCommands(folder string) []Command {
+	var commands []Command
+	for _, c := range strings.Fields(utilities.ReadStringValue(folder, commandsFD)) {
+		commands = append(commands, Command(c))
+	}
+
+	return commands
+}
+
+// Returns the stop actions the motor's driver supports.
+func (self Motor) StopActions() []StopAction {
+	return listStopActions(self.folder)
+}
+
+func listStopActions(folder string) []StopAction {
+	var actions []StopAction
+	for _, a := range strings.Fields(utilities.ReadStringValue(folder, stopActionsAttribute(folder))) {
+		actions = append(actions, StopAction(a))
+	}
+
+	return actions
+}
+
+// Returns an error matching Errors.ErrInvalidMode unless `value` is in the
+// supported list. Drivers that don't list what they support accept anything.
+func (self Motor) checkSupported(attribute string, value string) error {
+	return checkSupported(self.folder, "motor", self.port, attribute, value)
+}
+
+func checkSupported(folder string, device string, port OutPort, attribute string, value string) error {
+	supported := strings.Fields(utilities.ReadStringValue(folder, attribute))
+	if len(supported) == 0 {
+		return nil
+	}
+
+	for _, s := range supported {
+		if s == value {
+			return nil
+		}
+	}
+
+	return &Errors.DeviceError{
+		Kind:   Errors.ErrInvalidMode,
+		Device: device,
+		Port:   string(port),
+		Detail: fmt.Sprintf("%q, expected one of %s", value, strings.Join(supported, ", ")),
+	}
+}
+
+// Sets how the motor stops. An action the driver doesn't support is a fatal
+// error; use TrySetStopAction to handle it.
+func (self Motor) SetStopAction(action StopAction) {
+	if err := self.TrySetStopAction(action); errors.Is(err, Errors.ErrInvalidMode) {
+		utilities.Fatal(err)
+	}
+}
+
+// Sets how the motor stops like SetStopAction, but returns an error matching
+// Errors.ErrInvalidMode instead of exiting if the driver doesn't support it,
+// and the error of the write.
+func (self Motor) TrySetStopAction(action StopAction) error {
+	if err := self.checkSupported(stopActionsAttribute(self.folder), string(action)); err != nil {
+		return err
+	}
+
+	return utilities.WriteValue(self.folder, stopActionAttribute(self.folder), string(action))
+}
+
+// Returns how the motor stops.
+func (self Motor) StopAction() StopAction {
+	return StopAction(utilities.ReadStringValue(self.folder, stopActionAttribute(self.folder)))
+}
@@ -0,0 +1,101 @@
+package Motor
+
+import (
+	"path"
+	"sync"
+
+	"github.com/jermon/GoEV3/Errors"
+	"github.com/jermon/GoEV3/utilities"
+)
+
+// Names of the attributes that newer ev3dev kernels, from Stretch on, renamed.
+// They also dropped speed_regulation: their tacho motors always regulate
+// their speed when running, and only the run-direct command follows the duty
+// cycle setpoint.
+const (
+	stopActionFD  = "stop_action"
+	stopActionsFD = "stop_actions"
+)
+
+// The attribute names of a motor's kernel, probed when the motor is opened.
+type attributeNames struct {
+	stopAction  string
+	stopActions string
+	// Whether the driver has the speed_regulation attribute of older kernels.
+	regulation bool
+	// Whether the kernel is a newer one, always regulating tacho motors.
+	alwaysRegulated bool
+}
+
+var gAttributeNames = make(map[string]*attributeNames)
+var gAttributeNamesLock = &sync.Mutex{}
+
+// Looks up which attribute names the motor in `folder` has, from the older
+// ev3dev kernels' stop_command and speed_regulation or the newer ones'
+// stop_action. Folders are probed once and then remembered.
+func probeAttributes(folder string) *attributeNames {
+	gAttributeNamesLock.Lock()
+	defer gAttributeNamesLock.Unlock()
+
+	if names, ok := gAttributeNames[folder]; ok {
+		return names
+	}
+
+	names := &attributeNames{stopAction: stopModeFD, stopActions: stopCommandsFD}
+	if utilities.Exists(path.Join(folder, stopActionFD)) {
+		names.stopAction, names.stopActions = stopActionFD, stopActionsFD
+	}
+	names.regulation = utilities.Exists(path.Join(folder, regulationModeFD))
+	names.alwaysRegulated = !names.regulation && names.stopAction == stopActionFD
+
+	gAttributeNames[folder] = names
+	return names
+}
+
+// Returns the name of the attribute setting how the motor in `folder` stops.
+func stopActionAttribute(folder string) string {
+	return probeAttributes(folder).stopAction
+}
+
+// Returns the name of the attribute listing the stop actions of the motor in `folder`.
+func stopActionsAttribute(folder string) string {
+	return probeAttributes(folder).stopActions
+}
+
+// Reports whether the motor in `folder` regulates its speed: whether its
+// speed_regulation is on, or it always does on newer kernels. Run and the
+// position commands then take speeds in tacho counts per second instead of
+// duty cycles.
+func isRegulated(folder string) bool {
+	names := probeAttributes(folder)
+	if names.alwaysRegulated {
+		return true
+	}
+
+	return names.regulation && utilities.ReadStringValue(folder, regulationModeFD) == "on"
+}
+
+// Turns the speed regulation of the motor in `folder` on or off, on kernels
+// that let it be. Newer kernels always regulate it; turning it off there
+// returns an error matching Errors.ErrInvalidMode.
+func setRegulation(folder string, port OutPort, on bool) error {
+	names := probeAttributes(folder)
+	if names.alwaysRegulated {
+		if on {
+			return nil
+		}
+		return &Errors.DeviceError{
+			Kind:   Errors.ErrInvalidMode,
+			Device: "motor",
+			Port:   string(port),
+			Detail: "speed regulation is always on with this kernel",
+		}
+	}
+
+	value := "off"
+	if on {
+		value = "on"
+	}
+
+	return utilities.WriteValue(folder, regulationModeFD, value)
+}
@@ -0,0 +1,74 @@
+package Motor
+
+import (
+	"path"
+	"testing"
+
+	"github.com/jermon/GoEV3/utilities"
+)
+
+// Installs a memory backend with a large motor on outA with the attributes of
+// a Stretch kernel: stop_action rather than stop_command, and no
+// speed_regulation. Returns the backend and the motor's folder.
+func setUpStretchMotor(t *testing.T, name string) (*utilities.MemoryBackend, string) {
+	folder := path.Join(rootMotorPath, name)
+
+	backend := utilities.NewMemoryBackend()
+	for attribute, value := range map[string]string{
+		portFD:        "ev3-ports:outA",
+		driverFD:      "lego-ev3-l-motor",
+		commandsFD:    "run-forever run-to-abs-pos run-to-rel-pos run-timed run-direct stop reset",
+		stopActionFD:  "coast",
+		stopActionsFD: "coast brake hold",
+		maxSpeedFD:    "1050",
+		countPerRotFD: "360",
+		positionFD:    "0",
+		speedGetterFD: "0",
+		stateFD:       "",
+		speedSetterFD: "0",
+		powerSetterFD: "0",
+		"position_sp": "0",
+		runFD:         "",
+	} {
+		backend.SetFile(path.Join(folder, attribute), value)
+	}
+
+	utilities.SetBackend(backend)
+	t.Cleanup(func() { utilities.SetBackend(nil) })
+
+	return backend, folder
+}
+
+func TestPositionCommandsOnStretch(t *testing.T) {
+	tests := []struct {
+		name string
+		run  func(m *Motor) error
+		// The speed_sp expected: half power for Turn, and the lift's or the
+		// homing power otherwise, as speeds of the 1050 counts per second motor.
+		want string
+	}{
+		{"turn", func(m *Motor) error { return m.TryTurn(CommandRunToAbsPos, 90) }, "525"},
+		// The memory backend's motor never moves: the lift reports a stall.
+		{"lift", func(m *Motor) error { NewLift(m, 40).MoveTo(90); return nil }, "420"},
+		{"home", func(m *Motor) error { return m.HomeTo(-1, 20, 0, 5) }, "210"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Attributes are probed once per folder: each test has its own.
+			backend, folder := setUpStretchMotor(t, "motor-"+test.name)
+
+			m, err := OpenMotor(OutPortA)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := test.run(m); err != nil {
+				t.Fatal(err)
+			}
+
+			if got, _ := backend.File(path.Join(folder, speedSetterFD)); got != test.want {
+				t.Fatalf("speed_sp %q, want %q", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,176 @@
+package Motor
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jermon/GoEV3/Errors"
+	"github.com/jermon/GoEV3/utilities"
+)
+
+const rootDCMotorPath = "/sys/class/dc-motor"
+
+// A motor without a tacho, such as a LEGO Power Functions motor connected
+// through an adapter, driven by its duty cycle only. It has no position or
+// speed to read, so only the commands of a MotorDevice apply.
+type DCMotor struct {
+	port   OutPort
+	folder string
+	driver string
+}
+
+// Provides access to the DC motor at the given port. A missing motor is a
+// fatal error; use OpenDCMotor to handle it.
+func FindDCMotor(port OutPort, opts ...Option) *DCMotor {
+	m, err := OpenDCMotor(port, opts...)
+	if err != nil {
+		utilities.Fatal(err)
+	}
+
+	return m
+}
+
+// Provides access to the DC motor at the given port. Returns an error
+// matching Errors.ErrDeviceNotFound if there is none, or
+// Errors.ErrPortMismatch if it doesn't have the driver required with
+// WithRequiredDriver.
+func OpenDCMotor(port OutPort, opts ...Option) (*DCMotor, error) {
+	m := new(DCMotor)
+	m.port = CanonicalOutPort(string(port))
+
+	folder, err := findFolderIn(rootDCMotorPath, "dc motor", m.port, newOptions(opts))
+	if err != nil {
+		return nil, err
+	}
+
+	m.folder = folder
+	m.driver = utilities.ReadStringValue(folder, driverFD)
+	probeAttributes(folder)
+	markOpened(folder)
+
+	return m, nil
+}
+
+// Returns the output port the motor is connected to.
+func (self DCMotor) Port() OutPort {
+	return self.port
+}
+
+// Closes the motor's attribute files kept open, as Motor.Close does.
+func (self DCMotor) Close() {
+	utilities.ReleaseFiles(self.folder)
+}
+
+// Returns the name of the motor's driver, e.g. "rcx-motor".
+func (self DCMotor) Driver() string {
+	return self.driver
+}
+
+// Runs the motor at the duty cycle `speed`, in range [-100, 100]. A speed out
+// of range is a fatal error; use TryRun to handle it.
+func (self DCMotor) Run(speed int16) {
+	if err := self.TryRun(speed); errors.Is(err, Errors.ErrOutOfRange) {
+		utilities.Fatal(err)
+	}
+}
+
+// Runs the motor like Run, but returns an error matching Errors.ErrOutOfRange
+// instead of exiting if the speed is out of range, and the error of any write.
+func (self DCMotor) TryRun(speed int16) error {
+	lock := commandLock(self.folder)
+	lock.Lock()
+	defer lock.Unlock()
+
+	return self.start(speed, CommandRunForever)
+}
+
+// Runs the motor at the duty cycle `speed` for `d`, like Motor.RunForDuration.
+func (self DCMotor) TryRunForDuration(speed int16, d time.Duration) error {
+	if err := checkSupported(self.folder, "dc motor", self.port, commandsFD, string(CommandRunTimed)); err != nil {
+		return err
+	}
+
+	lock := commandLock(self.folder)
+	lock.Lock()
+	defer lock.Unlock()
+
+	if err := utilities.WriteValue(self.folder, timeSetterFD, d.Milliseconds()); err != nil {
+		return err
+	}
+
+	return self.start(speed, CommandRunTimed)
+}
+
+func (self DCMotor) start(speed int16, command Command) error {
+	if speed > 100 || speed < -100 {
+		return &Errors.DeviceError{
+			Kind:   Errors.ErrOutOfRange,
+			Device: "dc motor",
+			Port:   string(self.port),
+			Detail: fmt.Sprintf("speed %d, expected [-100, 100]", speed),
+		}
+	}
+
+	markStarted(self.folder)
+	if err := utilities.WriteValue(self.folder, powerSetterFD, speed); err != nil {
+		return err
+	}
+
+	return utilities.WriteValue(self.folder, runFD, string(command))
+}
+
+// Stops the motor, as the stop action says.
+func (self DCMotor) Stop() {
+	self.TryStop()
+}
+
+// Stops the motor like Stop, but returns the error of the write.
+func (self DCMotor) TryStop() error {
+	lock := commandLock(self.folder)
+	lock.Lock()
+	defer lock.Unlock()
+
+	return utilities.WriteValue(self.folder, runFD, string(CommandStop))
+}
+
+// Returns the commands the motor's driver supports.
+func (self DCMotor) Commands() []Command {
+	return listCommands(self.folder)
+}
+
+// Returns the stop actions the motor's driver supports, Coast and Brake.
+func (self DCMotor) StopActions() []StopAction {
+	return listStopActions(self.folder)
+}
+
+// Sets how the motor stops, returning an error matching Errors.ErrInvalidMode
+// if the driver doesn't support it, and the error of the write.
+func (self DCMotor) TrySetStopAction(action StopAction) error {
+	if err := checkSupported(self.folder, "dc motor", self.port, stopActionsAttribute(self.folder), string(action)); err != nil {
+		return err
+	}
+
+	return utilities.WriteValue(self.folder, stopActionAttribute(self.folder), string(action))
+}
+
+// Reads the duty cycle the motor runs at.
+func (self DCMotor) CurrentPower() int16 {
+	value, _ := utilities.ReadValue[int16](self.folder, powerGetterFD)
+	return value
+}
+
+// Reads the state of the motor as flags.
+func (self DCMotor) State() State {
+	return ParseState(utilities.ReadStringValue(self.folder, stateFD))
+}
+
+// Makes the driver take `d` to ramp the duty cycle from 0 to 100%.
+func (self DCMotor) SetRampUp(d time.Duration) {
+	utilities.WriteValue(self.folder, rampUpFD, d.Milliseconds())
+}
+
+// Makes the driver take `d` to ramp the duty cycle from 100% to 0.
+func (self DCMotor) SetRampDown(d time.Duration) {
+	utilities.WriteValue(self.folder, rampDownFD, d.Milliseconds())
+}
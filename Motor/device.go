@@ -0,0 +1,74 @@
+package Motor
+
+import (
+	"errors"
+	"time"
+
+	"github.com/jermon/GoEV3/Errors"
+)
+
+// What all motors do, whether tacho motors, Motor, or DC motors, DCMotor.
+// Code that only starts and stops a motor, e.g. a conveyor or a spinner,
+// can take either. Features needing a tacho, such as positions and speed
+// regulation, are on Motor only.
+type MotorDevice interface {
+	// Returns the output port the motor is connected to.
+	Port() OutPort
+	// Returns the name of the motor's driver.
+	Driver() string
+	// Runs the motor at `speed`, as Motor.Run takes it.
+	TryRun(speed int16) error
+	// Runs the motor at `speed` for `d`.
+	TryRunForDuration(speed int16, d time.Duration) error
+	// Stops the motor, as the stop action says.
+	TryStop() error
+	// Returns the commands the motor's driver supports.
+	Commands() []Command
+	// Returns the stop actions the motor's driver supports.
+	StopActions() []StopAction
+	// Sets how the motor stops.
+	TrySetStopAction(action StopAction) error
+	// Reads the state of the motor as flags.
+	State() State
+}
+
+// Provides access to whatever motor is connected to the given port: a
+// *Motor for a tacho motor, or a *DCMotor. Returns an error matching
+// Errors.ErrDeviceNotFound if there is neither.
+func OpenMotorDevice(port OutPort, opts ...Option) (MotorDevice, error) {
+	deadline := time.Now().Add(newOptions(opts).timeout)
+	// Looking up both classes without waiting, and then waiting for either.
+	now := append(append([]Option(nil), opts...), WithTimeout(0))
+
+	for {
+		m, err := OpenMotor(port, now...)
+		if err == nil {
+			return m, nil
+		}
+		if !errors.Is(err, Errors.ErrDeviceNotFound) {
+			return nil, err
+		}
+
+		if dc, dcErr := OpenDCMotor(port, now...); dcErr == nil {
+			return dc, nil
+		}
+
+		if !time.Now().Before(deadline) {
+			return nil, err
+		}
+
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+// Reports whether the motor is an EV3 medium motor, which is faster and
+// weaker than the large one.
+func (self Motor) IsMedium() bool {
+	return self.driver == DriverMedium
+}
+
+// Reports whether the motor is an EV3 large motor. Motors of unknown drivers
+// are not.
+func (self Motor) IsLarge() bool {
+	return self.driver == DriverLarge
+}
@@ -0,0 +1,97 @@
+package Motor
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/jermon/GoEV3/utilities"
+)
+
+// Derives a motor's speed from the change of its position over time, for
+// motors and kernels whose speed attribute is missing or unreliable.
+//
+// Every call to CurrentSpeed or Sample reads the position; the speed between
+// two readings is smoothed with a low-pass filter, so call them regularly,
+// e.g. from the control loop that uses the speed, or run Run in a goroutine.
+type SpeedEstimator struct {
+	lock sync.Mutex
+
+	motor        *Motor
+	timeConstant time.Duration
+
+	lastPosition int32
+	lastTime     time.Time
+	speed        float64
+	initialized  bool
+}
+
+// Creates an estimator for the motor, smoothing with the given time constant.
+// Zero disables smoothing; 50 to 100 milliseconds suit most control loops.
+func NewSpeedEstimator(m *Motor, timeConstant time.Duration) *SpeedEstimator {
+	e := new(SpeedEstimator)
+	e.motor = m
+	e.timeConstant = timeConstant
+
+	return e
+}
+
+// Reads the motor position and updates the estimate.
+func (self *SpeedEstimator) Sample() {
+	self.update(self.motor.CurrentPosition(), time.Now())
+}
+
+func (self *SpeedEstimator) update(position int32, now time.Time) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	if !self.initialized {
+		self.initialized = true
+		self.lastPosition, self.lastTime = position, now
+		return
+	}
+
+	seconds := now.Sub(self.lastTime).Seconds()
+	if seconds <= 0 {
+		return
+	}
+
+	raw := float64(position-self.lastPosition) / seconds
+	if tau := self.timeConstant.Seconds(); tau > 0 {
+		self.speed += seconds / (tau + seconds) * (raw - self.speed)
+	} else {
+		self.speed = raw
+	}
+
+	self.lastPosition, self.lastTime = position, now
+}
+
+// Reads the motor position and returns the estimated speed in tacho counts per
+// second, as Motor.CurrentSpeed does.
+func (self *SpeedEstimator) CurrentSpeed() int16 {
+	self.Sample()
+
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	return int16(math.Round(self.speed))
+}
+
+// Reads the motor position and returns the estimated speed.
+func (self *SpeedEstimator) Speed() Speed {
+	return DegPerSec(self.motor.CountsToDegrees(float64(self.CurrentSpeed())))
+}
+
+// Forgets the previous readings and restarts the estimate from zero.
+func (self *SpeedEstimator) Reset() {
+	self.lock.Lock()
+	self.speed = 0
+	self.initialized = false
+	self.lock.Unlock()
+}
+
+// Samples the position every `interval`, from the shared polling scheduler,
+// until a value is sent to `stop`.
+func (self *SpeedEstimator) Run(stop <-chan bool, interval time.Duration) {
+	utilities.PollUntil(stop, interval, self.Sample)
+}
@@ -0,0 +1,169 @@
+package Motor
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// A gripper or claw driven by a motor, usually a medium one. Closing runs the
+// motor until it stalls, which means the claw closed on an object, or until
+// it reaches its travel limit, which means there was nothing to grip. An
+// object is then held with reduced power, so that it doesn't slip without
+// overheating the motor:
+//
+//	claw := Motor.NewGripper(Motor.FindMotor(Motor.OutPortA), 40)
+//	claw.SetLimit(120)
+//	if claw.Close() {
+//		base.OnForDistance(30, 50, true, true)
+//		claw.Open()
+//	}
+//
+// The position the motor is in when the gripper is created is taken as open.
+type Gripper struct {
+	lock sync.Mutex
+
+	motor      *Motor
+	closeSpeed int16
+	holdPower  int16
+	limit      float64
+	open       float64
+
+	minMovement float64
+	window      time.Duration
+
+	held bool
+}
+
+// Creates a gripper closing when its motor runs at `closeSpeed`, as passed to
+// Motor.Run, and opening when it runs the other way.
+func NewGripper(m *Motor, closeSpeed int16) *Gripper {
+	g := new(Gripper)
+	g.motor = m
+	g.closeSpeed = closeSpeed
+	g.holdPower = closeSpeed / 3
+	g.open = m.CurrentDegrees()
+	g.minMovement = 3
+	g.window = 150 * time.Millisecond
+
+	return g
+}
+
+// Sets the degrees the motor turns from open to fully closed on nothing.
+// Stalling short of it means an object was gripped. Without a limit, any
+// stall counts as gripping.
+func (self *Gripper) SetLimit(degrees float64) {
+	self.lock.Lock()
+	self.limit = math.Abs(degrees)
+	self.lock.Unlock()
+}
+
+// Sets the power an object is held with, as passed to Motor.Run; 0 lets go
+// of it as soon as it is gripped. Defaults to a third of the closing speed.
+func (self *Gripper) SetHoldPower(power int16) {
+	self.lock.Lock()
+	self.holdPower = power
+	self.lock.Unlock()
+}
+
+// Sets how little the motor must turn, in degrees, within `window` to be
+// considered stalled.
+func (self *Gripper) SetStallDetection(minMovement float64, window time.Duration) {
+	self.lock.Lock()
+	self.minMovement, self.window = minMovement, window
+	self.lock.Unlock()
+}
+
+// Takes the current position as open.
+func (self *Gripper) SetOpenHere() {
+	self.lock.Lock()
+	self.open = self.motor.CurrentDegrees()
+	self.lock.Unlock()
+}
+
+// Reports whether an object is held.
+func (self *Gripper) Held() bool {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	return self.held
+}
+
+// Returns the degrees turned from the open position towards closed.
+func (self *Gripper) travel() float64 {
+	self.lock.Lock()
+	open, sign := self.open, float64(self.closeSpeed)
+	self.lock.Unlock()
+
+	return math.Copysign(1, sign) * (self.motor.CurrentDegrees() - open)
+}
+
+// Runs the motor at `speed` until it stalls or `done` returns true, and
+// reports whether it stalled.
+func (self *Gripper) runUntil(speed int16, done func(travel float64) bool) (bool, error) {
+	self.lock.Lock()
+	minMovement, window := self.minMovement, self.window
+	self.lock.Unlock()
+
+	if err := self.motor.TryRun(speed); err != nil {
+		return false, err
+	}
+
+	return waitUntilStall(self.motor, minMovement, window, func() bool {
+		return done(self.travel())
+	}), nil
+}
+
+// Closes the gripper and reports whether it gripped an object, which it then
+// holds. Returns false if it closed on nothing, and on errors.
+func (self *Gripper) Close() bool {
+	held, _ := self.TryClose()
+	return held
+}
+
+// Closes the gripper like Close, but returns the error if the motor can't run.
+func (self *Gripper) TryClose() (bool, error) {
+	self.lock.Lock()
+	speed, hold, limit := self.closeSpeed, self.holdPower, self.limit
+	self.lock.Unlock()
+
+	// Stalling this close to the limit is the claw closing on itself.
+	const margin = 5
+
+	stalled, err := self.runUntil(speed, func(travel float64) bool {
+		return limit > 0 && travel >= limit
+	})
+	if err != nil {
+		self.motor.Stop()
+		return false, err
+	}
+
+	held := stalled && (limit == 0 || self.travel() < limit-margin)
+
+	if held && hold != 0 {
+		err = self.motor.TryRun(int16(math.Copysign(math.Abs(float64(hold)), float64(speed))))
+	} else {
+		self.motor.Stop()
+	}
+
+	self.lock.Lock()
+	self.held = held && err == nil
+	self.lock.Unlock()
+
+	return held, err
+}
+
+// Opens the gripper back to its open position, releasing any object.
+func (self *Gripper) Open() error {
+	self.lock.Lock()
+	speed := self.closeSpeed
+	self.held = false
+	self.lock.Unlock()
+
+	_, err := self.runUntil(-speed, func(travel float64) bool {
+		return travel <= 0
+	})
+	self.motor.Stop()
+
+	return err
+}
@@ -0,0 +1,167 @@
+package Motor
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/jermon/GoEV3/Errors"
+	"github.com/jermon/GoEV3/utilities"
+)
+
+// Motors commanded together, e.g. the arm and the wheels of a robot. Each
+// command sets the setpoints of all the motors first and then writes their
+// commands in a single loop, so that they start as close together as sysfs
+// allows; the writes go through the files the sysfs backend keeps open.
+//
+// Each motor runs at the group's speed multiplied by its ratio, 1 by
+// default, e.g. 3 for a motor geared down three times as much as the others:
+//
+//	lift := Motor.NewMotorGroup(left, right)
+//	lift.SetRatio(1, -1) // mounted the other way round
+//	lift.RunToRelativePosition(360, Motor.Percent(40))
+//	lift.WaitForAllCompleted(5 * time.Second)
+type MotorGroup struct {
+	lock   sync.Mutex
+	motors []*Motor
+	ratios []float64
+}
+
+// Creates a group of the motors, all with a ratio of 1.
+func NewMotorGroup(motors ...*Motor) *MotorGroup {
+	g := new(MotorGroup)
+	g.motors = append([]*Motor(nil), motors...)
+	g.ratios = make([]float64, len(motors))
+	for i := range g.ratios {
+		g.ratios[i] = 1
+	}
+
+	return g
+}
+
+// Returns the motors of the group, in the order given to NewMotorGroup.
+func (self *MotorGroup) Motors() []*Motor {
+	return append([]*Motor(nil), self.motors...)
+}
+
+// Sets the ratio of the motor at `index`, by which its speeds and position
+// changes are multiplied; negative for a motor turning the other way.
+// Returns an error matching Errors.ErrOutOfRange if there is no such motor.
+func (self *MotorGroup) SetRatio(index int, ratio float64) error {
+	if err := self.checkIndex(index); err != nil {
+		return err
+	}
+
+	self.lock.Lock()
+	self.ratios[index] = ratio
+	self.lock.Unlock()
+
+	return nil
+}
+
+func (self *MotorGroup) ratio(index int) float64 {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	return self.ratios[index]
+}
+
+// Runs all the motors at `speed` times their ratios, as Run takes it. Returns
+// an error matching Errors.ErrOutOfRange, before starting any, if a speed is
+// out of range, and the error of any write.
+func (self *MotorGroup) Run(speed int16) error {
+	speeds := make([]int16, len(self.motors))
+	for i := range self.motors {
+		speeds[i] = int16(math.Round(float64(speed) * self.ratio(i)))
+	}
+
+	return RunTogether(self.motors, speeds)
+}
+
+// Runs all the motors at `speed` times their ratios, as RunAt takes it.
+func (self *MotorGroup) RunAt(speed Speed) error {
+	speeds := make([]int16, len(self.motors))
+	for i, m := range self.motors {
+		speeds[i] = m.RunValue(Speed{speed.value * self.ratio(i), speed.unit})
+	}
+
+	return RunTogether(self.motors, speeds)
+}
+
+// Runs all the motors by `delta` tacho counts times their ratios, at `speed`
+// times their ratios, within their soft limits. Returns an error matching
+// Errors.ErrInvalidMode, before starting any, if a driver doesn't support
+// it, and the error of any write. Use WaitForAllCompleted to wait for them.
+func (self *MotorGroup) RunToRelativePosition(delta int64, speed Speed) error {
+	for _, m := range self.motors {
+		if err := m.checkSupported(commandsFD, string(CommandRunToAbsPos)); err != nil {
+			return err
+		}
+	}
+
+	for i, m := range self.motors {
+		m.cancelRamp()
+		m.cancelHold()
+
+		ratio := self.ratio(i)
+		position := int64(m.CurrentPosition())
+		target := m.clampTarget(position + int64(math.Round(float64(delta)*ratio)))
+
+		setter, value := m.positionSetpoint(Speed{speed.value * ratio, speed.unit})
+
+		markStarted(m.folder)
+		if err := utilities.WriteValue(m.folder, setter, value); err != nil {
+			return err
+		}
+		if err := utilities.WriteValue(m.folder, "position_sp", target); err != nil {
+			return err
+		}
+	}
+
+	for _, m := range self.motors {
+		if err := utilities.WriteValue(m.folder, runFD, string(CommandRunToAbsPos)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Stops all the motors, one right after the other, each as its stop action
+// says. All are stopped even if one fails; the first error is returned.
+func (self *MotorGroup) Stop() error {
+	return StopTogether(self.motors...)
+}
+
+// Waits until all the motors stop running after a position command, or
+// `timeout` passes, and reports how each ended, as WaitForCompletion does. A
+// timeout of 0 waits for as long as it takes.
+func (self *MotorGroup) WaitForAllCompleted(timeout time.Duration) []Completion {
+	results := make([]Completion, len(self.motors))
+
+	var wg sync.WaitGroup
+	for i, m := range self.motors {
+		wg.Add(1)
+		go func(i int, m *Motor) {
+			defer wg.Done()
+			results[i] = m.WaitForCompletion(timeout)
+		}(i, m)
+	}
+	wg.Wait()
+
+	return results
+}
+
+// Returns an error matching Errors.ErrOutOfRange if `index` isn't a motor of the group.
+func (self *MotorGroup) checkIndex(index int) error {
+	if index < 0 || index >= len(self.motors) {
+		return &Errors.DeviceError{
+			Kind:   Errors.ErrOutOfRange,
+			Device: "motor group",
+			Detail: fmt.Sprintf("index %d, the group has %d motors", index, len(self.motors)),
+		}
+	}
+
+	return nil
+}
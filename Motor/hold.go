@@ -0,0 +1,206 @@
+package Motor
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/jermon/GoEV3/Control"
+	"github.com/jermon/GoEV3/utilities"
+)
+
+// How often the software servo corrects the motor's power.
+const holdInterval = 10 * time.Millisecond
+
+// Default gains of the software servo, in percent of full power per tacho
+// count of error.
+const (
+	HOLD_KP = 2.0
+	HOLD_KI = 1.0
+	HOLD_KD = 0.05
+)
+
+// The position a motor is held at in software, the controller doing it, and
+// the motion profile moving the position there, if any; see profile.go.
+type servo struct {
+	lock    sync.Mutex
+	pid     *Control.PID
+	task    *utilities.PollTask
+	profile *trajectory
+}
+
+var gServos = make(map[string]*servo)
+var gServosLock = &sync.Mutex{}
+
+func (self Motor) servo(create bool) *servo {
+	gServosLock.Lock()
+	defer gServosLock.Unlock()
+
+	s, ok := gServos[self.folder]
+	if !ok && create {
+		s = new(servo)
+		s.pid = Control.NewPID(HOLD_KP, HOLD_KI, HOLD_KD)
+		s.pid.SetOutputLimits(-100, 100)
+		gServos[self.folder] = s
+	}
+
+	return s
+}
+
+// Sets the gains HoldAt uses for this motor, in percent of full power per
+// tacho count of error. A stiffer arm or a heavier load needs higher gains.
+func (self Motor) SetHoldGains(kp float64, ki float64, kd float64) {
+	self.servo(true).pid.SetGains(kp, ki, kd)
+}
+
+// Holds the motor at `position`, in tacho counts and within the soft limits,
+// by correcting its power in software until ReleaseHold or another command,
+// so that an arm or a pen lift resists being pushed away. Unlike the Hold
+// stop action, this works on kernels without it or with flaky regulation:
+//
+//	arm.HoldAt(90)
+//	... // the arm stays at 90 while the robot drives
+//	arm.ReleaseHold()
+func (self Motor) HoldAt(position int64) error {
+	return self.hold(self.clampTarget(position), nil)
+}
+
+// Starts the software servo towards `target`, along `profile` if not nil.
+func (self Motor) hold(target int64, profile *trajectory) error {
+	self.cancelRamp()
+
+	lock := commandLock(self.folder)
+	lock.Lock()
+	defer lock.Unlock()
+
+	s := self.servo(true)
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.task != nil {
+		s.task.Cancel()
+		s.task = nil
+	}
+	s.pid.Reset()
+	s.profile = profile
+	if profile != nil {
+		profile.began = time.Now()
+		s.pid.SetSetpoint(profile.start)
+	} else {
+		s.pid.SetSetpoint(float64(target))
+	}
+
+	markStarted(self.folder)
+	if err := utilities.WriteValue(self.folder, self.holdSetter(), 0); err != nil {
+		return err
+	}
+	if err := utilities.WriteValue(self.folder, runFD, string(CommandRunForever)); err != nil {
+		return err
+	}
+
+	var task *utilities.PollTask
+	task = utilities.Poll(holdInterval, func() {
+		self.holdStep(s, &task)
+	})
+	s.task = task
+
+	return nil
+}
+
+// Stops holding the motor in software, and stops the motor, as its stop
+// action says. Does nothing unless HoldAt is holding it.
+func (self Motor) ReleaseHold() {
+	s := self.servo(false)
+	if s == nil {
+		return
+	}
+
+	s.lock.Lock()
+	holding := s.task != nil
+	s.lock.Unlock()
+
+	if holding {
+		self.Stop()
+	}
+}
+
+// Returns the position HoldAt is holding the motor at, or a profiled move
+// is heading for, and whether it is.
+func (self Motor) HoldTarget() (int64, bool) {
+	s := self.servo(false)
+	if s == nil {
+		return 0, false
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.profile != nil {
+		return int64(s.profile.target), s.task != nil
+	}
+
+	return int64(s.pid.Setpoint()), s.task != nil
+}
+
+// Returns the setpoint the servo writes its output to: the duty cycle, or
+// the speed when the driver regulates it.
+func (self Motor) holdSetter() string {
+	if isRegulated(self.folder) {
+		return speedSetterFD
+	}
+
+	return powerSetterFD
+}
+
+// Corrects the motor's power towards the held position.
+func (self Motor) holdStep(s *servo, taskRef **utilities.PollTask) {
+	lock := commandLock(self.folder)
+	lock.Lock()
+	defer lock.Unlock()
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	// Released or replaced in the meantime.
+	task := *taskRef
+	if s.task != task {
+		return
+	}
+
+	position, err := self.TryCurrentPosition()
+	if err != nil {
+		return
+	}
+
+	// Moving the setpoint along the profile, feeding its speed forward.
+	var feedforward float64
+	if s.profile != nil {
+		setpoint, speed := s.profile.at(time.Since(s.profile.began))
+		s.pid.SetSetpoint(setpoint)
+		feedforward = speed / float64(self.MaxSpeed()) * 100
+	}
+
+	output := math.Max(-100, math.Min(100, s.pid.Update(float64(position))+feedforward))
+	value := int16(math.Round(output))
+	setter := self.holdSetter()
+	if setter == speedSetterFD {
+		value = self.RunValue(Percent(output))
+	}
+
+	utilities.WriteValue(self.folder, setter, value)
+}
+
+// Stops the software servo, if any, leaving the motor as it is.
+func (self Motor) cancelHold() {
+	s := self.servo(false)
+	if s == nil {
+		return
+	}
+
+	s.lock.Lock()
+	if s.task != nil {
+		s.task.Cancel()
+		s.task = nil
+	}
+	s.lock.Unlock()
+}
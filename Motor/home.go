@@ -0,0 +1,73 @@
+package Motor
+
+import (
+	"math"
+	"strings"
+	"time"
+
+	"github.com/jermon/GoEV3/utilities"
+)
+
+// Degrees a motor backs off from the end stop after homing.
+const homeBackOff = 5
+
+// Longest a homing back-off may take.
+const backOffTimeout = 2 * time.Second
+
+// Drives the motor gently in `direction` (positive or negative) at `power`
+// until it stalls against a mechanical end stop, takes that position as zero
+// and backs off slightly, so that the mechanism doesn't rest strained against
+// the stop. This is the standard way for attachments to establish a reference
+// position at startup:
+//
+//	arm.Home(-1, 30)
+//	arm.SetLimits(0, 180)
+func (self Motor) Home(direction int, power int16) error {
+	return self.HomeTo(direction, power, 0, homeBackOff)
+}
+
+// Homes the motor like Home, but takes the end stop as position `offset`
+// degrees and backs off by `backOff` degrees, 0 staying against the stop.
+// Soft limits are ignored while homing.
+func (self Motor) HomeTo(direction int, power int16, offset float64, backOff float64) error {
+	sign := int16(1)
+	if direction < 0 {
+		sign = -1
+	}
+	power = sign * int16(math.Abs(float64(power)))
+
+	// The end stop may well be beyond the limits until the position is known.
+	min, max, limited := self.Limits()
+	if limited {
+		self.ClearLimits()
+		defer self.SetLimits(min, max)
+	}
+
+	if err := self.TryRun(power); err != nil {
+		return err
+	}
+	waitUntilStall(&self, 3, 200*time.Millisecond, func() bool { return false })
+	self.Stop()
+
+	self.InitializePosition(int32(math.Round(self.DegreesToCounts(offset))))
+
+	if backOff <= 0 {
+		return nil
+	}
+
+	setter, value := self.positionSetpoint(Percent(float64(power)))
+	lock := commandLock(self.folder)
+	lock.Lock()
+	markStarted(self.folder)
+	utilities.WriteValue(self.folder, setter, value)
+	utilities.WriteValue(self.folder, "position_sp", -int64(sign)*int64(math.Round(self.DegreesToCounts(backOff))))
+	utilities.WriteStringValue(self.folder, runFD, string(CommandRunToRelPos))
+	lock.Unlock()
+
+	deadline := time.Now().Add(backOffTimeout)
+	for strings.Contains(self.GetState(), "running") && time.Now().Before(deadline) {
+		time.Sleep(stallInterval)
+	}
+
+	return nil
+}
@@ -0,0 +1,203 @@
+package Motor
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jermon/GoEV3/utilities"
+)
+
+// Degrees from the target within which a lift move is complete.
+const liftTolerance = 2
+
+// A lift, elevator or crane arm driven by a motor, moved between named
+// heights within soft travel limits and held in place between moves:
+//
+//	fork := Motor.NewLift(Motor.FindMotor(Motor.OutPortD), 60)
+//	fork.SetScale(12)          // 12 motor degrees per millimeter of height
+//	fork.SetLimits(0, 80)
+//	fork.Define("floor", 0)
+//	fork.Define("pallet", 15)
+//	fork.Define("shelf", 72)
+//	fork.Home()
+//	fork.GoTo("pallet")
+//
+// Heights are in any unit chosen with SetScale, degrees of the motor by
+// default, measured from the bottom found by Home, or from the position the
+// motor is in when the lift is created.
+type Lift struct {
+	lock sync.Mutex
+
+	motor  *Motor
+	power  int16
+	scale  float64
+	bottom float64
+
+	min, max float64
+	limited  bool
+	presets  map[string]float64
+
+	minMovement float64
+	window      time.Duration
+}
+
+// Creates a lift raised when its motor runs at positive speeds, moving at
+// `power`, a duty cycle in [1, 100].
+func NewLift(m *Motor, power int16) *Lift {
+	l := new(Lift)
+	l.motor = m
+	l.power = power
+	l.scale = 1
+	l.bottom = m.CurrentDegrees()
+	l.presets = make(map[string]float64)
+	l.minMovement = 3
+	l.window = 200 * time.Millisecond
+
+	return l
+}
+
+// Sets how many degrees the motor turns per unit of height.
+func (self *Lift) SetScale(degreesPerUnit float64) {
+	self.lock.Lock()
+	self.scale = degreesPerUnit
+	self.lock.Unlock()
+}
+
+// Sets the lowest and highest heights moves are allowed to reach. Targets
+// beyond them are clamped.
+func (self *Lift) SetLimits(min float64, max float64) {
+	if min > max {
+		min, max = max, min
+	}
+
+	self.lock.Lock()
+	self.min, self.max, self.limited = min, max, true
+	self.lock.Unlock()
+}
+
+// Sets the power moves are made with, a duty cycle in [1, 100].
+func (self *Lift) SetPower(power int16) {
+	self.lock.Lock()
+	self.power = power
+	self.lock.Unlock()
+}
+
+// Names a height, to move to it with GoTo.
+func (self *Lift) Define(name string, height float64) {
+	self.lock.Lock()
+	self.presets[name] = height
+	self.lock.Unlock()
+}
+
+// Returns the names of the heights defined, ordered by height.
+func (self *Lift) Presets() []string {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	names := make([]string, 0, len(self.presets))
+	for name := range self.presets {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool { return self.presets[names[i]] < self.presets[names[j]] })
+
+	return names
+}
+
+// Returns the current height.
+func (self *Lift) Height() float64 {
+	self.lock.Lock()
+	bottom, scale := self.bottom, self.scale
+	self.lock.Unlock()
+
+	return (self.motor.CurrentDegrees() - bottom) / scale
+}
+
+// Lowers the lift until it stalls against its bottom, which becomes height 0,
+// and holds it there.
+func (self *Lift) Home() error {
+	self.lock.Lock()
+	power := self.power
+	self.lock.Unlock()
+
+	if err := self.motor.HomeTo(-1, power, 0, 0); err != nil {
+		return err
+	}
+	self.Hold()
+
+	self.lock.Lock()
+	self.bottom = 0
+	self.lock.Unlock()
+
+	return nil
+}
+
+// Moves to the height named `name`.
+func (self *Lift) GoTo(name string) error {
+	self.lock.Lock()
+	height, ok := self.presets[name]
+	self.lock.Unlock()
+
+	if !ok {
+		return fmt.Errorf("lift: no height named %q", name)
+	}
+
+	return self.MoveTo(height)
+}
+
+// Moves to `height`, clamped to the limits, and holds it there. Returns an
+// error if the lift stalls on the way, e.g. against an obstacle; it is then
+// held where it stopped.
+func (self *Lift) MoveTo(height float64) error {
+	self.lock.Lock()
+	if self.limited {
+		height = math.Max(self.min, math.Min(self.max, height))
+	}
+	target := self.bottom + height*self.scale
+	power, minMovement, window := self.power, self.minMovement, self.window
+	self.lock.Unlock()
+
+	m := self.motor
+	setter, value := m.positionSetpoint(Percent(float64(power)))
+	lock := commandLock(m.folder)
+	lock.Lock()
+	markStarted(m.folder)
+	utilities.WriteValue(m.folder, setter, value)
+	utilities.WriteValue(m.folder, "position_sp", m.clampTarget(int64(math.Round(m.DegreesToCounts(target)))))
+	utilities.WriteStringValue(m.folder, stopActionAttribute(m.folder), string(Hold))
+	utilities.WriteStringValue(m.folder, runFD, string(CommandRunToAbsPos))
+	lock.Unlock()
+
+	arrived := func() bool {
+		return math.Abs(m.CurrentDegrees()-target) <= liftTolerance
+	}
+	// The driver leaves the running state once it holds the target.
+	done := func() bool {
+		return arrived() || !strings.Contains(m.GetState(), "running")
+	}
+	if waitUntilStall(m, minMovement, window, done) && !arrived() {
+		self.Hold()
+		return fmt.Errorf("lift: stalled at height %.1f on the way to %.1f", self.Height(), height)
+	}
+
+	return nil
+}
+
+// Moves by `delta` from the current height, within the limits.
+func (self *Lift) MoveBy(delta float64) error {
+	return self.MoveTo(self.Height() + delta)
+}
+
+// Stops the motor and actively holds the current position.
+func (self *Lift) Hold() {
+	self.motor.HoldPosition()
+}
+
+// Stops the motor and lets the lift go, e.g. to lower it by hand.
+func (self *Lift) Release() {
+	utilities.WriteStringValue(self.motor.folder, stopActionAttribute(self.motor.folder), string(Coast))
+	self.motor.Stop()
+}
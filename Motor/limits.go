@@ -0,0 +1,98 @@
+package Motor
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/jermon/GoEV3/utilities"
+)
+
+// How often motors with soft limits are checked for overtravel.
+const limitsInterval = 20 * time.Millisecond
+
+// Soft travel limits of a motor, in degrees.
+type softLimits struct {
+	motor    Motor
+	min, max float64
+}
+
+var gLimits = make(map[string]softLimits)
+var gLimitsLock = &sync.Mutex{}
+var gLimitsTask *utilities.PollTask
+
+// Limits the motor's travel to positions between `min` and `max` degrees,
+// protecting the mechanism it drives whatever code commands it: position
+// targets are clamped to the limits, running further past a limit stops the
+// motor instead, and the motor is stopped whenever it is found driving past
+// a limit, which is checked every 20 milliseconds in the background.
+func (self Motor) SetLimits(min float64, max float64) {
+	if min > max {
+		min, max = max, min
+	}
+
+	gLimitsLock.Lock()
+	gLimits[self.folder] = softLimits{self, min, max}
+	if gLimitsTask == nil {
+		gLimitsTask = utilities.Poll(limitsInterval, checkLimits)
+	}
+	gLimitsLock.Unlock()
+}
+
+// Removes the motor's soft limits.
+func (self Motor) ClearLimits() {
+	gLimitsLock.Lock()
+	delete(gLimits, self.folder)
+	if len(gLimits) == 0 && gLimitsTask != nil {
+		gLimitsTask.Cancel()
+		gLimitsTask = nil
+	}
+	gLimitsLock.Unlock()
+}
+
+// Returns the motor's soft limits in degrees, and whether it has any.
+func (self Motor) Limits() (min float64, max float64, ok bool) {
+	gLimitsLock.Lock()
+	defer gLimitsLock.Unlock()
+
+	l, ok := gLimits[self.folder]
+	return l.min, l.max, ok
+}
+
+// Clamps a position target in tacho counts to the soft limits.
+func (self Motor) clampTarget(counts int64) int64 {
+	min, max, ok := self.Limits()
+	if !ok {
+		return counts
+	}
+
+	degrees := math.Max(min, math.Min(max, self.CountsToDegrees(float64(counts))))
+	return int64(math.Round(self.DegreesToCounts(degrees)))
+}
+
+// Reports whether running at `speed` would drive the motor further past a limit.
+func (self Motor) pastLimit(speed int16) bool {
+	min, max, ok := self.Limits()
+	if !ok || speed == 0 {
+		return false
+	}
+
+	position := self.CurrentDegrees()
+	return (speed > 0 && position >= max) || (speed < 0 && position <= min)
+}
+
+// Stops the motors found driving past their limits.
+func checkLimits() {
+	gLimitsLock.Lock()
+	limits := make([]softLimits, 0, len(gLimits))
+	for _, l := range gLimits {
+		limits = append(limits, l)
+	}
+	gLimitsLock.Unlock()
+
+	for _, l := range limits {
+		if l.motor.pastLimit(l.motor.CurrentSpeed()) {
+			l.motor.Stop()
+		}
+	}
+}
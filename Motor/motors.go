@@ -1,14 +1,23 @@
 // Provides APIs for interacting with EV3's motors.
+//
+// The methods of the motors are safe for concurrent use: the writes of a
+// command, such as the speed setpoint and then the command itself, aren't
+// interleaved with those of another goroutine commanding the same motor.
+// The last command wins.
 package Motor
 
 import (
+	"errors"
+	"fmt"
+	"github.com/jermon/GoEV3/Errors"
+	"github.com/jermon/GoEV3/Platform"
 	"github.com/jermon/GoEV3/utilities"
-	"log"
-	"os"
 	"path"
+	"strings"
+	"sync"
+	"time"
 )
 
-
 // Constants for output ports.
 type OutPort string
 
@@ -21,8 +30,9 @@ const (
 
 // Motor type.
 type Motor struct {
-	port OutPort
+	port   OutPort
 	folder string
+	driver string
 }
 
 // Names of files which constitute the low-level motor API
@@ -30,6 +40,7 @@ const (
 	rootMotorPath = "/sys/class/tacho-motor"
 	// File descriptors for getting/setting parameters
 	portFD           = "address"
+	driverFD         = "driver_name"
 	regulationModeFD = "speed_regulation"
 	speedGetterFD    = "speed"
 	speedSetterFD    = "speed_sp"
@@ -41,35 +52,243 @@ const (
 	stateFD          = "state"
 )
 
-func FindMotor(port OutPort) *Motor {
-	m := new(Motor)
-	m.port = port
+// Returns the output port corresponding to an address such as "outA" or
+// "ev3-ports:outA", or a platform specific address such as "pistorms:BAM1" or
+// "spi0.1:MA" on a BrickPi3.
+func CanonicalOutPort(address string) OutPort {
+	return OutPort(strings.TrimPrefix(Platform.Current().PortName(address), "out"))
+}
+
+// Parses an output port given as "A", "outA", an address such as
+// "ev3-ports:outA" or "pistorms:BAM1", or the sysfs path of the motor connected
+// to it, e.g. "/sys/class/tacho-motor/motor0". Letters are case insensitive.
+func ParseOutPort(s string) (OutPort, error) {
+	name := strings.TrimSpace(s)
+
+	if strings.HasPrefix(name, "/sys/") {
+		name = utilities.ReadStringValue(name, portFD)
+	}
+	if len(name) == 1 {
+		name = "out" + strings.ToUpper(name)
+	}
+	if len(name) == 4 && strings.EqualFold(name[:3], "out") {
+		name = "out" + strings.ToUpper(name[3:])
+	}
+
+	port := CanonicalOutPort(name)
+	switch port {
+	case OutPortA, OutPortB, OutPortC, OutPortD:
+		return port, nil
+	}
+
+	return "", fmt.Errorf("invalid output port %q, expected A to D", s)
+}
+
+func (self OutPort) String() string {
+	return string(self)
+}
+
+// Parses the port with ParseOutPort. Allows ports to be passed as command-line flags.
+func (self *OutPort) Set(s string) error {
+	port, err := ParseOutPort(s)
+	if err != nil {
+		return err
+	}
+
+	*self = port
+	return nil
+}
+
+// Parses the port with ParseOutPort. Allows ports to be read from configuration files.
+func (self *OutPort) UnmarshalText(text []byte) error {
+	return self.Set(string(text))
+}
+
+// Locks serializing the commands of each motor, by folder: a command is a
+// sequence of writes, e.g. a setpoint and then the command itself, which
+// another goroutine commanding the motor mustn't come in between.
+var gCommandLocks = make(map[string]*sync.Mutex)
+var gCommandLocksLock = &sync.Mutex{}
+
+func commandLock(folder string) *sync.Mutex {
+	gCommandLocksLock.Lock()
+	defer gCommandLocksLock.Unlock()
+
+	lock, ok := gCommandLocks[folder]
+	if !ok {
+		lock = &sync.Mutex{}
+		gCommandLocks[folder] = lock
+	}
+
+	return lock
+}
+
+// Folders of the motors started by this program, stopped by StopAll.
+var gStarted = make(map[string]bool)
+var gStartedLock = &sync.Mutex{}
+
+func markStarted(folder string) {
+	gStartedLock.Lock()
+	gStarted[folder] = true
+	gStartedLock.Unlock()
+}
+
+// Folders of the motors opened by this program, coasted by CoastAll.
+var gOpened = make(map[string]bool)
+var gOpenedLock = &sync.Mutex{}
+
+func markOpened(folder string) {
+	gOpenedLock.Lock()
+	gOpened[folder] = true
+	gOpenedLock.Unlock()
+}
+
+// Stops every motor this program has started.
+func StopAll() {
+	gStartedLock.Lock()
+	folders := make([]string, 0, len(gStarted))
+	for folder := range gStarted {
+		folders = append(folders, folder)
+	}
+	gStartedLock.Unlock()
+
+	for _, folder := range folders {
+		Motor{folder: folder}.cancelHold()
+		utilities.WriteStringValue(folder, runFD, string(CommandStop))
+	}
+}
+
+// Stops every motor this program has opened or started and lets them coast,
+// whatever their stop actions, so that none is left holding its position
+// once the program exits.
+func CoastAll() {
+	folders := make(map[string]bool)
+	gOpenedLock.Lock()
+	for folder := range gOpened {
+		folders[folder] = true
+	}
+	gOpenedLock.Unlock()
+	gStartedLock.Lock()
+	for folder := range gStarted {
+		folders[folder] = true
+	}
+	gStartedLock.Unlock()
+
+	for folder := range folders {
+		m := Motor{folder: folder}
+		m.cancelRamp()
+		m.cancelHold()
+		utilities.WriteStringValue(folder, stopActionAttribute(folder), string(Coast))
+		utilities.WriteStringValue(folder, runFD, string(CommandStop))
+	}
+}
+
+// Provides access to the motor at the given port. A missing motor is a fatal
+// error; use OpenMotor to handle it.
+func FindMotor(port OutPort, opts ...Option) *Motor {
+	m, err := OpenMotor(port, opts...)
+	if err != nil {
+		utilities.Fatal(err)
+	}
 
-	m.folder = findFolder(port)
 	return m
 }
 
-func findFolder(port OutPort) string {
-	if _, err := os.Stat(rootMotorPath); os.IsNotExist(err) {
-		log.Fatal("There are no motors connected")
+// Provides access to the motor at the given port. Returns an error matching
+// Errors.ErrDeviceNotFound if there is no motor, or Errors.ErrPortMismatch if
+// the motor doesn't have the driver required with WithRequiredDriver.
+func OpenMotor(port OutPort, opts ...Option) (*Motor, error) {
+	m := new(Motor)
+	m.port = CanonicalOutPort(string(port))
+
+	o := newOptions(opts)
+	folder, err := findFolder(m.port, o)
+	if err != nil {
+		return nil, err
 	}
 
-	rootMotorFolder, _ := os.Open(rootMotorPath)
-	motorFolders, _ := rootMotorFolder.Readdir(-1)
-	if len(motorFolders) == 0 {
-		log.Fatal("There are no motors connected")
+	m.folder = folder
+	m.driver = utilities.ReadStringValue(folder, driverFD)
+	probeAttributes(folder)
+	markOpened(folder)
+
+	if o.poll > 0 {
+		gPollIntervalsLock.Lock()
+		gPollIntervals[folder] = o.poll
+		gPollIntervalsLock.Unlock()
 	}
 
-	for _, folderInfo := range motorFolders {
-		folder := folderInfo.Name()
-		motorPort := utilities.ReadStringValue(path.Join(rootMotorPath, folder), portFD)
-		if motorPort == "out"+string(port) {
-			return path.Join(rootMotorPath, folder)
+	return m, nil
+}
+
+func findFolder(port OutPort, o options) (string, error) {
+	return findFolderIn(rootMotorPath, "motor", port, o)
+}
+
+// Looks for the device at `port` in the class folder `root`, e.g.
+// /sys/class/tacho-motor. `device` names the kind of device in errors.
+func findFolderIn(root string, device string, port OutPort, o options) (string, error) {
+	deadline := time.Now().Add(o.timeout)
+
+	for {
+		folder, found := lookupFolder(root, port, o.driver)
+		if folder != "" {
+			return folder, nil
 		}
+
+		if !time.Now().Before(deadline) {
+			err := &Errors.DeviceError{Kind: Errors.ErrDeviceNotFound, Device: device, Port: string(port)}
+
+			switch {
+			case found:
+				err.Kind = Errors.ErrPortMismatch
+				err.Detail = "expected " + o.driver
+			case !utilities.Exists(root) || len(utilities.ListDir(root)) == 0:
+				err.Detail = "there are no " + device + "s connected"
+				err.Cause = Platform.EnvironmentError()
+			}
+
+			return "", err
+		}
+
+		time.Sleep(100 * time.Millisecond)
 	}
+}
 
-	log.Fatal("No motor is connected to port ", port )
-	return ""
+// Returns the folder of the motor at the given port with the given driver, or
+// any driver if empty. `found` reports whether a motor with another driver is there.
+func lookupFolder(root string, port OutPort, driver string) (folder string, found bool) {
+	for _, name := range utilities.ListDir(root) {
+		motorPort := utilities.ReadStringValue(path.Join(root, name), portFD)
+		if Platform.Current().Matches("out"+string(port), motorPort) {
+			if driver != "" && utilities.ReadStringValue(path.Join(root, name), driverFD) != driver {
+				found = true
+				continue
+			}
+			return path.Join(root, name), true
+		}
+	}
+
+	return "", found
+}
+
+// Returns the output port the motor is connected to.
+func (self Motor) Port() OutPort {
+	return self.port
+}
+
+// Closes the motor's attribute files kept open, once the program is done with
+// it; see utilities.IOStrategy. Using the motor again opens them again.
+func (self Motor) Close() {
+	utilities.ReleaseFiles(self.folder)
+}
+
+// Makes reads and writes of the motor's attributes fail with an error matching
+// Errors.ErrTimeout once they take longer than `timeout`, instead of the
+// global timeout set with utilities.SetIOTimeout. A negative timeout waits
+// for as long as it takes.
+func (self Motor) SetIOTimeout(timeout time.Duration) {
+	utilities.SetDeviceIOTimeout(self.folder, timeout)
 }
 
 // Runs the motor at the given port.
@@ -85,76 +304,223 @@ func findFolder(port OutPort) string {
 // which ranges from about -1000 to 1000. The actual range depends on the type of the motor - see ev3dev docs.
 //
 // Negative values indicate reverse motion regardless of the regulation mode.
+//
+// A speed out of range is a fatal error; use TryRun to handle it.
 func (self Motor) Run(speed int16) {
-	regulationMode := utilities.ReadStringValue(self.folder, regulationModeFD)
-
-	switch regulationMode {
-	case "on":
-		utilities.WriteIntValue(self.folder, speedSetterFD, int64(speed))
-		utilities.WriteStringValue(self.folder, runFD, "run-forever")
-	case "off":
-		if speed > 100 || speed < -100 {
-			log.Fatal("The speed must be in range [-100, 100]")
+	if err := self.TryRun(speed); errors.Is(err, Errors.ErrOutOfRange) {
+		utilities.Fatal(err)
+	}
+}
+
+// Runs the motor like Run, but returns an error matching Errors.ErrOutOfRange
+// instead of exiting if the speed is out of range, and the error of any write. A motor at one of its soft
+// limits is stopped instead of running further past it.
+func (self Motor) TryRun(speed int16) error {
+	if self.pastLimit(speed) {
+		self.Stop()
+		return nil
+	}
+	self.cancelHold()
+
+	lock := commandLock(self.folder)
+	lock.Lock()
+	defer lock.Unlock()
+
+	// With regulation off, the duty cycle is ramped here if the motor has ramp times.
+	if speed >= -100 && speed <= 100 && !isRegulated(self.folder) {
+		if ramped, err := self.runRamped(speed); ramped {
+			return err
 		}
-		utilities.WriteIntValue(self.folder, powerSetterFD, int64(speed))
-		utilities.WriteStringValue(self.folder, runFD, "run-forever")
 	}
+
+	return self.start(speed, CommandRunForever)
 }
 
-func (self Motor) Turn(command string, data int64) {
-	utilities.WriteIntValue(self.folder, powerSetterFD, 50)
-	utilities.WriteIntValue(self.folder, "position_sp", data)
-	utilities.WriteStringValue(self.folder, runFD, command)
+// Sets the speed as Run does and runs `command`.
+func (self Motor) start(speed int16, command Command) error {
+	if err := self.setRunSpeed(speed); err != nil {
+		return err
+	}
+
+	return utilities.WriteValue(self.folder, runFD, string(command))
+}
+
+// Writes the speed as Run takes it to the setpoint of the motor's regulation mode.
+func (self Motor) setRunSpeed(speed int16) error {
+	self.cancelRamp()
+	self.cancelHold()
+
+	if isRegulated(self.folder) {
+		markStarted(self.folder)
+		return utilities.WriteValue(self.folder, speedSetterFD, speed)
+	}
+
+	// Off, or not supported by the driver, as with some NXT motor drivers.
+	if speed > 100 || speed < -100 {
+		return &Errors.DeviceError{
+			Kind:   Errors.ErrOutOfRange,
+			Device: "motor",
+			Port:   string(self.port),
+			Detail: fmt.Sprintf("speed %d, expected [-100, 100]", speed),
+		}
+	}
+	markStarted(self.folder)
+	return utilities.WriteValue(self.folder, powerSetterFD, speed)
+}
+
+// Runs a position command, CommandRunToAbsPos or CommandRunToRelPos, to
+// the position `data` in tacho counts at half power. A command the driver
+// doesn't support is a fatal error; use TryTurn to handle it.
+func (self Motor) Turn(command Command, data int64) {
+	if err := self.TryTurn(command, data); errors.Is(err, Errors.ErrInvalidMode) {
+		utilities.Fatal(err)
+	}
+}
+
+// Runs a command like Turn, but returns an error matching Errors.ErrInvalidMode
+// instead of exiting if the driver doesn't support it, and the error of any write.
+func (self Motor) TryTurn(command Command, data int64) error {
+	if err := self.checkSupported(commandsFD, string(command)); err != nil {
+		return err
+	}
+	self.cancelRamp()
+	self.cancelHold()
+
+	switch command {
+	case CommandRunToAbsPos:
+		data = self.clampTarget(data)
+	case CommandRunToRelPos:
+		position := int64(self.CurrentPosition())
+		data = self.clampTarget(position+data) - position
+	}
+
+	lock := commandLock(self.folder)
+	lock.Lock()
+	defer lock.Unlock()
+
+	setter, value := self.positionSetpoint(Percent(50))
+	markStarted(self.folder)
+	if err := utilities.WriteValue(self.folder, setter, value); err != nil {
+		return err
+	}
+	if err := utilities.WriteValue(self.folder, "position_sp", data); err != nil {
+		return err
+	}
+
+	return utilities.WriteValue(self.folder, runFD, string(command))
 }
 
 // Stops the motor at the given port.
 func (self Motor) Stop() {
-	utilities.WriteStringValue(self.folder, runFD, "stop")
+	self.TryStop()
+}
+
+// Stops the motor like Stop, but returns the error of the write, e.g. an
+// *utilities.IOError matching Errors.ErrDisconnected or Errors.ErrTimeout.
+func (self Motor) TryStop() error {
+	lock := commandLock(self.folder)
+	lock.Lock()
+	defer lock.Unlock()
+
+	self.cancelRamp()
+	self.cancelHold()
+	return utilities.WriteValue(self.folder, runFD, string(CommandStop))
 }
 
 // Reads the operating speed of the motor at the given port.
 func (self Motor) CurrentSpeed() int16 {
-	return utilities.ReadInt16Value(self.folder, speedGetterFD)
+	value, _ := self.TryCurrentSpeed()
+	return value
+}
+
+// Reads the operating speed like CurrentSpeed, but returns the error of the read.
+func (self Motor) TryCurrentSpeed() (int16, error) {
+	return utilities.ReadValue[int16](self.folder, speedGetterFD)
 }
 
 // Reads the operating power of the motor at the given port.
 func (self Motor) CurrentPower() int16 {
-	return utilities.ReadInt16Value(self.folder, powerGetterFD)
+	value, _ := self.TryCurrentPower()
+	return value
+}
+
+// Reads the operating power like CurrentPower, but returns the error of the read.
+func (self Motor) TryCurrentPower() (int16, error) {
+	return utilities.ReadValue[int16](self.folder, powerGetterFD)
 }
 
 // Enables regulation mode, causing the motor at the given port to compensate
-// for any resistance and maintain its target speed.
+// for any resistance and maintain its target speed. Newer ev3dev kernels
+// always regulate the speed.
 func (self Motor) EnableRegulationMode() {
-	utilities.WriteStringValue(self.folder, regulationModeFD, "on")
+	setRegulation(self.folder, self.port, true)
 }
 
-// Disables regulation mode. Regulation mode is off by default.
+// Disables regulation mode. Regulation mode is off by default, except on newer
+// ev3dev kernels, which always regulate the speed and ignore this.
 func (self Motor) DisableRegulationMode(port OutPort) {
-	utilities.WriteStringValue(findFolder(port), regulationModeFD, "off")
+	folder, err := findFolder(port, options{})
+	if err != nil {
+		utilities.Fatal(err)
+	}
+
+	setRegulation(folder, port, false)
 }
 
 // Enables brake mode, causing the motor at the given port to brake to stops.
 func (self Motor) EnableBrakeMode() {
-	utilities.WriteStringValue(self.folder, stopModeFD, "brake")
+	utilities.WriteStringValue(self.folder, stopActionAttribute(self.folder), string(Brake))
 }
 
 // Disables brake mode, causing the motor at the given port to coast to stops. Brake mode is off by default.
 func (self Motor) DisableBrakeMode() {
-	utilities.WriteStringValue(self.folder, stopModeFD, "coast")
+	utilities.WriteStringValue(self.folder, stopActionAttribute(self.folder), string(Coast))
+}
+
+// Enables hold mode, causing the motor at the given port to actively hold the
+// position it stops at, e.g. so that an arm doesn't sag under its load. Use
+// DisableBrakeMode to go back to coasting.
+func (self Motor) EnableHoldMode() {
+	utilities.WriteStringValue(self.folder, stopActionAttribute(self.folder), string(Hold))
+}
+
+// Stops the motor and actively holds its current position, whatever its stop
+// action, which is then left as Hold.
+func (self Motor) HoldPosition() {
+	self.EnableHoldMode()
+	self.Stop()
 }
 
-// Reads the position of the motor at the given port.
+// Reads the position of the motor at the given port, in tacho counts.
 func (self Motor) CurrentPosition() int32 {
-	return utilities.ReadInt32Value(self.folder, positionFD)
+	value, _ := self.TryCurrentPosition()
+	return value
 }
 
-// Set the position of the motor at the given port.
+// Reads the position like CurrentPosition, but returns the error of the read.
+func (self Motor) TryCurrentPosition() (int32, error) {
+	return utilities.ReadValue[int32](self.folder, positionFD)
+}
+
+// Reads the position of the motor in degrees, converting tacho counts for
+// motors that don't count 360 per rotation.
+func (self Motor) CurrentDegrees() float64 {
+	return self.CountsToDegrees(float64(self.CurrentPosition()))
+}
+
+// Returns the name of the motor's driver, e.g. "lego-nxt-motor".
+func (self Motor) Driver() string {
+	return self.driver
+}
+
+// Set the position of the motor at the given port. Travel is unaffected.
 func (self Motor) InitializePosition(value int32) {
-	utilities.WriteIntValue(self.folder, positionFD, int64(value))
+	self.setPosition(value, func() {
+		utilities.WriteValue(self.folder, positionFD, value)
+	})
 }
 
 // Get motor state
 func (self Motor) GetState() string {
-  return utilities.ReadStringValue(self.folder, stateFD)
+	return utilities.ReadStringValue(self.folder, stateFD)
 }
-
@@ -0,0 +1,65 @@
+package Motor
+
+import (
+	"sync"
+	"time"
+)
+
+// Customizes how FindMotor looks up a motor.
+type Option func(*options)
+
+type options struct {
+	timeout time.Duration
+	driver  string
+	poll    time.Duration
+}
+
+// Waits up to `timeout` for the motor to appear, e.g. while its driver is
+// still loading at boot. By default a missing motor is a fatal error straight away.
+func WithTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.timeout = timeout
+	}
+}
+
+// Accepts only a motor with the given driver, e.g. "lego-ev3-l-motor". By
+// default any tacho motor is accepted.
+func WithRequiredDriver(driver string) Option {
+	return func(o *options) {
+		o.driver = driver
+	}
+}
+
+// Sets how often the motor's state callbacks, such as OnStalled, read it. By
+// default they read it every STATE_POLLING_INTERVAL.
+func WithPollInterval(interval time.Duration) Option {
+	return func(o *options) {
+		o.poll = interval
+	}
+}
+
+// Polling intervals set with WithPollInterval, by motor folder.
+var gPollIntervals = make(map[string]time.Duration)
+var gPollIntervalsLock = &sync.Mutex{}
+
+// Returns the interval set with WithPollInterval, or `fallback`.
+func (self Motor) pollInterval(fallback time.Duration) time.Duration {
+	gPollIntervalsLock.Lock()
+	defer gPollIntervalsLock.Unlock()
+
+	if interval, ok := gPollIntervals[self.folder]; ok {
+		return interval
+	}
+
+	return fallback
+}
+
+func newOptions(opts []Option) options {
+	var o options
+
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return o
+}
@@ -0,0 +1,149 @@
+package Motor
+
+import (
+	"context"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/jermon/GoEV3/utilities"
+)
+
+// How a position command ended, as WaitForCompletion reports it.
+type Completion int
+
+const (
+	// The motor reached its target.
+	Completed Completion = iota
+	// The motor stopped moving before reaching its target, e.g. against an obstacle.
+	Stalled
+	// The timeout passed with the motor still on its way.
+	TimedOut
+)
+
+func (self Completion) String() string {
+	switch self {
+	case Stalled:
+		return "stalled"
+	case TimedOut:
+		return "timed out"
+	default:
+		return "completed"
+	}
+}
+
+// Least movement, in degrees, within completionWindow for a running motor not
+// to count as stalled, for drivers that don't report it.
+const (
+	completionMovement = 2
+	completionWindow   = 500 * time.Millisecond
+)
+
+// Runs the motor to the absolute position `position`, in tacho counts, at
+// `speed`, within the soft limits. Returns an error matching
+// Errors.ErrInvalidMode if the driver doesn't support it, and the error of
+// any write. Use WaitForCompletion to wait until it gets there:
+//
+//	m.RunToAbsolutePosition(180, Motor.DegPerSec(360))
+//	if m.WaitForCompletion(2*time.Second) != Motor.Completed {
+//		...
+//	}
+func (self Motor) RunToAbsolutePosition(position int64, speed Speed) error {
+	return self.runToPosition(CommandRunToAbsPos, self.clampTarget(position), speed)
+}
+
+// Runs the motor by `delta` tacho counts from its current position at
+// `speed`, within the soft limits, like RunToAbsolutePosition.
+func (self Motor) RunToRelativePosition(delta int64, speed Speed) error {
+	position := int64(self.CurrentPosition())
+	return self.runToPosition(CommandRunToRelPos, self.clampTarget(position+delta)-position, speed)
+}
+
+// Returns the setpoint position commands take their speed from, the duty
+// cycle or the speed when the driver regulates it, as on Stretch kernels,
+// and the value of `speed` for it. The direction comes from the target; the
+// driver only takes the magnitude.
+func (self Motor) positionSetpoint(speed Speed) (string, int16) {
+	value := int16(math.Abs(float64(self.RunValue(speed))))
+	if isRegulated(self.folder) {
+		return speedSetterFD, value
+	}
+
+	return powerSetterFD, value
+}
+
+func (self Motor) runToPosition(command Command, data int64, speed Speed) error {
+	if err := self.checkSupported(commandsFD, string(command)); err != nil {
+		return err
+	}
+	self.cancelRamp()
+	self.cancelHold()
+
+	setter, value := self.positionSetpoint(speed)
+
+	lock := commandLock(self.folder)
+	lock.Lock()
+	defer lock.Unlock()
+
+	markStarted(self.folder)
+	if err := utilities.WriteValue(self.folder, setter, value); err != nil {
+		return err
+	}
+	if err := utilities.WriteValue(self.folder, "position_sp", data); err != nil {
+		return err
+	}
+
+	return utilities.WriteValue(self.folder, runFD, string(command))
+}
+
+// Waits until the motor stops running after a position command, and reports
+// whether it got there, stalled on the way, or was still running after
+// `timeout`. A timeout of 0 waits for as long as it takes. The motor is
+// left running when the wait times out, and stopped when it stalls. A motor
+// turning less than 2 degrees in half a second counts as stalled.
+func (self Motor) WaitForCompletion(timeout time.Duration) Completion {
+	return self.waitForCompletion(timeout, nil)
+}
+
+// Waits like WaitForCompletion until the motor stops running, or until `ctx`
+// is done, reporting TimedOut with its error. The motor is left running then.
+func (self Motor) WaitForCompletionCtx(ctx context.Context) (Completion, error) {
+	result := self.waitForCompletion(0, func() bool { return ctx.Err() != nil })
+	if result == TimedOut {
+		return result, ctx.Err()
+	}
+
+	return result, nil
+}
+
+// Waits like WaitForCompletion, also giving up once `cancelled`, if given,
+// returns true.
+func (self Motor) waitForCompletion(timeout time.Duration, cancelled func() bool) Completion {
+	deadline := time.Now().Add(timeout)
+	result := Completed
+
+	stalled := waitUntilStall(&self, completionMovement, completionWindow, func() bool {
+		state := self.GetState()
+		switch {
+		case strings.Contains(state, "stalled"):
+			result = Stalled
+		case !strings.Contains(state, "running"):
+			return true
+		case timeout > 0 && time.Now().After(deadline):
+			result = TimedOut
+		case cancelled != nil && cancelled():
+			result = TimedOut
+		default:
+			return false
+		}
+
+		return true
+	})
+
+	if stalled || result == Stalled {
+		self.Stop()
+		return Stalled
+	}
+
+	return result
+}
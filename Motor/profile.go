@@ -0,0 +1,158 @@
+package Motor
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Limits of a trapezoidal move: the motor speeds up at Acceleration to
+// MaxSpeed, cruises, and slows down at the same rate to stop at the target.
+// Short moves never reach MaxSpeed and slow down straight away.
+type MotionProfile struct {
+	MaxSpeed Speed
+	// Rate of speeding up and slowing down, in degrees per second squared.
+	Acceleration float64
+}
+
+// Positions and speeds of a move along a profile, in tacho counts.
+type trajectory struct {
+	start  float64
+	target float64
+	// Peak speed and acceleration, positive whatever the direction.
+	speed        float64
+	acceleration float64
+	// Times speeding up and cruising, in seconds.
+	accelerating float64
+	cruising     float64
+	began        time.Time
+}
+
+// Plans a move from `start` to `target` at up to `speed` counts per second,
+// speeding up and slowing down at `acceleration` counts per second squared.
+func newTrajectory(start float64, target float64, speed float64, acceleration float64) *trajectory {
+	t := &trajectory{start: start, target: target, speed: math.Abs(speed), acceleration: math.Abs(acceleration)}
+
+	distance := math.Abs(target - start)
+	if t.speed == 0 || t.acceleration == 0 || distance == 0 {
+		t.speed = 0
+		return t
+	}
+
+	// Too short to reach the speed: a triangle rather than a trapezoid.
+	if distance < t.speed*t.speed/t.acceleration {
+		t.speed = math.Sqrt(distance * t.acceleration)
+	}
+	t.accelerating = t.speed / t.acceleration
+	t.cruising = distance/t.speed - t.accelerating
+
+	return t
+}
+
+// Returns the time the move takes.
+func (self *trajectory) duration() time.Duration {
+	return time.Duration((2*self.accelerating + self.cruising) * float64(time.Second))
+}
+
+// Returns the position and the speed `elapsed` into the move.
+func (self *trajectory) at(elapsed time.Duration) (position float64, speed float64) {
+	t := elapsed.Seconds()
+	total := 2*self.accelerating + self.cruising
+	if self.speed == 0 || t >= total {
+		return self.target, 0
+	}
+
+	var distance float64
+	switch {
+	case t < self.accelerating:
+		speed = self.acceleration * t
+		distance = speed * t / 2
+	case t < self.accelerating+self.cruising:
+		speed = self.speed
+		distance = self.speed * (t - self.accelerating/2)
+	default:
+		remaining := total - t
+		speed = self.acceleration * remaining
+		distance = math.Abs(self.target-self.start) - speed*remaining/2
+	}
+
+	direction := math.Copysign(1, self.target-self.start)
+	return self.start + direction*distance, direction * speed
+}
+
+// Moves the motor to the absolute position `position`, in tacho counts and
+// within the soft limits, along `profile`, streaming the position and speed
+// it should be at to the software servo of HoldAt from the shared polling
+// scheduler. Ends are smoother and more repeatable than with
+// RunToAbsolutePosition, e.g. for precision runs. Once there, the motor is
+// held at the position until ReleaseHold or another command:
+//
+//	arm.RunProfiledToAbsolutePosition(360, Motor.MotionProfile{MaxSpeed: Motor.DegPerSec(500), Acceleration: 1000})
+//	arm.WaitForProfile(0)
+//	arm.ReleaseHold()
+func (self Motor) RunProfiledToAbsolutePosition(position int64, profile MotionProfile) error {
+	target := self.clampTarget(position)
+	start, err := self.TryCurrentPosition()
+	if err != nil {
+		return err
+	}
+
+	countPerRot := float64(self.CountPerRot())
+	speed := profile.MaxSpeed.countsPerSecond(float64(self.MaxSpeed()), countPerRot)
+	speed = math.Min(math.Abs(speed), float64(self.MaxSpeed()))
+
+	return self.hold(target, newTrajectory(float64(start), float64(target), speed, self.DegreesToCounts(profile.Acceleration)))
+}
+
+// Moves the motor by `delta` tacho counts from its current position along
+// `profile`, like RunProfiledToAbsolutePosition.
+func (self Motor) RunProfiledToRelativePosition(delta int64, profile MotionProfile) error {
+	position, err := self.TryCurrentPosition()
+	if err != nil {
+		return err
+	}
+
+	return self.RunProfiledToAbsolutePosition(int64(position)+delta, profile)
+}
+
+// Reports whether a profiled move is still on its way, rather than holding
+// the target or cancelled.
+func (self Motor) IsProfiling() bool {
+	s := self.servo(false)
+	if s == nil {
+		return false
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.task != nil && s.profile != nil && time.Since(s.profile.began) < s.profile.duration()
+}
+
+// Tacho counts from the target a profiled move counts as arrived within.
+const profileTolerance = 2
+
+// Waits until the profiled move in progress is over and the motor is within
+// a couple of tacho counts of its target, as WaitFor does. Returns at once
+// if there is no move in progress, or once another command cancels it.
+func (self Motor) WaitForProfile(timeout time.Duration) error {
+	target, ok := self.HoldTarget()
+	if !ok {
+		return nil
+	}
+
+	return self.WaitFor(positionFD, func(s string) bool {
+		position, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+		if err != nil {
+			return false
+		}
+
+		// Released or replaced by another command in the meantime.
+		if _, holding := self.HoldTarget(); !holding {
+			return true
+		}
+
+		return !self.IsProfiling() && math.Abs(float64(position-target)) <= profileTolerance
+	}, timeout)
+}
@@ -0,0 +1,186 @@
+package Motor
+
+import (
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jermon/GoEV3/utilities"
+)
+
+const (
+	rampUpFD   = "ramp_up_sp"
+	rampDownFD = "ramp_down_sp"
+)
+
+// How often the software ramp steps the duty cycle.
+const rampInterval = 20 * time.Millisecond
+
+// Ramp times of a motor, and the duty cycle being ramped in software when
+// the driver can't ramp it.
+type ramp struct {
+	lock     sync.Mutex
+	up, down time.Duration
+	current  float64
+	target   float64
+	task     *utilities.PollTask
+	last     time.Time
+}
+
+var gRamps = make(map[string]*ramp)
+var gRampsLock = &sync.Mutex{}
+
+func (self Motor) ramp(create bool) *ramp {
+	gRampsLock.Lock()
+	defer gRampsLock.Unlock()
+
+	r, ok := gRamps[self.folder]
+	if !ok && create {
+		r = new(ramp)
+		gRamps[self.folder] = r
+	}
+
+	return r
+}
+
+// Makes the motor take `d` to speed up from standstill to full speed, and
+// proportionally less to lesser speeds, so that a heavy robot doesn't spin
+// its wheels or tip over as it sets off. Pass 0 to start at once, the default.
+//
+// The driver ramps the speed itself when speed regulation is on. With
+// regulation off, Run ramps the duty cycle in software instead.
+func (self Motor) SetRampUp(d time.Duration) {
+	r := self.ramp(true)
+	r.lock.Lock()
+	r.up = d
+	r.lock.Unlock()
+
+	utilities.WriteValue(self.folder, rampUpFD, d.Milliseconds())
+}
+
+// Makes the motor take `d` to slow down from full speed to standstill, as
+// SetRampUp does for speeding up. Stop still stops at once; run the motor at
+// speed 0 to ramp it down.
+func (self Motor) SetRampDown(d time.Duration) {
+	r := self.ramp(true)
+	r.lock.Lock()
+	r.down = d
+	r.lock.Unlock()
+
+	utilities.WriteValue(self.folder, rampDownFD, d.Milliseconds())
+}
+
+// Returns the ramp times set with SetRampUp and SetRampDown.
+func (self Motor) Ramps() (up time.Duration, down time.Duration) {
+	r := self.ramp(false)
+	if r == nil {
+		return 0, 0
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	return r.up, r.down
+}
+
+// Runs the motor at the duty cycle `speed` through the software ramp, if it
+// has ramp times. Reports whether it did. Speeds must be within range.
+func (self Motor) runRamped(speed int16) (bool, error) {
+	r := self.ramp(false)
+	if r == nil {
+		return false, nil
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.up == 0 && r.down == 0 {
+		return false, nil
+	}
+
+	if r.task == nil {
+		// Starting from whatever the motor was last told.
+		duty, _ := utilities.ReadValue[float64](self.folder, powerSetterFD)
+		if !self.isRunning() {
+			duty = 0
+		}
+		r.current = duty
+	}
+	r.target = float64(speed)
+
+	markStarted(self.folder)
+	if r.task == nil && r.current != r.target {
+		r.last = time.Now()
+		var task *utilities.PollTask
+		task = utilities.Poll(rampInterval, func() {
+			self.rampStep(r, &task)
+		})
+		r.task = task
+	}
+
+	if err := utilities.WriteValue(self.folder, powerSetterFD, int16(math.Round(r.current))); err != nil {
+		return true, err
+	}
+
+	return true, utilities.WriteValue(self.folder, runFD, string(CommandRunForever))
+}
+
+// Moves the duty cycle towards the target by the change the ramp times allow
+// since the last step.
+func (self Motor) rampStep(r *ramp, taskRef **utilities.PollTask) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	// Cancelled or replaced in the meantime.
+	task := *taskRef
+	if r.task != task {
+		return
+	}
+
+	now := time.Now()
+	elapsed := now.Sub(r.last)
+	r.last = now
+
+	// Speeding up moves away from 0, slowing down towards it.
+	d := r.down
+	if math.Abs(r.target) > math.Abs(r.current) && r.target*r.current >= 0 {
+		d = r.up
+	}
+
+	step := math.Inf(1)
+	if d > 0 {
+		step = 100 * elapsed.Seconds() / d.Seconds()
+	}
+
+	delta := r.target - r.current
+	if math.Abs(delta) <= step {
+		r.current = r.target
+		task.Cancel()
+		r.task = nil
+	} else {
+		r.current += math.Copysign(step, delta)
+	}
+
+	utilities.WriteValue(self.folder, powerSetterFD, int16(math.Round(r.current)))
+}
+
+// Stops the software ramp in progress, if any, leaving the duty cycle as it is.
+func (self Motor) cancelRamp() {
+	r := self.ramp(false)
+	if r == nil {
+		return
+	}
+
+	r.lock.Lock()
+	if r.task != nil {
+		r.task.Cancel()
+		r.task = nil
+	}
+	r.lock.Unlock()
+}
+
+func (self Motor) isRunning() bool {
+	state, _ := utilities.ReadValue[string](self.folder, stateFD)
+	return strings.Contains(state, "running")
+}
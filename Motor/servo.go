@@ -0,0 +1,169 @@
+package Motor
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jermon/GoEV3/Errors"
+	"github.com/jermon/GoEV3/utilities"
+)
+
+const rootServoPath = "/sys/class/servo-motor"
+
+const (
+	servoRateFD     = "rate_sp"
+	servoMinPulseFD = "min_pulse_sp"
+	servoMidPulseFD = "mid_pulse_sp"
+	servoMaxPulseFD = "max_pulse_sp"
+)
+
+// Commands of servo motors.
+const (
+	servoRun   = "run"
+	servoFloat = "float"
+)
+
+// An RC servo motor, e.g. connected through a servo controller, turned to
+// positions given as a percentage of its travel.
+type Servo struct {
+	port   OutPort
+	folder string
+	driver string
+}
+
+// Provides access to the servo at the given port. A missing servo is a fatal
+// error; use OpenServo to handle it.
+func FindServo(port OutPort, opts ...Option) *Servo {
+	s, err := OpenServo(port, opts...)
+	if err != nil {
+		utilities.Fatal(err)
+	}
+
+	return s
+}
+
+// Provides access to the servo at the given port. Returns an error matching
+// Errors.ErrDeviceNotFound if there is none, or Errors.ErrPortMismatch if it
+// doesn't have the driver required with WithRequiredDriver.
+func OpenServo(port OutPort, opts ...Option) (*Servo, error) {
+	s := new(Servo)
+	s.port = CanonicalOutPort(string(port))
+
+	folder, err := findFolderIn(rootServoPath, "servo", s.port, newOptions(opts))
+	if err != nil {
+		return nil, err
+	}
+
+	s.folder = folder
+	s.driver = utilities.ReadStringValue(folder, driverFD)
+
+	return s, nil
+}
+
+// Returns the output port the servo is connected to.
+func (self Servo) Port() OutPort {
+	return self.port
+}
+
+// Closes the servo's attribute files kept open, as Motor.Close does.
+func (self Servo) Close() {
+	utilities.ReleaseFiles(self.folder)
+}
+
+// Returns the name of the servo's driver.
+func (self Servo) Driver() string {
+	return self.driver
+}
+
+func (self Servo) outOfRange(detail string) error {
+	return &Errors.DeviceError{Kind: Errors.ErrOutOfRange, Device: "servo", Port: string(self.port), Detail: detail}
+}
+
+// Turns the servo to `percent` of its travel, from -100 at the minimum pulse
+// through 0 at the middle one to 100 at the maximum, and holds it there. A
+// position out of range is a fatal error; use TrySetPosition to handle it.
+func (self Servo) SetPosition(percent int) {
+	if err := self.TrySetPosition(percent); errors.Is(err, Errors.ErrOutOfRange) {
+		utilities.Fatal(err)
+	}
+}
+
+// Turns the servo like SetPosition, but returns an error matching
+// Errors.ErrOutOfRange instead of exiting if the position is out of range,
+// and the error of any write.
+func (self Servo) TrySetPosition(percent int) error {
+	if percent < -100 || percent > 100 {
+		return self.outOfRange(fmt.Sprintf("position %d, expected [-100, 100]", percent))
+	}
+
+	if err := utilities.WriteValue(self.folder, "position_sp", percent); err != nil {
+		return err
+	}
+
+	return utilities.WriteValue(self.folder, runFD, servoRun)
+}
+
+// Returns the position the servo was last turned to, in percent of its travel.
+func (self Servo) Position() int {
+	value, _ := utilities.ReadValue[int](self.folder, "position_sp")
+	return value
+}
+
+// Sets how long the servo takes to turn from one end of its travel to the
+// other, slowing it down. 0, the default, turns it as fast as it goes.
+func (self Servo) SetRate(d time.Duration) error {
+	return utilities.WriteValue(self.folder, servoRateFD, d.Milliseconds())
+}
+
+// Removes the signal from the servo, letting it turn freely.
+func (self Servo) Float() error {
+	return utilities.WriteValue(self.folder, runFD, servoFloat)
+}
+
+// Sets the pulse widths, in microseconds, of the minimum, middle and maximum
+// positions, to match the servo's travel. The driver accepts 300 to 700 for
+// the minimum, 1300 to 1700 for the middle and 2300 to 2700 for the maximum;
+// the defaults are 600, 1500 and 2400. Returns an error matching
+// Errors.ErrOutOfRange, without changing any, if one is out of range.
+func (self Servo) SetPulses(min int, mid int, max int) error {
+	checks := []struct {
+		name      string
+		value     int
+		low, high int
+	}{
+		{"minimum", min, 300, 700},
+		{"middle", mid, 1300, 1700},
+		{"maximum", max, 2300, 2700},
+	}
+	for _, c := range checks {
+		if c.value < c.low || c.value > c.high {
+			return self.outOfRange(fmt.Sprintf("%s pulse %d µs, expected [%d, %d]", c.name, c.value, c.low, c.high))
+		}
+	}
+
+	for _, w := range []struct {
+		attribute string
+		value     int
+	}{{servoMinPulseFD, min}, {servoMidPulseFD, mid}, {servoMaxPulseFD, max}} {
+		if err := utilities.WriteValue(self.folder, w.attribute, w.value); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Returns the pulse widths of the minimum, middle and maximum positions, in microseconds.
+func (self Servo) Pulses() (min int, mid int, max int) {
+	min, _ = utilities.ReadValue[int](self.folder, servoMinPulseFD)
+	mid, _ = utilities.ReadValue[int](self.folder, servoMidPulseFD)
+	max, _ = utilities.ReadValue[int](self.folder, servoMaxPulseFD)
+
+	return min, mid, max
+}
+
+// Reads the state of the servo; StateRunning while it holds a position.
+func (self Servo) State() State {
+	return ParseState(utilities.ReadStringValue(self.folder, stateFD))
+}
@@ -0,0 +1,158 @@
+package Motor
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/jermon/GoEV3/utilities"
+)
+
+const (
+	maxSpeedFD    = "max_speed"
+	countPerRotFD = "count_per_rot"
+)
+
+// Drivers of the LEGO motors.
+const (
+	DriverLarge  = "lego-ev3-l-motor"
+	DriverMedium = "lego-ev3-m-motor"
+	DriverNXT    = "lego-nxt-motor"
+)
+
+// Limits of a kind of motor, in tacho counts.
+type limits struct {
+	maxSpeed    int
+	countPerRot int
+}
+
+// Limits of the motors whose drivers may not report them, as older kernels'
+// NXT motor driver doesn't. Unknown drivers are assumed to be EV3 large motors.
+var driverLimits = map[string]limits{
+	DriverLarge:  {1050, 360},
+	DriverMedium: {1560, 360},
+	DriverNXT:    {1020, 360},
+}
+
+func (self Motor) limits() limits {
+	if l, ok := driverLimits[self.driver]; ok {
+		return l
+	}
+
+	return driverLimits[DriverLarge]
+}
+
+type speedUnit int
+
+const (
+	unitPercent speedUnit = iota
+	unitDegPerSec
+	unitRPM
+)
+
+// A motor speed in an explicit unit. Speeds are converted to whatever the motor
+// expects in its regulation mode, so the same value works in both:
+//
+//	m.RunAt(Motor.RPM(60))
+//	m.RunAt(Motor.Percent(-50))
+type Speed struct {
+	value float64
+	unit  speedUnit
+}
+
+// A speed as a percentage of the motor's maximum speed, in range [-100, 100].
+func Percent(percent float64) Speed {
+	return Speed{percent, unitPercent}
+}
+
+// A speed in degrees per second.
+func DegPerSec(degrees float64) Speed {
+	return Speed{degrees, unitDegPerSec}
+}
+
+// A speed in revolutions per minute.
+func RPM(rpm float64) Speed {
+	return Speed{rpm, unitRPM}
+}
+
+// Returns the speed multiplied by `factor`, in the same unit.
+func (self Speed) Scaled(factor float64) Speed {
+	return Speed{self.value * factor, self.unit}
+}
+
+func (self Speed) String() string {
+	switch self.unit {
+	case unitDegPerSec:
+		return fmt.Sprintf("%g°/s", self.value)
+	case unitRPM:
+		return fmt.Sprintf("%g rpm", self.value)
+	default:
+		return fmt.Sprintf("%g%%", self.value)
+	}
+}
+
+// Converts the speed to tacho counts per second for a motor with the given limits.
+func (self Speed) countsPerSecond(maxSpeed float64, countPerRot float64) float64 {
+	switch self.unit {
+	case unitDegPerSec:
+		return self.value * countPerRot / 360
+	case unitRPM:
+		return self.value * countPerRot / 60
+	default:
+		return self.value / 100 * maxSpeed
+	}
+}
+
+// Returns the maximum speed of the motor in tacho counts per second.
+func (self Motor) MaxSpeed() int {
+	value, err := utilities.ReadValue[int](self.folder, maxSpeedFD)
+	if err != nil || value <= 0 {
+		return self.limits().maxSpeed
+	}
+
+	return value
+}
+
+// Returns the number of tacho counts in one rotation of the motor.
+func (self Motor) CountPerRot() int {
+	value, err := utilities.ReadValue[int](self.folder, countPerRotFD)
+	if err != nil || value <= 0 {
+		return self.limits().countPerRot
+	}
+
+	return value
+}
+
+// Converts tacho counts to degrees.
+func (self Motor) CountsToDegrees(counts float64) float64 {
+	return counts * 360 / float64(self.CountPerRot())
+}
+
+// Converts degrees to tacho counts.
+func (self Motor) DegreesToCounts(degrees float64) float64 {
+	return degrees * float64(self.CountPerRot()) / 360
+}
+
+// Returns `speed` as the value Run expects in the motor's current regulation
+// mode: a duty cycle when regulation is off and a speed setpoint when it is on.
+// Speeds beyond the motor's maximum are clamped.
+func (self Motor) RunValue(speed Speed) int16 {
+	maxSpeed := float64(self.MaxSpeed())
+	counts := speed.countsPerSecond(maxSpeed, float64(self.CountPerRot()))
+	counts = math.Max(-maxSpeed, math.Min(maxSpeed, counts))
+
+	if isRegulated(self.folder) {
+		return int16(math.Round(counts))
+	}
+
+	return int16(math.Round(counts / maxSpeed * 100))
+}
+
+// Runs the motor at the given speed, whatever its regulation mode.
+func (self Motor) RunAt(speed Speed) {
+	self.Run(self.RunValue(speed))
+}
+
+// Reads the operating speed of the motor.
+func (self Motor) Speed() Speed {
+	return DegPerSec(self.CountsToDegrees(float64(self.CurrentSpeed())))
+}
@@ -0,0 +1,36 @@
+package Motor
+
+import (
+	"math"
+	"time"
+)
+
+// How often attachments check their motor while moving.
+const stallInterval = 10 * time.Millisecond
+
+// Waits, with the motor already started, until it stalls or `done` returns
+// true, and reports whether it stalled. The motor is stalled once it turned
+// less than `minMovement` degrees within `window`, leaving it a window to
+// speed up first.
+func waitUntilStall(m *Motor, minMovement float64, window time.Duration, done func() bool) bool {
+	ticker := time.NewTicker(stallInterval)
+	defer ticker.Stop()
+
+	start := time.Now()
+	refTime, ref := start, m.CurrentDegrees()
+
+	for range ticker.C {
+		if done() {
+			return false
+		}
+
+		now := time.Now()
+		if position := m.CurrentDegrees(); math.Abs(position-ref) >= minMovement {
+			refTime, ref = now, position
+		} else if now.Sub(refTime) >= window && now.Sub(start) >= 2*window {
+			return true
+		}
+	}
+
+	return false
+}
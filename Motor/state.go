@@ -0,0 +1,112 @@
+package Motor
+
+import (
+	"strings"
+	"time"
+
+	"github.com/jermon/GoEV3/utilities"
+)
+
+// Flags of a motor's state attribute.
+type State uint8
+
+const (
+	// The motor is powered.
+	StateRunning State = 1 << iota
+	// The motor is speeding up or slowing down, see SetRampUp.
+	StateRamping
+	// The motor is actively held at a position, as the Hold stop action does.
+	StateHolding
+	// The motor is running at its full power without reaching its speed setpoint.
+	StateOverloaded
+	// The motor is powered but not turning.
+	StateStalled
+)
+
+var stateNames = []struct {
+	flag State
+	name string
+}{
+	{StateRunning, "running"},
+	{StateRamping, "ramping"},
+	{StateHolding, "holding"},
+	{StateOverloaded, "overloaded"},
+	{StateStalled, "stalled"},
+}
+
+// Reports whether all of `flags` are set.
+func (self State) Has(flags State) bool {
+	return self&flags == flags
+}
+
+// Returns the flags as the driver writes them, e.g. "running stalled".
+func (self State) String() string {
+	var names []string
+	for _, s := range stateNames {
+		if self.Has(s.flag) {
+			names = append(names, s.name)
+		}
+	}
+
+	return strings.Join(names, " ")
+}
+
+// Parses a state attribute. Unknown flags are ignored.
+func ParseState(s string) State {
+	var state State
+	for _, field := range strings.Fields(s) {
+		for _, n := range stateNames {
+			if field == n.name {
+				state |= n.flag
+			}
+		}
+	}
+
+	return state
+}
+
+// Reads the state of the motor as flags:
+//
+//	if m.State().Has(Motor.StateStalled) {
+//		m.Stop()
+//	}
+func (self Motor) State() State {
+	return ParseState(self.GetState())
+}
+
+// Interval between the reads of the state callbacks, in milliseconds, unless
+// set with WithPollInterval.
+var STATE_POLLING_INTERVAL = 50
+
+// Registers a callback to be triggered when the motor stalls, e.g. to back a
+// gripper off when it jams. It is triggered again only once the motor has
+// turned freely in between. The listening can be stopped by sending any
+// boolean value to a `stop` channel.
+func (self Motor) OnStalled(stop <-chan bool, fn func()) {
+	self.onState(stop, StateStalled, fn)
+}
+
+// Registers a callback to be triggered when the motor is overloaded, as
+// OnStalled does for stalls.
+func (self Motor) OnOverloaded(stop <-chan bool, fn func()) {
+	self.onState(stop, StateOverloaded, fn)
+}
+
+// Calls `fn` in a goroutine of its own whenever `flag` gets set.
+func (self Motor) onState(stop <-chan bool, flag State, fn func()) {
+	set := self.State().Has(flag)
+
+	go utilities.PollUntil(stop, self.pollInterval(time.Millisecond*time.Duration(STATE_POLLING_INTERVAL)), func() {
+		state, err := utilities.ReadValue[string](self.folder, stateFD)
+		if err != nil {
+			return
+		}
+
+		now := ParseState(state).Has(flag)
+		if now && !set {
+			// Off the shared scheduler, which the callback may well block.
+			go fn()
+		}
+		set = now
+	})
+}
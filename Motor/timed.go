@@ -0,0 +1,57 @@
+package Motor
+
+import (
+	"errors"
+	"time"
+
+	"github.com/jermon/GoEV3/Errors"
+	"github.com/jermon/GoEV3/utilities"
+)
+
+const timeSetterFD = "time_sp"
+
+// Runs the motor at `speed`, as Run takes it, for `d`, after which the
+// driver stops it as the stop action says. Returns straight away; use
+// RunForDurationAndWait to wait for the motor to stop. A speed out of range
+// is a fatal error; use TryRunForDuration to handle it.
+func (self Motor) RunForDuration(speed int16, d time.Duration) {
+	if err := self.TryRunForDuration(speed, d); errors.Is(err, Errors.ErrOutOfRange) || errors.Is(err, Errors.ErrInvalidMode) {
+		utilities.Fatal(err)
+	}
+}
+
+// Runs the motor like RunForDuration, but returns an error matching
+// Errors.ErrOutOfRange if the speed is out of range, or Errors.ErrInvalidMode
+// if the driver doesn't support timed runs, instead of exiting, and the
+// error of any write.
+func (self Motor) TryRunForDuration(speed int16, d time.Duration) error {
+	if err := self.checkSupported(commandsFD, string(CommandRunTimed)); err != nil {
+		return err
+	}
+
+	if self.pastLimit(speed) {
+		self.Stop()
+		return nil
+	}
+
+	lock := commandLock(self.folder)
+	lock.Lock()
+	defer lock.Unlock()
+
+	if err := utilities.WriteValue(self.folder, timeSetterFD, d.Milliseconds()); err != nil {
+		return err
+	}
+
+	return self.start(speed, CommandRunTimed)
+}
+
+// Runs the motor like RunForDuration and waits until it stops. Returns
+// Stalled if it stopped turning before the time was up.
+func (self Motor) RunForDurationAndWait(speed int16, d time.Duration) (Completion, error) {
+	if err := self.TryRunForDuration(speed, d); err != nil {
+		return Completed, err
+	}
+
+	// Leaving the driver time to stop the motor.
+	return self.WaitForCompletion(d + completionWindow), nil
+}
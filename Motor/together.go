@@ -0,0 +1,61 @@
+package Motor
+
+import (
+	"fmt"
+
+	"github.com/jermon/GoEV3/Errors"
+	"github.com/jermon/GoEV3/utilities"
+)
+
+// Runs several motors at once, each at its speed as Run takes it, e.g. the
+// wheels of a robot. The speeds are all set before the motors are started
+// one right after the other, so that they start as close together as sysfs
+// allows. Returns an error matching Errors.ErrOutOfRange, before starting
+// any of them, if a speed is out of range, and the error of any write.
+func RunTogether(motors []*Motor, speeds []int16) error {
+	if len(motors) != len(speeds) {
+		return &Errors.DeviceError{
+			Kind:   Errors.ErrOutOfRange,
+			Device: "motor",
+			Detail: fmt.Sprintf("%d speeds for %d motors", len(speeds), len(motors)),
+		}
+	}
+
+	started := make([]bool, len(motors))
+	for i, m := range motors {
+		if m.pastLimit(speeds[i]) {
+			m.Stop()
+			continue
+		}
+
+		if err := m.setRunSpeed(speeds[i]); err != nil {
+			return err
+		}
+		started[i] = true
+	}
+
+	for i, m := range motors {
+		if !started[i] {
+			continue
+		}
+
+		if err := utilities.WriteValue(m.folder, runFD, string(CommandRunForever)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Stops several motors, one right after the other, each as its stop action
+// says. All are stopped even if one fails; the first error is returned.
+func StopTogether(motors ...*Motor) error {
+	var first error
+	for _, m := range motors {
+		if err := m.TryStop(); err != nil && first == nil {
+			first = err
+		}
+	}
+
+	return first
+}
@@ -0,0 +1,105 @@
+package Motor
+
+import (
+	"sync"
+)
+
+// Total travel of a motor, accumulated from the changes of its position.
+type travel struct {
+	lock  sync.Mutex
+	last  int32
+	total int64
+	// Whether `last` was read, so that changes can be counted from it.
+	known bool
+}
+
+var gTravel = make(map[string]*travel)
+var gTravelLock = &sync.Mutex{}
+
+func (self Motor) travel() *travel {
+	gTravelLock.Lock()
+	defer gTravelLock.Unlock()
+
+	t, ok := gTravel[self.folder]
+	if !ok {
+		t = new(travel)
+		if position, err := self.TryCurrentPosition(); err == nil {
+			t.last, t.known = position, true
+		}
+		gTravel[self.folder] = t
+	}
+
+	return t
+}
+
+// Adds the change of position since the last reading. A failed read is
+// skipped rather than taken as position 0, and the first good one after the
+// position was unknown only sets where changes are counted from. Must be
+// called with the travel's lock held.
+func (self Motor) accumulate(t *travel) {
+	position, err := self.TryCurrentPosition()
+	if err != nil {
+		return
+	}
+	if !t.known {
+		t.last, t.known = position, true
+		return
+	}
+
+	// Subtracting as 32-bit integers gives the right change across the wrap
+	// of the kernel's counter, as long as readings are less than 2^31 counts apart.
+	t.total += int64(position - t.last)
+	t.last = position
+}
+
+// Returns the total travel of the motor in tacho counts, counted in 64 bits
+// from the first call for the motor or the last ResetTravel. Unlike
+// CurrentPosition it goes on past the range of the kernel's 32-bit counter,
+// and InitializePosition doesn't change it, so that odometry can rely on it
+// however long the robot drives. Calls must come at least every 2^31 counts,
+// which takes weeks of driving.
+func (self Motor) Travel() int64 {
+	t := self.travel()
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	self.accumulate(t)
+	return t.total
+}
+
+// Returns the total travel of the motor in degrees; see Travel.
+func (self Motor) TravelDegrees() float64 {
+	return self.CountsToDegrees(float64(self.Travel()))
+}
+
+// Sets the total travel of the motor, in tacho counts, to `counts`.
+func (self Motor) ResetTravel(counts int64) {
+	t := self.travel()
+
+	t.lock.Lock()
+	position, err := self.TryCurrentPosition()
+	t.last, t.known = position, err == nil
+	t.total = counts
+	t.lock.Unlock()
+}
+
+// Counts the travel of a tracked motor up to a change of position set with
+// InitializePosition, and then continues from the new position.
+func (self Motor) setPosition(value int32, write func()) {
+	gTravelLock.Lock()
+	t := gTravel[self.folder]
+	gTravelLock.Unlock()
+
+	if t == nil {
+		write()
+		return
+	}
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	self.accumulate(t)
+	write()
+	t.last, t.known = value, true
+}
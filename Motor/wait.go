@@ -0,0 +1,54 @@
+package Motor
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jermon/GoEV3/utilities"
+)
+
+// Returns the interval between the reads of the waits.
+func (self Motor) waitInterval() time.Duration {
+	return self.pollInterval(time.Duration(utilities.WAIT_POLLING_INTERVAL) * time.Millisecond)
+}
+
+// Waits until `predicate` accepts the content of the motor's attribute
+// `attribute`, e.g. "position", reading it every
+// utilities.WAIT_POLLING_INTERVAL, or the interval set with WithPollInterval.
+// Returns an error matching Errors.ErrWaitTimeout if it doesn't within
+// `timeout`, 0 to wait for as long as it takes, and the error of a read;
+// see utilities.WaitFor.
+func (self Motor) WaitFor(attribute string, predicate func(string) bool, timeout time.Duration) error {
+	return utilities.WaitFor(self.folder, attribute, predicate, timeout, self.waitInterval())
+}
+
+// Waits until all of `flags` are set in the motor's state, e.g.
+// StateHolding, as WaitFor does.
+func (self Motor) WaitForState(flags State, timeout time.Duration) error {
+	return self.WaitFor(stateFD, func(s string) bool {
+		return ParseState(s).Has(flags)
+	}, timeout)
+}
+
+// Waits until the motor is neither running nor turning, e.g. once it has
+// coasted to a stop, as WaitFor does.
+func (self Motor) WaitUntilNotMoving(timeout time.Duration) error {
+	start := time.Now()
+	err := self.WaitFor(stateFD, func(s string) bool {
+		return !strings.Contains(s, "running")
+	}, timeout)
+	if err != nil {
+		return err
+	}
+
+	if timeout > 0 {
+		// At least one read, even if the state took the whole timeout.
+		timeout = max(timeout-time.Since(start), time.Nanosecond)
+	}
+
+	return self.WaitFor(speedGetterFD, func(s string) bool {
+		speed, err := strconv.Atoi(strings.TrimSpace(s))
+		return err == nil && speed == 0
+	}, timeout)
+}
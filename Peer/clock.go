@@ -0,0 +1,243 @@
+package Peer
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// Message types of the time synchronization handshake.
+const (
+	timeRequestType = "time.request"
+	timeReplyType   = "time.reply"
+)
+
+// Timestamps in nanoseconds since the Unix epoch: `Sent` by the requester,
+// `Received` and `Replied` by the time server.
+type timeStamps struct {
+	ID       uint64 `json:"id"`
+	Sent     int64  `json:"sent"`
+	Received int64  `json:"received,omitempty"`
+	Replied  int64  `json:"replied,omitempty"`
+}
+
+// A reply along with the local time it arrived at.
+type timeReply struct {
+	stamps   timeStamps
+	returned int64
+}
+
+var ErrNoTimeReply = errors.New("peer: no reply from the time server")
+
+// Anything messages can be handled on.
+type Registrar interface {
+	Handle(msgType string, fn Handler)
+}
+
+// A connection messages can be sent and handled on, such as a Conn or a Client.
+type Endpoint interface {
+	Registrar
+	Send(msgType string, data interface{}) error
+}
+
+// Makes a peer answer time requests, so that others can synchronize their
+// Clock with its time. Usually the server brick serves the time:
+//
+//	server, _ := Peer.Listen(":4747")
+//	Peer.ServeTime(server)
+func ServeTime(peer Registrar) {
+	peer.Handle(timeRequestType, func(c *Conn, m Message) {
+		received := time.Now().UnixNano()
+
+		var stamps timeStamps
+		if err := m.Decode(&stamps); err != nil {
+			return
+		}
+
+		stamps.Received = received
+		stamps.Replied = time.Now().UnixNano()
+		c.Send(timeReplyType, stamps)
+	})
+}
+
+// Estimates the time of a peer serving it with ServeTime, for scheduling
+// coordinated actions across bricks:
+//
+//	clock := Peer.NewClock(client)
+//	go clock.Run(stop, 10*time.Second)
+//	client.Handle("lift", func(c *Peer.Conn, m Peer.Message) {
+//		var at int64
+//		m.Decode(&at)
+//		clock.At(time.Unix(0, at), func() { arm.Run(50) })
+//	})
+//
+// while the server sends "lift" with time.Now().Add(500*time.Millisecond).UnixNano()
+// and schedules its own action for the same moment.
+type Clock struct {
+	peer Endpoint
+
+	lock        sync.Mutex
+	nextID      uint64
+	pending     map[uint64]chan timeReply
+	synced      bool
+	offset      time.Duration
+	uncertainty time.Duration
+}
+
+// Creates a clock synchronized with the time server at the other end of `peer`.
+// It follows the local time until synchronized.
+func NewClock(peer Endpoint) *Clock {
+	c := new(Clock)
+	c.peer = peer
+	c.pending = make(map[uint64]chan timeReply)
+
+	peer.Handle(timeReplyType, func(_ *Conn, m Message) {
+		returned := time.Now().UnixNano()
+
+		var stamps timeStamps
+		if err := m.Decode(&stamps); err != nil {
+			return
+		}
+
+		c.lock.Lock()
+		ch := c.pending[stamps.ID]
+		c.lock.Unlock()
+
+		if ch != nil {
+			ch <- timeReply{stamps, returned}
+		}
+	})
+
+	return c
+}
+
+// Performs one request and returns the offset of the server's clock and the
+// round-trip delay.
+func (self *Clock) sample(timeout time.Duration) (time.Duration, time.Duration, error) {
+	ch := make(chan timeReply, 1)
+
+	self.lock.Lock()
+	self.nextID++
+	id := self.nextID
+	self.pending[id] = ch
+	self.lock.Unlock()
+
+	defer func() {
+		self.lock.Lock()
+		delete(self.pending, id)
+		self.lock.Unlock()
+	}()
+
+	sent := time.Now().UnixNano()
+	if err := self.peer.Send(timeRequestType, timeStamps{ID: id, Sent: sent}); err != nil {
+		return 0, 0, err
+	}
+
+	select {
+	case reply := <-ch:
+		stamps := reply.stamps
+		// Round trip as measured locally, less the time spent in the server.
+		delay := (reply.returned - sent) - (stamps.Replied - stamps.Received)
+		offset := ((stamps.Received - sent) + (stamps.Replied - reply.returned)) / 2
+		if delay < 0 {
+			delay = 0
+		}
+
+		return time.Duration(offset), time.Duration(delay), nil
+	case <-time.After(timeout):
+		return 0, 0, ErrNoTimeReply
+	}
+}
+
+// Exchanges `samples` requests with the time server and adopts the offset
+// measured by the one with the shortest round trip, which is the most
+// accurate. Each request waits up to `timeout`.
+func (self *Clock) Sync(samples int, timeout time.Duration) error {
+	var best time.Duration = -1
+	var offset time.Duration
+	var err error
+
+	for i := 0; i < samples; i++ {
+		o, delay, e := self.sample(timeout)
+		if e != nil {
+			err = e
+			continue
+		}
+
+		if best < 0 || delay < best {
+			best, offset = delay, o
+		}
+	}
+
+	if best < 0 {
+		return err
+	}
+
+	self.lock.Lock()
+	self.synced = true
+	self.offset = offset
+	// The offset is exact if both directions took as long; it is off by at
+	// most half the round trip otherwise.
+	self.uncertainty = best / 2
+	self.lock.Unlock()
+
+	return nil
+}
+
+// Reports whether the clock was synchronized at least once.
+func (self *Clock) Synced() bool {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	return self.synced
+}
+
+// Returns how far the server's clock is ahead of the local one.
+func (self *Clock) Offset() time.Duration {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	return self.offset
+}
+
+// Returns the bound on the error of Offset, half the round trip of the best sample.
+func (self *Clock) Uncertainty() time.Duration {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	return self.uncertainty
+}
+
+// Returns the current time of the server.
+func (self *Clock) Now() time.Time {
+	return time.Now().Add(self.Offset())
+}
+
+// Converts a time of the server to local time.
+func (self *Clock) Local(t time.Time) time.Time {
+	return t.Add(-self.Offset())
+}
+
+// Calls `fn` in its own goroutine at the server's time `t`, right away if it
+// has passed. The returned timer can cancel the call.
+func (self *Clock) At(t time.Time, fn func()) *time.Timer {
+	return time.AfterFunc(time.Until(self.Local(t)), fn)
+}
+
+// Synchronizes right away and then every `interval`, so that clock drift
+// stays small, until a value is sent to `stop`. Failed attempts keep the
+// previous offset.
+func (self *Clock) Run(stop <-chan bool, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		self.Sync(8, time.Second)
+
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
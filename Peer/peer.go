@@ -0,0 +1,381 @@
+// Provides lightweight messaging between bricks (or a brick and a computer) over TCP.
+//
+// Messages are typed JSON values sent one per line. Handlers are registered per
+// message type and receive the connection the message arrived on, so they can
+// reply. A Client keeps reconnecting to its server in the background, which
+// suits robots whose Wi-Fi comes and goes.
+package Peer
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"net"
+	"sync"
+	"time"
+)
+
+// Default TCP port for brick-to-brick messaging.
+const DefaultPort = 4747
+
+var ErrNotConnected = errors.New("peer: not connected")
+
+// A typed message.
+type Message struct {
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data,omitempty"`
+}
+
+// Unmarshals the message data into `v`.
+func (self Message) Decode(v interface{}) error {
+	if len(self.Data) == 0 {
+		return nil
+	}
+
+	return json.Unmarshal(self.Data, v)
+}
+
+// Callback invoked for a received message.
+type Handler func(c *Conn, m Message)
+
+type handlers struct {
+	lock   sync.Mutex
+	byType map[string][]Handler
+}
+
+func (self *handlers) add(msgType string, fn Handler) {
+	self.lock.Lock()
+	if self.byType == nil {
+		self.byType = make(map[string][]Handler)
+	}
+	self.byType[msgType] = append(self.byType[msgType], fn)
+	self.lock.Unlock()
+}
+
+func (self *handlers) dispatch(c *Conn, m Message) {
+	self.lock.Lock()
+	fns := append([]Handler(nil), self.byType[m.Type]...)
+	self.lock.Unlock()
+
+	for _, fn := range fns {
+		fn(c, m)
+	}
+}
+
+// A messaging connection.
+type Conn struct {
+	conn    net.Conn
+	scanner *bufio.Scanner
+
+	writeLock sync.Mutex
+	handlers  *handlers
+}
+
+func newConn(conn net.Conn, h *handlers) *Conn {
+	c := new(Conn)
+	c.conn = conn
+	c.scanner = bufio.NewScanner(conn)
+	c.scanner.Buffer(make([]byte, 4096), 1<<20)
+	c.handlers = h
+
+	return c
+}
+
+// Connects to a peer listening at `addr` ("host:port").
+func Dial(addr string) (*Conn, error) {
+	conn, err := net.DialTimeout("tcp", addr, 5*time.Second)
+	if err != nil {
+		return nil, err
+	}
+
+	return newConn(conn, new(handlers)), nil
+}
+
+// Returns the address of the remote peer.
+func (self *Conn) RemoteAddr() net.Addr {
+	return self.conn.RemoteAddr()
+}
+
+// Closes the connection.
+func (self *Conn) Close() error {
+	return self.conn.Close()
+}
+
+// Sends a message of the given type. `data` is marshaled to JSON and may be nil.
+func (self *Conn) Send(msgType string, data interface{}) error {
+	m := Message{Type: msgType}
+
+	if data != nil {
+		raw, err := json.Marshal(data)
+		if err != nil {
+			return err
+		}
+		m.Data = raw
+	}
+
+	line, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+
+	self.writeLock.Lock()
+	defer self.writeLock.Unlock()
+
+	self.conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
+	_, err = self.conn.Write(append(line, '\n'))
+
+	return err
+}
+
+// Waits for the next message.
+func (self *Conn) Receive() (Message, error) {
+	for self.scanner.Scan() {
+		var m Message
+		if err := json.Unmarshal(self.scanner.Bytes(), &m); err != nil {
+			continue
+		}
+
+		return m, nil
+	}
+
+	if err := self.scanner.Err(); err != nil {
+		return Message{}, err
+	}
+
+	return Message{}, ErrNotConnected
+}
+
+// Registers a handler invoked by Serve for messages of the given type.
+func (self *Conn) Handle(msgType string, fn Handler) {
+	self.handlers.add(msgType, fn)
+}
+
+// Receives messages and dispatches them to the handlers until the connection fails.
+func (self *Conn) Serve() error {
+	for {
+		m, err := self.Receive()
+		if err != nil {
+			return err
+		}
+
+		self.handlers.dispatch(self, m)
+	}
+}
+
+// Accepts messaging connections and serves them with a shared set of handlers.
+type Server struct {
+	listener net.Listener
+	handlers handlers
+
+	lock    sync.Mutex
+	conns   map[*Conn]bool
+	onJoin  func(c *Conn)
+	onLeave func(c *Conn)
+}
+
+// Listens for peers on `addr`, e.g. ":4747".
+func Listen(addr string) (*Server, error) {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	s := new(Server)
+	s.listener = l
+	s.conns = make(map[*Conn]bool)
+
+	return s, nil
+}
+
+// Registers a handler for messages of the given type from any peer.
+func (self *Server) Handle(msgType string, fn Handler) {
+	self.handlers.add(msgType, fn)
+}
+
+// Registers callbacks invoked when a peer connects or disconnects.
+func (self *Server) OnConnection(join func(c *Conn), leave func(c *Conn)) {
+	self.lock.Lock()
+	self.onJoin, self.onLeave = join, leave
+	self.lock.Unlock()
+}
+
+// Sends a message to every connected peer.
+func (self *Server) Broadcast(msgType string, data interface{}) {
+	self.lock.Lock()
+	conns := make([]*Conn, 0, len(self.conns))
+	for c := range self.conns {
+		conns = append(conns, c)
+	}
+	self.lock.Unlock()
+
+	for _, c := range conns {
+		c.Send(msgType, data)
+	}
+}
+
+// Accepts and serves peers until the server is closed.
+func (self *Server) Serve() error {
+	for {
+		conn, err := self.listener.Accept()
+		if err != nil {
+			return err
+		}
+
+		c := newConn(conn, &self.handlers)
+
+		self.lock.Lock()
+		self.conns[c] = true
+		join, leave := self.onJoin, self.onLeave
+		self.lock.Unlock()
+
+		go func() {
+			if join != nil {
+				join(c)
+			}
+
+			c.Serve()
+			c.Close()
+
+			self.lock.Lock()
+			delete(self.conns, c)
+			self.lock.Unlock()
+
+			if leave != nil {
+				leave(c)
+			}
+		}()
+	}
+}
+
+// Stops accepting peers and disconnects the connected ones.
+func (self *Server) Close() error {
+	err := self.listener.Close()
+
+	self.lock.Lock()
+	for c := range self.conns {
+		c.Close()
+	}
+	self.lock.Unlock()
+
+	return err
+}
+
+// A connection to a server that is re-established automatically whenever it drops.
+type Client struct {
+	addr     string
+	handlers handlers
+
+	lock      sync.Mutex
+	conn      *Conn
+	closed    bool
+	onConnect func(c *Conn)
+	done      chan bool
+}
+
+// Starts connecting to the server at `addr` in the background. Failed attempts
+// are retried with exponential backoff of up to `maxBackoff`.
+func DialPersistent(addr string, maxBackoff time.Duration) *Client {
+	c := new(Client)
+	c.addr = addr
+	c.done = make(chan bool)
+
+	go c.run(maxBackoff)
+
+	return c
+}
+
+// Registers a handler for messages of the given type.
+func (self *Client) Handle(msgType string, fn Handler) {
+	self.handlers.add(msgType, fn)
+}
+
+// Registers a callback invoked after every successful (re)connection, e.g. to re-announce state.
+func (self *Client) OnConnect(fn func(c *Conn)) {
+	self.lock.Lock()
+	self.onConnect = fn
+	self.lock.Unlock()
+}
+
+// Reports whether the client is currently connected.
+func (self *Client) Connected() bool {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	return self.conn != nil
+}
+
+// Sends a message, failing with ErrNotConnected while the connection is down.
+func (self *Client) Send(msgType string, data interface{}) error {
+	self.lock.Lock()
+	conn := self.conn
+	self.lock.Unlock()
+
+	if conn == nil {
+		return ErrNotConnected
+	}
+
+	return conn.Send(msgType, data)
+}
+
+// Disconnects and stops reconnecting.
+func (self *Client) Close() {
+	self.lock.Lock()
+	if self.closed {
+		self.lock.Unlock()
+		return
+	}
+	self.closed = true
+	conn := self.conn
+	self.lock.Unlock()
+
+	close(self.done)
+
+	if conn != nil {
+		conn.Close()
+	}
+}
+
+func (self *Client) run(maxBackoff time.Duration) {
+	backoff := 100 * time.Millisecond
+
+	for {
+		conn, err := Dial(self.addr)
+
+		if err == nil {
+			backoff = 100 * time.Millisecond
+			conn.handlers = &self.handlers
+
+			self.lock.Lock()
+			if self.closed {
+				self.lock.Unlock()
+				conn.Close()
+				return
+			}
+			self.conn = conn
+			onConnect := self.onConnect
+			self.lock.Unlock()
+
+			if onConnect != nil {
+				onConnect(conn)
+			}
+
+			conn.Serve()
+			conn.Close()
+
+			self.lock.Lock()
+			self.conn = nil
+			self.lock.Unlock()
+		}
+
+		select {
+		case <-self.done:
+			return
+		case <-time.After(backoff):
+		}
+
+		backoff *= 2
+		if backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
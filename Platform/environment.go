@@ -0,0 +1,156 @@
+package Platform
+
+import (
+	"errors"
+	"os"
+	"path"
+	"runtime"
+
+	"github.com/jermon/GoEV3/utilities"
+)
+
+const (
+	legoPortPath    = "/sys/class/lego-port"
+	motorClassPath  = "/sys/class/tacho-motor"
+	sensorClassPath = "/sys/class/lego-sensor"
+)
+
+// Describes why the program can't talk to the devices, and how to fix it.
+type Diagnostic struct {
+	// What is wrong, e.g. "no permission to write motor attributes".
+	Problem string
+	// The file, directory or system the problem was found with.
+	Missing string
+	// Suggested remediation.
+	Remedy string
+	// The underlying error, if any.
+	Err error
+}
+
+func (self *Diagnostic) Error() string {
+	s := self.Problem
+	if self.Missing != "" {
+		s += " (" + self.Missing + ")"
+	}
+	if self.Err != nil {
+		s += ": " + self.Err.Error()
+	}
+
+	return s + ". " + self.Remedy
+}
+
+func (self *Diagnostic) Unwrap() error {
+	return self.Err
+}
+
+func notLinux() *Diagnostic {
+	return &Diagnostic{
+		Problem: "not running on Linux",
+		Missing: runtime.GOOS,
+		Remedy:  "Cross-compile with GOOS=linux GOARCH=arm and run the program on the brick, or use the Simulation package",
+	}
+}
+
+func notEV3Dev() *Diagnostic {
+	return &Diagnostic{
+		Problem: "the ev3dev drivers are not loaded",
+		Missing: legoPortPath,
+		Remedy:  "Boot the brick from an ev3dev SD card, see http://www.ev3dev.org/docs/getting-started/",
+	}
+}
+
+func noPermission(name string, err error) *Diagnostic {
+	return &Diagnostic{
+		Problem: "no permission to access device attributes",
+		Missing: name,
+		Remedy:  "Run the program as the robot user, which is in the ev3dev group, or with sudo",
+		Err:     err,
+	}
+}
+
+// Checks that the program runs on ev3dev with access to its devices, and
+// returns a diagnostic for every problem found. Programs can call it at
+// startup to report problems clearly before any device is used:
+//
+//	if problems := Platform.CheckEnvironment(); len(problems) > 0 {
+//		for _, p := range problems {
+//			fmt.Println(p)
+//		}
+//		os.Exit(1)
+//	}
+//
+// Simulated and recorded backends are always ready, so no problems are returned for them.
+func CheckEnvironment() []*Diagnostic {
+	if !utilities.UsesSysfs() {
+		return nil
+	}
+
+	if runtime.GOOS != "linux" {
+		return []*Diagnostic{notLinux()}
+	}
+
+	if !utilities.Exists(legoPortPath) {
+		return []*Diagnostic{notEV3Dev()}
+	}
+
+	var problems []*Diagnostic
+
+	check := func(class string, attribute string) {
+		for _, name := range utilities.ListDir(class) {
+			filename := path.Join(class, name, attribute)
+			if err := utilities.CheckAccess(filename, true); errors.Is(err, os.ErrPermission) {
+				problems = append(problems, noPermission(filename, err))
+				return
+			}
+		}
+	}
+
+	check(motorClassPath, "command")
+	check(sensorClassPath, "mode")
+
+	return problems
+}
+
+// Explains an error returned by device I/O, e.g. a *utilities.IOError.
+// Returns nil if the error isn't caused by the environment.
+func Diagnose(err error) *Diagnostic {
+	var d *Diagnostic
+	if errors.As(err, &d) {
+		return d
+	}
+
+	var ioErr *utilities.IOError
+	name := ""
+	if errors.As(err, &ioErr) {
+		name = ioErr.Path
+	}
+
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, os.ErrPermission):
+		return noPermission(name, err)
+	case errors.Is(err, os.ErrNotExist) && runtime.GOOS != "linux":
+		return notLinux()
+	case errors.Is(err, os.ErrNotExist) && !utilities.Exists(legoPortPath):
+		return notEV3Dev()
+	case errors.Is(err, os.ErrNotExist):
+		return &Diagnostic{
+			Problem: "the device was disconnected",
+			Missing: name,
+			Remedy:  "Check the cable and find the device again",
+			Err:     err,
+		}
+	}
+
+	return nil
+}
+
+// Returns the first problem reported by CheckEnvironment, or nil if there is none.
+func EnvironmentError() error {
+	if problems := CheckEnvironment(); len(problems) > 0 {
+		return problems[0]
+	}
+
+	return nil
+}
@@ -0,0 +1,248 @@
+// Provides detection of the board GoEV3 runs on and the mapping of EV3 port
+// names to the addresses each board reports.
+//
+// Programs always refer to ports by their EV3 names (outA..outD, in1..in4);
+// the Motor and Sensors packages translate them with the current platform, so
+// the same program runs unchanged on any supported board.
+package Platform
+
+import (
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/jermon/GoEV3/utilities"
+)
+
+// Describes a board supported by ev3dev.
+type Platform struct {
+	// Short identifier, e.g. "ev3" or "pistorms".
+	Name string
+	// Text contained in BOARD_INFO_MODEL for this board.
+	Model string
+	// Maps EV3 port names ("outA", "in1") to the addresses reported by the board.
+	Ports map[string]string
+	// Maps the address prefixes some ev3dev releases report to those used in
+	// Ports, e.g. "ttyAMA0" to "serial0-0".
+	Aliases map[string]string
+	// Input event device of the brick buttons, empty if the board has none.
+	ButtonDevice string
+	// Whether the board has the EV3 status LEDs.
+	LEDs bool
+}
+
+// The LEGO MINDSTORMS EV3 brick.
+var EV3 = &Platform{
+	Name:  "ev3",
+	Model: "LEGO MINDSTORMS EV3",
+	Ports: map[string]string{},
+
+	ButtonDevice: "/dev/input/by-path/platform-gpio-keys.0-event",
+	LEDs:         true,
+}
+
+// The mindsensors.com PiStorms, a Raspberry Pi shield with two banks of two motor and two sensor ports.
+var PiStorms = &Platform{
+	Name:  "pistorms",
+	Model: "PiStorms",
+	Ports: map[string]string{
+		"outA": "pistorms:BAM1",
+		"outB": "pistorms:BAM2",
+		"outC": "pistorms:BBM1",
+		"outD": "pistorms:BBM2",
+		"in1":  "pistorms:BAS1",
+		"in2":  "pistorms:BAS2",
+		"in3":  "pistorms:BBS1",
+		"in4":  "pistorms:BBS2",
+	},
+}
+
+// The FatcatLab EVB, a BeagleBone cape with the EV3 port layout. It has no status LEDs.
+var EVB = &Platform{
+	Name:  "evb",
+	Model: "FatcatLab EVB",
+	Ports: map[string]string{
+		"outA": "evb-ports:outA",
+		"outB": "evb-ports:outB",
+		"outC": "evb-ports:outC",
+		"outD": "evb-ports:outD",
+		"in1":  "evb-ports:in1",
+		"in2":  "evb-ports:in2",
+		"in3":  "evb-ports:in3",
+		"in4":  "evb-ports:in4",
+	},
+
+	ButtonDevice: "/dev/input/by-path/platform-evb-buttons-event",
+}
+
+// The Dexter Industries BrickPi and BrickPi+, Raspberry Pi shields with four
+// motor and four sensor ports, reached over the serial port. Older ev3dev
+// images name it ttyAMA0, newer ones serial0-0. They have no buttons or status LEDs.
+var BrickPi = &Platform{
+	Name:  "brickpi",
+	Model: "BrickPi",
+	Ports: map[string]string{
+		"outA": "serial0-0:MA",
+		"outB": "serial0-0:MB",
+		"outC": "serial0-0:MC",
+		"outD": "serial0-0:MD",
+		"in1":  "serial0-0:S1",
+		"in2":  "serial0-0:S2",
+		"in3":  "serial0-0:S3",
+		"in4":  "serial0-0:S4",
+	},
+	Aliases: map[string]string{
+		"ttyAMA0": "serial0-0",
+	},
+}
+
+// The Dexter Industries BrickPi3, a Raspberry Pi shield with four motor and
+// four sensor ports, reached over SPI. It has no buttons or status LEDs.
+var BrickPi3 = &Platform{
+	Name:  "brickpi3",
+	Model: "BrickPi3",
+	Ports: map[string]string{
+		"outA": "spi0.1:MA",
+		"outB": "spi0.1:MB",
+		"outC": "spi0.1:MC",
+		"outD": "spi0.1:MD",
+		"in1":  "spi0.1:S1",
+		"in2":  "spi0.1:S2",
+		"in3":  "spi0.1:S3",
+		"in4":  "spi0.1:S4",
+	},
+}
+
+// Platforms recognized by Detect, in the order they are tried. BrickPi3 comes
+// before BrickPi, whose model name it contains.
+var Known = []*Platform{EV3, PiStorms, EVB, BrickPi3, BrickPi}
+
+const boardInfoPath = "/sys/class/board-info"
+
+var gCurrent *Platform
+var gCurrentLock sync.Mutex
+
+// Detects the platform from the board information published by ev3dev, or
+// else from the addresses of the ports, as boards without an ID EEPROM, such
+// as the BrickPi, don't publish any. Returns EV3 when no known board is found.
+func Detect() *Platform {
+	for _, board := range utilities.ListDir(boardInfoPath) {
+		model := boardModel(path.Join(boardInfoPath, board))
+		if model == "" {
+			continue
+		}
+
+		for _, p := range Known {
+			if strings.Contains(model, p.Model) {
+				return p
+			}
+		}
+	}
+
+	for _, port := range utilities.ListDir(legoPortPath) {
+		address := utilities.ReadStringValue(path.Join(legoPortPath, port), "address")
+		for _, p := range Known {
+			if p.owns(address) {
+				return p
+			}
+		}
+	}
+
+	return EV3
+}
+
+func boardModel(folder string) string {
+	for _, line := range strings.Split(utilities.ReadStringValue(folder, "uevent"), "\n") {
+		if strings.HasPrefix(line, "BOARD_INFO_MODEL=") {
+			return strings.TrimPrefix(line, "BOARD_INFO_MODEL=")
+		}
+	}
+
+	return ""
+}
+
+// Returns the platform GoEV3 runs on, detecting it on first use.
+func Current() *Platform {
+	gCurrentLock.Lock()
+	defer gCurrentLock.Unlock()
+
+	if gCurrent == nil {
+		gCurrent = Detect()
+	}
+
+	return gCurrent
+}
+
+// Overrides the detected platform. Passing nil detects it again on next use.
+func Set(p *Platform) {
+	gCurrentLock.Lock()
+	gCurrent = p
+	gCurrentLock.Unlock()
+}
+
+// Returns the address the platform reports for the given EV3 port name.
+func (self *Platform) Address(port string) string {
+	if address, ok := self.Ports[port]; ok {
+		return address
+	}
+
+	return port
+}
+
+// Returns the EV3 port name for an address reported by the platform. Both the
+// bare addresses of older ev3dev images ("in1") and the prefixed ones of newer
+// images ("ev3-ports:in1", "ev3-ports:in1:i2c1") are recognized, as are the
+// board's own addresses under any of its aliases ("ttyAMA0:S1" on a BrickPi).
+// Returns the address itself if it doesn't correspond to any EV3 port.
+func (self *Platform) PortName(address string) string {
+	if port, ok := self.lookup(address); ok {
+		return port
+	}
+
+	for _, part := range strings.Split(address, ":") {
+		if isPortName(part) {
+			return part
+		}
+	}
+
+	return address
+}
+
+// Reports whether `address` refers to the same port as `port`. Either may be
+// given in any of the forms accepted by PortName.
+func (self *Platform) Matches(port string, address string) bool {
+	return self.PortName(address) == self.PortName(port)
+}
+
+// Returns the EV3 port name the board maps `address` to.
+func (self *Platform) lookup(address string) (string, bool) {
+	for from, to := range self.Aliases {
+		if address == from || strings.HasPrefix(address, from+":") {
+			address = to + strings.TrimPrefix(address, from)
+			break
+		}
+	}
+
+	for port, a := range self.Ports {
+		if a == address || strings.HasPrefix(address, a+":") {
+			return port, true
+		}
+	}
+
+	return "", false
+}
+
+// Reports whether `address` is one of the board's own port addresses.
+func (self *Platform) owns(address string) bool {
+	_, ok := self.lookup(address)
+	return ok
+}
+
+func isPortName(name string) bool {
+	switch name {
+	case "outA", "outB", "outC", "outD", "in1", "in2", "in3", "in4":
+		return true
+	}
+
+	return false
+}
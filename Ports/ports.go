@@ -0,0 +1,191 @@
+// Provides APIs for configuring the brick's input and output ports, through
+// the lego-port class.
+//
+// Sensors the ports can't detect on their own, e.g. most I2C sensors, need the
+// port put into the right mode, and sometimes told the driver to load, before
+// their device appears:
+//
+//	port := Ports.FindPort("in2")
+//	port.SetMode(Ports.ModeNXTI2C)
+//	port.SetDevice("ht-nxt-compass 0x01")
+//	compass, err := port.WaitForSensor("ht-nxt-compass", 5*time.Second)
+package Ports
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/jermon/GoEV3/Errors"
+	"github.com/jermon/GoEV3/Platform"
+	"github.com/jermon/GoEV3/Sensors"
+	"github.com/jermon/GoEV3/utilities"
+)
+
+const basePortPath = "/sys/class/lego-port"
+
+// Modes of the ports, as listed in their modes attribute.
+type Mode string
+
+const (
+	// Detects the device connected, the default for input and output ports.
+	ModeAuto Mode = "auto"
+	// An EV3 analog sensor, e.g. the touch sensor.
+	ModeEV3Analog = "ev3-analog"
+	// An EV3 UART sensor, e.g. the color sensor.
+	ModeEV3UART = "ev3-uart"
+	// An NXT analog sensor, e.g. the NXT light sensor.
+	ModeNXTAnalog = "nxt-analog"
+	// An NXT I2C sensor, e.g. the NXT ultrasonic sensor or most third-party sensors.
+	ModeNXTI2C = "nxt-i2c"
+	// A UART device other than an EV3 sensor.
+	ModeOtherUART = "other-uart"
+	// An I2C device other than an NXT sensor.
+	ModeOtherI2C = "other-i2c"
+	// A LEGO motor with a tachometer.
+	ModeTachoMotor = "tacho-motor"
+	// A motor without a tachometer.
+	ModeDCMotor = "dc-motor"
+	// An LED, on an output port.
+	ModeLED = "led"
+	// A hobby servo, on an output port.
+	ModeRawServo = "raw"
+)
+
+// A port of the lego-port class.
+type Port struct {
+	path    string
+	address string
+}
+
+// Returns all the ports.
+func ListPorts() []*Port {
+	var ports []*Port
+
+	for _, name := range utilities.ListDir(basePortPath) {
+		if strings.HasPrefix(name, "port") {
+			ports = append(ports, newPort(path.Join(basePortPath, name)))
+		}
+	}
+
+	return ports
+}
+
+func newPort(folder string) *Port {
+	p := new(Port)
+	p.path = folder
+	p.address = utilities.ReadStringValue(folder, "address")
+
+	return p
+}
+
+// Provides access to the port with the given name, e.g. "in1" or "outA", or
+// address. A missing port is a fatal error; use OpenPort to handle it.
+func FindPort(name string) *Port {
+	p, err := OpenPort(name)
+	if err != nil {
+		utilities.Fatal(err)
+	}
+
+	return p
+}
+
+// Provides access to the port like FindPort, but returns an error matching
+// Errors.ErrDeviceNotFound instead of exiting if there is none.
+func OpenPort(name string) (*Port, error) {
+	for _, p := range ListPorts() {
+		if p.address == name || Platform.Current().Matches(name, p.address) {
+			return p, nil
+		}
+	}
+
+	return nil, &Errors.DeviceError{
+		Kind:   Errors.ErrDeviceNotFound,
+		Device: "port",
+		Port:   name,
+		Cause:  Platform.EnvironmentError(),
+	}
+}
+
+// Returns the address of the port, e.g. "ev3-ports:in1".
+func (self *Port) Address() string {
+	return self.address
+}
+
+// Returns the EV3 name of the port, e.g. "in1".
+func (self *Port) Name() string {
+	return Platform.Current().PortName(self.address)
+}
+
+// Returns the name of the port's driver, e.g. "legoev3-input-port".
+func (self *Port) Driver() string {
+	return utilities.ReadStringValue(self.path, "driver_name")
+}
+
+// Returns the modes the port has.
+func (self *Port) Modes() []Mode {
+	var modes []Mode
+	for _, m := range strings.Fields(utilities.ReadStringValue(self.path, "modes")) {
+		modes = append(modes, Mode(m))
+	}
+
+	return modes
+}
+
+// Returns the mode the port is in.
+func (self *Port) Mode() Mode {
+	return Mode(utilities.ReadStringValue(self.path, "mode"))
+}
+
+// Puts the port into `mode`, unloading the device connected. Returns an error
+// matching Errors.ErrInvalidMode if the port doesn't have the mode, and the
+// error of the write.
+func (self *Port) SetMode(mode Mode) error {
+	modes := self.Modes()
+	supported := len(modes) == 0
+	for _, m := range modes {
+		supported = supported || m == mode
+	}
+
+	if !supported {
+		names := make([]string, len(modes))
+		for i, m := range modes {
+			names[i] = string(m)
+		}
+
+		return &Errors.DeviceError{
+			Kind:   Errors.ErrInvalidMode,
+			Device: "port",
+			Port:   self.Name(),
+			Detail: fmt.Sprintf("%q, expected one of %s", mode, strings.Join(names, ", ")),
+		}
+	}
+
+	return utilities.WriteValue(self.path, "mode", string(mode))
+}
+
+// Loads the driver of the device connected, for modes where it can't be
+// detected, e.g. "lego-nxt-us" in ModeNXTI2C, or "ht-nxt-compass 0x01" with
+// the I2C address of the sensor.
+func (self *Port) SetDevice(driver string) error {
+	return utilities.WriteValue(self.path, "set_device", driver)
+}
+
+// Returns the status of the port, e.g. "no-device", or the mode in use.
+func (self *Port) Status() string {
+	return utilities.ReadStringValue(self.path, "status")
+}
+
+// Waits up to `timeout` for the sensor with the given driver to appear on the
+// port, e.g. after SetMode and SetDevice, and provides access to it. An empty
+// driver accepts any sensor. Returns an error matching Errors.ErrDeviceNotFound
+// or Errors.ErrPortMismatch if it doesn't appear in time.
+func (self *Port) WaitForSensor(driver string, timeout time.Duration) (*Sensors.GenericSensor, error) {
+	opts := []Sensors.Option{Sensors.WithTimeout(timeout)}
+	if driver != "" {
+		opts = append(opts, Sensors.WithRequiredDriver(driver))
+	}
+
+	return Sensors.OpenSensor(Sensors.InPort(self.Name()), opts...)
+}
@@ -0,0 +1,131 @@
+// Provides the state of the brick's battery, from the power supply class.
+package Power
+
+import (
+	"math"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/jermon/GoEV3/utilities"
+)
+
+const (
+	powerSupplyClass = "/sys/class/power_supply"
+	// The EV3's battery, used if no supply reports being one.
+	defaultBattery = "legoev3-battery"
+)
+
+// Design voltages of six AA cells, used if the battery doesn't report its own.
+const (
+	defaultMaxVoltage = 9.0
+	defaultMinVoltage = 6.0
+)
+
+// Time between battery readings when watching for a low battery, in milliseconds.
+var BATTERY_POLLING_INTERVAL = 1000
+
+// Consecutive readings below the threshold for the battery to count as low,
+// so that the voltage dropping while motors start doesn't.
+const lowBatteryReadings = 3
+
+var gBattery string
+var gBatteryLock = &sync.Mutex{}
+
+// Returns the folder of the battery: the first power supply of type Battery.
+func battery() string {
+	gBatteryLock.Lock()
+	defer gBatteryLock.Unlock()
+
+	if gBattery == "" {
+		gBattery = path.Join(powerSupplyClass, defaultBattery)
+		for _, name := range utilities.ListDir(powerSupplyClass) {
+			folder := path.Join(powerSupplyClass, name)
+			if utilities.ReadStringValue(folder, "type") == "Battery" {
+				gBattery = folder
+				break
+			}
+		}
+	}
+
+	return gBattery
+}
+
+// Reads an attribute in micro-units, returning 0 if it can't be read.
+func readMicroUnits(attribute string) float64 {
+	value, _ := utilities.ReadFloatValue(battery(), attribute, 1e-6)
+	return value
+}
+
+// Returns the battery voltage, in volts.
+func Voltage() float64 {
+	return readMicroUnits("voltage_now")
+}
+
+// Returns the current drawn from the battery, in amperes.
+func Current() float64 {
+	return readMicroUnits("current_now")
+}
+
+// Returns the voltage of a full battery, in volts.
+func MaxVoltage() float64 {
+	if v := readMicroUnits("voltage_max_design"); v > 0 {
+		return v
+	}
+
+	return defaultMaxVoltage
+}
+
+// Returns the voltage of an empty battery, in volts.
+func MinVoltage() float64 {
+	if v := readMicroUnits("voltage_min_design"); v > 0 {
+		return v
+	}
+
+	return defaultMinVoltage
+}
+
+// Estimates the charge left in range [0, 100] from where the voltage lies
+// between MinVoltage and MaxVoltage. The voltage drops under load, so the
+// estimate is best read while the motors are stopped.
+func PercentEstimate() float64 {
+	min, max := MinVoltage(), MaxVoltage()
+	if max <= min {
+		return 0
+	}
+
+	return math.Max(0, math.Min(100, (Voltage()-min)/(max-min)*100))
+}
+
+// Registers a callback to be triggered once when the battery voltage stays
+// below `threshold` volts, e.g. to park the robot before it dies:
+//
+//	Power.OnLowBattery(stop, 6.5, func() {
+//		base.Stop()
+//		Sound.SOS.Play()
+//	})
+//
+// The battery is read every BATTERY_POLLING_INTERVAL. The watching can be
+// stopped by sending any boolean value to a `stop` channel.
+func OnLowBattery(stop <-chan bool, threshold float64, fn func()) {
+	low := 0
+	done := false
+
+	go utilities.PollUntil(stop, time.Millisecond*time.Duration(BATTERY_POLLING_INTERVAL), func() {
+		if done {
+			return
+		}
+
+		if v := Voltage(); v > 0 && v < threshold {
+			low++
+		} else {
+			low = 0
+		}
+
+		if low >= lowBatteryReadings {
+			done = true
+			// Off the shared scheduler, which the callback may well block.
+			go fn()
+		}
+	})
+}
@@ -0,0 +1,187 @@
+package PoweredUp
+
+import (
+	"encoding/binary"
+)
+
+// What a motor does when a move completes.
+type EndState byte
+
+const (
+	Float EndState = 0
+	Hold  EndState = 126
+	Brake EndState = 127
+)
+
+// Modes of tacho motors.
+const (
+	motorModeSpeed    = 1
+	motorModePosition = 2
+)
+
+// Output subcommands.
+const (
+	cmdStartSpeed      = 0x07
+	cmdSpeedForTime    = 0x09
+	cmdSpeedForDegrees = 0x0B
+	cmdGotoPosition    = 0x0D
+	cmdWriteDirectMode = 0x51
+)
+
+// Parameters of the speed commands: full power is allowed, without acceleration profiles.
+const (
+	maxPower            = 100
+	useAccelerationNone = 0x00
+)
+
+// A motor attached to a hub port.
+type Motor struct {
+	hub  *Hub
+	port uint8
+}
+
+// Returns the motor attached to the given port.
+func (self *Hub) Motor(port uint8) *Motor {
+	return &Motor{self, port}
+}
+
+// Returns the port the motor is attached to.
+func (self *Motor) Port() uint8 {
+	return self.port
+}
+
+func clampSpeed(speed int) byte {
+	if speed > 100 {
+		speed = 100
+	} else if speed < -100 {
+		speed = -100
+	}
+
+	return byte(int8(speed))
+}
+
+// Runs the motor at a power in range [-100, 100], without speed regulation.
+// Works with motors without encoders, such as train motors.
+func (self *Motor) SetPower(power int) error {
+	return self.hub.output(self.port, false, cmdWriteDirectMode, 0x00, clampSpeed(power))
+}
+
+// Runs the motor at a regulated speed in range [-100, 100] until another command is given.
+func (self *Motor) Run(speed int) error {
+	return self.hub.output(self.port, false, cmdStartSpeed, clampSpeed(speed), maxPower, useAccelerationNone)
+}
+
+// Stops the motor.
+func (self *Motor) Stop() error {
+	return self.hub.output(self.port, false, cmdStartSpeed, 0, maxPower, useAccelerationNone)
+}
+
+// Runs the motor for the given time in milliseconds. `wait` blocks until the move completes.
+func (self *Motor) RunForTime(milliseconds uint16, speed int, end EndState, wait bool) error {
+	params := []byte{0, 0, clampSpeed(speed), maxPower, byte(end), useAccelerationNone}
+	binary.LittleEndian.PutUint16(params[0:2], milliseconds)
+
+	return self.hub.output(self.port, wait, cmdSpeedForTime, params...)
+}
+
+// Turns the motor by the given number of degrees. A negative speed reverses
+// the direction. `wait` blocks until the move completes.
+func (self *Motor) RunForDegrees(degrees int32, speed int, end EndState, wait bool) error {
+	if degrees < 0 {
+		degrees, speed = -degrees, -speed
+	}
+
+	params := []byte{0, 0, 0, 0, clampSpeed(speed), maxPower, byte(end), useAccelerationNone}
+	binary.LittleEndian.PutUint32(params[0:4], uint32(degrees))
+
+	return self.hub.output(self.port, wait, cmdSpeedForDegrees, params...)
+}
+
+// Turns the motor to an absolute position in degrees. `wait` blocks until the move completes.
+func (self *Motor) RunToPosition(position int32, speed int, end EndState, wait bool) error {
+	if speed < 0 {
+		speed = -speed
+	}
+
+	params := []byte{0, 0, 0, 0, clampSpeed(speed), maxPower, byte(end), useAccelerationNone}
+	binary.LittleEndian.PutUint32(params[0:4], uint32(position))
+
+	return self.hub.output(self.port, wait, cmdGotoPosition, params...)
+}
+
+// Makes the hub report the motor's position, read with Position.
+func (self *Motor) TrackPosition() error {
+	return self.hub.Subscribe(self.port, motorModePosition, 1)
+}
+
+// Returns the last reported position in degrees. Call TrackPosition first.
+func (self *Motor) Position() int32 {
+	value := self.hub.Value(self.port)
+	if len(value) < 4 {
+		return 0
+	}
+
+	return int32(binary.LittleEndian.Uint32(value))
+}
+
+// Colors reported by the color and distance sensor.
+const (
+	ColorBlack  = 0
+	ColorBlue   = 3
+	ColorGreen  = 5
+	ColorYellow = 7
+	ColorRed    = 9
+	ColorWhite  = 10
+	ColorNone   = 255
+)
+
+// Modes of the Boost color and distance sensor.
+const (
+	colorDistanceModeColor    = 0
+	colorDistanceModeDistance = 1
+)
+
+// A color and distance sensor attached to a hub port.
+type ColorDistanceSensor struct {
+	hub  *Hub
+	port uint8
+	mode uint8
+}
+
+// Returns the color and distance sensor attached to the given port.
+func (self *Hub) ColorDistanceSensor(port uint8) *ColorDistanceSensor {
+	return &ColorDistanceSensor{hub: self, port: port, mode: 0xFF}
+}
+
+func (self *ColorDistanceSensor) use(mode uint8) {
+	if self.mode != mode {
+		self.hub.Subscribe(self.port, mode, 1)
+		self.mode = mode
+	}
+}
+
+// Returns the last reported color, ColorNone if nothing is seen. The first call
+// switches the sensor into color mode; the value follows shortly after.
+func (self *ColorDistanceSensor) Color() uint8 {
+	self.use(colorDistanceModeColor)
+
+	value := self.hub.Value(self.port)
+	if len(value) < 1 {
+		return ColorNone
+	}
+
+	return value[0]
+}
+
+// Returns the last reported distance to an object, 0 (touching) to 10 (far).
+// The first call switches the sensor into distance mode.
+func (self *ColorDistanceSensor) Distance() uint8 {
+	self.use(colorDistanceModeDistance)
+
+	value := self.hub.Value(self.port)
+	if len(value) < 1 {
+		return 10
+	}
+
+	return value[0]
+}
@@ -0,0 +1,274 @@
+// Provides control of LEGO Powered Up and Boost hubs, motors and sensors over
+// Bluetooth Low Energy, using the LEGO Wireless Protocol 3.0, so that a robot
+// can combine EV3 devices with the newer LEGO electronics:
+//
+//	hub, err := PoweredUp.Connect("90:84:2B:4E:5A:11")
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	defer hub.Close()
+//
+//	arm := hub.Motor(PoweredUp.PortA)
+//	arm.RunForDegrees(90, 50, PoweredUp.Brake, true)
+//
+// Hubs are connected to by address; use `bluetoothctl scan on` to find it while
+// the hub's button blinks.
+package PoweredUp
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jermon/GoEV3/Bluetooth"
+)
+
+// The LEGO hub service and its single characteristic carrying all messages.
+var (
+	HubService        = Bluetooth.MustParseUUID("00001623-1212-efde-1623-785feabcd123")
+	HubCharacteristic = Bluetooth.MustParseUUID("00001624-1212-efde-1623-785feabcd123")
+)
+
+// Message types of the LEGO Wireless Protocol.
+const (
+	msgHubActions        = 0x02
+	msgHubAttachedIO     = 0x04
+	msgInputFormatSingle = 0x41
+	msgPortValueSingle   = 0x45
+	msgOutputCommand     = 0x81
+	msgOutputFeedback    = 0x82
+)
+
+// Ports of the hubs. The Powered Up hub only has A and B.
+const (
+	PortA uint8 = 0
+	PortB uint8 = 1
+	PortC uint8 = 2
+	PortD uint8 = 3
+
+	// The RGB light of the hub.
+	PortLight uint8 = 0x32
+)
+
+// IO type IDs of common devices, as reported when they are attached.
+const (
+	TypeMediumMotor   uint16 = 0x0001
+	TypeTrainMotor    uint16 = 0x0002
+	TypeLight         uint16 = 0x0008
+	TypeRGBLight      uint16 = 0x0017
+	TypeTiltSensor    uint16 = 0x0022
+	TypeMotionSensor  uint16 = 0x0023
+	TypeColorDistance uint16 = 0x0025
+	TypeBoostMotor    uint16 = 0x0026
+	TypeInternalMotor uint16 = 0x0027
+	TypeInternalTilt  uint16 = 0x0028
+	TypeLargeMotor    uint16 = 0x002E
+	TypeXLargeMotor   uint16 = 0x002F
+)
+
+// How long a blocking motor command may run before it is considered lost.
+const commandTimeout = 30 * time.Second
+
+// A connected hub.
+type Hub struct {
+	client *Bluetooth.GATTClient
+	handle uint16
+
+	lock     sync.Mutex
+	devices  map[uint8]uint16
+	values   map[uint8][]byte
+	feedback map[uint8]chan byte
+	onAttach []func(port uint8, ioType uint16, attached bool)
+}
+
+// Connects to the hub with the given Bluetooth address.
+func Connect(address string) (*Hub, error) {
+	client, err := Bluetooth.DialGATT(address, false)
+	if err != nil {
+		return nil, err
+	}
+
+	h, err := NewHub(client)
+	if err != nil {
+		client.Close()
+		return nil, err
+	}
+
+	return h, nil
+}
+
+// Starts talking to a hub over an established GATT connection.
+func NewHub(client *Bluetooth.GATTClient) (*Hub, error) {
+	h := new(Hub)
+	h.client = client
+	h.devices = make(map[uint8]uint16)
+	h.values = make(map[uint8][]byte)
+	h.feedback = make(map[uint8]chan byte)
+
+	handle, err := client.FindCharacteristic(HubCharacteristic)
+	if err != nil {
+		return nil, err
+	}
+	h.handle = handle
+
+	// The hub reports the attached devices once notifications are enabled.
+	if err := client.Subscribe(handle, h.receive); err != nil {
+		return nil, err
+	}
+
+	return h, nil
+}
+
+// Sends a message of the given type.
+func (self *Hub) send(msgType byte, payload ...byte) error {
+	msg := append([]byte{byte(3 + len(payload)), 0x00, msgType}, payload...)
+	return self.client.Write(self.handle, msg)
+}
+
+func (self *Hub) receive(msg []byte) {
+	if len(msg) < 3 || int(msg[0]) != len(msg) {
+		return
+	}
+	payload := msg[3:]
+
+	switch msg[2] {
+	case msgHubAttachedIO:
+		if len(payload) < 2 {
+			return
+		}
+		port, event := payload[0], payload[1]
+
+		self.lock.Lock()
+		var ioType uint16
+		if event != 0 && len(payload) >= 4 {
+			ioType = binary.LittleEndian.Uint16(payload[2:4])
+			self.devices[port] = ioType
+		} else {
+			delete(self.devices, port)
+			delete(self.values, port)
+		}
+		callbacks := append(([]func(uint8, uint16, bool))(nil), self.onAttach...)
+		self.lock.Unlock()
+
+		for _, fn := range callbacks {
+			fn(port, ioType, event != 0)
+		}
+	case msgPortValueSingle:
+		if len(payload) < 1 {
+			return
+		}
+
+		self.lock.Lock()
+		self.values[payload[0]] = append([]byte(nil), payload[1:]...)
+		self.lock.Unlock()
+	case msgOutputFeedback:
+		// Pairs of port and feedback flags.
+		for i := 0; i+1 < len(payload); i += 2 {
+			self.lock.Lock()
+			ch := self.feedback[payload[i]]
+			self.lock.Unlock()
+
+			if ch != nil {
+				select {
+				case ch <- payload[i+1]:
+				default:
+				}
+			}
+		}
+	}
+}
+
+// Registers a callback invoked when a device is attached to or detached from a port.
+func (self *Hub) OnAttach(fn func(port uint8, ioType uint16, attached bool)) {
+	self.lock.Lock()
+	self.onAttach = append(self.onAttach, fn)
+	self.lock.Unlock()
+}
+
+// Returns the IO type of the device attached to a port, and whether there is one.
+func (self *Hub) Device(port uint8) (uint16, bool) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	t, ok := self.devices[port]
+	return t, ok
+}
+
+// Sets the color of the hub's light to one of the LEGO color indices, 0 (off) to 10 (white).
+func (self *Hub) SetLightColor(color uint8) error {
+	return self.send(msgOutputCommand, PortLight, 0x11, 0x51, 0x00, color)
+}
+
+// Switches the hub off, which also disconnects it.
+func (self *Hub) SwitchOff() error {
+	return self.send(msgHubActions, 0x01)
+}
+
+// Disconnects from the hub, leaving it on.
+func (self *Hub) Close() error {
+	self.send(msgHubActions, 0x02)
+	return self.client.Close()
+}
+
+// Makes the hub report the values of the device on `port` in the given mode
+// whenever they change by at least `delta`.
+func (self *Hub) Subscribe(port uint8, mode uint8, delta uint32) error {
+	payload := []byte{port, mode, 0, 0, 0, 0, 0x01}
+	binary.LittleEndian.PutUint32(payload[2:6], delta)
+
+	return self.send(msgInputFormatSingle, payload...)
+}
+
+// Returns the raw bytes of the last value reported for `port`, nil if none was.
+func (self *Hub) Value(port uint8) []byte {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	return self.values[port]
+}
+
+// Sends an output command and, if `wait` is set, waits until the hub reports it completed.
+func (self *Hub) output(port uint8, wait bool, subcommand byte, params ...byte) error {
+	var ch chan byte
+	if wait {
+		ch = make(chan byte, 4)
+
+		self.lock.Lock()
+		self.feedback[port] = ch
+		self.lock.Unlock()
+
+		defer func() {
+			self.lock.Lock()
+			if self.feedback[port] == ch {
+				delete(self.feedback, port)
+			}
+			self.lock.Unlock()
+		}()
+	}
+
+	// Execute immediately and request feedback.
+	if err := self.send(msgOutputCommand, append([]byte{port, 0x11, subcommand}, params...)...); err != nil {
+		return err
+	}
+
+	if !wait {
+		return nil
+	}
+
+	timeout := time.After(commandTimeout)
+	for {
+		select {
+		case flags := <-ch:
+			switch {
+			case flags&0x04 != 0:
+				return errors.New("poweredup: command discarded")
+			case flags&0x02 != 0:
+				return nil
+			}
+		case <-timeout:
+			return fmt.Errorf("poweredup: no feedback from port %d", port)
+		}
+	}
+}
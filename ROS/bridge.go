@@ -0,0 +1,331 @@
+// Provides a rosbridge protocol client that lets the EV3 act as a ROS mobile base.
+//
+// The bridge talks JSON over a WebSocket connection to a rosbridge_server
+// (http://wiki.ros.org/rosbridge_suite). Odometry, range readings and brick
+// button states are published periodically, while geometry_msgs/Twist messages
+// received on cmd_vel are forwarded to a drive base.
+package ROS
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/jermon/GoEV3/Button"
+	"github.com/jermon/GoEV3/Geometry"
+	"github.com/jermon/GoEV3/utilities/websocket"
+)
+
+// Anything that can follow a velocity command. `linear` is measured in centimeters
+// per second and `angular` in degrees per second, counter-clockwise being positive.
+type Drive interface {
+	SetVelocity(linear float64, angular float64)
+	Stop()
+}
+
+// Anything that reports the robot pose. `x` and `y` are measured in centimeters
+// and `theta` in degrees, counter-clockwise being positive.
+type PoseSource = Geometry.PoseSource
+
+// Radiation types of sensor_msgs/Range.
+const (
+	Ultrasound uint8 = 0
+	Infrared         = 1
+)
+
+// A rosbridge client connection.
+type Bridge struct {
+	conn *websocket.Conn
+
+	lock       sync.Mutex
+	handlers   map[string]func(json.RawMessage)
+	publishers []func(stamp time.Time) error
+	watchdogs  []func(now time.Time)
+}
+
+type operation struct {
+	Op    string          `json:"op"`
+	Topic string          `json:"topic,omitempty"`
+	Type  string          `json:"type,omitempty"`
+	Msg   json.RawMessage `json:"msg,omitempty"`
+}
+
+// Connects to a rosbridge server, e.g. "ws://192.168.0.10:9090".
+func Dial(url string) (*Bridge, error) {
+	conn, err := websocket.Dial(url)
+	if err != nil {
+		return nil, err
+	}
+
+	b := new(Bridge)
+	b.conn = conn
+	b.handlers = make(map[string]func(json.RawMessage))
+
+	return b, nil
+}
+
+// Closes the connection to the rosbridge server.
+func (self *Bridge) Close() error {
+	return self.conn.Close()
+}
+
+func (self *Bridge) send(op interface{}) error {
+	data, err := json.Marshal(op)
+	if err != nil {
+		return err
+	}
+
+	return self.conn.WriteMessage(data)
+}
+
+// Announces that the bridge will publish messages of the given type on a topic.
+func (self *Bridge) Advertise(topic string, msgType string) error {
+	return self.send(operation{Op: "advertise", Topic: topic, Type: msgType})
+}
+
+// Publishes a message on a previously advertised topic. `msg` must marshal to
+// the JSON representation of the topic's message type.
+func (self *Bridge) Publish(topic string, msg interface{}) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	return self.send(operation{Op: "publish", Topic: topic, Msg: data})
+}
+
+// Subscribes to a topic. `fn` is called from Spin with the raw JSON message.
+func (self *Bridge) Subscribe(topic string, msgType string, fn func(msg json.RawMessage)) error {
+	self.lock.Lock()
+	self.handlers[topic] = fn
+	self.lock.Unlock()
+
+	return self.send(operation{Op: "subscribe", Topic: topic, Type: msgType})
+}
+
+func (self *Bridge) addPublisher(fn func(stamp time.Time) error) {
+	self.lock.Lock()
+	self.publishers = append(self.publishers, fn)
+	self.lock.Unlock()
+}
+
+// Publishes nav_msgs/Odometry on the given topic every Spin tick. Velocities are
+// derived from consecutive poses.
+func (self *Bridge) PublishOdometry(topic string, frame string, childFrame string, source PoseSource) error {
+	if err := self.Advertise(topic, "nav_msgs/Odometry"); err != nil {
+		return err
+	}
+
+	var last time.Time
+	var lastX, lastY, lastTheta float64
+
+	self.addPublisher(func(stamp time.Time) error {
+		x, y, theta := source.Pose()
+
+		msg := odometry{}
+		msg.Header = newHeader(stamp, frame)
+		msg.ChildFrameID = childFrame
+		msg.Pose.Pose.Position = vector3{X: x / 100, Y: y / 100}
+		msg.Pose.Pose.Orientation = yawToQuaternion(theta * math.Pi / 180)
+		msg.Pose.Covariance = make([]float64, 36)
+		msg.Twist.Covariance = make([]float64, 36)
+
+		if !last.IsZero() {
+			dt := stamp.Sub(last).Seconds()
+
+			if dt > 0 {
+				dx := (x - lastX) / 100
+				dy := (y - lastY) / 100
+				heading := theta * math.Pi / 180
+
+				// Twist is expressed in the child (robot) frame.
+				msg.Twist.Twist.Linear.X = (dx*math.Cos(heading) + dy*math.Sin(heading)) / dt
+				msg.Twist.Twist.Angular.Z = Geometry.NormalizeDegrees(theta-lastTheta) * math.Pi / 180 / dt
+			}
+		}
+
+		last, lastX, lastY, lastTheta = stamp, x, y, theta
+
+		return self.Publish(topic, msg)
+	})
+
+	return nil
+}
+
+// Publishes sensor_msgs/Range on the given topic every Spin tick. `read` returns
+// the distance in centimeters; `minRange` and `maxRange` are in centimeters too
+// and `fieldOfView` is in degrees.
+func (self *Bridge) PublishRange(topic string, frame string, radiationType uint8, fieldOfView float64, minRange float64, maxRange float64, read func() float64) error {
+	if err := self.Advertise(topic, "sensor_msgs/Range"); err != nil {
+		return err
+	}
+
+	self.addPublisher(func(stamp time.Time) error {
+		msg := rangeMessage{
+			Header:        newHeader(stamp, frame),
+			RadiationType: radiationType,
+			FieldOfView:   fieldOfView * math.Pi / 180,
+			MinRange:      minRange / 100,
+			MaxRange:      maxRange / 100,
+			Range:         read() / 100,
+		}
+
+		return self.Publish(topic, msg)
+	})
+
+	return nil
+}
+
+// Publishes the state of every brick button as std_msgs/Bool on `<prefix>/up`,
+// `<prefix>/down` and so on every Spin tick. This call must be preceded
+// with a call to `Button.Watch`.
+func (self *Bridge) PublishButtons(prefix string) error {
+	names := map[Button.Kind]string{
+		Button.Up:     "up",
+		Button.Down:   "down",
+		Button.Left:   "left",
+		Button.Right:  "right",
+		Button.Enter:  "enter",
+		Button.Escape: "escape",
+	}
+
+	for kind, name := range names {
+		topic := prefix + "/" + name
+
+		if err := self.Advertise(topic, "std_msgs/Bool"); err != nil {
+			return err
+		}
+
+		kind := kind
+		self.addPublisher(func(stamp time.Time) error {
+			return self.Publish(topic, boolMessage{Data: Button.IsPressed(kind)})
+		})
+	}
+
+	return nil
+}
+
+// Subscribes to geometry_msgs/Twist on the given topic (usually "cmd_vel") and
+// forwards linear.x and angular.z to the drive. If no command arrives within
+// `timeout` the drive is stopped, so a lost connection doesn't leave the robot
+// running. A zero timeout disables the watchdog.
+func (self *Bridge) SubscribeCmdVel(topic string, drive Drive, timeout time.Duration) error {
+	var lock sync.Mutex
+	var lastCommand time.Time
+
+	self.lock.Lock()
+	self.watchdogs = append(self.watchdogs, func(now time.Time) {
+		lock.Lock()
+		expired := timeout > 0 && !lastCommand.IsZero() && now.Sub(lastCommand) > timeout
+		if expired {
+			lastCommand = time.Time{}
+		}
+		lock.Unlock()
+
+		if expired {
+			drive.Stop()
+		}
+	})
+	self.lock.Unlock()
+
+	return self.Subscribe(topic, "geometry_msgs/Twist", func(raw json.RawMessage) {
+		var msg twist
+		if err := json.Unmarshal(raw, &msg); err != nil {
+			return
+		}
+
+		lock.Lock()
+		lastCommand = time.Now()
+		lock.Unlock()
+
+		if msg.Linear.X == 0 && msg.Angular.Z == 0 {
+			drive.Stop()
+		} else {
+			drive.SetVelocity(msg.Linear.X*100, msg.Angular.Z*180/math.Pi)
+		}
+	})
+}
+
+// Processes incoming messages and publishes all registered topics at the given
+// interval until a value is sent to `stop` or the connection fails. Spin owns
+// the connection: it is closed once Spin returns, which is the only way to
+// stop the read in progress, so the bridge can't be spun again.
+func (self *Bridge) Spin(stop <-chan bool, interval time.Duration) error {
+	incoming := make(chan operation, 16)
+	failed := make(chan error, 1)
+
+	// Closed on return, so that the reader doesn't block on a full queue.
+	done := make(chan bool)
+	defer close(done)
+	defer self.conn.Close()
+
+	go func() {
+		for {
+			data, err := self.conn.ReadMessage()
+			if err != nil {
+				failed <- err
+				return
+			}
+
+			var op operation
+			if err := json.Unmarshal(data, &op); err != nil {
+				continue
+			}
+
+			select {
+			case incoming <- op:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return nil
+		case err := <-failed:
+			if errors.Is(err, websocket.ErrClosed) {
+				return nil
+			}
+			return fmt.Errorf("rosbridge connection failed: %v", err)
+		case op := <-incoming:
+			if op.Op != "publish" {
+				continue
+			}
+
+			self.lock.Lock()
+			fn := self.handlers[op.Topic]
+			self.lock.Unlock()
+
+			if fn != nil {
+				fn(op.Msg)
+			}
+		case now := <-ticker.C:
+			self.lock.Lock()
+			publishers := append(([]func(time.Time) error)(nil), self.publishers...)
+			watchdogs := append(([]func(time.Time))(nil), self.watchdogs...)
+			self.lock.Unlock()
+
+			for _, fn := range watchdogs {
+				fn(now)
+			}
+
+			for _, fn := range publishers {
+				if err := fn(now); err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
+
+func yawToQuaternion(yaw float64) quaternion {
+	return quaternion{Z: math.Sin(yaw / 2), W: math.Cos(yaw / 2)}
+}
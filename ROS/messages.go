@@ -0,0 +1,71 @@
+package ROS
+
+import (
+	"time"
+)
+
+// JSON representations of the ROS message types used by the bridge.
+
+type rosTime struct {
+	Secs  int64 `json:"secs"`
+	Nsecs int64 `json:"nsecs"`
+}
+
+type header struct {
+	Stamp   rosTime `json:"stamp"`
+	FrameID string  `json:"frame_id"`
+}
+
+func newHeader(stamp time.Time, frame string) header {
+	return header{
+		Stamp:   rosTime{Secs: stamp.Unix(), Nsecs: int64(stamp.Nanosecond())},
+		FrameID: frame,
+	}
+}
+
+type vector3 struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+	Z float64 `json:"z"`
+}
+
+type quaternion struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+	Z float64 `json:"z"`
+	W float64 `json:"w"`
+}
+
+type twist struct {
+	Linear  vector3 `json:"linear"`
+	Angular vector3 `json:"angular"`
+}
+
+type odometry struct {
+	Header       header `json:"header"`
+	ChildFrameID string `json:"child_frame_id"`
+	Pose         struct {
+		Pose struct {
+			Position    vector3    `json:"position"`
+			Orientation quaternion `json:"orientation"`
+		} `json:"pose"`
+		Covariance []float64 `json:"covariance"`
+	} `json:"pose"`
+	Twist struct {
+		Twist      twist     `json:"twist"`
+		Covariance []float64 `json:"covariance"`
+	} `json:"twist"`
+}
+
+type rangeMessage struct {
+	Header        header  `json:"header"`
+	RadiationType uint8   `json:"radiation_type"`
+	FieldOfView   float64 `json:"field_of_view"`
+	MinRange      float64 `json:"min_range"`
+	MaxRange      float64 `json:"max_range"`
+	Range         float64 `json:"range"`
+}
+
+type boolMessage struct {
+	Data bool `json:"data"`
+}
@@ -0,0 +1,302 @@
+// Provides record-and-replay of device I/O for reproducing field failures.
+//
+// A Recorder wraps the current backend and logs every attribute read and write,
+// with timestamps, as JSON lines. A Player later serves the recorded sensor
+// values back to the program in the same order, so a run can be replayed
+// deterministically on a desktop.
+package Recording
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/jermon/GoEV3/Safety"
+	"github.com/jermon/GoEV3/utilities"
+)
+
+// Kinds of recorded operations.
+const (
+	OpRead   = "read"
+	OpWrite  = "write"
+	OpList   = "list"
+	OpExists = "exists"
+)
+
+// A single recorded operation.
+type Event struct {
+	// Time since the recording started, in nanoseconds.
+	Time    int64    `json:"t"`
+	Op      string   `json:"op"`
+	Path    string   `json:"path"`
+	Value   string   `json:"value,omitempty"`
+	Entries []string `json:"entries,omitempty"`
+	Exists  bool     `json:"exists,omitempty"`
+	Error   string   `json:"error,omitempty"`
+}
+
+// Backend wrapper that logs every operation.
+type Recorder struct {
+	lock     sync.Mutex
+	backend  utilities.Backend
+	previous utilities.Backend
+	start    time.Time
+	writer   *bufio.Writer
+	encoder  *json.Encoder
+	closer   io.Closer
+	err      error
+}
+
+// Creates a recorder logging the operations performed on `backend` to `w`.
+func NewRecorder(w io.Writer, backend utilities.Backend) *Recorder {
+	r := new(Recorder)
+	r.backend = backend
+	r.start = time.Now()
+	r.writer = bufio.NewWriter(w)
+	r.encoder = json.NewEncoder(r.writer)
+
+	return r
+}
+
+// Starts recording all device I/O to the given file. The recording is stopped
+// automatically by Safety.Shutdown.
+func StartRecording(filename string) (*Recorder, error) {
+	f, err := os.Create(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	previous := utilities.CurrentBackend()
+
+	r := NewRecorder(f, previous)
+	r.previous = previous
+	r.closer = f
+
+	utilities.SetBackend(r)
+	Safety.OnShutdown(Safety.FlushData, func() { r.Stop() })
+
+	return r, nil
+}
+
+// Stops the recording started with StartRecording, restoring the previous backend.
+func (self *Recorder) Stop() error {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	if self.previous != nil {
+		utilities.SetBackend(self.previous)
+		self.previous = nil
+	}
+
+	if err := self.writer.Flush(); err != nil && self.err == nil {
+		self.err = err
+	}
+
+	if self.closer != nil {
+		if err := self.closer.Close(); err != nil && self.err == nil {
+			self.err = err
+		}
+		self.closer = nil
+	}
+
+	return self.err
+}
+
+func (self *Recorder) log(e Event, err error) {
+	if err != nil {
+		e.Error = err.Error()
+	}
+
+	self.lock.Lock()
+	e.Time = int64(time.Since(self.start))
+	if werr := self.encoder.Encode(e); werr != nil && self.err == nil {
+		self.err = werr
+	}
+	self.lock.Unlock()
+}
+
+// Implements utilities.Backend.
+func (self *Recorder) ReadFile(name string) ([]byte, error) {
+	data, err := self.backend.ReadFile(name)
+	self.log(Event{Op: OpRead, Path: name, Value: string(data)}, err)
+
+	return data, err
+}
+
+// Implements utilities.Backend.
+func (self *Recorder) WriteFile(name string, data []byte) error {
+	err := self.backend.WriteFile(name, data)
+	self.log(Event{Op: OpWrite, Path: name, Value: string(data)}, err)
+
+	return err
+}
+
+// Implements utilities.Backend.
+func (self *Recorder) ReadDir(name string) ([]string, error) {
+	entries, err := self.backend.ReadDir(name)
+	self.log(Event{Op: OpList, Path: name, Entries: entries}, err)
+
+	return entries, err
+}
+
+// Implements utilities.Backend.
+func (self *Recorder) Exists(name string) bool {
+	exists := self.backend.Exists(name)
+	self.log(Event{Op: OpExists, Path: name, Exists: exists}, nil)
+
+	return exists
+}
+
+// Backend that serves recorded values back to the program.
+//
+// Reads of every attribute return the recorded values in order; once they're
+// exhausted the last value is repeated. Writes are not executed, but are compared
+// against the recording and any difference is reported by Divergences.
+type Player struct {
+	lock        sync.Mutex
+	reads       map[string][]Event
+	writes      map[string][]Event
+	lists       map[string]Event
+	exists      map[string]bool
+	divergences []string
+}
+
+// Reads a recording produced by a Recorder.
+func NewPlayer(r io.Reader) (*Player, error) {
+	p := new(Player)
+	p.reads = make(map[string][]Event)
+	p.writes = make(map[string][]Event)
+	p.lists = make(map[string]Event)
+	p.exists = make(map[string]bool)
+
+	decoder := json.NewDecoder(r)
+
+	for {
+		var e Event
+		err := decoder.Decode(&e)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch e.Op {
+		case OpRead:
+			p.reads[e.Path] = append(p.reads[e.Path], e)
+		case OpWrite:
+			p.writes[e.Path] = append(p.writes[e.Path], e)
+		case OpList:
+			if _, ok := p.lists[e.Path]; !ok {
+				p.lists[e.Path] = e
+			}
+		case OpExists:
+			if _, ok := p.exists[e.Path]; !ok {
+				p.exists[e.Path] = e.Exists
+			}
+		}
+	}
+
+	return p, nil
+}
+
+// Loads a recording from the given file.
+func LoadPlayer(filename string) (*Player, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return NewPlayer(f)
+}
+
+// Makes the player the backend for all device I/O.
+func (self *Player) Install() {
+	utilities.SetBackend(self)
+}
+
+func eventError(e Event) error {
+	if e.Error == "" {
+		return nil
+	}
+
+	return errors.New(e.Error)
+}
+
+// Implements utilities.Backend.
+func (self *Player) ReadFile(name string) ([]byte, error) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	queue := self.reads[name]
+	if len(queue) == 0 {
+		return nil, &os.PathError{Op: "read", Path: name, Err: os.ErrNotExist}
+	}
+
+	e := queue[0]
+	if len(queue) > 1 {
+		self.reads[name] = queue[1:]
+	}
+
+	return []byte(e.Value), eventError(e)
+}
+
+// Implements utilities.Backend.
+func (self *Player) WriteFile(name string, data []byte) error {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	queue := self.writes[name]
+	if len(queue) == 0 {
+		self.divergences = append(self.divergences, fmt.Sprintf("unexpected write of %q to %s", string(data), name))
+		return nil
+	}
+
+	e := queue[0]
+	self.writes[name] = queue[1:]
+
+	if e.Value != string(data) {
+		self.divergences = append(self.divergences, fmt.Sprintf("wrote %q to %s, recording has %q", string(data), name, e.Value))
+	}
+
+	return eventError(e)
+}
+
+// Implements utilities.Backend.
+func (self *Player) ReadDir(name string) ([]string, error) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	e, ok := self.lists[name]
+	if !ok {
+		return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrNotExist}
+	}
+
+	return append([]string(nil), e.Entries...), eventError(e)
+}
+
+// Implements utilities.Backend.
+func (self *Player) Exists(name string) bool {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	if exists, ok := self.exists[name]; ok {
+		return exists
+	}
+
+	return len(self.reads[name]) > 0 || len(self.lists[name].Entries) > 0
+}
+
+// Returns the writes that differed from the recording so far.
+func (self *Player) Divergences() []string {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	return append([]string(nil), self.divergences...)
+}
@@ -0,0 +1,275 @@
+package Recording
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/jermon/GoEV3/Motor"
+	"github.com/jermon/GoEV3/Safety"
+	"github.com/jermon/GoEV3/Sensors"
+	"github.com/jermon/GoEV3/utilities"
+)
+
+// Formats of telemetry logs.
+type Format int
+
+const (
+	// Comma separated values, with a header row naming the columns.
+	CSV Format = iota
+	// One JSON object per line, keyed by the column names.
+	JSONLines
+)
+
+// Logs timestamped readings of sensors and motors to a file, one row per
+// sample, e.g. to tune a robot's controllers from what it did on a run:
+//
+//	logger := Recording.NewLogger("/home/robot/run.csv", Recording.CSV)
+//	logger.AddMotor("left", left)
+//	logger.AddGyro("gyro", gyro)
+//	logger.Add("error", func() float64 { return lastError })
+//	go logger.Run(stop, 20*time.Millisecond)
+//
+// Each row starts with the time since the logging started, in seconds.
+type Logger struct {
+	lock     sync.Mutex
+	filename string
+	format   Format
+	maxSize  int64
+	columns  []column
+
+	file    *os.File
+	writer  *bufio.Writer
+	size    int64
+	rotated int
+	start   time.Time
+	err     error
+}
+
+type column struct {
+	name string
+	read func() float64
+}
+
+// Creates a logger writing to `filename` in the given format. Nothing is
+// written until Start.
+func NewLogger(filename string, format Format) *Logger {
+	l := new(Logger)
+	l.filename = filename
+	l.format = format
+
+	return l
+}
+
+// Starts a new file once the current one has grown past `bytes`: the full one
+// is renamed with a suffix counting the rotations, as in "run.csv.1",
+// "run.csv.2". 0, the default, never rotates.
+func (self *Logger) SetMaxSize(bytes int64) {
+	self.lock.Lock()
+	self.maxSize = bytes
+	self.lock.Unlock()
+}
+
+// Adds a column read by `read`. Columns must be added before Start.
+func (self *Logger) Add(name string, read func() float64) {
+	self.lock.Lock()
+	self.columns = append(self.columns, column{name, read})
+	self.lock.Unlock()
+}
+
+// Adds the speed and position of a motor, in tacho counts, as the columns
+// "name.speed" and "name.position".
+func (self *Logger) AddMotor(name string, motor Motor.Motor) {
+	self.Add(name+".speed", func() float64 { return float64(motor.CurrentSpeed()) })
+	self.Add(name+".position", func() float64 { return float64(motor.CurrentPosition()) })
+}
+
+// Adds the angle and rate of a gyro, in degrees and degrees per second, as
+// the columns "name.angle" and "name.rate".
+func (self *Logger) AddGyro(name string, gyro *Sensors.GyroSensor) {
+	self.Add(name+".angle", func() float64 { return float64(gyro.ReadAngle()) })
+	self.Add(name+".rate", func() float64 { return float64(gyro.ReadRate()) })
+}
+
+// Adds the reflected light intensity of a color sensor, in percent.
+func (self *Logger) AddColor(name string, sensor *Sensors.ColorSensor) {
+	self.Add(name, func() float64 { return float64(sensor.ReadReflectedLightIntensity()) })
+}
+
+// Adds the proximity an infrared sensor measures, in percent.
+func (self *Logger) AddProximity(name string, sensor *Sensors.InfraredSensor) {
+	self.Add(name, func() float64 { return float64(sensor.ReadProximity()) })
+}
+
+// Adds the distance an ultrasonic sensor measures, in centimeters.
+func (self *Logger) AddDistance(name string, sensor *Sensors.UltrasonicSensor) {
+	self.Add(name, sensor.ReadDistanceCentimeters)
+}
+
+// Creates the file and writes its header. The logging is stopped
+// automatically by Safety.Shutdown.
+func (self *Logger) Start() error {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	if self.file != nil {
+		return errors.New("recording: logger already started")
+	}
+
+	if err := self.open(); err != nil {
+		return err
+	}
+
+	self.start = time.Now()
+	self.rotated = 0
+	self.err = nil
+	Safety.OnShutdown(Safety.FlushData, func() { self.Stop() })
+
+	return nil
+}
+
+// Creates the file and writes the CSV header. Must be called with the lock held.
+func (self *Logger) open() error {
+	f, err := os.Create(self.filename)
+	if err != nil {
+		return err
+	}
+
+	self.file = f
+	self.writer = bufio.NewWriter(f)
+	self.size = 0
+
+	if self.format == CSV {
+		names := []string{"time"}
+		for _, c := range self.columns {
+			names = append(names, c.name)
+		}
+		self.writeCSV(names)
+	}
+
+	return nil
+}
+
+// Flushes and closes the file. Must be called with the lock held.
+func (self *Logger) close() {
+	if err := self.writer.Flush(); err != nil && self.err == nil {
+		self.err = err
+	}
+	if err := self.file.Close(); err != nil && self.err == nil {
+		self.err = err
+	}
+	self.file, self.writer = nil, nil
+}
+
+// Moves the full file aside and starts a new one. Must be called with the lock held.
+func (self *Logger) rotate() {
+	self.close()
+
+	self.rotated++
+	if err := os.Rename(self.filename, fmt.Sprintf("%s.%d", self.filename, self.rotated)); err != nil && self.err == nil {
+		self.err = err
+	}
+
+	if err := self.open(); err != nil && self.err == nil {
+		self.err = err
+	}
+}
+
+func (self *Logger) writeCSV(fields []string) {
+	w := csv.NewWriter(countingWriter{self})
+	w.Write(fields)
+	w.Flush()
+}
+
+// Writes to a logger's file for the CSV encoder, counting the bytes written.
+type countingWriter struct {
+	logger *Logger
+}
+
+func (self countingWriter) Write(data []byte) (int, error) {
+	return self.logger.write(data)
+}
+
+// Writes to the file, counting the bytes written. Must be called with the lock held.
+func (self *Logger) write(data []byte) (int, error) {
+	n, err := self.writer.Write(data)
+	self.size += int64(n)
+	if err != nil && self.err == nil {
+		self.err = err
+	}
+
+	return n, err
+}
+
+// Reads all columns once and writes them as a row. Does nothing unless started.
+func (self *Logger) Sample() {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	if self.file == nil {
+		return
+	}
+
+	elapsed := time.Since(self.start).Seconds()
+	values := make([]float64, len(self.columns))
+	for i, c := range self.columns {
+		values[i] = c.read()
+	}
+
+	switch self.format {
+	case CSV:
+		fields := []string{strconv.FormatFloat(elapsed, 'f', 3, 64)}
+		for _, v := range values {
+			fields = append(fields, strconv.FormatFloat(v, 'g', -1, 64))
+		}
+		self.writeCSV(fields)
+
+	case JSONLines:
+		line := []byte(`{"time":` + strconv.FormatFloat(elapsed, 'f', 3, 64))
+		for i, c := range self.columns {
+			name, _ := json.Marshal(c.name)
+			line = append(line, ',')
+			line = append(line, name...)
+			line = append(line, ':')
+			line = strconv.AppendFloat(line, values[i], 'g', -1, 64)
+		}
+		line = append(line, "}\n"...)
+		self.write(line)
+	}
+
+	if self.maxSize > 0 && self.size >= self.maxSize {
+		self.rotate()
+	}
+}
+
+// Starts logging and writes a row every `interval`, from the shared polling
+// scheduler, until a value is sent to `stop`; then stops. Returns the first
+// error encountered.
+func (self *Logger) Run(stop <-chan bool, interval time.Duration) error {
+	if err := self.Start(); err != nil {
+		return err
+	}
+
+	utilities.PollUntil(stop, interval, self.Sample)
+
+	return self.Stop()
+}
+
+// Flushes and closes the file. Returns the first error encountered since
+// Start. Safe to call more than once.
+func (self *Logger) Stop() error {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	if self.file != nil {
+		self.close()
+	}
+
+	return self.err
+}
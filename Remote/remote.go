@@ -0,0 +1,200 @@
+// Serves live sensor values and takes motor commands over a WebSocket, so that
+// a laptop or phone can monitor and drive the robot:
+//
+//	poller := Sensors.NewPoller()
+//	poller.Add("distance", sonar.ReadDistanceCentimeters, 0.5)
+//	go poller.Run(stop, 50*time.Millisecond)
+//
+//	server := Remote.NewServer(poller)
+//	server.AddMotor("left", left)
+//	server.AddMotor("right", right)
+//	server.ListenAndServe(":8081")
+//
+// Clients connect to /ws and receive each sample of the poller as a JSON message:
+//
+//	{"type": "sample", "name": "distance", "value": 42.5, "time": 1700000000000}
+//
+// where the time is in milliseconds since the Unix epoch. They send commands as:
+//
+//	{"command": "run", "motor": "left", "speed": 50}
+//	{"command": "run-to-rel-pos", "motor": "left", "position": 360, "speed": 30}
+//	{"command": "run-to-abs-pos", "motor": "left", "position": 0, "speed": 30}
+//	{"command": "stop", "motor": "left"}
+//	{"command": "stop-all"}
+//
+// with speeds in percent of the motor's maximum speed and positions in tacho
+// counts. A command that fails is answered with {"type": "error", "error": "..."}.
+// The motors a client ran are stopped when it disconnects.
+package Remote
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"sync"
+
+	"github.com/jermon/GoEV3/Motor"
+	"github.com/jermon/GoEV3/Sensors"
+	"github.com/jermon/GoEV3/utilities/websocket"
+)
+
+// A message sent to clients.
+type Message struct {
+	// "sample" or "error".
+	Type  string  `json:"type"`
+	Name  string  `json:"name,omitempty"`
+	Value float64 `json:"value"`
+	// Milliseconds since the Unix epoch.
+	Time  int64  `json:"time,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// A command received from clients.
+type Command struct {
+	Command  string  `json:"command"`
+	Motor    string  `json:"motor"`
+	Speed    float64 `json:"speed"`
+	Position int64   `json:"position"`
+}
+
+// A server of sensor values and motor commands.
+type Server struct {
+	lock   sync.Mutex
+	poller *Sensors.Poller
+	motors map[string]*Motor.Motor
+}
+
+// Creates a server sending the samples of `poller`, which may be nil for a
+// server only taking commands.
+func NewServer(poller *Sensors.Poller) *Server {
+	s := new(Server)
+	s.poller = poller
+	s.motors = make(map[string]*Motor.Motor)
+
+	return s
+}
+
+// Lets clients command `motor` under `name`.
+func (self *Server) AddMotor(name string, motor *Motor.Motor) {
+	self.lock.Lock()
+	self.motors[name] = motor
+	self.lock.Unlock()
+}
+
+// Runs a command, returning the motors it ran.
+func (self *Server) execute(c Command) ([]*Motor.Motor, error) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	if c.Command == "stop-all" {
+		for _, m := range self.motors {
+			m.Stop()
+		}
+		return nil, nil
+	}
+
+	m, ok := self.motors[c.Motor]
+	if !ok {
+		return nil, fmt.Errorf("remote: unknown motor %q", c.Motor)
+	}
+
+	speed := Motor.Percent(math.Max(-100, math.Min(100, c.Speed)))
+
+	switch c.Command {
+	case "run":
+		m.RunAt(speed)
+	case "run-to-rel-pos":
+		if err := m.RunToRelativePosition(c.Position, speed); err != nil {
+			return nil, err
+		}
+	case "run-to-abs-pos":
+		if err := m.RunToAbsolutePosition(c.Position, speed); err != nil {
+			return nil, err
+		}
+	case "stop":
+		m.Stop()
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("remote: unknown command %q", c.Command)
+	}
+
+	return []*Motor.Motor{m}, nil
+}
+
+// Serves a client until it disconnects.
+func (self *Server) serve(conn *websocket.Conn) {
+	defer conn.Close()
+
+	stop := make(chan bool)
+	defer close(stop)
+
+	send := func(m Message) error {
+		data, _ := json.Marshal(m)
+		return conn.WriteMessage(data)
+	}
+
+	if self.poller != nil {
+		samples := self.poller.Subscribe(stop)
+		go func() {
+			for s := range samples {
+				// NaN and infinities can't be encoded as JSON.
+				if math.IsNaN(s.Value) || math.IsInf(s.Value, 0) {
+					continue
+				}
+				if send(Message{Type: "sample", Name: s.Name, Value: s.Value, Time: s.Time.UnixMilli()}) != nil {
+					conn.Close()
+				}
+			}
+		}()
+	}
+
+	ran := make(map[*Motor.Motor]bool)
+	defer func() {
+		for m := range ran {
+			m.Stop()
+		}
+	}()
+
+	for {
+		data, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		var c Command
+		if err := json.Unmarshal(data, &c); err != nil {
+			send(Message{Type: "error", Error: err.Error()})
+			continue
+		}
+
+		motors, err := self.execute(c)
+		if err != nil {
+			send(Message{Type: "error", Error: err.Error()})
+			continue
+		}
+		for _, m := range motors {
+			ran[m] = true
+		}
+	}
+}
+
+// Serves the WebSocket endpoint at /ws.
+func (self *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ws", func(w http.ResponseWriter, r *http.Request) {
+		conn, err := websocket.Upgrade(w, r)
+		if err != nil {
+			return
+		}
+
+		self.serve(conn)
+	})
+
+	return mux
+}
+
+// Serves clients on `addr`. This call blocks.
+func (self *Server) ListenAndServe(addr string) error {
+	return http.ListenAndServe(addr, self.Handler())
+}
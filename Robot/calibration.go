@@ -0,0 +1,176 @@
+package Robot
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/jermon/GoEV3/Behavior"
+	"github.com/jermon/GoEV3/Sensors"
+	"github.com/jermon/GoEV3/utilities"
+)
+
+// File the shared calibration store is kept in, on the SD card so it survives
+// reboots. Change it before the store is first used to keep calibrations elsewhere.
+var DefaultCalibrationPath = "/home/robot/.goev3/calibration.json"
+
+// Constants measured on a particular robot. Light and Infrared are keyed by
+// the sensor names used in the robot's Config; Color and Gyro by SensorKey,
+// the sensor's port and driver, so that a calibration only applies to the
+// same kind of sensor on the same port.
+type Calibration struct {
+	// Reflected light intensities of color sensors over the line and the
+	// background, keyed by sensor name.
+	Light map[string]Behavior.LightCalibration `json:"light,omitempty"`
+	// White and black references of color sensors, keyed by SensorKey; see
+	// SaveSensorCalibrations.
+	Color map[string]Sensors.ColorCalibration `json:"color,omitempty"`
+	// Keyed by SensorKey; see SaveGyroCalibration. Entries keyed by sensor
+	// name, as earlier versions saved them, are still read.
+	Gyro map[string]GyroCalibration `json:"gyro,omitempty"`
+	// Correction of the proximity reported by infrared sensors, keyed by
+	// sensor name.
+	Infrared map[string]LinearCalibration `json:"infrared,omitempty"`
+	Geometry Geometry                     `json:"geometry"`
+	// Any other constants a program wants to keep, e.g. tuned PID gains.
+	Values map[string]float64 `json:"values,omitempty"`
+}
+
+// Drift of a gyro sensor at rest.
+type GyroCalibration struct {
+	// Angle in degrees reported when the robot faces its starting direction.
+	Offset float64 `json:"offset"`
+	// Degrees per second reported while the robot doesn't turn.
+	Drift float64 `json:"drift"`
+}
+
+// Maps a raw reading r to Scale*r + Offset.
+type LinearCalibration struct {
+	Scale  float64 `json:"scale"`
+	Offset float64 `json:"offset"`
+}
+
+// Applies the calibration to a raw reading.
+func (self LinearCalibration) Apply(raw float64) float64 {
+	return self.Scale*raw + self.Offset
+}
+
+// Measured dimensions of a drive base, in centimeters.
+type Geometry struct {
+	WheelDiameter float64 `json:"wheel_diameter,omitempty"`
+	TrackWidth    float64 `json:"track_width,omitempty"`
+}
+
+// Calibrations of several robots, keyed by robot name and kept in a JSON file.
+type CalibrationStore struct {
+	lock   sync.Mutex
+	path   string
+	robots map[string]Calibration
+}
+
+// Loads the store kept in the given file. A missing file yields an empty store,
+// created by the first Put.
+func OpenCalibrationStore(filename string) (*CalibrationStore, error) {
+	s := &CalibrationStore{path: filename, robots: make(map[string]Calibration)}
+
+	data, err := ioutil.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return s, err
+	}
+
+	return s, json.Unmarshal(data, &s.robots)
+}
+
+var gCalibrations *CalibrationStore
+var gCalibrationsOnce sync.Once
+
+// Returns the store kept at DefaultCalibrationPath, loading it on first use.
+// A store that can't be read is logged and treated as empty.
+func Calibrations() *CalibrationStore {
+	gCalibrationsOnce.Do(func() {
+		var err error
+		gCalibrations, err = OpenCalibrationStore(DefaultCalibrationPath)
+		if err != nil {
+			utilities.Logger().Warn("Could not load calibrations", "path", DefaultCalibrationPath, "err", err)
+			gCalibrations.robots = make(map[string]Calibration)
+		}
+	})
+
+	return gCalibrations
+}
+
+// Returns the calibration of the named robot, and whether one was stored.
+func (self *CalibrationStore) Get(robot string) (Calibration, bool) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	c, ok := self.robots[robot]
+	return c, ok
+}
+
+// Replaces the calibration of the named robot and saves the store.
+func (self *CalibrationStore) Put(robot string, calibration Calibration) error {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	self.robots[robot] = calibration
+	return self.save()
+}
+
+// Changes the calibration of the named robot with `fn` and saves the store.
+func (self *CalibrationStore) Update(robot string, fn func(*Calibration)) error {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	c := self.robots[robot]
+	fn(&c)
+	self.robots[robot] = c
+
+	return self.save()
+}
+
+// Removes the calibration of the named robot and saves the store.
+func (self *CalibrationStore) Delete(robot string) error {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	delete(self.robots, robot)
+	return self.save()
+}
+
+// Writes the store to a temporary file and renames it into place, so that a
+// program stopped halfway doesn't leave a truncated file behind.
+func (self *CalibrationStore) save() error {
+	data, err := json.MarshalIndent(self.robots, "", "\t")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(self.path), 0755); err != nil {
+		return err
+	}
+
+	tmp := self.path + ".tmp"
+	if err := ioutil.WriteFile(tmp, append(data, '\n'), 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, self.path)
+}
+
+// Returns the robot's calibration from the shared store, or an empty one if
+// it hasn't been calibrated yet.
+func (self Config) Calibration() Calibration {
+	c, _ := Calibrations().Get(self.Name)
+	return c
+}
+
+// Saves the robot's calibration to the shared store.
+func (self Config) SaveCalibration(calibration Calibration) error {
+	return Calibrations().Put(self.Name, calibration)
+}
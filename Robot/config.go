@@ -0,0 +1,86 @@
+// Provides a declarative description of a robot's devices and routines that
+// check the robot against it.
+//
+// A configuration lists the motors and sensors the program expects, by name:
+//
+//	config := Robot.Config{
+//		Name: "explorer",
+//		Motors: []Robot.MotorConfig{
+//			{Name: "left", Port: Motor.OutPortB, Driver: "lego-ev3-l-motor"},
+//			{Name: "right", Port: Motor.OutPortC, Driver: "lego-ev3-l-motor"},
+//		},
+//		Sensors: []Robot.SensorConfig{
+//			{Name: "line", Port: Sensors.InPort1, Type: Sensors.TypeColor, Mode: "COL-REFLECT"},
+//		},
+//	}
+//
+// Configurations can also be kept in JSON files and loaded with LoadConfig.
+// RegisterDevices makes the devices available by name through Role:
+//
+//	config.RegisterDevices()
+//	left := Robot.Role[*Motor.Motor]("left")
+package Robot
+
+import (
+	"encoding/json"
+	"io/ioutil"
+
+	"github.com/jermon/GoEV3/Motor"
+	"github.com/jermon/GoEV3/Sensors"
+)
+
+// Describes the devices of a robot.
+type Config struct {
+	Name    string         `json:"name"`
+	Motors  []MotorConfig  `json:"motors"`
+	Sensors []SensorConfig `json:"sensors"`
+}
+
+// Describes a motor the robot expects.
+type MotorConfig struct {
+	Name string        `json:"name"`
+	Port Motor.OutPort `json:"port"`
+	// Expected driver, e.g. "lego-ev3-l-motor". Any tacho motor is accepted if empty.
+	Driver string `json:"driver,omitempty"`
+	// Skips moving the motor during a self-test, for mechanisms that must not move.
+	Fixed bool `json:"fixed,omitempty"`
+	// Seconds Initialize waits for the motor to appear. Its default is used if 0.
+	Timeout float64 `json:"timeout,omitempty"`
+}
+
+// Describes a sensor the robot expects.
+type SensorConfig struct {
+	Name string         `json:"name"`
+	Port Sensors.InPort `json:"port"`
+	Type Sensors.Type   `json:"type"`
+	// Mode the sensor is used in. The current mode is kept if empty.
+	Mode string `json:"mode,omitempty"`
+	// Range of sane values of value0. When both are 0 the range is derived from the mode.
+	Min float64 `json:"min,omitempty"`
+	Max float64 `json:"max,omitempty"`
+	// Seconds Initialize waits for the sensor to appear. Its default is used if 0.
+	Timeout float64 `json:"timeout,omitempty"`
+}
+
+// Reads a configuration from a JSON file.
+func LoadConfig(filename string) (Config, error) {
+	var config Config
+
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return config, err
+	}
+
+	err = json.Unmarshal(data, &config)
+	return config, err
+}
+
+// Writes a configuration to a JSON file.
+func SaveConfig(filename string, config Config) error {
+	data, err := json.MarshalIndent(config, "", "\t")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(filename, append(data, '\n'), 0644)
+}
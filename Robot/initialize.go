@@ -0,0 +1,128 @@
+package Robot
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jermon/GoEV3/Motor"
+	"github.com/jermon/GoEV3/Sensors"
+)
+
+// Devices of a robot found by Initialize, by name.
+type Devices struct {
+	Motors map[string]*Motor.Motor
+	// Sensors of the types matching their Type, e.g. *Sensors.ColorSensor.
+	Sensors map[string]interface{}
+}
+
+// Registers every device under its name, so it can be retrieved with Role.
+func (self *Devices) Register() {
+	for name, m := range self.Motors {
+		Register(name, m)
+	}
+
+	for name, s := range self.Sensors {
+		Register(name, s)
+	}
+}
+
+// A device Initialize couldn't set up.
+type MissingDevice struct {
+	Name string
+	Kind string // "motor" or "sensor"
+	Port string
+	Err  error
+}
+
+// Error returned by Initialize when some devices couldn't be set up.
+type InitError struct {
+	Robot   string
+	Elapsed time.Duration
+	Missing []MissingDevice
+}
+
+func (self *InitError) Error() string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "%d devices of %q missing after %v", len(self.Missing), self.Robot, self.Elapsed.Round(time.Millisecond))
+	for _, m := range self.Missing {
+		fmt.Fprintf(&b, "\n  %-6s %-12s %-5s %v", m.Kind, m.Name, m.Port, m.Err)
+	}
+
+	return b.String()
+}
+
+// Finds all devices of the configuration at once, waiting up to `timeout` (or
+// the device's own Timeout) for each to appear, as their drivers may still be
+// loading at boot. Sensors are put into their configured modes.
+//
+// Returns the devices if all were found, or an *InitError listing the missing
+// ones. Since devices are looked up concurrently, startup takes as long as the
+// slowest device rather than the sum of all:
+//
+//	devices, err := Robot.Initialize(config, 10*time.Second)
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	devices.Register()
+func Initialize(config Config, timeout time.Duration) (*Devices, error) {
+	started := time.Now()
+	devices := &Devices{Motors: make(map[string]*Motor.Motor), Sensors: make(map[string]interface{})}
+	initErr := &InitError{Robot: config.Name}
+
+	var lock sync.Mutex
+	var wg sync.WaitGroup
+
+	wait := func(seconds float64) time.Duration {
+		if seconds > 0 {
+			return time.Duration(seconds * float64(time.Second))
+		}
+		return timeout
+	}
+
+	for _, m := range config.Motors {
+		wg.Add(1)
+		go func(m MotorConfig) {
+			defer wg.Done()
+
+			motor, err := openMotor(m, Motor.WithTimeout(wait(m.Timeout)))
+
+			lock.Lock()
+			defer lock.Unlock()
+			if err != nil {
+				initErr.Missing = append(initErr.Missing, MissingDevice{m.Name, "motor", string(m.Port), err})
+				return
+			}
+			devices.Motors[m.Name] = motor
+		}(m)
+	}
+
+	for _, s := range config.Sensors {
+		wg.Add(1)
+		go func(s SensorConfig) {
+			defer wg.Done()
+
+			sensor, err := openSensor(s, Sensors.WithTimeout(wait(s.Timeout)))
+
+			lock.Lock()
+			defer lock.Unlock()
+			switch {
+			case err != nil:
+				initErr.Missing = append(initErr.Missing, MissingDevice{s.Name, "sensor", string(s.Port), err})
+			case sensor != nil:
+				devices.Sensors[s.Name] = sensor
+			}
+		}(s)
+	}
+
+	wg.Wait()
+
+	if len(initErr.Missing) > 0 {
+		initErr.Elapsed = time.Since(started)
+		return nil, initErr
+	}
+
+	return devices, nil
+}
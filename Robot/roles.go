@@ -0,0 +1,138 @@
+package Robot
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/jermon/GoEV3/Motor"
+	"github.com/jermon/GoEV3/Sensors"
+	"github.com/jermon/GoEV3/utilities"
+)
+
+// Devices registered under the roles they play, such as "leftDrive" or
+// "lineSensor", so that behavior code needn't know which port they are on.
+var gRoles = make(map[string]interface{})
+var gRolesLock = &sync.RWMutex{}
+
+// Registers a device, or anything else, under a role, replacing the device
+// previously registered under it.
+func Register(role string, device interface{}) {
+	gRolesLock.Lock()
+	gRoles[role] = device
+	gRolesLock.Unlock()
+}
+
+// Removes the device registered under a role.
+func Unregister(role string) {
+	gRolesLock.Lock()
+	delete(gRoles, role)
+	gRolesLock.Unlock()
+}
+
+// Returns the device registered under a role, and whether it exists and is a T:
+//
+//	gripper, ok := Robot.Lookup[*Motor.Motor]("gripper")
+func Lookup[T any](role string) (T, bool) {
+	gRolesLock.RLock()
+	device, ok := gRoles[role]
+	gRolesLock.RUnlock()
+
+	t, ok := device.(T)
+	return t, ok
+}
+
+// Returns the device registered under a role. A missing device, or one that
+// isn't a T, is a fatal error.
+func Role[T any](role string) T {
+	t, ok := Lookup[T](role)
+	if !ok {
+		var zero T
+		utilities.Fatalf("No %T is registered as %q", zero, role)
+	}
+
+	return t
+}
+
+// Returns the registered roles, sorted.
+func Roles() []string {
+	gRolesLock.RLock()
+	roles := make([]string, 0, len(gRoles))
+	for role := range gRoles {
+		roles = append(roles, role)
+	}
+	gRolesLock.RUnlock()
+
+	sort.Strings(roles)
+	return roles
+}
+
+// Finds every device of the configuration and registers it under its name.
+// Motors are registered as *Motor.Motor and sensors as the sensor type matching
+// their Type, e.g. *Sensors.ColorSensor. Sensors of other types are skipped.
+// A missing device is a fatal error; use Initialize to handle it.
+func (self Config) RegisterDevices() {
+	for _, m := range self.Motors {
+		motor, err := openMotor(m)
+		if err != nil {
+			utilities.Fatal(err)
+		}
+
+		Register(m.Name, motor)
+	}
+
+	for _, s := range self.Sensors {
+		sensor, err := openSensor(s)
+		if err != nil {
+			utilities.Fatal(err)
+		}
+
+		if sensor != nil {
+			Register(s.Name, sensor)
+		}
+	}
+}
+
+func openMotor(config MotorConfig, opts ...Motor.Option) (*Motor.Motor, error) {
+	if config.Driver != "" {
+		opts = append(opts, Motor.WithRequiredDriver(config.Driver))
+	}
+
+	return Motor.OpenMotor(config.Port, opts...)
+}
+
+// Opens the sensor as the type matching its Type. Returns nil for sensors of other types.
+func openSensor(config SensorConfig, opts ...Sensors.Option) (interface{}, error) {
+	if config.Mode != "" {
+		opts = append(opts, Sensors.WithInitialMode(config.Mode))
+	}
+
+	switch config.Type {
+	case Sensors.TypeTouch:
+		return opened(Sensors.OpenTouchSensor(config.Port, opts...))
+	case Sensors.TypeColor:
+		return opened(Sensors.OpenColorSensor(config.Port, opts...))
+	case Sensors.TypeUltrasonic:
+		return opened(Sensors.OpenUltrasonicSensor(config.Port, opts...))
+	case Sensors.TypeInfrared:
+		return opened(Sensors.OpenInfraredSensor(config.Port, opts...))
+	case Sensors.TypeGyro:
+		return opened(Sensors.OpenGyroSensor(config.Port, opts...))
+	case Sensors.TypeNXTSound:
+		return opened(Sensors.OpenNXTSoundSensor(config.Port, opts...))
+	case Sensors.TypeNXTLight:
+		return opened(Sensors.OpenNXTLightSensor(config.Port, opts...))
+	case Sensors.TypeNXTTemperature:
+		return opened(Sensors.OpenNXTTemperatureSensor(config.Port, opts...))
+	}
+
+	return nil, nil
+}
+
+// Returns the sensor as an interface, nil rather than a nil pointer on errors.
+func opened[T any](sensor *T, err error) (interface{}, error) {
+	if err != nil {
+		return nil, err
+	}
+
+	return sensor, nil
+}
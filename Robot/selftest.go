@@ -0,0 +1,274 @@
+package Robot
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/jermon/GoEV3/Motor"
+	"github.com/jermon/GoEV3/Platform"
+	"github.com/jermon/GoEV3/Sensors"
+	"github.com/jermon/GoEV3/utilities"
+)
+
+const (
+	motorClassPath  = "/sys/class/tacho-motor"
+	sensorClassPath = "/sys/class/lego-sensor"
+)
+
+// Power at which motors are twitched, and the movement a twitch must produce.
+const (
+	twitchPower   = 30
+	twitchDegrees = 3
+	twitchTimeout = 400 * time.Millisecond
+)
+
+// Ranges of value0 in the modes of the EV3 sensors.
+var modeRanges = map[string][2]float64{
+	"TOUCH":       {0, 1},
+	"COL-REFLECT": {0, 100},
+	"COL-AMBIENT": {0, 100},
+	"COL-COLOR":   {0, 7},
+	"US-DIST-CM":  {0, 2550},
+	"US-DIST-IN":  {0, 1003},
+	"US-SI-CM":    {0, 2550},
+	"US-SI-IN":    {0, 1003},
+	"US-LISTEN":   {0, 1},
+	"IR-PROX":     {0, 100},
+	"IR-SEEK":     {-25, 25},
+	"GYRO-ANG":    {-32768, 32767},
+	"GYRO-RATE":   {-440, 440},
+	"GYRO-G&A":    {-32768, 32767},
+}
+
+// Outcome of checking a single device.
+type Result struct {
+	Name   string
+	Kind   string // "motor" or "sensor"
+	Port   string
+	Driver string
+	// Whether a device was found at the port, answered reads, and returned sane values.
+	Present  bool
+	Responds bool
+	Sane     bool
+	// Value read from a sensor, or the movement of a motor twitch in degrees.
+	Value float64
+	// Describes the first failed check; empty if all passed.
+	Problem string
+}
+
+// Reports whether all checks of the device passed.
+func (self Result) Passed() bool {
+	return self.Problem == ""
+}
+
+// Outcome of a self-test.
+type Report struct {
+	Robot   string
+	Started time.Time
+	Elapsed time.Duration
+	Results []Result
+}
+
+// Reports whether every device passed.
+func (self Report) Passed() bool {
+	return len(self.Failures()) == 0
+}
+
+// Returns the results of the devices that failed.
+func (self Report) Failures() []Result {
+	var failures []Result
+
+	for _, r := range self.Results {
+		if !r.Passed() {
+			failures = append(failures, r)
+		}
+	}
+
+	return failures
+}
+
+// Formats the report as a table, one device per line.
+func (self Report) String() string {
+	var b strings.Builder
+
+	status := "PASSED"
+	if !self.Passed() {
+		status = "FAILED"
+	}
+	fmt.Fprintf(&b, "Self-test of %q %s in %v\n", self.Robot, status, self.Elapsed.Round(time.Millisecond))
+
+	for _, r := range self.Results {
+		outcome := "ok"
+		if !r.Passed() {
+			outcome = r.Problem
+		}
+		fmt.Fprintf(&b, "  %-6s %-12s %-5s %-20s %8.1f  %s\n", r.Kind, r.Name, r.Port, r.Driver, r.Value, outcome)
+	}
+
+	return b.String()
+}
+
+// Checks that every device declared in `config` is connected, responds and
+// returns sane values. Motors that aren't fixed are twitched by a few degrees
+// back and forth; sensors are read once in their configured mode, which is
+// restored afterwards.
+func SelfTest(config Config) Report {
+	report := Report{Robot: config.Name, Started: time.Now()}
+
+	for _, m := range config.Motors {
+		report.Results = append(report.Results, testMotor(m))
+	}
+
+	for _, s := range config.Sensors {
+		report.Results = append(report.Results, testSensor(s))
+	}
+
+	report.Elapsed = time.Since(report.Started)
+	return report
+}
+
+func testMotor(config MotorConfig) Result {
+	r := Result{Name: config.Name, Kind: "motor", Port: string(config.Port)}
+
+	port, err := Motor.ParseOutPort(string(config.Port))
+	if err != nil {
+		r.Problem = err.Error()
+		return r
+	}
+	r.Port = "out" + string(port)
+
+	folder := findDevice(motorClassPath, r.Port)
+	if folder == "" {
+		r.Problem = "not connected"
+		return r
+	}
+	r.Present = true
+
+	r.Driver = utilities.ReadStringValue(folder, "driver_name")
+	if config.Driver != "" && r.Driver != config.Driver {
+		r.Problem = fmt.Sprintf("expected %s", config.Driver)
+		return r
+	}
+
+	if _, err := utilities.ReadValue[int32](folder, "position"); err != nil {
+		r.Problem = err.Error()
+		return r
+	}
+	r.Responds = true
+
+	if config.Fixed {
+		r.Sane = true
+		return r
+	}
+
+	m := Motor.FindMotor(port)
+	defer m.Stop()
+
+	forward := twitch(m, twitchPower)
+	twitch(m, -twitchPower)
+
+	r.Value = float64(forward)
+	if forward < twitchDegrees {
+		r.Problem = fmt.Sprintf("moved %d degrees, jammed or disconnected", forward)
+		return r
+	}
+	r.Sane = true
+
+	return r
+}
+
+// Runs the motor until it has moved by a few degrees or the timeout elapses,
+// and returns the movement.
+func twitch(m *Motor.Motor, power int16) int32 {
+	start := m.CurrentPosition()
+	deadline := time.Now().Add(twitchTimeout)
+
+	m.Run(power)
+	defer m.Stop()
+
+	moved := int32(0)
+	for time.Now().Before(deadline) {
+		moved = m.CurrentPosition() - start
+		if moved < 0 {
+			moved = -moved
+		}
+		if moved >= 2*twitchDegrees {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	return moved
+}
+
+func testSensor(config SensorConfig) Result {
+	r := Result{Name: config.Name, Kind: "sensor", Port: string(config.Port)}
+
+	port, err := Sensors.ParseInPort(string(config.Port))
+	if err != nil {
+		r.Problem = err.Error()
+		return r
+	}
+	r.Port = string(port)
+
+	folder := findDevice(sensorClassPath, r.Port)
+	if folder == "" {
+		r.Problem = "not connected"
+		return r
+	}
+	r.Present = true
+
+	r.Driver = utilities.ReadStringValue(folder, "driver_name")
+	if config.Type != "" && r.Driver != string(config.Type) {
+		r.Problem = fmt.Sprintf("expected %s", config.Type)
+		return r
+	}
+
+	mode := utilities.ReadStringValue(folder, "mode")
+	if config.Mode != "" && config.Mode != mode {
+		defer utilities.WriteStringValue(folder, "mode", mode)
+		utilities.WriteStringValue(folder, "mode", config.Mode)
+		mode = config.Mode
+	}
+
+	value, err := utilities.ReadValue[float64](folder, "value0")
+	if err != nil {
+		r.Problem = err.Error()
+		return r
+	}
+	r.Responds = true
+	r.Value = value
+
+	min, max := config.Min, config.Max
+	if min == 0 && max == 0 {
+		bounds, ok := modeRanges[mode]
+		if !ok {
+			r.Sane = true
+			return r
+		}
+		min, max = bounds[0], bounds[1]
+	}
+
+	if value < min || value > max {
+		r.Problem = fmt.Sprintf("read %v, expected %v to %v", value, min, max)
+		return r
+	}
+	r.Sane = true
+
+	return r
+}
+
+// Returns the folder of the device of the given class connected to `port`, or
+// an empty string if there is none.
+func findDevice(class string, port string) string {
+	for _, name := range utilities.ListDir(class) {
+		folder := path.Join(class, name)
+		if Platform.Current().Matches(port, utilities.ReadStringValue(folder, "address")) {
+			return folder
+		}
+	}
+
+	return ""
+}
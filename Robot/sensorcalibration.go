@@ -0,0 +1,151 @@
+package Robot
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jermon/GoEV3/Drive"
+	"github.com/jermon/GoEV3/Motor"
+	"github.com/jermon/GoEV3/Sensors"
+)
+
+// Returns the key a sensor's calibration is kept under by SaveSensorCalibrations,
+// e.g. "in3:lego-ev3-color", so that it is only applied again to the same
+// kind of sensor on the same port, whatever the sensor is named.
+func SensorKey(port Sensors.InPort, driver Sensors.Type) string {
+	return fmt.Sprintf("%s:%s", Sensors.CanonicalInPort(string(port)), string(driver))
+}
+
+// Returns the key of the configuration's sensor with the given name, and
+// whether there is one.
+func (self Config) sensorKey(name string) (string, bool) {
+	for _, s := range self.Sensors {
+		if s.Name == name {
+			return SensorKey(s.Port, s.Type), true
+		}
+	}
+
+	return "", false
+}
+
+// Saves the white and black references of the color sensors registered under
+// the names of the configuration's sensors, e.g. by RegisterDevices, to the
+// shared store. Sensors not calibrated both ways are skipped.
+//
+//	config.RegisterDevices()
+//	line := Robot.Role[*Sensors.ColorSensor]("line")
+//	line.CalibrateWhite()
+//	line.CalibrateBlack()
+//	config.SaveSensorCalibrations()
+func (self Config) SaveSensorCalibrations() error {
+	return Calibrations().Update(self.Name, func(c *Calibration) {
+		for _, s := range self.Sensors {
+			sensor, ok := Lookup[*Sensors.ColorSensor](s.Name)
+			if !ok {
+				continue
+			}
+
+			calibration, complete := sensor.Calibration()
+			if !complete {
+				continue
+			}
+
+			if c.Color == nil {
+				c.Color = make(map[string]Sensors.ColorCalibration)
+			}
+			c.Color[SensorKey(s.Port, s.Type)] = calibration
+		}
+	})
+}
+
+// Applies the references saved with SaveSensorCalibrations to the registered
+// color sensors, e.g. at startup after RegisterDevices, so that the robot
+// needn't be calibrated every boot. Returns the names of the sensors calibrated.
+func (self Config) LoadSensorCalibrations() []string {
+	c := self.Calibration()
+
+	var loaded []string
+	for _, s := range self.Sensors {
+		sensor, ok := Lookup[*Sensors.ColorSensor](s.Name)
+		if !ok {
+			continue
+		}
+
+		if calibration, ok := c.Color[SensorKey(s.Port, s.Type)]; ok {
+			sensor.SetCalibration(calibration)
+			loaded = append(loaded, s.Name)
+		}
+	}
+
+	return loaded
+}
+
+// Measures the offset and drift of a gyro sensor at rest over `duration`. The
+// robot must be still until it returns.
+func MeasureGyroCalibration(sensor *Sensors.GyroSensor, duration time.Duration) GyroCalibration {
+	start := time.Now()
+	first := float64(sensor.ReadAngle())
+	time.Sleep(duration)
+	last := float64(sensor.ReadAngle())
+
+	return GyroCalibration{Offset: first, Drift: (last - first) / time.Since(start).Seconds()}
+}
+
+// Corrects an angle read `elapsed` after the calibration was measured.
+func (self GyroCalibration) Apply(angle float64, elapsed time.Duration) float64 {
+	return angle - self.Offset - self.Drift*elapsed.Seconds()
+}
+
+// Saves the calibration of the configuration's gyro sensor with the given name
+// to the shared store, keyed by its port and driver. An entry kept under the
+// sensor's name, as earlier versions saved it, is replaced.
+func (self Config) SaveGyroCalibration(name string, calibration GyroCalibration) error {
+	key, ok := self.sensorKey(name)
+	if !ok {
+		return fmt.Errorf("no sensor named %q in the configuration of %q", name, self.Name)
+	}
+
+	return Calibrations().Update(self.Name, func(c *Calibration) {
+		if c.Gyro == nil {
+			c.Gyro = make(map[string]GyroCalibration)
+		}
+		c.Gyro[key] = calibration
+		delete(c.Gyro, name)
+	})
+}
+
+// Returns the calibration of the configuration's gyro sensor with the given
+// name saved with SaveGyroCalibration, or else kept under the sensor's name,
+// and whether there is one.
+func (self Config) GyroCalibration(name string) (GyroCalibration, bool) {
+	gyro := self.Calibration().Gyro
+
+	if key, ok := self.sensorKey(name); ok {
+		if c, ok := gyro[key]; ok {
+			return c, true
+		}
+	}
+
+	c, ok := gyro[name]
+	return c, ok
+}
+
+// Saves the measured dimensions of the robot's drive base to the shared store.
+func (self Config) SaveGeometry(geometry Geometry) error {
+	return Calibrations().Update(self.Name, func(c *Calibration) {
+		c.Geometry = geometry
+	})
+}
+
+// Provides access to a drive base with motors at the given ports and the
+// measured dimensions, or the given defaults for those that weren't measured.
+func (self Geometry) NewDriveBase(left Motor.OutPort, right Motor.OutPort, wheelDiameter float64, trackWidth float64) *Drive.DriveBase {
+	if self.WheelDiameter > 0 {
+		wheelDiameter = self.WheelDiameter
+	}
+	if self.TrackWidth > 0 {
+		trackWidth = self.TrackWidth
+	}
+
+	return Drive.NewDriveBase(left, right, wheelDiameter, trackWidth)
+}
@@ -0,0 +1,99 @@
+// Provides an emergency stop that halts every motor the program has started
+// and puts sensors back into the modes they were found in, an orderly
+// shutdown for programs that are about to exit, a watchdog detecting jammed
+// and back-driven motors, and a guard against tipping over.
+//
+// A typical program arms it once at the beginning of main:
+//
+//	Safety.HandleSignals()
+//	defer Safety.Shutdown()
+//	defer Safety.Recover()
+//
+// so that pressing Ctrl-C, killing the program or a panic never leaves the
+// robot running.
+package Safety
+
+import (
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+
+	"github.com/jermon/GoEV3/Motor"
+	"github.com/jermon/GoEV3/Sensors"
+	"github.com/jermon/GoEV3/utilities"
+)
+
+var gHooks []func()
+var gHooksLock = &sync.Mutex{}
+var gSignalsOnce sync.Once
+
+// Registers a function invoked by EmergencyStop after the motors have been
+// stopped, e.g. to turn off LEDs or close a connection.
+func OnEmergencyStop(fn func()) {
+	gHooksLock.Lock()
+	gHooks = append(gHooks, fn)
+	gHooksLock.Unlock()
+}
+
+// Stops every motor started by the program, restores the sensor modes and runs
+// the registered hooks. Safe to call any number of times from any goroutine.
+func EmergencyStop() {
+	Motor.StopAll()
+	Sensors.RestoreModes()
+
+	gHooksLock.Lock()
+	hooks := append(([]func())(nil), gHooks...)
+	gHooksLock.Unlock()
+
+	for _, fn := range hooks {
+		// A failing hook must not prevent the others from running.
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					utilities.Logger().Error("safety hook panicked", "panic", r)
+				}
+			}()
+			fn()
+		}()
+	}
+}
+
+// Shuts down and exits when the program receives SIGINT or SIGTERM. Calling
+// it more than once has no further effect.
+func HandleSignals() {
+	gSignalsOnce.Do(func() {
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+		go func() {
+			s := <-signals
+			Shutdown()
+
+			if s == os.Interrupt {
+				os.Exit(130)
+			}
+			os.Exit(143)
+		}()
+	})
+}
+
+// Performs an emergency stop if the calling goroutine is panicking, then
+// continues panicking. Must be deferred directly:
+//
+//	defer Safety.Recover()
+func Recover() {
+	if r := recover(); r != nil {
+		EmergencyStop()
+		panic(r)
+	}
+}
+
+// Runs `fn` in a new goroutine guarded by Recover. Panics in goroutines can't
+// be recovered by main, so goroutines driving motors should be started this way.
+func Go(fn func()) {
+	go func() {
+		defer Recover()
+		fn()
+	}()
+}
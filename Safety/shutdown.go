@@ -0,0 +1,74 @@
+package Safety
+
+import (
+	"sync"
+
+	"github.com/jermon/GoEV3/Motor"
+	"github.com/jermon/GoEV3/utilities"
+)
+
+// Stages of Shutdown. Hooks run stage by stage in the order listed, and in
+// registration order within a stage.
+type Phase int
+
+const (
+	// Cancels subscriptions, pollers and other background tasks reading devices.
+	StopTasks Phase = iota
+	// Flushes and closes data logs and recordings.
+	FlushData
+	// Closes cached device files and network connections.
+	CloseFiles
+
+	phaseCount
+)
+
+var gShutdownHooks [phaseCount][]func()
+var gShutdownLock = &sync.Mutex{}
+var gShutdownOnce sync.Once
+
+// Registers a function to be run by Shutdown during the given phase.
+func OnShutdown(phase Phase, fn func()) {
+	gShutdownLock.Lock()
+	gShutdownHooks[phase] = append(gShutdownHooks[phase], fn)
+	gShutdownLock.Unlock()
+}
+
+// Stops the motors and cleans up so the program can exit: the StopTasks hooks
+// run first, so that no control loop is left to restart a motor, then the
+// emergency stop is performed and every motor the program opened is left
+// coasting, then the FlushData and CloseFiles hooks run in that order, and
+// finally the device files kept open are closed. A panicking hook doesn't
+// prevent the rest from running. Only the first call has an effect; later
+// calls wait for it to finish.
+func Shutdown() {
+	gShutdownOnce.Do(func() {
+		gShutdownLock.Lock()
+		hooks := gShutdownHooks
+		gShutdownLock.Unlock()
+
+		runShutdownHooks(hooks[StopTasks])
+
+		EmergencyStop()
+		Motor.CoastAll()
+
+		runShutdownHooks(hooks[FlushData])
+		runShutdownHooks(hooks[CloseFiles])
+
+		utilities.ReleaseAllFiles()
+	})
+}
+
+// Runs the hooks of a phase in order.
+func runShutdownHooks(hooks []func()) {
+	for _, fn := range hooks {
+		// A failing hook must not prevent the others from running.
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					utilities.Logger().Error("shutdown hook panicked", "panic", r)
+				}
+			}()
+			fn()
+		}()
+	}
+}
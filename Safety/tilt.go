@@ -0,0 +1,174 @@
+package Safety
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/jermon/GoEV3/Control"
+	"github.com/jermon/GoEV3/Drive"
+	"github.com/jermon/GoEV3/Motor"
+	"github.com/jermon/GoEV3/Sensors"
+)
+
+// Degrees the tilt must fall back below the limit before the guard rearms.
+const tiltHysteresis = 5
+
+// Reports the robot tipping over or righting itself.
+type TiltEvent struct {
+	Time time.Time
+	// Tilt in degrees, positive when leaning forward.
+	Angle float64
+	// False when the tilt exceeds the limit, true when it is back within it.
+	Recovered bool
+}
+
+func (self TiltEvent) String() string {
+	if self.Recovered {
+		return fmt.Sprintf("tilt back to %.1f degrees", self.Angle)
+	}
+
+	return fmt.Sprintf("tipping at %.1f degrees", self.Angle)
+}
+
+// Returns the tilt measured by a gyro sensor mounted to turn with the robot's
+// pitch, relative to its angle now, which must be upright. Negate it if the
+// gyro reads negative angles when the robot leans forward.
+func GyroTilt(gyro *Sensors.GyroSensor) func() float64 {
+	upright := gyro.Angle().Degrees()
+	return func() float64 { return gyro.Angle().Degrees() - upright }
+}
+
+// Returns the forward tilt measured by an accelerometer, from the gravity
+// `read` returns along its axes, Z pointing up and X forward when upright.
+// Sideways tilt counts as well, with the sign of the forward one.
+func AccelerometerTilt(read func() (x float64, y float64, z float64)) func() float64 {
+	return func() float64 {
+		pitch, roll := Control.TiltFromAcceleration(read())
+		// Leaning forward points X down, which is a negative pitch.
+		pitch = -pitch
+
+		if math.Abs(roll) > math.Abs(pitch) {
+			return math.Copysign(math.Abs(roll), pitch)
+		}
+		return pitch
+	}
+}
+
+// Detects the robot tipping beyond a set angle and cuts the motors right
+// away, or drives the wheels under the fall to catch it, protecting tall
+// robots from falling over:
+//
+//	guard := Safety.NewTiltGuard(Safety.GyroTilt(gyro), 25)
+//	guard.SetCatch(base, 100, 300*time.Millisecond)
+//	go guard.Run(stop, 10*time.Millisecond)
+type TiltGuard struct {
+	lock sync.Mutex
+
+	tilt  func() float64
+	limit float64
+
+	base          *Drive.DriveBase
+	catchSpeed    int16
+	catchDuration time.Duration
+
+	tipped bool
+	onTilt []func(TiltEvent)
+}
+
+// Creates a guard reacting when `tilt` exceeds `limit` degrees either way.
+func NewTiltGuard(tilt func() float64, limit float64) *TiltGuard {
+	g := new(TiltGuard)
+	g.tilt = tilt
+	g.limit = math.Abs(limit)
+
+	return g
+}
+
+// Sets the tilt in degrees beyond which the guard reacts.
+func (self *TiltGuard) SetLimit(limit float64) {
+	self.lock.Lock()
+	self.limit = math.Abs(limit)
+	self.lock.Unlock()
+}
+
+// Makes the guard drive `base` at `speed` towards the fall for `duration`,
+// which puts the wheels back under the robot, before stopping the motors.
+// Without it, and by default, the motors are stopped right away.
+func (self *TiltGuard) SetCatch(base *Drive.DriveBase, speed int16, duration time.Duration) {
+	self.lock.Lock()
+	self.base, self.catchSpeed, self.catchDuration = base, speed, duration
+	self.lock.Unlock()
+}
+
+// Registers a callback invoked when the robot tips over the limit, after the
+// guard reacted, and when it is back within it.
+func (self *TiltGuard) OnTilt(fn func(TiltEvent)) {
+	self.lock.Lock()
+	self.onTilt = append(self.onTilt, fn)
+	self.lock.Unlock()
+}
+
+// Reports whether the robot is tilted beyond the limit.
+func (self *TiltGuard) Tipped() bool {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	return self.tipped
+}
+
+// Measures the tilt once and reacts if it exceeds the limit.
+func (self *TiltGuard) Check() {
+	angle := self.tilt()
+
+	self.lock.Lock()
+	was := self.tipped
+	switch {
+	case !was && math.Abs(angle) > self.limit:
+		self.tipped = true
+	case was && math.Abs(angle) < self.limit-tiltHysteresis:
+		self.tipped = false
+	}
+	tipped := self.tipped
+	base, speed, duration := self.base, self.catchSpeed, self.catchDuration
+	callbacks := append(([]func(TiltEvent))(nil), self.onTilt...)
+	self.lock.Unlock()
+
+	if tipped == was {
+		return
+	}
+
+	if tipped {
+		if base != nil && duration > 0 {
+			if angle < 0 {
+				speed = -speed
+			}
+			base.Tank(speed, speed)
+			time.Sleep(duration)
+		}
+		Motor.StopAll()
+	}
+
+	e := TiltEvent{Time: time.Now(), Angle: angle, Recovered: !tipped}
+	for _, fn := range callbacks {
+		fn(e)
+	}
+}
+
+// Checks the tilt every `interval` until a value is sent to `stop`. Falls are
+// quick: 10 to 20 milliseconds suit most robots.
+func (self *TiltGuard) Run(stop <-chan bool, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		self.Check()
+
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
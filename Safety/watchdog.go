@@ -0,0 +1,257 @@
+package Safety
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jermon/GoEV3/Motor"
+)
+
+// Kinds of mechanical failures detected by a Watchdog.
+type Fault int
+
+const (
+	// The motor is powered but its encoder doesn't move: it is jammed, stalled
+	// against an obstacle or its cable is loose.
+	Jammed Fault = iota
+	// The motor isn't powered but its encoder moves: something is pushing or
+	// back-driving it.
+	BackDriven
+)
+
+func (self Fault) String() string {
+	switch self {
+	case Jammed:
+		return "jammed"
+	case BackDriven:
+		return "back-driven"
+	default:
+		return "unknown"
+	}
+}
+
+// Reports a fault starting or clearing.
+type FaultEvent struct {
+	Time  time.Time
+	Port  Motor.OutPort
+	Fault Fault
+	// False when the fault starts, true when the motor behaves again.
+	Cleared bool
+	// Duty cycle the motor was running at, 0 when stopped.
+	Power int16
+	// Encoder movement in degrees over the watchdog's window.
+	Moved int32
+}
+
+func (self FaultEvent) String() string {
+	if self.Cleared {
+		return fmt.Sprintf("motor %v no longer %v", self.Port, self.Fault)
+	}
+
+	return fmt.Sprintf("motor %v %v (power %d, moved %d degrees)", self.Port, self.Fault, self.Power, self.Moved)
+}
+
+type motorSample struct {
+	time     time.Time
+	position int32
+	power    int16
+	running  bool
+}
+
+type watchedMotor struct {
+	motor   *Motor.Motor
+	samples []motorSample
+	faults  map[Fault]bool
+}
+
+// Compares the commanded and measured motion of motors and reports the ones
+// that don't move although powered, or move although stopped.
+type Watchdog struct {
+	lock sync.Mutex
+
+	motors      []*watchedMotor
+	minPower    int16
+	minMovement int32
+	window      time.Duration
+
+	onFault []func(FaultEvent)
+	events  chan FaultEvent
+}
+
+// Creates a watchdog for the given motors. By default a motor is jammed when
+// it runs at 15% power or more without moving 5 degrees within half a second,
+// and back-driven when it moves 5 degrees or more within half a second while stopped.
+func NewWatchdog(motors ...*Motor.Motor) *Watchdog {
+	w := new(Watchdog)
+	w.minPower = 15
+	w.minMovement = 5
+	w.window = 500 * time.Millisecond
+
+	for _, m := range motors {
+		w.Watch(m)
+	}
+
+	return w
+}
+
+// Adds a motor to watch.
+func (self *Watchdog) Watch(m *Motor.Motor) {
+	self.lock.Lock()
+	self.motors = append(self.motors, &watchedMotor{motor: m, faults: make(map[Fault]bool)})
+	self.lock.Unlock()
+}
+
+// Sets the minimum power at which a motor is expected to move, the movement in
+// degrees that counts as moving, and the time span over which it is measured.
+func (self *Watchdog) SetThresholds(minPower int16, minMovement int32, window time.Duration) {
+	self.lock.Lock()
+	self.minPower, self.minMovement, self.window = minPower, minMovement, window
+	self.lock.Unlock()
+}
+
+// Registers a callback invoked whenever a fault starts or clears, e.g. to call EmergencyStop.
+func (self *Watchdog) OnFault(fn func(FaultEvent)) {
+	self.lock.Lock()
+	self.onFault = append(self.onFault, fn)
+	self.lock.Unlock()
+}
+
+// Returns a channel delivering all fault events. Events are dropped while the
+// channel's buffer is full.
+func (self *Watchdog) Events() <-chan FaultEvent {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	if self.events == nil {
+		self.events = make(chan FaultEvent, 16)
+	}
+
+	return self.events
+}
+
+// Reports whether the motor at the given port currently has the given fault.
+func (self *Watchdog) Faulted(port Motor.OutPort, fault Fault) bool {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	for _, w := range self.motors {
+		if w.motor.Port() == port {
+			return w.faults[fault]
+		}
+	}
+
+	return false
+}
+
+// Samples every motor once and reports the faults that started or cleared.
+func (self *Watchdog) Check() {
+	self.lock.Lock()
+	motors := append(([]*watchedMotor)(nil), self.motors...)
+	self.lock.Unlock()
+
+	for _, w := range motors {
+		// Sample outside the lock; sysfs reads are slow.
+		s := motorSample{
+			time:     time.Now(),
+			position: w.motor.CurrentPosition(),
+			power:    w.motor.CurrentPower(),
+			running:  strings.Contains(w.motor.GetState(), "running"),
+		}
+
+		for _, e := range self.update(w, s) {
+			self.emit(e)
+		}
+	}
+}
+
+func (self *Watchdog) update(w *watchedMotor, s motorSample) []FaultEvent {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	w.samples = append(w.samples, s)
+
+	// Keep the newest sample at least a window old as the reference.
+	cutoff := s.time.Add(-self.window)
+	i := 0
+	for i+1 < len(w.samples) && !w.samples[i+1].time.After(cutoff) {
+		i++
+	}
+	w.samples = w.samples[i:]
+
+	first := w.samples[0]
+	if s.time.Sub(first.time) < self.window {
+		return nil
+	}
+
+	moved := s.position - first.position
+	if moved < 0 {
+		moved = -moved
+	}
+
+	powered, stopped := true, true
+	for _, sample := range w.samples {
+		power := sample.power
+		if power < 0 {
+			power = -power
+		}
+		if !sample.running || power < self.minPower {
+			powered = false
+		}
+		if sample.running {
+			stopped = false
+		}
+	}
+
+	var events []FaultEvent
+	set := func(fault Fault, active bool) {
+		if w.faults[fault] == active {
+			return
+		}
+		w.faults[fault] = active
+
+		e := FaultEvent{Time: s.time, Port: w.motor.Port(), Fault: fault, Cleared: !active, Moved: moved}
+		if s.running {
+			e.Power = s.power
+		}
+		events = append(events, e)
+	}
+
+	set(Jammed, powered && moved < self.minMovement)
+	set(BackDriven, stopped && moved >= self.minMovement)
+
+	return events
+}
+
+func (self *Watchdog) emit(e FaultEvent) {
+	self.lock.Lock()
+	callbacks := append(([]func(FaultEvent))(nil), self.onFault...)
+	if self.events != nil {
+		select {
+		case self.events <- e:
+		default:
+		}
+	}
+	self.lock.Unlock()
+
+	for _, fn := range callbacks {
+		fn(e)
+	}
+}
+
+// Checks the motors every `interval` until a value is sent to `stop`.
+func (self *Watchdog) Run(stop <-chan bool, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		self.Check()
+
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package Script
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/jermon/GoEV3/Drive"
+	"github.com/jermon/GoEV3/Motor"
+	"github.com/jermon/GoEV3/Robot"
+	"github.com/jermon/GoEV3/Sensors"
+	"go.starlark.net/starlark"
+	"go.starlark.net/starlarkstruct"
+)
+
+// Makes a device available to scripts under `name`:
+//
+//	*Motor.Motor              run(speed), stop(), position(), degrees(), speed(), reset()
+//	*Drive.DriveBase          tank(left, right), steer(steering, speed), stop(),
+//	                          straight(cm, speed), turn(degrees, speed), distance(), rotation()
+//	*Sensors.TouchSensor      pressed()
+//	*Sensors.ColorSensor      color(), reflected(), ambient()
+//	*Sensors.UltrasonicSensor distance()
+//	*Sensors.InfraredSensor   proximity()
+//	*Sensors.GyroSensor       angle(), rate()
+//	func() float64            called without arguments, returns the number
+//
+// Speeds are passed to Motor.Run as is. Motors and drive bases are stopped by
+// Stop. Starlark values, such as a *starlark.Builtin, are defined unchanged.
+func (self *Interpreter) Bind(name string, device interface{}) error {
+	var members starlark.StringDict
+
+	switch d := device.(type) {
+	case *Motor.Motor:
+		members = starlark.StringDict{
+			"run": NumberFunc("run", 1, func(args []float64) (starlark.Value, error) {
+				return starlark.None, d.TryRun(int16(args[0]))
+			}),
+			"stop":     action("stop", d.Stop),
+			"position": reading("position", func() float64 { return float64(d.CurrentPosition()) }),
+			"degrees":  reading("degrees", d.CurrentDegrees),
+			"speed":    reading("speed", func() float64 { return float64(d.CurrentSpeed()) }),
+			"reset":    action("reset", func() { d.InitializePosition(0) }),
+		}
+		self.OnStop(d.Stop)
+
+	case *Drive.DriveBase:
+		members = starlark.StringDict{
+			"tank": NumberFunc("tank", 2, func(args []float64) (starlark.Value, error) {
+				d.Tank(int16(args[0]), int16(args[1]))
+				return starlark.None, nil
+			}),
+			"steer": NumberFunc("steer", 2, func(args []float64) (starlark.Value, error) {
+				d.Steer(args[0], int16(args[1]))
+				return starlark.None, nil
+			}),
+			"stop": action("stop", d.Stop),
+			// Drives `cm` centimeters, backwards if negative, and waits until done.
+			"straight": NumberFunc("straight", 2, func(args []float64) (starlark.Value, error) {
+				speed := int16(math.Abs(args[1]))
+				if args[0] < 0 {
+					speed = -speed
+				}
+				d.OnForDistance(speed, math.Abs(args[0]), true, true)
+				return starlark.None, nil
+			}),
+			// Turns in place by `degrees`, counter-clockwise if positive, and waits until done.
+			"turn": NumberFunc("turn", 2, func(args []float64) (starlark.Value, error) {
+				arc := math.Abs(args[0]) * math.Pi / 180 * d.TrackWidth() / 2
+				speed := int16(math.Abs(args[1]))
+				if args[0] < 0 {
+					speed = -speed
+				}
+				d.OnForDegrees(-speed, speed, d.DistanceToDegrees(arc), true, true)
+				return starlark.None, nil
+			}),
+			"distance": reading("distance", d.Distance),
+			"rotation": reading("rotation", func() float64 { return d.Rotation().Degrees() }),
+		}
+		self.OnStop(d.Stop)
+
+	case *Sensors.TouchSensor:
+		members = starlark.StringDict{
+			"pressed": starlark.NewBuiltin("pressed", func(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+				if err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 0); err != nil {
+					return nil, err
+				}
+				return starlark.Bool(d.IsPressed()), nil
+			}),
+		}
+
+	case *Sensors.ColorSensor:
+		members = starlark.StringDict{
+			"color":     reading("color", func() float64 { return float64(d.ReadColor()) }),
+			"reflected": reading("reflected", func() float64 { return float64(d.ReadReflectedLightIntensity()) }),
+			"ambient":   reading("ambient", func() float64 { return float64(d.ReadAmbientLightIntensity()) }),
+		}
+
+	case *Sensors.UltrasonicSensor:
+		members = starlark.StringDict{
+			"distance": reading("distance", func() float64 { return d.Distance().Centimeters() }),
+		}
+
+	case *Sensors.InfraredSensor:
+		members = starlark.StringDict{
+			"proximity": reading("proximity", func() float64 { return float64(d.ReadProximity()) }),
+		}
+
+	case *Sensors.GyroSensor:
+		members = starlark.StringDict{
+			"angle": reading("angle", func() float64 { return d.Angle().Degrees() }),
+			"rate":  reading("rate", func() float64 { return d.Rate().DegreesPerSecond() }),
+		}
+
+	case func() float64:
+		self.Define(name, reading(name, d))
+		return nil
+
+	case starlark.Value:
+		self.Define(name, d)
+		return nil
+
+	default:
+		return fmt.Errorf("script: cannot bind %T", device)
+	}
+
+	self.Define(name, &starlarkstruct.Module{Name: name, Members: members})
+	return nil
+}
+
+// Binds every device registered with Robot.Register under its role, skipping
+// those of types Bind doesn't support.
+func (self *Interpreter) BindRoles() {
+	for _, role := range Robot.Roles() {
+		if device, ok := Robot.Lookup[interface{}](role); ok {
+			self.Bind(role, device)
+		}
+	}
+}
+
+func action(name string, fn func()) *starlark.Builtin {
+	return starlark.NewBuiltin(name, func(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+		if err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 0); err != nil {
+			return nil, err
+		}
+
+		fn()
+		return starlark.None, nil
+	})
+}
+
+func reading(name string, fn func() float64) *starlark.Builtin {
+	return starlark.NewBuiltin(name, func(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+		if err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 0); err != nil {
+			return nil, err
+		}
+
+		return starlark.Float(fn()), nil
+	})
+}
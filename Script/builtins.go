@@ -0,0 +1,61 @@
+package Script
+
+import (
+	"fmt"
+	"time"
+
+	"go.starlark.net/starlark"
+)
+
+// Returns a built-in function taking `arity` numbers, integers or floats.
+func NumberFunc(name string, arity int, fn func(args []float64) (starlark.Value, error)) *starlark.Builtin {
+	return starlark.NewBuiltin(name, func(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+		if len(kwargs) > 0 {
+			return nil, fmt.Errorf("%s: unexpected keyword arguments", name)
+		}
+		if len(args) != arity {
+			return nil, fmt.Errorf("%s takes %d arguments, got %d", name, arity, len(args))
+		}
+
+		numbers := make([]float64, len(args))
+		for i, arg := range args {
+			n, ok := starlark.AsFloat(arg)
+			if !ok {
+				return nil, fmt.Errorf("%s: argument %d must be a number, not %s", name, i+1, arg.Type())
+			}
+			numbers[i] = n
+		}
+
+		return fn(numbers)
+	})
+}
+
+func (self *Interpreter) defineBuiltins() {
+	// Waits for the given number of seconds, or until the script is stopped.
+	self.globals["sleep"] = starlark.NewBuiltin("sleep", func(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+		var seconds starlark.Value
+		if err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 1, &seconds); err != nil {
+			return nil, err
+		}
+		n, ok := starlark.AsFloat(seconds)
+		if !ok {
+			return nil, fmt.Errorf("sleep: argument must be a number, not %s", seconds.Type())
+		}
+
+		select {
+		case <-time.After(time.Duration(n * float64(time.Second))):
+			return starlark.None, nil
+		case <-thread.Local(stopLocal).(chan bool):
+			return nil, ErrStopped
+		}
+	})
+
+	// Returns the number of seconds elapsed since an arbitrary point, for timing.
+	start := time.Now()
+	self.globals["clock"] = starlark.NewBuiltin("clock", func(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+		if err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 0); err != nil {
+			return nil, err
+		}
+		return starlark.Float(time.Since(start).Seconds()), nil
+	})
+}
@@ -0,0 +1,209 @@
+// Runs robot programs written in Starlark, a small dialect of Python, with
+// the interpreter of go.starlark.net, so missions can be edited on the SD
+// card and rerun without cross-compiling and redeploying the Go binary:
+//
+//	# mission.star
+//	def approach(limit):
+//	    drive.tank(40, 40)
+//	    while sonar.distance() > limit:
+//	        sleep(0.02)
+//	    drive.stop()
+//
+//	for i in range(4):
+//	    approach(15)
+//	    drive.turn(90, 30)
+//
+// The Go program binds devices under names and runs the file:
+//
+//	s := Script.New()
+//	s.Bind("drive", base)
+//	s.Bind("sonar", sonar)
+//	if err := s.RunFile("/home/robot/mission.star"); err != nil {
+//		log.Print(err)
+//	}
+//
+// Besides Starlark's built-in functions, scripts have sleep(seconds) and
+// clock(), the seconds elapsed since the interpreter was created. Unlike
+// standard Starlark, while loops, and if and for statements at the top level
+// are allowed, since robot programs mostly consist of them, and globals may
+// be reassigned.
+//
+// Watch reruns a script whenever its file changes, for tuning a mission while
+// the robot sits on the table.
+package Script
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+
+	"go.starlark.net/starlark"
+	"go.starlark.net/syntax"
+)
+
+// Returned when a script is interrupted by Stop.
+var ErrStopped = errors.New("script: stopped")
+
+// The dialect scripts are written in.
+var fileOptions = &syntax.FileOptions{
+	While:           true,
+	TopLevelControl: true,
+	GlobalReassign:  true,
+}
+
+// Key of the stop channel of a run among its thread's locals.
+const stopLocal = "stop"
+
+// Runs scripts sharing a set of global values.
+type Interpreter struct {
+	lock    sync.Mutex
+	globals starlark.StringDict
+	onStop  []func()
+	stop    chan bool
+	threads map[*starlark.Thread]bool
+	out     func(line string)
+}
+
+// Creates an interpreter with the built-in functions defined.
+func New() *Interpreter {
+	i := new(Interpreter)
+	i.globals = make(starlark.StringDict)
+	i.stop = make(chan bool)
+	i.threads = make(map[*starlark.Thread]bool)
+	i.out = func(line string) { fmt.Println(line) }
+	i.defineBuiltins()
+
+	return i
+}
+
+// Defines a global value, such as a *starlark.Builtin or a module.
+func (self *Interpreter) Define(name string, value starlark.Value) {
+	self.lock.Lock()
+	self.globals[name] = value
+	self.lock.Unlock()
+}
+
+// Returns a global value, and whether it is defined.
+func (self *Interpreter) Global(name string) (starlark.Value, bool) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	v, ok := self.globals[name]
+	return v, ok
+}
+
+// Sets the function receiving the lines printed by scripts. They go to
+// standard output by default.
+func (self *Interpreter) SetOutput(fn func(line string)) {
+	self.lock.Lock()
+	self.out = fn
+	self.lock.Unlock()
+}
+
+// Registers a function called by Stop, such as one stopping motors.
+func (self *Interpreter) OnStop(fn func()) {
+	self.lock.Lock()
+	self.onStop = append(self.onStop, fn)
+	self.lock.Unlock()
+}
+
+// Interrupts the scripts running, which then return ErrStopped, and calls the
+// functions registered with OnStop. Later runs are unaffected.
+func (self *Interpreter) Stop() {
+	self.lock.Lock()
+	close(self.stop)
+	self.stop = make(chan bool)
+	for thread := range self.threads {
+		thread.Cancel("stopped")
+	}
+	hooks := append([]func(){}, self.onStop...)
+	self.lock.Unlock()
+
+	for _, fn := range hooks {
+		fn()
+	}
+}
+
+// Runs `fn` in a new thread named `name`, interrupted by Stop, and returns
+// ErrStopped if it was.
+func (self *Interpreter) do(name string, fn func(thread *starlark.Thread, globals starlark.StringDict) error) error {
+	thread := &starlark.Thread{Name: name}
+	thread.Print = func(_ *starlark.Thread, msg string) {
+		self.lock.Lock()
+		out := self.out
+		self.lock.Unlock()
+
+		out(msg)
+	}
+
+	self.lock.Lock()
+	stop := self.stop
+	thread.SetLocal(stopLocal, stop)
+	self.threads[thread] = true
+	globals := make(starlark.StringDict, len(self.globals))
+	for k, v := range self.globals {
+		globals[k] = v
+	}
+	self.lock.Unlock()
+
+	defer func() {
+		self.lock.Lock()
+		delete(self.threads, thread)
+		self.lock.Unlock()
+	}()
+
+	err := fn(thread, globals)
+
+	select {
+	case <-stop:
+		return ErrStopped
+	default:
+		return err
+	}
+}
+
+// Runs the script in the given file, read anew on every call.
+func (self *Interpreter) RunFile(filename string) error {
+	source, err := os.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+
+	return self.Run(filename, string(source))
+}
+
+// Runs a script, whose functions and other globals are then defined for
+// later scripts and Call. `filename` is only used in error messages. Errors
+// of the script are a *starlark.EvalError, whose Backtrace locates them, or
+// syntax errors.
+func (self *Interpreter) Run(filename string, source string) error {
+	return self.do(filename, func(thread *starlark.Thread, globals starlark.StringDict) error {
+		defined, err := starlark.ExecFileOptions(fileOptions, thread, filename, source, globals)
+
+		self.lock.Lock()
+		for k, v := range defined {
+			self.globals[k] = v
+		}
+		self.lock.Unlock()
+
+		return err
+	})
+}
+
+// Calls a global function, such as one defined by a script run before.
+func (self *Interpreter) Call(name string, args ...starlark.Value) (starlark.Value, error) {
+	fn, ok := self.Global(name)
+	if !ok {
+		return nil, fmt.Errorf("script: %s is not defined", name)
+	}
+
+	var result starlark.Value
+	err := self.do(name, func(thread *starlark.Thread, _ starlark.StringDict) error {
+		var err error
+		result, err = starlark.Call(thread, fn, starlark.Tuple(args), nil)
+		return err
+	})
+
+	return result, err
+}
@@ -0,0 +1,89 @@
+package Script
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"go.starlark.net/starlark"
+)
+
+func TestRun(t *testing.T) {
+	s := New()
+
+	var lines []string
+	s.SetOutput(func(line string) { lines = append(lines, line) })
+
+	distance := 50.0
+	if err := s.Bind("distance", func() float64 { distance -= 10; return distance }); err != nil {
+		t.Fatal(err)
+	}
+
+	source := `
+def approach(limit):
+    steps = 0
+    while distance() > limit:
+        steps += 1
+    return steps
+
+n = approach(15)
+print("steps", n)
+`
+	if err := s.Run("mission.star", source); err != nil {
+		t.Fatal(err)
+	}
+	if len(lines) != 1 || lines[0] != "steps 3" {
+		t.Fatalf("printed %q, want [\"steps 3\"]", lines)
+	}
+
+	// Functions defined by a script are kept for Call.
+	distance = 100
+	result, err := s.Call("approach", starlark.MakeInt(55))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := starlark.AsInt32(result); n != 4 {
+		t.Fatalf("Call returned %v, want 4", result)
+	}
+}
+
+func TestStop(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+	}{
+		{"loop", "while True:\n    pass\n"},
+		{"sleep", "sleep(60)\n"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := New()
+			stopped := make(chan bool, 1)
+			s.OnStop(func() { stopped <- true })
+
+			done := make(chan error)
+			go func() { done <- s.Run("stop.star", test.source) }()
+
+			time.Sleep(20 * time.Millisecond)
+			s.Stop()
+
+			select {
+			case err := <-done:
+				if !errors.Is(err, ErrStopped) {
+					t.Fatalf("Run returned %v, want ErrStopped", err)
+				}
+			case <-time.After(time.Second):
+				t.Fatal("Run didn't return after Stop")
+			}
+			if len(stopped) != 1 {
+				t.Fatal("OnStop hook not called")
+			}
+
+			// Later runs are unaffected.
+			if err := s.Run("after.star", "x = 1\n"); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package Script
+
+import (
+	"errors"
+	"os"
+	"time"
+
+	"github.com/jermon/GoEV3/utilities"
+	"go.starlark.net/starlark"
+)
+
+// Runs the script in `filename` and, whenever the file is modified, stops it
+// and runs the new version, until a value is sent to `stop`. The file is
+// checked every `interval`. Errors other than ErrStopped are passed to
+// `report`, or logged with their backtrace if it is nil.
+func (self *Interpreter) Watch(filename string, stop <-chan bool, interval time.Duration, report func(error)) {
+	if report == nil {
+		report = func(err error) {
+			var evalErr *starlark.EvalError
+			if errors.As(err, &evalErr) {
+				utilities.Logger().Error(evalErr.Backtrace())
+				return
+			}
+			utilities.Logger().Error(err.Error())
+		}
+	}
+
+	modified := func() time.Time {
+		info, err := os.Stat(filename)
+		if err != nil {
+			return time.Time{}
+		}
+		return info.ModTime()
+	}
+
+	start := func() chan bool {
+		done := make(chan bool)
+		go func() {
+			defer close(done)
+			if err := self.RunFile(filename); err != nil && !errors.Is(err, ErrStopped) {
+				report(err)
+			}
+		}()
+		return done
+	}
+
+	version := modified()
+	done := start()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			self.Stop()
+			<-done
+			return
+		case <-ticker.C:
+		}
+
+		if current := modified(); !current.Equal(version) {
+			version = current
+
+			self.Stop()
+			<-done
+			done = start()
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package Sensors
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jermon/GoEV3/utilities"
+)
+
+// The last ambient light read by a color sensor compensating for it.
+type ambientBaseline struct {
+	lock  sync.Mutex
+	value uint8
+	read  time.Time
+}
+
+// Full scale of the RGB-RAW values.
+const rawScale = 1020
+
+// Returns the ambient light to subtract from reflected light reads, read again
+// when older than the compensation interval, or 0 without compensation.
+func (self *ColorSensor) ambientLight() uint8 {
+	if self.opts.ambient <= 0 || self.opts.noSwitch {
+		return 0
+	}
+
+	self.baseline.lock.Lock()
+	defer self.baseline.lock.Unlock()
+
+	if self.baseline.read.IsZero() || time.Since(self.baseline.read) >= self.opts.ambient {
+		lock := deviceLock(self.path)
+		lock.Lock()
+		writeMode(self.path, "COL-AMBIENT")
+		self.baseline.value, _ = utilities.ReadValue[uint8](self.path, "value0")
+		lock.Unlock()
+
+		self.baseline.read = time.Now()
+	}
+
+	return self.baseline.value
+}
+
+// Records an ambient light read, sparing the next compensated read from taking one.
+func (self *ColorSensor) setAmbientLight(value uint8) {
+	self.baseline.lock.Lock()
+	self.baseline.value = value
+	self.baseline.read = time.Now()
+	self.baseline.lock.Unlock()
+}
+
+// Returns `value` less `ambient`, not below 0.
+func compensate(value int, ambient int) int {
+	if value < ambient {
+		return 0
+	}
+
+	return value - ambient
+}
+
+// Returns the ambient light subtracted from the last compensated read, in
+// range [0, 100], or 0 if the sensor doesn't compensate for it.
+func (self *ColorSensor) AmbientBaseline() uint8 {
+	if self.opts.ambient <= 0 || self.opts.noSwitch {
+		return 0
+	}
+
+	self.baseline.lock.Lock()
+	defer self.baseline.lock.Unlock()
+
+	return self.baseline.value
+}
+
+// Reads the raw red, green and blue light intensities, in range [0, 1020].
+// With WithAmbientCompensation the ambient light is subtracted from each.
+func (self *ColorSensor) ReadRGB() (r, g, b uint16) {
+	ambient := int(self.ambientLight()) * rawScale / 100
+
+	lock := deviceLock(self.path)
+	lock.Lock()
+	defer lock.Unlock()
+
+	self.opts.switchMode(self.path, "RGB-RAW")
+	values := [3]uint16{}
+	for i := range values {
+		v, _ := utilities.ReadValue[uint16](self.path, fmt.Sprintf("value%d", i))
+		values[i] = uint16(compensate(int(v), ambient))
+	}
+
+	return values[0], values[1], values[2]
+}
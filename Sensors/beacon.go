@@ -0,0 +1,162 @@
+package Sensors
+
+import (
+	"math"
+	"sync"
+
+	"github.com/jermon/GoEV3/Geometry"
+)
+
+// Smallest angle, in degrees, between the two sensors' lines of sight for
+// them to be intersected; closer to parallel, the intersection is too
+// sensitive to the heading steps to be of use.
+const minTriangulationAngle = 3
+
+// Where an infrared sensor sits on the robot: its position in centimeters, X
+// forward and Y to the left of the robot's origin, and the direction it faces
+// in degrees, counter-clockwise being positive.
+type SensorMount struct {
+	Position Geometry.Point
+	Heading  float64
+}
+
+// Location of a beacon relative to the robot.
+type BeaconFix struct {
+	// Direction of the beacon from the robot's origin in degrees,
+	// counter-clockwise being positive.
+	Bearing float64
+	// Distance from the robot's origin in centimeters.
+	Range float64
+	// Position in the robot frame, in centimeters.
+	Position Geometry.Point
+	// True if the position was found by intersecting the lines of sight of
+	// both sensors, false if it rests on the rough distance one of them reports.
+	Triangulated bool
+}
+
+// Locates the IR beacon with two infrared sensors in seek mode. A single
+// sensor only reports a heading and a rough distance; mounted apart, two of
+// them see the beacon from different angles, and where their lines of sight
+// cross gives its position:
+//
+//	locator := Sensors.NewBeaconLocator(
+//		left, Sensors.SensorMount{Position: Geometry.Point{X: 5, Y: 6}, Heading: 10},
+//		right, Sensors.SensorMount{Position: Geometry.Point{X: 5, Y: -6}, Heading: -10})
+//	if fix, ok := locator.Locate(); ok {
+//		base.SetVelocity(20, fix.Bearing)
+//	}
+type BeaconLocator struct {
+	sensors [2]*InfraredSensor
+	mounts  [2]SensorMount
+
+	channel            Channel
+	degreesPerStep     float64
+	centimetersPerStep float64
+}
+
+// Creates a locator reading the beacon on channel 1 with sensors `a` and `b`,
+// mounted as given.
+func NewBeaconLocator(a *InfraredSensor, mountA SensorMount, b *InfraredSensor, mountB SensorMount) *BeaconLocator {
+	l := new(BeaconLocator)
+	l.sensors = [2]*InfraredSensor{a, b}
+	l.mounts = [2]SensorMount{mountA, mountB}
+	l.channel = Channel1
+	l.degreesPerStep = 3
+	l.centimetersPerStep = 2
+
+	return l
+}
+
+// Selects the channel the beacon is set to.
+func (self *BeaconLocator) SetChannel(c Channel) {
+	self.channel = c
+}
+
+// Sets the degrees one step of the seek heading, in range [-25, 25], stands
+// for, and the centimeters one step of the seek distance, in range [0, 100],
+// stands for. Both vary between sensors; the defaults are 3 and 2.
+func (self *BeaconLocator) SetScales(degreesPerStep float64, centimetersPerStep float64) {
+	self.degreesPerStep = degreesPerStep
+	self.centimetersPerStep = centimetersPerStep
+}
+
+// A beacon seen by one sensor, in the robot frame.
+type sighting struct {
+	origin    Geometry.Point
+	direction float64
+	distance  float64
+}
+
+// Reads both sensors at once and locates the beacon. Returns false if neither
+// sensor sees it.
+func (self *BeaconLocator) Locate() (BeaconFix, bool) {
+	var readings [2]BeaconReading
+	var wg sync.WaitGroup
+
+	for i, s := range self.sensors {
+		wg.Add(1)
+		go func(i int, s *InfraredSensor) {
+			defer wg.Done()
+			readings[i] = s.ReadIRSEEK(self.channel)
+		}(i, s)
+	}
+	wg.Wait()
+
+	var seen []sighting
+	for i, r := range readings {
+		if !r.Detected {
+			continue
+		}
+
+		// The seek heading grows to the right, clockwise.
+		seen = append(seen, sighting{
+			origin:    self.mounts[i].Position,
+			direction: self.mounts[i].Heading - float64(r.Heading)*self.degreesPerStep,
+			distance:  float64(r.Distance) * self.centimetersPerStep,
+		})
+	}
+
+	switch len(seen) {
+	case 0:
+		return BeaconFix{}, false
+	case 2:
+		if p, ok := intersect(seen[0], seen[1]); ok {
+			return newBeaconFix(p, true), true
+		}
+		// Averaging the sightings along their own lines of sight.
+		a, b := project(seen[0]), project(seen[1])
+		return newBeaconFix(Geometry.Point{X: (a.X + b.X) / 2, Y: (a.Y + b.Y) / 2}, false), true
+	}
+
+	return newBeaconFix(project(seen[0]), false), true
+}
+
+func newBeaconFix(p Geometry.Point, triangulated bool) BeaconFix {
+	return BeaconFix{Bearing: p.Angle(), Range: p.Length(), Position: p, Triangulated: triangulated}
+}
+
+// Returns the point at the reported distance along the line of sight.
+func project(s sighting) Geometry.Point {
+	return s.origin.Add(Geometry.Point{X: s.distance}.Rotate(s.direction))
+}
+
+// Returns where the lines of sight cross, if they do in front of both sensors
+// at an angle wide enough to be trusted.
+func intersect(a sighting, b sighting) (Geometry.Point, bool) {
+	da := Geometry.Point{X: 1}.Rotate(a.direction)
+	db := Geometry.Point{X: 1}.Rotate(b.direction)
+
+	cross := da.X*db.Y - da.Y*db.X
+	if math.Abs(cross) < math.Sin(minTriangulationAngle*math.Pi/180) {
+		return Geometry.Point{}, false
+	}
+
+	d := b.origin.Sub(a.origin)
+	ta := (d.X*db.Y - d.Y*db.X) / cross
+	tb := (d.X*da.Y - d.Y*da.X) / cross
+	if ta <= 0 || tb <= 0 {
+		return Geometry.Point{}, false
+	}
+
+	return a.origin.Add(Geometry.Point{X: da.X * ta, Y: da.Y * ta}), true
+}
@@ -0,0 +1,127 @@
+package Sensors
+
+import (
+	"math"
+	"sync"
+)
+
+// Readings of a color sensor over a white and a black surface, which reads
+// are scaled between.
+type ColorCalibration struct {
+	// Reflected light intensities, in range [0, 100].
+	BlackReflected uint8 `json:"black_reflected"`
+	WhiteReflected uint8 `json:"white_reflected"`
+	// Raw red, green and blue intensities, in range [0, 1020].
+	BlackRGB [3]uint16 `json:"black_rgb"`
+	WhiteRGB [3]uint16 `json:"white_rgb"`
+}
+
+// Reads averaged for each calibration.
+const calibrationSamples = 5
+
+// Calibration of a color sensor, set apart from the sensor's other state.
+type colorCalibration struct {
+	lock        sync.Mutex
+	calibration ColorCalibration
+	black       bool
+	white       bool
+}
+
+// Takes the surface under the sensor as white, the brightest it is to read.
+// Call it and CalibrateBlack on the mat the robot runs on, in its lighting.
+func (self *ColorSensor) CalibrateWhite() {
+	reflected, rgb := self.sampleCalibration()
+
+	self.calibration.lock.Lock()
+	self.calibration.calibration.WhiteReflected = reflected
+	self.calibration.calibration.WhiteRGB = rgb
+	self.calibration.white = true
+	self.calibration.lock.Unlock()
+}
+
+// Takes the surface under the sensor as black, the darkest it is to read.
+func (self *ColorSensor) CalibrateBlack() {
+	reflected, rgb := self.sampleCalibration()
+
+	self.calibration.lock.Lock()
+	self.calibration.calibration.BlackReflected = reflected
+	self.calibration.calibration.BlackRGB = rgb
+	self.calibration.black = true
+	self.calibration.lock.Unlock()
+}
+
+// Averages a few reflected light and RGB reads.
+func (self *ColorSensor) sampleCalibration() (uint8, [3]uint16) {
+	var reflected float64
+	var rgb [3]float64
+
+	for i := 0; i < calibrationSamples; i++ {
+		reflected += float64(self.ReadReflectedLightIntensity())
+	}
+	for i := 0; i < calibrationSamples; i++ {
+		r, g, b := self.ReadRGB()
+		rgb[0], rgb[1], rgb[2] = rgb[0]+float64(r), rgb[1]+float64(g), rgb[2]+float64(b)
+	}
+
+	average := func(sum float64) float64 {
+		return math.Round(sum / calibrationSamples)
+	}
+
+	return uint8(average(reflected)), [3]uint16{uint16(average(rgb[0])), uint16(average(rgb[1])), uint16(average(rgb[2]))}
+}
+
+// Sets a calibration, e.g. one saved from an earlier run.
+func (self *ColorSensor) SetCalibration(calibration ColorCalibration) {
+	self.calibration.lock.Lock()
+	self.calibration.calibration = calibration
+	self.calibration.black, self.calibration.white = true, true
+	self.calibration.lock.Unlock()
+}
+
+// Returns the calibration, to be saved, and whether both white and black were
+// calibrated. Uncalibrated reads range from 0 for black to the full scale for white.
+func (self *ColorSensor) Calibration() (ColorCalibration, bool) {
+	self.calibration.lock.Lock()
+	defer self.calibration.lock.Unlock()
+
+	c := self.calibration.calibration
+	if !self.calibration.white {
+		c.WhiteReflected = 100
+		c.WhiteRGB = [3]uint16{rawScale, rawScale, rawScale}
+	}
+
+	return c, self.calibration.black && self.calibration.white
+}
+
+// Scales `value` from [black, white] to [0, 100], clamped.
+func scaleCalibrated(value float64, black float64, white float64) float64 {
+	if white <= black {
+		return value
+	}
+
+	return math.Max(0, math.Min(100, (value-black)/(white-black)*100))
+}
+
+// Reads the reflected light intensity scaled so that the calibrated black
+// reads 0 and white 100, whatever the lighting and the sensor's height.
+func (self *ColorSensor) ReadCalibratedReflectedIntensity() float64 {
+	c, _ := self.Calibration()
+	value := self.ReadReflectedLightIntensity()
+
+	return scaleCalibrated(float64(value), float64(c.BlackReflected), float64(c.WhiteReflected))
+}
+
+// Reads the red, green and blue intensities each scaled so that the
+// calibrated black reads 0 and white 100, balancing the sensor's colors.
+func (self *ColorSensor) ReadCalibratedRGB() (r, g, b float64) {
+	c, _ := self.Calibration()
+	raw := [3]uint16{}
+	raw[0], raw[1], raw[2] = self.ReadRGB()
+
+	var scaled [3]float64
+	for i := range scaled {
+		scaled[i] = scaleCalibrated(float64(raw[i]), float64(c.BlackRGB[i]), float64(c.WhiteRGB[i]))
+	}
+
+	return scaled[0], scaled[1], scaled[2]
+}
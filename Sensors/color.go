@@ -1,25 +1,67 @@
 package Sensors
 
 import (
-	"fmt"
 	"github.com/jermon/GoEV3/utilities"
+	"time"
 )
 
 // Color sensor type.
 type ColorSensor struct {
 	port InPort
 	path string
+	opts options
+
+	baseline    ambientBaseline
+	samples     int
+	calibration colorCalibration
 }
 
 // Provides access to a color sensor at the given port.
-func FindColorSensor(port InPort) *ColorSensor {
-	snr := findSensor(port, TypeColor)
+// A missing sensor is a fatal error; use OpenColorSensor to handle it.
+func FindColorSensor(port InPort, opts ...Option) *ColorSensor {
+	s, err := OpenColorSensor(port, opts...)
+	if err != nil {
+		utilities.Fatal(err)
+	}
+
+	return s
+}
+
+// Provides access to a color sensor at the given port. Returns an error matching
+// Errors.ErrDeviceNotFound, Errors.ErrPortMismatch or Errors.ErrInvalidMode
+// instead of exiting if the sensor can't be set up.
+func OpenColorSensor(port InPort, opts ...Option) (*ColorSensor, error) {
+	port = CanonicalInPort(string(port))
 
 	s := new(ColorSensor)
 	s.port = port
+	s.opts = newOptions(TypeColor, opts)
 
-	s.path = fmt.Sprintf("%s/%s", baseSensorPath, snr)
-	return s
+	path, err := s.opts.setUp(port, "")
+	if err != nil {
+		return nil, err
+	}
+	s.path = path
+
+	return s, nil
+}
+
+// Returns the input port the sensor is connected to.
+func (self *ColorSensor) Port() InPort {
+	return self.port
+}
+
+// Puts the sensor into `mode`, e.g. to read it WithoutAutoModeSwitch. Returns
+// an error matching Errors.ErrInvalidMode if the sensor doesn't have the
+// mode, and the error of the write.
+func (self *ColorSensor) SetMode(mode string) error {
+	return setMode(self.port, self.path, mode)
+}
+
+// Closes the sensor's attribute files kept open, once the program is done
+// with it; see utilities.IOStrategy. Reading the sensor again opens them again.
+func (self *ColorSensor) Close() {
+	utilities.ReleaseFiles(self.path)
 }
 
 // Constants for color values.
@@ -59,24 +101,127 @@ func (self Color) String() string {
 
 // Reads one of seven color values.
 func (self *ColorSensor) ReadColor() Color {
-	utilities.WriteStringValue(self.path, "mode", "COL-COLOR")
-	value := utilities.ReadUInt8Value(self.path, "value0")
+	value, _ := self.TryReadColor()
+	return value
+}
 
-	return Color(value)
+// Reads the color like ReadColor, but returns the error of the read, e.g. an
+// *utilities.IOError matching Errors.ErrDisconnected or Errors.ErrTimeout.
+func (self *ColorSensor) TryReadColor() (Color, error) {
+	value, err := readInMode[uint8](self.opts, self.path, "COL-COLOR", "value0")
+	return Color(value), err
 }
 
-// Reads the reflected light intensity in range [0, 100].
+// Reads the reflected light intensity in range [0, 100]. With
+// WithAmbientCompensation the ambient light is subtracted from it.
 func (self *ColorSensor) ReadReflectedLightIntensity() uint8 {
-	utilities.WriteStringValue(self.path, "mode", "COL-REFLECT")
-	value := utilities.ReadUInt8Value(self.path, "value0")
-
+	value, _ := self.TryReadReflectedLightIntensity()
 	return value
 }
 
+// Reads the reflected light intensity like ReadReflectedLightIntensity, but
+// returns the error of the read.
+func (self *ColorSensor) TryReadReflectedLightIntensity() (uint8, error) {
+	ambient := self.ambientLight()
+
+	value, err := readInMode[uint8](self.opts, self.path, "COL-REFLECT", "value0")
+	if err != nil {
+		return 0, err
+	}
+
+	return uint8(compensate(int(value), int(ambient))), nil
+}
+
 // Reads the ambient light intensity in range [0, 100].
 func (self *ColorSensor) ReadAmbientLightIntensity() uint8 {
-	utilities.WriteStringValue(self.path, "mode", "COL-AMBIENT")
-	value := utilities.ReadUInt8Value(self.path, "value0")
-
+	value, _ := self.TryReadAmbientLightIntensity()
 	return value
 }
+
+// Reads the ambient light intensity like ReadAmbientLightIntensity, but
+// returns the error of the read.
+func (self *ColorSensor) TryReadAmbientLightIntensity() (uint8, error) {
+	value, err := readInMode[uint8](self.opts, self.path, "COL-AMBIENT", "value0")
+	if err != nil {
+		return 0, err
+	}
+
+	if self.opts.ambient > 0 && !self.opts.noSwitch {
+		self.setAmbientLight(value)
+	}
+
+	return value, nil
+}
+
+// Interval between the reads of WaitForColor, in milliseconds, unless set
+// with WithPollInterval.
+const COLOR_POLLING_INTERVAL = 20
+
+// Sets how many reads in a row WaitForColor needs to see a color before
+// reporting it, so that the edge of a marker or a stray reflection doesn't
+// end the wait. Defaults to 3.
+func (self *ColorSensor) SetConfirmationSamples(n int) {
+	self.samples = n
+}
+
+// Blocks until the sensor sees one of `colors` for the number of reads in a
+// row set with SetConfirmationSamples, and returns it, e.g. to drive until a
+// red marker:
+//
+//	base.Tank(30, 30)
+//	_, found := color.WaitForColor(stop, 5*time.Second, Sensors.Red)
+//	base.Stop()
+//
+// Returns false if `timeout` passes first, or a value is sent to `stop`. A
+// timeout of 0 waits for as long as it takes.
+func (self *ColorSensor) WaitForColor(stop <-chan bool, timeout time.Duration, colors ...Color) (Color, bool) {
+	samples := self.samples
+	if samples <= 0 {
+		samples = 3
+	}
+
+	var deadline <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		deadline = timer.C
+	}
+
+	ticker := time.NewTicker(self.opts.pollInterval(time.Millisecond * COLOR_POLLING_INTERVAL))
+	defer ticker.Stop()
+
+	last, count := None, 0
+	for {
+		c := self.ReadColor()
+
+		matches := false
+		for _, x := range colors {
+			if c == x {
+				matches = true
+				break
+			}
+		}
+
+		switch {
+		case !matches:
+			count = 0
+		case c == last:
+			count++
+		default:
+			count = 1
+		}
+		last = c
+
+		if count >= samples {
+			return c, true
+		}
+
+		select {
+		case <-stop:
+			return None, false
+		case <-deadline:
+			return None, false
+		case <-ticker.C:
+		}
+	}
+}
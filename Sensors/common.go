@@ -1,12 +1,20 @@
 // Provides APIs for interacting with EV3's sensors.
+//
+// The methods of the sensors are safe for concurrent use: reads switching a
+// sensor into the mode they need hold it in that mode until they are done,
+// so that e.g. ReadColor and ReadReflectedLightIntensity can be called from
+// two goroutines without reading in each other's mode.
 package Sensors
 
 import (
+	"errors"
 	"fmt"
-	"github.com/ldmberman/GoEV3/utilities"
-	"io/ioutil"
-	"log"
+	"github.com/jermon/GoEV3/Errors"
+	"github.com/jermon/GoEV3/Platform"
+	"github.com/jermon/GoEV3/utilities"
 	"strings"
+	"sync"
+	"time"
 )
 
 // Constants for input ports.
@@ -30,6 +38,10 @@ const (
 	TypeUltrasonic      = "lego-ev3-us"
 	TypeInfrared        = "lego-ev3-ir"
 	TypeGyro            = "lego-ev3-gyro"
+
+	TypeNXTSound       Type = "lego-nxt-sound"
+	TypeNXTLight            = "lego-nxt-light"
+	TypeNXTTemperature      = "lego-nxt-temp"
 )
 
 func (self Type) String() string {
@@ -44,30 +56,227 @@ func (self Type) String() string {
 		return "infrared"
 	case TypeGyro:
 		return "gyro"
+	case TypeNXTSound:
+		return "nxt sound"
+	case TypeNXTLight:
+		return "nxt light"
+	case TypeNXTTemperature:
+		return "nxt temperature"
 	default:
 		return "unknown"
 	}
 }
 
-func findSensor(port InPort, t Type) string {
-	sensors, _ := ioutil.ReadDir(baseSensorPath)
+// Returns the input port corresponding to an address such as "in1" or
+// "ev3-ports:in1", or a platform specific address such as "pistorms:BAS1" or
+// "spi0.1:S1" on a BrickPi3.
+func CanonicalInPort(address string) InPort {
+	return InPort(Platform.Current().PortName(address))
+}
+
+// Parses an input port given as "1", "in1", an address such as "ev3-ports:in1"
+// or "pistorms:BAS1", or the sysfs path of the sensor connected to it, e.g.
+// "/sys/class/lego-sensor/sensor0".
+func ParseInPort(s string) (InPort, error) {
+	name := strings.TrimSpace(s)
+
+	if strings.HasPrefix(name, "/sys/") {
+		name = utilities.ReadStringValue(name, "address")
+	}
+	if len(name) == 1 {
+		name = "in" + name
+	}
+	if len(name) == 3 && strings.EqualFold(name[:2], "in") {
+		name = strings.ToLower(name)
+	}
+
+	port := CanonicalInPort(name)
+	switch port {
+	case InPort1, InPort2, InPort3, InPort4:
+		return port, nil
+	}
+
+	return "", fmt.Errorf("invalid input port %q, expected 1 to 4", s)
+}
+
+func (self InPort) String() string {
+	return string(self)
+}
+
+// Parses the port with ParseInPort. Allows ports to be passed as command-line flags.
+func (self *InPort) Set(s string) error {
+	port, err := ParseInPort(s)
+	if err != nil {
+		return err
+	}
+
+	*self = port
+	return nil
+}
+
+// Parses the port with ParseInPort. Allows ports to be read from configuration files.
+func (self *InPort) UnmarshalText(text []byte) error {
+	return self.Set(string(text))
+}
+
+// Returns the path of the sensor's folder, looked up again for sensors
+// that don't keep it, so that they can be unplugged and plugged back in.
+func sensorPath(port InPort, o options) (string, error) {
+	name, err := locateSensor(port, o)
+	if err != nil {
+		return "", err
+	}
+
+	return baseSensorPath + "/" + name, nil
+}
+
+// Exits if the sensor a read looked up is missing, as reads that don't
+// return errors have always done; other errors are ignored.
+func exitIfMissing(err error) {
+	if errors.Is(err, Errors.ErrDeviceNotFound) || errors.Is(err, Errors.ErrPortMismatch) {
+		utilities.Fatal(err)
+	}
+}
+
+func locateSensor(port InPort, o options) (string, error) {
+	deadline := time.Now().Add(o.timeout)
+
+	for {
+		name, found := lookupSensor(port, o.driver)
+		if name != "" {
+			return name, nil
+		}
+
+		if !time.Now().Before(deadline) {
+			err := &Errors.DeviceError{
+				Kind:   Errors.ErrDeviceNotFound,
+				Device: "sensor",
+				Port:   string(port),
+				Detail: "expected " + string(o.driver),
+			}
+			if o.driver == "" {
+				err.Detail = "expected any sensor"
+			}
+
+			if found {
+				err.Kind = Errors.ErrPortMismatch
+			} else {
+				err.Cause = Platform.EnvironmentError()
+			}
 
-	for _, item := range sensors {
-		if strings.HasPrefix(item.Name(), "sensor") {
-			sensorPath := fmt.Sprintf("%s/%s", baseSensorPath, item.Name())
+			return "", err
+		}
+
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+// Returns the name of the folder of the sensor at the given port with the given
+// driver, or with any driver if `t` is empty. `found` reports whether a sensor
+// with another driver is there.
+func lookupSensor(port InPort, t Type) (name string, found bool) {
+	sensors := utilities.ListDir(baseSensorPath)
+
+	for _, name := range sensors {
+		if strings.HasPrefix(name, "sensor") {
+			sensorPath := fmt.Sprintf("%s/%s", baseSensorPath, name)
 			portr := utilities.ReadStringValue(sensorPath, "address")
 
-			if InPort(portr) == port {
+			if Platform.Current().Matches(string(port), portr) {
 				typer := utilities.ReadStringValue(sensorPath, "driver_name")
 
-				if Type(typer) == t {
-					return item.Name()
+				if t == "" || Type(typer) == t {
+					return name, true
 				}
+				found = true
 			}
 		}
 	}
 
-	log.Fatalf("Could not find %v sensor on port %v\n", t, port)
+	return "", found
+}
+
+// Locks serializing the mode switches and reads of each sensor, by path, so
+// that a read from one goroutine doesn't happen in the mode another goroutine
+// switched the sensor into in the meantime.
+var gDeviceLocks = make(map[string]*sync.Mutex)
+var gDeviceLocksLock = &sync.Mutex{}
+
+func deviceLock(path string) *sync.Mutex {
+	gDeviceLocksLock.Lock()
+	defer gDeviceLocksLock.Unlock()
 
-	return ""
+	lock, ok := gDeviceLocks[path]
+	if !ok {
+		lock = &sync.Mutex{}
+		gDeviceLocks[path] = lock
+	}
+
+	return lock
+}
+
+// Modes the sensors were in before this program first changed them.
+var gOriginalModes = make(map[string]string)
+
+// Modes this program last put the sensors into.
+var gCurrentModes = make(map[string]string)
+var gModesLock = &sync.Mutex{}
+
+// Puts the sensor at `path` into `mode`, unless this program already did.
+// Writing the mode restarts the sensor, which takes tens of milliseconds, so
+// reads switching modes only pay for it when the mode changes.
+func writeMode(path string, mode string) error {
+	gModesLock.Lock()
+	current := gCurrentModes[path]
+	gModesLock.Unlock()
+
+	if current == mode {
+		return nil
+	}
+
+	return forceMode(path, mode)
+}
+
+// Puts the sensor at `path` into `mode`, whatever mode it is in.
+func forceMode(path string, mode string) error {
+	gModesLock.Lock()
+	if _, ok := gOriginalModes[path]; !ok {
+		gOriginalModes[path] = utilities.ReadStringValue(path, "mode")
+	}
+	gModesLock.Unlock()
+
+	err := utilities.WriteValue(path, "mode", mode)
+
+	gModesLock.Lock()
+	if err == nil {
+		gCurrentModes[path] = mode
+	} else {
+		delete(gCurrentModes, path)
+	}
+	gModesLock.Unlock()
+
+	return err
+}
+
+// Records the mode the sensor at `path` was found in.
+func rememberMode(path string, mode string) {
+	gModesLock.Lock()
+	gCurrentModes[path] = mode
+	gModesLock.Unlock()
+}
+
+// Puts every sensor whose mode this program has changed back into its original mode.
+func RestoreModes() {
+	gModesLock.Lock()
+	modes := make(map[string]string, len(gOriginalModes))
+	for path, mode := range gOriginalModes {
+		modes[path] = mode
+	}
+	gModesLock.Unlock()
+
+	for path, mode := range modes {
+		if mode != "" {
+			forceMode(path, mode)
+		}
+	}
 }
@@ -0,0 +1,120 @@
+package Sensors
+
+import (
+	"context"
+)
+
+// Returns a channel closed once `ctx` is done, for the waits taking a stop
+// channel, and a function to call once the wait is over.
+func stopOnDone(ctx context.Context) (<-chan bool, func()) {
+	stop := make(chan bool)
+	over := make(chan bool)
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			close(stop)
+		case <-over:
+		}
+	}()
+
+	return stop, func() { close(over) }
+}
+
+// Blocks like WaitForProximity until an object is near, or until `ctx` is
+// done, returning its error.
+func (self *InfraredSensor) WaitForProximityCtx(ctx context.Context) error {
+	stop, release := stopOnDone(ctx)
+	defer release()
+
+	if !self.waitForProximity(stop) {
+		return ctx.Err()
+	}
+
+	return nil
+}
+
+// Registers a callback like OnRemotePressed, listening until `ctx` is done
+// or the returned listener is removed.
+func (self *InfraredSensor) OnRemotePressedCtx(ctx context.Context, fn func(c Channel, b Button)) *RemoteListener {
+	return removeOnDone(ctx, self.OnRemotePressed(nil, fn))
+}
+
+// Registers a callback like OnRemoteReleased, listening until `ctx` is done
+// or the returned listener is removed.
+func (self *InfraredSensor) OnRemoteReleasedCtx(ctx context.Context, fn func(c Channel, b Button)) *RemoteListener {
+	return removeOnDone(ctx, self.OnRemoteReleased(nil, fn))
+}
+
+// Returns a channel receiving remote events like RemoteEvents, closed once
+// `ctx` is done.
+func (self *InfraredSensor) RemoteEventsCtx(ctx context.Context) <-chan RemoteEvent {
+	return removeOnDone(ctx, self.listenRemote(nil)).events
+}
+
+// Removes the listener once `ctx` is done.
+func removeOnDone(ctx context.Context, l *RemoteListener) *RemoteListener {
+	go func() {
+		select {
+		case <-ctx.Done():
+			l.Remove()
+		case <-l.done:
+		}
+	}()
+
+	return l
+}
+
+// Blocks like WaitForPress until the touch sensor is pressed, or until `ctx`
+// is done, returning its error.
+func (self *TouchSensor) WaitForPressCtx(ctx context.Context) error {
+	return self.waitForCtx(ctx, true)
+}
+
+// Blocks like WaitForRelease until the touch sensor is released, or until
+// `ctx` is done, returning its error.
+func (self *TouchSensor) WaitForReleaseCtx(ctx context.Context) error {
+	return self.waitForCtx(ctx, false)
+}
+
+func (self *TouchSensor) waitForCtx(ctx context.Context, pressed bool) error {
+	stop, release := stopOnDone(ctx)
+	defer release()
+
+	if !self.waitFor(stop, pressed) {
+		return ctx.Err()
+	}
+
+	return nil
+}
+
+// Blocks like WaitForDistanceBelow until the sensor measures less than
+// `threshold` centimeters, or until `ctx` is done, returning its error.
+func (self *UltrasonicSensor) WaitForDistanceBelowCtx(ctx context.Context, threshold float64) error {
+	stop, release := stopOnDone(ctx)
+	defer release()
+
+	if !self.waitForDistanceBelow(stop, threshold) {
+		return ctx.Err()
+	}
+
+	return nil
+}
+
+// Blocks like WaitForColor until the sensor sees one of `colors`, and returns
+// it, or until `ctx` is done, returning its error, e.g. with a deadline:
+//
+//	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+//	defer cancel()
+//	c, err := color.WaitForColorCtx(ctx, Sensors.Red, Sensors.Green)
+func (self *ColorSensor) WaitForColorCtx(ctx context.Context, colors ...Color) (Color, error) {
+	stop, release := stopOnDone(ctx)
+	defer release()
+
+	c, found := self.WaitForColor(stop, 0, colors...)
+	if !found {
+		return None, ctx.Err()
+	}
+
+	return c, nil
+}
@@ -0,0 +1,242 @@
+package Sensors
+
+import (
+	"fmt"
+	"math"
+	"path"
+	"strings"
+
+	"github.com/jermon/GoEV3/Errors"
+	"github.com/jermon/GoEV3/Platform"
+	"github.com/jermon/GoEV3/utilities"
+)
+
+// Any sensor of the lego-sensor class, with its attributes as the driver
+// reports them, for sensors without a type of their own, e.g. HiTechnic or
+// mindsensors.com sensors:
+//
+//	compass := Sensors.FindSensorByDriver("ht-nxt-compass")
+//	heading, _ := compass.Value(0)
+type GenericSensor struct {
+	port   InPort
+	path   string
+	driver string
+	opts   options
+}
+
+// Provides access to the sensor at the given port, whatever its driver, or
+// only one with the driver given WithRequiredDriver. A missing sensor is a
+// fatal error; use OpenSensor to handle it.
+func FindSensor(port InPort, opts ...Option) *GenericSensor {
+	s, err := OpenSensor(port, opts...)
+	if err != nil {
+		utilities.Fatal(err)
+	}
+
+	return s
+}
+
+// Provides access to the sensor at the given port like FindSensor. Returns an
+// error matching Errors.ErrDeviceNotFound, Errors.ErrPortMismatch or
+// Errors.ErrInvalidMode instead of exiting if the sensor can't be set up.
+func OpenSensor(port InPort, opts ...Option) (*GenericSensor, error) {
+	port = CanonicalInPort(string(port))
+
+	o := newOptions("", opts)
+	path, err := o.setUp(port, "")
+	if err != nil {
+		return nil, err
+	}
+
+	return newGenericSensor(path, o), nil
+}
+
+// Provides access to the first sensor with the given driver, e.g.
+// "ht-nxt-compass", on any port. Options other than WithPollInterval are
+// ignored. A missing sensor is a fatal error; use OpenSensorByDriver to
+// handle it.
+func FindSensorByDriver(driver string, opts ...Option) *GenericSensor {
+	s, err := OpenSensorByDriver(driver, opts...)
+	if err != nil {
+		utilities.Fatal(err)
+	}
+
+	return s
+}
+
+// Provides access to the first sensor with the given driver like
+// FindSensorByDriver, but returns an error matching Errors.ErrDeviceNotFound
+// instead of exiting if there is none.
+func OpenSensorByDriver(driver string, opts ...Option) (*GenericSensor, error) {
+	for _, name := range utilities.ListDir(baseSensorPath) {
+		if !strings.HasPrefix(name, "sensor") {
+			continue
+		}
+
+		folder := path.Join(baseSensorPath, name)
+		if utilities.ReadStringValue(folder, "driver_name") == driver {
+			return newGenericSensor(folder, newOptions(Type(driver), opts)), nil
+		}
+	}
+
+	return nil, &Errors.DeviceError{
+		Kind:   Errors.ErrDeviceNotFound,
+		Device: "sensor",
+		Detail: "expected " + driver,
+		Cause:  Platform.EnvironmentError(),
+	}
+}
+
+func newGenericSensor(folder string, o options) *GenericSensor {
+	s := new(GenericSensor)
+	s.path = folder
+	s.opts = o
+	s.port = CanonicalInPort(utilities.ReadStringValue(folder, "address"))
+	s.driver = utilities.ReadStringValue(folder, "driver_name")
+
+	return s
+}
+
+// Returns the input port the sensor is connected to.
+func (self *GenericSensor) Port() InPort {
+	return self.port
+}
+
+// Returns the name of the sensor's driver, e.g. "lego-ev3-color".
+func (self *GenericSensor) Driver() string {
+	return self.driver
+}
+
+// Returns the sensor's folder in sysfs, for attributes without a method.
+func (self *GenericSensor) Path() string {
+	return self.path
+}
+
+// Returns the modes the sensor has.
+func (self *GenericSensor) Modes() []string {
+	return strings.Fields(utilities.ReadStringValue(self.path, "modes"))
+}
+
+// Returns the mode the sensor is in.
+func (self *GenericSensor) Mode() string {
+	return utilities.ReadStringValue(self.path, "mode")
+}
+
+// Puts the sensor into `mode`. Returns an error matching Errors.ErrInvalidMode
+// if the sensor doesn't have the mode, and the error of the write.
+func (self *GenericSensor) SetMode(mode string) error {
+	return setMode(self.port, self.path, mode)
+}
+
+// Returns the number of values the current mode reports.
+func (self *GenericSensor) NumValues() int {
+	n, _ := utilities.ReadValue[int](self.path, "num_values")
+	return n
+}
+
+// Returns the number of decimal places of the values of the current mode.
+func (self *GenericSensor) Decimals() int {
+	n, _ := utilities.ReadValue[int](self.path, "decimals")
+	return n
+}
+
+// Returns the units of the values of the current mode, e.g. "pct" or "cm";
+// empty if they have none.
+func (self *GenericSensor) Units() string {
+	return utilities.ReadStringValue(self.path, "units")
+}
+
+// Reads the raw value `n` of the current mode, in range [0, NumValues). Returns
+// an error matching Errors.ErrOutOfRange if there is no such value, and the
+// error of the read.
+func (self *GenericSensor) Value(n int) (int, error) {
+	if count := self.NumValues(); n < 0 || n >= count {
+		return 0, &Errors.DeviceError{
+			Kind:   Errors.ErrOutOfRange,
+			Device: "sensor",
+			Port:   string(self.port),
+			Detail: fmt.Sprintf("value %d, the mode has %d", n, count),
+		}
+	}
+
+	return readLocked[int](self.path, fmt.Sprintf("value%d", n))
+}
+
+// Reads value `n` like Value, scaled by its decimal places.
+func (self *GenericSensor) FloatValue(n int) (float64, error) {
+	value, err := self.Value(n)
+	if err != nil {
+		return 0, err
+	}
+
+	return float64(value) * math.Pow10(-self.Decimals()), nil
+}
+
+// Reads all the values of the current mode, scaled by their decimal places.
+func (self *GenericSensor) Values() ([]float64, error) {
+	lock := deviceLock(self.path)
+	lock.Lock()
+	defer lock.Unlock()
+
+	return self.readValues()
+}
+
+// Switches the sensor into `mode`, like the reads of the typed sensors do, and
+// reads all its values, scaled by their decimal places, without another
+// goroutine switching the mode in between.
+func (self *GenericSensor) ValuesInMode(mode string) ([]float64, error) {
+	lock := deviceLock(self.path)
+	lock.Lock()
+	defer lock.Unlock()
+
+	if err := self.opts.switchMode(self.path, mode); err != nil {
+		return nil, err
+	}
+
+	return self.readValues()
+}
+
+// Reads all the values of the current mode. Must be called with the device lock held.
+func (self *GenericSensor) readValues() ([]float64, error) {
+	scale := math.Pow10(-self.Decimals())
+
+	values := make([]float64, self.NumValues())
+	for i := range values {
+		value, err := utilities.ReadValue[int](self.path, fmt.Sprintf("value%d", i))
+		if err != nil {
+			return nil, err
+		}
+		values[i] = float64(value) * scale
+	}
+
+	return values, nil
+}
+
+// Returns the commands the sensor accepts; most sensors have none.
+func (self *GenericSensor) Commands() []string {
+	return strings.Fields(utilities.ReadStringValue(self.path, "commands"))
+}
+
+// Sends a command to the sensor, e.g. "RESET". Returns an error matching
+// Errors.ErrInvalidMode if the sensor doesn't list it, and the error of the write.
+func (self *GenericSensor) SendCommand(command string) error {
+	supported := self.Commands()
+	for _, c := range supported {
+		if c == command {
+			return utilities.WriteValue(self.path, "command", command)
+		}
+	}
+
+	return &Errors.DeviceError{
+		Kind:   Errors.ErrInvalidMode,
+		Device: "sensor",
+		Port:   string(self.port),
+		Detail: fmt.Sprintf("command %q, expected one of %s", command, strings.Join(supported, ", ")),
+	}
+}
+
+// Closes the sensor's attribute files kept open, once the program is done
+// with it; see utilities.IOStrategy. Reading the sensor again opens them again.
+func (self *GenericSensor) Close() {
+	utilities.ReleaseFiles(self.path)
+}
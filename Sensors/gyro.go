@@ -1,44 +1,182 @@
 package Sensors
 
 import (
-	"fmt"
-	"github.com/ldmberman/GoEV3/utilities"
+	"github.com/jermon/GoEV3/Units"
+	"github.com/jermon/GoEV3/utilities"
+	"time"
 )
 
 // Gyro sensor type.
 type GyroSensor struct {
 	port InPort
+	opts options
 }
 
 // Provides access to a gyro sensor at the given port.
-func FindGyroSensor(port InPort) *GyroSensor {
-	snr := findSensor(port, TypeGyro)
+// Unless told otherwise, the sensor is put into the mode reporting both the angle and the rotational speed.
+// A missing sensor is a fatal error; use OpenGyroSensor to handle it.
+func FindGyroSensor(port InPort, opts ...Option) *GyroSensor {
+	s, err := OpenGyroSensor(port, opts...)
+	if err != nil {
+		utilities.Fatal(err)
+	}
+
+	return s
+}
+
+// Provides access to a gyro sensor at the given port. Returns an error matching
+// Errors.ErrDeviceNotFound, Errors.ErrPortMismatch or Errors.ErrInvalidMode
+// instead of exiting if the sensor can't be set up.
+func OpenGyroSensor(port InPort, opts ...Option) (*GyroSensor, error) {
+	port = CanonicalInPort(string(port))
 
 	s := new(GyroSensor)
 	s.port = port
+	s.opts = newOptions(TypeGyro, opts)
+	if _, err := s.opts.setUp(port, "GYRO-G&A"); err != nil {
+		return nil, err
+	}
 
-	path := fmt.Sprintf("%s/%s", baseSensorPath, snr)
-	utilities.WriteStringValue(path, "mode", "GYRO-G&A")
+	return s, nil
+}
 
-	return s
+// Returns the input port the sensor is connected to.
+func (self *GyroSensor) Port() InPort {
+	return self.port
 }
 
-// Reads the angle of degrees.
-func (self *GyroSensor) ReadAngle() int16 {
-	snr := findSensor(self.port, TypeGyro)
+// Puts the sensor into `mode`, e.g. to read it WithoutAutoModeSwitch. Returns
+// an error matching Errors.ErrInvalidMode if the sensor doesn't have the
+// mode, Errors.ErrDeviceNotFound if it is missing, and the error of the write.
+func (self *GyroSensor) SetMode(mode string) error {
+	path, err := sensorPath(self.port, self.opts)
+	if err != nil {
+		return err
+	}
 
-	path := fmt.Sprintf("%s/%s", baseSensorPath, snr)
-	value := utilities.ReadInt16Value(path, "value0")
+	return setMode(self.port, path, mode)
+}
+
+// Closes the sensor's attribute files kept open, once the program is done
+// with it; see utilities.IOStrategy. Reading the sensor again opens them again.
+func (self *GyroSensor) Close() {
+	if path, err := sensorPath(self.port, self.opts); err == nil {
+		utilities.ReleaseFiles(path)
+	}
+}
+
+// Reads the angle of degrees. A missing sensor is a fatal error.
+func (self *GyroSensor) ReadAngle() int16 {
+	value, err := self.TryReadAngle()
+	exitIfMissing(err)
 
 	return value
 }
 
-// Reads the rotational speed in range [-440, 440].
-func (self *GyroSensor) ReadRotationalSpeed() int16 {
-	snr := findSensor(self.port, TypeGyro)
+// Reads the angle like ReadAngle, but returns an error matching
+// Errors.ErrDeviceNotFound instead of exiting if the sensor is missing, and
+// the error of the read.
+func (self *GyroSensor) TryReadAngle() (int16, error) {
+	path, err := sensorPath(self.port, self.opts)
+	if err != nil {
+		return 0, err
+	}
 
-	path := fmt.Sprintf("%s/%s", baseSensorPath, snr)
-	value := utilities.ReadInt16Value(path, "value1")
+	return readLocked[int16](path, "value0")
+}
+
+// Reads the rotational speed in range [-440, 440]. A missing sensor is a fatal error.
+func (self *GyroSensor) ReadRotationalSpeed() int16 {
+	value, err := self.TryReadRotationalSpeed()
+	exitIfMissing(err)
 
 	return value
 }
+
+// Reads the rotational speed like ReadRotationalSpeed, but returns the error
+// instead of exiting, as TryReadAngle does.
+func (self *GyroSensor) TryReadRotationalSpeed() (int16, error) {
+	path, err := sensorPath(self.port, self.opts)
+	if err != nil {
+		return 0, err
+	}
+
+	return readLocked[int16](path, "value1")
+}
+
+// Reads the rotational speed in range [-440, 440]; same as ReadRotationalSpeed.
+func (self *GyroSensor) ReadRate() int16 {
+	return self.ReadRotationalSpeed()
+}
+
+// Reads the angle and the rotational speed, looking the sensor up once for
+// both. A missing sensor is a fatal error.
+func (self *GyroSensor) ReadAngleAndRate() (angle int16, rate int16) {
+	angle, rate, err := self.TryReadAngleAndRate()
+	exitIfMissing(err)
+
+	return angle, rate
+}
+
+// Reads the angle and the rotational speed like ReadAngleAndRate, but returns
+// the error instead of exiting, as TryReadAngle does.
+func (self *GyroSensor) TryReadAngleAndRate() (angle int16, rate int16, err error) {
+	path, err := sensorPath(self.port, self.opts)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	lock := deviceLock(path)
+	lock.Lock()
+	defer lock.Unlock()
+
+	if angle, err = utilities.ReadValue[int16](path, "value0"); err != nil {
+		return 0, 0, err
+	}
+	rate, err = utilities.ReadValue[int16](path, "value1")
+
+	return angle, rate, err
+}
+
+// Sets the angle back to 0 by switching the sensor out of and back into the
+// mode reporting both the angle and the rotational speed, which restarts its
+// angle. The robot must be still. A missing sensor is a fatal error.
+func (self *GyroSensor) Reset() {
+	self.restartIn("GYRO-RATE")
+}
+
+// Recalibrates the sensor, removing the drift it picks up when it was
+// started moving, and sets the angle back to 0. The robot must be still until
+// it returns, about a second.
+func (self *GyroSensor) Calibrate() {
+	self.restartIn("GYRO-CAL")
+}
+
+// Time the sensor takes to calibrate itself.
+const gyroCalibrationTime = time.Second
+
+// Switches the sensor into `mode` and back into GYRO-G&A.
+func (self *GyroSensor) restartIn(mode string) {
+	path, err := sensorPath(self.port, self.opts)
+	exitIfMissing(err)
+
+	lock := deviceLock(path)
+	lock.Lock()
+	defer lock.Unlock()
+
+	writeMode(path, mode)
+	if mode == "GYRO-CAL" {
+		time.Sleep(gyroCalibrationTime)
+	}
+	writeMode(path, "GYRO-G&A")
+}
+
+// Reads the angle, clockwise being positive.
+func (self *GyroSensor) Angle() Units.Angle {
+	return Units.Angle(self.ReadAngle()) * Units.Degree
+}
+
+// Reads the rotational speed, clockwise being positive.
+func (self *GyroSensor) Rate() Units.AngularSpeed {
+	return Units.AngularSpeed(self.ReadRotationalSpeed()) * Units.DegreePerSecond
+}
@@ -0,0 +1,118 @@
+package Sensors
+
+import (
+	"math"
+)
+
+// A color as hue, in degrees in range [0, 360), and saturation and value, in
+// range [0, 1]. Unlike the raw channels, the hue of a surface barely changes
+// with the light on it.
+type HSV struct {
+	H float64
+	S float64
+	V float64
+}
+
+// Converts raw red, green and blue intensities, in range [0, 1020] as
+// ReadRGB returns them, to hue, saturation and value.
+func RGBToHSV(r, g, b uint16) HSV {
+	red := float64(r) / rawScale
+	green := float64(g) / rawScale
+	blue := float64(b) / rawScale
+
+	max := math.Max(red, math.Max(green, blue))
+	min := math.Min(red, math.Min(green, blue))
+	delta := max - min
+
+	hsv := HSV{V: math.Min(max, 1)}
+	if max == 0 || delta == 0 {
+		return hsv
+	}
+	hsv.S = delta / max
+
+	switch max {
+	case red:
+		hsv.H = 60 * math.Mod((green-blue)/delta, 6)
+	case green:
+		hsv.H = 60 * ((blue-red)/delta + 2)
+	default:
+		hsv.H = 60 * ((red-green)/delta + 4)
+	}
+	if hsv.H < 0 {
+		hsv.H += 360
+	}
+
+	return hsv
+}
+
+// Reads the color seen by the sensor as hue, saturation and value.
+func (self *ColorSensor) ReadHSV() HSV {
+	return RGBToHSV(self.ReadRGB())
+}
+
+// A named color of a palette.
+type Swatch struct {
+	Name  string
+	Color HSV
+}
+
+// Colors to tell apart, e.g. those of a competition mat.
+type Palette []Swatch
+
+// Weight of the value against hue and saturation in the distance between
+// colors, low so that a color seen under brighter or dimmer light still matches.
+const valueWeight = 0.5
+
+// Returns the distance between two colors, as points of a cylinder whose
+// angle is the hue, radius the saturation and height the value. Greys,
+// whose hue means nothing, differ only by their value.
+func (self HSV) Distance(other HSV) float64 {
+	a, b := self.H*math.Pi/180, other.H*math.Pi/180
+	dx := self.S*math.Cos(a) - other.S*math.Cos(b)
+	dy := self.S*math.Sin(a) - other.S*math.Sin(b)
+	dz := (self.V - other.V) * valueWeight
+
+	return math.Sqrt(dx*dx + dy*dy + dz*dz)
+}
+
+// Rough colors of the LEGO elements the seven-color mode recognizes, seen
+// from about 1 cm. Calibrated palettes, made with Sample, match better.
+var DefaultPalette = Palette{
+	{"Black", HSV{0, 0, 0.03}},
+	{"Blue", HSV{215, 0.65, 0.12}},
+	{"Green", HSV{135, 0.5, 0.1}},
+	{"Yellow", HSV{50, 0.7, 0.3}},
+	{"Red", HSV{0, 0.8, 0.2}},
+	{"White", HSV{0, 0, 0.35}},
+	{"Brown", HSV{25, 0.55, 0.08}},
+}
+
+// Returns the swatch of the palette nearest to `color` and its distance, to
+// reject colors too far from any of them. Returns false for an empty palette.
+func (self Palette) Match(color HSV) (Swatch, float64, bool) {
+	best, distance := Swatch{}, math.Inf(1)
+
+	for _, s := range self {
+		if d := s.Color.Distance(color); d < distance {
+			best, distance = s, d
+		}
+	}
+
+	return best, distance, len(self) > 0
+}
+
+// Reads the color seen by the sensor and returns the nearest swatch of the palette:
+//
+//	swatch, _, _ := color.MatchColor(Sensors.DefaultPalette)
+//	fmt.Println(swatch.Name)
+func (self *ColorSensor) MatchColor(palette Palette) (Swatch, float64, bool) {
+	return palette.Match(self.ReadHSV())
+}
+
+// Reads the color seen by the sensor as a swatch named `name`, to build a
+// palette from the surfaces at hand:
+//
+//	palette := Sensors.Palette{color.Sample("mat"), color.Sample("tape")}
+func (self *ColorSensor) Sample(name string) Swatch {
+	return Swatch{Name: name, Color: self.ReadHSV()}
+}
@@ -1,14 +1,11 @@
 package Sensors
 
 import (
+	"errors"
 	"fmt"
-	"io/ioutil"
-	"log"
-	"os"
-	"strconv"
-	"strings"
 	"time"
 
+	"github.com/jermon/GoEV3/Errors"
 	"github.com/jermon/GoEV3/utilities"
 )
 
@@ -17,23 +14,36 @@ const (
 	RedDown         = 2
 	BlueUp          = 3
 	BlueDown        = 4
+	// Reported while the beacon is switched on, until it is pressed again.
+	Beacon = 9
+
+	// Codes of two buttons pressed together; see Button.Buttons.
+	RedUpBlueUp     Button = 5
+	RedUpBlueDown          = 6
+	RedDownBlueUp          = 7
+	RedDownBlueDown        = 8
+	RedUpRedDown           = 10
+	BlueUpBlueDown         = 11
 
 	Channel1 Channel = 0
 	Channel2         = 1
 	Channel3         = 2
 	Channel4         = 3
 
-/*	
-	Mode-IR-PROX String  = "IR-PROX"
-	Mode-IR-SEEK         = "IR-SEEK"
-	Mode-IR-REMOTE       = "IR-REMOTE"
-	Mode-IR-REM-A        = "IR-REM-A"
-	Mode-IR-S-ALT        = "IR-S-ALT"
-	Mode-IR-CAL          = "IR-CAL"
-*/	
+/*
+Mode-IR-PROX String  = "IR-PROX"
+Mode-IR-SEEK         = "IR-SEEK"
+Mode-IR-REMOTE       = "IR-REMOTE"
+Mode-IR-REM-A        = "IR-REM-A"
+Mode-IR-S-ALT        = "IR-S-ALT"
+Mode-IR-CAL          = "IR-CAL"
+*/
 )
 
 var (
+	// Deprecated: the remote is now watched with utilities.WatchValue, whose
+	// intervals are set with utilities.WATCH_MIN_INTERVAL and WATCH_MAX_INTERVAL,
+	// or for a sensor with WithPollInterval.
 	REMOTE_POLLING_INTERVAL = 500 // milliseconds
 )
 
@@ -42,6 +52,7 @@ type (
 	InfraredSensor struct {
 		port InPort
 		path string
+		opts options
 	}
 
 	RemoteSignal struct {
@@ -52,192 +63,255 @@ type (
 	Channel uint64
 )
 
+// The state of the remote buttons on a channel, as read with ReadRemote: the
+// code of the buttons held, which Has and Buttons decode.
+type ButtonState = Button
+
 // Provides access to an infrared sensor at the given port.
-func FindInfraredSensor(port InPort) *InfraredSensor {
-	snr := findSensor(port, TypeInfrared)
+// A missing sensor is a fatal error; use OpenInfraredSensor to handle it.
+func FindInfraredSensor(port InPort, opts ...Option) *InfraredSensor {
+	s, err := OpenInfraredSensor(port, opts...)
+	if err != nil {
+		utilities.Fatal(err)
+	}
+
+	return s
+}
+
+// Provides access to an infrared sensor at the given port. Returns an error matching
+// Errors.ErrDeviceNotFound, Errors.ErrPortMismatch or Errors.ErrInvalidMode
+// instead of exiting if the sensor can't be set up.
+func OpenInfraredSensor(port InPort, opts ...Option) (*InfraredSensor, error) {
+	port = CanonicalInPort(string(port))
 
 	s := new(InfraredSensor)
 	s.port = port
-	s.path = fmt.Sprintf("%s/%s", baseSensorPath, snr)
+	s.opts = newOptions(TypeInfrared, opts)
+	path, err := s.opts.setUp(port, "")
+	if err != nil {
+		return nil, err
+	}
+	s.path = path
 
-	return s
+	return s, nil
+}
+
+// Returns the input port the sensor is connected to.
+func (self *InfraredSensor) Port() InPort {
+	return self.port
+}
+
+// Puts the sensor into `mode`, e.g. to read it WithoutAutoModeSwitch. Returns
+// an error matching Errors.ErrInvalidMode if the sensor doesn't have the
+// mode, and the error of the write.
+func (self *InfraredSensor) SetMode(mode string) error {
+	return setMode(self.port, self.path, mode)
+}
+
+// Closes the sensor's attribute files kept open, once the program is done
+// with it; see utilities.IOStrategy. Reading the sensor again opens them again.
+func (self *InfraredSensor) Close() {
+	utilities.ReleaseFiles(self.path)
 }
 
 func (self *InfraredSensor) WriteMode(mode string) {
-  utilities.WriteStringValue(self.path, "mode", mode)
-}
-
-func (self *InfraredSensor) ReadIRSEEK(channel int16) (int16, int16){
-
-	var channel1 string
-	var channel2 string
-	
-	switch channel {
-	case 1:
-	  channel1 = "value0"
-	  channel2 = "value1"
-	case 2:
-	  channel1 = "value2"
-	  channel2 = "value3"
-	case 3:
-	  channel1 = "value4"
-	  channel2 = "value5"
-	case 4:
-	  channel1 = "value6"
-	  channel2 = "value7"
-	  }
-	utilities.WriteStringValue(self.path, "mode", "IR-SEEK")
-	heading :=   utilities.ReadInt16Value(self.path, channel1)
-	distance :=  utilities.ReadInt16Value(self.path, channel2)
-  return heading, distance
+	lock := deviceLock(self.path)
+	lock.Lock()
+	defer lock.Unlock()
+
+	forceMode(self.path, mode)
 }
 
-// Reads the proximity value (in range 0 - 100) reported by the infrared sensor. A value of 100 corresponds to a range of approximately 70 cm.
-func (self *InfraredSensor) ReadProximity() uint8 {
+// Distance the sensor reports on a channel without a beacon.
+const noBeacon = -128
+
+// The beacon on one channel, as read in IR-SEEK mode.
+type BeaconReading struct {
+	// Direction of the beacon in range [-25, 25], growing to the right; 0 is straight ahead.
+	Heading int16
+	// Distance to the beacon in range [0, 100]; 0 when not detected.
+	Distance int16
+	// False when no beacon is seen on the channel, and Heading and Distance mean nothing.
+	Detected bool
+}
+
+// Reads the heading and distance of the beacon on the given channel. An
+// invalid channel is a fatal error; use TryReadIRSEEK to handle it.
+func (self *InfraredSensor) ReadIRSEEK(c Channel) BeaconReading {
+	reading, err := self.TryReadIRSEEK(c)
+	if errors.Is(err, Errors.ErrOutOfRange) {
+		utilities.Fatal(err)
+	}
+
+	return reading
+}
 
-	utilities.WriteStringValue(self.path, "mode", "IR-PROX")
-	value := utilities.ReadUInt8Value(self.path, "value0")
+// Reads the beacon like ReadIRSEEK, but returns an error matching
+// Errors.ErrOutOfRange instead of exiting if the channel is invalid, and the
+// error of the read.
+func (self *InfraredSensor) TryReadIRSEEK(c Channel) (BeaconReading, error) {
+	if c > Channel4 {
+		return BeaconReading{}, &Errors.DeviceError{
+			Kind:   Errors.ErrOutOfRange,
+			Device: "infrared sensor",
+			Port:   string(self.port),
+			Detail: fmt.Sprintf("channel %d, expected Channel1 to Channel4", c),
+		}
+	}
+
+	lock := deviceLock(self.path)
+	lock.Lock()
+	defer lock.Unlock()
 
+	if err := self.opts.switchMode(self.path, "IR-SEEK"); err != nil {
+		return BeaconReading{}, err
+	}
+	heading, err := utilities.ReadValue[int16](self.path, fmt.Sprintf("value%d", 2*c))
+	if err != nil {
+		return BeaconReading{}, err
+	}
+	distance, err := utilities.ReadValue[int16](self.path, fmt.Sprintf("value%d", 2*c+1))
+	if err != nil {
+		return BeaconReading{}, err
+	}
+
+	return newBeaconReading(heading, distance), nil
+}
+
+func newBeaconReading(heading int16, distance int16) BeaconReading {
+	if distance == noBeacon {
+		return BeaconReading{}
+	}
+
+	return BeaconReading{Heading: heading, Distance: distance, Detected: true}
+}
+
+// Reads the beacon on the given channel, returning an error matching
+// Errors.ErrOutOfRange if the channel is invalid, and the error of the read.
+// Same as TryReadIRSEEK.
+func (self *InfraredSensor) ReadBeacon(c Channel) (BeaconReading, error) {
+	return self.TryReadIRSEEK(c)
+}
+
+// Reads the beacons on the four channels in one pass, indexed by channel.
+// Channels that fail to read report no beacon.
+func (self *InfraredSensor) ReadAllBeacons() [4]BeaconReading {
+	var readings [4]BeaconReading
+
+	lock := deviceLock(self.path)
+	lock.Lock()
+	defer lock.Unlock()
+
+	if err := self.opts.switchMode(self.path, "IR-SEEK"); err != nil {
+		return readings
+	}
+
+	for c := range readings {
+		heading, err := utilities.ReadValue[int16](self.path, fmt.Sprintf("value%d", 2*c))
+		if err != nil {
+			continue
+		}
+		distance, err := utilities.ReadValue[int16](self.path, fmt.Sprintf("value%d", 2*c+1))
+		if err != nil {
+			continue
+		}
+
+		readings[c] = newBeaconReading(heading, distance)
+	}
+
+	return readings
+}
+
+// Reads the proximity value (in range 0 - 100) reported by the infrared sensor. A value of 100 corresponds to a range of approximately 70 cm.
+func (self *InfraredSensor) ReadProximity() uint8 {
+	value, _ := self.TryReadProximity()
 	return value
 }
 
-// Blocks until the infrared sensor detects a nearby object.
+// Reads the proximity like ReadProximity, but returns the error of the read,
+// e.g. an *utilities.IOError matching Errors.ErrDisconnected or Errors.ErrTimeout.
+func (self *InfraredSensor) TryReadProximity() (uint8, error) {
+	return readInMode[uint8](self.opts, self.path, "IR-PROX", "value0")
+}
+
+// Blocks until the infrared sensor detects a nearby object: a proximity under
+// 20 percent, or the threshold set with WithProximityThreshold, twice in a row.
 func (self *InfraredSensor) WaitForProximity() {
+	self.waitForProximity(nil)
+}
 
+// Waits like WaitForProximity, returning false if a value is sent to `stop` first.
+func (self *InfraredSensor) waitForProximity(stop <-chan bool) bool {
 	for {
 		p1 := self.ReadProximity()
-		time.Sleep(time.Millisecond * 100)
+		select {
+		case <-stop:
+			return false
+		case <-time.After(self.opts.pollInterval(100 * time.Millisecond)):
+		}
 		p2 := self.ReadProximity()
 
-		if p1 < 20 && p2 < 20 {
-			return
+		threshold := self.opts.proximity
+		if threshold == 0 {
+			threshold = 20
+		}
+
+		if p1 < threshold && p2 < threshold {
+			return true
 		}
 	}
 }
 
 // Turns on the remote control mode.
 func (self *InfraredSensor) RemoteModeOn() {
-	utilities.WriteStringValue(self.path, "mode", "IR-REMOTE")
-}
-
-// Registers a callback to be triggered when a remote button is pressed. The listening
-// can be stopped by sending any boolean value to a `stop` channel.
-func (self *InfraredSensor) OnRemotePressed(stop <-chan bool, fn func(c Channel, b Button)) {
-	self.RemoteModeOn()
-	s := make(chan RemoteSignal, 50)
-
-	go func() {
-		pressed := map[uint64]bool{}
-		for {
-			select {
-			case <-stop:
-				return
-			case signal := <-s:
-				c := parseChannel(signal.Name)
-
-				if signal.Value == 0 {
-					for b := RedUp; b <= BlueDown; b++ {
-						pressed[buttonID(c, b)] = false
-					}
-					continue
-				}
-				k := buttonID(c, Button(signal.Value))
-				if v, ok := pressed[k]; ok && v {
-					continue
-				}
-				pressed[k] = true
-				fn(c, Button(signal.Value))
-			}
-		}
-	}()
-	self.pollRemote(s, stop)
-}
-
-// Registers a callback to be triggered when a remote button is released. The listening
-// can be stopped by sending any boolean value to a `stop` channel.
-func (self *InfraredSensor) OnRemoteReleased(stop <-chan bool, fn func(c Channel, b Button)) {
-	self.RemoteModeOn()
-	s := make(chan RemoteSignal, 50)
-
-	go func() {
-		pressed := map[uint64]bool{}
-		for {
-			select {
-			case <-stop:
-				return
-			case signal := <-s:
-				c := parseChannel(signal.Name)
-
-				if signal.Value != 0 {
-					pressed[buttonID(c, Button(signal.Value))] = true
-					continue
-				}
-				for b := RedUp; b <= BlueDown; b++ {
-					if v, ok := pressed[buttonID(c, b)]; ok && v {
-						fn(c, b)
-						pressed[buttonID(c, b)] = false
-					}
-				}
-			}
+	self.WriteMode("IR-REMOTE")
+}
+
+// Buttons held for the codes the remote sends when two of them are pressed together.
+var remoteCombinations = map[Button][]Button{
+	RedUpBlueUp:     {RedUp, BlueUp},
+	RedUpBlueDown:   {RedUp, BlueDown},
+	RedDownBlueUp:   {RedDown, BlueUp},
+	RedDownBlueDown: {RedDown, BlueDown},
+	RedUpRedDown:    {RedUp, RedDown},
+	BlueUpBlueDown:  {BlueUp, BlueDown},
+}
+
+// Returns the buttons held for a code read with ReadRemote: none for 0, two
+// for the combinations, and the code itself otherwise.
+func (self Button) Buttons() []Button {
+	if self == 0 {
+		return nil
+	}
+
+	if buttons, ok := remoteCombinations[self]; ok {
+		return append([]Button(nil), buttons...)
+	}
+
+	return []Button{self}
+}
+
+// Reports whether the code read with ReadRemote includes button `b`, which
+// it does when the code is `b` or one of the combinations of two buttons.
+func (self Button) Has(b Button) bool {
+	for _, c := range self.Buttons() {
+		if c == b {
+			return true
 		}
-	}()
-	self.pollRemote(s, stop)
-}
-
-func parseChannel(name string) Channel {
-	var c Channel
-	switch name {
-	case "value0":
-		c = Channel1
-	case "value1":
-		c = Channel2
-	case "value2":
-		c = Channel3
-	case "value3":
-		c = Channel4
-	default:
-		log.Fatal("Invalid channel")
-	}
-	return c
-}
-
-func buttonID(c Channel, b Button) uint64 {
-	return uint64(c)*10 + uint64(b)
-}
-
-func (self *InfraredSensor) pollRemote(s chan<- RemoteSignal, stop <-chan bool) {
-	snr := findSensor(self.port, TypeInfrared)
-	for i := 0; i < 4; i++ {
-		name := fmt.Sprintf("value%d", i)
-		p := fmt.Sprintf("%s/%s/%s", baseSensorPath, snr, name)
-		go func() {
-			f, err := os.Open(p)
-			defer f.Close()
-			if err != nil {
-				log.Fatal(err)
-			}
-			for {
-				select {
-				case <-stop:
-					return
-				default:
-				}
-
-				data, err := ioutil.ReadAll(f)
-				if err != nil {
-					log.Fatal(err)
-				}
-				_, err = f.Seek(0, 0)
-				if err != nil {
-					log.Fatal(err)
-				}
-				b, err := strconv.ParseUint(strings.Trim(string(data), " \n"), 10, 16)
-				if err != nil {
-					log.Fatal(err)
-				}
-				s <- RemoteSignal{name, b}
-				time.Sleep(time.Millisecond * time.Duration(REMOTE_POLLING_INTERVAL))
-			}
-		}()
 	}
+
+	return false
+}
+
+// Reads the code of the remote buttons held on the given channel: 0 if none,
+// a single button, Beacon, or a combination of two buttons. See Button.Has.
+func (self *InfraredSensor) ReadRemote(c Channel) ButtonState {
+	value, _ := self.TryReadRemote(c)
+	return value
+}
+
+// Reads the remote buttons like ReadRemote, but returns the error of the read.
+func (self *InfraredSensor) TryReadRemote(c Channel) (ButtonState, error) {
+	value, err := readInMode[uint8](self.opts, self.path, "IR-REMOTE", fmt.Sprintf("value%d", c))
+	return ButtonState(value), err
 }
@@ -0,0 +1,96 @@
+package Sensors
+
+import (
+	"github.com/jermon/GoEV3/utilities"
+)
+
+// NXT light sensor type.
+type NXTLightSensor struct {
+	port InPort
+	opts options
+}
+
+// Provides access to an NXT light sensor at the given port.
+// A missing sensor is a fatal error; use OpenNXTLightSensor to handle it.
+func FindNXTLightSensor(port InPort, opts ...Option) *NXTLightSensor {
+	s, err := OpenNXTLightSensor(port, opts...)
+	if err != nil {
+		utilities.Fatal(err)
+	}
+
+	return s
+}
+
+// Provides access to an NXT light sensor at the given port. Returns an error
+// matching Errors.ErrDeviceNotFound, Errors.ErrPortMismatch or
+// Errors.ErrInvalidMode instead of exiting if the sensor can't be set up.
+func OpenNXTLightSensor(port InPort, opts ...Option) (*NXTLightSensor, error) {
+	port = CanonicalInPort(string(port))
+
+	s := new(NXTLightSensor)
+	s.port = port
+	s.opts = newOptions(TypeNXTLight, opts)
+	if _, err := s.opts.setUp(port, ""); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// Returns the input port the sensor is connected to.
+func (self *NXTLightSensor) Port() InPort {
+	return self.port
+}
+
+// Puts the sensor into `mode`, "REFLECT" or "AMBIENT", e.g. to read it
+// WithoutAutoModeSwitch. Returns an error matching Errors.ErrInvalidMode if
+// the sensor doesn't have the mode, Errors.ErrDeviceNotFound if it is
+// missing, and the error of the write.
+func (self *NXTLightSensor) SetMode(mode string) error {
+	path, err := sensorPath(self.port, self.opts)
+	if err != nil {
+		return err
+	}
+
+	return setMode(self.port, path, mode)
+}
+
+// Closes the sensor's attribute files kept open, once the program is done
+// with it; see utilities.IOStrategy. Reading the sensor again opens them again.
+func (self *NXTLightSensor) Close() {
+	if path, err := sensorPath(self.port, self.opts); err == nil {
+		utilities.ReleaseFiles(path)
+	}
+}
+
+// Reads the light reflected from the sensor's own LED, in percent, with a
+// resolution of a tenth. A missing sensor is a fatal error.
+func (self *NXTLightSensor) ReadReflectedLight() float64 {
+	value, err := self.TryReadReflectedLight()
+	exitIfMissing(err)
+
+	return value
+}
+
+// Reads the reflected light like ReadReflectedLight, but returns an error
+// matching Errors.ErrDeviceNotFound instead of exiting if the sensor is
+// missing, and the error of the read.
+func (self *NXTLightSensor) TryReadReflectedLight() (float64, error) {
+	return readScaledAt(self.port, self.opts, "REFLECT")
+}
+
+// Reads the ambient light, with the LED off, in percent. A missing sensor is
+// a fatal error.
+func (self *NXTLightSensor) ReadAmbientLight() float64 {
+	value, err := self.TryReadAmbientLight()
+	exitIfMissing(err)
+
+	return value
+}
+
+// Reads the ambient light like ReadAmbientLight, but returns an error
+// matching Errors.ErrDeviceNotFound instead of exiting if the sensor is
+// missing, and the error of the read.
+func (self *NXTLightSensor) TryReadAmbientLight() (float64, error) {
+	return readScaledAt(self.port, self.opts, "AMBIENT")
+}
@@ -0,0 +1,97 @@
+package Sensors
+
+import (
+	"github.com/jermon/GoEV3/utilities"
+)
+
+// NXT sound sensor type.
+type NXTSoundSensor struct {
+	port InPort
+	opts options
+}
+
+// Provides access to an NXT sound sensor at the given port.
+// A missing sensor is a fatal error; use OpenNXTSoundSensor to handle it.
+func FindNXTSoundSensor(port InPort, opts ...Option) *NXTSoundSensor {
+	s, err := OpenNXTSoundSensor(port, opts...)
+	if err != nil {
+		utilities.Fatal(err)
+	}
+
+	return s
+}
+
+// Provides access to an NXT sound sensor at the given port. Returns an error
+// matching Errors.ErrDeviceNotFound, Errors.ErrPortMismatch or
+// Errors.ErrInvalidMode instead of exiting if the sensor can't be set up.
+func OpenNXTSoundSensor(port InPort, opts ...Option) (*NXTSoundSensor, error) {
+	port = CanonicalInPort(string(port))
+
+	s := new(NXTSoundSensor)
+	s.port = port
+	s.opts = newOptions(TypeNXTSound, opts)
+	if _, err := s.opts.setUp(port, ""); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// Returns the input port the sensor is connected to.
+func (self *NXTSoundSensor) Port() InPort {
+	return self.port
+}
+
+// Puts the sensor into `mode`, "DB" or "DBA", e.g. to read it
+// WithoutAutoModeSwitch. Returns an error matching Errors.ErrInvalidMode if
+// the sensor doesn't have the mode, Errors.ErrDeviceNotFound if it is
+// missing, and the error of the write.
+func (self *NXTSoundSensor) SetMode(mode string) error {
+	path, err := sensorPath(self.port, self.opts)
+	if err != nil {
+		return err
+	}
+
+	return setMode(self.port, path, mode)
+}
+
+// Closes the sensor's attribute files kept open, once the program is done
+// with it; see utilities.IOStrategy. Reading the sensor again opens them again.
+func (self *NXTSoundSensor) Close() {
+	if path, err := sensorPath(self.port, self.opts); err == nil {
+		utilities.ReleaseFiles(path)
+	}
+}
+
+// Reads the sound pressure level with flat (dB) weighting, in percent of the
+// sensor's range, with a resolution of a tenth. A missing sensor is a fatal error.
+func (self *NXTSoundSensor) ReadDecibels() float64 {
+	value, err := self.TryReadDecibels()
+	exitIfMissing(err)
+
+	return value
+}
+
+// Reads the sound pressure level like ReadDecibels, but returns an error
+// matching Errors.ErrDeviceNotFound instead of exiting if the sensor is
+// missing, and the error of the read.
+func (self *NXTSoundSensor) TryReadDecibels() (float64, error) {
+	return readScaledAt(self.port, self.opts, "DB")
+}
+
+// Reads the sound pressure level with A (dBA) weighting, which follows the
+// sensitivity of the human ear, in percent of the sensor's range. A missing
+// sensor is a fatal error.
+func (self *NXTSoundSensor) ReadAdjustedDecibels() float64 {
+	value, err := self.TryReadAdjustedDecibels()
+	exitIfMissing(err)
+
+	return value
+}
+
+// Reads the sound pressure level like ReadAdjustedDecibels, but returns an
+// error matching Errors.ErrDeviceNotFound instead of exiting if the sensor is
+// missing, and the error of the read.
+func (self *NXTSoundSensor) TryReadAdjustedDecibels() (float64, error) {
+	return readScaledAt(self.port, self.opts, "DBA")
+}
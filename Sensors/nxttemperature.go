@@ -0,0 +1,96 @@
+package Sensors
+
+import (
+	"github.com/jermon/GoEV3/utilities"
+)
+
+// NXT temperature sensor type.
+type NXTTemperatureSensor struct {
+	port InPort
+	opts options
+}
+
+// Provides access to an NXT temperature sensor at the given port.
+// A missing sensor is a fatal error; use OpenNXTTemperatureSensor to handle it.
+func FindNXTTemperatureSensor(port InPort, opts ...Option) *NXTTemperatureSensor {
+	s, err := OpenNXTTemperatureSensor(port, opts...)
+	if err != nil {
+		utilities.Fatal(err)
+	}
+
+	return s
+}
+
+// Provides access to an NXT temperature sensor at the given port. Returns an
+// error matching Errors.ErrDeviceNotFound, Errors.ErrPortMismatch or
+// Errors.ErrInvalidMode instead of exiting if the sensor can't be set up.
+func OpenNXTTemperatureSensor(port InPort, opts ...Option) (*NXTTemperatureSensor, error) {
+	port = CanonicalInPort(string(port))
+
+	s := new(NXTTemperatureSensor)
+	s.port = port
+	s.opts = newOptions(TypeNXTTemperature, opts)
+	if _, err := s.opts.setUp(port, ""); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// Returns the input port the sensor is connected to.
+func (self *NXTTemperatureSensor) Port() InPort {
+	return self.port
+}
+
+// Puts the sensor into `mode`, "NXT-TEMP-C" or "NXT-TEMP-F", e.g. to read it
+// WithoutAutoModeSwitch. Returns an error matching Errors.ErrInvalidMode if
+// the sensor doesn't have the mode, Errors.ErrDeviceNotFound if it is
+// missing, and the error of the write.
+func (self *NXTTemperatureSensor) SetMode(mode string) error {
+	path, err := sensorPath(self.port, self.opts)
+	if err != nil {
+		return err
+	}
+
+	return setMode(self.port, path, mode)
+}
+
+// Closes the sensor's attribute files kept open, once the program is done
+// with it; see utilities.IOStrategy. Reading the sensor again opens them again.
+func (self *NXTTemperatureSensor) Close() {
+	if path, err := sensorPath(self.port, self.opts); err == nil {
+		utilities.ReleaseFiles(path)
+	}
+}
+
+// Reads the temperature in degrees Celsius, with a resolution of a tenth of a
+// degree, in range [-55, 128]. A missing sensor is a fatal error.
+func (self *NXTTemperatureSensor) ReadCelsius() float64 {
+	value, err := self.TryReadCelsius()
+	exitIfMissing(err)
+
+	return value
+}
+
+// Reads the temperature like ReadCelsius, but returns an error matching
+// Errors.ErrDeviceNotFound instead of exiting if the sensor is missing, and
+// the error of the read.
+func (self *NXTTemperatureSensor) TryReadCelsius() (float64, error) {
+	return readScaledAt(self.port, self.opts, "NXT-TEMP-C")
+}
+
+// Reads the temperature in degrees Fahrenheit, with a resolution of a tenth
+// of a degree, in range [-67, 262.4]. A missing sensor is a fatal error.
+func (self *NXTTemperatureSensor) ReadFahrenheit() float64 {
+	value, err := self.TryReadFahrenheit()
+	exitIfMissing(err)
+
+	return value
+}
+
+// Reads the temperature like ReadFahrenheit, but returns an error matching
+// Errors.ErrDeviceNotFound instead of exiting if the sensor is missing, and
+// the error of the read.
+func (self *NXTTemperatureSensor) TryReadFahrenheit() (float64, error) {
+	return readScaledAt(self.port, self.opts, "NXT-TEMP-F")
+}
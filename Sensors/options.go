@@ -0,0 +1,236 @@
+package Sensors
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jermon/GoEV3/Errors"
+	"github.com/jermon/GoEV3/utilities"
+)
+
+// Customizes how a Find* constructor looks up and sets up a sensor.
+type Option func(*options)
+
+type options struct {
+	timeout  time.Duration
+	driver   Type
+	mode     string
+	noSwitch bool
+	ambient  time.Duration
+	io       time.Duration
+	readBack bool
+	poll     time.Duration
+	// Proximity under which an object is near, 0 for the default.
+	proximity uint8
+}
+
+// Waits up to `timeout` for the sensor to appear whenever it is looked up,
+// e.g. while its driver is still loading at boot. By default a missing sensor
+// is a fatal error straight away.
+func WithTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.timeout = timeout
+	}
+}
+
+// Accepts only a sensor with the given driver, e.g. "nxt-analog", instead of
+// the LEGO EV3 sensor the constructor is named after.
+func WithRequiredDriver(driver string) Option {
+	return func(o *options) {
+		o.driver = Type(driver)
+	}
+}
+
+// Puts the sensor into the given mode when it is found.
+func WithInitialMode(mode string) Option {
+	return func(o *options) {
+		o.mode = mode
+	}
+}
+
+// Stops reads from switching the sensor into the mode they need. Reads then
+// report the values of whatever mode the sensor is in, set with WithInitialMode
+// or by another program, which avoids the delay of a mode switch.
+func WithoutAutoModeSwitch() Option {
+	return func(o *options) {
+		o.noSwitch = true
+	}
+}
+
+// Makes a color sensor compensate its reflected light and RGB reads for the
+// lighting of the room, by subtracting the ambient light it reads at most
+// `interval` apart between them. Thresholds calibrated with it on hold under
+// brighter or dimmer lights. It has no effect with WithoutAutoModeSwitch.
+func WithAmbientCompensation(interval time.Duration) Option {
+	return func(o *options) {
+		o.ambient = interval
+	}
+}
+
+// Makes reads and writes of the sensor fail with an error matching
+// Errors.ErrTimeout once they take longer than `timeout`; see
+// utilities.SetIOTimeout.
+func WithIOTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.io = timeout
+	}
+}
+
+// Makes reads check the mode the sensor is in before switching it, by
+// reading it back, instead of trusting the mode this program last put it
+// into. Slower, but safe when another program may switch the sensor's mode.
+func WithModeReadBack() Option {
+	return func(o *options) {
+		o.readBack = true
+	}
+}
+
+// Sets how often the sensor's waits and callbacks read it, e.g. to watch one
+// sensor quickly and another slowly. By default they use the package's
+// intervals, such as TOUCH_POLLING_INTERVAL. For the remote buttons of an
+// infrared sensor it is the longest interval between reads, and applies to
+// all the listeners of the sensor if set on the first to listen.
+func WithPollInterval(interval time.Duration) Option {
+	return func(o *options) {
+		o.poll = interval
+	}
+}
+
+// Sets the proximity, in percent, under which WaitForProximity reports an
+// object. Defaults to 20.
+func WithProximityThreshold(percent uint8) Option {
+	return func(o *options) {
+		o.proximity = percent
+	}
+}
+
+// Returns the interval set with WithPollInterval, or `fallback`.
+func (self options) pollInterval(fallback time.Duration) time.Duration {
+	if self.poll > 0 {
+		return self.poll
+	}
+
+	return fallback
+}
+
+// Returns the intervals of a watch: those of the package, or the interval set
+// with WithPollInterval as the longest.
+func (self options) watchIntervals() (time.Duration, time.Duration) {
+	shortest := time.Duration(utilities.WATCH_MIN_INTERVAL) * time.Millisecond
+	longest := self.pollInterval(time.Duration(utilities.WATCH_MAX_INTERVAL) * time.Millisecond)
+
+	return min(shortest, longest), longest
+}
+
+func newOptions(t Type, opts []Option) options {
+	o := options{driver: t}
+
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return o
+}
+
+// Finds the sensor and applies the initial mode. Returns the sensor's path.
+func (self options) setUp(port InPort, defaultMode string) (string, error) {
+	name, err := locateSensor(port, self)
+	if err != nil {
+		return "", err
+	}
+	path := baseSensorPath + "/" + name
+
+	if self.io != 0 {
+		utilities.SetDeviceIOTimeout(path, self.io)
+	}
+
+	switch {
+	case self.mode != "":
+		if err := checkMode(port, path, self.mode); err != nil {
+			return "", err
+		}
+		forceMode(path, self.mode)
+	case defaultMode != "" && !self.noSwitch:
+		forceMode(path, defaultMode)
+	}
+
+	return path, nil
+}
+
+// Returns an error matching Errors.ErrInvalidMode if the sensor at `path`
+// doesn't list `mode` among its modes.
+func checkMode(port InPort, path string, mode string) error {
+	modes := strings.Fields(utilities.ReadStringValue(path, "modes"))
+	if len(modes) == 0 {
+		return nil
+	}
+
+	for _, m := range modes {
+		if m == mode {
+			return nil
+		}
+	}
+
+	return &Errors.DeviceError{
+		Kind:   Errors.ErrInvalidMode,
+		Device: "sensor",
+		Port:   string(port),
+		Detail: fmt.Sprintf("%q, expected one of %v", mode, strings.Join(modes, ", ")),
+	}
+}
+
+// Switches the sensor at `path` into `mode` unless automatic switching is disabled.
+func (self options) switchMode(path string, mode string) error {
+	if self.noSwitch {
+		return nil
+	}
+
+	if self.readBack {
+		if current := utilities.ReadStringValue(path, "mode"); current == mode {
+			rememberMode(path, mode)
+			return nil
+		}
+		return forceMode(path, mode)
+	}
+
+	return writeMode(path, mode)
+}
+
+// Puts the sensor at `path` into `mode`, after checking that it has it.
+func setMode(port InPort, path string, mode string) error {
+	if err := checkMode(port, path, mode); err != nil {
+		return err
+	}
+
+	lock := deviceLock(path)
+	lock.Lock()
+	defer lock.Unlock()
+
+	return forceMode(path, mode)
+}
+
+// Switches the sensor at `path` into `mode` like switchMode and reads the
+// attribute `basename`, returning the first error.
+func readInMode[T utilities.Value](o options, path string, mode string, basename string) (T, error) {
+	lock := deviceLock(path)
+	lock.Lock()
+	defer lock.Unlock()
+
+	if err := o.switchMode(path, mode); err != nil {
+		var zero T
+		return zero, err
+	}
+
+	return utilities.ReadValue[T](path, basename)
+}
+
+// Reads the attribute `basename` of the sensor at `path` in whatever mode it
+// is in, but not while another goroutine switches it.
+func readLocked[T utilities.Value](path string, basename string) (T, error) {
+	lock := deviceLock(path)
+	lock.Lock()
+	defer lock.Unlock()
+
+	return utilities.ReadValue[T](path, basename)
+}
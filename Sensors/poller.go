@@ -0,0 +1,118 @@
+package Sensors
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/jermon/GoEV3/utilities"
+)
+
+// A value read by a Poller.
+type Sample struct {
+	// Name the value was added to the poller with.
+	Name  string
+	Value float64
+	Time  time.Time
+}
+
+// Samples not yet received by a subscriber, beyond which its samples are
+// dropped rather than holding up the other subscribers.
+const sampleQueueSize = 50
+
+// Samples a set of values at a common rate and sends them to any number of
+// subscribers, so that callers don't each poll the sensors:
+//
+//	poller := Sensors.NewPoller()
+//	poller.Add("distance", us.ReadDistanceCentimeters, 0.5)
+//	poller.Add("angle", func() float64 { return float64(gyro.ReadAngle()) }, 1)
+//	go poller.Run(stop, 20*time.Millisecond)
+//
+//	for s := range poller.Subscribe(done) {
+//		...
+//	}
+type Poller struct {
+	lock        sync.Mutex
+	sources     []*pollSource
+	subscribers map[chan Sample]bool
+}
+
+type pollSource struct {
+	name     string
+	read     func() float64
+	deadband float64
+	last     float64
+	sampled  bool
+}
+
+// Creates a poller with no values.
+func NewPoller() *Poller {
+	p := new(Poller)
+	p.subscribers = make(map[chan Sample]bool)
+
+	return p
+}
+
+// Adds a value to sample, read by `read`. A reading is only sent once it
+// differs from the last one sent by more than `deadband`; 0 sends every change.
+func (self *Poller) Add(name string, read func() float64, deadband float64) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	self.sources = append(self.sources, &pollSource{name: name, read: read, deadband: deadband})
+}
+
+// Returns a channel receiving the samples, starting with the first reading
+// of each value. Sending a value to `stop`, or closing it, ends the
+// subscription and closes the returned channel. A subscriber falling more
+// than 50 samples behind misses the new ones.
+func (self *Poller) Subscribe(stop <-chan bool) <-chan Sample {
+	c := make(chan Sample, sampleQueueSize)
+
+	self.lock.Lock()
+	self.subscribers[c] = true
+	// New subscribers get the current values, sent to all subscribers again.
+	for _, s := range self.sources {
+		s.sampled = false
+	}
+	self.lock.Unlock()
+
+	go func() {
+		<-stop
+
+		self.lock.Lock()
+		delete(self.subscribers, c)
+		close(c)
+		self.lock.Unlock()
+	}()
+
+	return c
+}
+
+// Reads all values once and sends those that changed.
+func (self *Poller) Sample() {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	for _, s := range self.sources {
+		value := s.read()
+		if s.sampled && math.Abs(value-s.last) <= s.deadband {
+			continue
+		}
+		s.last, s.sampled = value, true
+
+		sample := Sample{s.name, value, time.Now()}
+		for c := range self.subscribers {
+			select {
+			case c <- sample:
+			default:
+			}
+		}
+	}
+}
+
+// Samples the values every `interval`, from the shared polling scheduler,
+// until a value is sent to `stop`.
+func (self *Poller) Run(stop <-chan bool, interval time.Duration) {
+	utilities.PollUntil(stop, interval, self.Sample)
+}
@@ -0,0 +1,275 @@
+package Sensors
+
+import (
+	"sync"
+
+	"github.com/jermon/GoEV3/utilities"
+)
+
+// A remote button pressed or released.
+type RemoteEvent struct {
+	Channel Channel
+	Button  Button
+	// True when the button was pressed, false when it was released.
+	Pressed bool
+	// Buttons held on the channel once the event happened.
+	Held []Button
+}
+
+// Events not yet handled by a listener, beyond which its new events are
+// dropped rather than holding up the other listeners.
+const remoteQueueSize = 50
+
+// Receives the remote events of an infrared sensor, until removed.
+type RemoteListener struct {
+	poller *remotePoller
+	events chan RemoteEvent
+	done   chan bool
+	once   sync.Once
+}
+
+// Stops delivering events to the listener. Safe to call more than once.
+func (self *RemoteListener) Remove() {
+	self.once.Do(func() {
+		close(self.done)
+		self.poller.remove(self)
+	})
+}
+
+// Watches the remote buttons of a sensor for all its listeners, so that they
+// don't each poll the sensor.
+type remotePoller struct {
+	sensor *InfraredSensor
+	watch  *utilities.Watch[[4]Button]
+
+	lock      sync.Mutex
+	listeners []*RemoteListener
+	held      [4][]Button
+}
+
+// Pollers by sensor path.
+var gRemotePollers = make(map[string]*remotePoller)
+var gRemotePollersLock = &sync.Mutex{}
+
+// Registers a listener with the sensor's poller, starting it if needed. The
+// listener is removed once a value is sent to `stop`, or `stop` is closed.
+func (self *InfraredSensor) listenRemote(stop <-chan bool) *RemoteListener {
+	gRemotePollersLock.Lock()
+	defer gRemotePollersLock.Unlock()
+
+	p, ok := gRemotePollers[self.path]
+	if !ok {
+		p = &remotePoller{sensor: self}
+		gRemotePollers[self.path] = p
+	}
+
+	l := &RemoteListener{poller: p, events: make(chan RemoteEvent, remoteQueueSize), done: make(chan bool)}
+
+	p.lock.Lock()
+	p.listeners = append(p.listeners, l)
+	p.lock.Unlock()
+
+	// Started once the first listener is in, so that it gets the buttons
+	// already held.
+	if !ok {
+		shortest, longest := self.opts.watchIntervals()
+		p.watch = utilities.WatchValueBetween(shortest, longest, p.read, p.deliver)
+	}
+
+	go func() {
+		select {
+		case <-stop:
+			l.Remove()
+		case <-l.done:
+		}
+	}()
+
+	return l
+}
+
+// Removes a listener and closes its channel, stopping the poller once no
+// listener is left.
+func (self *remotePoller) remove(l *RemoteListener) {
+	gRemotePollersLock.Lock()
+	defer gRemotePollersLock.Unlock()
+
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	for i, x := range self.listeners {
+		if x == l {
+			self.listeners = append(self.listeners[:i], self.listeners[i+1:]...)
+			close(l.events)
+			break
+		}
+	}
+
+	if len(self.listeners) == 0 && gRemotePollers[self.sensor.path] == self {
+		self.watch.Cancel()
+		delete(gRemotePollers, self.sensor.path)
+	}
+}
+
+// Reads the buttons held on the four channels.
+func (self *remotePoller) read() [4]Button {
+	var codes [4]Button
+	for c := range codes {
+		codes[c] = self.sensor.ReadRemote(Channel(c))
+	}
+
+	return codes
+}
+
+// Delivers the changes to the buttons held to the listeners.
+func (self *remotePoller) deliver(codes [4]Button) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	for c, code := range codes {
+		current := code.Buttons()
+
+		for _, e := range remoteChanges(Channel(c), self.held[c], current) {
+			for _, l := range self.listeners {
+				select {
+				case l.events <- e:
+				default:
+				}
+			}
+		}
+		self.held[c] = current
+	}
+}
+
+// Returns the events that lead from the buttons held `before` to those held `after`.
+func remoteChanges(c Channel, before []Button, after []Button) []RemoteEvent {
+	contains := func(buttons []Button, b Button) bool {
+		for _, x := range buttons {
+			if x == b {
+				return true
+			}
+		}
+		return false
+	}
+
+	var events []RemoteEvent
+	for _, b := range before {
+		if !contains(after, b) {
+			events = append(events, RemoteEvent{Channel: c, Button: b, Held: after})
+		}
+	}
+	for _, b := range after {
+		if !contains(before, b) {
+			events = append(events, RemoteEvent{Channel: c, Button: b, Pressed: true, Held: after})
+		}
+	}
+
+	return events
+}
+
+// Returns a channel receiving remote button presses and releases, for handling
+// the remote within a select loop:
+//
+//	events := ir.RemoteEvents(stop)
+//	for {
+//		select {
+//		case e := <-events:
+//			if e.Pressed && e.Button == Sensors.Beacon {
+//				...
+//			}
+//		case <-done:
+//			...
+//		}
+//	}
+//
+// The channels are watched with utilities.WatchValue, by a poller shared
+// with the sensor's other listeners, so that presses are seen within
+// utilities.WATCH_MAX_INTERVAL. Sending a value to `stop`, or closing
+// it, ends the listening and closes the returned channel. Each button of a
+// combination held together is reported on its own.
+func (self *InfraredSensor) RemoteEvents(stop <-chan bool) <-chan RemoteEvent {
+	return self.listenRemote(stop).events
+}
+
+// Calls `fn` for the events of the listener, in a goroutine of its own.
+func (self *RemoteListener) handle(fn func(e RemoteEvent)) *RemoteListener {
+	go func() {
+		for e := range self.events {
+			fn(e)
+		}
+	}()
+
+	return self
+}
+
+// Registers a callback to be triggered when a remote button is pressed. The listening
+// can be stopped by sending any boolean value to a `stop` channel, or with the
+// returned listener's Remove. Any number of listeners can share a sensor.
+func (self *InfraredSensor) OnRemotePressed(stop <-chan bool, fn func(c Channel, b Button)) *RemoteListener {
+	return self.listenRemote(stop).handle(func(e RemoteEvent) {
+		if e.Pressed {
+			fn(e.Channel, e.Button)
+		}
+	})
+}
+
+// Registers a callback to be triggered when a remote button is released. The listening
+// can be stopped by sending any boolean value to a `stop` channel, or with the
+// returned listener's Remove. Any number of listeners can share a sensor.
+func (self *InfraredSensor) OnRemoteReleased(stop <-chan bool, fn func(c Channel, b Button)) *RemoteListener {
+	return self.listenRemote(stop).handle(func(e RemoteEvent) {
+		if !e.Pressed {
+			fn(e.Channel, e.Button)
+		}
+	})
+}
+
+// Registers a callback to be triggered when the set of remote buttons held on
+// a channel changes, with the code of the buttons now held: 0 once all are
+// released, a single button, Beacon, or a combination such as RedUpBlueUp.
+// Use Button.Has or Button.Buttons to take the code apart. The listening is
+// stopped like with OnRemotePressed.
+func (self *InfraredSensor) OnRemoteChanged(stop <-chan bool, fn func(c Channel, held Button)) *RemoteListener {
+	var last [4][]Button
+
+	return self.listenRemote(stop).handle(func(e RemoteEvent) {
+		// The events of a single change share the buttons held.
+		if sameButtons(last[e.Channel], e.Held) {
+			return
+		}
+		last[e.Channel] = e.Held
+
+		fn(e.Channel, buttonCode(e.Held))
+	})
+}
+
+func sameButtons(a []Button, b []Button) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Returns the code the remote sends for the buttons held.
+func buttonCode(held []Button) Button {
+	switch len(held) {
+	case 0:
+		return 0
+	case 1:
+		return held[0]
+	}
+
+	for code, buttons := range remoteCombinations {
+		if sameButtons(buttons, held) {
+			return code
+		}
+	}
+
+	return 0
+}
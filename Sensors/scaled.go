@@ -0,0 +1,122 @@
+package Sensors
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/jermon/GoEV3/utilities"
+)
+
+// Switches the sensor at `path` into `mode` and reads value `n`, scaled by
+// the mode's decimal places, with the mode's units.
+func readScaled(o options, path string, mode string, n int) (float64, string, error) {
+	lock := deviceLock(path)
+	lock.Lock()
+	defer lock.Unlock()
+
+	if err := o.switchMode(path, mode); err != nil {
+		return 0, "", err
+	}
+
+	value, err := utilities.ReadDecimalValue(path, fmt.Sprintf("value%d", n))
+	if err != nil {
+		return 0, "", err
+	}
+
+	return value, utilities.ReadStringValue(path, "units"), nil
+}
+
+// Reads value 0 of `mode` of the sensor at `port`, scaled like readScaled.
+func readScaledAt(port InPort, o options, mode string) (float64, error) {
+	path, err := sensorPath(port, o)
+	if err != nil {
+		return 0, err
+	}
+
+	value, _, err := readScaled(o, path, mode, 0)
+	return value, err
+}
+
+// Reads the number of decimal places and the units of the sensor's current mode.
+func readScale(path string) (float64, string) {
+	decimals, _ := utilities.ReadValue[int](path, "decimals")
+	return math.Pow10(-decimals), utilities.ReadStringValue(path, "units")
+}
+
+// Reads value `n` of `mode`, e.g. "COL-REFLECT", scaled by the decimal places
+// the sensor reports, and its units, e.g. "pct". The sensor is switched into
+// the mode like the other reads.
+func (self *ColorSensor) ReadScaled(mode string, n int) (float64, string, error) {
+	return readScaled(self.opts, self.path, mode, n)
+}
+
+// Returns the units of the values of the sensor's current mode.
+func (self *ColorSensor) Units() string {
+	_, units := readScale(self.path)
+	return units
+}
+
+// Reads value `n` of `mode`, e.g. "IR-PROX", scaled as ColorSensor.ReadScaled does.
+func (self *InfraredSensor) ReadScaled(mode string, n int) (float64, string, error) {
+	return readScaled(self.opts, self.path, mode, n)
+}
+
+// Returns the units of the values of the sensor's current mode.
+func (self *InfraredSensor) Units() string {
+	_, units := readScale(self.path)
+	return units
+}
+
+// Reads value `n` of `mode`, e.g. "US-DIST-CM", scaled as ColorSensor.ReadScaled
+// does, e.g. the distance in centimeters.
+func (self *UltrasonicSensor) ReadScaled(mode string, n int) (float64, string, error) {
+	path, err := sensorPath(self.port, self.opts)
+	if err != nil {
+		return 0, "", err
+	}
+
+	return readScaled(self.opts, path, mode, n)
+}
+
+// Returns the units of the values of the sensor's current mode.
+func (self *UltrasonicSensor) Units() string {
+	path, err := sensorPath(self.port, self.opts)
+	exitIfMissing(err)
+
+	_, units := readScale(path)
+	return units
+}
+
+// Reads value `n` of `mode`, e.g. "GYRO-RATE", scaled as ColorSensor.ReadScaled
+// does. Switching the mode restarts the angle.
+func (self *GyroSensor) ReadScaled(mode string, n int) (float64, string, error) {
+	path, err := sensorPath(self.port, self.opts)
+	if err != nil {
+		return 0, "", err
+	}
+
+	return readScaled(self.opts, path, mode, n)
+}
+
+// Returns the units of the values of the sensor's current mode.
+func (self *GyroSensor) Units() string {
+	path, err := sensorPath(self.port, self.opts)
+	exitIfMissing(err)
+
+	_, units := readScale(path)
+	return units
+}
+
+// Reads the angle and the rotational speed like ReadAngleAndRate, scaled by
+// the decimal places the sensor reports, in degrees and degrees per second.
+func (self *GyroSensor) ReadAngleAndRateScaled() (angle float64, rate float64, err error) {
+	path, err := sensorPath(self.port, self.opts)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	a, r, err := self.TryReadAngleAndRate()
+	scale, _ := readScale(path)
+
+	return float64(a) * scale, float64(r) * scale, err
+}
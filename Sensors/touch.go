@@ -1,38 +1,177 @@
 package Sensors
 
 import (
-	"fmt"
-	"github.com/ldmberman/GoEV3/utilities"
+	"github.com/jermon/GoEV3/utilities"
 	"time"
 )
 
 // Touch sensor type.
 type TouchSensor struct {
 	port InPort
+	opts options
 }
 
 // Provides access to a touch sensor at the given port.
-func FindTouchSensor(port InPort) *TouchSensor {
-	findSensor(port, TypeTouch)
+// A missing sensor is a fatal error; use OpenTouchSensor to handle it.
+func FindTouchSensor(port InPort, opts ...Option) *TouchSensor {
+	s, err := OpenTouchSensor(port, opts...)
+	if err != nil {
+		utilities.Fatal(err)
+	}
+
+	return s
+}
+
+// Provides access to a touch sensor at the given port. Returns an error matching
+// Errors.ErrDeviceNotFound, Errors.ErrPortMismatch or Errors.ErrInvalidMode
+// instead of exiting if the sensor can't be set up.
+func OpenTouchSensor(port InPort, opts ...Option) (*TouchSensor, error) {
+	port = CanonicalInPort(string(port))
 
 	s := new(TouchSensor)
 	s.port = port
+	s.opts = newOptions(TypeTouch, opts)
+	if _, err := s.opts.setUp(port, ""); err != nil {
+		return nil, err
+	}
 
-	return s
+	return s, nil
+}
+
+// Returns the input port the sensor is connected to.
+func (self *TouchSensor) Port() InPort {
+	return self.port
+}
+
+// Puts the sensor into `mode`, e.g. to read it WithoutAutoModeSwitch. Returns
+// an error matching Errors.ErrInvalidMode if the sensor doesn't have the
+// mode, Errors.ErrDeviceNotFound if it is missing, and the error of the write.
+func (self *TouchSensor) SetMode(mode string) error {
+	path, err := sensorPath(self.port, self.opts)
+	if err != nil {
+		return err
+	}
+
+	return setMode(self.port, path, mode)
+}
+
+// Closes the sensor's attribute files kept open, once the program is done
+// with it; see utilities.IOStrategy. Reading the sensor again opens them again.
+func (self *TouchSensor) Close() {
+	if path, err := sensorPath(self.port, self.opts); err == nil {
+		utilities.ReleaseFiles(path)
+	}
 }
 
-// Waits for the touch sensor to be pressed.
+// Interval between the reads of the waits and callbacks, in milliseconds,
+// unless set with WithPollInterval.
+var TOUCH_POLLING_INTERVAL = 50
+
+// Waits for the touch sensor to be pressed. A missing sensor is a fatal error.
 func (self *TouchSensor) Wait() {
-	snr := findSensor(self.port, TypeTouch)
-	path := fmt.Sprintf("%s/%s", baseSensorPath, snr)
+	self.waitFor(nil, true)
+}
+
+// Waits for the touch sensor to be pressed; same as Wait.
+func (self *TouchSensor) WaitForPress() {
+	self.waitFor(nil, true)
+}
+
+// Waits for the touch sensor to be released. A missing sensor is a fatal error.
+func (self *TouchSensor) WaitForRelease() {
+	self.waitFor(nil, false)
+}
+
+// Waits for the sensor to be pressed or released, returning false if a value
+// is sent to `stop` first.
+func (self *TouchSensor) waitFor(stop <-chan bool, pressed bool) bool {
+	path, err := sensorPath(self.port, self.opts)
+	exitIfMissing(err)
 
 	for {
-		value := utilities.ReadUInt8Value(path, "value0")
+		value, _ := utilities.ReadValue[uint8](path, "value0")
+
+		if (value == 1) == pressed {
+			return true
+		}
+
+		select {
+		case <-stop:
+			return false
+		case <-time.After(self.opts.pollInterval(time.Millisecond * time.Duration(TOUCH_POLLING_INTERVAL))):
+		}
+	}
+}
+
+// Registers a callback to be triggered when the touch sensor is pressed. The
+// listening can be stopped by sending any boolean value to a `stop` channel.
+// A missing sensor is a fatal error.
+func (self *TouchSensor) OnPressed(stop <-chan bool, fn func()) {
+	self.listen(stop, func(pressed bool) {
+		if pressed {
+			fn()
+		}
+	})
+}
+
+// Registers a callback to be triggered when the touch sensor is released. The
+// listening can be stopped by sending any boolean value to a `stop` channel.
+// A missing sensor is a fatal error.
+func (self *TouchSensor) OnReleased(stop <-chan bool, fn func()) {
+	self.listen(stop, func(pressed bool) {
+		if !pressed {
+			fn()
+		}
+	})
+}
+
+// Polls the sensor in a goroutine of its own, calling `fn` whenever it is
+// pressed or released.
+func (self *TouchSensor) listen(stop <-chan bool, fn func(pressed bool)) {
+	path, err := sensorPath(self.port, self.opts)
+	exitIfMissing(err)
+
+	value, _ := utilities.ReadValue[uint8](path, "value0")
+	last := value == 1
+
+	go func() {
+		for {
+			select {
+			case <-stop:
+				return
+			case <-time.After(self.opts.pollInterval(time.Millisecond * time.Duration(TOUCH_POLLING_INTERVAL))):
+			}
+
+			value, err := utilities.ReadValue[uint8](path, "value0")
+			if err != nil {
+				continue
+			}
 
-		if value == 1 {
-			return
+			if pressed := value == 1; pressed != last {
+				last = pressed
+				fn(pressed)
+			}
 		}
+	}()
+}
+
+// Reports whether the touch sensor is currently pressed. A missing sensor is a fatal error.
+func (self *TouchSensor) IsPressed() bool {
+	pressed, err := self.TryIsPressed()
+	exitIfMissing(err)
 
-		time.Sleep(time.Millisecond * 50)
+	return pressed
+}
+
+// Reports whether the touch sensor is pressed like IsPressed, but returns an
+// error matching Errors.ErrDeviceNotFound instead of exiting if the sensor is
+// missing, and the error of the read.
+func (self *TouchSensor) TryIsPressed() (bool, error) {
+	path, err := sensorPath(self.port, self.opts)
+	if err != nil {
+		return false, err
 	}
+
+	value, err := utilities.ReadValue[uint8](path, "value0")
+	return value == 1, err
 }
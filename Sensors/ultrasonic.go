@@ -1,43 +1,159 @@
 package Sensors
 
 import (
-	"fmt"
-	"github.com/ldmberman/GoEV3/utilities"
+	"github.com/jermon/GoEV3/Units"
+	"github.com/jermon/GoEV3/utilities"
+	"time"
 )
 
 // Ultrasonic sensor type.
 type UltrasonicSensor struct {
 	port InPort
+	opts options
 }
 
 // Provides access to an ultrasonic sensor at the given port.
-func FindUltrasonicSensor(port InPort) *UltrasonicSensor {
-	findSensor(port, TypeUltrasonic)
+// A missing sensor is a fatal error; use OpenUltrasonicSensor to handle it.
+func FindUltrasonicSensor(port InPort, opts ...Option) *UltrasonicSensor {
+	s, err := OpenUltrasonicSensor(port, opts...)
+	if err != nil {
+		utilities.Fatal(err)
+	}
+
+	return s
+}
+
+// Provides access to an ultrasonic sensor at the given port. Returns an error matching
+// Errors.ErrDeviceNotFound, Errors.ErrPortMismatch or Errors.ErrInvalidMode
+// instead of exiting if the sensor can't be set up.
+func OpenUltrasonicSensor(port InPort, opts ...Option) (*UltrasonicSensor, error) {
+	port = CanonicalInPort(string(port))
 
 	s := new(UltrasonicSensor)
 	s.port = port
+	s.opts = newOptions(TypeUltrasonic, opts)
+	if _, err := s.opts.setUp(port, ""); err != nil {
+		return nil, err
+	}
 
-	return s
+	return s, nil
+}
+
+// Returns the input port the sensor is connected to.
+func (self *UltrasonicSensor) Port() InPort {
+	return self.port
+}
+
+// Puts the sensor into `mode`, e.g. to read it WithoutAutoModeSwitch. Returns
+// an error matching Errors.ErrInvalidMode if the sensor doesn't have the
+// mode, Errors.ErrDeviceNotFound if it is missing, and the error of the write.
+func (self *UltrasonicSensor) SetMode(mode string) error {
+	path, err := sensorPath(self.port, self.opts)
+	if err != nil {
+		return err
+	}
+
+	return setMode(self.port, path, mode)
+}
+
+// Closes the sensor's attribute files kept open, once the program is done
+// with it; see utilities.IOStrategy. Reading the sensor again opens them again.
+func (self *UltrasonicSensor) Close() {
+	if path, err := sensorPath(self.port, self.opts); err == nil {
+		utilities.ReleaseFiles(path)
+	}
 }
 
 // Reads the distance (in centimeters) reported by the ultrasonic sensor.
+// A missing sensor is a fatal error.
 func (self *UltrasonicSensor) ReadDistance() uint16 {
-	snr := findSensor(self.port, TypeUltrasonic)
-
-	path := fmt.Sprintf("%s/%s", baseSensorPath, snr)
-	utilities.WriteStringValue(path, "mode", "US-SI-CM")
-	value := utilities.ReadUInt16Value(path, "value0")
+	value, err := self.readMillimeters()
+	exitIfMissing(err)
 
 	return (value / 10)
 }
 
+// Reads the distance reported by the ultrasonic sensor, with millimeter resolution.
+// A missing sensor is a fatal error.
+func (self *UltrasonicSensor) Distance() Units.Distance {
+	value, err := self.TryDistance()
+	exitIfMissing(err)
+
+	return value
+}
+
+// Reads the distance like Distance, but returns an error matching
+// Errors.ErrDeviceNotFound instead of exiting if the sensor is missing, and
+// the error of the read.
+func (self *UltrasonicSensor) TryDistance() (Units.Distance, error) {
+	value, err := self.readMillimeters()
+	return Units.Distance(value) * Units.Millimeter, err
+}
+
+func (self *UltrasonicSensor) readMillimeters() (uint16, error) {
+	path, err := sensorPath(self.port, self.opts)
+	if err != nil {
+		return 0, err
+	}
+
+	return readInMode[uint16](self.opts, path, "US-SI-CM", "value0")
+}
+
+// Reads the distance in centimeters, with millimeter resolution, in range
+// [0, 255]. A missing sensor is a fatal error.
+func (self *UltrasonicSensor) ReadDistanceCentimeters() float64 {
+	value, err := self.readMillimeters()
+	exitIfMissing(err)
+
+	return float64(value) / 10
+}
+
+// Reads the distance in inches, with a resolution of a tenth of an inch, in
+// range [0, 100.3]. A missing sensor is a fatal error.
+func (self *UltrasonicSensor) ReadDistanceInches() float64 {
+	path, err := sensorPath(self.port, self.opts)
+	exitIfMissing(err)
+
+	value, _ := readInMode[uint16](self.opts, path, "US-SI-IN", "value0")
+	return float64(value) / 10
+}
+
+// Blocks until the sensor measures less than `threshold` centimeters twice
+// in a row, so that a single stray echo doesn't end the wait.
+func (self *UltrasonicSensor) WaitForDistanceBelow(threshold float64) {
+	self.waitForDistanceBelow(nil, threshold)
+}
+
+// Waits like WaitForDistanceBelow, returning false if a value is sent to `stop` first.
+func (self *UltrasonicSensor) waitForDistanceBelow(stop <-chan bool, threshold float64) bool {
+	for {
+		d1 := self.ReadDistanceCentimeters()
+		select {
+		case <-stop:
+			return false
+		case <-time.After(self.opts.pollInterval(100 * time.Millisecond)):
+		}
+		d2 := self.ReadDistanceCentimeters()
+
+		if d1 < threshold && d2 < threshold {
+			return true
+		}
+	}
+}
+
+// Reports whether another ultrasonic sensor is sending nearby, e.g. the
+// opponent's robot; same as Listen.
+func (self *UltrasonicSensor) DetectOtherUltrasonic() bool {
+	return self.Listen()
+}
+
 // Looks for other nearby ultrasonic sensors and returns true if one is found.
+// A missing sensor is a fatal error.
 func (self *UltrasonicSensor) Listen() bool {
-	snr := findSensor(self.port, TypeUltrasonic)
+	path, err := sensorPath(self.port, self.opts)
+	exitIfMissing(err)
 
-	path := fmt.Sprintf("%s/%s", baseSensorPath, snr)
-	utilities.WriteStringValue(path, "mode", "US-LISTEN")
-	value := utilities.ReadUInt8Value(path, "value0")
+	value, _ := readInMode[uint8](self.opts, path, "US-LISTEN", "value0")
 
 	if value == 1 {
 		return true
@@ -0,0 +1,73 @@
+package Sensors
+
+import (
+	"sync"
+	"time"
+
+	"github.com/jermon/GoEV3/utilities"
+)
+
+// Changes not yet handled by an OnValueChanged callback, beyond which the new
+// ones are dropped.
+const watchQueueSize = 50
+
+// Registers a callback to be triggered with value `n` of the sensor's current
+// mode, scaled like FloatValue, right away and then whenever it changes. The
+// value is watched with utilities.WatchValue, so that changes are seen within
+// utilities.WATCH_MAX_INTERVAL, or the interval set with WithPollInterval,
+// without a goroutine polling it. The callbacks
+// run one after the other in a goroutine of their own. The listening can be
+// stopped by sending any boolean value to a `stop` channel. Failed reads are skipped.
+func (self *GenericSensor) OnValueChanged(stop <-chan bool, n int, fn func(value float64)) {
+	changes := make(chan float64, watchQueueSize)
+
+	var lock sync.Mutex
+	closed := false
+
+	var last float64
+	shortest, longest := self.opts.watchIntervals()
+	watch := utilities.WatchValueBetween(shortest, longest, func() float64 {
+		if value, err := self.FloatValue(n); err == nil {
+			last = value
+		}
+		return last
+	}, func(value float64) {
+		lock.Lock()
+		defer lock.Unlock()
+
+		if closed {
+			return
+		}
+		select {
+		case changes <- value:
+		default:
+		}
+	})
+
+	go func() {
+		<-stop
+		watch.Cancel()
+
+		// A change being delivered as the watch is cancelled is dropped.
+		lock.Lock()
+		closed = true
+		close(changes)
+		lock.Unlock()
+	}()
+
+	go func() {
+		for value := range changes {
+			fn(value)
+		}
+	}()
+}
+
+// Waits until `predicate` accepts the content of the sensor's attribute
+// `attribute`, e.g. "value0", reading it every utilities.WAIT_POLLING_INTERVAL,
+// or the interval set with WithPollInterval. Returns an error matching
+// Errors.ErrWaitTimeout if it doesn't within `timeout`, 0 to wait for as long
+// as it takes, and the error of a read; see utilities.WaitFor.
+func (self *GenericSensor) WaitFor(attribute string, predicate func(string) bool, timeout time.Duration) error {
+	interval := self.opts.pollInterval(time.Duration(utilities.WAIT_POLLING_INTERVAL) * time.Millisecond)
+	return utilities.WaitFor(self.path, attribute, predicate, timeout, interval)
+}
@@ -0,0 +1,642 @@
+package Simulation
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jermon/GoEV3/Motor"
+	"github.com/jermon/GoEV3/Sensors"
+)
+
+const (
+	motorClassPath  = "/sys/class/tacho-motor"
+	sensorClassPath = "/sys/class/lego-sensor"
+
+	largeMotorDriver    = "lego-ev3-l-motor"
+	mediumMotorDriver   = "lego-ev3-m-motor"
+	largeMotorMaxSpeed  = 1050
+	mediumMotorMaxSpeed = 1560
+
+	// Maximum range of the ultrasonic sensor in centimeters.
+	ultrasonicRange = 255
+	// Range corresponding to an infrared proximity of 100, in centimeters.
+	proximityRange = 70
+	// Range corresponding to an infrared beacon distance of 100, in centimeters.
+	beaconRange = 200
+	// Distance from a wall at which a touch sensor reports a press, in centimeters.
+	touchRange = 0.5
+)
+
+// Simulated tacho motor. 1 tacho count equals 1 degree.
+type simMotor struct {
+	name     string
+	port     Motor.OutPort
+	driver   string
+	maxSpeed float64
+
+	dutyCycleSp int64
+	speedSp     int64
+	positionSp  int64
+	timeSp      int64
+	regulation  string
+	stopCommand string
+
+	command  string
+	target   float64
+	deadline time.Duration
+	running  bool
+	holding  bool
+
+	position float64
+	velocity float64
+}
+
+// The world lock must be held.
+func (self *World) addMotor(port Motor.OutPort, driver string, maxSpeed float64) *simMotor {
+	m := &simMotor{
+		name:        fmt.Sprintf("motor%d", len(self.motors)),
+		port:        port,
+		driver:      driver,
+		maxSpeed:    maxSpeed,
+		regulation:  "off",
+		stopCommand: "coast",
+	}
+	self.motors = append(self.motors, m)
+
+	return m
+}
+
+func (self *simMotor) commandedSpeed() float64 {
+	if self.regulation == "on" {
+		return math.Max(-self.maxSpeed, math.Min(self.maxSpeed, float64(self.speedSp)))
+	}
+
+	return float64(self.dutyCycleSp) / 100 * self.maxSpeed
+}
+
+func (self *simMotor) execute(command string, clock time.Duration) error {
+	switch command {
+	case "run-forever":
+	case "run-to-abs-pos":
+		self.target = float64(self.positionSp)
+	case "run-to-rel-pos":
+		self.target = self.position + float64(self.positionSp)
+	case "run-timed":
+		self.deadline = clock + time.Duration(self.timeSp)*time.Millisecond
+	case "stop":
+		self.halt()
+		return nil
+	case "reset":
+		*self = simMotor{name: self.name, port: self.port, driver: self.driver, maxSpeed: self.maxSpeed,
+			regulation: "off", stopCommand: "coast"}
+		return nil
+	default:
+		return fmt.Errorf("invalid command %q", command)
+	}
+
+	self.command = command
+	self.running = true
+	self.holding = false
+
+	return nil
+}
+
+func (self *simMotor) halt() {
+	self.running = false
+	self.velocity = 0
+	self.holding = self.stopCommand == "hold"
+}
+
+func (self *simMotor) step(clock time.Duration, seconds float64) {
+	if !self.running {
+		return
+	}
+
+	speed := self.commandedSpeed()
+
+	switch self.command {
+	case "run-to-abs-pos", "run-to-rel-pos":
+		remaining := self.target - self.position
+		speed = math.Copysign(math.Abs(speed), remaining)
+
+		if math.Abs(remaining) <= math.Abs(speed*seconds) {
+			self.velocity = remaining / seconds
+			self.position = self.target
+			self.halt()
+			return
+		}
+	case "run-timed":
+		if clock >= self.deadline {
+			self.halt()
+			return
+		}
+	}
+
+	self.velocity = speed
+	self.position += speed * seconds
+}
+
+func (self *simMotor) state() string {
+	var flags []string
+
+	if self.running {
+		flags = append(flags, "running")
+	}
+	if self.holding {
+		flags = append(flags, "holding")
+	}
+
+	return strings.Join(flags, " ")
+}
+
+func (self *simMotor) read(attribute string) (string, bool) {
+	switch attribute {
+	case "address":
+		return "out" + string(self.port), true
+	case "driver_name":
+		return self.driver, true
+	case "commands":
+		return "run-forever run-to-abs-pos run-to-rel-pos run-timed stop reset", true
+	case "stop_commands":
+		return "coast brake hold", true
+	case "count_per_rot":
+		return "360", true
+	case "max_speed":
+		return strconv.Itoa(int(self.maxSpeed)), true
+	case "duty_cycle":
+		return strconv.Itoa(int(math.Round(self.velocity / self.maxSpeed * 100))), true
+	case "duty_cycle_sp":
+		return strconv.FormatInt(self.dutyCycleSp, 10), true
+	case "speed":
+		return strconv.Itoa(int(math.Round(self.velocity))), true
+	case "speed_sp":
+		return strconv.FormatInt(self.speedSp, 10), true
+	case "position":
+		return strconv.Itoa(int(math.Round(self.position))), true
+	case "position_sp":
+		return strconv.FormatInt(self.positionSp, 10), true
+	case "time_sp":
+		return strconv.FormatInt(self.timeSp, 10), true
+	case "speed_regulation":
+		return self.regulation, true
+	case "stop_command":
+		return self.stopCommand, true
+	case "state":
+		return self.state(), true
+	}
+
+	return "", false
+}
+
+func (self *simMotor) write(attribute string, value string, clock time.Duration) error {
+	switch attribute {
+	case "command":
+		return self.execute(value, clock)
+	case "speed_regulation":
+		if value != "on" && value != "off" {
+			return fmt.Errorf("invalid value %q", value)
+		}
+		self.regulation = value
+		return nil
+	case "stop_command":
+		if value != "coast" && value != "brake" && value != "hold" {
+			return fmt.Errorf("invalid value %q", value)
+		}
+		self.stopCommand = value
+		return nil
+	}
+
+	number, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return err
+	}
+
+	switch attribute {
+	case "duty_cycle_sp":
+		if number < -100 || number > 100 {
+			return fmt.Errorf("duty cycle %d out of range", number)
+		}
+		self.dutyCycleSp = number
+	case "speed_sp":
+		self.speedSp = number
+	case "position_sp":
+		self.positionSp = number
+	case "time_sp":
+		self.timeSp = number
+	case "position":
+		self.position = float64(number)
+	default:
+		return os.ErrNotExist
+	}
+
+	return nil
+}
+
+// Simulated lego-sensor device.
+type simSensor struct {
+	name   string
+	port   Sensors.InPort
+	driver Sensors.Type
+	mode   string
+	offset Point
+	angle  float64
+
+	// Gyro reference heading, reset on every mode change.
+	reference float64
+}
+
+// The world lock must be held.
+func (self *World) addSensor(port Sensors.InPort, t Sensors.Type, offset Point, angle float64) {
+	s := &simSensor{
+		name:   fmt.Sprintf("sensor%d", len(self.sensors)),
+		port:   port,
+		driver: t,
+		offset: offset,
+		angle:  angle,
+	}
+	s.mode = s.modes()[0]
+	s.reference = self.theta
+
+	self.sensors = append(self.sensors, s)
+}
+
+func (self *simSensor) modes() []string {
+	switch self.driver {
+	case Sensors.TypeColor:
+		return []string{"COL-REFLECT", "COL-AMBIENT", "COL-COLOR"}
+	case Sensors.TypeUltrasonic:
+		return []string{"US-DIST-CM", "US-DIST-IN", "US-LISTEN", "US-SI-CM", "US-SI-IN"}
+	case Sensors.TypeInfrared:
+		return []string{"IR-PROX", "IR-SEEK", "IR-REMOTE"}
+	case Sensors.TypeGyro:
+		return []string{"GYRO-ANG", "GYRO-RATE", "GYRO-G&A", "GYRO-CAL"}
+	default:
+		return []string{"TOUCH"}
+	}
+}
+
+func (self *simSensor) numValues() int {
+	switch self.mode {
+	case "IR-SEEK":
+		return 8
+	case "IR-REMOTE":
+		return 4
+	case "GYRO-G&A":
+		return 2
+	}
+
+	return 1
+}
+
+// Computes the current sensor values. The world lock must be held.
+func (self *World) sensorValues(s *simSensor) []int64 {
+	origin, heading := self.sensorPose(s.offset, s.angle)
+
+	switch s.mode {
+	case "COL-COLOR":
+		return []int64{int64(self.floorAt(origin))}
+	case "COL-REFLECT":
+		return []int64{int64(math.Round(self.reflectanceAt(origin)))}
+	case "COL-AMBIENT":
+		return []int64{int64(self.ambientLight)}
+	case "US-DIST-CM", "US-SI-CM":
+		return []int64{int64(math.Round(math.Min(ultrasonicRange, self.castRay(origin, heading)) * 10))}
+	case "US-DIST-IN", "US-SI-IN":
+		return []int64{int64(math.Round(math.Min(ultrasonicRange, self.castRay(origin, heading)) / 2.54 * 10))}
+	case "US-LISTEN":
+		return []int64{0}
+	case "IR-PROX":
+		d := self.castRay(origin, heading)
+		return []int64{int64(math.Round(math.Min(100, d/proximityRange*100)))}
+	case "IR-SEEK":
+		values := make([]int64, 8)
+		for c := 0; c < 4; c++ {
+			values[2*c+1] = -128
+		}
+		for _, b := range self.beacons {
+			bearing := normalizeDegrees(math.Atan2(b.Position.Y-origin.Y, b.Position.X-origin.X)*180/math.Pi - heading)
+			if math.Abs(bearing) > 90 {
+				continue
+			}
+			// EV3 reports headings from -25 (left) to 25 (right).
+			values[2*b.Channel] = int64(math.Round(-bearing * 25 / 90))
+			values[2*b.Channel+1] = int64(math.Round(math.Min(100, distance(origin, b.Position)/beaconRange*100)))
+		}
+		return values
+	case "IR-REMOTE":
+		return []int64{int64(self.remote[0]), int64(self.remote[1]), int64(self.remote[2]), int64(self.remote[3])}
+	case "GYRO-ANG", "GYRO-RATE", "GYRO-G&A", "GYRO-CAL":
+		// The EV3 gyro reports clockwise rotation as positive.
+		angle := int64(math.Round(-normalizeDegrees(self.theta - s.reference)))
+		rate := int64(math.Round(-self.omega))
+		switch s.mode {
+		case "GYRO-ANG":
+			return []int64{angle}
+		case "GYRO-RATE":
+			return []int64{rate}
+		case "GYRO-CAL":
+			return []int64{0}
+		}
+		return []int64{angle, rate}
+	case "TOUCH":
+		if self.nearestWall(origin) <= touchRange || (self.bumped && self.castRay(self.position, self.theta) <= self.geometry.Radius+touchRange) {
+			return []int64{1}
+		}
+		return []int64{0}
+	}
+
+	return []int64{0}
+}
+
+// Reflected light intensity of each floor color, in percent.
+var reflectance = map[Sensors.Color]uint8{
+	Sensors.None:   0,
+	Sensors.Black:  5,
+	Sensors.Blue:   15,
+	Sensors.Green:  20,
+	Sensors.Yellow: 70,
+	Sensors.Red:    60,
+	Sensors.White:  90,
+	Sensors.Brown:  25,
+}
+
+// Radius of the spot lit by the color sensor, in centimeters.
+const colorSpotRadius = 0.8
+
+// Averages the reflectance over the sensor's light spot, so readings change
+// gradually when crossing the edge of a line. The world lock must be held.
+func (self *World) reflectanceAt(p Point) float64 {
+	samples := []Point{{0, 0}, {colorSpotRadius, 0}, {-colorSpotRadius, 0}, {0, colorSpotRadius}, {0, -colorSpotRadius},
+		{colorSpotRadius / 2, colorSpotRadius / 2}, {-colorSpotRadius / 2, colorSpotRadius / 2},
+		{colorSpotRadius / 2, -colorSpotRadius / 2}, {-colorSpotRadius / 2, -colorSpotRadius / 2}}
+
+	total := 0.0
+	for _, offset := range samples {
+		total += float64(reflectance[self.floorAt(Point{p.X + offset.X, p.Y + offset.Y})])
+	}
+
+	return total / float64(len(samples))
+}
+
+func (self *World) readSensor(s *simSensor, attribute string) (string, bool) {
+	switch attribute {
+	case "address":
+		return string(s.port), true
+	case "driver_name":
+		return string(s.driver), true
+	case "mode":
+		return s.mode, true
+	case "modes":
+		return strings.Join(s.modes(), " "), true
+	case "num_values":
+		return strconv.Itoa(s.numValues()), true
+	case "decimals":
+		decimals, _ := modeScale(s.mode)
+		return strconv.Itoa(decimals), true
+	case "units":
+		_, units := modeScale(s.mode)
+		return units, true
+	}
+
+	var index int
+	if _, err := fmt.Sscanf(attribute, "value%d", &index); err == nil && index >= 0 && index < 8 {
+		values := self.sensorValues(s)
+		if index < len(values) {
+			return strconv.FormatInt(values[index], 10), true
+		}
+		return "0", true
+	}
+
+	return "", false
+}
+
+// Returns the decimal places and the units of a mode, as the ev3dev drivers
+// report them.
+func modeScale(mode string) (int, string) {
+	switch mode {
+	case "US-DIST-CM", "US-SI-CM":
+		return 1, "cm"
+	case "US-DIST-IN", "US-SI-IN":
+		return 1, "in"
+	case "COL-REFLECT", "COL-AMBIENT", "IR-PROX", "IR-SEEK":
+		return 0, "pct"
+	case "COL-COLOR":
+		return 0, "col"
+	case "IR-REMOTE":
+		return 0, "btn"
+	case "GYRO-ANG":
+		return 0, "deg"
+	case "GYRO-RATE":
+		return 0, "d/s"
+	}
+
+	return 0, ""
+}
+
+func (self *World) writeSensor(s *simSensor, attribute string, value string) error {
+	if attribute != "mode" {
+		return os.ErrPermission
+	}
+
+	for _, mode := range s.modes() {
+		if mode == value {
+			s.mode = value
+			s.reference = self.theta
+			return nil
+		}
+	}
+
+	return fmt.Errorf("invalid mode %q", value)
+}
+
+// Splits "/sys/class/<class>/<device>/<attribute>" into its components.
+func splitDevicePath(name string) (class string, device string, attribute string) {
+	name = path.Clean(name)
+
+	for _, root := range []string{motorClassPath, sensorClassPath} {
+		if name == root {
+			return root, "", ""
+		}
+
+		if strings.HasPrefix(name, root+"/") {
+			parts := strings.SplitN(strings.TrimPrefix(name, root+"/"), "/", 2)
+			if len(parts) == 2 {
+				return root, parts[0], parts[1]
+			}
+			return root, parts[0], ""
+		}
+	}
+
+	return "", "", ""
+}
+
+func (self *World) findDevice(class string, device string) (*simMotor, *simSensor) {
+	switch class {
+	case motorClassPath:
+		for _, m := range self.motors {
+			if m.name == device {
+				return m, nil
+			}
+		}
+	case sensorClassPath:
+		for _, s := range self.sensors {
+			if s.name == device {
+				return nil, s
+			}
+		}
+	}
+
+	return nil, nil
+}
+
+// Implements utilities.Backend.
+func (self *World) ReadFile(name string) ([]byte, error) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	class, device, attribute := splitDevicePath(name)
+	if class != "" {
+		m, s := self.findDevice(class, device)
+
+		var value string
+		var ok bool
+
+		if m != nil {
+			value, ok = m.read(attribute)
+		} else if s != nil {
+			value, ok = self.readSensor(s, attribute)
+		}
+
+		if !ok {
+			return nil, &os.PathError{Op: "read", Path: name, Err: os.ErrNotExist}
+		}
+
+		return []byte(value + "\n"), nil
+	}
+
+	if value, ok := self.files[path.Clean(name)]; ok {
+		return []byte(value + "\n"), nil
+	}
+
+	return nil, &os.PathError{Op: "read", Path: name, Err: os.ErrNotExist}
+}
+
+// Implements utilities.Backend.
+func (self *World) WriteFile(name string, data []byte) error {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	value := strings.TrimSpace(string(data))
+
+	class, device, attribute := splitDevicePath(name)
+	if class != "" {
+		m, s := self.findDevice(class, device)
+
+		var err error
+
+		switch {
+		case m != nil:
+			err = m.write(attribute, value, self.clock)
+		case s != nil:
+			err = self.writeSensor(s, attribute, value)
+		default:
+			err = os.ErrNotExist
+		}
+
+		if err != nil {
+			return &os.PathError{Op: "write", Path: name, Err: err}
+		}
+
+		return nil
+	}
+
+	if _, ok := self.files[path.Clean(name)]; !ok {
+		return &os.PathError{Op: "write", Path: name, Err: os.ErrNotExist}
+	}
+
+	self.files[path.Clean(name)] = value
+
+	return nil
+}
+
+// Implements utilities.Backend.
+func (self *World) ReadDir(name string) ([]string, error) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	var names []string
+
+	switch path.Clean(name) {
+	case motorClassPath:
+		for _, m := range self.motors {
+			names = append(names, m.name)
+		}
+	case sensorClassPath:
+		for _, s := range self.sensors {
+			names = append(names, s.name)
+		}
+	default:
+		prefix := path.Clean(name) + "/"
+		seen := map[string]bool{}
+
+		for file := range self.files {
+			if strings.HasPrefix(file, prefix) {
+				entry := strings.SplitN(strings.TrimPrefix(file, prefix), "/", 2)[0]
+				if !seen[entry] {
+					seen[entry] = true
+					names = append(names, entry)
+				}
+			}
+		}
+
+		if len(names) == 0 {
+			return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrNotExist}
+		}
+	}
+
+	sort.Strings(names)
+
+	return names, nil
+}
+
+// Implements utilities.Backend.
+func (self *World) Exists(name string) bool {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	class, device, attribute := splitDevicePath(name)
+	if class != "" {
+		if device == "" {
+			return true
+		}
+
+		m, s := self.findDevice(class, device)
+		if m == nil && s == nil {
+			return false
+		}
+		if attribute == "" {
+			return true
+		}
+		if m != nil {
+			_, ok := m.read(attribute)
+			return ok || attribute == "command"
+		}
+		_, ok := self.readSensor(s, attribute)
+		return ok
+	}
+
+	clean := path.Clean(name)
+	for file := range self.files {
+		if file == clean || strings.HasPrefix(file, clean+"/") {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,40 @@
+package Simulation
+
+import (
+	"time"
+
+	"github.com/jermon/GoEV3/Motor"
+	"github.com/jermon/GoEV3/Sensors"
+	"github.com/jermon/GoEV3/utilities"
+)
+
+// Time step of the world selected with the environment variable.
+const environmentTick = 10 * time.Millisecond
+
+// Makes GOEV3_BACKEND=simulation run programs importing this package on a
+// world with the robot of NewEducationWorld, e.g. in CI; see
+// utilities.BackendVariable.
+func init() {
+	utilities.RegisterBackend("simulation", func() utilities.Backend {
+		w := NewEducationWorld()
+		go w.Run(nil, environmentTick)
+
+		return w
+	})
+}
+
+// Creates a world with the robot of the EV3 education set's driving base: a
+// medium motor on port A, drive motors on B (left) and C (right), a front
+// bumper on port 1, a gyro sensor on port 2, a color sensor facing down on
+// port 3 and an ultrasonic sensor facing forward on port 4.
+func NewEducationWorld() *World {
+	w := NewWorld(DefaultGeometry)
+	w.AttachMediumMotor(Motor.OutPortA)
+	w.AttachDriveMotors(Motor.OutPortB, Motor.OutPortC)
+	w.AttachTouchSensor(Sensors.InPort1, Point{X: DefaultGeometry.Radius})
+	w.AttachGyroSensor(Sensors.InPort2)
+	w.AttachColorSensor(Sensors.InPort3, Point{X: 6})
+	w.AttachUltrasonicSensor(Sensors.InPort4, Point{X: 7}, 0)
+
+	return w
+}
@@ -0,0 +1,74 @@
+package Simulation
+
+import (
+	"math"
+)
+
+// A point on the simulated field, measured in centimeters.
+type Point struct {
+	X float64
+	Y float64
+}
+
+// Rotates a point given in the robot frame by `theta` degrees and translates it by `origin`.
+func (self Point) transform(origin Point, theta float64) Point {
+	rad := theta * math.Pi / 180
+	cos, sin := math.Cos(rad), math.Sin(rad)
+
+	return Point{
+		X: origin.X + self.X*cos - self.Y*sin,
+		Y: origin.Y + self.X*sin + self.Y*cos,
+	}
+}
+
+func distance(a Point, b Point) float64 {
+	return math.Hypot(a.X-b.X, a.Y-b.Y)
+}
+
+// Returns the distance from `p` to the segment [a, b].
+func distanceToSegment(p Point, a Point, b Point) float64 {
+	dx, dy := b.X-a.X, b.Y-a.Y
+	lengthSquared := dx*dx + dy*dy
+
+	if lengthSquared == 0 {
+		return distance(p, a)
+	}
+
+	t := ((p.X-a.X)*dx + (p.Y-a.Y)*dy) / lengthSquared
+	t = math.Max(0, math.Min(1, t))
+
+	return distance(p, Point{a.X + t*dx, a.Y + t*dy})
+}
+
+// Returns the distance along the ray starting at `origin` with direction `theta`
+// (in degrees) to the segment [a, b], or +Inf if the ray misses it.
+func rayToSegment(origin Point, theta float64, a Point, b Point) float64 {
+	rad := theta * math.Pi / 180
+	rx, ry := math.Cos(rad), math.Sin(rad)
+	sx, sy := b.X-a.X, b.Y-a.Y
+
+	denominator := rx*sy - ry*sx
+	if math.Abs(denominator) < 1e-12 {
+		return math.Inf(1)
+	}
+
+	qx, qy := a.X-origin.X, a.Y-origin.Y
+	t := (qx*sy - qy*sx) / denominator
+	u := (qx*ry - qy*rx) / denominator
+
+	if t < 0 || u < 0 || u > 1 {
+		return math.Inf(1)
+	}
+
+	return t
+}
+
+// Normalizes an angle in degrees to the range [-180, 180).
+func normalizeDegrees(angle float64) float64 {
+	angle = math.Mod(angle+180, 360)
+	if angle < 0 {
+		angle += 360
+	}
+
+	return angle - 180
+}
@@ -0,0 +1,361 @@
+// Provides a pure-Go 2D simulation of an EV3 robot and its surroundings.
+//
+// A World emulates the ev3dev sysfs device tree in memory and implements
+// utilities.Backend, so the regular Motor and Sensors APIs drive the simulated
+// robot once the world is installed:
+//
+//	world := Simulation.NewWorld(Simulation.DefaultGeometry)
+//	world.AttachDriveMotors(Motor.OutPortB, Motor.OutPortC)
+//	world.AttachColorSensor(Sensors.InPort1, Simulation.Point{X: 6})
+//	world.AddLine(1.5, Sensors.Black, Simulation.Point{X: 0}, Simulation.Point{X: 100})
+//	world.Install()
+//	go world.Run(stop, 10*time.Millisecond)
+//
+// Positions are measured in centimeters and angles in degrees, counter-clockwise
+// being positive. The robot starts at the origin facing along the X axis.
+package Simulation
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/jermon/GoEV3/Motor"
+	"github.com/jermon/GoEV3/Sensors"
+	"github.com/jermon/GoEV3/utilities"
+)
+
+// Physical dimensions of the simulated robot, in centimeters.
+type Geometry struct {
+	WheelDiameter float64
+	TrackWidth    float64
+	// Radius of the robot's footprint, used for collisions with walls.
+	Radius float64
+}
+
+// Dimensions of the standard EV3 education base.
+var DefaultGeometry = Geometry{WheelDiameter: 5.6, TrackWidth: 12, Radius: 8}
+
+// A straight wall segment that blocks the robot and reflects distance sensors.
+type Wall struct {
+	A Point
+	B Point
+}
+
+// A polyline painted on the floor, seen by color sensors.
+type Line struct {
+	Points []Point
+	Width  float64
+	Color  Sensors.Color
+}
+
+// An infrared beacon broadcasting on one of the four remote channels.
+type Beacon struct {
+	Position Point
+	Channel  Sensors.Channel
+}
+
+// Simulated robot and environment.
+type World struct {
+	lock sync.Mutex
+
+	geometry Geometry
+	clock    time.Duration
+
+	position Point
+	theta    float64
+	omega    float64
+	bumped   bool
+
+	walls   []Wall
+	lines   []Line
+	beacons []Beacon
+
+	floorColor   Sensors.Color
+	ambientLight uint8
+
+	motors     []*simMotor
+	leftWheel  *simMotor
+	rightWheel *simMotor
+	sensors    []*simSensor
+	remote     [4]uint8
+
+	files map[string]string
+}
+
+// Creates an empty world with a white floor.
+func NewWorld(geometry Geometry) *World {
+	w := new(World)
+	w.geometry = geometry
+	w.floorColor = Sensors.White
+	w.ambientLight = 10
+	w.files = map[string]string{
+		"/sys/devices/platform/snd-legoev3/volume":            "100",
+		"/sys/devices/platform/snd-legoev3/tone":              "0",
+		"/sys/class/power_supply/legoev3-battery/voltage_now": "7500000",
+		"/sys/class/power_supply/legoev3-battery/current_now": "150000",
+		"/sys/class/leds/ev3:left:green:ev3dev/brightness":    "0",
+		"/sys/class/leds/ev3:left:red:ev3dev/brightness":      "0",
+		"/sys/class/leds/ev3:right:green:ev3dev/brightness":   "0",
+		"/sys/class/leds/ev3:right:red:ev3dev/brightness":     "0",
+	}
+
+	return w
+}
+
+// Makes the world the backend for all device I/O.
+func (self *World) Install() {
+	utilities.SetBackend(self)
+}
+
+// Restores the real sysfs backend.
+func (self *World) Uninstall() {
+	utilities.SetBackend(nil)
+}
+
+// Adds a wall between the given points.
+func (self *World) AddWall(a Point, b Point) {
+	self.lock.Lock()
+	self.walls = append(self.walls, Wall{a, b})
+	self.lock.Unlock()
+}
+
+// Adds four walls enclosing the rectangle between the given corners.
+func (self *World) AddBox(min Point, max Point) {
+	self.AddWall(Point{min.X, min.Y}, Point{max.X, min.Y})
+	self.AddWall(Point{max.X, min.Y}, Point{max.X, max.Y})
+	self.AddWall(Point{max.X, max.Y}, Point{min.X, max.Y})
+	self.AddWall(Point{min.X, max.Y}, Point{min.X, min.Y})
+}
+
+// Paints a line of the given width (in centimeters) and color through the given points.
+func (self *World) AddLine(width float64, color Sensors.Color, points ...Point) {
+	self.lock.Lock()
+	self.lines = append(self.lines, Line{append([]Point(nil), points...), width, color})
+	self.lock.Unlock()
+}
+
+// Places an infrared beacon at the given position.
+func (self *World) AddBeacon(position Point, channel Sensors.Channel) {
+	self.lock.Lock()
+	self.beacons = append(self.beacons, Beacon{position, channel})
+	self.lock.Unlock()
+}
+
+// Sets the color of the floor wherever no line is painted.
+func (self *World) SetFloorColor(color Sensors.Color) {
+	self.lock.Lock()
+	self.floorColor = color
+	self.lock.Unlock()
+}
+
+// Sets the ambient light intensity in range [0, 100].
+func (self *World) SetAmbientLight(intensity uint8) {
+	self.lock.Lock()
+	self.ambientLight = intensity
+	self.lock.Unlock()
+}
+
+// Simulates pressing the given IR remote button value on a channel. A value of 0 releases all buttons.
+func (self *World) PressRemote(channel Sensors.Channel, value uint8) {
+	self.lock.Lock()
+	self.remote[channel] = value
+	self.lock.Unlock()
+}
+
+// Places the robot at the given position and heading.
+func (self *World) SetPose(x float64, y float64, theta float64) {
+	self.lock.Lock()
+	self.position = Point{x, y}
+	self.theta = theta
+	self.lock.Unlock()
+}
+
+// Returns the robot position (in centimeters) and heading (in degrees).
+func (self *World) Pose() (x float64, y float64, theta float64) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	return self.position.X, self.position.Y, self.theta
+}
+
+// Reports whether the robot was blocked by a wall during the last step.
+func (self *World) Bumped() bool {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	return self.bumped
+}
+
+// Returns the simulated time elapsed since the world was created.
+func (self *World) Elapsed() time.Duration {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	return self.clock
+}
+
+// Connects a large motor that is not part of the drive train to the given port.
+func (self *World) AttachMotor(port Motor.OutPort) {
+	self.lock.Lock()
+	self.addMotor(port, largeMotorDriver, largeMotorMaxSpeed)
+	self.lock.Unlock()
+}
+
+// Connects a medium motor that is not part of the drive train to the given port.
+func (self *World) AttachMediumMotor(port Motor.OutPort) {
+	self.lock.Lock()
+	self.addMotor(port, mediumMotorDriver, mediumMotorMaxSpeed)
+	self.lock.Unlock()
+}
+
+// Connects the left and right wheel motors of a differential drive.
+func (self *World) AttachDriveMotors(left Motor.OutPort, right Motor.OutPort) {
+	self.lock.Lock()
+	self.leftWheel = self.addMotor(left, largeMotorDriver, largeMotorMaxSpeed)
+	self.rightWheel = self.addMotor(right, largeMotorDriver, largeMotorMaxSpeed)
+	self.lock.Unlock()
+}
+
+// Connects a downward-facing color sensor mounted at `offset` in the robot frame.
+func (self *World) AttachColorSensor(port Sensors.InPort, offset Point) {
+	self.attachSensor(port, Sensors.TypeColor, offset, 0)
+}
+
+// Connects an ultrasonic sensor mounted at `offset` and facing `angle` degrees relative to the robot.
+func (self *World) AttachUltrasonicSensor(port Sensors.InPort, offset Point, angle float64) {
+	self.attachSensor(port, Sensors.TypeUltrasonic, offset, angle)
+}
+
+// Connects an infrared sensor mounted at `offset` and facing `angle` degrees relative to the robot.
+func (self *World) AttachInfraredSensor(port Sensors.InPort, offset Point, angle float64) {
+	self.attachSensor(port, Sensors.TypeInfrared, offset, angle)
+}
+
+// Connects a gyro sensor measuring the robot's rotation.
+func (self *World) AttachGyroSensor(port Sensors.InPort) {
+	self.attachSensor(port, Sensors.TypeGyro, Point{}, 0)
+}
+
+// Connects a touch sensor acting as a bumper at `offset` in the robot frame.
+func (self *World) AttachTouchSensor(port Sensors.InPort, offset Point) {
+	self.attachSensor(port, Sensors.TypeTouch, offset, 0)
+}
+
+func (self *World) attachSensor(port Sensors.InPort, t Sensors.Type, offset Point, angle float64) {
+	self.lock.Lock()
+	self.addSensor(port, t, offset, angle)
+	self.lock.Unlock()
+}
+
+// Advances the simulation by `dt`.
+func (self *World) Step(dt time.Duration) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	self.clock += dt
+	seconds := dt.Seconds()
+
+	for _, m := range self.motors {
+		m.step(self.clock, seconds)
+	}
+
+	self.bumped = false
+	self.omega = 0
+
+	if self.leftWheel == nil || self.rightWheel == nil {
+		return
+	}
+
+	circumference := math.Pi * self.geometry.WheelDiameter
+	vl := self.leftWheel.velocity * circumference / 360
+	vr := self.rightWheel.velocity * circumference / 360
+
+	v := (vl + vr) / 2
+	omega := (vr - vl) / self.geometry.TrackWidth * 180 / math.Pi
+
+	heading := (self.theta + omega*seconds/2) * math.Pi / 180
+	next := Point{
+		X: self.position.X + v*seconds*math.Cos(heading),
+		Y: self.position.Y + v*seconds*math.Sin(heading),
+	}
+
+	if self.collides(next) && distance(next, self.position) > 0 {
+		self.bumped = true
+	} else {
+		self.position = next
+	}
+
+	self.omega = omega
+	self.theta = normalizeDegrees(self.theta + omega*seconds)
+}
+
+func (self *World) collides(p Point) bool {
+	for _, wall := range self.walls {
+		if distanceToSegment(p, wall.A, wall.B) < self.geometry.Radius &&
+			distanceToSegment(p, wall.A, wall.B) < distanceToSegment(self.position, wall.A, wall.B) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Steps the simulation in real time every `tick` until a value is sent to `stop`.
+func (self *World) Run(stop <-chan bool, tick time.Duration) {
+	ticker := time.NewTicker(tick)
+	defer ticker.Stop()
+
+	last := time.Now()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case now := <-ticker.C:
+			self.Step(now.Sub(last))
+			last = now
+		}
+	}
+}
+
+// Geometric queries used by the simulated sensors. The world lock must be held.
+
+func (self *World) sensorPose(offset Point, angle float64) (Point, float64) {
+	return offset.transform(self.position, self.theta), self.theta + angle
+}
+
+func (self *World) floorAt(p Point) Sensors.Color {
+	// Lines painted later cover earlier ones.
+	for i := len(self.lines) - 1; i >= 0; i-- {
+		line := self.lines[i]
+
+		for j := 0; j+1 < len(line.Points); j++ {
+			if distanceToSegment(p, line.Points[j], line.Points[j+1]) <= line.Width/2 {
+				return line.Color
+			}
+		}
+	}
+
+	return self.floorColor
+}
+
+func (self *World) castRay(origin Point, theta float64) float64 {
+	nearest := math.Inf(1)
+
+	for _, wall := range self.walls {
+		nearest = math.Min(nearest, rayToSegment(origin, theta, wall.A, wall.B))
+	}
+
+	return nearest
+}
+
+func (self *World) nearestWall(p Point) float64 {
+	nearest := math.Inf(1)
+
+	for _, wall := range self.walls {
+		nearest = math.Min(nearest, distanceToSegment(p, wall.A, wall.B))
+	}
+
+	return nearest
+}
@@ -0,0 +1,141 @@
+package Sound
+
+import (
+	"fmt"
+	"github.com/jermon/GoEV3/utilities"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A tone of a melody: its frequency in Hz, 0 for a rest, how long it sounds
+// and how long the silence after it lasts, in milliseconds.
+type Tone struct {
+	Frequency uint32
+	Duration  uint64
+	Rest      uint64
+}
+
+// Semitones of the natural notes above C.
+var noteSemitones = map[byte]int{'C': 0, 'D': 2, 'E': 4, 'F': 5, 'G': 7, 'A': 9, 'B': 11}
+
+// Fraction of each note left silent, so that repeated notes are heard apart.
+const articulationGap = 0.1
+
+// Returns the frequency in Hz of a note such as "A4", "C#5" or "Bb3": a
+// letter, an optional sharp (#) or flat (b), and the octave, in scientific
+// pitch notation where A4 is 440 Hz.
+func NoteFrequency(note string) (float64, error) {
+	if len(note) < 2 {
+		return 0, fmt.Errorf("sound: invalid note %q", note)
+	}
+
+	semitone, ok := noteSemitones[note[0]&^0x20]
+	if !ok {
+		return 0, fmt.Errorf("sound: invalid note %q", note)
+	}
+
+	rest := note[1:]
+	switch rest[0] {
+	case '#':
+		semitone++
+		rest = rest[1:]
+	case 'b':
+		semitone--
+		rest = rest[1:]
+	}
+
+	octave, err := strconv.Atoi(rest)
+	if err != nil || octave < 0 || octave > 9 {
+		return 0, fmt.Errorf("sound: invalid octave in note %q", note)
+	}
+
+	// MIDI numbering, where A4 is 69.
+	number := 12*(octave+1) + semitone
+	return 440 * math.Pow(2, float64(number-69)/12), nil
+}
+
+// Parses a melody written as notes separated by spaces, each a note as
+// NoteFrequency reads it, or R for a rest, followed by its length as a
+// fraction of a whole note: ":4" for a quarter, ":8" for an eighth, ":4."
+// for a dotted quarter. Notes without a length are quarters. `tempo` is in
+// quarter notes per minute:
+//
+//	tones, err := Sound.ParseMelody("C4:8 E4:8 G4:8 C5:4. R:8 G4:8 C5:2", 120)
+func ParseMelody(melody string, tempo int) ([]Tone, error) {
+	if tempo <= 0 {
+		return nil, fmt.Errorf("sound: invalid tempo %d", tempo)
+	}
+
+	whole := 4 * 60000 / float64(tempo)
+
+	var tones []Tone
+	for _, token := range strings.Fields(melody) {
+		name, length := token, "4"
+		if i := strings.IndexByte(token, ':'); i >= 0 {
+			name, length = token[:i], token[i+1:]
+		}
+
+		dotted := strings.HasSuffix(length, ".")
+		divisor, err := strconv.Atoi(strings.TrimSuffix(length, "."))
+		if err != nil || divisor <= 0 {
+			return nil, fmt.Errorf("sound: invalid length in %q", token)
+		}
+
+		duration := whole / float64(divisor)
+		if dotted {
+			duration *= 1.5
+		}
+
+		if name == "R" || name == "r" {
+			tones = append(tones, Tone{Rest: uint64(math.Round(duration))})
+			continue
+		}
+
+		frequency, err := NoteFrequency(name)
+		if err != nil {
+			return nil, err
+		}
+
+		gap := math.Round(duration * articulationGap)
+		tones = append(tones, Tone{
+			Frequency: uint32(math.Round(frequency)),
+			Duration:  uint64(math.Round(duration - gap)),
+			Rest:      uint64(gap),
+		})
+	}
+
+	return tones, nil
+}
+
+// Plays the tone, then its rest, blocking until done.
+func (self Tone) Play() {
+	PlayTones([]Tone{self})
+}
+
+// Plays the tones one after the other, blocking until done.
+func PlayTones(tones []Tone) {
+	for _, t := range tones {
+		if t.Frequency == 0 {
+			time.Sleep(time.Duration(t.Duration+t.Rest) * time.Millisecond)
+			continue
+		}
+
+		PlayToneAndRest(t.Frequency, t.Duration, t.Rest)
+	}
+}
+
+// Plays a melody written as for ParseMelody, blocking until done:
+//
+//	Sound.PlayMelody("G4:8 G4:8 G4:8 Eb4:2", 100)
+//
+// An invalid melody is a fatal error; use ParseMelody and PlayTones to handle it.
+func PlayMelody(melody string, tempo int) {
+	tones, err := ParseMelody(melody, tempo)
+	if err != nil {
+		utilities.Fatal(err)
+	}
+
+	PlayTones(tones)
+}
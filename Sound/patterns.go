@@ -0,0 +1,115 @@
+package Sound
+
+import (
+	"sync"
+	"time"
+
+	"github.com/jermon/GoEV3/LED"
+)
+
+// A sequence of beeps signaling a status, heard even where the display can't
+// be seen. Patterns play one at a time: one started while another is playing
+// waits for it to end.
+type Pattern []Tone
+
+// Lengths of the beeps and pauses of patterns written with NewPattern, in milliseconds.
+const (
+	shortBeep   = 100
+	longBeep    = 350
+	beepGap     = 80
+	patternWord = 250
+)
+
+// Predefined patterns.
+var (
+	// Two short beeps and a long one, e.g. for a program ready to start.
+	ShortShortLong = NewPattern(1000, "..-")
+	// The distress signal, for a robot needing help.
+	SOS = NewPattern(1000, "... --- ...")
+	// A quick descending chirp, for errors.
+	ErrorChirp = Pattern{{1600, 60, 10}, {1100, 60, 10}, {700, 120, 0}}
+	// A quick ascending chirp, for success.
+	SuccessChirp = Pattern{{700, 60, 10}, {1100, 60, 10}, {1600, 120, 0}}
+)
+
+var gPatternLock = &sync.Mutex{}
+
+// Writes a pattern of beeps at `frequency` Hz: each '.' is a short beep,
+// each '-' a long one and each space a pause between groups, as in Morse code:
+//
+//	Sound.NewPattern(880, ".. ..").PlayAsync()
+func NewPattern(frequency uint32, beeps string) Pattern {
+	var pattern Pattern
+
+	for _, c := range beeps {
+		switch c {
+		case '.':
+			pattern = append(pattern, Tone{frequency, shortBeep, beepGap})
+		case '-':
+			pattern = append(pattern, Tone{frequency, longBeep, beepGap})
+		case ' ':
+			if len(pattern) > 0 {
+				pattern[len(pattern)-1].Rest += patternWord
+			}
+		}
+	}
+
+	return pattern
+}
+
+// Plays the pattern, blocking until done.
+func (self Pattern) Play() {
+	self.Signal("")
+}
+
+// Plays the pattern in the background.
+func (self Pattern) PlayAsync() {
+	go self.Play()
+}
+
+// Plays the pattern while flashing both status LEDs in `color` with each
+// beep, blocking until done. An empty color leaves the LEDs alone.
+func (self Pattern) Signal(color LED.Color) {
+	gPatternLock.Lock()
+	defer gPatternLock.Unlock()
+
+	for _, t := range self {
+		if t.Frequency == 0 {
+			time.Sleep(time.Duration(t.Duration+t.Rest) * time.Millisecond)
+			continue
+		}
+
+		if color != "" {
+			LED.TurnOn(color, LED.Left)
+			LED.TurnOn(color, LED.Right)
+		}
+
+		PlayTone(t.Frequency, t.Duration)
+
+		if color != "" {
+			LED.TurnOff(color, LED.Left)
+			LED.TurnOff(color, LED.Right)
+		}
+
+		time.Sleep(time.Duration(t.Rest) * time.Millisecond)
+	}
+}
+
+// Plays the pattern with Signal in the background.
+func (self Pattern) SignalAsync(color LED.Color) {
+	go self.Signal(color)
+}
+
+// Signals the pattern over and over, `pause` apart, until a value is sent to
+// `stop`, e.g. while waiting for help after a fault.
+func (self Pattern) Repeat(stop <-chan bool, pause time.Duration, color LED.Color) {
+	for {
+		self.Signal(color)
+
+		select {
+		case <-stop:
+			return
+		case <-time.After(pause):
+		}
+	}
+}
@@ -2,8 +2,10 @@
 package Sound
 
 import (
-	"github.com/ldmberman/GoEV3/utilities"
+	"fmt"
+	"github.com/jermon/GoEV3/utilities"
 	"os/exec"
+	"strings"
 	"time"
 )
 
@@ -20,9 +22,21 @@ func PlayAsync(path string) {
 	_ = c1.Start()
 }
 
+// Plays the given wave file like Play, but returns the error of the playback,
+// e.g. a missing file or aplay not being installed.
+func PlayWAV(path string) error {
+	output, err := exec.Command("aplay", "-q", path).CombinedOutput()
+	if err != nil && len(output) > 0 {
+		return fmt.Errorf("sound: playing %s: %v: %s", path, err, strings.TrimSpace(string(output)))
+	}
+
+	return err
+}
+
 // Returns the current system volume in range [0, 100].
 func CurrentVolume() uint8 {
-	return utilities.ReadUInt8Value("/sys/devices/platform/snd-legoev3", "volume")
+	value, _ := utilities.ReadValue[uint8]("/sys/devices/platform/snd-legoev3", "volume")
+	return value
 }
 
 // Sets the system volume to the specified argument in range [0, 100].
@@ -31,19 +45,41 @@ func SetVolume(volume uint8) {
 		volume = 100
 	}
 
-	utilities.WriteUIntValue("/sys/devices/platform/snd-legoev3", "volume", uint64(volume))
+	utilities.WriteValue("/sys/devices/platform/snd-legoev3", "volume", volume)
 }
 
 // Returns the frequency of the current playing tone.
 func CurrentTone() uint32 {
-	return utilities.ReadUInt32Value("/sys/devices/platform/snd-legoev3", "tone")
+	value, _ := utilities.ReadValue[uint32]("/sys/devices/platform/snd-legoev3", "tone")
+	return value
 }
 
 // Plays a tone at the given frequency for the given duration (in ms). To play a sequence of tones asynchronously, call PlayTone repeatedly in a goroutine.
 func PlayTone(freq uint32, duration uint64) {
-	utilities.WriteUIntValue("/sys/devices/platform/snd-legoev3", "tone", uint64(freq))
+	utilities.WriteValue("/sys/devices/platform/snd-legoev3", "tone", freq)
 	time.Sleep(time.Duration(duration) * time.Millisecond)
-	utilities.WriteUIntValue("/sys/devices/platform/snd-legoev3", "tone", 0)
+	utilities.WriteValue("/sys/devices/platform/snd-legoev3", "tone", 0)
+}
+
+// Frequency and length of Beep.
+const (
+	beepFrequency = 1000
+	beepDuration  = 100 * time.Millisecond
+)
+
+// Plays a short beep, blocking until done.
+func Beep() {
+	PlayToneFor(beepFrequency, beepDuration)
+}
+
+// Plays a tone at `frequency` Hz for `duration`, blocking until done; PlayTone
+// with the duration as a time.Duration. Tone being the type of the notes of a
+// melody, a single tone is played with this or with Tone.Play:
+//
+//	Sound.PlayToneFor(440, 200*time.Millisecond)
+//	Sound.Tone{Frequency: 440, Duration: 200}.Play()
+func PlayToneFor(frequency uint32, duration time.Duration) {
+	PlayTone(frequency, uint64(duration.Milliseconds()))
 }
 
 // Plays a tone at the given frequency for the given duration (in ms). Then sleeps for `rest` ms.
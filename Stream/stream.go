@@ -0,0 +1,206 @@
+// Provides resampling, alignment and differentiation of timestamped sensor
+// readings.
+//
+// Sensors are polled at irregular intervals: sysfs reads take varying time
+// and goroutines are scheduled late. Series turns such readings into evenly
+// spaced ones by linear interpolation, lines several of them up on a common
+// timebase, and computes rates of change:
+//
+//	var distance Stream.Series
+//	distance.Add(time.Now(), sensor.Distance().Centimeters())
+//	...
+//	even := distance.Resample(20 * time.Millisecond)
+//	speed := even.Derivative()
+package Stream
+
+import (
+	"sort"
+	"time"
+)
+
+// A reading taken at a point in time.
+type Sample struct {
+	Time  time.Time
+	Value float64
+}
+
+// Readings ordered by time.
+type Series []Sample
+
+// Appends a reading. Readings must be added in chronological order; use Sort otherwise.
+func (self *Series) Add(t time.Time, value float64) {
+	*self = append(*self, Sample{t, value})
+}
+
+// Orders the readings by time, keeping readings with equal times in their order.
+func (self Series) Sort() {
+	sort.SliceStable(self, func(i, j int) bool {
+		return self[i].Time.Before(self[j].Time)
+	})
+}
+
+// Returns the time of the first and the last reading.
+func (self Series) Span() (time.Time, time.Time) {
+	if len(self) == 0 {
+		return time.Time{}, time.Time{}
+	}
+
+	return self[0].Time, self[len(self)-1].Time
+}
+
+// Returns the value at time `t`, interpolated linearly between the readings
+// around it. Reports false if `t` is outside the span of the series.
+func (self Series) At(t time.Time) (float64, bool) {
+	if len(self) == 0 || t.Before(self[0].Time) || t.After(self[len(self)-1].Time) {
+		return 0, false
+	}
+
+	// Index of the first reading not before t.
+	i := sort.Search(len(self), func(i int) bool {
+		return !self[i].Time.Before(t)
+	})
+
+	if self[i].Time.Equal(t) || i == 0 {
+		return self[i].Value, true
+	}
+
+	return interpolate(self[i-1], self[i], t), true
+}
+
+func interpolate(a Sample, b Sample, t time.Time) float64 {
+	span := b.Time.Sub(a.Time)
+	if span <= 0 {
+		return b.Value
+	}
+
+	f := float64(t.Sub(a.Time)) / float64(span)
+	return a.Value + f*(b.Value-a.Value)
+}
+
+// Returns the series sampled every `interval` from its first reading to its last.
+func (self Series) Resample(interval time.Duration) Series {
+	start, end := self.Span()
+	return self.ResampleRange(start, end, interval)
+}
+
+// Returns the series sampled every `interval` from `start` to `end`, skipping
+// the times outside the span of the series.
+func (self Series) ResampleRange(start time.Time, end time.Time, interval time.Duration) Series {
+	if interval <= 0 || len(self) == 0 {
+		return nil
+	}
+
+	var result Series
+	for t := start; !t.After(end); t = t.Add(interval) {
+		if value, ok := self.At(t); ok {
+			result = append(result, Sample{t, value})
+		}
+	}
+
+	return result
+}
+
+// Returns the rate of change of the series, in units per second, using central
+// differences at inner readings and one-sided differences at the ends.
+// Readings with equal times are skipped.
+func (self Series) Derivative() Series {
+	if len(self) < 2 {
+		return nil
+	}
+
+	slope := func(a Sample, b Sample) (float64, bool) {
+		dt := b.Time.Sub(a.Time).Seconds()
+		if dt <= 0 {
+			return 0, false
+		}
+		return (b.Value - a.Value) / dt, true
+	}
+
+	var result Series
+	for i := range self {
+		a, b := self[max(i-1, 0)], self[min(i+1, len(self)-1)]
+		if rate, ok := slope(a, b); ok {
+			result = append(result, Sample{self[i].Time, rate})
+		}
+	}
+
+	return result
+}
+
+// Samples all series every `interval` over the time span they have in common.
+// Returns the common times, and for each series its values at those times.
+// Returns nothing if the series don't overlap.
+func Align(interval time.Duration, series ...Series) ([]time.Time, [][]float64) {
+	if len(series) == 0 || interval <= 0 {
+		return nil, nil
+	}
+
+	var start, end time.Time
+	for i, s := range series {
+		if len(s) == 0 {
+			return nil, nil
+		}
+
+		first, last := s.Span()
+		if i == 0 || first.After(start) {
+			start = first
+		}
+		if i == 0 || last.Before(end) {
+			end = last
+		}
+	}
+
+	var times []time.Time
+	for t := start; !t.After(end); t = t.Add(interval) {
+		times = append(times, t)
+	}
+
+	values := make([][]float64, len(series))
+	for i, s := range series {
+		values[i] = make([]float64, len(times))
+		for j, t := range times {
+			values[i][j], _ = s.At(t)
+		}
+	}
+
+	return times, values
+}
+
+// Turns readings arriving one by one at irregular times into evenly spaced
+// ones, e.g. to feed a filter expecting a fixed rate.
+type Resampler struct {
+	interval time.Duration
+	last     Sample
+	next     time.Time
+	started  bool
+}
+
+// Creates a resampler producing a sample every `interval`.
+func NewResampler(interval time.Duration) *Resampler {
+	return &Resampler{interval: interval}
+}
+
+// Adds a reading and returns the evenly spaced samples up to its time, if any.
+// The first reading is returned as is and sets the timebase.
+func (self *Resampler) Push(t time.Time, value float64) []Sample {
+	s := Sample{t, value}
+
+	if !self.started {
+		self.started = true
+		self.last = s
+		self.next = t.Add(self.interval)
+		return []Sample{s}
+	}
+
+	if !t.After(self.last.Time) {
+		return nil
+	}
+
+	var result []Sample
+	for ; !self.next.After(t); self.next = self.next.Add(self.interval) {
+		result = append(result, Sample{self.next, interpolate(self.last, s, self.next)})
+	}
+	self.last = s
+
+	return result
+}
@@ -0,0 +1,311 @@
+// Coordinates several robots on the same network with UDP broadcasts, without
+// a server: every member periodically broadcasts a heartbeat carrying its
+// shared state, learns about the others from theirs, and agrees with them on
+// a leader. For formation driving, the leader publishes where it goes and the
+// others follow:
+//
+//	swarm, _ := Swarm.Join(config.Name, Swarm.DefaultPort)
+//	go swarm.Run(stop, 100*time.Millisecond)
+//
+//	if swarm.IsLeader() {
+//		swarm.Set("heading", heading)
+//	} else {
+//		var heading float64
+//		swarm.Get(swarm.Leader(), "heading", &heading)
+//	}
+//
+// Messages can also be broadcast to every member with Send. Broadcasts are
+// not delivered reliably: state is repeated with every heartbeat, and
+// messages that must arrive should be sent over Peer connections instead.
+package Swarm
+
+import (
+	"encoding/json"
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/jermon/GoEV3/Peer"
+)
+
+// Default UDP port of the swarm.
+const DefaultPort = 4748
+
+// Number of heartbeat intervals after which a silent member is considered gone.
+const missedHeartbeats = 3
+
+const heartbeatType = "swarm.heartbeat"
+
+// A datagram: a message along with its sender.
+type packet struct {
+	From string `json:"from"`
+	Peer.Message
+}
+
+// Callback invoked for a message broadcast by another member.
+type Handler func(from string, m Peer.Message)
+
+// Another robot of the swarm.
+type Member struct {
+	ID       string
+	Addr     *net.UDPAddr
+	LastSeen time.Time
+	State    map[string]json.RawMessage
+}
+
+// A robot's membership of the swarm.
+type Swarm struct {
+	id        string
+	conn      *net.UDPConn
+	broadcast *net.UDPAddr
+
+	lock     sync.Mutex
+	interval time.Duration
+	state    map[string]json.RawMessage
+	members  map[string]*Member
+	handlers map[string][]Handler
+	leader   string
+	onLeader func(leader string)
+}
+
+// Joins the swarm on UDP `port` as `id`, which must be unique among its
+// members, e.g. the robot's name. Nothing is sent or received until Run.
+func Join(id string, port int) (*Swarm, error) {
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{Port: port})
+	if err != nil {
+		return nil, err
+	}
+
+	s := new(Swarm)
+	s.id = id
+	s.conn = conn
+	s.broadcast = &net.UDPAddr{IP: net.IPv4bcast, Port: port}
+	s.interval = time.Second
+	s.state = make(map[string]json.RawMessage)
+	s.members = make(map[string]*Member)
+	s.handlers = make(map[string][]Handler)
+	s.leader = id
+
+	return s, nil
+}
+
+// Returns the ID of this robot.
+func (self *Swarm) ID() string {
+	return self.id
+}
+
+// Sets a key of this robot's shared state, which the others read with Get,
+// and broadcasts it right away.
+func (self *Swarm) Set(key string, value interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	self.lock.Lock()
+	self.state[key] = data
+	self.lock.Unlock()
+
+	return self.heartbeat()
+}
+
+// Unmarshals the value of `key` in the shared state of member `id`, which may
+// be this robot, into `v`. Reports whether the member and key are known.
+func (self *Swarm) Get(id string, key string, v interface{}) bool {
+	self.lock.Lock()
+	var data json.RawMessage
+	if id == self.id {
+		data = self.state[key]
+	} else if m, ok := self.members[id]; ok {
+		data = m.State[key]
+	}
+	self.lock.Unlock()
+
+	if data == nil {
+		return false
+	}
+
+	return json.Unmarshal(data, v) == nil
+}
+
+// Returns the other members heard from recently, ordered by ID.
+func (self *Swarm) Members() []Member {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	members := make([]Member, 0, len(self.members))
+	for _, m := range self.members {
+		members = append(members, *m)
+	}
+	sort.Slice(members, func(i, j int) bool { return members[i].ID < members[j].ID })
+
+	return members
+}
+
+// Registers a handler for messages of the given type broadcast by other members.
+func (self *Swarm) Handle(msgType string, fn Handler) {
+	self.lock.Lock()
+	self.handlers[msgType] = append(self.handlers[msgType], fn)
+	self.lock.Unlock()
+}
+
+// Broadcasts a message to every member.
+func (self *Swarm) Send(msgType string, data interface{}) error {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	return self.send(packet{self.id, Peer.Message{Type: msgType, Data: raw}})
+}
+
+func (self *Swarm) send(p packet) error {
+	data, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+
+	_, err = self.conn.WriteToUDP(data, self.broadcast)
+	return err
+}
+
+func (self *Swarm) heartbeat() error {
+	self.lock.Lock()
+	data, err := json.Marshal(self.state)
+	self.lock.Unlock()
+	if err != nil {
+		return err
+	}
+
+	return self.send(packet{self.id, Peer.Message{Type: heartbeatType, Data: data}})
+}
+
+// Returns the leader: the member with the lowest ID among those heard from
+// recently and this robot. All members agree on it once they have heard from
+// each other, and elect another one when it goes silent.
+func (self *Swarm) Leader() string {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	return self.leader
+}
+
+// Reports whether this robot is the leader.
+func (self *Swarm) IsLeader() bool {
+	return self.Leader() == self.id
+}
+
+// Sets a function called with the new leader whenever it changes.
+func (self *Swarm) OnLeaderChange(fn func(leader string)) {
+	self.lock.Lock()
+	self.onLeader = fn
+	self.lock.Unlock()
+}
+
+// Elects the leader again, after members came or went.
+func (self *Swarm) elect() {
+	self.lock.Lock()
+	leader := self.id
+	for id := range self.members {
+		if id < leader {
+			leader = id
+		}
+	}
+
+	changed := leader != self.leader
+	self.leader = leader
+	onLeader := self.onLeader
+	self.lock.Unlock()
+
+	if changed && onLeader != nil {
+		onLeader(leader)
+	}
+}
+
+// Forgets the members that missed too many heartbeats.
+func (self *Swarm) expire() {
+	self.lock.Lock()
+	deadline := time.Now().Add(-missedHeartbeats * self.interval)
+	for id, m := range self.members {
+		if m.LastSeen.Before(deadline) {
+			delete(self.members, id)
+		}
+	}
+	self.lock.Unlock()
+
+	self.elect()
+}
+
+func (self *Swarm) receive() {
+	buf := make([]byte, 65536)
+
+	for {
+		n, from, err := self.conn.ReadFromUDP(buf)
+		if err != nil {
+			return
+		}
+
+		var p packet
+		if json.Unmarshal(buf[:n], &p) != nil || p.From == "" || p.From == self.id {
+			continue
+		}
+
+		self.lock.Lock()
+		m, known := self.members[p.From]
+		if !known {
+			m = &Member{ID: p.From, State: make(map[string]json.RawMessage)}
+			self.members[p.From] = m
+		}
+		m.Addr = from
+		m.LastSeen = time.Now()
+
+		if p.Type == heartbeatType {
+			var state map[string]json.RawMessage
+			if p.Decode(&state) == nil && state != nil {
+				m.State = state
+			}
+		}
+		fns := append([]Handler(nil), self.handlers[p.Type]...)
+		self.lock.Unlock()
+
+		if !known {
+			self.elect()
+		}
+
+		for _, fn := range fns {
+			fn(p.From, p.Message)
+		}
+	}
+}
+
+// Broadcasts a heartbeat every `interval` and handles the broadcasts of the
+// other members until a value is sent to `stop`, when it leaves the swarm.
+// Members must all use the same interval.
+func (self *Swarm) Run(stop <-chan bool, interval time.Duration) {
+	self.lock.Lock()
+	self.interval = interval
+	self.lock.Unlock()
+
+	done := make(chan bool)
+	go func() {
+		self.receive()
+		close(done)
+	}()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		self.heartbeat()
+		self.expire()
+
+		select {
+		case <-stop:
+			self.conn.Close()
+			<-done
+			return
+		case <-ticker.C:
+		}
+	}
+}
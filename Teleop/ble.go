@@ -0,0 +1,155 @@
+package Teleop
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/jermon/GoEV3/Bluetooth"
+)
+
+// UUIDs of the remote-control GATT service and its characteristics.
+var (
+	RemoteService   = Bluetooth.MustParseUUID("6e400100-b5a3-f393-e0a9-e50e24dcca9e")
+	RemoteDrive     = Bluetooth.MustParseUUID("6e400101-b5a3-f393-e0a9-e50e24dcca9e")
+	RemoteTelemetry = Bluetooth.MustParseUUID("6e400102-b5a3-f393-e0a9-e50e24dcca9e")
+)
+
+// How long drive commands stay in effect without a new write, so the robot
+// stops when the phone goes out of range.
+const bleDeadman = 500 * time.Millisecond
+
+// Receives drive commands from phone apps over a Bluetooth Low Energy GATT
+// service, and sends them telemetry.
+//
+// Apps write 3 bytes to the RemoteDrive characteristic: the forward and turn
+// commands as signed bytes in range [-100, 100] and a bitmask of held buttons.
+// They subscribe to RemoteTelemetry to receive JSON values passed to Publish.
+//
+//	remote := Teleop.NewBLERemote("ev3")
+//	go remote.ListenAndServe()
+//	m.SetDrive(remote.Forward(), remote.Turn())
+//	m.BindAction(remote.Button(0), honk)
+type BLERemote struct {
+	name      string
+	server    *Bluetooth.GATTServer
+	telemetry *Bluetooth.Characteristic
+
+	lock     sync.Mutex
+	forward  float64
+	turn     float64
+	buttons  byte
+	received time.Time
+	last     []byte
+}
+
+// Creates a remote advertised under `name`.
+func NewBLERemote(name string) *BLERemote {
+	r := new(BLERemote)
+	r.name = name
+
+	drive := &Bluetooth.Characteristic{UUID: RemoteDrive, Write: r.receive}
+	r.telemetry = &Bluetooth.Characteristic{UUID: RemoteTelemetry, Read: r.lastTelemetry, Notify: true}
+
+	r.server = Bluetooth.NewGATTServer(name, &Bluetooth.Service{
+		UUID:            RemoteService,
+		Characteristics: []*Bluetooth.Characteristic{drive, r.telemetry},
+	})
+
+	return r
+}
+
+// Advertises the remote and serves connections until listening fails.
+func (self *BLERemote) ListenAndServe() error {
+	listener, err := Bluetooth.ListenLE()
+	if err != nil {
+		return err
+	}
+	defer listener.Close()
+
+	if err := Bluetooth.Advertise(self.name, RemoteService); err != nil {
+		return err
+	}
+
+	return self.server.Serve(listener)
+}
+
+// Returns the GATT server, to serve the remote over other connections.
+func (self *BLERemote) Server() *Bluetooth.GATTServer {
+	return self.server
+}
+
+func (self *BLERemote) receive(value []byte) {
+	if len(value) < 2 {
+		return
+	}
+
+	self.lock.Lock()
+	self.forward = float64(int8(value[0])) / 100
+	self.turn = float64(int8(value[1])) / 100
+	self.buttons = 0
+	if len(value) >= 3 {
+		self.buttons = value[2]
+	}
+	self.received = time.Now()
+	self.lock.Unlock()
+}
+
+// Returns the last command, or zeros once it expired.
+func (self *BLERemote) command() (float64, float64, byte) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	if time.Since(self.received) > bleDeadman {
+		return 0, 0, 0
+	}
+
+	return self.forward, self.turn, self.buttons
+}
+
+// Reads the forward command, positive being forward.
+func (self *BLERemote) Forward() Source {
+	return func() float64 {
+		f, _, _ := self.command()
+		return f
+	}
+}
+
+// Reads the turn command, positive being left.
+func (self *BLERemote) Turn() Source {
+	return func() float64 {
+		_, t, _ := self.command()
+		return t
+	}
+}
+
+// Fires while the given button, 0 to 7, is held.
+func (self *BLERemote) Button(n uint) Trigger {
+	return func() bool {
+		_, _, b := self.command()
+		return b&(1<<n) != 0
+	}
+}
+
+func (self *BLERemote) lastTelemetry() []byte {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	return self.last
+}
+
+// Sends `v` encoded as JSON to subscribed apps. Keep values small: notifications
+// are truncated to the connection's MTU, 20 bytes unless the app negotiated more.
+func (self *BLERemote) Publish(v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	self.lock.Lock()
+	self.last = data
+	self.lock.Unlock()
+
+	self.server.Notify(self.telemetry, data)
+	return nil
+}
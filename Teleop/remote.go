@@ -0,0 +1,61 @@
+package Teleop
+
+import (
+	"github.com/jermon/GoEV3/Drive"
+	"github.com/jermon/GoEV3/Sensors"
+)
+
+// Assigns IR remote buttons to the wheels of a drive base, driven tank style.
+type RemoteLayout struct {
+	// Channel selected on the remote, so that several robots can be driven at once.
+	Channel Sensors.Channel
+
+	LeftForward   Sensors.Button
+	LeftBackward  Sensors.Button
+	RightForward  Sensors.Button
+	RightBackward Sensors.Button
+
+	// Stops the robot while held or, for Beacon, while the beacon is on.
+	// 0 leaves the robot without a stop button.
+	Stop Sensors.Button
+}
+
+// Drives the left wheel with the red buttons and the right wheel with the blue
+// ones, and stops while the beacon is on, on channel 1.
+var DefaultRemoteLayout = RemoteLayout{
+	Channel:       Sensors.Channel1,
+	LeftForward:   Sensors.RedUp,
+	LeftBackward:  Sensors.RedDown,
+	RightForward:  Sensors.BlueUp,
+	RightBackward: Sensors.BlueDown,
+	Stop:          Sensors.Beacon,
+}
+
+// Creates a mapping driving `base` with the IR remote, the buttons assigned as
+// in `layout`. Each wheel runs at the full speed set with SetSpeeds while its
+// button is held, which requires a turn rate of 1, the preset's. More motors
+// and actions can be bound as usual:
+//
+//	ir := Sensors.FindInfraredSensor(Sensors.InPort4)
+//	m := Teleop.NewRemoteMapping(base, Teleop.NewRemote(ir, stop), Teleop.DefaultRemoteLayout)
+//	m.Run(stop, 20*time.Millisecond)
+func NewRemoteMapping(base *Drive.DriveBase, remote *Remote, layout RemoteLayout) *Mapping {
+	c := layout.Channel
+	left := RemoteButtons(remote, c, layout.LeftForward, layout.LeftBackward)
+	right := RemoteButtons(remote, c, layout.RightForward, layout.RightBackward)
+
+	m := NewMapping(base)
+	// Forward and turn mixed back with a turn rate of 1 give each wheel its own buttons.
+	m.SetDrive(
+		func() float64 { return (left() + right()) / 2 },
+		func() float64 { return (right() - left()) / 2 },
+	)
+	m.SetCurves(Linear, Linear)
+	m.turnRate = 1
+
+	if layout.Stop != 0 {
+		m.SetHalt(RemoteButton(remote, c, layout.Stop))
+	}
+
+	return m
+}
@@ -0,0 +1,206 @@
+package Teleop
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"math"
+	"sync"
+	"time"
+)
+
+// Kinds of recorded commands.
+const (
+	CommandDrive  = "drive"
+	CommandMotor  = "motor"
+	CommandAction = "action"
+)
+
+// A command given by a Mapping while recording.
+type RecordedCommand struct {
+	// Milliseconds since the recording started.
+	Time int64  `json:"t"`
+	Kind string `json:"kind"`
+	// Wheel speeds of drive commands.
+	Left  int16 `json:"left,omitempty"`
+	Right int16 `json:"right,omitempty"`
+	// Index of the motor or action binding, in the order they were bound.
+	Index int `json:"index,omitempty"`
+	// Speed of motor commands.
+	Speed int16 `json:"speed,omitempty"`
+}
+
+// Commands recorded during teleoperation, to be replayed autonomously.
+type Routine struct {
+	Commands []RecordedCommand `json:"commands"`
+	// Milliseconds from the start of the recording to its end.
+	Duration int64 `json:"duration"`
+}
+
+// Reads a routine from a JSON file.
+func LoadRoutine(filename string) (*Routine, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	routine := new(Routine)
+	if err := json.Unmarshal(data, routine); err != nil {
+		return nil, err
+	}
+
+	return routine, nil
+}
+
+// Writes the routine to a JSON file.
+func (self *Routine) Save(filename string) error {
+	data, err := json.MarshalIndent(self, "", "\t")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(filename, append(data, '\n'), 0644)
+}
+
+// Records the commands a Mapping gives, for teaching a routine by driving it:
+//
+//	recorder := Teleop.NewRecorder()
+//	m.SetRecorder(recorder)
+//	recorder.Start()
+//	m.Run(stop, 20*time.Millisecond)
+//	recorder.Stop().Save("/home/robot/routine.json")
+//
+// The routine is replayed with Mapping.Replay, on a mapping with the same bindings.
+type Recorder struct {
+	lock      sync.Mutex
+	recording bool
+	start     time.Time
+	commands  []RecordedCommand
+}
+
+// Creates a recorder, which records nothing until started.
+func NewRecorder() *Recorder {
+	return new(Recorder)
+}
+
+// Discards the commands recorded so far and starts recording.
+func (self *Recorder) Start() {
+	self.lock.Lock()
+	self.recording = true
+	self.start = time.Now()
+	self.commands = nil
+	self.lock.Unlock()
+}
+
+// Stops recording and returns the routine recorded.
+func (self *Recorder) Stop() *Routine {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	self.recording = false
+
+	return &Routine{
+		Commands: append([]RecordedCommand(nil), self.commands...),
+		Duration: time.Since(self.start).Milliseconds(),
+	}
+}
+
+// Reports whether the recorder is recording.
+func (self *Recorder) Recording() bool {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	return self.recording
+}
+
+func (self *Recorder) record(c RecordedCommand) {
+	if self == nil {
+		return
+	}
+
+	self.lock.Lock()
+	if self.recording {
+		c.Time = time.Since(self.start).Milliseconds()
+		self.commands = append(self.commands, c)
+	}
+	self.lock.Unlock()
+}
+
+// Records the commands the mapping gives to `recorder`, or stops recording them if nil.
+func (self *Mapping) SetRecorder(recorder *Recorder) {
+	self.lock.Lock()
+	self.recorder = recorder
+	self.lock.Unlock()
+}
+
+// Replays a recorded routine on the drive base and motors of the mapping,
+// without reading its inputs, and stops all motors at the end. `scale` slows
+// down (below 1) or speeds up (above 1) the replay: speeds are multiplied and
+// durations divided by it, so the robot follows the same path as long as the
+// scaled speeds stay in range. Returns early if a value is sent to `stop`.
+func (self *Mapping) Replay(routine *Routine, scale float64, stop <-chan bool) error {
+	if scale <= 0 {
+		return errors.New("teleop: replay scale must be positive")
+	}
+
+	self.lock.Lock()
+	base := self.base
+	motors := append(([]*motorBinding)(nil), self.motors...)
+	actions := append(([]*actionBinding)(nil), self.actions...)
+	self.lock.Unlock()
+
+	defer self.stopAll()
+
+	speed := func(s int16) int16 {
+		return int16(math.Round(float64(s) * scale))
+	}
+
+	start := time.Now()
+	// Waits until `t` milliseconds of the recording have passed, scaled.
+	wait := func(t int64) bool {
+		due := start.Add(time.Duration(float64(t) / scale * float64(time.Millisecond)))
+
+		select {
+		case <-stop:
+			return false
+		case <-time.After(time.Until(due)):
+			return true
+		}
+	}
+
+	for _, c := range routine.Commands {
+		if !wait(c.Time) {
+			return nil
+		}
+
+		switch c.Kind {
+		case CommandDrive:
+			if base == nil {
+				continue
+			}
+			if c.Left == 0 && c.Right == 0 {
+				base.Stop()
+			} else {
+				base.Tank(speed(c.Left), speed(c.Right))
+			}
+
+		case CommandMotor:
+			if c.Index < 0 || c.Index >= len(motors) {
+				continue
+			}
+			if m := motors[c.Index].motor; c.Speed == 0 {
+				m.Stop()
+			} else {
+				m.Run(speed(c.Speed))
+			}
+
+		case CommandAction:
+			if c.Index >= 0 && c.Index < len(actions) {
+				actions[c.Index].fn()
+			}
+		}
+	}
+
+	wait(routine.Duration)
+	return nil
+}
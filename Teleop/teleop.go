@@ -0,0 +1,439 @@
+// Provides a mapping layer that binds gamepad, keyboard and IR remote inputs
+// to a drive base and auxiliary motors.
+//
+// Inputs are read as sources of values in range [-1, 1], shaped by curves with
+// dead zones and exponential response, and mixed into wheel speeds:
+//
+//	pad, _ := Input.FindGamepad()
+//	m := Teleop.NewMapping(base)
+//	m.SetDrive(Teleop.GamepadAxis(pad, 1, true), Teleop.GamepadAxis(pad, 0, true))
+//	m.BindMotor(arm, Teleop.GamepadButtons(pad, 5, 4), Teleop.Linear, 40)
+//	m.BindAction(Teleop.GamepadButton(pad, 0), func() { Sound.PlayTone(880, 100) })
+//	m.Run(stop, 20*time.Millisecond)
+package Teleop
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/jermon/GoEV3/Drive"
+	"github.com/jermon/GoEV3/Input"
+	"github.com/jermon/GoEV3/Motor"
+	"github.com/jermon/GoEV3/Sensors"
+	"github.com/jermon/GoEV3/utilities"
+)
+
+// Provides an input value in range [-1, 1].
+type Source func() float64
+
+// Provides the state of a digital input such as a button.
+type Trigger func() bool
+
+// Shapes an input value in range [-1, 1].
+type Curve struct {
+	// Inputs with a magnitude below the dead zone read as 0; the remaining range
+	// is stretched so the output still starts at 0.
+	Deadzone float64
+	// Blend between a linear (0) and a cubic (1) response, for finer control near the center.
+	Expo float64
+	// Reverses the direction.
+	Invert bool
+}
+
+// Passes values through unchanged.
+var Linear = Curve{}
+
+// Suits the sticks of most gamepads.
+var Stick = Curve{Deadzone: 0.08, Expo: 0.4}
+
+// Applies the curve to `value`.
+func (self Curve) Apply(value float64) float64 {
+	value = math.Max(-1, math.Min(1, value))
+
+	magnitude := math.Abs(value)
+	if magnitude < self.Deadzone {
+		return 0
+	}
+	if self.Deadzone > 0 && self.Deadzone < 1 {
+		magnitude = (magnitude - self.Deadzone) / (1 - self.Deadzone)
+	}
+
+	magnitude = (1-self.Expo)*magnitude + self.Expo*magnitude*magnitude*magnitude
+
+	if (value < 0) != self.Invert {
+		return -magnitude
+	}
+
+	return magnitude
+}
+
+// Reads a gamepad axis. Gamepads report up as negative on vertical axes; pass
+// `invert` to make pushing a stick up or left read as positive.
+func GamepadAxis(pad *Input.Gamepad, axis int, invert bool) Source {
+	return func() float64 {
+		if invert {
+			return -pad.Axis(axis)
+		}
+
+		return pad.Axis(axis)
+	}
+}
+
+// Reads 1 while the `positive` button is held, -1 while the `negative` one is.
+func GamepadButtons(pad *Input.Gamepad, positive int, negative int) Source {
+	return func() float64 {
+		return pair(pad.Button(positive), pad.Button(negative))
+	}
+}
+
+// Fires while the given gamepad button is held.
+func GamepadButton(pad *Input.Gamepad, button int) Trigger {
+	return func() bool {
+		return pad.Button(button)
+	}
+}
+
+// Reads 1 while the `positive` key is held, -1 while the `negative` one is.
+func Keys(keyboard *Input.Keyboard, positive Input.Key, negative Input.Key) Source {
+	return func() float64 {
+		return pair(keyboard.Pressed(positive), keyboard.Pressed(negative))
+	}
+}
+
+// Fires while the given key is held.
+func KeyPressed(keyboard *Input.Keyboard, key Input.Key) Trigger {
+	return func() bool {
+		return keyboard.Pressed(key)
+	}
+}
+
+func pair(positive bool, negative bool) float64 {
+	switch {
+	case positive && !negative:
+		return 1
+	case negative && !positive:
+		return -1
+	}
+
+	return 0
+}
+
+// How often the IR remote channels are read.
+const remoteInterval = 50 * time.Millisecond
+
+// Tracks which IR remote buttons are held.
+type Remote struct {
+	lock   sync.Mutex
+	sensor *Sensors.InfraredSensor
+	codes  [4]Sensors.Button
+	stop   <-chan bool
+	task   *utilities.PollTask
+}
+
+// Starts listening to the IR remote through `sensor`, from the shared polling
+// scheduler, until a value is sent to `stop`.
+func NewRemote(sensor *Sensors.InfraredSensor, stop <-chan bool) *Remote {
+	r := new(Remote)
+	r.sensor = sensor
+	r.stop = stop
+
+	// The codes are read rather than followed through press and release
+	// events, which miss a button let go while another is still held. The
+	// first read may come before Poll returns; the lock held makes it wait
+	// for `task` to be set.
+	r.lock.Lock()
+	r.task = utilities.Poll(remoteInterval, r.read)
+	r.lock.Unlock()
+
+	return r
+}
+
+func (self *Remote) read() {
+	self.lock.Lock()
+	task := self.task
+	self.lock.Unlock()
+
+	select {
+	case <-self.stop:
+		task.Cancel()
+		return
+	default:
+	}
+
+	var codes [4]Sensors.Button
+	for c := range codes {
+		codes[c] = self.sensor.ReadRemote(Sensors.Channel(c))
+	}
+
+	self.lock.Lock()
+	self.codes = codes
+	self.lock.Unlock()
+}
+
+// Reports whether the given button is held on the given channel.
+func (self *Remote) Held(c Sensors.Channel, b Sensors.Button) bool {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	return c < 4 && self.codes[c].Has(b)
+}
+
+// Reads 1 while the `positive` remote button is held, -1 while the `negative` one is.
+func RemoteButtons(remote *Remote, c Sensors.Channel, positive Sensors.Button, negative Sensors.Button) Source {
+	return func() float64 {
+		return pair(remote.Held(c, positive), remote.Held(c, negative))
+	}
+}
+
+// Fires while the given remote button is held.
+func RemoteButton(remote *Remote, c Sensors.Channel, b Sensors.Button) Trigger {
+	return func() bool {
+		return remote.Held(c, b)
+	}
+}
+
+type motorBinding struct {
+	motor    *Motor.Motor
+	source   Source
+	curve    Curve
+	maxSpeed int16
+	last     int16
+}
+
+type actionBinding struct {
+	trigger Trigger
+	fn      func()
+	was     bool
+}
+
+// Binds inputs to a drive base and auxiliary motors.
+type Mapping struct {
+	lock sync.Mutex
+
+	base         *Drive.DriveBase
+	forward      Source
+	turn         Source
+	forwardCurve Curve
+	turnCurve    Curve
+	maxSpeed     int16
+	turnRate     float64
+
+	motors  []*motorBinding
+	actions []*actionBinding
+
+	halt   Trigger
+	halted bool
+
+	lastLeft  int16
+	lastRight int16
+
+	recorder *Recorder
+}
+
+// Creates a mapping driving `base`, which may be nil for robots without one.
+func NewMapping(base *Drive.DriveBase) *Mapping {
+	m := new(Mapping)
+	m.base = base
+	m.forwardCurve = Stick
+	m.turnCurve = Stick
+	m.maxSpeed = 100
+	m.turnRate = 0.6
+
+	return m
+}
+
+// Sets the sources of the forward (positive being forward) and turn (positive
+// being left) commands, mixed arcade style.
+func (self *Mapping) SetDrive(forward Source, turn Source) {
+	self.lock.Lock()
+	self.forward, self.turn = forward, turn
+	self.lock.Unlock()
+}
+
+// Sets the curves applied to the forward and turn commands. Both default to Stick.
+func (self *Mapping) SetCurves(forward Curve, turn Curve) {
+	self.lock.Lock()
+	self.forwardCurve, self.turnCurve = forward, turn
+	self.lock.Unlock()
+}
+
+// Sets the wheel speed at full forward command, as passed to Motor.Run, and
+// the fraction of it a full turn command adds to one wheel and subtracts from the other.
+func (self *Mapping) SetSpeeds(maxSpeed int16, turnRate float64) {
+	self.lock.Lock()
+	self.maxSpeed, self.turnRate = maxSpeed, turnRate
+	self.lock.Unlock()
+}
+
+// Runs `motor` at up to `maxSpeed` in proportion to `source`.
+func (self *Mapping) BindMotor(motor *Motor.Motor, source Source, curve Curve, maxSpeed int16) {
+	self.lock.Lock()
+	self.motors = append(self.motors, &motorBinding{motor: motor, source: source, curve: curve, maxSpeed: maxSpeed})
+	self.lock.Unlock()
+}
+
+// Calls `fn` whenever `trigger` starts firing.
+func (self *Mapping) BindAction(trigger Trigger, fn func()) {
+	self.lock.Lock()
+	self.actions = append(self.actions, &actionBinding{trigger: trigger, fn: fn})
+	self.lock.Unlock()
+}
+
+// Stops all motors and ignores the other inputs while `trigger` fires, such as
+// while the beacon of the IR remote is switched on.
+func (self *Mapping) SetHalt(trigger Trigger) {
+	self.lock.Lock()
+	self.halt = trigger
+	self.lock.Unlock()
+}
+
+// Returns the left and right wheel speeds for the current drive inputs.
+func (self *Mapping) WheelSpeeds() (int16, int16) {
+	self.lock.Lock()
+	forward, turn := self.forward, self.turn
+	fc, tc := self.forwardCurve, self.turnCurve
+	maxSpeed, turnRate := float64(self.maxSpeed), self.turnRate
+	self.lock.Unlock()
+
+	f, t := 0.0, 0.0
+	if forward != nil {
+		f = fc.Apply(forward())
+	}
+	if turn != nil {
+		t = tc.Apply(turn()) * turnRate
+	}
+
+	l, r := f-t, f+t
+
+	// Scale both wheels down together so turning at full speed keeps its radius.
+	if peak := math.Max(math.Abs(l), math.Abs(r)); peak > 1 {
+		l, r = l/peak, r/peak
+	}
+
+	return int16(math.Round(l * maxSpeed)), int16(math.Round(r * maxSpeed))
+}
+
+// Reads all inputs once and updates the motors and actions.
+func (self *Mapping) Step() {
+	self.lock.Lock()
+	base := self.base
+	motors := append(([]*motorBinding)(nil), self.motors...)
+	actions := append(([]*actionBinding)(nil), self.actions...)
+	recorder := self.recorder
+	halt := self.halt
+	self.lock.Unlock()
+
+	if halt != nil && halt() {
+		self.lock.Lock()
+		halted := self.halted
+		self.halted = true
+		moving := self.lastLeft != 0 || self.lastRight != 0
+		self.lock.Unlock()
+
+		if !halted {
+			if base != nil && moving {
+				recorder.record(RecordedCommand{Kind: CommandDrive})
+			}
+			for i, b := range motors {
+				if b.last != 0 {
+					recorder.record(RecordedCommand{Kind: CommandMotor, Index: i})
+				}
+			}
+			self.stopAll()
+		}
+
+		return
+	}
+
+	self.lock.Lock()
+	self.halted = false
+	self.lock.Unlock()
+
+	if base != nil {
+		l, r := self.WheelSpeeds()
+
+		self.lock.Lock()
+		changed := l != self.lastLeft || r != self.lastRight
+		self.lastLeft, self.lastRight = l, r
+		self.lock.Unlock()
+
+		if changed {
+			recorder.record(RecordedCommand{Kind: CommandDrive, Left: l, Right: r})
+
+			if l == 0 && r == 0 {
+				base.Stop()
+			} else {
+				base.Tank(l, r)
+			}
+		}
+	}
+
+	for i, b := range motors {
+		speed := int16(math.Round(b.curve.Apply(b.source()) * float64(b.maxSpeed)))
+		if speed == b.last {
+			continue
+		}
+		b.last = speed
+		recorder.record(RecordedCommand{Kind: CommandMotor, Index: i, Speed: speed})
+
+		if speed == 0 {
+			b.motor.Stop()
+		} else {
+			b.motor.Run(speed)
+		}
+	}
+
+	for i, a := range actions {
+		firing := a.trigger()
+		if firing && !a.was {
+			recorder.record(RecordedCommand{Kind: CommandAction, Index: i})
+			a.fn()
+		}
+		a.was = firing
+	}
+}
+
+// Steps every `interval` from the shared polling scheduler until a value is
+// sent to `stop`, then stops all motors.
+func (self *Mapping) Run(stop <-chan bool, interval time.Duration) {
+	// Keeps a step in progress as the task is cancelled from running the
+	// motors again after they were stopped.
+	var lock sync.Mutex
+	stopped := false
+
+	task := utilities.Poll(interval, func() {
+		lock.Lock()
+		defer lock.Unlock()
+
+		if !stopped {
+			self.Step()
+		}
+	})
+
+	<-stop
+	task.Cancel()
+
+	lock.Lock()
+	stopped = true
+	lock.Unlock()
+
+	self.stopAll()
+}
+
+func (self *Mapping) stopAll() {
+	self.lock.Lock()
+	base := self.base
+	motors := append(([]*motorBinding)(nil), self.motors...)
+	self.lastLeft, self.lastRight = 0, 0
+	self.lock.Unlock()
+
+	if base != nil {
+		base.Stop()
+	}
+
+	for _, b := range motors {
+		b.motor.Stop()
+		b.last = 0
+	}
+}
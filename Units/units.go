@@ -0,0 +1,204 @@
+// Provides typed distances, angles and rotational speeds with conversions
+// between common units.
+//
+// Values are stored in centimeters, degrees and degrees per second, the units
+// used throughout GoEV3, and can be expressed in any unit by multiplying with
+// or dividing by the unit constants:
+//
+//	d := 4 * Units.Inch
+//	fmt.Println(d.Centimeters(), d.In(Units.Millimeter))
+//
+// The preferred unit system chosen with Use determines how values are printed.
+package Units
+
+import (
+	"math"
+	"strconv"
+	"sync"
+)
+
+// A distance, stored in centimeters.
+type Distance float64
+
+const (
+	Millimeter Distance = 0.1
+	Centimeter Distance = 1
+	Meter      Distance = 100
+	Inch       Distance = 2.54
+	Foot       Distance = 30.48
+)
+
+// An angle, stored in degrees.
+type Angle float64
+
+const (
+	Degree     Angle = 1
+	Radian     Angle = 180 / math.Pi
+	Revolution Angle = 360
+)
+
+// A rotational speed, stored in degrees per second.
+type AngularSpeed float64
+
+const (
+	DegreePerSecond     AngularSpeed = 1
+	RadianPerSecond     AngularSpeed = 180 / math.Pi
+	RevolutionPerMinute AngularSpeed = 6
+	RevolutionPerSecond AngularSpeed = 360
+)
+
+// Returns the distance in centimeters.
+func (self Distance) Centimeters() float64 {
+	return float64(self)
+}
+
+// Returns the distance in millimeters.
+func (self Distance) Millimeters() float64 {
+	return self.In(Millimeter)
+}
+
+// Returns the distance in inches.
+func (self Distance) Inches() float64 {
+	return self.In(Inch)
+}
+
+// Returns the distance in meters.
+func (self Distance) Meters() float64 {
+	return self.In(Meter)
+}
+
+// Returns the distance as a multiple of `unit`.
+func (self Distance) In(unit Distance) float64 {
+	return float64(self / unit)
+}
+
+// Formats the distance in the unit of the preferred system.
+func (self Distance) String() string {
+	unit := Current().Distance
+	return format(self.In(unit), distanceSymbols[unit])
+}
+
+// Returns the angle in degrees.
+func (self Angle) Degrees() float64 {
+	return float64(self)
+}
+
+// Returns the angle in radians.
+func (self Angle) Radians() float64 {
+	return self.In(Radian)
+}
+
+// Returns the angle in full revolutions.
+func (self Angle) Revolutions() float64 {
+	return self.In(Revolution)
+}
+
+// Returns the angle as a multiple of `unit`.
+func (self Angle) In(unit Angle) float64 {
+	return float64(self / unit)
+}
+
+// Returns the equivalent angle in range (-180, 180].
+func (self Angle) Normalized() Angle {
+	a := Angle(math.Mod(float64(self), 360))
+	if a > 180 {
+		a -= 360
+	} else if a <= -180 {
+		a += 360
+	}
+
+	return a
+}
+
+// Formats the angle in the unit of the preferred system.
+func (self Angle) String() string {
+	unit := Current().Angle
+	return format(self.In(unit), angleSymbols[unit])
+}
+
+// Returns the speed in degrees per second.
+func (self AngularSpeed) DegreesPerSecond() float64 {
+	return float64(self)
+}
+
+// Returns the speed in radians per second.
+func (self AngularSpeed) RadiansPerSecond() float64 {
+	return self.In(RadianPerSecond)
+}
+
+// Returns the speed in revolutions per minute.
+func (self AngularSpeed) RPM() float64 {
+	return self.In(RevolutionPerMinute)
+}
+
+// Returns the speed as a multiple of `unit`.
+func (self AngularSpeed) In(unit AngularSpeed) float64 {
+	return float64(self / unit)
+}
+
+// Formats the speed in the unit of the preferred system.
+func (self AngularSpeed) String() string {
+	unit := Current().AngularSpeed
+	return format(self.In(unit), speedSymbols[unit])
+}
+
+// The units a program prefers to present values in.
+type System struct {
+	Distance     Distance
+	Angle        Angle
+	AngularSpeed AngularSpeed
+}
+
+// Centimeters, degrees and degrees per second.
+var Metric = System{Centimeter, Degree, DegreePerSecond}
+
+// Inches, degrees and revolutions per minute.
+var Imperial = System{Inch, Degree, RevolutionPerMinute}
+
+var gSystem = Metric
+var gSystemLock = &sync.RWMutex{}
+
+// Selects the preferred unit system. Metric is used by default.
+func Use(system System) {
+	gSystemLock.Lock()
+	gSystem = system
+	gSystemLock.Unlock()
+}
+
+// Returns the preferred unit system.
+func Current() System {
+	gSystemLock.RLock()
+	defer gSystemLock.RUnlock()
+
+	return gSystem
+}
+
+var distanceSymbols = map[Distance]string{
+	Millimeter: "mm",
+	Centimeter: "cm",
+	Meter:      "m",
+	Inch:       "in",
+	Foot:       "ft",
+}
+
+var angleSymbols = map[Angle]string{
+	Degree:     "°",
+	Radian:     "rad",
+	Revolution: "rev",
+}
+
+var speedSymbols = map[AngularSpeed]string{
+	DegreePerSecond:     "°/s",
+	RadianPerSecond:     "rad/s",
+	RevolutionPerMinute: "rpm",
+	RevolutionPerSecond: "rps",
+}
+
+func format(value float64, symbol string) string {
+	s := strconv.FormatFloat(math.Round(value*100)/100, 'f', -1, 64)
+	if symbol == "°" || symbol == "" {
+		return s + symbol
+	}
+
+	return s + " " + symbol
+}
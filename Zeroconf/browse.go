@@ -0,0 +1,135 @@
+package Zeroconf
+
+import (
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A service found by Browse.
+type Entry struct {
+	// Instance name, usually the robot's name.
+	Instance string
+	// Host name, e.g. "explorer.local".
+	Host string
+	// IPv4 addresses of the host.
+	Addrs []net.IP
+	Port  int
+	Text  map[string]string
+}
+
+// Returns the address to connect to the service, e.g. "192.168.1.12:8080".
+func (self Entry) Addr() string {
+	host := strings.TrimSuffix(self.Host, ".")
+	if len(self.Addrs) > 0 {
+		host = self.Addrs[0].String()
+	}
+
+	return net.JoinHostPort(host, strconv.Itoa(self.Port))
+}
+
+// Looks for instances of the service type `serviceType`, e.g. PeerType, on the
+// local network, collecting the answers that arrive within `timeout`.
+//
+//	entries, _ := Zeroconf.Browse(Zeroconf.PeerType, time.Second)
+//	for _, e := range entries {
+//		client, _ := Peer.Dial(e.Addr())
+//	}
+func Browse(serviceType string, timeout time.Duration) ([]Entry, error) {
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{})
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	name := serviceType + ".local."
+	query := &message{questions: []question{{name: name, qtype: typePTR, class: classIN}}}
+	if _, err := conn.WriteToUDP(query.pack(), mdnsAddr); err != nil {
+		return nil, err
+	}
+
+	var instances []string
+	srv := make(map[string]record)
+	txt := make(map[string]record)
+	addrs := make(map[string][]net.IP)
+
+	deadline := time.Now().Add(timeout)
+	buf := make([]byte, 9000)
+	for {
+		conn.SetReadDeadline(deadline)
+		n, _, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			break
+		}
+
+		reply, err := unpack(buf[:n])
+		if err != nil || reply.flags&0x8000 == 0 {
+			continue
+		}
+
+		for _, r := range append(reply.answers, reply.additional...) {
+			key := strings.ToLower(r.name)
+
+			switch r.rtype {
+			case typePTR:
+				if strings.EqualFold(r.name, name) && !contains(instances, r.target) {
+					instances = append(instances, r.target)
+				}
+			case typeSRV:
+				srv[key] = r
+			case typeTXT:
+				txt[key] = r
+			case typeA:
+				ip := net.IP(append([]byte(nil), r.addr[:]...))
+				if !containsIP(addrs[key], ip) {
+					addrs[key] = append(addrs[key], ip)
+				}
+			}
+		}
+	}
+
+	var entries []Entry
+	for _, instance := range instances {
+		s, ok := srv[strings.ToLower(instance)]
+		if !ok {
+			continue
+		}
+
+		e := Entry{
+			Instance: strings.TrimSuffix(instance, "."+name),
+			Host:     strings.TrimSuffix(s.target, "."),
+			Addrs:    addrs[strings.ToLower(s.target)],
+			Port:     int(s.port),
+			Text:     make(map[string]string),
+		}
+		for _, kv := range txt[strings.ToLower(instance)].text {
+			k, v, _ := strings.Cut(kv, "=")
+			e.Text[k] = v
+		}
+
+		entries = append(entries, e)
+	}
+
+	return entries, nil
+}
+
+func contains(names []string, name string) bool {
+	for _, n := range names {
+		if strings.EqualFold(n, name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func containsIP(ips []net.IP, ip net.IP) bool {
+	for _, i := range ips {
+		if i.Equal(ip) {
+			return true
+		}
+	}
+
+	return false
+}
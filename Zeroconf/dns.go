@@ -0,0 +1,265 @@
+package Zeroconf
+
+import (
+	"encoding/binary"
+	"errors"
+	"strings"
+)
+
+// Record types used by DNS-SD.
+const (
+	typeA   = 1
+	typePTR = 12
+	typeTXT = 16
+	typeSRV = 33
+	typeANY = 255
+)
+
+const (
+	classIN = 1
+	// Set on the class of unique records, telling caches to drop older ones.
+	classCacheFlush = 0x8000
+	// Set on the class of questions asking for a unicast reply.
+	classUnicast = 0x8000
+)
+
+// Flags of a response.
+const flagResponse = 0x8400
+
+var errMalformed = errors.New("zeroconf: malformed message")
+
+type question struct {
+	name  string
+	qtype uint16
+	class uint16
+}
+
+type record struct {
+	name  string
+	rtype uint16
+	class uint16
+	ttl   uint32
+	// Decoded data of the types used: the target of PTR records, the host of
+	// SRV records, the address of A records, the strings of TXT records.
+	target string
+	port   uint16
+	addr   [4]byte
+	text   []string
+}
+
+type message struct {
+	id         uint16
+	flags      uint16
+	questions  []question
+	answers    []record
+	additional []record
+}
+
+func appendName(b []byte, name string) []byte {
+	for _, label := range strings.Split(strings.TrimSuffix(name, "."), ".") {
+		if label == "" {
+			continue
+		}
+		if len(label) > 63 {
+			label = label[:63]
+		}
+		b = append(b, byte(len(label)))
+		b = append(b, label...)
+	}
+
+	return append(b, 0)
+}
+
+func appendRecord(b []byte, r record) []byte {
+	b = appendName(b, r.name)
+	b = binary.BigEndian.AppendUint16(b, r.rtype)
+	b = binary.BigEndian.AppendUint16(b, r.class)
+	b = binary.BigEndian.AppendUint32(b, r.ttl)
+
+	var data []byte
+	switch r.rtype {
+	case typePTR:
+		data = appendName(nil, r.target)
+	case typeSRV:
+		// Priority and weight, unused.
+		data = []byte{0, 0, 0, 0}
+		data = binary.BigEndian.AppendUint16(data, r.port)
+		data = appendName(data, r.target)
+	case typeA:
+		data = r.addr[:]
+	case typeTXT:
+		for _, s := range r.text {
+			if len(s) > 255 {
+				s = s[:255]
+			}
+			data = append(data, byte(len(s)))
+			data = append(data, s...)
+		}
+		if len(data) == 0 {
+			data = []byte{0}
+		}
+	}
+
+	b = binary.BigEndian.AppendUint16(b, uint16(len(data)))
+	return append(b, data...)
+}
+
+func (self *message) pack() []byte {
+	b := binary.BigEndian.AppendUint16(nil, self.id)
+	b = binary.BigEndian.AppendUint16(b, self.flags)
+	b = binary.BigEndian.AppendUint16(b, uint16(len(self.questions)))
+	b = binary.BigEndian.AppendUint16(b, uint16(len(self.answers)))
+	b = binary.BigEndian.AppendUint16(b, 0)
+	b = binary.BigEndian.AppendUint16(b, uint16(len(self.additional)))
+
+	for _, q := range self.questions {
+		b = appendName(b, q.name)
+		b = binary.BigEndian.AppendUint16(b, q.qtype)
+		b = binary.BigEndian.AppendUint16(b, q.class)
+	}
+	for _, r := range self.answers {
+		b = appendRecord(b, r)
+	}
+	for _, r := range self.additional {
+		b = appendRecord(b, r)
+	}
+
+	return b
+}
+
+// Reads a possibly compressed name at `offset`, returning it with a trailing
+// dot and the offset following it.
+func readName(b []byte, offset int) (string, int, error) {
+	var labels []string
+	end := -1
+
+	for jumps := 0; ; {
+		if offset >= len(b) {
+			return "", 0, errMalformed
+		}
+
+		n := int(b[offset])
+		switch {
+		case n == 0:
+			if end < 0 {
+				end = offset + 1
+			}
+			return strings.Join(labels, ".") + ".", end, nil
+
+		case n&0xC0 == 0xC0:
+			if offset+1 >= len(b) || jumps > 32 {
+				return "", 0, errMalformed
+			}
+			if end < 0 {
+				end = offset + 2
+			}
+			offset = int(binary.BigEndian.Uint16(b[offset:]) & 0x3FFF)
+			jumps++
+
+		default:
+			if offset+1+n > len(b) {
+				return "", 0, errMalformed
+			}
+			labels = append(labels, string(b[offset+1:offset+1+n]))
+			offset += 1 + n
+		}
+	}
+}
+
+func readRecord(b []byte, offset int) (record, int, error) {
+	var r record
+
+	name, offset, err := readName(b, offset)
+	if err != nil {
+		return r, 0, err
+	}
+	if offset+10 > len(b) {
+		return r, 0, errMalformed
+	}
+
+	r.name = name
+	r.rtype = binary.BigEndian.Uint16(b[offset:])
+	r.class = binary.BigEndian.Uint16(b[offset+2:])
+	r.ttl = binary.BigEndian.Uint32(b[offset+4:])
+	length := int(binary.BigEndian.Uint16(b[offset+8:]))
+	offset += 10
+
+	if offset+length > len(b) {
+		return r, 0, errMalformed
+	}
+	data := b[offset : offset+length]
+
+	switch r.rtype {
+	case typePTR:
+		r.target, _, err = readName(b, offset)
+	case typeSRV:
+		if length < 7 {
+			return r, 0, errMalformed
+		}
+		r.port = binary.BigEndian.Uint16(data[4:])
+		r.target, _, err = readName(b, offset+6)
+	case typeA:
+		if length == 4 {
+			copy(r.addr[:], data)
+		}
+	case typeTXT:
+		for i := 0; i < len(data); {
+			n := int(data[i])
+			if i+1+n > len(data) {
+				break
+			}
+			if n > 0 {
+				r.text = append(r.text, string(data[i+1:i+1+n]))
+			}
+			i += 1 + n
+		}
+	}
+
+	return r, offset + length, err
+}
+
+func unpack(b []byte) (*message, error) {
+	if len(b) < 12 {
+		return nil, errMalformed
+	}
+
+	m := new(message)
+	m.id = binary.BigEndian.Uint16(b)
+	m.flags = binary.BigEndian.Uint16(b[2:])
+	counts := []int{
+		int(binary.BigEndian.Uint16(b[4:])),
+		int(binary.BigEndian.Uint16(b[6:])),
+		int(binary.BigEndian.Uint16(b[8:])),
+		int(binary.BigEndian.Uint16(b[10:])),
+	}
+
+	offset := 12
+	for i := 0; i < counts[0]; i++ {
+		name, next, err := readName(b, offset)
+		if err != nil || next+4 > len(b) {
+			return nil, errMalformed
+		}
+		m.questions = append(m.questions, question{
+			name:  name,
+			qtype: binary.BigEndian.Uint16(b[next:]),
+			class: binary.BigEndian.Uint16(b[next+2:]),
+		})
+		offset = next + 4
+	}
+
+	// Authority records are read with the additional ones.
+	for i := 0; i < counts[1]+counts[2]+counts[3]; i++ {
+		r, next, err := readRecord(b, offset)
+		if err != nil {
+			return nil, err
+		}
+		if i < counts[1] {
+			m.answers = append(m.answers, r)
+		} else {
+			m.additional = append(m.additional, r)
+		}
+		offset = next
+	}
+
+	return m, nil
+}
@@ -0,0 +1,314 @@
+// Advertises a robot's network services on the local network with multicast
+// DNS service discovery (mDNS/DNS-SD, also known as Bonjour or zeroconf), so
+// that dashboards and controllers find robots by name instead of by address:
+//
+//	adv := Zeroconf.NewAdvertiser(config.Name)
+//	adv.Add(Zeroconf.Service{Type: Zeroconf.HTTPType, Port: 8080, Text: map[string]string{"path": "/"}})
+//	adv.Add(Zeroconf.Service{Type: Zeroconf.PeerType, Port: 4747})
+//	go adv.Run(stop)
+//	go dashboard.ListenAndServe(":8080")
+//
+// The robot then answers as "explorer.local", and its dashboard shows up in
+// any DNS-SD browser. Other programs find robots with Browse.
+package Zeroconf
+
+import (
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Service types of the servers of the library.
+const (
+	// Web servers, such as the dashboard and the metrics endpoint.
+	HTTPType = "_http._tcp"
+	// Peer servers.
+	PeerType = "_goev3-peer._tcp"
+	// DirectCommand servers listening on Wi-Fi.
+	DirectCommandType = "_ev3-direct._tcp"
+)
+
+// Time to live of host and service records, and of pointer records, in seconds.
+const (
+	hostTTL    = 120
+	pointerTTL = 4500
+)
+
+var mdnsAddr = &net.UDPAddr{IP: net.IPv4(224, 0, 0, 251), Port: 5353}
+
+const servicesName = "_services._dns-sd._udp.local."
+
+// A service to advertise.
+type Service struct {
+	// Service type, e.g. HTTPType.
+	Type string
+	// Port the service listens on.
+	Port int
+	// Name of this instance of the service. The advertiser's name is used if empty.
+	Instance string
+	// Key-value pairs describing the service, e.g. a path.
+	Text map[string]string
+}
+
+// Returns the port of a listening address such as ":8080", or 0.
+func PortOf(addr string) int {
+	_, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return 0
+	}
+
+	n, _ := strconv.Atoi(port)
+	return n
+}
+
+// Answers mDNS queries for a robot's host name and services.
+type Advertiser struct {
+	name string
+	host string
+
+	lock     sync.Mutex
+	services []Service
+}
+
+// Creates an advertiser for the robot `name`, which is also used for its
+// host name, "name.local", once lowercased and stripped of characters host
+// names cannot have.
+func NewAdvertiser(name string) *Advertiser {
+	a := new(Advertiser)
+	a.name = name
+	a.host = hostLabel(name) + ".local."
+
+	return a
+}
+
+func hostLabel(name string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(name) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			b.WriteRune(r)
+		case b.Len() > 0 && !strings.HasSuffix(b.String(), "-"):
+			b.WriteByte('-')
+		}
+	}
+
+	label := strings.TrimSuffix(b.String(), "-")
+	if label == "" {
+		return "ev3dev"
+	}
+
+	return label
+}
+
+// Returns the host name the robot answers to, e.g. "explorer.local".
+func (self *Advertiser) Host() string {
+	return strings.TrimSuffix(self.host, ".")
+}
+
+// Adds a service to advertise. Services added while running are announced
+// when next asked for.
+func (self *Advertiser) Add(service Service) {
+	if service.Instance == "" {
+		service.Instance = self.name
+	}
+
+	self.lock.Lock()
+	self.services = append(self.services, service)
+	self.lock.Unlock()
+}
+
+func serviceName(s Service) string {
+	return s.Type + ".local."
+}
+
+func instanceName(s Service) string {
+	return s.Instance + "." + serviceName(s)
+}
+
+func (self *Advertiser) addresses() []record {
+	var records []record
+
+	addrs, _ := net.InterfaceAddrs()
+	for _, addr := range addrs {
+		ipnet, ok := addr.(*net.IPNet)
+		if !ok || ipnet.IP.IsLoopback() {
+			continue
+		}
+
+		if ip4 := ipnet.IP.To4(); ip4 != nil {
+			r := record{name: self.host, rtype: typeA, class: classIN | classCacheFlush, ttl: hostTTL}
+			copy(r.addr[:], ip4)
+			records = append(records, r)
+		}
+	}
+
+	return records
+}
+
+func serviceRecords(s Service, host string) []record {
+	keys := make([]string, 0, len(s.Text))
+	for k := range s.Text {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	text := make([]string, len(keys))
+	for i, k := range keys {
+		text[i] = k + "=" + s.Text[k]
+	}
+
+	return []record{
+		{name: instanceName(s), rtype: typeSRV, class: classIN | classCacheFlush, ttl: hostTTL,
+			target: host, port: uint16(s.Port)},
+		{name: instanceName(s), rtype: typeTXT, class: classIN | classCacheFlush, ttl: pointerTTL,
+			text: text},
+	}
+}
+
+func pointerRecord(s Service) record {
+	return record{name: serviceName(s), rtype: typePTR, class: classIN, ttl: pointerTTL,
+		target: instanceName(s)}
+}
+
+// Returns the records answering `q`, and additional ones the asker will
+// probably need next.
+func (self *Advertiser) answer(q question) ([]record, []record) {
+	self.lock.Lock()
+	services := append([]Service(nil), self.services...)
+	self.lock.Unlock()
+
+	var answers, additional []record
+	matches := func(name string, rtype uint16) bool {
+		return strings.EqualFold(q.name, name) && (q.qtype == rtype || q.qtype == typeANY)
+	}
+
+	types := make(map[string]bool)
+	for _, s := range services {
+		if matches(servicesName, typePTR) && !types[s.Type] {
+			types[s.Type] = true
+			answers = append(answers, record{name: servicesName, rtype: typePTR, class: classIN,
+				ttl: pointerTTL, target: serviceName(s)})
+		}
+
+		if matches(serviceName(s), typePTR) {
+			answers = append(answers, pointerRecord(s))
+			additional = append(additional, serviceRecords(s, self.host)...)
+			additional = append(additional, self.addresses()...)
+		}
+
+		records := serviceRecords(s, self.host)
+		if matches(instanceName(s), typeSRV) {
+			answers = append(answers, records[0])
+			additional = append(additional, self.addresses()...)
+		}
+		if matches(instanceName(s), typeTXT) {
+			answers = append(answers, records[1])
+		}
+	}
+
+	if matches(self.host, typeA) {
+		answers = append(answers, self.addresses()...)
+	}
+
+	return answers, additional
+}
+
+// Every record, with the given time to live, for announcements and goodbyes.
+func (self *Advertiser) all(ttl uint32) []record {
+	self.lock.Lock()
+	services := append([]Service(nil), self.services...)
+	self.lock.Unlock()
+
+	records := self.addresses()
+	for _, s := range services {
+		records = append(records, pointerRecord(s))
+		records = append(records, serviceRecords(s, self.host)...)
+	}
+
+	for i := range records {
+		records[i].ttl = ttl
+	}
+
+	return records
+}
+
+// Announces the services and answers queries until a value is sent to `stop`,
+// when it announces that they are gone. Returns early only if the multicast
+// group cannot be joined.
+func (self *Advertiser) Run(stop <-chan bool) error {
+	conn, err := net.ListenMulticastUDP("udp4", nil, mdnsAddr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	announce := func(ttl uint32) {
+		m := &message{flags: flagResponse, answers: self.all(ttl)}
+		conn.WriteToUDP(m.pack(), mdnsAddr)
+	}
+
+	announce(hostTTL)
+	// Announced again a second later, in case the first one was lost.
+	again := time.After(time.Second)
+
+	buf := make([]byte, 9000)
+	for {
+		select {
+		case <-stop:
+			announce(0)
+			return nil
+		case <-again:
+			announce(hostTTL)
+			again = nil
+		default:
+		}
+
+		conn.SetReadDeadline(time.Now().Add(250 * time.Millisecond))
+		n, from, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			continue
+		}
+
+		query, err := unpack(buf[:n])
+		if err != nil || query.flags&0x8000 != 0 {
+			continue
+		}
+
+		self.respond(conn, query, from)
+	}
+}
+
+func (self *Advertiser) respond(conn *net.UDPConn, query *message, from *net.UDPAddr) {
+	reply := &message{flags: flagResponse}
+	unicast := from.Port != mdnsAddr.Port
+
+	for _, q := range query.questions {
+		answers, additional := self.answer(q)
+		reply.answers = append(reply.answers, answers...)
+		reply.additional = append(reply.additional, additional...)
+
+		if q.class&classUnicast != 0 {
+			unicast = true
+		}
+	}
+
+	if len(reply.answers) == 0 {
+		return
+	}
+
+	to := mdnsAddr
+	if unicast {
+		to = from
+	}
+	if from.Port != mdnsAddr.Port {
+		// Simple resolvers sending from another port expect a classic DNS
+		// reply, echoing the query.
+		reply.id = query.id
+		reply.questions = query.questions
+	}
+
+	conn.WriteToUDP(reply.pack(), to)
+}
@@ -0,0 +1,143 @@
+// Command ev3bench measures the latency of reading and writing device
+// attributes with each of the utilities I/O strategies, to pick the defaults
+// and to check the effect of a kernel or library change on a brick.
+//
+// Usage:
+//
+//	ev3bench [-n count] [-sensor port] [-motor port]
+//
+// It reads `value0` of the sensor with every strategy, all its values from
+// `bin_data`, the `position` of the motor and writes its `speed_sp`, printing
+// the mean, median and 99th percentile latencies of `count` accesses each.
+// The motor is not started. Ports are named as on the brick; the first
+// connected sensor and motor are used by default.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"time"
+
+	"github.com/jermon/GoEV3/Motor"
+	"github.com/jermon/GoEV3/Platform"
+	"github.com/jermon/GoEV3/Sensors"
+	"github.com/jermon/GoEV3/utilities"
+)
+
+const (
+	motorClassPath  = "/sys/class/tacho-motor"
+	sensorClassPath = "/sys/class/lego-sensor"
+)
+
+var strategies = []struct {
+	name     string
+	strategy utilities.IOStrategy
+}{
+	{"open per call", utilities.OpenPerCall},
+	{"cached files", utilities.CachedFiles},
+}
+
+func main() {
+	count := flag.Int("n", 1000, "accesses per measurement")
+	sensorPort := flag.String("sensor", "", "port of the sensor to read")
+	motorPort := flag.String("motor", "", "port of the motor to read and write")
+	flag.Parse()
+
+	if *sensorPort != "" {
+		port, err := Sensors.ParseInPort(*sensorPort)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "ev3bench:", err)
+			os.Exit(2)
+		}
+		*sensorPort = string(port)
+	}
+	if *motorPort != "" {
+		port, err := Motor.ParseOutPort(*motorPort)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "ev3bench:", err)
+			os.Exit(2)
+		}
+		*motorPort = string(port)
+	}
+
+	sensor := findDevice(sensorClassPath, *sensorPort)
+	motor := findDevice(motorClassPath, *motorPort)
+	if sensor == "" && motor == "" {
+		fmt.Fprintln(os.Stderr, "ev3bench: no sensor or motor connected")
+		os.Exit(1)
+	}
+
+	fmt.Printf("%-36s %10s %10s %10s\n", "ACCESS", "MEAN", "P50", "P99")
+
+	for _, s := range strategies {
+		utilities.SetIOStrategy(s.strategy)
+
+		if sensor != "" {
+			measure(s.name+": read value0", *count, func() error {
+				_, err := utilities.ReadValue[int](sensor, "value0")
+				return err
+			})
+			measure(s.name+": read bin_data", *count, func() error {
+				_, err := utilities.ReadBinValues(sensor)
+				return err
+			})
+		}
+
+		if motor != "" {
+			measure(s.name+": read position", *count, func() error {
+				_, err := utilities.ReadValue[int](motor, "position")
+				return err
+			})
+			measure(s.name+": write speed_sp", *count, func() error {
+				return utilities.WriteValue(motor, "speed_sp", 0)
+			})
+		}
+	}
+
+	utilities.SetIOStrategy(utilities.CachedFiles)
+}
+
+// Times `count` calls of `fn` and prints the statistics.
+func measure(name string, count int, fn func() error) {
+	latencies := make([]time.Duration, 0, count)
+	var total time.Duration
+
+	for i := 0; i < count; i++ {
+		start := time.Now()
+		if err := fn(); err != nil {
+			fmt.Printf("%-36s %v\n", name, err)
+			return
+		}
+		d := time.Since(start)
+
+		latencies = append(latencies, d)
+		total += d
+	}
+
+	if count <= 0 {
+		return
+	}
+
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+
+	fmt.Printf("%-36s %10v %10v %10v\n", name,
+		(total / time.Duration(count)).Round(time.Microsecond/10),
+		latencies[count/2].Round(time.Microsecond/10),
+		latencies[count*99/100].Round(time.Microsecond/10))
+}
+
+// Returns the folder of the device of the given class connected to `port`,
+// or of the first one if `port` is empty, or an empty string if there is none.
+func findDevice(class string, port string) string {
+	for _, name := range utilities.ListDir(class) {
+		folder := path.Join(class, name)
+		if port == "" || Platform.Current().Matches(port, utilities.ReadStringValue(folder, "address")) {
+			return folder
+		}
+	}
+
+	return ""
+}
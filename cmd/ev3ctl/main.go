@@ -0,0 +1,407 @@
+// Command ev3ctl inspects and drives the devices connected to an EV3, for
+// checking wiring and debugging programs.
+//
+// Usage:
+//
+//	ev3ctl list                          lists the connected motors and sensors
+//	ev3ctl read <port> [mode]            prints the values of a sensor, optionally switching its mode
+//	ev3ctl tail [-interval d] <port> [mode]
+//	                                     prints the values of a sensor or motor until interrupted
+//	ev3ctl run <port> <speed> [duration] runs a motor, until interrupted if no duration is given
+//	ev3ctl motor <port>                  drives a motor from the keyboard
+//	ev3ctl battery                       prints the battery status
+//	ev3ctl repl                          reads commands interactively, see its help command
+//
+// Ports are named as on the brick: 1 to 4 (or in1 to in4) for sensors and
+// A to D (or outA to outD) for motors.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jermon/GoEV3/Input"
+	"github.com/jermon/GoEV3/Motor"
+	"github.com/jermon/GoEV3/Platform"
+	"github.com/jermon/GoEV3/Safety"
+	"github.com/jermon/GoEV3/Sensors"
+	"github.com/jermon/GoEV3/utilities"
+)
+
+const (
+	motorClassPath  = "/sys/class/tacho-motor"
+	sensorClassPath = "/sys/class/lego-sensor"
+	batteryPath     = "/sys/class/power_supply/legoev3-battery"
+)
+
+const usage = `Usage:
+  ev3ctl list
+  ev3ctl read <port> [mode]
+  ev3ctl tail [-interval d] <port> [mode]
+  ev3ctl run <port> <speed> [duration]
+  ev3ctl motor <port>
+  ev3ctl battery
+  ev3ctl repl
+`
+
+func main() {
+	Safety.HandleSignals()
+	defer Safety.Shutdown()
+	defer Safety.Recover()
+
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	args := os.Args[2:]
+
+	var err error
+	switch os.Args[1] {
+	case "list":
+		err = list()
+	case "read":
+		err = read(args)
+	case "tail":
+		err = tail(args)
+	case "run":
+		err = run(args)
+	case "motor":
+		err = interactive(args)
+	case "battery":
+		err = battery()
+	case "repl":
+		err = interpret(args)
+	case "help", "-h", "-help", "--help":
+		fmt.Print(usage)
+	default:
+		err = fmt.Errorf("unknown command %q\n%s", os.Args[1], usage)
+	}
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "ev3ctl:", err)
+		Safety.Shutdown()
+		os.Exit(1)
+	}
+}
+
+func list() error {
+	fmt.Printf("%-6s %-8s %-20s %s\n", "PORT", "DEVICE", "DRIVER", "MODE")
+
+	for _, folder := range devices(motorClassPath) {
+		address := utilities.ReadStringValue(folder, "address")
+		fmt.Printf("%-6s %-8s %-20s %s\n",
+			Platform.Current().PortName(address), path.Base(folder),
+			utilities.ReadStringValue(folder, "driver_name"), "-")
+	}
+
+	for _, folder := range devices(sensorClassPath) {
+		address := utilities.ReadStringValue(folder, "address")
+		fmt.Printf("%-6s %-8s %-20s %s\n",
+			Platform.Current().PortName(address), path.Base(folder),
+			utilities.ReadStringValue(folder, "driver_name"),
+			utilities.ReadStringValue(folder, "mode"))
+	}
+
+	return nil
+}
+
+func read(args []string) error {
+	if len(args) < 1 || len(args) > 2 {
+		return fmt.Errorf("read takes a port and an optional mode")
+	}
+
+	folder, err := openSensor(args)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(sensorValues(folder))
+	return nil
+}
+
+func tail(args []string) error {
+	flags := flag.NewFlagSet("tail", flag.ContinueOnError)
+	interval := flags.Duration("interval", 200*time.Millisecond, "time between readings")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	args = flags.Args()
+
+	if len(args) < 1 || len(args) > 2 {
+		return fmt.Errorf("tail takes a port and an optional mode")
+	}
+
+	var sample func() string
+
+	if port, err := Motor.ParseOutPort(args[0]); err == nil && len(args) == 1 {
+		folder := findDevice(motorClassPath, "out"+string(port))
+		if folder == "" {
+			return fmt.Errorf("no motor is connected to port %s", port)
+		}
+
+		sample = func() string {
+			return fmt.Sprintf("position=%s speed=%s state=%s",
+				utilities.ReadStringValue(folder, "position"),
+				utilities.ReadStringValue(folder, "speed"),
+				utilities.ReadStringValue(folder, "state"))
+		}
+	} else {
+		folder, err := openSensor(args)
+		if err != nil {
+			return err
+		}
+		sample = func() string {
+			return sensorValues(folder)
+		}
+	}
+
+	start := time.Now()
+	for {
+		fmt.Printf("%8.3f  %s\n", time.Since(start).Seconds(), sample())
+		time.Sleep(*interval)
+	}
+}
+
+func run(args []string) error {
+	if len(args) < 2 || len(args) > 3 {
+		return fmt.Errorf("run takes a port, a speed and an optional duration")
+	}
+
+	speed, err := strconv.ParseInt(args[1], 10, 16)
+	if err != nil || speed < -100 || speed > 100 {
+		return fmt.Errorf("invalid speed %q, expected -100 to 100", args[1])
+	}
+
+	m, err := openMotor(args[0])
+	if err != nil {
+		return err
+	}
+
+	m.Run(int16(speed))
+
+	if len(args) == 2 {
+		fmt.Println("Running, press Ctrl-C to stop")
+		select {}
+	}
+
+	duration, err := time.ParseDuration(args[2])
+	if err != nil {
+		m.Stop()
+		return err
+	}
+
+	time.Sleep(duration)
+	m.Stop()
+
+	fmt.Println("position", m.CurrentPosition())
+	return nil
+}
+
+func interactive(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("motor takes a port")
+	}
+
+	m, err := openMotor(args[0])
+	if err != nil {
+		return err
+	}
+
+	keyboard, err := Input.OpenKeyboard()
+	if err != nil {
+		return err
+	}
+	defer keyboard.Close()
+	Safety.OnShutdown(Safety.CloseFiles, keyboard.Close)
+
+	fmt.Print("Up/down: change speed, space: stop, b: toggle brake, r: reset position, q: quit\r\n")
+
+	var lock sync.Mutex
+	var speed int16
+	brake := false
+	quit := make(chan bool)
+
+	keyboard.OnKey(func(key Input.Key, pressed bool) {
+		if !pressed {
+			return
+		}
+
+		lock.Lock()
+		defer lock.Unlock()
+
+		switch key {
+		case Input.KeyUp, 'w', '+':
+			speed = clamp(speed + 10)
+		case Input.KeyDown, 's', '-':
+			speed = clamp(speed - 10)
+		case Input.KeySpace, '0':
+			speed = 0
+		case 'b':
+			brake = !brake
+			if brake {
+				m.EnableBrakeMode()
+			} else {
+				m.DisableBrakeMode()
+			}
+		case 'r':
+			m.InitializePosition(0)
+		case 'q', Input.KeyEscape:
+			select {
+			case <-quit:
+			default:
+				close(quit)
+			}
+			return
+		}
+
+		if speed == 0 {
+			m.Stop()
+		} else {
+			m.Run(speed)
+		}
+	})
+
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-quit:
+			m.Stop()
+			fmt.Print("\r\n")
+			return nil
+		case <-ticker.C:
+			lock.Lock()
+			fmt.Printf("\rspeed %4d  brake %-5v  position %7d  actual %5d  ",
+				speed, brake, m.CurrentPosition(), m.CurrentSpeed())
+			lock.Unlock()
+		}
+	}
+}
+
+func clamp(speed int16) int16 {
+	if speed > 100 {
+		return 100
+	}
+	if speed < -100 {
+		return -100
+	}
+
+	return speed
+}
+
+func battery() error {
+	if !utilities.Exists(batteryPath) {
+		return fmt.Errorf("no battery information at %s", batteryPath)
+	}
+
+	voltage, err := utilities.ReadFloatValue(batteryPath, "voltage_now", 1e-6)
+	if err != nil {
+		return err
+	}
+	current, err := utilities.ReadFloatValue(batteryPath, "current_now", 1e-6)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("voltage  %.2f V\n", voltage)
+	fmt.Printf("current  %.0f mA\n", current*1000)
+	fmt.Printf("power    %.2f W\n", voltage*current)
+	if technology := utilities.ReadStringValue(batteryPath, "technology"); technology != "" {
+		fmt.Printf("type     %s\n", technology)
+	}
+
+	return nil
+}
+
+func devices(class string) []string {
+	var folders []string
+
+	for _, name := range utilities.ListDir(class) {
+		folders = append(folders, path.Join(class, name))
+	}
+
+	return folders
+}
+
+// Returns the folder of the device of the given class connected to `port`, or
+// an empty string if there is none.
+func findDevice(class string, port string) string {
+	for _, folder := range devices(class) {
+		if Platform.Current().Matches(port, utilities.ReadStringValue(folder, "address")) {
+			return folder
+		}
+	}
+
+	return ""
+}
+
+func openSensor(args []string) (string, error) {
+	port, err := Sensors.ParseInPort(args[0])
+	if err != nil {
+		return "", err
+	}
+
+	folder := findDevice(sensorClassPath, string(port))
+	if folder == "" {
+		return "", fmt.Errorf("no sensor is connected to port %s", args[0])
+	}
+
+	if len(args) > 1 {
+		mode := strings.ToUpper(args[1])
+		modes := strings.Fields(utilities.ReadStringValue(folder, "modes"))
+		if !contains(modes, mode) {
+			return "", fmt.Errorf("%s does not support mode %s, expected one of %s",
+				utilities.ReadStringValue(folder, "driver_name"), mode, strings.Join(modes, " "))
+		}
+		utilities.WriteStringValue(folder, "mode", mode)
+	}
+
+	return folder, nil
+}
+
+func sensorValues(folder string) string {
+	count, _ := utilities.ReadValue[int](folder, "num_values")
+	decimals, _ := utilities.ReadValue[int](folder, "decimals")
+
+	values := make([]string, count)
+	for i := range values {
+		value, _ := utilities.ReadValue[float64](folder, fmt.Sprintf("value%d", i))
+		for d := 0; d < decimals; d++ {
+			value /= 10
+		}
+		values[i] = strconv.FormatFloat(value, 'f', decimals, 64)
+	}
+
+	return fmt.Sprintf("%s %s", utilities.ReadStringValue(folder, "mode"), strings.Join(values, " "))
+}
+
+func openMotor(port string) (*Motor.Motor, error) {
+	p, err := Motor.ParseOutPort(port)
+	if err != nil {
+		return nil, err
+	}
+
+	if findDevice(motorClassPath, "out"+string(p)) == "" {
+		return nil, fmt.Errorf("no motor is connected to port %s", port)
+	}
+
+	return Motor.FindMotor(p), nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
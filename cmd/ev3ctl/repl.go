@@ -0,0 +1,320 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jermon/GoEV3/Motor"
+	"github.com/jermon/GoEV3/Safety"
+	"github.com/jermon/GoEV3/Sensors"
+	"github.com/jermon/GoEV3/utilities"
+)
+
+const replHelp = `Commands:
+  list                        lists the connected motors and sensors
+  read <port> [mode]          prints the values of a sensor, optionally switching its mode
+  modes <port>                lists the modes of a sensor
+  run <port> <speed> [duration]
+                              runs a motor, in the background if no duration is given
+  stop [port]                 stops a motor, or all motors
+  position <port>             prints the position and speed of a motor
+  reset <port>                resets the position of a motor to 0
+  brake <port> on|off         sets whether a motor brakes when stopped
+  get <port> [attribute]      prints a raw sysfs attribute of a device, or lists them
+  set <port> <attribute> <value>
+                              writes a raw sysfs attribute of a device
+  battery                     prints the battery status
+  help                        prints this help
+  quit                        stops all motors and exits
+`
+
+// An interactive session reading commands line by line.
+type repl struct {
+	out    io.Writer
+	motors map[string]*Motor.Motor
+}
+
+// Reads commands from standard input until it ends or `quit` is entered. Works
+// over SSH or a serial console, as it doesn't need a terminal.
+func interpret(args []string) error {
+	if len(args) != 0 {
+		return fmt.Errorf("repl takes no arguments")
+	}
+
+	r := &repl{out: os.Stdout, motors: make(map[string]*Motor.Motor)}
+	Safety.OnShutdown(Safety.StopTasks, r.stopAll)
+	defer r.stopAll()
+
+	fmt.Fprint(r.out, "Type help for a list of commands\n")
+
+	scanner := bufio.NewScanner(os.Stdin)
+	for {
+		fmt.Fprint(r.out, "ev3> ")
+		if !scanner.Scan() {
+			fmt.Fprintln(r.out)
+			return scanner.Err()
+		}
+
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if fields[0] == "quit" || fields[0] == "exit" {
+			return nil
+		}
+
+		if err := r.execute(fields[0], fields[1:]); err != nil {
+			fmt.Fprintln(r.out, "error:", err)
+		}
+	}
+}
+
+func (self *repl) execute(command string, args []string) error {
+	switch command {
+	case "list":
+		return list()
+	case "read":
+		return read(args)
+	case "modes":
+		return self.modes(args)
+	case "run":
+		return self.run(args)
+	case "stop":
+		return self.stop(args)
+	case "position":
+		return self.position(args)
+	case "reset":
+		return self.reset(args)
+	case "brake":
+		return self.brake(args)
+	case "get":
+		return self.get(args)
+	case "set":
+		return self.set(args)
+	case "battery":
+		return battery()
+	case "help":
+		fmt.Fprint(self.out, replHelp)
+		return nil
+	}
+
+	return fmt.Errorf("unknown command %q, type help for a list of commands", command)
+}
+
+// Returns the motor on `port`, opening it on first use.
+func (self *repl) motor(port string) (*Motor.Motor, error) {
+	p, err := Motor.ParseOutPort(port)
+	if err != nil {
+		return nil, err
+	}
+
+	if m, ok := self.motors[string(p)]; ok {
+		return m, nil
+	}
+
+	m, err := openMotor(port)
+	if err != nil {
+		return nil, err
+	}
+	self.motors[string(p)] = m
+
+	return m, nil
+}
+
+func (self *repl) modes(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("modes takes a port")
+	}
+
+	folder, err := openSensor(args)
+	if err != nil {
+		return err
+	}
+
+	current := utilities.ReadStringValue(folder, "mode")
+	for _, mode := range strings.Fields(utilities.ReadStringValue(folder, "modes")) {
+		marker := " "
+		if mode == current {
+			marker = "*"
+		}
+		fmt.Fprintf(self.out, "%s %s\n", marker, mode)
+	}
+
+	return nil
+}
+
+func (self *repl) run(args []string) error {
+	if len(args) < 2 || len(args) > 3 {
+		return fmt.Errorf("run takes a port, a speed and an optional duration")
+	}
+
+	speed, err := strconv.ParseInt(args[1], 10, 16)
+	if err != nil {
+		return fmt.Errorf("invalid speed %q", args[1])
+	}
+
+	var duration time.Duration
+	if len(args) == 3 {
+		if duration, err = time.ParseDuration(args[2]); err != nil {
+			return err
+		}
+	}
+
+	m, err := self.motor(args[0])
+	if err != nil {
+		return err
+	}
+
+	if err := m.TryRun(int16(speed)); err != nil {
+		return err
+	}
+
+	if len(args) == 3 {
+		time.Sleep(duration)
+		m.Stop()
+		fmt.Fprintln(self.out, "position", m.CurrentPosition())
+	}
+
+	return nil
+}
+
+func (self *repl) stop(args []string) error {
+	switch len(args) {
+	case 0:
+		self.stopAll()
+		return nil
+	case 1:
+		m, err := self.motor(args[0])
+		if err != nil {
+			return err
+		}
+		m.Stop()
+		return nil
+	}
+
+	return fmt.Errorf("stop takes an optional port")
+}
+
+func (self *repl) stopAll() {
+	for _, m := range self.motors {
+		m.Stop()
+	}
+}
+
+func (self *repl) position(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("position takes a port")
+	}
+
+	m, err := self.motor(args[0])
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(self.out, "position %d (%.1f°)  speed %d  state %s\n",
+		m.CurrentPosition(), m.CurrentDegrees(), m.CurrentSpeed(), m.GetState())
+	return nil
+}
+
+func (self *repl) reset(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("reset takes a port")
+	}
+
+	m, err := self.motor(args[0])
+	if err != nil {
+		return err
+	}
+
+	m.InitializePosition(0)
+	return nil
+}
+
+func (self *repl) brake(args []string) error {
+	if len(args) != 2 || (args[1] != "on" && args[1] != "off") {
+		return fmt.Errorf("brake takes a port and on or off")
+	}
+
+	m, err := self.motor(args[0])
+	if err != nil {
+		return err
+	}
+
+	if args[1] == "on" {
+		m.EnableBrakeMode()
+	} else {
+		m.DisableBrakeMode()
+	}
+
+	return nil
+}
+
+// Returns the sysfs folder of the motor or sensor connected to `port`.
+func deviceFolder(port string) (string, error) {
+	if p, err := Motor.ParseOutPort(port); err == nil {
+		if folder := findDevice(motorClassPath, "out"+string(p)); folder != "" {
+			return folder, nil
+		}
+		return "", fmt.Errorf("no motor is connected to port %s", port)
+	}
+
+	p, err := Sensors.ParseInPort(port)
+	if err != nil {
+		return "", err
+	}
+	if folder := findDevice(sensorClassPath, string(p)); folder != "" {
+		return folder, nil
+	}
+
+	return "", fmt.Errorf("no sensor is connected to port %s", port)
+}
+
+func (self *repl) get(args []string) error {
+	if len(args) == 1 {
+		folder, err := deviceFolder(args[0])
+		if err != nil {
+			return err
+		}
+
+		attributes := utilities.ListDir(folder)
+		sort.Strings(attributes)
+		fmt.Fprintln(self.out, strings.Join(attributes, " "))
+		return nil
+	}
+
+	if len(args) != 2 {
+		return fmt.Errorf("get takes a port and an attribute")
+	}
+
+	folder, err := deviceFolder(args[0])
+	if err != nil {
+		return err
+	}
+
+	value, err := utilities.ReadValue[string](folder, args[1])
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(self.out, value)
+	return nil
+}
+
+func (self *repl) set(args []string) error {
+	if len(args) < 3 {
+		return fmt.Errorf("set takes a port, an attribute and a value")
+	}
+
+	folder, err := deviceFolder(args[0])
+	if err != nil {
+		return err
+	}
+
+	return utilities.WriteValue(folder, args[1], strings.Join(args[2:], " "))
+}
@@ -0,0 +1,192 @@
+package utilities
+
+import (
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// File system-like interface through which all device attribute I/O goes.
+// The default backend talks to the real sysfs, see IOStrategy, unless the
+// BackendVariable environment variable names another; simulators and
+// test doubles can install their own with SetBackend.
+type Backend interface {
+	// Reads the whole content of the given attribute file.
+	ReadFile(name string) ([]byte, error)
+	// Replaces the content of the given attribute file.
+	WriteFile(name string, data []byte) error
+	// Lists the names of the entries of the given directory, sorted.
+	ReadDir(name string) ([]string, error)
+	// Reports whether the given file or directory exists.
+	Exists(name string) bool
+}
+
+type osBackend struct{}
+
+func (osBackend) ReadFile(name string) ([]byte, error) {
+	return readAttribute(HostPath(name))
+}
+
+func (osBackend) WriteFile(name string, data []byte) error {
+	return writeAttribute(HostPath(name), data)
+}
+
+func (osBackend) ReadDir(name string) ([]string, error) {
+	infos, err := ioutil.ReadDir(HostPath(name))
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, len(infos))
+	for i, info := range infos {
+		names[i] = info.Name()
+	}
+	sort.Strings(names)
+
+	return names, nil
+}
+
+func (osBackend) Exists(name string) bool {
+	_, err := os.Stat(HostPath(name))
+	return !os.IsNotExist(err)
+}
+
+// The backend installed, nil until selected from the environment on first use.
+var gBackend Backend
+var gBackendLock = &sync.RWMutex{}
+
+// Environment variable naming the backend to use until one is installed with
+// SetBackend, e.g. GOEV3_BACKEND=memory to run a program or its tests without
+// a brick. Names are those registered with RegisterBackend; "sysfs", the
+// default, and "memory" always are.
+const BackendVariable = "GOEV3_BACKEND"
+
+var gBackendFactories = map[string]func() Backend{
+	"sysfs":  func() Backend { return osBackend{} },
+	"memory": func() Backend { return NewMemoryBackend() },
+}
+var gBackendFactoriesLock = &sync.Mutex{}
+
+// Makes the backend created by `factory` selectable by `name` in the
+// BackendVariable environment variable. The Simulation package registers
+// "simulation" this way.
+func RegisterBackend(name string, factory func() Backend) {
+	gBackendFactoriesLock.Lock()
+	gBackendFactories[name] = factory
+	gBackendFactoriesLock.Unlock()
+}
+
+// Creates the backend named by the BackendVariable environment variable. An
+// unknown name is a fatal error, rather than a program driving the real
+// devices when meant not to.
+func backendFromEnvironment() Backend {
+	name := os.Getenv(BackendVariable)
+	if name == "" {
+		return osBackend{}
+	}
+
+	gBackendFactoriesLock.Lock()
+	factory, ok := gBackendFactories[name]
+	var names []string
+	for n := range gBackendFactories {
+		names = append(names, n)
+	}
+	gBackendFactoriesLock.Unlock()
+
+	if !ok {
+		sort.Strings(names)
+		Fatalf("Unknown %s %q, expected one of %s; packages such as Simulation register theirs when imported",
+			BackendVariable, name, strings.Join(names, ", "))
+	}
+
+	return factory()
+}
+
+// Installs the backend used for all subsequent device I/O.
+// Pass nil to restore the real sysfs backend.
+func SetBackend(backend Backend) {
+	if backend == nil {
+		backend = osBackend{}
+	}
+
+	gBackendLock.Lock()
+	gBackend = backend
+	gBackendLock.Unlock()
+}
+
+// Returns the backend currently used for device I/O.
+func CurrentBackend() Backend {
+	gBackendLock.RLock()
+	backend := gBackend
+	gBackendLock.RUnlock()
+
+	if backend != nil {
+		return backend
+	}
+
+	// Created unlocked, as factories may well do I/O of their own.
+	selected := backendFromEnvironment()
+
+	gBackendLock.Lock()
+	defer gBackendLock.Unlock()
+
+	if gBackend == nil {
+		gBackend = selected
+	}
+
+	return gBackend
+}
+
+// Reports whether device I/O goes to the real sysfs rather than a simulator,
+// recording or other backend. Wrappers such as CoalescingBackend are looked through.
+func UsesSysfs() bool {
+	backend := CurrentBackend()
+
+	for {
+		switch b := backend.(type) {
+		case osBackend:
+			return true
+		case interface{ Wrapped() Backend }:
+			backend = b.Wrapped()
+		default:
+			return false
+		}
+	}
+}
+
+// Lists the entries of the given directory, or nil if it cannot be read.
+func ListDir(name string) []string {
+	names, _ := CurrentBackend().ReadDir(name)
+	return names
+}
+
+// Reports whether the given file or directory exists.
+func Exists(name string) bool {
+	return CurrentBackend().Exists(name)
+}
+
+// Checks that the given attribute file can be opened for reading, or writing
+// if `write` is set, without reading or writing it. Backends other than the
+// real sysfs only check that the file exists.
+func CheckAccess(name string, write bool) error {
+	if !UsesSysfs() {
+		if !Exists(name) {
+			return &os.PathError{Op: "open", Path: name, Err: os.ErrNotExist}
+		}
+		return nil
+	}
+
+	flag := os.O_RDONLY
+	if write {
+		flag = os.O_WRONLY
+	}
+
+	f, err := os.OpenFile(HostPath(name), flag, 0)
+	if err != nil {
+		return err
+	}
+
+	return f.Close()
+}
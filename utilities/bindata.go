@@ -0,0 +1,84 @@
+package utilities
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"path"
+)
+
+// Reads all values of a sensor at once from its `bin_data` attribute, decoded
+// according to `bin_data_format` and `num_values`. This takes one read instead
+// of one per `valueN` attribute, and skips formatting and parsing the numbers.
+// As with the `valueN` attributes, the values are not scaled by `decimals`.
+func ReadBinValues(filename string) ([]float64, error) {
+	format := ReadStringValue(filename, "bin_data_format")
+	count, err := ReadValue[int](filename, "num_values")
+	if err != nil {
+		return nil, err
+	}
+
+	var size int
+	switch format {
+	case "u8", "s8":
+		size = 1
+	case "u16", "s16", "s16_be":
+		size = 2
+	case "s32", "float":
+		size = 4
+	default:
+		return nil, fmt.Errorf("unknown bin_data_format %q", format)
+	}
+
+	var data string
+	actualFilename := path.Join(filename, "bin_data")
+	err = retry("read", actualFilename, func() error {
+		a := intercept("read", actualFilename, "", func(a *Access) {
+			filename := path.Join(a.Path, a.Attribute)
+			lock := ensureLockForFilename(filename)
+
+			raw, err := withTimeout(filename, func() ([]byte, error) {
+				lock.RLock()
+				defer lock.RUnlock()
+
+				return CurrentBackend().ReadFile(filename)
+			})
+
+			a.Value, a.Err = string(raw), err
+		})
+
+		data = a.Value
+		if a.Err == nil && len(data) < count*size {
+			// Reads short while a sensor is switching modes.
+			return errEmptyValue
+		}
+		return a.Err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	values := make([]float64, count)
+	for i := range values {
+		b := []byte(data[i*size : (i+1)*size])
+
+		switch format {
+		case "u8":
+			values[i] = float64(b[0])
+		case "s8":
+			values[i] = float64(int8(b[0]))
+		case "u16":
+			values[i] = float64(binary.LittleEndian.Uint16(b))
+		case "s16":
+			values[i] = float64(int16(binary.LittleEndian.Uint16(b)))
+		case "s16_be":
+			values[i] = float64(int16(binary.BigEndian.Uint16(b)))
+		case "s32":
+			values[i] = float64(int32(binary.LittleEndian.Uint32(b)))
+		case "float":
+			values[i] = float64(math.Float32frombits(binary.LittleEndian.Uint32(b)))
+		}
+	}
+
+	return values, nil
+}
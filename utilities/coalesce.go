@@ -0,0 +1,180 @@
+package utilities
+
+import (
+	"path"
+	"sync"
+	"time"
+)
+
+// Attributes coalesced by EnableWriteCoalescing when no names are given: the
+// setpoints a control loop typically updates every few milliseconds.
+var DefaultCoalescedAttributes = []string{"duty_cycle_sp", "speed_sp"}
+
+type pendingWrite struct {
+	data  []byte
+	timer *time.Timer
+}
+
+// Backend wrapper that limits how often selected attributes are written.
+//
+// A write arriving less than the interval after the previous write to the same
+// file is held back; only the latest held value is written once the interval
+// has passed. Writes to any other attribute of the same device first flush
+// the held values, so e.g. a command never runs with a stale setpoint.
+type CoalescingBackend struct {
+	backend  Backend
+	interval time.Duration
+	names    map[string]bool
+
+	lock    sync.Mutex
+	last    map[string]time.Time
+	pending map[string]*pendingWrite
+	err     error
+}
+
+// Creates a backend that passes all I/O to `backend`, writing each of the
+// attributes named in `basenames` at most once per `interval`.
+func NewCoalescingBackend(backend Backend, interval time.Duration, basenames ...string) *CoalescingBackend {
+	if len(basenames) == 0 {
+		basenames = DefaultCoalescedAttributes
+	}
+
+	b := new(CoalescingBackend)
+	b.backend = backend
+	b.interval = interval
+	b.names = make(map[string]bool)
+	for _, name := range basenames {
+		b.names[name] = true
+	}
+	b.last = make(map[string]time.Time)
+	b.pending = make(map[string]*pendingWrite)
+
+	return b
+}
+
+// Wraps the current backend with a CoalescingBackend and installs it.
+func EnableWriteCoalescing(interval time.Duration, basenames ...string) *CoalescingBackend {
+	b := NewCoalescingBackend(CurrentBackend(), interval, basenames...)
+	SetBackend(b)
+
+	return b
+}
+
+// Returns the backend all I/O is passed to.
+func (self *CoalescingBackend) Wrapped() Backend {
+	return self.backend
+}
+
+// Flushes the held writes and reinstalls the wrapped backend.
+func (self *CoalescingBackend) Disable() error {
+	err := self.Flush()
+	SetBackend(self.backend)
+
+	return err
+}
+
+// Writes all held values immediately. Returns the first error encountered by
+// a deferred write since the last call.
+func (self *CoalescingBackend) Flush() error {
+	self.lock.Lock()
+	names := make([]string, 0, len(self.pending))
+	for name := range self.pending {
+		names = append(names, name)
+	}
+	self.lock.Unlock()
+
+	for _, name := range names {
+		self.flush(name)
+	}
+
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	err := self.err
+	self.err = nil
+
+	return err
+}
+
+func (self *CoalescingBackend) flush(name string) {
+	self.lock.Lock()
+	p, ok := self.pending[name]
+	if !ok {
+		self.lock.Unlock()
+		return
+	}
+
+	p.timer.Stop()
+	delete(self.pending, name)
+	self.last[name] = time.Now()
+
+	// Written with the lock held so a newer value can't overtake this one.
+	err := self.backend.WriteFile(name, p.data)
+	if err != nil && self.err == nil {
+		self.err = err
+	}
+	self.lock.Unlock()
+}
+
+func (self *CoalescingBackend) flushDevice(folder string) {
+	self.lock.Lock()
+	var names []string
+	for name := range self.pending {
+		if path.Dir(name) == folder {
+			names = append(names, name)
+		}
+	}
+	self.lock.Unlock()
+
+	for _, name := range names {
+		self.flush(name)
+	}
+}
+
+func (self *CoalescingBackend) ReadFile(name string) ([]byte, error) {
+	self.lock.Lock()
+	if p, ok := self.pending[name]; ok {
+		data := append([]byte(nil), p.data...)
+		self.lock.Unlock()
+		return data, nil
+	}
+	self.lock.Unlock()
+
+	return self.backend.ReadFile(name)
+}
+
+func (self *CoalescingBackend) WriteFile(name string, data []byte) error {
+	if !self.names[path.Base(name)] {
+		self.flushDevice(path.Dir(name))
+		return self.backend.WriteFile(name, data)
+	}
+
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	if p, ok := self.pending[name]; ok {
+		p.data = append([]byte(nil), data...)
+		return nil
+	}
+
+	wait := self.interval - time.Since(self.last[name])
+	if wait <= 0 {
+		self.last[name] = time.Now()
+		return self.backend.WriteFile(name, data)
+	}
+
+	self.pending[name] = &pendingWrite{
+		data:  append([]byte(nil), data...),
+		timer: time.AfterFunc(wait, func() { self.flush(name) }),
+	}
+
+	return nil
+}
+
+func (self *CoalescingBackend) ReadDir(name string) ([]string, error) {
+	return self.backend.ReadDir(name)
+}
+
+func (self *CoalescingBackend) Exists(name string) bool {
+	return self.backend.Exists(name)
+}
@@ -0,0 +1,106 @@
+package utilities
+
+import (
+	"log"
+	"strings"
+	"sync"
+)
+
+// Device classes whose writes are held back by a DryRunBackend.
+var DryRunClasses = []string{
+	"/sys/class/tacho-motor/",
+	"/sys/class/dc-motor/",
+	"/sys/class/servo-motor/",
+}
+
+// Backend wrapper that logs writes to motors instead of performing them, so a
+// program can be rehearsed with the robot on a stand. Everything else,
+// including sensor reads and mode switches, is passed through.
+//
+// Values written to a motor are remembered and returned when the same
+// attribute is read back, so e.g. a regulation mode set earlier is seen by
+// later commands.
+type DryRunBackend struct {
+	backend Backend
+	logger  *log.Logger
+
+	lock    sync.Mutex
+	written map[string][]byte
+}
+
+// Creates a backend that passes all I/O except motor writes to `backend`.
+// Held back writes are logged to `logger`, or the standard logger if nil.
+func NewDryRunBackend(backend Backend, logger *log.Logger) *DryRunBackend {
+	b := new(DryRunBackend)
+	b.backend = backend
+	b.logger = logger
+	b.written = make(map[string][]byte)
+
+	return b
+}
+
+// Wraps the current backend with a DryRunBackend and installs it.
+func EnableDryRun(logger *log.Logger) *DryRunBackend {
+	b := NewDryRunBackend(CurrentBackend(), logger)
+	SetBackend(b)
+
+	return b
+}
+
+// Returns the backend all I/O except motor writes is passed to.
+func (self *DryRunBackend) Wrapped() Backend {
+	return self.backend
+}
+
+// Reinstalls the wrapped backend. Motors keep their state from before the dry run.
+func (self *DryRunBackend) Disable() {
+	SetBackend(self.backend)
+}
+
+func (self *DryRunBackend) holdsBack(name string) bool {
+	for _, class := range DryRunClasses {
+		if strings.HasPrefix(name, class) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (self *DryRunBackend) ReadFile(name string) ([]byte, error) {
+	self.lock.Lock()
+	data, ok := self.written[name]
+	self.lock.Unlock()
+
+	if ok {
+		return append([]byte(nil), data...), nil
+	}
+
+	return self.backend.ReadFile(name)
+}
+
+func (self *DryRunBackend) WriteFile(name string, data []byte) error {
+	if !self.holdsBack(name) {
+		return self.backend.WriteFile(name, data)
+	}
+
+	self.lock.Lock()
+	self.written[name] = append([]byte(nil), data...)
+	self.lock.Unlock()
+
+	if self.logger != nil {
+		self.logger.Printf("dry run: %s = %s\n", name, data)
+	} else {
+		Logger().Info("dry run", "file", name, "value", string(data))
+	}
+
+	return nil
+}
+
+func (self *DryRunBackend) ReadDir(name string) ([]string, error) {
+	return self.backend.ReadDir(name)
+}
+
+func (self *DryRunBackend) Exists(name string) bool {
+	return self.backend.Exists(name)
+}
@@ -0,0 +1,228 @@
+package utilities
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+// How the sysfs backend accesses attribute files.
+//
+// Every access of an attribute used to open, read or write, and close its
+// file. On the EV3's 300 MHz ARM9 the open and close system calls, with their
+// path lookups through sysfs, add to every access, so by default files are
+// opened once and kept open: sysfs regenerates an attribute's content
+// whenever it is read from offset 0, which pread does without seeking, and
+// takes every write whole.
+//
+// Plain files behave otherwise: a shorter write leaves the end of a longer
+// value behind, and a file served over sshfs or another FUSE mount may be
+// read stale while kept open. Files outside a sysfs mount, e.g. a device tree
+// in a directory set with SetSysfsRoot, are therefore opened for every access
+// whatever the strategy.
+//
+// Run cmd/ev3bench on a brick to compare the strategies there.
+type IOStrategy int
+
+const (
+	// Keeps attribute files open and reads and writes them at offset 0. The default.
+	CachedFiles IOStrategy = iota
+	// Opens and closes the attribute file for every access.
+	OpenPerCall
+)
+
+// Most files kept open. Beyond that the cache is emptied, which only happens
+// if a program touches an unusual number of attributes.
+const maxCachedFiles = 256
+
+// Largest sysfs attribute, one page.
+const maxAttributeSize = 4096
+
+var gIOStrategy = CachedFiles
+var gIOStrategyLock = &sync.RWMutex{}
+
+// Selects how the sysfs backend accesses attribute files. Switching closes
+// the files kept open.
+func SetIOStrategy(strategy IOStrategy) {
+	gIOStrategyLock.Lock()
+	gIOStrategy = strategy
+	gIOStrategyLock.Unlock()
+
+	ReleaseAllFiles()
+}
+
+// Closes the attribute files of the device at `folder` kept open, e.g.
+// "/sys/class/tacho-motor/motor0", for a program done with the device. Any
+// later access opens them again.
+func ReleaseFiles(folder string) {
+	folder = path.Clean(HostPath(folder))
+	gReadFiles.release(folder)
+	gWriteFiles.release(folder)
+}
+
+// Closes all the attribute files kept open, e.g. as the program exits.
+func ReleaseAllFiles() {
+	gReadFiles.clear()
+	gWriteFiles.clear()
+}
+
+func currentIOStrategy() IOStrategy {
+	gIOStrategyLock.RLock()
+	defer gIOStrategyLock.RUnlock()
+
+	return gIOStrategy
+}
+
+// Open attribute files by path, all opened with the same flag.
+type fileCache struct {
+	flag  int
+	lock  sync.Mutex
+	files map[string]*os.File
+}
+
+var gReadFiles = &fileCache{flag: os.O_RDONLY}
+var gWriteFiles = &fileCache{flag: os.O_WRONLY}
+
+func (self *fileCache) get(name string) (*os.File, error) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	if f, ok := self.files[name]; ok {
+		return f, nil
+	}
+
+	f, err := os.OpenFile(name, self.flag, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	if self.files == nil || len(self.files) >= maxCachedFiles {
+		self.closeAll()
+		self.files = make(map[string]*os.File)
+	}
+	self.files[name] = f
+
+	return f, nil
+}
+
+// Closes and forgets the file if it is still the one cached for `name`.
+func (self *fileCache) evict(name string, f *os.File) {
+	self.lock.Lock()
+	if self.files[name] == f {
+		delete(self.files, name)
+		f.Close()
+	}
+	self.lock.Unlock()
+}
+
+func (self *fileCache) clear() {
+	self.lock.Lock()
+	self.closeAll()
+	self.files = nil
+	self.lock.Unlock()
+}
+
+// Closes and forgets the files within `folder`.
+func (self *fileCache) release(folder string) {
+	self.lock.Lock()
+	for name, f := range self.files {
+		if strings.HasPrefix(name, folder+"/") {
+			delete(self.files, name)
+			f.Close()
+		}
+	}
+	self.lock.Unlock()
+}
+
+func (self *fileCache) closeAll() {
+	for _, f := range self.files {
+		f.Close()
+	}
+}
+
+// Reports whether an error on a kept open file means the file has gone stale,
+// as happens when its device is unplugged, so that it must be opened again.
+func isStale(err error) bool {
+	return errors.Is(err, syscall.ENODEV) || errors.Is(err, syscall.ENOENT) ||
+		errors.Is(err, os.ErrClosed) || errors.Is(err, syscall.EBADF)
+}
+
+// Accesses a kept open file, reopening it once if it went stale.
+func (self *fileCache) do(name string, fn func(f *os.File) error) error {
+	for attempt := 0; ; attempt++ {
+		f, err := self.get(name)
+		if err != nil {
+			return err
+		}
+
+		err = fn(f)
+		if err == nil || !isStale(err) || attempt > 0 {
+			return err
+		}
+
+		self.evict(name, f)
+	}
+}
+
+// Reports whether the attribute file `name` may be kept open: the strategy is
+// CachedFiles and the file is on a sysfs mount.
+func keepsOpen(name string) bool {
+	if currentIOStrategy() == OpenPerCall {
+		return false
+	}
+
+	sys := HostPath("/sys")
+	if !strings.HasPrefix(name, sys+"/") {
+		return false
+	}
+
+	gSysfsMountsLock.Lock()
+	defer gSysfsMountsLock.Unlock()
+
+	mounted, ok := gSysfsMounts[sys]
+	if !ok {
+		mounted = isSysfs(sys)
+		gSysfsMounts[sys] = mounted
+	}
+
+	return mounted
+}
+
+// Whether the directories looked at by keepsOpen are sysfs mounts.
+var gSysfsMounts = make(map[string]bool)
+var gSysfsMountsLock = &sync.Mutex{}
+
+func readAttribute(name string) ([]byte, error) {
+	if !keepsOpen(name) {
+		return ioutil.ReadFile(name)
+	}
+
+	var data []byte
+	err := gReadFiles.do(name, func(f *os.File) error {
+		buf := make([]byte, maxAttributeSize)
+		n, err := f.ReadAt(buf, 0)
+		if err == io.EOF {
+			err = nil
+		}
+		data = buf[:n]
+		return err
+	})
+
+	return data, err
+}
+
+func writeAttribute(name string, data []byte) error {
+	if !keepsOpen(name) {
+		return ioutil.WriteFile(name, data, 0644)
+	}
+
+	return gWriteFiles.do(name, func(f *os.File) error {
+		_, err := f.WriteAt(data, 0)
+		return err
+	})
+}
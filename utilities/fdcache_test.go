@@ -0,0 +1,104 @@
+package utilities
+
+import (
+	"os"
+	"path"
+	"testing"
+)
+
+// Folder of the device the benchmarks read and write, under the temporary root.
+const benchmarkDevice = "/sys/class/lego-sensor/sensor0"
+
+// Points the sysfs backend at a temporary directory holding a device with a
+// `value0` and a `speed_sp` attribute, in `strategy`, until the test ends.
+// Unless `asSysfs` is set, the directory is taken for what it is, a plain
+// file system, whose files aren't kept open.
+func setUpRoot(tb testing.TB, strategy IOStrategy, asSysfs bool) {
+	root := tb.TempDir()
+	folder := path.Join(root, benchmarkDevice)
+	if err := os.MkdirAll(folder, 0755); err != nil {
+		tb.Fatal(err)
+	}
+	for _, attribute := range []string{"value0", "speed_sp"} {
+		if err := os.WriteFile(path.Join(folder, attribute), []byte("42\n"), 0644); err != nil {
+			tb.Fatal(err)
+		}
+	}
+
+	probe := isSysfs
+	if asSysfs {
+		isSysfs = func(string) bool { return true }
+	}
+	SetBackend(nil)
+	SetSysfsRoot(root)
+	SetIOStrategy(strategy)
+
+	tb.Cleanup(func() {
+		SetIOStrategy(CachedFiles)
+		SetSysfsRoot("")
+		isSysfs = probe
+		gSysfsMountsLock.Lock()
+		gSysfsMounts = make(map[string]bool)
+		gSysfsMountsLock.Unlock()
+	})
+}
+
+// Sets up the root of setUpRoot as if it were sysfs, so that the benchmarks
+// compare the strategies, although plain files don't regenerate their
+// content as attributes do.
+func setUpBenchmark(b *testing.B, strategy IOStrategy) {
+	setUpRoot(b, strategy, true)
+	b.ResetTimer()
+}
+
+func TestWriteShorterValue(t *testing.T) {
+	for _, strategy := range []IOStrategy{CachedFiles, OpenPerCall} {
+		setUpRoot(t, strategy, false)
+
+		for _, value := range []int{100, 5} {
+			if err := WriteValue(benchmarkDevice, "speed_sp", value); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if got, err := ReadValue[int](benchmarkDevice, "speed_sp"); err != nil || got != 5 {
+			t.Fatalf("strategy %d: read %d, %v after writing 5 over 100", strategy, got, err)
+		}
+	}
+}
+
+func benchmarkRead(b *testing.B, strategy IOStrategy) {
+	setUpBenchmark(b, strategy)
+
+	for i := 0; i < b.N; i++ {
+		if _, err := ReadValue[int](benchmarkDevice, "value0"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func benchmarkWrite(b *testing.B, strategy IOStrategy) {
+	setUpBenchmark(b, strategy)
+
+	for i := 0; i < b.N; i++ {
+		if err := WriteValue(benchmarkDevice, "speed_sp", i%1000); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkReadCachedFiles(b *testing.B) {
+	benchmarkRead(b, CachedFiles)
+}
+
+func BenchmarkReadOpenPerCall(b *testing.B) {
+	benchmarkRead(b, OpenPerCall)
+}
+
+func BenchmarkWriteCachedFiles(b *testing.B) {
+	benchmarkWrite(b, CachedFiles)
+}
+
+func BenchmarkWriteOpenPerCall(b *testing.B) {
+	benchmarkWrite(b, OpenPerCall)
+}
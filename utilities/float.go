@@ -0,0 +1,59 @@
+package utilities
+
+import (
+	"math"
+	"path"
+	"strconv"
+)
+
+// Ways of turning a fractional value into the integer an attribute stores.
+type Rounding int
+
+const (
+	// Rounds half away from zero.
+	RoundNearest Rounding = iota
+	// Rounds towards negative infinity.
+	RoundDown
+	// Rounds towards positive infinity.
+	RoundUp
+	// Drops the fractional part.
+	RoundTowardZero
+)
+
+// Rounds `value` to an integer.
+func (self Rounding) Apply(value float64) float64 {
+	switch self {
+	case RoundDown:
+		return math.Floor(value)
+	case RoundUp:
+		return math.Ceil(value)
+	case RoundTowardZero:
+		return math.Trunc(value)
+	}
+
+	return math.Round(value)
+}
+
+// Reads a numeric attribute and multiplies it by `scale`, e.g. 1e-6 for the
+// microvolts reported by power supplies.
+func ReadFloatValue(filename string, basename string, scale float64) (float64, error) {
+	value, err := ReadValue[float64](filename, basename)
+	return value * scale, err
+}
+
+// Divides `value` by `scale`, rounds it and writes it to an integer attribute.
+func WriteFloatValue(filename string, basename string, value float64, scale float64, rounding Rounding) error {
+	raw := rounding.Apply(value / scale)
+	return writeString(path.Join(filename, basename), strconv.FormatInt(int64(raw), 10))
+}
+
+// Reads an attribute of a device that reports its number of decimal places in
+// a "decimals" attribute, as ev3dev sensors do, and returns the fractional value.
+func ReadDecimalValue(filename string, basename string) (float64, error) {
+	decimals, err := ReadValue[int](filename, "decimals")
+	if err != nil {
+		return 0, err
+	}
+
+	return ReadFloatValue(filename, basename, math.Pow10(-decimals))
+}
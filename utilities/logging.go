@@ -0,0 +1,97 @@
+package utilities
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"sync/atomic"
+)
+
+var gLogger *slog.Logger
+var gLoggerSet bool
+var gLoggerLock = &sync.RWMutex{}
+var gTracing atomic.Bool
+
+// Sets the logger of the library's diagnostics: the errors the Find*
+// constructors exit on, warnings such as calibrations that couldn't be
+// loaded, and the attribute accesses traced with SetTracing. By default they
+// go to slog.Default(), which writes through the standard log package. nil
+// silences them; fatal errors still exit the program.
+//
+//	utilities.SetLogger(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelDebug})))
+//	utilities.SetTracing(true)
+func SetLogger(logger *slog.Logger) {
+	gLoggerLock.Lock()
+	gLogger, gLoggerSet = logger, true
+	gLoggerLock.Unlock()
+}
+
+// Returns the logger set with SetLogger, slog.Default() if there is none, or
+// a logger discarding everything if the diagnostics were silenced.
+func Logger() *slog.Logger {
+	gLoggerLock.RLock()
+	logger, set := gLogger, gLoggerSet
+	gLoggerLock.RUnlock()
+
+	switch {
+	case !set:
+		return slog.Default()
+	case logger == nil:
+		return slog.New(discardHandler{})
+	}
+
+	return logger
+}
+
+// Makes every attribute read and write logged at slog.LevelDebug, with the
+// device folder, the attribute, the value, how long the I/O took and its
+// error, e.g. to debug timing problems on the brick. The logger must have
+// the debug level enabled to show them. Off by default.
+func SetTracing(enabled bool) {
+	gTracing.Store(enabled)
+}
+
+// Logs an attribute access if tracing is on.
+func trace(a *Access) {
+	if !gTracing.Load() {
+		return
+	}
+
+	logger := Logger()
+	if !logger.Enabled(context.Background(), slog.LevelDebug) {
+		return
+	}
+
+	attrs := []slog.Attr{
+		slog.String("device", a.Path),
+		slog.String("attribute", a.Attribute),
+		slog.String("value", a.Value),
+		slog.Duration("duration", a.Duration),
+	}
+	if a.Err != nil {
+		attrs = append(attrs, slog.Any("err", a.Err))
+	}
+	logger.LogAttrs(context.Background(), slog.LevelDebug, "sysfs "+a.Op, attrs...)
+}
+
+// Logs `err` at slog.LevelError and exits with status 1, as log.Fatal does,
+// for the errors the library can't go on after.
+func Fatal(err error) {
+	Logger().Error(err.Error())
+	os.Exit(1)
+}
+
+// Formats the message like fmt.Sprintf and exits like Fatal.
+func Fatalf(format string, args ...interface{}) {
+	Fatal(fmt.Errorf(format, args...))
+}
+
+// A slog handler dropping every record.
+type discardHandler struct{}
+
+func (discardHandler) Enabled(context.Context, slog.Level) bool  { return false }
+func (discardHandler) Handle(context.Context, slog.Record) error { return nil }
+func (self discardHandler) WithAttrs([]slog.Attr) slog.Handler   { return self }
+func (self discardHandler) WithGroup(string) slog.Handler        { return self }
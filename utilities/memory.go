@@ -0,0 +1,129 @@
+package utilities
+
+import (
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Backend keeping attribute files in memory, for testing code against a
+// device tree made by hand, without a brick:
+//
+//	b := utilities.NewMemoryBackend()
+//	b.SetFile("/sys/class/lego-sensor/sensor0/address", "ev3-ports:in1")
+//	b.SetFile("/sys/class/lego-sensor/sensor0/driver_name", "lego-ev3-touch")
+//	b.SetFile("/sys/class/lego-sensor/sensor0/value0", "1")
+//	utilities.SetBackend(b)
+//
+// Directories exist as long as files are in them. As in sysfs, only files
+// that exist can be written. For motors and sensors that act on what is
+// written, see the Simulation package.
+type MemoryBackend struct {
+	lock  sync.RWMutex
+	files map[string][]byte
+}
+
+// Creates an empty backend.
+func NewMemoryBackend() *MemoryBackend {
+	b := new(MemoryBackend)
+	b.files = make(map[string][]byte)
+
+	return b
+}
+
+// Creates the file `name`, or replaces its content, with `value`.
+func (self *MemoryBackend) SetFile(name string, value string) {
+	self.lock.Lock()
+	self.files[name] = []byte(value)
+	self.lock.Unlock()
+}
+
+// Returns the content of the file `name`, and whether it exists.
+func (self *MemoryBackend) File(name string) (string, bool) {
+	self.lock.RLock()
+	defer self.lock.RUnlock()
+
+	data, ok := self.files[name]
+	return string(data), ok
+}
+
+// Removes the file `name`, or the directory `name` with all its files, e.g.
+// to unplug a device.
+func (self *MemoryBackend) Remove(name string) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	prefix := strings.TrimSuffix(name, "/") + "/"
+	for file := range self.files {
+		if file == name || strings.HasPrefix(file, prefix) {
+			delete(self.files, file)
+		}
+	}
+}
+
+func (self *MemoryBackend) ReadFile(name string) ([]byte, error) {
+	self.lock.RLock()
+	defer self.lock.RUnlock()
+
+	data, ok := self.files[name]
+	if !ok {
+		return nil, &os.PathError{Op: "read", Path: name, Err: os.ErrNotExist}
+	}
+
+	return append([]byte(nil), data...), nil
+}
+
+func (self *MemoryBackend) WriteFile(name string, data []byte) error {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	if _, ok := self.files[name]; !ok {
+		return &os.PathError{Op: "write", Path: name, Err: os.ErrNotExist}
+	}
+	self.files[name] = append([]byte(nil), data...)
+
+	return nil
+}
+
+func (self *MemoryBackend) ReadDir(name string) ([]string, error) {
+	self.lock.RLock()
+	defer self.lock.RUnlock()
+
+	prefix := strings.TrimSuffix(name, "/") + "/"
+	seen := make(map[string]bool)
+	for file := range self.files {
+		if strings.HasPrefix(file, prefix) {
+			seen[strings.SplitN(file[len(prefix):], "/", 2)[0]] = true
+		}
+	}
+	if len(seen) == 0 {
+		return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrNotExist}
+	}
+
+	names := make([]string, 0, len(seen))
+	for n := range seen {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+
+	return names, nil
+}
+
+func (self *MemoryBackend) Exists(name string) bool {
+	self.lock.RLock()
+	defer self.lock.RUnlock()
+
+	if _, ok := self.files[name]; ok {
+		return true
+	}
+
+	prefix := strings.TrimSuffix(name, "/") + "/"
+	for file := range self.files {
+		if strings.HasPrefix(file, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
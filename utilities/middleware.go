@@ -0,0 +1,98 @@
+package utilities
+
+import (
+	"path"
+	"sync"
+	"time"
+)
+
+// A single attempt to read or write an attribute, as seen by middleware.
+type Access struct {
+	// "read" or "write".
+	Op string
+	// Folder of the device, e.g. "/sys/class/tacho-motor/motor0".
+	Path string
+	// Name of the attribute, e.g. "speed_sp".
+	Attribute string
+	// The value to write, or the value read once the access has been performed.
+	Value string
+	// Time spent on the I/O itself, set once the access has been performed.
+	Duration time.Duration
+	// Error of the I/O, set once the access has been performed.
+	Err error
+}
+
+// Intercepts attribute I/O. A middleware performs the access by calling `next`
+// and may inspect or change the access before and after doing so, e.g. to
+// alter the value read, or skip `next` to fake the access entirely:
+//
+//	utilities.Use(func(a *utilities.Access, next func(*utilities.Access)) {
+//		next(a)
+//		log.Println(a.Op, a.Path, a.Attribute, a.Value, a.Duration, a.Err)
+//	})
+type Middleware func(access *Access, next func(*Access))
+
+var gMiddleware []Middleware
+var gMiddlewareLock = &sync.RWMutex{}
+
+// Adds a middleware that sees every attribute read and write. Middleware added
+// first is outermost. Retried accesses are passed through once per attempt.
+func Use(middleware Middleware) {
+	gMiddlewareLock.Lock()
+	gMiddleware = append(gMiddleware[:len(gMiddleware):len(gMiddleware)], middleware)
+	gMiddlewareLock.Unlock()
+}
+
+// Removes all middleware.
+func ClearMiddleware() {
+	gMiddlewareLock.Lock()
+	gMiddleware = nil
+	gMiddlewareLock.Unlock()
+}
+
+var gObservers []func(Access)
+var gObserversLock = &sync.RWMutex{}
+
+// Registers a function called with every attribute read and write once it
+// has been performed, after the middleware, e.g. to record latencies and
+// errors as the Metrics package does. Unlike middleware, it can't change the
+// access, and it stays registered through ClearMiddleware.
+func OnAccess(fn func(a Access)) {
+	gObserversLock.Lock()
+	gObservers = append(gObservers[:len(gObservers):len(gObservers)], fn)
+	gObserversLock.Unlock()
+}
+
+// Passes an access to the attribute file `filename` through the middleware,
+// with `perform` doing the actual I/O at the end of the chain.
+func intercept(op string, filename string, value string, perform func(*Access)) *Access {
+	gMiddlewareLock.RLock()
+	chain := gMiddleware
+	gMiddlewareLock.RUnlock()
+
+	a := &Access{Op: op, Path: path.Dir(filename), Attribute: path.Base(filename), Value: value}
+
+	var call func(i int, a *Access)
+	call = func(i int, a *Access) {
+		if i == len(chain) {
+			start := time.Now()
+			perform(a)
+			a.Duration = time.Since(start)
+			return
+		}
+
+		chain[i](a, func(a *Access) { call(i+1, a) })
+	}
+	call(0, a)
+	trace(a)
+
+	gObserversLock.RLock()
+	observers := gObservers
+	gObserversLock.RUnlock()
+
+	for _, fn := range observers {
+		fn(*a)
+	}
+
+	return a
+}
@@ -0,0 +1,92 @@
+package utilities
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/jermon/GoEV3/Errors"
+)
+
+// Error returned by attribute I/O once all attempts have failed.
+type IOError struct {
+	// "read" or "write".
+	Op string
+	// Full path of the attribute file.
+	Path string
+	// Number of attempts made.
+	Attempts int
+	// The error of the last attempt.
+	Err error
+}
+
+func (self *IOError) Error() string {
+	if self.Attempts > 1 {
+		return fmt.Sprintf("%s %s failed after %d attempts: %v", self.Op, self.Path, self.Attempts, self.Err)
+	}
+
+	return fmt.Sprintf("%s %s: %v", self.Op, self.Path, self.Err)
+}
+
+func (self *IOError) Unwrap() error {
+	return self.Err
+}
+
+// Makes errors.Is match Errors.ErrDisconnected when the attribute file has gone
+// away, as happens when a device is unplugged.
+func (self *IOError) Is(target error) bool {
+	return target == Errors.ErrDisconnected &&
+		(errors.Is(self.Err, os.ErrNotExist) || errors.Is(self.Err, syscall.ENODEV))
+}
+
+var errEmptyValue = errors.New("attribute is empty")
+
+var gRetryAttempts = 3
+var gRetryBackoff = 2 * time.Millisecond
+var gRetryLock = &sync.RWMutex{}
+
+// Sets how many times attribute I/O is attempted when it fails with a
+// transient error, and the delay before the first retry, doubled for every
+// further retry. Pass 1 to disable retrying.
+func SetRetryPolicy(attempts int, backoff time.Duration) {
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	gRetryLock.Lock()
+	gRetryAttempts = attempts
+	gRetryBackoff = backoff
+	gRetryLock.Unlock()
+}
+
+// Reports whether an I/O error is likely to go away on its own, as happens
+// while a driver is busy switching sensor modes.
+func isTransient(err error) bool {
+	return err == errEmptyValue ||
+		errors.Is(err, syscall.EAGAIN) ||
+		errors.Is(err, syscall.EBUSY) ||
+		errors.Is(err, syscall.EINTR)
+}
+
+func retry(op string, filename string, fn func() error) error {
+	gRetryLock.RLock()
+	attempts, backoff := gRetryAttempts, gRetryBackoff
+	gRetryLock.RUnlock()
+
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil {
+			return nil
+		}
+
+		if attempt >= attempts || !isTransient(err) {
+			return &IOError{op, filename, attempt, err}
+		}
+
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
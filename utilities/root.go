@@ -0,0 +1,49 @@
+package utilities
+
+import (
+	"os"
+	"path"
+	"strings"
+	"sync"
+)
+
+// Environment variable setting the directory the brick's file system is
+// mounted at, see SetSysfsRoot.
+const SysfsRootVariable = "GOEV3_SYSFS_ROOT"
+
+var gSysfsRoot = os.Getenv(SysfsRootVariable)
+var gSysfsRootLock = &sync.RWMutex{}
+
+// Makes the sysfs backend look for the device files, e.g.
+// /sys/class/tacho-motor, under `root` instead of /, for a brick's sysfs
+// bind-mounted into a container or its file system mounted over sshfs:
+//
+//	utilities.SetSysfsRoot("/mnt/ev3")
+//
+// Applies to every path the sysfs backend reads and writes, including /dev,
+// and to the files opened with HostPath. An empty root, the default unless
+// set with GOEV3_SYSFS_ROOT, restores /.
+func SetSysfsRoot(root string) {
+	gSysfsRootLock.Lock()
+	gSysfsRoot = strings.TrimSuffix(root, "/")
+	gSysfsRootLock.Unlock()
+}
+
+// Returns the directory set with SetSysfsRoot, empty for /.
+func SysfsRoot() string {
+	gSysfsRootLock.RLock()
+	defer gSysfsRootLock.RUnlock()
+
+	return gSysfsRoot
+}
+
+// Returns where the device file `name`, e.g. "/dev/input/event0", is found
+// under the root set with SetSysfsRoot.
+func HostPath(name string) string {
+	root := SysfsRoot()
+	if root == "" || !path.IsAbs(name) {
+		return name
+	}
+
+	return root + name
+}
@@ -0,0 +1,189 @@
+package utilities
+
+import (
+	"container/heap"
+	"sync"
+	"time"
+)
+
+// A function called periodically by the shared polling scheduler.
+//
+// Rather than each subscription running its own goroutine and ticker, all of
+// them are serviced by a single goroutine that sleeps on a single timer until
+// the earliest deadline, which matters on the EV3's single slow core. Polled
+// functions therefore run one after the other and must return promptly: a
+// slow one delays all the others.
+type PollTask struct {
+	interval time.Duration
+	next     time.Time
+	fn       func()
+	// Position in the scheduler's heap, -1 once cancelled.
+	index int
+}
+
+// Tasks ordered by deadline.
+type taskHeap []*PollTask
+
+func (self taskHeap) Len() int           { return len(self) }
+func (self taskHeap) Less(i, j int) bool { return self[i].next.Before(self[j].next) }
+
+func (self taskHeap) Swap(i, j int) {
+	self[i], self[j] = self[j], self[i]
+	self[i].index = i
+	self[j].index = j
+}
+
+func (self *taskHeap) Push(x interface{}) {
+	t := x.(*PollTask)
+	t.index = len(*self)
+	*self = append(*self, t)
+}
+
+func (self *taskHeap) Pop() interface{} {
+	old := *self
+	t := old[len(old)-1]
+	old[len(old)-1] = nil
+	*self = old[:len(old)-1]
+	t.index = -1
+	return t
+}
+
+var gSchedulerLock = &sync.Mutex{}
+var gTasks taskHeap
+var gSchedulerWake chan bool
+
+// Calls `fn` right away and then every `interval` from the shared scheduler
+// goroutine, until the task is cancelled. Like a ticker, it skips calls it
+// is too late for rather than catching up.
+func Poll(interval time.Duration, fn func()) *PollTask {
+	if interval <= 0 {
+		panic("utilities: non-positive interval for Poll")
+	}
+
+	t := &PollTask{interval: interval, next: time.Now(), fn: fn}
+
+	gSchedulerLock.Lock()
+	heap.Push(&gTasks, t)
+	if gSchedulerWake == nil {
+		gSchedulerWake = make(chan bool, 1)
+		go schedule()
+	}
+	wake := gSchedulerWake
+	gSchedulerLock.Unlock()
+
+	select {
+	case wake <- true:
+	default:
+	}
+
+	return t
+}
+
+// Calls `fn` every `interval` until a value is sent to `stop`, as Poll does.
+// It blocks, like the Run methods with their own ticker it replaces, but
+// without a timer of its own.
+func PollUntil(stop <-chan bool, interval time.Duration, fn func()) {
+	t := Poll(interval, fn)
+	<-stop
+	t.Cancel()
+}
+
+// Stops calling the task's function. A call in progress completes. Safe to
+// call more than once, and from the function itself.
+func (self *PollTask) Cancel() {
+	gSchedulerLock.Lock()
+	if self.index >= 0 {
+		heap.Remove(&gTasks, self.index)
+	}
+	gSchedulerLock.Unlock()
+}
+
+// Changes how often the task's function is called, from its last call on.
+func (self *PollTask) SetInterval(interval time.Duration) {
+	if interval <= 0 {
+		panic("utilities: non-positive interval for SetInterval")
+	}
+
+	gSchedulerLock.Lock()
+	if self.index >= 0 {
+		self.next = self.next.Add(interval - self.interval)
+		heap.Fix(&gTasks, self.index)
+	}
+	self.interval = interval
+	wake := gSchedulerWake
+	gSchedulerLock.Unlock()
+
+	// The deadline may now be sooner than the scheduler sleeps for.
+	select {
+	case wake <- true:
+	default:
+	}
+}
+
+// Returns the tasks whose deadline has passed, after moving their deadlines
+// on, and how long to sleep until the next one.
+func dueTasks() ([]*PollTask, time.Duration) {
+	gSchedulerLock.Lock()
+	defer gSchedulerLock.Unlock()
+
+	now := time.Now()
+	var due []*PollTask
+
+	for len(gTasks) > 0 && !gTasks[0].next.After(now) {
+		t := gTasks[0]
+		due = append(due, t)
+
+		t.next = t.next.Add(t.interval)
+		if t.next.Before(now) {
+			t.next = now.Add(t.interval)
+		}
+		heap.Fix(&gTasks, 0)
+	}
+
+	if len(gTasks) == 0 {
+		return due, -1
+	}
+
+	return due, gTasks[0].next.Sub(now)
+}
+
+func (self *PollTask) cancelled() bool {
+	gSchedulerLock.Lock()
+	defer gSchedulerLock.Unlock()
+
+	return self.index < 0
+}
+
+// Runs the scheduler forever, started by the first call to Poll.
+func schedule() {
+	timer := time.NewTimer(time.Hour)
+	timer.Stop()
+
+	for {
+		due, wait := dueTasks()
+
+		for _, t := range due {
+			if !t.cancelled() {
+				t.fn()
+			}
+		}
+		if len(due) > 0 {
+			// Running them took time; look again before sleeping.
+			continue
+		}
+
+		if wait < 0 {
+			<-gSchedulerWake
+			continue
+		}
+
+		timer.Reset(wait)
+		select {
+		case <-timer.C:
+		case <-gSchedulerWake:
+			if !timer.Stop() {
+				<-timer.C
+			}
+		}
+	}
+}
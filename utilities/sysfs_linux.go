@@ -0,0 +1,20 @@
+//go:build linux
+
+package utilities
+
+import (
+	"syscall"
+)
+
+// File system type of sysfs mounts, SYSFS_MAGIC.
+const sysfsMagic = 0x62656572
+
+// Reports whether the directory `name` is on a sysfs mount.
+var isSysfs = func(name string) bool {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(name, &stat); err != nil {
+		return false
+	}
+
+	return int64(stat.Type) == sysfsMagic
+}
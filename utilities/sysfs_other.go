@@ -0,0 +1,8 @@
+//go:build !linux
+
+package utilities
+
+// Only Linux has sysfs.
+var isSysfs = func(name string) bool {
+	return false
+}
@@ -0,0 +1,87 @@
+package utilities
+
+import (
+	"path"
+	"sync"
+	"time"
+
+	"github.com/jermon/GoEV3/Errors"
+)
+
+var gIOTimeout time.Duration
+var gDeviceIOTimeouts = make(map[string]time.Duration)
+var gIOTimeoutLock = &sync.RWMutex{}
+
+// Sets how long any attribute read or write may take before it fails with an
+// *IOError matching Errors.ErrTimeout, so that a wedged driver stalls one
+// call rather than the whole program. Pass 0, the default, to wait for as
+// long as it takes. Timeouts set for a device with SetDeviceIOTimeout take
+// precedence.
+//
+// The I/O itself can't be interrupted: it goes on in the background, and
+// later accesses to the same attribute time out too until it returns.
+func SetIOTimeout(timeout time.Duration) {
+	gIOTimeoutLock.Lock()
+	gIOTimeout = timeout
+	gIOTimeoutLock.Unlock()
+}
+
+// Sets the timeout of the attribute I/O of the device at `folder`, e.g.
+// "/sys/class/tacho-motor/motor0", as SetIOTimeout does for all devices.
+// Pass a negative timeout to wait for as long as it takes, or 0 to go back
+// to the global timeout.
+func SetDeviceIOTimeout(folder string, timeout time.Duration) {
+	gIOTimeoutLock.Lock()
+	if timeout == 0 {
+		delete(gDeviceIOTimeouts, folder)
+	} else {
+		gDeviceIOTimeouts[folder] = timeout
+	}
+	gIOTimeoutLock.Unlock()
+}
+
+// Returns the timeout of I/O on the attribute file `filename`, or 0 for none.
+func ioTimeout(filename string) time.Duration {
+	gIOTimeoutLock.RLock()
+	defer gIOTimeoutLock.RUnlock()
+
+	timeout, ok := gDeviceIOTimeouts[path.Dir(filename)]
+	if !ok {
+		timeout = gIOTimeout
+	}
+	if timeout < 0 {
+		return 0
+	}
+
+	return timeout
+}
+
+// Runs `fn`, giving up on it after the timeout of the attribute file `filename`.
+func withTimeout[T any](filename string, fn func() (T, error)) (T, error) {
+	timeout := ioTimeout(filename)
+	if timeout == 0 {
+		return fn()
+	}
+
+	type result struct {
+		value T
+		err   error
+	}
+
+	done := make(chan result, 1)
+	go func() {
+		value, err := fn()
+		done <- result{value, err}
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case r := <-done:
+		return r.value, r.err
+	case <-timer.C:
+		var zero T
+		return zero, Errors.ErrTimeout
+	}
+}
@@ -2,9 +2,7 @@
 package utilities
 
 import (
-	"io/ioutil"
 	"path"
-	"strconv"
 	"strings"
 	"sync"
 )
@@ -12,8 +10,10 @@ import (
 var gLocks map[string]*sync.RWMutex
 var gCreationLock = &sync.Mutex{}
 
-func ensureLockForFilename(filename string) {
+// Returns the lock of the attribute file `filename`, creating it if needed.
+func ensureLockForFilename(filename string) *sync.RWMutex {
 	gCreationLock.Lock()
+	defer gCreationLock.Unlock()
 
 	if _, ok := gLocks[filename]; !ok {
 		if gLocks == nil {
@@ -23,67 +23,65 @@ func ensureLockForFilename(filename string) {
 		gLocks[filename] = &sync.RWMutex{}
 	}
 
-	gCreationLock.Unlock()
+	return gLocks[filename]
 }
 
+// Reads a string attribute, trimming surrounding whitespace. Failures yield an empty string.
 func ReadStringValue(filename string, basename string) string {
-	actualFilename := path.Join(filename, basename)
-	ensureLockForFilename(actualFilename)
-
-	gLocks[actualFilename].RLock()
-
-	data, _ := ioutil.ReadFile(actualFilename)
-	str := string(data)
-
-	gLocks[actualFilename].RUnlock()
-
-	return strings.TrimSpace(str)
+	str, _ := readString(path.Join(filename, basename))
+	return str
 }
 
-func ReadIntValue(filename string, basename string) int64 {
-	str := ReadStringValue(filename, basename)
-	result, _ := strconv.ParseInt(str, 10, 16)
-
-	return result
+// Writes a string attribute, ignoring failures.
+func WriteStringValue(filename string, basename string, value string) {
+	writeString(path.Join(filename, basename), value)
 }
 
-func ReadUInt8Value(filename string, basename string) uint8 {
-	return uint8(ReadIntValue(filename, basename))
-}
+func readString(filename string) (string, error) {
+	var str string
 
-func ReadUInt16Value(filename string, basename string) uint16 {
-	return uint16(ReadIntValue(filename, basename))
-}
+	err := retry("read", filename, func() error {
+		var err error
+		str, err = readOnce(filename)
+		return err
+	})
 
-func ReadInt16Value(filename string, basename string) int16 {
-	return int16(ReadIntValue(filename, basename))
+	return str, err
 }
 
-func ReadUInt32Value(filename string, basename string) uint32 {
-	return uint32(ReadIntValue(filename, basename))
-}
+func readOnce(filename string) (string, error) {
+	a := intercept("read", filename, "", func(a *Access) {
+		filename := path.Join(a.Path, a.Attribute)
+		lock := ensureLockForFilename(filename)
 
-func ReadInt32Value(filename string, basename string) int32 {
-	return int32(ReadIntValue(filename, basename))
-}
+		data, err := withTimeout(filename, func() ([]byte, error) {
+			lock.RLock()
+			defer lock.RUnlock()
 
-func WriteStringValue(filename string, basename string, value string) {
-	actualFilename := path.Join(filename, basename)
-	ensureLockForFilename(actualFilename)
+			return CurrentBackend().ReadFile(filename)
+		})
 
-	gLocks[actualFilename].Lock()
+		a.Value, a.Err = strings.TrimSpace(string(data)), err
+	})
 
-	data := []byte(value)
+	return a.Value, a.Err
+}
 
-	ioutil.WriteFile(actualFilename, data, 0644)
+func writeString(filename string, value string) error {
+	return retry("write", filename, func() error {
+		a := intercept("write", filename, value, func(a *Access) {
+			filename := path.Join(a.Path, a.Attribute)
+			lock := ensureLockForFilename(filename)
 
-	gLocks[actualFilename].Unlock()
-}
+			data := []byte(a.Value)
+			_, a.Err = withTimeout(filename, func() (bool, error) {
+				lock.Lock()
+				defer lock.Unlock()
 
-func WriteIntValue(filename string, basename string, value int64) {
-	WriteStringValue(filename, basename, strconv.FormatInt(value, 10))
-}
+				return true, CurrentBackend().WriteFile(filename, data)
+			})
+		})
 
-func WriteUIntValue(filename string, basename string, value uint64) {
-	WriteStringValue(filename, basename, strconv.FormatUint(value, 10))
+		return a.Err
+	})
 }
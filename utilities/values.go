@@ -0,0 +1,104 @@
+package utilities
+
+import (
+	"path"
+	"reflect"
+	"strconv"
+)
+
+// Types that can be stored in a sysfs attribute.
+type Value interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64 |
+		~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 |
+		~float32 | ~float64 | ~string | ~bool
+}
+
+// Reads an attribute and parses it as a T. Integers that don't fit into T are
+// reported as errors rather than truncated. Transient failures are retried
+// according to the retry policy; errors are returned as *IOError.
+func ReadValue[T Value](filename string, basename string) (T, error) {
+	var result T
+
+	actualFilename := path.Join(filename, basename)
+	v := reflect.ValueOf(&result).Elem()
+
+	err := retry("read", actualFilename, func() error {
+		str, err := readOnce(actualFilename)
+		if err != nil {
+			return err
+		}
+
+		// Numeric attributes read empty while a sensor is switching modes.
+		if str == "" && v.Kind() != reflect.String {
+			return errEmptyValue
+		}
+
+		return parseValue(str, v)
+	})
+
+	return result, err
+}
+
+// Formats `value` and writes it to an attribute.
+func WriteValue[T Value](filename string, basename string, value T) error {
+	return writeString(path.Join(filename, basename), formatValue(reflect.ValueOf(value)))
+}
+
+func parseValue(str string, v reflect.Value) error {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(str, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(str, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(str, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	case reflect.Bool:
+		// sysfs flags are written as 0 and 1.
+		switch str {
+		case "0":
+			v.SetBool(false)
+		case "1":
+			v.SetBool(true)
+		default:
+			b, err := strconv.ParseBool(str)
+			if err != nil {
+				return err
+			}
+			v.SetBool(b)
+		}
+	default:
+		v.SetString(str)
+	}
+
+	return nil
+}
+
+func formatValue(v reflect.Value) string {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', -1, v.Type().Bits())
+	case reflect.Bool:
+		if v.Bool() {
+			return "1"
+		}
+		return "0"
+	}
+
+	return v.String()
+}
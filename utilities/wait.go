@@ -0,0 +1,61 @@
+package utilities
+
+import (
+	"path"
+	"sync"
+	"time"
+
+	"github.com/jermon/GoEV3/Errors"
+)
+
+// Interval between the reads of WaitFor, in milliseconds, unless the device
+// was given another one.
+var WAIT_POLLING_INTERVAL = 20
+
+// Reads the attribute `basename` of the device at `folder` right away and
+// then every `interval`, from the shared polling scheduler, until `predicate`
+// accepts its content, instead of a loop of reads and sleeps:
+//
+//	err := utilities.WaitFor(folder, "state", func(s string) bool {
+//		return !strings.Contains(s, "running")
+//	}, 5*time.Second, 20*time.Millisecond)
+//
+// Returns an *IOError matching Errors.ErrWaitTimeout if it doesn't within
+// `timeout`, 0 to wait for as long as it takes, and the error of a read,
+// e.g. one matching Errors.ErrDisconnected.
+func WaitFor(folder string, basename string, predicate func(string) bool, timeout time.Duration, interval time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	attempts := 0
+	done := make(chan error, 1)
+
+	// Held until the task is assigned, which its first call may come before.
+	var lock sync.Mutex
+	lock.Lock()
+
+	var task *PollTask
+	task = Poll(interval, func() {
+		lock.Lock()
+		defer lock.Unlock()
+
+		attempts++
+
+		value, err := ReadValue[string](folder, basename)
+		switch {
+		case err != nil:
+		case predicate(value):
+		case timeout > 0 && !time.Now().Before(deadline):
+			err = &IOError{Op: "wait for", Path: path.Join(folder, basename), Attempts: attempts, Err: Errors.ErrWaitTimeout}
+		default:
+			return
+		}
+
+		task.Cancel()
+		select {
+		case done <- err:
+		default:
+		}
+	})
+	lock.Unlock()
+
+	return <-done
+}
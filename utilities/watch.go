@@ -0,0 +1,98 @@
+package utilities
+
+import (
+	"path"
+	"sync"
+	"time"
+)
+
+// Shortest and longest intervals between the reads of a watched value, in
+// milliseconds. A value is read at the shortest interval after it changed,
+// and less and less often while it stays the same.
+var (
+	WATCH_MIN_INTERVAL = 10
+	WATCH_MAX_INTERVAL = 40
+)
+
+// Calls a function whenever a value read periodically changes.
+//
+// ev3dev's drivers don't notify changes of their attributes to poll(2), so
+// values are read, from the shared polling scheduler: quickly after a change,
+// when more are likely to follow, e.g. as a button is pressed and released,
+// and then backing off to WATCH_MAX_INTERVAL, which bounds the latency.
+type Watch[T comparable] struct {
+	lock     sync.Mutex
+	task     *PollTask
+	read     func() T
+	fn       func(value T)
+	last     T
+	started  bool
+	interval time.Duration
+	shortest time.Duration
+	longest  time.Duration
+}
+
+// Calls `fn` with the value returned by `read` right away and then whenever
+// it changes, until the watch is cancelled. `fn` is called from the shared
+// scheduler goroutine, as Poll calls its function, and must return promptly.
+func WatchValue[T comparable](read func() T, fn func(value T)) *Watch[T] {
+	return WatchValueBetween(time.Duration(WATCH_MIN_INTERVAL)*time.Millisecond,
+		time.Duration(WATCH_MAX_INTERVAL)*time.Millisecond, read, fn)
+}
+
+// Watches a value like WatchValue, but between reads `shortest` apart after a
+// change and `longest` apart at most, instead of the package's intervals.
+func WatchValueBetween[T comparable](shortest time.Duration, longest time.Duration, read func() T, fn func(value T)) *Watch[T] {
+	if longest < shortest {
+		longest = shortest
+	}
+
+	w := &Watch[T]{read: read, fn: fn, shortest: shortest, longest: longest}
+
+	w.lock.Lock()
+	w.interval = shortest
+	w.task = Poll(w.interval, w.check)
+	w.lock.Unlock()
+
+	return w
+}
+
+// Watches the contents of the attribute file `basename` of the device folder
+// `filename`, as WatchValue does. Failed reads are skipped.
+func WatchAttribute(filename string, basename string, fn func(value string)) *Watch[string] {
+	var last string
+
+	return WatchValue(func() string {
+		if value, err := readString(path.Join(filename, basename)); err == nil {
+			last = value
+		}
+		return last
+	}, fn)
+}
+
+func (self *Watch[T]) check() {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+
+	value := self.read()
+
+	interval := self.interval * 2
+	if !self.started || value != self.last {
+		self.started, self.last = true, value
+		self.fn(value)
+		interval = self.shortest
+	}
+
+	if interval > self.longest {
+		interval = self.longest
+	}
+	if interval != self.interval {
+		self.interval = interval
+		self.task.SetInterval(interval)
+	}
+}
+
+// Stops watching. A call of the function in progress completes.
+func (self *Watch[T]) Cancel() {
+	self.task.Cancel()
+}